@@ -0,0 +1,116 @@
+// Command cdc-consumer is a sample consumer of the graph change stream. It
+// keeps an in-memory index of the current state of every entity, as a search
+// index or warehouse loader would, and commits each change only after it has
+// been applied so no change is lost if the consumer stops.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/graph"
+	"github.com/segmentio/kafka-go"
+)
+
+// seenLimit bounds how many change IDs are remembered to drop redeliveries
+const seenLimit = 100000
+
+func main() {
+	var (
+		brokers       = flag.String("brokers", "localhost:9092", "Comma-separated Kafka brokers")
+		group         = flag.String("group", "securizon-cdc-sample", "Consumer group")
+		fromBeginning = flag.Bool("from-beginning", false, "Replay the retained stream when the group has no committed offset")
+	)
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	startOffset := kafka.LastOffset
+	if *fromBeginning {
+		startOffset = kafka.FirstOffset
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     strings.Split(*brokers, ","),
+		GroupID:     *group,
+		Topic:       events.TopicGraphChanges,
+		StartOffset: startOffset,
+	})
+	defer reader.Close()
+
+	index := newIndex()
+	log.Printf("Consuming %s as group %s", events.TopicGraphChanges, *group)
+
+	for {
+		message, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("Stopping: indexed %d entities", len(index.entities))
+				return
+			}
+			log.Printf("Error reading change: %v", err)
+			continue
+		}
+
+		var change graph.ChangeEvent
+		if err := json.Unmarshal(message.Value, &change); err != nil {
+			// A malformed change can never be applied; skip it
+			log.Printf("Skipping malformed change at offset %d: %v", message.Offset, err)
+		} else {
+			index.apply(change)
+		}
+
+		if err := reader.CommitMessages(ctx, message); err != nil {
+			log.Printf("Error committing offset %d: %v", message.Offset, err)
+		}
+	}
+}
+
+// index holds the latest state of every entity seen on the stream
+type index struct {
+	entities map[string]json.RawMessage
+	seen     map[string]bool
+	order    []string
+}
+
+func newIndex() *index {
+	return &index{
+		entities: make(map[string]json.RawMessage),
+		seen:     make(map[string]bool),
+	}
+}
+
+// apply updates the index with a change, ignoring changes already applied
+func (idx *index) apply(change graph.ChangeEvent) {
+	if idx.seen[change.ID] {
+		return
+	}
+	idx.remember(change.ID)
+
+	key := change.Key()
+	switch change.Op {
+	case graph.ChangeDelete:
+		delete(idx.entities, key)
+	default:
+		idx.entities[key] = change.After
+	}
+	log.Printf("%s %s %s", change.Op, change.Entity, change.EntityID)
+}
+
+// remember records a change ID, forgetting the oldest beyond seenLimit
+func (idx *index) remember(id string) {
+	idx.seen[id] = true
+	idx.order = append(idx.order, id)
+	if len(idx.order) > seenLimit {
+		delete(idx.seen, idx.order[0])
+		idx.order = idx.order[1:]
+	}
+}
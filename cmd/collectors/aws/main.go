@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -33,6 +35,19 @@ func main() {
 	// Start collection routines
 	collectorMgr.Start()
 
+	// Serve the latest collection results next to the health checks
+	if cfg.Health.Enabled && cfg.Health.CollectionStatusPath != "" {
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Health.CollectionStatusPath, collectorMgr.StatusHandler())
+		statusServer := &http.Server{Addr: fmt.Sprintf(":%d", cfg.Health.Port), Handler: mux}
+		go func() {
+			if err := statusServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Collection status server failed: %v", err)
+			}
+		}()
+		defer statusServer.Shutdown(context.Background())
+	}
+
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
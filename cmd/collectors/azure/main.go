@@ -10,13 +10,16 @@ import (
 	"time"
 
 	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/sandbox"
+	"github.com/securizon/pkg/models"
 )
 
 func main() {
 	var (
-		configFile = flag.String("config", "config/azure-collector.yaml", "Configuration file path")
+		configFile   = flag.String("config", "config/azure-collector.yaml", "Configuration file path")
 		subscription = flag.String("subscription", "", "Azure subscription ID")
-		interval   = flag.Duration("interval", 5*time.Minute, "Collection interval")
+		interval     = flag.Duration("interval", 5*time.Minute, "Collection interval")
+		sandboxMode  = flag.Bool("sandbox", false, "Publish synthetic assets instead of calling Azure")
 	)
 	flag.Parse()
 
@@ -34,7 +37,9 @@ func main() {
 	defer eventBus.Close()
 
 	// Start collection
-	if err := startCollection(ctx, *subscription, *interval, eventBus); err != nil {
+	if *sandboxMode {
+		go sandbox.NewGenerator(sandboxConfig(*subscription, *interval)).Run(ctx, eventBus)
+	} else if err := startCollection(ctx, *subscription, *interval, eventBus); err != nil {
 		log.Fatalf("Failed to start collection: %v", err)
 	}
 
@@ -68,6 +73,18 @@ func collectAzureResources(ctx context.Context, subscription string, eventBus ev
 	return nil
 }
 
+// sandboxConfig generates a synthetic estate for the subscription being collected
+func sandboxConfig(subscription string, interval time.Duration) sandbox.Config {
+	config := sandbox.DefaultConfig()
+	config.Enabled = true
+	config.Interval = interval
+	if subscription == "" {
+		subscription = "sandbox-azure"
+	}
+	config.Accounts = map[models.Provider][]string{models.ProviderAzure: {subscription}}
+	return config
+}
+
 func waitForShutdown(ctx context.Context, cancel context.CancelFunc) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
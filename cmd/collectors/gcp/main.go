@@ -10,13 +10,16 @@ import (
 	"time"
 
 	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/sandbox"
+	"github.com/securizon/pkg/models"
 )
 
 func main() {
 	var (
-		configFile = flag.String("config", "config/gcp-collector.yaml", "Configuration file path")
-		project    = flag.String("project", "", "GCP project ID")
-		interval   = flag.Duration("interval", 5*time.Minute, "Collection interval")
+		configFile  = flag.String("config", "config/gcp-collector.yaml", "Configuration file path")
+		project     = flag.String("project", "", "GCP project ID")
+		interval    = flag.Duration("interval", 5*time.Minute, "Collection interval")
+		sandboxMode = flag.Bool("sandbox", false, "Publish synthetic assets instead of calling GCP")
 	)
 	flag.Parse()
 
@@ -34,7 +37,9 @@ func main() {
 	defer eventBus.Close()
 
 	// Start collection
-	if err := startCollection(ctx, *project, *interval, eventBus); err != nil {
+	if *sandboxMode {
+		go sandbox.NewGenerator(sandboxConfig(*project, *interval)).Run(ctx, eventBus)
+	} else if err := startCollection(ctx, *project, *interval, eventBus); err != nil {
 		log.Fatalf("Failed to start collection: %v", err)
 	}
 
@@ -68,6 +73,18 @@ func collectGCPResources(ctx context.Context, project string, eventBus events.Ev
 	return nil
 }
 
+// sandboxConfig generates a synthetic estate for the project being collected
+func sandboxConfig(project string, interval time.Duration) sandbox.Config {
+	config := sandbox.DefaultConfig()
+	config.Enabled = true
+	config.Interval = interval
+	if project == "" {
+		project = "sandbox-gcp"
+	}
+	config.Accounts = map[models.Provider][]string{models.ProviderGCP: {project}}
+	return config
+}
+
 func waitForShutdown(ctx context.Context, cancel context.CancelFunc) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
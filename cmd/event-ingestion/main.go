@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"os"
 	"os/signal"
@@ -9,7 +10,9 @@ import (
 
 	"github.com/securazion/event-ingestion/internal/api"
 	"github.com/securazion/event-ingestion/internal/kafka"
-	"github.com/securazion/event-ingestion/internal/validator"
+	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/validator"
+	"github.com/securizon/pkg/models"
 )
 
 func main() {
@@ -74,17 +77,46 @@ func consumeCollectorEvents(ctx context.Context, consumer kafka.Consumer,
 			// Validate and normalize the event
 			normalized, err := validator.ValidateAndNormalize(msg.Value)
 			if err != nil {
+				// Every error is a *validator.ValidationError: the event
+				// cannot be processed, so it is dead-lettered as received
 				log.Printf("Invalid event: %v", err)
-				// Send to DLQ
 				producer.Produce("events.dlq", msg.Key, msg.Value)
 				continue
 			}
 			
+			value, err := json.Marshal(normalized)
+			if err != nil {
+				log.Printf("Failed to encode event %s: %v", normalized.ID, err)
+				continue
+			}
+			
 			// Route to appropriate topic based on event type
 			topic := determineTopic(normalized)
-			if err := producer.Produce(topic, msg.Key, normalized); err != nil {
+			if err := producer.Produce(topic, msg.Key, value); err != nil {
 				log.Printf("Failed to produce event: %v", err)
 			}
 		}
 	}
 }
+
+// determineTopic returns the topic a normalized event is published to.
+// Provider audit records of resource changes are security events: asset
+// topics carry AssetEvent payloads, which the records are not.
+func determineTopic(event *models.BaseEvent) string {
+	switch event.Type {
+	case models.EventTypeResourceCreated, models.EventTypeResourceUpdated, models.EventTypeResourceDeleted:
+		return events.TopicSecurityEvents
+	case models.EventTypeAssetCreated, models.EventTypeAssetUpdated, models.EventTypeAssetDeleted:
+		return events.TopicAssetUpserts
+	case models.EventTypeRelationshipCreated, models.EventTypeRelationshipUpdated, models.EventTypeRelationshipDeleted:
+		return events.TopicAssetRelationships
+	case models.EventTypeFindingCreated, models.EventTypeFindingUpdated, models.EventTypeFindingResolved:
+		return events.TopicFindings
+	case models.EventTypePolicyViolation:
+		return events.TopicPolicyViolations
+	case models.EventTypeRiskScoreChanged:
+		return events.TopicRiskScores
+	default:
+		return events.TopicSecurityEvents
+	}
+}
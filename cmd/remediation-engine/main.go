@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/securazion/remediation-engine/internal/executor"
 	"github.com/securazion/remediation-engine/internal/kafka"
 	"github.com/securazion/remediation-engine/internal/playbook"
+	"github.com/securazion/remediation-engine/internal/sandbox"
 	"github.com/securazion/remediation-engine/internal/store"
 	"github.com/securazion/remediation-engine/internal/workflow"
 )
@@ -35,18 +37,41 @@ func main() {
 		log.Fatal("Failed to load playbooks:", err)
 	}
 
+	config := loadEngineConfig()
+
+	// In sandbox mode, remediation steps and approval notifications are
+	// recorded instead of touching real infrastructure or people
+	var recorder *sandbox.Recorder
+	if config.Sandbox {
+		log.Println("Sandbox mode: remediation steps and notifications are recorded, not run")
+		recorder = sandbox.NewRecorder()
+	}
+
 	// Create executor with different runners for each cloud provider
 	exec := executor.NewExecutor(db, kafkaProducer)
-	exec.RegisterRunner("aws", executor.NewAWSRunner())
-	exec.RegisterRunner("azure", executor.NewAzureRunner())
-	exec.RegisterRunner("gcp", executor.NewGCPRunner())
-	exec.RegisterRunner("script", executor.NewScriptRunner())
+	if recorder != nil {
+		for _, runner := range []string{"aws", "azure", "gcp", "script"} {
+			exec.RegisterRunner(runner, executor.NewSandboxRunner(runner, recorder))
+		}
+	} else {
+		exec.RegisterRunner("aws", executor.NewAWSRunner())
+		exec.RegisterRunner("azure", executor.NewAzureRunner())
+		exec.RegisterRunner("gcp", executor.NewGCPRunner())
+		exec.RegisterRunner("script", executor.NewScriptRunner())
+	}
 
 	// Create approval workflow manager
 	approvalManager := workflow.NewApprovalManager(db, kafkaProducer)
+	if recorder != nil {
+		approvalManager.AddNotifier(sandbox.NewNotifier(recorder))
+	}
 
 	// Create remediation engine
-	engine := NewRemediationEngine(exec, approvalManager, playbookManager, db)
+	engine, err := NewRemediationEngine(exec, approvalManager, playbookManager, db, config)
+	if err != nil {
+		log.Fatal("Failed to create remediation engine:", err)
+	}
+	engine.SetFindingEventPublisher(NewFindingEventPublisher(kafkaProducer, "findings"))
 	go engine.Start(ctx)
 
 	// Start HTTP server for API
@@ -65,3 +90,39 @@ func main() {
 	log.Println("Shutting down remediation engine...")
 	server.Stop(ctx)
 }
+
+// loadEngineConfig builds the engine configuration, applying any overrides
+// from the environment on top of the defaults
+func loadEngineConfig() EngineConfig {
+	config := DefaultEngineConfig()
+
+	if v, err := strconv.Atoi(os.Getenv("REMEDIATION_WORKERS")); err == nil {
+		config.Workers = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REMEDIATION_QUEUE_SIZE")); err == nil {
+		config.QueueSize = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("REMEDIATION_WORKER_TIMEOUT")); err == nil {
+		config.WorkerTimeout = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REMEDIATION_MAX_RETRIES")); err == nil {
+		config.MaxRetries = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("REMEDIATION_RETRY_BACKOFF")); err == nil {
+		config.RetryBackoff = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("REMEDIATION_JOB_RETENTION")); err == nil {
+		config.JobRetention = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("REMEDIATION_CLEANUP_INTERVAL")); err == nil {
+		config.CleanupInterval = v
+	}
+	if v, err := strconv.ParseBool(os.Getenv("REMEDIATION_AUTO_RESOLVE_FINDINGS")); err == nil {
+		config.AutoResolveFindings = v
+	}
+	if v, err := strconv.ParseBool(os.Getenv("REMEDIATION_SANDBOX")); err == nil {
+		config.Sandbox = v
+	}
+
+	return config
+}
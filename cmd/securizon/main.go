@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -11,10 +13,16 @@ import (
 	"time"
 
 	"github.com/securizon/internal/api"
+	"github.com/securizon/internal/enrichment"
 	"github.com/securizon/internal/events"
 	"github.com/securizon/internal/graph"
+	"github.com/securizon/internal/policy"
 	"github.com/securizon/internal/risk"
+	"github.com/securizon/internal/sandbox"
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/internal/threatintel"
 	"github.com/securizon/pkg/models"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -26,6 +34,7 @@ var (
 func main() {
 	var (
 		configFile = flag.String("config", "config/config.yaml", "Configuration file path")
+		validate   = flag.Bool("validate-config", false, "Validate the configuration and exit")
 		version    = flag.Bool("version", false, "Show version information")
 		help       = flag.Bool("help", false, "Show help information")
 	)
@@ -41,6 +50,13 @@ func main() {
 		return
 	}
 
+	if *validate {
+		if !validateConfig(*configFile, os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Printf("Starting SecuRizon v%s (commit: %s, built: %s)", version, commit, date)
 
 	// Load configuration
@@ -48,6 +64,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := config.API.ValidateAuth(); err != nil {
+		log.Fatalf("Invalid API configuration: %v", err)
+	}
 
 	// Initialize components
 	ctx, cancel := context.WithCancel(context.Background())
@@ -67,19 +86,180 @@ func main() {
 	}
 	defer eventBus.Close()
 
+	// Keep pinned tenants' data on their region's Neo4j and Kafka endpoints
+	var store graph.GraphStore = graphStore
+	var bus changeBus = eventBus
+	var pathSource attackPathSource = graphStore
+	regions := []string{""}
+	if config.Residency.Enabled() {
+		regionalStore, regionalBus, closeRegions, err := openRegions(config, graphStore, eventBus)
+		if err != nil {
+			log.Fatalf("Failed to initialize regions: %v", err)
+		}
+		defer closeRegions()
+		store, bus, pathSource = regionalStore, regionalBus, regionalStore
+		regions = config.Residency.RegionNames()
+	}
+
+	// Publish every graph mutation to the change stream when enabled
+	if config.CDC.Enabled {
+		store = graph.NewCDCStore(store, bus, config.CDC)
+	}
+
+	// Match assets against threat intelligence feeds when configured
+	var threatIntel risk.ThreatIntelProvider
+	if config.ThreatIntel.Enabled {
+		provider := threatintel.NewProvider(config.ThreatIntel, nil)
+		go provider.Run(ctx)
+		threatIntel = provider
+	}
+
 	// Initialize risk engine
-	riskEngine := risk.NewEngine(config.Risk, graphStore, nil, nil)
+	riskEngine := risk.NewEngine(config.Risk, store, threatIntel, nil, nil)
+	// Level the scores the store reads and writes with the engine's mapping
+	graph.SetRiskLevels(store, riskEngine.RiskLevel)
+
+	// Recalculate risk on a per-environment cadence, expire assets that are
+	// no longer collected and prune old risk history, in every region
+	for _, region := range regions {
+		regionCtx := tenant.WithRegion(ctx, region)
+		go risk.NewScheduler(riskEngine, config.RiskSchedule).Run(regionCtx)
+		go graph.NewExpirySweeper(store, config.Expiry).Run(regionCtx)
+		go graph.NewRiskHistoryPruner(store, config.RiskHistory).Run(regionCtx)
+	}
 
 	// Initialize API gateway
-	gateway := api.NewGateway(config.API, graphStore, riskEngine, eventBus)
+	gateway := api.NewGateway(config.API, store, riskEngine, bus)
+
+	// Flag accepted attack paths in results and serve the allowlist
+	paths, _ := store.(graph.AcceptedPathStore)
+	allowlist, err := graph.NewPathAllowlist(ctx, paths)
+	if err != nil {
+		log.Fatalf("Failed to load accepted attack paths: %v", err)
+	}
+	gateway.SetAcceptedPaths(allowlist)
+
+	// Serve the policy category taxonomy of the loaded policies
+	policyEngine := policy.NewEngine(policy.PolicyConfig{
+		PolicyPaths: config.PolicyDirs,
+		Categories:  config.PolicyCategories,
+	})
+	gateway.SetPolicyCatalog(policyEngine)
+
+	// Process collector events and list the processor's handlers
+	processor := events.NewEventProcessor(bus, store, processorRiskEngine{ctx: ctx, engine: riskEngine},
+		processorPolicyEngine{engine: policyEngine}, events.DefaultProcessorConfig())
+	gateway.SetEventHandlerRegistry(processor)
+
+	// Attach CVSS and EPSS scores to findings that reference CVEs
+	if config.CVE.Enabled {
+		processor.SetFindingEnricher(enrichment.NewCVEEnricher(config.CVE, enrichment.NewHTTPCVEFeed(config.CVE)))
+	}
+
+	// Serve selector-based attack path discovery
+	attackPathConfig := graph.DefaultAttackPathConfig()
+	for relType, mapping := range config.AttackTechniques {
+		attackPathConfig.TechniqueMapping[relType] = mapping
+	}
+	attackPaths, err := pathSource.AttackPathEngine(attackPathConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize attack path engine: %v", err)
+	}
+	attackPaths.SetAllowlist(allowlist)
+	gateway.SetAttackPathFinder(attackPaths)
+	gateway.SetAttackPathWatcher(attackPaths)
+
+	// Recompute the attack paths through assets whose relationships change
+	processor.SetPathRecomputer(attackPaths)
 
 	// Start services
-	if err := startServices(ctx, config, eventBus, gateway); err != nil {
+	if err := startServices(ctx, processor); err != nil {
 		log.Fatalf("Failed to start services: %v", err)
 	}
 
+	// Feed a synthetic estate through the normal pipeline in sandbox mode
+	if config.Sandbox.Enabled {
+		go sandbox.NewGenerator(config.Sandbox).Run(ctx, bus)
+	}
+
 	// Wait for shutdown signal
-	waitForShutdown(ctx, cancel, gateway)
+	waitForShutdown(ctx, cancel, gateway, processor)
+}
+
+// attackPathSource builds the attack path engine over the graph databases
+// of the store, one per region with data residency
+type attackPathSource interface {
+	AttackPathEngine(config graph.AttackPathConfig) (*graph.AttackPathEngine, error)
+}
+
+// changeBus is an event bus that also carries the graph change stream
+type changeBus interface {
+	events.EventBus
+	graph.ChangePublisher
+}
+
+// openRegions connects to the Neo4j and Kafka endpoints of every configured
+// region and returns a store and bus routing by data region, with the main
+// store and bus serving the default region. The returned function closes the
+// regional connections.
+func openRegions(config *Config, graphStore graph.GraphStore, eventBus *events.KafkaEventBus) (*graph.RegionalStore, *events.RegionalEventBus, func(), error) {
+	stores := map[string]graph.GraphStore{config.Residency.DefaultRegion: graphStore}
+	buses := map[string]events.EventBus{config.Residency.DefaultRegion: eventBus}
+	var closers []io.Closer
+	closeRegions := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	for region, endpoints := range config.Residency.Regions {
+		if region == config.Residency.DefaultRegion {
+			continue
+		}
+
+		// A region never falls back to the default region's endpoints
+		if endpoints.Neo4jURI == "" || len(endpoints.KafkaBrokers) == 0 {
+			closeRegions()
+			return nil, nil, nil, fmt.Errorf("region %s needs its own neo4j_uri and kafka_brokers", region)
+		}
+
+		graphConfig := config.Graph
+		graphConfig.URI = endpoints.Neo4jURI
+		if endpoints.Neo4jDatabase != "" {
+			graphConfig.Database = endpoints.Neo4jDatabase
+		}
+		regionStore, err := graph.NewNeo4jStore(graphConfig)
+		if err != nil {
+			closeRegions()
+			return nil, nil, nil, fmt.Errorf("region %s graph store: %w", region, err)
+		}
+		closers = append(closers, regionStore)
+		stores[region] = regionStore
+
+		eventsConfig := config.Events
+		eventsConfig.Brokers = endpoints.KafkaBrokers
+		regionBus, err := events.NewKafkaEventBus(eventsConfig)
+		if err != nil {
+			closeRegions()
+			return nil, nil, nil, fmt.Errorf("region %s event bus: %w", region, err)
+		}
+		closers = append(closers, regionBus)
+		buses[region] = regionBus
+
+		log.Printf("Region %s: graph %s, brokers %v", region, graphConfig.URI, eventsConfig.Brokers)
+	}
+
+	regionalStore, err := graph.NewRegionalStore(stores, config.Residency.DefaultRegion)
+	if err != nil {
+		closeRegions()
+		return nil, nil, nil, err
+	}
+	regionalBus, err := events.NewRegionalEventBus(buses, config.Residency.DefaultRegion)
+	if err != nil {
+		closeRegions()
+		return nil, nil, nil, err
+	}
+	return regionalStore, regionalBus, closeRegions, nil
 }
 
 func showHelp() {
@@ -91,6 +271,9 @@ Usage:
 Flags:
   -config string
         Configuration file path (default "config/config.yaml")
+  -validate-config
+        Validate the configuration, including connectivity to Neo4j and
+        Kafka, and exit without starting services
   -version
         Show version information
   -help
@@ -99,6 +282,7 @@ Flags:
 Examples:
   securizon                                    # Start with default config
   securizon -config config/production.yaml     # Start with production config
+  securizon -validate-config -config prod.yaml # Check a config before deploying
   securizon -version                           # Show version
 
 For more information, visit: https://github.com/prompt-general/SecuRizon
@@ -111,18 +295,56 @@ func showVersion() {
 	fmt.Printf("Built: %s\n", date)
 }
 
+// loadConfig reads the configuration at path over the defaults. Passwords
+// may reference environment variables as ${VAR}.
 func loadConfig(path string) (*Config, error) {
-	// Configuration loading implementation
-	return &Config{}, nil
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Unknown keys are rejected so a misspelled setting is not silently
+	// left at its default
+	config := defaultConfig()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	config.Graph.Password = os.ExpandEnv(config.Graph.Password)
+	config.Events.SASLPassword = os.ExpandEnv(config.Events.SASLPassword)
+
+	return config, nil
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Graph:        graph.DefaultGraphConfig(),
+		Events:       events.DefaultKafkaConfig(),
+		Risk:         risk.DefaultEngineConfig(),
+		RiskSchedule: risk.DefaultScheduleConfig(),
+		Expiry:       graph.DefaultExpiryConfig(),
+		RiskHistory:  graph.DefaultRiskHistoryConfig(),
+		CDC:          graph.DefaultCDCConfig(),
+		Residency:    tenant.DefaultResidencyConfig(),
+		Sandbox:      sandbox.DefaultConfig(),
+		ThreatIntel:  threatintel.DefaultConfig(),
+		CVE:          enrichment.DefaultCVEConfig(),
+		API:          api.DefaultGatewayConfig(),
+		PlaybookDirs: []string{"playbooks"},
+		PolicyDirs:   []string{"policies"},
+	}
 }
 
-func startServices(ctx context.Context, config *Config, eventBus events.EventBus, gateway *api.Gateway) error {
-	// Start event processor
-	// Start API gateway
+func startServices(ctx context.Context, processor *events.EventProcessor) error {
+	if err := processor.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start event processor: %w", err)
+	}
 	return nil
 }
 
-func waitForShutdown(ctx context.Context, cancel context.CancelFunc, gateway *api.Gateway) {
+func waitForShutdown(ctx context.Context, cancel context.CancelFunc, gateway *api.Gateway, processor *events.EventProcessor) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -136,13 +358,31 @@ func waitForShutdown(ctx context.Context, cancel context.CancelFunc, gateway *ap
 		log.Printf("Error during gateway shutdown: %v", err)
 	}
 
+	// Let the processor finish the events it has queued and its pending
+	// attack path recomputes
 	cancel()
+	processor.Wait()
 	log.Println("SecuRizon stopped")
 }
 
 type Config struct {
-	Graph   graph.GraphConfig    `yaml:"graph"`
-	Events  events.KafkaConfig   `yaml:"events"`
-	Risk    risk.EngineConfig    `yaml:"risk"`
-	API     api.GatewayConfig    `yaml:"api"`
+	Graph        graph.GraphConfig       `yaml:"graph"`
+	Events       events.KafkaConfig      `yaml:"events"`
+	Risk         risk.EngineConfig       `yaml:"risk"`
+	RiskSchedule risk.ScheduleConfig     `yaml:"risk_schedule"`
+	Expiry       graph.ExpiryConfig      `yaml:"expiry"`
+	RiskHistory  graph.RiskHistoryConfig `yaml:"risk_history"`
+	CDC          graph.CDCConfig         `yaml:"cdc"`
+	Residency    tenant.ResidencyConfig  `yaml:"residency"`
+	Sandbox      sandbox.Config          `yaml:"sandbox"`
+	ThreatIntel  threatintel.Config      `yaml:"threat_intel"`
+	CVE          enrichment.CVEConfig    `yaml:"cve"`
+	API          api.GatewayConfig       `yaml:"api"`
+	PlaybookDirs []string                `yaml:"playbook_dirs"`
+	PolicyDirs   []string                `yaml:"policy_dirs"`
+	// PolicyCategories replaces the built-in policy category taxonomy
+	PolicyCategories []models.PolicyCategory `yaml:"policy_categories"`
+	// AttackTechniques overrides the MITRE ATT&CK tactic and techniques of
+	// attack path hops by relationship type
+	AttackTechniques map[string]graph.TechniqueMapping `yaml:"attack_techniques"`
 }
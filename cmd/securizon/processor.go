@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/policy"
+	"github.com/securizon/internal/risk"
+	"github.com/securizon/pkg/models"
+)
+
+// processorRiskEngine adapts the risk engine to the event processor, whose
+// risk calls carry no context of their own
+type processorRiskEngine struct {
+	ctx    context.Context
+	engine *risk.Engine
+}
+
+func (r processorRiskEngine) CalculateRisk(asset models.Asset, findings []models.Finding, threats []models.ThreatEvent) models.RiskScore {
+	score, err := r.engine.CalculateRisk(r.ctx, asset, findings, threats)
+	if err != nil {
+		log.Printf("Failed to calculate risk for asset %s: %v", asset.GetID(), err)
+		return models.RiskScore{}
+	}
+	return score
+}
+
+func (r processorRiskEngine) RecalculateRisk(assetID string) (models.RiskScore, error) {
+	return r.engine.RecalculateRisk(r.ctx, assetID)
+}
+
+func (r processorRiskEngine) UpdateRiskScore(assetID string, score models.RiskScore) error {
+	return r.engine.UpdateRiskScore(r.ctx, assetID, score)
+}
+
+// processorPolicyEngine adapts the policy engine to the event processor
+type processorPolicyEngine struct {
+	engine *policy.PolicyEngine
+}
+
+func (p processorPolicyEngine) EvaluateAsset(ctx context.Context, asset models.Asset) ([]models.Finding, error) {
+	return p.engine.EvaluateAsset(asset), nil
+}
+
+func (p processorPolicyEngine) EvaluatePolicy(ctx context.Context, policyID string, asset models.Asset) (*models.Finding, error) {
+	for _, finding := range p.engine.EvaluateAsset(asset) {
+		if finding.PolicyID == policyID {
+			return &finding, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p processorPolicyEngine) GetPolicies(ctx context.Context, filter events.PolicyFilter) ([]events.Policy, error) {
+	return nil, nil
+}
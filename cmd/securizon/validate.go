@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/securizon/internal/api"
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/internal/threatintel"
+	"gopkg.in/yaml.v3"
+)
+
+// checkTimeout bounds each check that reaches out to a dependency
+const checkTimeout = 10 * time.Second
+
+// validationCheck is one step of -validate-config
+type validationCheck struct {
+	name string
+	run  func(ctx context.Context, config *Config) error
+}
+
+var validationChecks = []validationCheck{
+	{name: "required fields", run: checkRequiredFields},
+	{name: "neo4j connectivity", run: checkNeo4j},
+	{name: "kafka brokers", run: checkKafkaBrokers},
+	{name: "playbooks", run: checkPlaybooks},
+	{name: "policies", run: checkPolicies},
+}
+
+// validateConfig loads the configuration at path and runs every check
+// against it, writing a pass/fail report to out. It reports whether the
+// configuration is valid.
+func validateConfig(path string, out io.Writer) bool {
+	fmt.Fprintf(out, "Validating %s\n\n", path)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		fmt.Fprintf(out, "  FAIL  load config: %v\n\nConfiguration is invalid\n", err)
+		return false
+	}
+	fmt.Fprintf(out, "  PASS  load config\n")
+
+	failed := 0
+	for _, check := range validationChecks {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		err := check.run(ctx, config)
+		cancel()
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "  FAIL  %s: %v\n", check.name, err)
+			continue
+		}
+		fmt.Fprintf(out, "  PASS  %s\n", check.name)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(out, "\nConfiguration is invalid: %d of %d checks failed\n", failed, len(validationChecks))
+		return false
+	}
+	fmt.Fprintf(out, "\nConfiguration is valid\n")
+	return true
+}
+
+// checkRequiredFields reports every missing or out-of-range setting
+func checkRequiredFields(ctx context.Context, config *Config) error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if config.Graph.URI == "" {
+		add("graph.uri is required")
+	} else if u, err := url.Parse(config.Graph.URI); err != nil || u.Scheme == "" || u.Host == "" {
+		add("graph.uri %q is not a valid URI", config.Graph.URI)
+	}
+	if config.Graph.Username == "" {
+		add("graph.username is required")
+	}
+	if config.Graph.MaxPoolSize <= 0 {
+		add("graph.maxpoolsize must be greater than 0")
+	}
+	if config.Graph.DataEncoding != graph.DataEncodingJSON && config.Graph.DataEncoding != graph.DataEncodingGzip {
+		add("graph.dataencoding must be %s or %s", graph.DataEncodingJSON, graph.DataEncodingGzip)
+	}
+
+	if len(config.Events.Brokers) == 0 {
+		add("events.brokers is required")
+	}
+	if config.Events.ClientID == "" {
+		add("events.clientid is required")
+	}
+	if config.Events.ConsumerGroup == "" {
+		add("events.consumergroup is required")
+	}
+
+	if config.API.Port <= 0 || config.API.Port > 65535 {
+		add("api.port must be between 1 and 65535")
+	}
+	if config.API.EnableAuth && config.API.AuthType == "jwt" && config.API.JWTSecret == "" {
+		add("api.jwtsecret is required when jwt auth is enabled")
+	}
+	for i, key := range config.API.APIKeys {
+		if key.ID == "" || len(key.KeyHash) != 64 {
+			add("api.api_keys[%d] must have an id and a hex SHA-256 key_hash", i)
+		}
+	}
+	if config.API.RateLimitEnabled && config.API.RateLimitRPS <= 0 {
+		add("api.rate_limit_rps must be greater than 0 when rate limiting is enabled")
+	}
+	if config.API.EnableAuth && config.API.AuthType == "jwt" {
+		if len(config.API.JWTAlgorithms) == 0 {
+			add("api.jwt_algorithms must not be empty when jwt auth is enabled")
+		}
+		for _, alg := range config.API.JWTAlgorithms {
+			if !api.SupportedJWTAlgorithm(alg) {
+				add("api.jwt_algorithms: unsupported algorithm %q", alg)
+			}
+		}
+	}
+	if config.API.GraphQLMaxDepth <= 0 {
+		add("api.graphql_max_depth must be greater than 0")
+	}
+	if config.API.GraphQLMaxCost <= 0 {
+		add("api.graphql_max_cost must be greater than 0")
+	}
+	if config.API.StreamKeepAlive <= 0 {
+		add("api.stream_keep_alive must be greater than 0")
+	}
+	if config.API.AttackPathDebounce < 0 {
+		add("api.attack_path_debounce must not be negative")
+	}
+
+	risk := config.Risk
+	if risk.CriticalThreshold > 100 || !(risk.CriticalThreshold > risk.HighThreshold &&
+		risk.HighThreshold > risk.MediumThreshold && risk.MediumThreshold > 0) {
+		add("risk thresholds must satisfy 100 >= critical > high > medium > 0")
+	}
+	if risk.EnablePropagation && !(risk.DecayFactor > 0 && risk.DecayFactor < 1) {
+		add("risk.decay_factor must be between 0 and 1 when propagation is enabled")
+	}
+	if risk.MaxPropagationAssets < 0 {
+		add("risk.max_propagation_assets must not be negative")
+	}
+	if risk.BatchSize <= 0 {
+		add("risk.batch_size must be greater than 0")
+	}
+	if risk.MaxConcurrency < 0 {
+		add("risk.max_concurrency must not be negative")
+	}
+	if risk.CacheRefreshAhead && !(risk.CacheRefreshThreshold > 0 && risk.CacheRefreshThreshold < 1) {
+		add("risk.cache_refresh_threshold must be between 0 and 1 when refresh-ahead is enabled")
+	}
+
+	if config.RiskSchedule.CheckInterval <= 0 {
+		add("risk_schedule.check_interval must be greater than 0")
+	}
+	if len(config.Expiry.TTLs) > 0 && config.Expiry.SweepInterval <= 0 {
+		add("expiry.sweep_interval must be greater than 0 when ttls are set")
+	}
+	if config.RiskHistory.Retention > 0 && config.RiskHistory.PruneInterval <= 0 {
+		add("risk_history.prune_interval must be greater than 0 when retention is set")
+	}
+
+	if config.Residency.Enabled() {
+		if config.Residency.DefaultRegion == "" {
+			add("residency.default_region is required when regions are set")
+		}
+		for region, endpoints := range config.Residency.Regions {
+			if region == config.Residency.DefaultRegion {
+				continue
+			}
+			if endpoints.Neo4jURI == "" || len(endpoints.KafkaBrokers) == 0 {
+				add("residency.regions.%s needs its own neo4j_uri and kafka_brokers", region)
+			}
+		}
+	}
+
+	if config.Sandbox.Enabled {
+		if config.Sandbox.Interval <= 0 {
+			add("sandbox.interval must be greater than 0")
+		}
+		if config.Sandbox.Workloads <= 0 {
+			add("sandbox.workloads must be greater than 0")
+		}
+	}
+
+	if config.ThreatIntel.Enabled {
+		if len(config.ThreatIntel.Feeds) == 0 {
+			add("threat_intel.feeds must not be empty when threat intelligence is enabled")
+		}
+		for i, feed := range config.ThreatIntel.Feeds {
+			if feed.Name == "" {
+				add("threat_intel.feeds[%d].name is required", i)
+			}
+			if u, err := url.Parse(feed.URL); err != nil || u.Scheme == "" || u.Host == "" {
+				add("threat_intel.feeds[%d].url must be an absolute URL", i)
+			}
+			if feed.Format != "" && feed.Format != threatintel.FormatJSON && feed.Format != threatintel.FormatSTIX {
+				add("threat_intel.feeds[%d].format must be %q or %q", i, threatintel.FormatJSON, threatintel.FormatSTIX)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkNeo4j connects and authenticates to the graph database
+func checkNeo4j(ctx context.Context, config *Config) error {
+	return graph.VerifyConnectivity(ctx, config.Graph)
+}
+
+// checkKafkaBrokers resolves the host of every broker
+func checkKafkaBrokers(ctx context.Context, config *Config) error {
+	var problems []string
+	for _, broker := range config.Events.Brokers {
+		host, _, err := net.SplitHostPort(broker)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: expected host:port", broker))
+			continue
+		}
+		if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", broker, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// playbookDocument is the part of a playbook checked before deploying
+type playbookDocument struct {
+	ID    string `yaml:"id"`
+	Name  string `yaml:"name"`
+	Steps []struct {
+		Name   string `yaml:"name"`
+		Action string `yaml:"action"`
+	} `yaml:"steps"`
+}
+
+// checkPlaybooks parses every playbook and checks it can be run
+func checkPlaybooks(ctx context.Context, config *Config) error {
+	return walkYAML(config.PlaybookDirs, func(path string, decoder *yaml.Decoder) error {
+		var playbook playbookDocument
+		if err := decoder.Decode(&playbook); err != nil {
+			return err
+		}
+		if playbook.ID == "" || playbook.Name == "" {
+			return fmt.Errorf("id and name are required")
+		}
+		if len(playbook.Steps) == 0 {
+			return fmt.Errorf("playbook %s has no steps", playbook.ID)
+		}
+		for i, step := range playbook.Steps {
+			if step.Action == "" {
+				return fmt.Errorf("step %d (%s) of playbook %s has no action", i+1, step.Name, playbook.ID)
+			}
+		}
+		return nil
+	})
+}
+
+// checkPolicies parses every document of every policy file
+func checkPolicies(ctx context.Context, config *Config) error {
+	return walkYAML(config.PolicyDirs, func(path string, decoder *yaml.Decoder) error {
+		for {
+			var document map[string]interface{}
+			err := decoder.Decode(&document)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// walkYAML calls check with a decoder for each YAML file under dirs,
+// collecting the files that fail
+func walkYAML(dirs []string, check func(path string, decoder *yaml.Decoder) error) error {
+	var problems []string
+	files := 0
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := filepath.Ext(path)
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				return nil
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			files++
+			if err := check(path, yaml.NewDecoder(f)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			}
+			return nil
+		})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", dir, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	if files == 0 {
+		return fmt.Errorf("no YAML files found in %s", strings.Join(dirs, ", "))
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ require (
 	github.com/stripe/stripe-go/v74 v74.30.0
 	github.com/pgvector/pgvector-go v0.1.1
 	github.com/sashabaranov/go-openai v1.17.9
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// AcceptedPathRegistry manages the allowlist of accepted attack paths.
+// *graph.PathAllowlist satisfies it.
+type AcceptedPathRegistry interface {
+	List(ctx context.Context, includeExpired bool) []models.AcceptedPath
+	Get(ctx context.Context, id string) (models.AcceptedPath, error)
+	Create(ctx context.Context, entry models.AcceptedPath) (models.AcceptedPath, error)
+	Update(ctx context.Context, id string, entry models.AcceptedPath) (models.AcceptedPath, error)
+	Delete(ctx context.Context, id string) error
+	AnnotateGraphPaths(ctx context.Context, paths []models.GraphPath)
+}
+
+// AcceptedPathRequest creates or replaces an accepted path
+type AcceptedPathRequest struct {
+	SourceID  string     `json:"source_id"`
+	TargetID  string     `json:"target_id"`
+	EdgeTypes []string   `json:"edge_types,omitempty"`
+	Reason    string     `json:"reason"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (req AcceptedPathRequest) entry() models.AcceptedPath {
+	return models.AcceptedPath{
+		SourceID:  req.SourceID,
+		TargetID:  req.TargetID,
+		EdgeTypes: req.EdgeTypes,
+		Reason:    req.Reason,
+		CreatedBy: req.CreatedBy,
+		ExpiresAt: req.ExpiresAt,
+	}
+}
+
+// SetAcceptedPaths enables the accepted attack path endpoints and flags
+// accepted paths in attack path results
+func (g *Gateway) SetAcceptedPaths(registry AcceptedPathRegistry) {
+	g.acceptedPaths = registry
+}
+
+// acceptedPathRegistry returns the registry, writing a not implemented
+// response and returning false if none is set
+func (g *Gateway) acceptedPathRegistry(w http.ResponseWriter) (AcceptedPathRegistry, bool) {
+	if g.acceptedPaths == nil {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Accepted attack paths are not supported", "")
+		return nil, false
+	}
+	return g.acceptedPaths, true
+}
+
+// handleListAcceptedPaths lists the accepted paths; expired ones are only
+// included with include_expired=true
+func (g *Gateway) handleListAcceptedPaths(w http.ResponseWriter, r *http.Request) {
+	registry, ok := g.acceptedPathRegistry(w)
+	if !ok {
+		return
+	}
+
+	writeSuccessResponse(w, registry.List(r.Context(), r.URL.Query().Get("include_expired") == "true"), nil)
+}
+
+func (g *Gateway) handleGetAcceptedPath(w http.ResponseWriter, r *http.Request) {
+	registry, ok := g.acceptedPathRegistry(w)
+	if !ok {
+		return
+	}
+
+	entry, err := registry.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err, "Failed to get accepted path")
+		return
+	}
+
+	writeSuccessResponse(w, entry, nil)
+}
+
+func (g *Gateway) handleCreateAcceptedPath(w http.ResponseWriter, r *http.Request) {
+	registry, ok := g.acceptedPathRegistry(w)
+	if !ok {
+		return
+	}
+
+	var req AcceptedPathRequest
+	if err := parseRequestBody(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
+		return
+	}
+
+	entry, err := registry.Create(r.Context(), req.entry())
+	if err != nil {
+		writeError(w, err, "Failed to create accepted path")
+		return
+	}
+
+	writeSuccessResponse(w, entry, nil)
+}
+
+func (g *Gateway) handleUpdateAcceptedPath(w http.ResponseWriter, r *http.Request) {
+	registry, ok := g.acceptedPathRegistry(w)
+	if !ok {
+		return
+	}
+
+	var req AcceptedPathRequest
+	if err := parseRequestBody(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
+		return
+	}
+
+	entry, err := registry.Update(r.Context(), mux.Vars(r)["id"], req.entry())
+	if err != nil {
+		writeError(w, err, "Failed to update accepted path")
+		return
+	}
+
+	writeSuccessResponse(w, entry, nil)
+}
+
+func (g *Gateway) handleDeleteAcceptedPath(w http.ResponseWriter, r *http.Request) {
+	registry, ok := g.acceptedPathRegistry(w)
+	if !ok {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := registry.Delete(r.Context(), id); err != nil {
+		writeError(w, err, "Failed to delete accepted path")
+		return
+	}
+
+	writeSuccessResponse(w, map[string]string{"id": id}, nil)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/apperrors"
+)
+
+// APIKeyInfo describes an API key. Keys are stored as the hex SHA-256 hash
+// of the key, never in plaintext.
+type APIKeyInfo struct {
+	ID       string   `json:"id" yaml:"id"`
+	KeyHash  string   `json:"key_hash" yaml:"key_hash"`
+	TenantID string   `json:"tenant_id" yaml:"tenant_id"`
+	Region   string   `json:"region,omitempty" yaml:"region,omitempty"` // data region the tenant is pinned to
+	Scopes   []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Enabled  bool     `json:"enabled" yaml:"enabled"` // disabled keys are rejected, e.g. once revoked
+}
+
+// HasScope reports whether the key grants scope
+func (k *APIKeyInfo) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyStore resolves API keys. Validate returns an unauthorized error for
+// unknown or disabled keys.
+type APIKeyStore interface {
+	Validate(ctx context.Context, key string) (*APIKeyInfo, error)
+}
+
+// HashAPIKey returns the hash an API key is stored as
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryAPIKeyStore is an APIKeyStore holding a fixed set of keys, such as
+// those from the gateway configuration
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys []APIKeyInfo
+}
+
+// NewMemoryAPIKeyStore creates a new in-memory API key store
+func NewMemoryAPIKeyStore(keys []APIKeyInfo) *MemoryAPIKeyStore {
+	store := &MemoryAPIKeyStore{}
+	for _, key := range keys {
+		store.Add(key)
+	}
+	return store
+}
+
+// Add adds a key, replacing any key with the same ID
+func (s *MemoryAPIKeyStore) Add(info APIKeyInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info.KeyHash = strings.ToLower(info.KeyHash)
+	for i := range s.keys {
+		if s.keys[i].ID == info.ID {
+			s.keys[i] = info
+			return
+		}
+	}
+	s.keys = append(s.keys, info)
+}
+
+// Validate implements APIKeyStore. Every stored hash is compared in
+// constant time so lookups do not reveal how close a guess was.
+func (s *MemoryAPIKeyStore) Validate(ctx context.Context, key string) (*APIKeyInfo, error) {
+	hash := []byte(HashAPIKey(key))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found *APIKeyInfo
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(hash, []byte(s.keys[i].KeyHash)) == 1 {
+			info := s.keys[i]
+			found = &info
+		}
+	}
+	if found == nil {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "unknown API key")
+	}
+	if !found.Enabled {
+		return nil, apperrors.New(apperrors.CodeUnauthorized, "API key is disabled")
+	}
+	return found, nil
+}
+
+type apiKeyContextKey struct{}
+
+// WithAPIKey returns a copy of ctx carrying the key a request was made with
+func WithAPIKey(ctx context.Context, info *APIKeyInfo) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, info)
+}
+
+// APIKeyFromContext returns the key the request was authenticated with, if any
+func APIKeyFromContext(ctx context.Context) (*APIKeyInfo, bool) {
+	info, ok := ctx.Value(apiKeyContextKey{}).(*APIKeyInfo)
+	return info, ok
+}
+
+// SetAPIKeyStore replaces the store API keys are validated against. By
+// default keys come from the gateway configuration.
+func (g *Gateway) SetAPIKeyStore(store APIKeyStore) {
+	g.apiKeys = store
+}
+
+// apiKeyAuthMiddleware authenticates requests with an API key. The key and
+// the tenant it belongs to are stored in the request context.
+func (g *Gateway) apiKeyAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unauthenticatedPaths[r.URL.Path] || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := requestAPIKey(r)
+		if key == "" {
+			writeErrorResponse(w, http.StatusUnauthorized, apperrors.CodeUnauthorized, "Missing API key", "")
+			return
+		}
+
+		info, err := g.apiKeys.Validate(r.Context(), key)
+		if err != nil {
+			if apperrors.CodeOf(err) == apperrors.CodeUnauthorized {
+				writeErrorResponse(w, http.StatusUnauthorized, apperrors.CodeUnauthorized, "Invalid API key", apperrors.MessageOf(err))
+				return
+			}
+			writeError(w, err, "Failed to validate API key")
+			return
+		}
+
+		ctx := WithAPIKey(r.Context(), info)
+		if info.TenantID != "" {
+			ctx = tenant.WithTenantContext(ctx, &tenant.TenantContext{TenantID: info.TenantID, Region: info.Region, UserID: info.ID})
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/securizon/internal/tenant"
+)
+
+func TestAPIKeyAuthMiddleware(t *testing.T) {
+	config := DefaultGatewayConfig()
+	config.APIKeys = []APIKeyInfo{
+		// Hashes are matched regardless of case
+		{ID: "ci", KeyHash: strings.ToUpper(HashAPIKey("ci-key")), TenantID: "acme", Region: "eu", Scopes: []string{"assets:read"}, Enabled: true},
+		{ID: "revoked", KeyHash: HashAPIKey("revoked-key"), TenantID: "acme", Enabled: false},
+	}
+	g := NewGateway(config, nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"key header", "X-API-Key", "ci-key", http.StatusOK},
+		{"authorization header", "Authorization", "ApiKey ci-key", http.StatusOK},
+		{"authorization header in lower case", "Authorization", "apikey ci-key", http.StatusOK},
+		{"missing key", "", "", http.StatusUnauthorized},
+		{"unknown key", "X-API-Key", "guessed-key", http.StatusUnauthorized},
+		{"stored hash as key", "X-API-Key", HashAPIKey("ci-key"), http.StatusUnauthorized},
+		{"revoked key", "X-API-Key", "revoked-key", http.StatusUnauthorized},
+		{"bearer scheme", "Authorization", "Bearer ci-key", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info *APIKeyInfo
+			var tenantCtx *tenant.TenantContext
+			handler := g.apiKeyAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				info, _ = APIKeyFromContext(r.Context())
+				tenantCtx, _ = tenant.GetTenantContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/assets", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if info != nil {
+					t.Errorf("rejected request reached the handler with key %s", info.ID)
+				}
+				return
+			}
+
+			if info == nil || info.ID != "ci" || !info.HasScope("assets:read") || info.HasScope("assets:write") {
+				t.Fatalf("key in context = %+v, want ci with only the assets:read scope", info)
+			}
+			if tenantCtx == nil || tenantCtx.TenantID != "acme" || tenantCtx.Region != "eu" || tenantCtx.UserID != "ci" {
+				t.Errorf("tenant context = %+v, want key ci of acme in eu", tenantCtx)
+			}
+		})
+	}
+}
+
+func TestMemoryAPIKeyStoreRevokesReplacedKey(t *testing.T) {
+	store := NewMemoryAPIKeyStore([]APIKeyInfo{{ID: "ci", KeyHash: HashAPIKey("ci-key"), Enabled: true}})
+	if _, err := store.Validate(context.Background(), "ci-key"); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	store.Add(APIKeyInfo{ID: "ci", KeyHash: HashAPIKey("ci-key"), Enabled: false})
+	if _, err := store.Validate(context.Background(), "ci-key"); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("Validate() of a revoked key error = %v, want it disabled", err)
+	}
+}
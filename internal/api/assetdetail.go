@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/securizon/pkg/models"
+)
+
+const (
+	// assetDetailConcurrency bounds the sub-fetches run at once per request
+	assetDetailConcurrency = 3
+	// assetDetailFetchTimeout bounds each sub-fetch so one slow query only
+	// drops its own section
+	assetDetailFetchTimeout = 5 * time.Second
+	assetDetailPathDepth    = 5
+)
+
+// AssetDetail is everything needed to render an asset page. Sections that
+// failed to load are left empty and reported in Errors.
+type AssetDetail struct {
+	Asset         models.Asset          `json:"asset"`
+	Risk          *models.RiskScore     `json:"risk,omitempty"`
+	Findings      []models.Finding      `json:"findings"`
+	Neighbors     []models.Asset        `json:"neighbors"`
+	Relationships []models.Relationship `json:"relationships"`
+	AttackPaths   []models.GraphPath    `json:"attack_paths"`
+	Partial       bool                  `json:"partial"`
+	Errors        map[string]string     `json:"errors,omitempty"`
+}
+
+func (g *Gateway) handleGetAssetDetail(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := vars["id"]
+
+	asset, err := g.graphStore.GetAsset(r.Context(), assetID)
+	if err != nil {
+		writeError(w, err, "Failed to get asset")
+		return
+	}
+
+	detail := &AssetDetail{
+		Asset:         asset,
+		Findings:      []models.Finding{},
+		Neighbors:     []models.Asset{},
+		Relationships: []models.Relationship{},
+		AttackPaths:   []models.GraphPath{},
+	}
+
+	fetches := map[string]func(ctx context.Context) error{
+		"risk": func(ctx context.Context) error {
+			risk, err := g.graphStore.GetAssetRisk(ctx, assetID)
+			if err == nil {
+				detail.Risk = &risk
+			}
+			return err
+		},
+		"findings": func(ctx context.Context) error {
+			findings, err := g.graphStore.GetAssetFindings(ctx, assetID)
+			if err == nil && findings != nil {
+				detail.Findings = findings
+			}
+			return err
+		},
+		"neighbors": func(ctx context.Context) error {
+			neighbors, relationships, err := g.graphStore.GetNeighbors(ctx, assetID, "both", 1)
+			if err == nil {
+				if neighbors != nil {
+					detail.Neighbors = neighbors
+				}
+				if relationships != nil {
+					detail.Relationships = relationships
+				}
+			}
+			return err
+		},
+		"attack_paths": func(ctx context.Context) error {
+			paths, err := g.graphStore.FindAttackPaths(ctx, []string{assetID}, nil, assetDetailPathDepth)
+			if err == nil && paths != nil {
+				detail.AttackPaths = paths
+			}
+			return err
+		},
+	}
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, assetDetailConcurrency)
+	)
+
+	// Each fetch writes only its own section; mu guards the shared error map
+	for section, fetch := range fetches {
+		wg.Add(1)
+		go func(section string, fetch func(ctx context.Context) error) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ctx, cancel := context.WithTimeout(r.Context(), assetDetailFetchTimeout)
+			defer cancel()
+
+			if err := fetch(ctx); err != nil {
+				mu.Lock()
+				if detail.Errors == nil {
+					detail.Errors = make(map[string]string)
+				}
+				detail.Errors[section] = err.Error()
+				detail.Partial = true
+				mu.Unlock()
+			}
+		}(section, fetch)
+	}
+	wg.Wait()
+
+	writeSuccessResponse(w, detail, nil)
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// assetExportChunk is the number of assets read, written and flushed
+// together, and the page size when paging through ListAssets
+const assetExportChunk = 200
+
+// assetExportColumns are the columns of CSV asset exports
+var assetExportColumns = []string{
+	"id", "name", "type", "provider", "environment", "risk_score",
+	"reachable_from_internet", "first_seen", "last_seen",
+}
+
+// handleExportAssets streams the assets matching the list filters as CSV
+// or JSON Lines. Assets are written a chunk at a time as they are read, so
+// the inventory is never held in memory.
+func (g *Gateway) handleExportAssets(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid format", "format must be csv or jsonl")
+		return
+	}
+
+	filter, err := assetFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid asset filter", apperrors.MessageOf(err))
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	csvWriter := csv.NewWriter(w)
+	encoder := json.NewEncoder(w)
+
+	// Headers are sent with the first chunk, so a store that fails
+	// straight away still gets an error response
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+
+		// Exports of large inventories outlive the server's write timeout
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Printf("Failed to clear write deadline for asset export: %v", err)
+		}
+
+		filename := fmt.Sprintf("assets-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+
+		if format == "csv" {
+			return csvWriter.Write(assetExportColumns)
+		}
+		return nil
+	}
+
+	err = g.eachAssetChunk(r.Context(), filter, assetExportChunk, func(assets []models.Asset) error {
+		if err := start(); err != nil {
+			return err
+		}
+
+		if format == "csv" {
+			for _, asset := range assets {
+				if err := csvWriter.Write(assetExportRow(asset)); err != nil {
+					return err
+				}
+			}
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		} else {
+			for _, asset := range assets {
+				if err := encoder.Encode(asset); err != nil {
+					return err
+				}
+			}
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if !started {
+			writeError(w, err, "Failed to export assets")
+			return
+		}
+		// Headers are already sent, so a failure mid-stream can only be logged
+		log.Printf("Failed to stream asset export: %v", err)
+		return
+	}
+
+	// An empty export still has its headers, and its header row for CSV
+	if err := start(); err != nil {
+		log.Printf("Failed to stream asset export: %v", err)
+		return
+	}
+	csvWriter.Flush()
+}
+
+// eachAssetChunk calls fn with the assets matching filter, size at a time.
+// It streams from the store when it supports it and pages through
+// ListAssets otherwise, honoring the filter's limit and offset either way.
+func (g *Gateway) eachAssetChunk(ctx context.Context, filter models.AssetFilter, size int, fn func([]models.Asset) error) error {
+	if streamer, ok := g.graphStore.(GraphStreamer); ok {
+		// Stop the stream if fn fails
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		assets, errs := streamer.StreamAssets(ctx, filter)
+		chunk := make([]models.Asset, 0, size)
+		for asset := range assets {
+			chunk = append(chunk, asset)
+			if len(chunk) < size {
+				continue
+			}
+			if err := fn(chunk); err != nil {
+				return err
+			}
+			chunk = chunk[:0]
+		}
+		if err := <-errs; err != nil {
+			return err
+		}
+		if len(chunk) > 0 {
+			return fn(chunk)
+		}
+		return nil
+	}
+
+	page := filter
+	remaining := filter.Limit
+	for {
+		page.Limit = size
+		if remaining > 0 && remaining < size {
+			page.Limit = remaining
+		}
+
+		assets, err := g.graphStore.ListAssets(ctx, page)
+		if err != nil {
+			return err
+		}
+		if len(assets) > 0 {
+			if err := fn(assets); err != nil {
+				return err
+			}
+		}
+		if len(assets) < page.Limit {
+			return nil
+		}
+
+		page.Offset += len(assets)
+		if remaining > 0 {
+			if remaining -= len(assets); remaining == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// assetExportRow flattens an asset into the CSV export columns. The risk
+// score is the one the store read with the asset; like in JSON, an asset
+// without one has an empty risk_score.
+func assetExportRow(asset models.Asset) []string {
+	base := asset.GetBaseAsset()
+
+	riskScore := ""
+	if base.RiskScore != 0 {
+		riskScore = strconv.FormatFloat(base.RiskScore, 'f', 2, 64)
+	}
+
+	return []string{
+		csvCell(base.ID),
+		csvCell(base.Name),
+		csvCell(string(base.Type)),
+		csvCell(string(base.Provider)),
+		csvCell(string(base.Environment)),
+		riskScore,
+		strconv.FormatBool(base.ReachableFromInternet),
+		exportTime(base.FirstSeen),
+		exportTime(base.LastSeen),
+	}
+}
+
+// csvCell keeps collected values, such as asset names, from being run as
+// formulas when the export is opened in a spreadsheet
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/securizon/pkg/models"
+)
+
+// pagedAssetStore lists a fixed inventory a page at a time. Its embedded
+// GraphStore is nil, so any other store call fails the test.
+type pagedAssetStore struct {
+	GraphStore
+	assets []models.Asset
+	pages  int
+}
+
+func (s *pagedAssetStore) ListAssets(ctx context.Context, filter models.AssetFilter) ([]models.Asset, error) {
+	s.pages++
+	if filter.Offset >= len(s.assets) {
+		return nil, nil
+	}
+	end := filter.Offset + filter.Limit
+	if end > len(s.assets) {
+		end = len(s.assets)
+	}
+	return s.assets[filter.Offset:end], nil
+}
+
+func TestExportAssetsReadsRiskScoresWithAssets(t *testing.T) {
+	store := &pagedAssetStore{}
+	const total = assetExportChunk + 5
+	for i := 0; i < total; i++ {
+		asset := &models.Compute{}
+		asset.ID = fmt.Sprintf("vm-%03d", i)
+		asset.Type = models.AssetTypeCompute
+		if i%2 == 0 {
+			asset.SetRiskScore(42.5)
+		}
+		store.assets = append(store.assets, asset)
+	}
+	g := NewGateway(DefaultGatewayConfig(), store, nil, nil)
+
+	w := httptest.NewRecorder()
+	g.handleExportAssets(w, httptest.NewRequest(http.MethodGet, "/api/v1/assets/export?format=csv", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	if len(rows) != total+1 {
+		t.Fatalf("exported %d rows, want a header and %d assets", len(rows), total)
+	}
+	if store.pages != 2 {
+		t.Errorf("listed %d pages, want 2", store.pages)
+	}
+	for i, row := range rows[1:] {
+		want := ""
+		if i%2 == 0 {
+			want = "42.50"
+		}
+		if row[0] != store.assets[i].GetID() || row[5] != want {
+			t.Errorf("row %d = %v, want %s with risk score %q", i, row, store.assets[i].GetID(), want)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// assetImportMaxRecords caps the assets accepted by one import
+const assetImportMaxRecords = 5000
+
+// AssetBulkWriter is implemented by graph stores that can look up many
+// assets in one query and create or update them in batched writes
+type AssetBulkWriter interface {
+	GetAssets(ctx context.Context, ids []string) (map[string]models.Asset, error)
+	BulkCreateAssets(ctx context.Context, assets []models.Asset) error
+	BulkUpdateAssets(ctx context.Context, assets []models.Asset) error
+}
+
+// Import actions reported per record
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+)
+
+// AssetImportRecord is the outcome of importing one record. Action is what
+// was, or on a dry run would be, done with a valid record.
+type AssetImportRecord struct {
+	Index   int    `json:"index"`
+	ID      string `json:"id,omitempty"`
+	Action  string `json:"action,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AssetImportResponse reports an asset import record by record
+type AssetImportResponse struct {
+	DryRun  bool                `json:"dry_run"`
+	Total   int                 `json:"total"`
+	Created int                 `json:"created"`
+	Updated int                 `json:"updated"`
+	Failed  int                 `json:"failed"`
+	Records []AssetImportRecord `json:"records"`
+}
+
+// handleImportAssets creates or updates the assets in a JSON array or NDJSON
+// body. Each record is validated on its own; invalid records are reported
+// and the valid ones are still written, new assets through BulkCreateAssets
+// and existing ones through BulkUpdateAssets. With dry_run=true nothing is
+// written and the response shows what would have been.
+func (g *Gateway) handleImportAssets(w http.ResponseWriter, r *http.Request) {
+	writer, ok := g.graphStore.(AssetBulkWriter)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Bulk asset import is not supported", "")
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid dry_run", "dry_run must be true or false")
+			return
+		}
+	}
+
+	body := r.Body
+	if g.config.MaxRequestSize > 0 {
+		body = http.MaxBytesReader(w, body, g.config.MaxRequestSize)
+	}
+	defer body.Close()
+
+	raws, err := readImportRecords(body)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse import body", apperrors.MessageOf(err))
+		return
+	}
+
+	resp := AssetImportResponse{DryRun: dryRun, Total: len(raws), Records: make([]AssetImportRecord, len(raws))}
+	assets := make([]models.Asset, len(raws))
+	seen := make(map[string]int, len(raws))
+	for i, raw := range raws {
+		record := &resp.Records[i]
+		record.Index = i
+
+		asset, err := decodeImportAsset(raw)
+		if asset != nil {
+			record.ID = asset.GetID()
+		}
+		if err == nil {
+			if first, ok := seen[record.ID]; ok {
+				err = apperrors.Invalid("duplicate id %q, first seen in record %d", record.ID, first)
+			} else {
+				seen[record.ID] = i
+			}
+		}
+		if err != nil {
+			record.Error = apperrors.MessageOf(err)
+			continue
+		}
+		assets[i] = asset
+	}
+
+	planImport(r.Context(), writer, assets, resp.Records)
+
+	if !dryRun {
+		writeImport(r.Context(), writer, assets, resp.Records)
+	}
+
+	for i := range resp.Records {
+		record := &resp.Records[i]
+		switch {
+		case !record.Success:
+			resp.Failed++
+		case record.Action == ImportActionCreate:
+			resp.Created++
+		case record.Action == ImportActionUpdate:
+			resp.Updated++
+		}
+	}
+
+	writeSuccessResponse(w, resp, nil)
+}
+
+// readImportRecords splits a JSON array or NDJSON body into its records
+func readImportRecords(body io.Reader) ([]json.RawMessage, error) {
+	reader := bufio.NewReader(body)
+	decoder := json.NewDecoder(reader)
+
+	// A body whose first value opens an array is a JSON array; anything
+	// else is read as a stream of objects, one per line
+	var first byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, importReadError(err, 0)
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			first = b
+			break
+		}
+	}
+	reader.UnreadByte()
+	array := first == '['
+	if array {
+		// Consume the opening bracket
+		decoder.Token()
+	}
+
+	var raws []json.RawMessage
+	for {
+		if array && !decoder.More() {
+			break
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF && !array {
+				break
+			}
+			return nil, importReadError(err, len(raws))
+		}
+		if len(raws) == assetImportMaxRecords {
+			return nil, apperrors.Invalid("an import may contain at most %d assets", assetImportMaxRecords)
+		}
+		raws = append(raws, raw)
+	}
+	if array {
+		if _, err := decoder.Token(); err != nil {
+			return nil, importReadError(err, len(raws))
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return nil, apperrors.Invalid("unexpected data after JSON array")
+		}
+	}
+	if len(raws) == 0 {
+		return nil, apperrors.Invalid("body contains no assets")
+	}
+	return raws, nil
+}
+
+// importReadError describes a failure to read record index of an import
+func importReadError(err error, index int) error {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return apperrors.Invalid("body exceeds %d bytes", maxErr.Limit)
+	case err == io.EOF && index == 0:
+		return apperrors.Invalid("body contains no assets")
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return apperrors.Invalid("record %d is incomplete", index)
+	}
+	return apperrors.Invalid("record %d is not valid JSON: %v", index, err)
+}
+
+// decodeImportAsset decodes a record into the asset model for its type and
+// checks its required fields and enums. The asset is returned alongside a
+// validation error so the record can still be reported by id.
+func decodeImportAsset(raw json.RawMessage) (models.Asset, error) {
+	var base models.BaseAsset
+	if err := json.Unmarshal(raw, &base); err != nil {
+		return nil, apperrors.Invalid("invalid asset: %v", err)
+	}
+
+	var asset models.Asset
+	var err error
+	switch base.Type {
+	case models.AssetTypeIdentity:
+		var identity models.Identity
+		err = json.Unmarshal(raw, &identity)
+		// Identity's own type field shadows the asset type when decoding
+		identity.BaseAsset.Type = base.Type
+		asset = &identity
+	case models.AssetTypeCompute:
+		var compute models.Compute
+		err = json.Unmarshal(raw, &compute)
+		asset = &compute
+	case models.AssetTypeNetwork:
+		var network models.Network
+		err = json.Unmarshal(raw, &network)
+		asset = &network
+	case models.AssetTypeData:
+		var data models.Data
+		err = json.Unmarshal(raw, &data)
+		asset = &data
+	case models.AssetTypeSaaS:
+		var saas models.SaaS
+		err = json.Unmarshal(raw, &saas)
+		asset = &saas
+	case "":
+		return nil, apperrors.Invalid("type is required")
+	default:
+		return nil, apperrors.Invalid("unknown asset type %q", base.Type)
+	}
+	if err != nil {
+		return nil, apperrors.Invalid("invalid %s asset: %v", base.Type, err)
+	}
+
+	switch {
+	case base.ID == "":
+		return asset, apperrors.Invalid("id is required")
+	case base.Name == "":
+		return asset, apperrors.Invalid("name is required")
+	case base.Provider == "":
+		return asset, apperrors.Invalid("provider is required")
+	case base.Environment == "":
+		return asset, apperrors.Invalid("environment is required")
+	}
+
+	switch base.Provider {
+	case models.ProviderAWS, models.ProviderAzure, models.ProviderGCP, models.ProviderGitHub, models.ProviderJira:
+	default:
+		return asset, apperrors.Invalid("unknown provider %q", base.Provider)
+	}
+	switch base.Environment {
+	case models.EnvironmentProduction, models.EnvironmentStaging, models.EnvironmentDevelopment, models.EnvironmentTesting:
+	default:
+		return asset, apperrors.Invalid("unknown environment %q", base.Environment)
+	}
+	return asset, nil
+}
+
+// planImport sets the action of each valid asset by whether it is already
+// in the graph, looking all of them up in one query. Every valid record is
+// marked failed if the lookup fails.
+func planImport(ctx context.Context, writer AssetBulkWriter, assets []models.Asset, records []AssetImportRecord) {
+	var ids []string
+	for _, asset := range assets {
+		if asset != nil {
+			ids = append(ids, asset.GetID())
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	existing, err := writer.GetAssets(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to look up %d imported assets: %v", len(ids), err)
+	}
+	for i, asset := range assets {
+		if asset == nil {
+			continue
+		}
+		record := &records[i]
+		stored, ok := existing[record.ID]
+		switch {
+		case err != nil:
+			record.Error = "failed to check for an existing asset"
+		case ok && stored.GetType() != asset.GetType():
+			// Updates match the stored asset by type, so a changed type
+			// would silently update nothing
+			record.Error = fmt.Sprintf("asset exists with type %q", stored.GetType())
+		case ok:
+			record.Action = ImportActionUpdate
+			record.Success = true
+		default:
+			record.Action = ImportActionCreate
+			record.Success = true
+		}
+	}
+}
+
+// writeImport creates and updates the planned assets in bulk, marking the
+// records whose write fails
+func writeImport(ctx context.Context, writer AssetBulkWriter, assets []models.Asset, records []AssetImportRecord) {
+	var creates, updates []models.Asset
+	index := make(map[string]int)
+	for i, asset := range assets {
+		if asset == nil || !records[i].Success {
+			continue
+		}
+		if records[i].Action == ImportActionUpdate {
+			updates = append(updates, asset)
+		} else {
+			creates = append(creates, asset)
+		}
+		index[asset.GetID()] = i
+	}
+
+	if len(creates) > 0 {
+		markImportFailures("create", creates, writer.BulkCreateAssets(ctx, creates), index, records)
+	}
+	if len(updates) > 0 {
+		markImportFailures("update", updates, writer.BulkUpdateAssets(ctx, updates), index, records)
+	}
+}
+
+// markImportFailures marks the records of the assets a bulk write did not
+// store. A bulk write error names them; any other error leaves the outcome
+// of every write unknown.
+func markImportFailures(action string, assets []models.Asset, err error, index map[string]int, records []AssetImportRecord) {
+	if err == nil {
+		return
+	}
+	log.Printf("Failed to %s imported assets: %v", action, err)
+
+	var bulkErr *graph.BulkWriteError
+	failedIDs := make([]string, 0, len(assets))
+	if errors.As(err, &bulkErr) {
+		failedIDs = bulkErr.FailedIDs
+	} else {
+		for _, asset := range assets {
+			failedIDs = append(failedIDs, asset.GetID())
+		}
+	}
+	for _, id := range failedIDs {
+		if i, ok := index[id]; ok {
+			records[i].Success = false
+			records[i].Error = importWriteError(action, err)
+		}
+	}
+}
+
+// importWriteError reports a failed write, with the cause when it is safe
+// to show to the client
+func importWriteError(action string, err error) string {
+	if message := apperrors.MessageOf(err); message != "" {
+		return fmt.Sprintf("failed to %s asset: %s", action, message)
+	}
+	return fmt.Sprintf("failed to %s asset", action)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// bulkImportStore holds stored assets and records the bulk calls made. Its
+// embedded GraphStore is nil, so any per-asset store call fails the test.
+type bulkImportStore struct {
+	GraphStore
+	stored  map[string]models.Asset
+	missing map[string]bool // stored assets BulkUpdateAssets does not find
+
+	lookups int
+	created []string
+	updated []string
+}
+
+func (s *bulkImportStore) GetAssets(ctx context.Context, ids []string) (map[string]models.Asset, error) {
+	s.lookups++
+	assets := make(map[string]models.Asset)
+	for _, id := range ids {
+		if asset, ok := s.stored[id]; ok {
+			assets[id] = asset
+		}
+	}
+	return assets, nil
+}
+
+func (s *bulkImportStore) BulkCreateAssets(ctx context.Context, assets []models.Asset) error {
+	for _, asset := range assets {
+		s.created = append(s.created, asset.GetID())
+	}
+	return nil
+}
+
+func (s *bulkImportStore) BulkUpdateAssets(ctx context.Context, assets []models.Asset) error {
+	bulkErr := &graph.BulkWriteError{Total: len(assets)}
+	for _, asset := range assets {
+		if s.missing[asset.GetID()] {
+			bulkErr.FailedIDs = append(bulkErr.FailedIDs, asset.GetID())
+			bulkErr.Err = apperrors.NotFound("asset not found: %s", asset.GetID())
+			continue
+		}
+		s.updated = append(s.updated, asset.GetID())
+	}
+	if len(bulkErr.FailedIDs) > 0 {
+		return bulkErr
+	}
+	return nil
+}
+
+func storedCompute(id string) models.Asset {
+	asset := &models.Compute{}
+	asset.ID = id
+	asset.Type = models.AssetTypeCompute
+	return asset
+}
+
+func TestImportAssetsWritesInBulk(t *testing.T) {
+	store := &bulkImportStore{
+		stored: map[string]models.Asset{
+			"vm-1": storedCompute("vm-1"),
+			"vm-2": storedCompute("vm-2"),
+			"db-1": storedCompute("db-1"),
+		},
+		missing: map[string]bool{"vm-2": true},
+	}
+	g := NewGateway(DefaultGatewayConfig(), store, nil, nil)
+
+	body := strings.Join([]string{
+		`{"id": "vm-1", "name": "web", "type": "compute", "provider": "aws", "environment": "prod"}`,
+		`{"id": "vm-2", "name": "worker", "type": "compute", "provider": "aws", "environment": "prod"}`,
+		`{"id": "vm-3", "name": "batch", "type": "compute", "provider": "aws", "environment": "prod"}`,
+		`{"id": "db-1", "name": "orders", "type": "data", "provider": "aws", "environment": "prod"}`,
+	}, "\n")
+	w := httptest.NewRecorder()
+	g.handleImportAssets(w, httptest.NewRequest(http.MethodPost, "/api/v1/assets/import", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var envelope struct {
+		Data AssetImportResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	resp := envelope.Data
+
+	if store.lookups != 1 {
+		t.Errorf("looked up existing assets %d times, want once", store.lookups)
+	}
+	if len(store.created) != 1 || store.created[0] != "vm-3" {
+		t.Errorf("created %v, want [vm-3]", store.created)
+	}
+	if len(store.updated) != 1 || store.updated[0] != "vm-1" {
+		t.Errorf("updated %v, want [vm-1]", store.updated)
+	}
+	if resp.Created != 1 || resp.Updated != 1 || resp.Failed != 2 {
+		t.Errorf("created %d, updated %d, failed %d, want 1, 1 and 2", resp.Created, resp.Updated, resp.Failed)
+	}
+	if record := resp.Records[1]; record.Success || !strings.Contains(record.Error, "failed to update asset") {
+		t.Errorf("record of an asset the update missed = %+v, want it failed", record)
+	}
+	if record := resp.Records[3]; record.Success || !strings.Contains(record.Error, "exists with type") {
+		t.Errorf("record changing an asset's type = %+v, want it rejected", record)
+	}
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// AttackPathWatcher finds the attack paths running through changed assets
+// and keeps the paths last computed through each asset
+type AttackPathWatcher interface {
+	FindPathsAffectedByAsset(ctx context.Context, assetID string) ([]graph.AffectedPath, error)
+	RecomputeAffectedPaths(ctx context.Context, assetIDs []string) error
+	AffectedPaths(assetID string) ([]graph.AffectedPath, bool)
+}
+
+// SetAttackPathWatcher enables live attack path updates over WebSocket
+func (g *Gateway) SetAttackPathWatcher(watcher AttackPathWatcher) {
+	g.attackPathWatcher = watcher
+}
+
+// attackPathTopics carry the relationship and finding changes that can
+// alter attack paths
+var attackPathTopics = []string{events.TopicAssetRelationships, events.TopicFindings}
+
+// attackPathWriteTimeout bounds each write to a client
+const attackPathWriteTimeout = 10 * time.Second
+
+// AttackPathUpdate is sent to WebSocket clients with the attack paths
+// currently running through their target asset
+type AttackPathUpdate struct {
+	Type      string               `json:"type"` // attack_paths or error
+	TargetID  string               `json:"target_id"`
+	Paths     []graph.AffectedPath `json:"paths"`
+	Error     string               `json:"error,omitempty"`
+	Timestamp time.Time            `json:"timestamp"`
+}
+
+// handleAttackPathUpdates streams the attack paths through a target asset
+// to a WebSocket client. The paths are sent on connect and again whenever
+// a relationship or finding change touches an asset on them, with changes
+// arriving within AttackPathDebounce of each other sent as one update.
+// Clients share the gateway's subscriptions; a client that falls behind is
+// disconnected and can reconnect.
+func (g *Gateway) handleAttackPathUpdates(w http.ResponseWriter, r *http.Request) {
+	if g.attackPathWatcher == nil {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Attack path updates are not available", "")
+		return
+	}
+	subscriber, ok := g.eventBus.(EventSubscriber)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Event streaming is not available", "")
+		return
+	}
+	targetID := r.URL.Query().Get("target_id")
+	if targetID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "target_id is required", "")
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: g.allowedOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied to the client
+		log.Printf("Failed to upgrade attack path connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Replace any read deadline the server left on the connection with one
+	// the client's pongs to the keep-alive pings extend, so idle clients
+	// stay connected and ones that miss two pings in a row are dropped
+	pongWait := 2 * g.config.StreamKeepAlive
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	// Reading handles control frames and notices the client going away;
+	// messages from the client are ignored
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	client, err := g.streams.join(subscriber, attackPathTopics)
+	if err != nil {
+		log.Printf("Failed to subscribe to attack path changes: %v", err)
+		writeAttackPathUpdate(conn, AttackPathUpdate{Type: "error", TargetID: targetID, Error: "failed to subscribe to changes"})
+		return
+	}
+	defer g.streams.leave(client)
+
+	watch := &attackPathWatch{watcher: g.attackPathWatcher, targetID: targetID}
+	if !watch.send(ctx, conn, true) {
+		return
+	}
+
+	keepAlive := time.NewTicker(g.config.StreamKeepAlive)
+	defer keepAlive.Stop()
+
+	var (
+		pending  = make(map[string]bool)
+		debounce *time.Timer
+		flush    <-chan time.Time
+	)
+	defer func() {
+		if debounce != nil {
+			debounce.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.lagged:
+			return
+		case <-keepAlive.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(attackPathWriteTimeout)); err != nil {
+				return
+			}
+		case event := <-client.events:
+			assetIDs := changedAssets(event)
+			if len(assetIDs) == 0 {
+				continue
+			}
+			for _, id := range assetIDs {
+				pending[id] = true
+			}
+			// The first change of a burst starts the window; later ones
+			// join it rather than postponing the update
+			if flush == nil {
+				debounce = time.NewTimer(g.config.AttackPathDebounce)
+				flush = debounce.C
+			}
+		case <-flush:
+			flush = nil
+			changed := pending
+			pending = make(map[string]bool)
+			if watch.affectedBy(ctx, changed) && !watch.send(ctx, conn, false) {
+				return
+			}
+		}
+	}
+}
+
+// allowedOrigin accepts WebSocket connections from the CORS origins
+func (g *Gateway) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range g.config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedAssets returns the assets a relationship or finding event touches
+func changedAssets(event models.BaseEvent) []string {
+	var payload struct {
+		AssetID      string `json:"asset_id"`
+		Relationship struct {
+			FromAssetID string `json:"from_asset_id"`
+			ToAssetID   string `json:"to_asset_id"`
+		} `json:"relationship"`
+		Finding struct {
+			AssetID string `json:"asset_id"`
+		} `json:"finding"`
+	}
+	if event.RawData != nil {
+		// Payloads that fail to decode are matched on the envelope alone
+		json.Unmarshal(event.RawData, &payload)
+	}
+
+	var assetIDs []string
+	for _, id := range []string{event.AssetID, payload.AssetID, payload.Relationship.FromAssetID, payload.Relationship.ToAssetID, payload.Finding.AssetID} {
+		if id != "" && !containsString(assetIDs, id) {
+			assetIDs = append(assetIDs, id)
+		}
+	}
+	return assetIDs
+}
+
+// attackPathWatch tracks the paths last sent for one target
+type attackPathWatch struct {
+	watcher  AttackPathWatcher
+	targetID string
+	nodes    map[string]bool // assets on the paths last sent
+	sent     string          // nodes and risk of the paths last sent
+}
+
+// affectedBy reports whether changes to assetIDs can alter the target's
+// paths: the target or an asset on its paths changed, or the paths through
+// a changed asset now reach the target
+func (w *attackPathWatch) affectedBy(ctx context.Context, assetIDs map[string]bool) bool {
+	var unknown []string
+	for id := range assetIDs {
+		if id == w.targetID || w.nodes[id] {
+			return true
+		}
+		unknown = append(unknown, id)
+	}
+
+	for _, id := range unknown {
+		paths, err := w.watcher.FindPathsAffectedByAsset(ctx, id)
+		if err != nil {
+			log.Printf("Failed to find attack paths affected by asset %s: %v", id, err)
+			continue
+		}
+		for _, path := range paths {
+			if containsString(path.NodeIDs, w.targetID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// send recomputes the target's paths and writes them to the client if they
+// changed since the last update, or unconditionally when force is set. It
+// returns false once the client can no longer be written to.
+func (w *attackPathWatch) send(ctx context.Context, conn *websocket.Conn, force bool) bool {
+	update := AttackPathUpdate{Type: "attack_paths", TargetID: w.targetID}
+	if err := w.watcher.RecomputeAffectedPaths(ctx, []string{w.targetID}); err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		log.Printf("Failed to recompute attack paths for asset %s: %v", w.targetID, err)
+		update.Type = "error"
+		update.Error = "failed to recompute attack paths"
+		return writeAttackPathUpdate(conn, update) == nil
+	}
+
+	paths, _ := w.watcher.AffectedPaths(w.targetID)
+	if paths == nil {
+		paths = []graph.AffectedPath{}
+	}
+
+	keys := make([]string, len(paths))
+	nodes := make(map[string]bool)
+	for i, path := range paths {
+		keys[i] = fmt.Sprintf("%s@%.2f", strings.Join(path.NodeIDs, ","), path.PathRisk)
+		for _, id := range path.NodeIDs {
+			nodes[id] = true
+		}
+	}
+	key := strings.Join(keys, ";")
+	if !force && key == w.sent {
+		return true
+	}
+	w.nodes, w.sent = nodes, key
+
+	update.Paths = paths
+	return writeAttackPathUpdate(conn, update) == nil
+}
+
+func writeAttackPathUpdate(conn *websocket.Conn, update AttackPathUpdate) error {
+	update.Timestamp = time.Now()
+	conn.SetWriteDeadline(time.Now().Add(attackPathWriteTimeout))
+	return conn.WriteJSON(update)
+}
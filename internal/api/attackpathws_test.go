@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/pkg/models"
+)
+
+// streamBus is an event bus delivering the events published in a test
+type streamBus struct {
+	EventBus
+	*topicSubscriber
+}
+
+// riskyPathWatcher reports one path to its target whose risk rises on every
+// recompute, so each recompute is a change worth sending
+type riskyPathWatcher struct {
+	mu       sync.Mutex
+	computed int
+}
+
+func (w *riskyPathWatcher) FindPathsAffectedByAsset(ctx context.Context, assetID string) ([]graph.AffectedPath, error) {
+	return nil, nil
+}
+
+func (w *riskyPathWatcher) RecomputeAffectedPaths(ctx context.Context, assetIDs []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.computed++
+	return nil
+}
+
+func (w *riskyPathWatcher) AffectedPaths(assetID string) ([]graph.AffectedPath, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return []graph.AffectedPath{{NodeIDs: []string{"lb", assetID}, PathRisk: float64(w.computed)}}, true
+}
+
+func TestAttackPathSocketOutlivesServerReadTimeout(t *testing.T) {
+	config := DefaultGatewayConfig()
+	config.StreamKeepAlive = 50 * time.Millisecond
+	config.AttackPathDebounce = 10 * time.Millisecond
+	bus := &streamBus{topicSubscriber: &topicSubscriber{}}
+	g := NewGateway(config, nil, nil, bus)
+	g.SetAttackPathWatcher(&riskyPathWatcher{})
+	defer g.streams.close()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(g.handleAttackPathUpdates))
+	server.Config.ReadTimeout = 200 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?target_id=db"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	// Reading answers the server's keep-alive pings
+	updates := make(chan AttackPathUpdate)
+	go func() {
+		defer close(updates)
+		for {
+			var update AttackPathUpdate
+			if err := conn.ReadJSON(&update); err != nil {
+				return
+			}
+			updates <- update
+		}
+	}()
+
+	if update, ok := <-updates; !ok || update.Type != "attack_paths" {
+		t.Fatalf("first update = %+v, want the current attack paths", update)
+	}
+
+	// Stay idle well past the server's read timeout, then change the target
+	time.Sleep(3 * server.Config.ReadTimeout)
+	bus.publish(t, events.TopicAssetRelationships, models.BaseEvent{ID: "change-1", AssetID: "db"})
+
+	select {
+	case update, ok := <-updates:
+		if !ok {
+			t.Fatal("the connection closed while idle")
+		}
+		if update.Type != "attack_paths" {
+			t.Errorf("update = %+v, want new attack paths", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update after the target changed")
+	}
+}
+
+func TestAttackPathSocketDropsClientsThatStopAnswering(t *testing.T) {
+	config := DefaultGatewayConfig()
+	config.StreamKeepAlive = 50 * time.Millisecond
+	bus := &streamBus{topicSubscriber: &topicSubscriber{}}
+	g := NewGateway(config, nil, nil, bus)
+	g.SetAttackPathWatcher(&riskyPathWatcher{})
+	defer g.streams.close()
+
+	server := httptest.NewServer(http.HandlerFunc(g.handleAttackPathUpdates))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?target_id=db"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	var update AttackPathUpdate
+	if err := conn.ReadJSON(&update); err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+
+	// The client stops reading, so the server's pings go unanswered
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.streams.mu.Lock()
+		clients := len(g.streams.clients[events.TopicAssetRelationships])
+		g.streams.mu.Unlock()
+		if clients == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("a client that stopped answering pings was never dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
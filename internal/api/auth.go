@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/apperrors"
+)
+
+// jwtHashes are the HMAC signing algorithms tokens can be verified with.
+// Tokens signed with anything else, including "none", are rejected.
+var jwtHashes = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// SupportedJWTAlgorithm reports whether alg can be allowed for JWT auth
+func SupportedJWTAlgorithm(alg string) bool {
+	_, ok := jwtHashes[alg]
+	return ok
+}
+
+// unauthenticatedPaths are served without credentials so probes keep
+// working when auth is enabled
+var unauthenticatedPaths = map[string]bool{
+	"/api/v1/health": true,
+}
+
+// Claims are the claims of an authenticated request
+type Claims struct {
+	Subject   string    `json:"sub"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Region    string    `json:"region,omitempty"` // data region the tenant is pinned to
+	Roles     []string  `json:"roles,omitempty"`
+	IssuedAt  time.Time `json:"iat,omitempty"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// HasRole reports whether the claims grant role
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of ctx carrying claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the authenticated request, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok
+}
+
+// jwtPayload is the wire form of the claims a token is checked against
+type jwtPayload struct {
+	Subject   string   `json:"sub"`
+	TenantID  string   `json:"tenant_id"`
+	Region    string   `json:"region"`
+	Roles     []string `json:"roles"`
+	IssuedAt  *float64 `json:"iat"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// parseJWT verifies a compact HMAC-signed token and returns its claims.
+// Tokens must carry an expiry and be signed with one of the allowed
+// algorithms; the token's own header is never trusted to pick one.
+func parseJWT(token string, secret []byte, allowed []string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	if !containsString(allowed, header.Alg) {
+		return nil, fmt.Errorf("signing algorithm %q is not allowed", header.Alg)
+	}
+	newHash, ok := jwtHashes[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("signing algorithm %q is not supported", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("invalid token signature")
+	}
+	mac := hmac.New(newHash, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("invalid token signature")
+	}
+
+	var payload jwtPayload
+	if err := decodeJWTSegment(parts[1], &payload); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	if payload.ExpiresAt == nil {
+		return nil, errors.New("token has no expiry")
+	}
+
+	claims := &Claims{
+		Subject:   payload.Subject,
+		TenantID:  payload.TenantID,
+		Region:    payload.Region,
+		Roles:     payload.Roles,
+		ExpiresAt: numericDate(*payload.ExpiresAt),
+	}
+	if payload.IssuedAt != nil {
+		claims.IssuedAt = numericDate(*payload.IssuedAt)
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return nil, errors.New("token has expired")
+	}
+	if payload.NotBefore != nil && now.Before(numericDate(*payload.NotBefore)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return claims, nil
+}
+
+// decodeJWTSegment decodes a base64url JSON segment of a token into v
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts a JWT NumericDate, in seconds since the epoch
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+// jwtAuthMiddleware authenticates requests with a bearer JWT signed with the
+// configured secret. The claims are stored in the request context, and a
+// tenant context is derived from them for handlers that scope by tenant.
+// Without a secret every authenticated request is rejected, since any token
+// signed with an empty key would otherwise verify.
+func (g *Gateway) jwtAuthMiddleware(next http.Handler) http.Handler {
+	secret := []byte(g.config.JWTSecret)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unauthenticatedPaths[r.URL.Path] || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(secret) == 0 {
+			writeErrorResponse(w, http.StatusServiceUnavailable, apperrors.CodeUnavailable, "JWT authentication is not configured", "")
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="securizon"`)
+			writeErrorResponse(w, http.StatusUnauthorized, apperrors.CodeUnauthorized, "Missing bearer token", "")
+			return
+		}
+
+		claims, err := parseJWT(token, secret, g.config.JWTAlgorithms, time.Now())
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="securizon", error="invalid_token"`)
+			writeErrorResponse(w, http.StatusUnauthorized, apperrors.CodeUnauthorized, "Invalid bearer token", err.Error())
+			return
+		}
+
+		ctx := WithClaims(r.Context(), claims)
+		if claims.TenantID != "" {
+			tenantCtx := &tenant.TenantContext{TenantID: claims.TenantID, Region: claims.Region, UserID: claims.Subject}
+			if len(claims.Roles) > 0 {
+				tenantCtx.UserRole = claims.Roles[0]
+			}
+			ctx = tenant.WithTenantContext(ctx, tenantCtx)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/securizon/internal/tenant"
+)
+
+const testJWTSecret = "test-secret"
+
+// signJWT builds a compact token with the given header and claims, signed
+// with HS256 under secret. An empty secret leaves the token unsigned.
+func signJWT(t *testing.T, header, claims map[string]interface{}, secret string) string {
+	t.Helper()
+
+	segment := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode token segment: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	unsigned := segment(header) + "." + segment(claims)
+	if secret == "" {
+		return unsigned + "."
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWTAuthMiddleware(t *testing.T) {
+	now := time.Now()
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	valid := map[string]interface{}{
+		"sub":       "alice",
+		"tenant_id": "acme",
+		"region":    "eu",
+		"roles":     []string{"analyst"},
+		"exp":       now.Add(time.Hour).Unix(),
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"valid token", "Bearer " + signJWT(t, hs256, valid, testJWTSecret), http.StatusOK},
+		{"missing token", "", http.StatusUnauthorized},
+		{"other scheme", "Basic " + signJWT(t, hs256, valid, testJWTSecret), http.StatusUnauthorized},
+		{"wrong secret", "Bearer " + signJWT(t, hs256, valid, "other-secret"), http.StatusUnauthorized},
+		{"tampered signature", "Bearer " + signJWT(t, hs256, valid, testJWTSecret) + "x", http.StatusUnauthorized},
+		{"malformed token", "Bearer not-a-token", http.StatusUnauthorized},
+		{"expired token", "Bearer " + signJWT(t, hs256, map[string]interface{}{
+			"sub": "alice",
+			"exp": now.Add(-time.Minute).Unix(),
+		}, testJWTSecret), http.StatusUnauthorized},
+		{"no expiry", "Bearer " + signJWT(t, hs256, map[string]interface{}{"sub": "alice"}, testJWTSecret), http.StatusUnauthorized},
+		{"not valid yet", "Bearer " + signJWT(t, hs256, map[string]interface{}{
+			"sub": "alice",
+			"nbf": now.Add(time.Hour).Unix(),
+			"exp": now.Add(2 * time.Hour).Unix(),
+		}, testJWTSecret), http.StatusUnauthorized},
+		{"disallowed algorithm", "Bearer " + signJWT(t, map[string]interface{}{"alg": "HS512"}, valid, testJWTSecret), http.StatusUnauthorized},
+		{"none algorithm", "Bearer " + signJWT(t, map[string]interface{}{"alg": "none"}, valid, ""), http.StatusUnauthorized},
+	}
+
+	config := DefaultGatewayConfig()
+	config.JWTSecret = testJWTSecret
+	g := NewGateway(config, nil, nil, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims *Claims
+			var tenantCtx *tenant.TenantContext
+			handler := g.jwtAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				claims, _ = ClaimsFromContext(r.Context())
+				tenantCtx, _ = tenant.GetTenantContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/assets", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if claims != nil {
+					t.Errorf("rejected request reached the handler with claims %+v", claims)
+				}
+				if w.Header().Get("WWW-Authenticate") == "" {
+					t.Error("rejected request has no WWW-Authenticate header")
+				}
+				return
+			}
+
+			if claims == nil || claims.Subject != "alice" || !claims.HasRole("analyst") {
+				t.Fatalf("claims in context = %+v, want alice with the analyst role", claims)
+			}
+			if tenantCtx == nil || tenantCtx.TenantID != "acme" || tenantCtx.Region != "eu" || tenantCtx.UserID != "alice" || tenantCtx.UserRole != "analyst" {
+				t.Errorf("tenant context = %+v, want alice of acme in eu", tenantCtx)
+			}
+		})
+	}
+}
+
+func TestJWTAuthMiddlewareWithoutSecret(t *testing.T) {
+	g := NewGateway(DefaultGatewayConfig(), nil, nil, nil)
+	handler := g.jwtAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the handler without a configured secret")
+	}))
+
+	// A token signed with an empty key must not verify
+	token := signJWT(t, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}, "")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/assets", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	w = httptest.NewRecorder()
+	g.jwtAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("health status = %d, want it served without credentials", w.Code)
+	}
+}
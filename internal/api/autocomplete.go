@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/pkg/apperrors"
+)
+
+// Autocomplete result limits
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 25
+)
+
+// AssetAutocompleter is implemented by graph stores that can suggest assets
+// by name or id prefix
+type AssetAutocompleter interface {
+	AutocompleteAssets(ctx context.Context, prefix string, limit int) ([]graph.AssetSuggestion, error)
+}
+
+// handleAutocompleteAssets returns a short ranked list of assets matching
+// the q prefix for search-as-you-type
+func (g *Gateway) handleAutocompleteAssets(w http.ResponseWriter, r *http.Request) {
+	completer, ok := g.graphStore.(AssetAutocompleter)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Asset autocomplete is not supported", "")
+		return
+	}
+
+	prefix := strings.TrimSpace(r.URL.Query().Get("q"))
+	if prefix == "" {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Missing query", "q is required")
+		return
+	}
+
+	limit := defaultAutocompleteLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > maxAutocompleteLimit {
+		limit = maxAutocompleteLimit
+	}
+
+	suggestions, err := completer.AutocompleteAssets(r.Context(), prefix, limit)
+	if err != nil {
+		writeError(w, err, "Failed to autocomplete assets")
+		return
+	}
+
+	writeSuccessResponse(w, suggestions, nil)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/securizon/internal/events"
+	"github.com/securizon/pkg/models"
+)
+
+// eventBroadcaster shares one event bus subscription per topic among the
+// clients streaming it, so client connections never subscribe to the bus
+// themselves. A topic is subscribed when its first client joins and stays
+// subscribed until the gateway stops.
+type eventBroadcaster struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	subscribed map[string]bool
+	clients    map[string]map[*eventClient]bool // by topic
+}
+
+// eventClient receives the events of the topics it joined. A client that
+// falls riskStreamBuffer events behind is dropped: lagged is closed and no
+// more events are sent to it.
+type eventClient struct {
+	events chan models.BaseEvent
+	lagged chan struct{}
+	topics []string
+}
+
+func newEventBroadcaster() *eventBroadcaster {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &eventBroadcaster{
+		ctx:        ctx,
+		cancel:     cancel,
+		subscribed: make(map[string]bool),
+		clients:    make(map[string]map[*eventClient]bool),
+	}
+}
+
+// join registers a client for the events of topics, subscribing to the
+// topics that are not subscribed yet
+func (b *eventBroadcaster) join(subscriber EventSubscriber, topics []string) (*eventClient, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, topic := range topics {
+		if b.subscribed[topic] {
+			continue
+		}
+		topic := topic
+		handler := events.NamedHandler("broadcast_"+topic, func(ctx context.Context, event models.BaseEvent) error {
+			b.broadcast(topic, event)
+			return nil
+		})
+		if err := subscriber.Subscribe(b.ctx, topic, handler); err != nil {
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+		}
+		b.subscribed[topic] = true
+	}
+
+	client := &eventClient{
+		events: make(chan models.BaseEvent, riskStreamBuffer),
+		lagged: make(chan struct{}),
+		topics: topics,
+	}
+	for _, topic := range topics {
+		if b.clients[topic] == nil {
+			b.clients[topic] = make(map[*eventClient]bool)
+		}
+		b.clients[topic][client] = true
+	}
+	return client, nil
+}
+
+// leave unregisters a client
+func (b *eventBroadcaster) leave(client *eventClient) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(client)
+}
+
+// remove unregisters a client; b.mu must be held
+func (b *eventBroadcaster) remove(client *eventClient) {
+	for _, topic := range client.topics {
+		delete(b.clients[topic], client)
+	}
+}
+
+// broadcast sends an event to every client of its topic. Events are never
+// held for a slow client, so one client cannot stall the others.
+func (b *eventBroadcaster) broadcast(topic string, event models.BaseEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for client := range b.clients[topic] {
+		select {
+		case client.events <- event:
+		default:
+			log.Printf("Dropping event stream client %d events behind on %s", riskStreamBuffer, topic)
+			b.remove(client)
+			close(client.lagged)
+		}
+	}
+}
+
+// close ends the shared subscriptions
+func (b *eventBroadcaster) close() {
+	b.cancel()
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/securizon/internal/events"
+	"github.com/securizon/pkg/models"
+)
+
+// topicSubscriber records the handlers subscribed to each topic
+type topicSubscriber struct {
+	mu       sync.Mutex
+	handlers map[string][]events.EventHandler
+}
+
+func (s *topicSubscriber) Subscribe(ctx context.Context, topic string, handler events.EventHandler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handlers == nil {
+		s.handlers = make(map[string][]events.EventHandler)
+	}
+	s.handlers[topic] = append(s.handlers[topic], handler)
+	return nil
+}
+
+func (s *topicSubscriber) publish(t *testing.T, topic string, event models.BaseEvent) {
+	s.mu.Lock()
+	handlers := s.handlers[topic]
+	s.mu.Unlock()
+	for _, handler := range handlers {
+		if err := handler.Handle(context.Background(), event); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+}
+
+func TestBroadcasterSharesOneSubscriptionPerTopic(t *testing.T) {
+	subscriber := &topicSubscriber{}
+	b := newEventBroadcaster()
+	defer b.close()
+
+	const clients = 20
+	joined := make([]*eventClient, clients)
+	var wg sync.WaitGroup
+	for i := range joined {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := b.join(subscriber, riskStreamTopics)
+			if err != nil {
+				t.Errorf("join() error = %v", err)
+				return
+			}
+			joined[i] = client
+		}(i)
+	}
+	wg.Wait()
+
+	for _, topic := range riskStreamTopics {
+		if n := len(subscriber.handlers[topic]); n != 1 {
+			t.Errorf("%d subscriptions to %s, want 1", n, topic)
+		}
+	}
+
+	subscriber.publish(t, events.TopicRiskScores, models.BaseEvent{ID: "event-1"})
+	for i, client := range joined {
+		if event := <-client.events; event.ID != "event-1" {
+			t.Errorf("client %d received %s, want event-1", i, event.ID)
+		}
+	}
+
+	b.leave(joined[0])
+	subscriber.publish(t, events.TopicFindings, models.BaseEvent{ID: "event-2"})
+	if len(joined[0].events) != 0 {
+		t.Error("a client that left still received events")
+	}
+	if len(joined[1].events) != 1 {
+		t.Error("a remaining client did not receive the event")
+	}
+}
+
+func TestBroadcasterDropsClientsThatFallBehind(t *testing.T) {
+	subscriber := &topicSubscriber{}
+	b := newEventBroadcaster()
+	defer b.close()
+
+	slow, err := b.join(subscriber, riskStreamTopics)
+	if err != nil {
+		t.Fatalf("join() error = %v", err)
+	}
+	fast, err := b.join(subscriber, riskStreamTopics)
+	if err != nil {
+		t.Fatalf("join() error = %v", err)
+	}
+
+	for i := 0; i <= riskStreamBuffer; i++ {
+		subscriber.publish(t, events.TopicRiskScores, models.BaseEvent{ID: "event"})
+		// Only the fast client keeps up
+		<-fast.events
+	}
+
+	select {
+	case <-slow.lagged:
+	default:
+		t.Fatal("a client that fell behind was not dropped")
+	}
+	select {
+	case <-fast.lagged:
+		t.Fatal("a client that kept up was dropped")
+	default:
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/pkg/apperrors"
+)
+
+// FeatureExporter is implemented by graph stores that can export node and
+// edge feature tables for graph ML
+type FeatureExporter interface {
+	ExportFeatures(ctx context.Context) (*graph.FeatureSet, error)
+}
+
+// handleExportFeatures exports the node or edge feature table as CSV
+// (default) or JSON
+func (g *Gateway) handleExportFeatures(w http.ResponseWriter, r *http.Request) {
+	exporter, ok := g.graphStore.(FeatureExporter)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Feature export is not supported", "")
+		return
+	}
+
+	query := r.URL.Query()
+	table := query.Get("table")
+	if table == "" {
+		table = "nodes"
+	}
+	if table != "nodes" && table != "edges" {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid table", "table must be nodes or edges")
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid format", "format must be csv or json")
+		return
+	}
+
+	features, err := exporter.ExportFeatures(r.Context())
+	if err != nil {
+		writeError(w, err, "Failed to export features")
+		return
+	}
+
+	if format == "json" {
+		if table == "nodes" {
+			writeSuccessResponse(w, features.Nodes, nil)
+		} else {
+			writeSuccessResponse(w, features.Edges, nil)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+table+`.csv"`)
+	write := features.WriteNodesCSV
+	if table == "edges" {
+		write = features.WriteEdgesCSV
+	}
+	if err := write(w); err != nil {
+		log.Printf("Failed to write %s feature table: %v", table, err)
+	}
+}
@@ -1,15 +1,23 @@
 package api
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
+	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/internal/risk"
+	"github.com/securizon/pkg/apperrors"
 	"github.com/securizon/pkg/models"
 )
 
@@ -23,6 +31,34 @@ type Gateway struct {
 	config          GatewayConfig
 	middleware      []Middleware
 	metrics         *GatewayMetrics
+	attackPaths     AttackPathFinder
+	policyCatalog   PolicyCatalog
+	quotas          *quotaEnforcer
+	rateLimiter     *rateLimiter
+	apiKeys         APIKeyStore
+	processorMetrics ProcessorMetricsSource
+	recalcJobs      *recalcJobs
+	streams         *eventBroadcaster
+	eventHandlers   EventHandlerRegistry
+	acceptedPaths   AcceptedPathRegistry
+	attackPathWatcher AttackPathWatcher
+	llmSpend        LLMSpendReporter
+}
+
+// PolicyCatalog exposes the policy category taxonomy
+type PolicyCatalog interface {
+	Categories() []models.PolicyCategory
+}
+
+// EventHandlerRegistry reports the handlers registered with the event
+// processor and how they have fared
+type EventHandlerRegistry interface {
+	Handlers() []events.EventTypeHandlers
+}
+
+// AttackPathFinder discovers attack paths between user-defined node selectors
+type AttackPathFinder interface {
+	FindPathsMatching(ctx context.Context, entryPoints, targets []graph.NodeSelector, maxHops int) ([]graph.AttackPath, error)
 }
 
 // GraphStore interface for graph operations
@@ -45,19 +81,66 @@ type GraphStore interface {
 	GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error)
 	UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error
 	GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error)
+	GetFinding(ctx context.Context, id string) (models.Finding, error)
+	ListFindings(ctx context.Context, filter models.FindingFilter) ([]models.Finding, error)
 	CreateFinding(ctx context.Context, finding models.Finding) error
 	UpdateFinding(ctx context.Context, finding models.Finding) error
+	ResolveFinding(ctx context.Context, findingID string) (models.Finding, error)
 	GetRiskSummary(ctx context.Context, filter models.AssetFilter) (*models.RiskSummary, error)
 	GetRiskTrends(ctx context.Context, assetID string, timeRange models.TimeRange) (*models.RiskTrend, error)
 }
 
+// AssetUpserter is implemented by graph stores that can create-or-update assets
+type AssetUpserter interface {
+	UpsertAsset(ctx context.Context, asset models.Asset) (bool, error)
+}
+
+// GraphSnapshotter is implemented by graph stores that support backup and restore
+type GraphSnapshotter interface {
+	ExportGraph(ctx context.Context, tenant string) (io.Reader, error)
+	ImportGraph(ctx context.Context, tenant string, r io.Reader, labels []string) (*graph.ImportResult, error)
+}
+
+// FindingGrouper is implemented by graph stores that correlate findings
+type FindingGrouper interface {
+	GetFindingGroups(ctx context.Context, status string, minSize int) ([]models.FindingGroup, error)
+}
+
+// FindingFeedbackStore is implemented by graph stores that record analyst
+// feedback on findings
+type FindingFeedbackStore interface {
+	RecordFindingFeedback(ctx context.Context, feedback models.FindingFeedback) (models.Finding, error)
+	GetPolicyAccuracy(ctx context.Context, policyID string) (*models.PolicyAccuracy, error)
+}
+
+// GraphStreamer is implemented by graph stores that can stream large listings
+type GraphStreamer interface {
+	StreamAssets(ctx context.Context, filter models.AssetFilter) (<-chan models.Asset, <-chan error)
+	StreamRelationships(ctx context.Context, filter models.RelationshipFilter) (<-chan models.Relationship, <-chan error)
+}
+
+// GraphCounter is implemented by graph stores that can count list results,
+// letting list responses report a total
+type GraphCounter interface {
+	CountAssets(ctx context.Context, filter models.AssetFilter) (int, error)
+	CountRelationships(ctx context.Context, filter models.RelationshipFilter) (int, error)
+}
+
+// AssetBatchLoader is implemented by graph stores that can load the findings
+// and risk of several assets in one query
+type AssetBatchLoader interface {
+	GetFindingsForAssets(ctx context.Context, assetIDs []string) (map[string][]models.Finding, error)
+	GetAssetRisks(ctx context.Context, assetIDs []string) (map[string]models.RiskScore, error)
+}
+
 // RiskEngine interface for risk operations
 type RiskEngine interface {
 	CalculateRisk(ctx context.Context, asset models.Asset, findings []models.Finding, threats []models.ThreatEvent) (models.RiskScore, error)
 	RecalculateRisk(ctx context.Context, assetID string) (models.RiskScore, error)
 	UpdateRiskScore(ctx context.Context, assetID string, score models.RiskScore) error
-	BatchRecalculateRisk(ctx context.Context, assetIDs []string) ([]models.RiskScore, error)
-	GetMetrics() interface{}
+	BatchRecalculateRisk(ctx context.Context, assetIDs []string) (risk.BatchRiskResult, error)
+	ExplainRisk(ctx context.Context, assetID string) (*risk.RiskExplanation, error)
+	GetMetrics() risk.EngineMetricsSnapshot
 	GetRiskSummary(ctx context.Context) (*models.RiskSummary, error)
 }
 
@@ -70,25 +153,36 @@ type EventBus interface {
 
 // GatewayConfig represents gateway configuration
 type GatewayConfig struct {
-	Host              string        `json:"host"`
-	Port              int           `json:"port"`
-	ReadTimeout       time.Duration `json:"read_timeout"`
-	WriteTimeout      time.Duration `json:"write_timeout"`
-	IdleTimeout       time.Duration `json:"idle_timeout"`
-	EnableCORS        bool          `json:"enable_cors"`
-	AllowedOrigins    []string      `json:"allowed_origins"`
-	AllowedMethods    []string      `json:"allowed_methods"`
-	AllowedHeaders    []string      `json:"allowed_headers"`
-	EnableAuth        bool          `json:"enable_auth"`
-	AuthType          string        `json:"auth_type"` // jwt, oauth2, apikey
-	JWTSecret         string        `json:"jwt_secret"`
-	EnableMetrics     bool          `json:"enable_metrics"`
-	EnablePprof       bool          `json:"enable_pprof"`
-	EnableSwagger     bool          `json:"enable_swagger"`
-	RateLimitEnabled  bool          `json:"rate_limit_enabled"`
-	RateLimitRPS      int           `json:"rate_limit_rps"`
-	RequestTimeout    time.Duration `json:"request_timeout"`
-	MaxRequestSize    int64         `json:"max_request_size"`
+	Host              string        `json:"host" yaml:"host"`
+	Port              int           `json:"port" yaml:"port"`
+	ReadTimeout       time.Duration `json:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout      time.Duration `json:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout       time.Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	EnableCORS        bool          `json:"enable_cors" yaml:"enable_cors"`
+	AllowedOrigins    []string      `json:"allowed_origins" yaml:"allowed_origins"`
+	AllowedMethods    []string      `json:"allowed_methods" yaml:"allowed_methods"`
+	AllowedHeaders    []string      `json:"allowed_headers" yaml:"allowed_headers"`
+	EnableAuth        bool          `json:"enable_auth" yaml:"enable_auth"`
+	AuthType          string        `json:"auth_type" yaml:"auth_type"` // jwt, oauth2, apikey
+	JWTSecret         string        `json:"jwt_secret" yaml:"jwt_secret"`
+	JWTAlgorithms     []string      `json:"jwt_algorithms" yaml:"jwt_algorithms"` // signing algorithms tokens may use, e.g. HS256
+	APIKeys           []APIKeyInfo  `json:"api_keys" yaml:"api_keys"` // hashed keys accepted by apikey auth
+	EnableMetrics     bool          `json:"enable_metrics" yaml:"enable_metrics"`
+	EnablePprof       bool          `json:"enable_pprof" yaml:"enable_pprof"`
+	EnableSwagger     bool          `json:"enable_swagger" yaml:"enable_swagger"`
+	EnableDebug       bool          `json:"enable_debug" yaml:"enable_debug"` // allows explain=true on attack path queries
+	RateLimitEnabled  bool          `json:"rate_limit_enabled" yaml:"rate_limit_enabled"`
+	RateLimitRPS      int           `json:"rate_limit_rps" yaml:"rate_limit_rps"`
+	RateLimitBurst    int           `json:"rate_limit_burst" yaml:"rate_limit_burst"`  // requests a client may make at once; defaults to RateLimitRPS
+	RateLimitExempt   []string      `json:"rate_limit_exempt" yaml:"rate_limit_exempt"` // paths that are never rate limited
+	RequestTimeout    time.Duration `json:"request_timeout" yaml:"request_timeout"`
+	MaxRequestSize    int64         `json:"max_request_size" yaml:"max_request_size"`
+	Quotas            QuotaConfig   `json:"quotas" yaml:"quotas"` // per-tenant limits on expensive endpoints
+	MaxRecalcJobs     int           `json:"max_recalc_jobs" yaml:"max_recalc_jobs"` // risk recalculation jobs run at once
+	GraphQLMaxDepth   int           `json:"graphql_max_depth" yaml:"graphql_max_depth"` // deepest selection nesting a GraphQL query may use
+	GraphQLMaxCost    int           `json:"graphql_max_cost" yaml:"graphql_max_cost"` // estimated objects a GraphQL query may load
+	StreamKeepAlive   time.Duration `json:"stream_keep_alive" yaml:"stream_keep_alive"` // interval of keep-alive comments on event streams
+	AttackPathDebounce time.Duration `json:"attack_path_debounce" yaml:"attack_path_debounce"` // changes this close together send one attack path update
 }
 
 // DefaultGatewayConfig returns default gateway configuration
@@ -105,13 +199,22 @@ func DefaultGatewayConfig() GatewayConfig {
 		AllowedHeaders:   []string{"*"},
 		EnableAuth:       false,
 		AuthType:         "jwt",
+		JWTAlgorithms:    []string{"HS256"},
 		EnableMetrics:    true,
 		EnablePprof:      false,
 		EnableSwagger:    true,
 		RateLimitEnabled: false,
 		RateLimitRPS:     100,
+		RateLimitBurst:   200,
+		RateLimitExempt:  []string{"/api/v1/health", "/api/v1/metrics", "/api/v1/metrics/prometheus"},
 		RequestTimeout:   30 * time.Second,
 		MaxRequestSize:   10 << 20, // 10MB
+		Quotas:           DefaultQuotaConfig(),
+		MaxRecalcJobs:    2,
+		GraphQLMaxDepth:  6,
+		GraphQLMaxCost:   20000,
+		StreamKeepAlive:  15 * time.Second,
+		AttackPathDebounce: 2 * time.Second,
 	}
 }
 
@@ -120,14 +223,30 @@ type Middleware func(http.Handler) http.Handler
 
 // GatewayMetrics represents gateway metrics
 type GatewayMetrics struct {
+	mu               sync.RWMutex
 	RequestsTotal    int64                    `json:"requests_total"`
 	RequestsActive   int64                    `json:"requests_active"`
 	RequestsFailed   int64                    `json:"requests_failed"`
 	AverageLatency   time.Duration            `json:"average_latency"`
-	RequestsByPath   map[string]int64          `json:"requests_by_path"`
+	RequestsByPath   map[string]int64          `json:"requests_by_path"` // by route template, "unknown" for unmatched requests
 	RequestsByMethod map[string]int64         `json:"requests_by_method"`
 	RequestsByStatus map[int]int64             `json:"requests_by_status"`
 	LastRequest      time.Time                 `json:"last_request"`
+	requests         map[requestKey]int64      // by method, route template and status
+	latencies        map[latencyKey]*latencyHistogram
+}
+
+// GatewayMetricsSnapshot is a copy of the gateway metrics taken under their
+// lock. It holds no lock itself, so it can be copied and encoded freely.
+type GatewayMetricsSnapshot struct {
+	RequestsTotal    int64            `json:"requests_total"`
+	RequestsActive   int64            `json:"requests_active"`
+	RequestsFailed   int64            `json:"requests_failed"`
+	AverageLatency   time.Duration    `json:"average_latency"`
+	RequestsByPath   map[string]int64 `json:"requests_by_path"`
+	RequestsByMethod map[string]int64 `json:"requests_by_method"`
+	RequestsByStatus map[int]int64    `json:"requests_by_status"`
+	LastRequest      time.Time        `json:"last_request"`
 }
 
 // NewGateway creates a new API gateway
@@ -141,13 +260,24 @@ func NewGateway(config GatewayConfig, graphStore GraphStore, riskEngine RiskEngi
 		eventBus:   eventBus,
 		config:     config,
 		middleware: make([]Middleware, 0),
+		recalcJobs: newRecalcJobs(config.MaxRecalcJobs),
+		streams:    newEventBroadcaster(),
+		apiKeys:    NewMemoryAPIKeyStore(config.APIKeys),
 		metrics: &GatewayMetrics{
 			RequestsByPath:   make(map[string]int64),
 			RequestsByMethod: make(map[string]int64),
-			RequestsByStatus: make(map[string]int64),
+			RequestsByStatus: make(map[int]int64),
 		},
 	}
 	
+	if config.Quotas.Enabled {
+		gateway.quotas = newQuotaEnforcer(config.Quotas, NewMemoryQuotaStore())
+	}
+	
+	if config.RateLimitEnabled && config.RateLimitRPS > 0 {
+		gateway.rateLimiter = newRateLimiter(config.RateLimitRPS, config.RateLimitBurst)
+	}
+	
 	// Setup routes
 	gateway.setupRoutes()
 	
@@ -157,7 +287,7 @@ func NewGateway(config GatewayConfig, graphStore GraphStore, riskEngine RiskEngi
 	// Create HTTP server
 	gateway.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", config.Host, config.Port),
-		Handler:      router,
+		Handler:      gateway.metricsMiddleware(router), // outside the router to count unmatched and rejected requests too
 		ReadTimeout:  config.ReadTimeout,
 		WriteTimeout: config.WriteTimeout,
 		IdleTimeout:  config.IdleTimeout,
@@ -174,13 +304,19 @@ func (g *Gateway) setupRoutes() {
 	assets := api.PathPrefix("/assets").Subrouter()
 	assets.HandleFunc("", g.handleListAssets).Methods("GET")
 	assets.HandleFunc("", g.handleCreateAsset).Methods("POST")
+	assets.HandleFunc("/autocomplete", g.handleAutocompleteAssets).Methods("GET")
+	assets.HandleFunc("/export", g.withQuota(QuotaExport, g.handleExportAssets)).Methods("GET")
+	assets.HandleFunc("/import", g.withQuota(QuotaImport, g.handleImportAssets)).Methods("POST")
 	assets.HandleFunc("/{id}", g.handleGetAsset).Methods("GET")
 	assets.HandleFunc("/{id}", g.handleUpdateAsset).Methods("PUT")
+	assets.HandleFunc("/{id}", g.handlePatchAsset).Methods("PATCH")
 	assets.HandleFunc("/{id}", g.handleDeleteAsset).Methods("DELETE")
 	assets.HandleFunc("/search", g.handleSearchAssets).Methods("POST")
 	assets.HandleFunc("/{id}/neighbors", g.handleGetNeighbors).Methods("GET")
 	assets.HandleFunc("/{id}/risk", g.handleGetAssetRisk).Methods("GET")
+	assets.HandleFunc("/{id}/risk/explain", g.handleExplainAssetRisk).Methods("GET")
 	assets.HandleFunc("/{id}/findings", g.handleGetAssetFindings).Methods("GET")
+	assets.HandleFunc("/{id}/detail", g.handleGetAssetDetail).Methods("GET")
 	
 	// Relationship routes
 	relationships := api.PathPrefix("/relationships").Subrouter()
@@ -195,34 +331,71 @@ func (g *Gateway) setupRoutes() {
 	findings := api.PathPrefix("/findings").Subrouter()
 	findings.HandleFunc("", g.handleListFindings).Methods("GET")
 	findings.HandleFunc("", g.handleCreateFinding).Methods("POST")
+	findings.HandleFunc("/groups", g.handleGetFindingGroups).Methods("GET")
 	findings.HandleFunc("/{id}", g.handleGetFinding).Methods("GET")
 	findings.HandleFunc("/{id}", g.handleUpdateFinding).Methods("PUT")
+	findings.HandleFunc("/{id}", g.handlePatchFinding).Methods("PATCH")
 	findings.HandleFunc("/{id}/resolve", g.handleResolveFinding).Methods("POST")
+	findings.HandleFunc("/{id}/feedback", g.handleFindingFeedback).Methods("POST")
+	
+	// Policy routes
+	policies := api.PathPrefix("/policies").Subrouter()
+	policies.HandleFunc("/categories", g.handleListPolicyCategories).Methods("GET")
+	policies.HandleFunc("/{id}/accuracy", g.handleGetPolicyAccuracy).Methods("GET")
+	
+	// Graph visualization
+	api.HandleFunc("/graph/view", g.handleGraphView).Methods("GET")
+	
+	// GraphQL routes
+	api.HandleFunc("/graphql", g.handleGraphQL).Methods("GET", "POST")
+	
+	// Event streams
+	api.HandleFunc("/stream/risk", g.handleRiskStream).Methods("GET")
+	api.HandleFunc("/ws/attack-paths", g.handleAttackPathUpdates).Methods("GET")
 	
 	// Risk routes
 	risk := api.PathPrefix("/risk").Subrouter()
 	risk.HandleFunc("/summary", g.handleGetRiskSummary).Methods("GET")
 	risk.HandleFunc("/trends/{assetId}", g.handleGetRiskTrends).Methods("GET")
-	risk.HandleFunc("/recalculate", g.handleRecalculateRisk).Methods("POST")
-	risk.HandleFunc("/batch-recalculate", g.handleBatchRecalculateRisk).Methods("POST")
+	risk.HandleFunc("/recalculate", g.withQuota(QuotaRecalculate, g.handleRecalculateRisk)).Methods("POST")
+	risk.HandleFunc("/recalculate/{jobId}", g.handleGetRecalculationJob).Methods("GET")
+	risk.HandleFunc("/recalculate/{jobId}", g.handleCancelRecalculationJob).Methods("DELETE")
+	risk.HandleFunc("/batch-recalculate", g.withQuota(QuotaRecalculate, g.handleBatchRecalculateRisk)).Methods("POST")
 	
 	// Attack path routes
 	attackPaths := api.PathPrefix("/attack-paths").Subrouter()
-	attackPaths.HandleFunc("/find", g.handleFindAttackPaths).Methods("POST")
-	attackPaths.HandleFunc("/path", g.handleFindPath).Methods("POST")
+	attackPaths.HandleFunc("/find", g.withQuota(QuotaAttackPaths, g.handleFindAttackPaths)).Methods("POST")
+	attackPaths.HandleFunc("/path", g.withQuota(QuotaAttackPaths, g.handleFindPath)).Methods("POST")
+	attackPaths.HandleFunc("/discover", g.withQuota(QuotaAttackPaths, g.handleDiscoverAttackPaths)).Methods("POST")
+	attackPaths.HandleFunc("/accepted", g.handleListAcceptedPaths).Methods("GET")
+	attackPaths.HandleFunc("/accepted", g.handleCreateAcceptedPath).Methods("POST")
+	attackPaths.HandleFunc("/accepted/{id}", g.handleGetAcceptedPath).Methods("GET")
+	attackPaths.HandleFunc("/accepted/{id}", g.handleUpdateAcceptedPath).Methods("PUT")
+	attackPaths.HandleFunc("/accepted/{id}", g.handleDeleteAcceptedPath).Methods("DELETE")
 	
 	// Health and metrics
 	api.HandleFunc("/health", g.handleHealth).Methods("GET")
 	api.HandleFunc("/metrics", g.handleMetrics).Methods("GET")
+	api.HandleFunc("/metrics/prometheus", g.handlePrometheusMetrics).Methods("GET")
 	
 	// Admin routes
 	admin := api.PathPrefix("/admin").Subrouter()
 	admin.HandleFunc("/cache/clear", g.handleClearCache).Methods("POST")
 	admin.HandleFunc("/cache/stats", g.handleCacheStats).Methods("GET")
+	admin.HandleFunc("/handlers", g.handleListEventHandlers).Methods("GET")
+	admin.HandleFunc("/relationships/delete", g.handleDeleteRelationshipsByFilter).Methods("POST")
+	admin.HandleFunc("/assets/reencode", g.handleReencodeAssets).Methods("POST")
+	admin.HandleFunc("/graph/export", g.withQuota(QuotaExport, g.handleExportGraph)).Methods("GET")
+	admin.HandleFunc("/graph/import", g.handleImportGraph).Methods("POST")
+	admin.HandleFunc("/graph/features", g.withQuota(QuotaExport, g.handleExportFeatures)).Methods("GET")
+	admin.HandleFunc("/llm/spend", g.handleLLMSpend).Methods("GET")
 }
 
 // setupMiddleware configures HTTP middleware
 func (g *Gateway) setupMiddleware() {
+	// Report the matched route before any middleware can reject the request
+	g.router.Use(g.routeMiddleware)
+	
 	// Apply middleware in reverse order
 	for i := len(g.middleware) - 1; i >= 0; i-- {
 		g.router.Use(g.middleware[i])
@@ -240,9 +413,6 @@ func (g *Gateway) setupMiddleware() {
 	if g.config.RateLimitEnabled {
 		g.setupRateLimit()
 	}
-	
-	// Metrics middleware (always last to capture all requests)
-	g.router.Use(g.metricsMiddleware)
 }
 
 // setupCORS configures CORS
@@ -275,8 +445,20 @@ func (g *Gateway) setupRateLimit() {
 	g.router.Use(g.rateLimitMiddleware)
 }
 
+// ValidateAuth rejects authentication settings the gateway cannot enforce
+// safely, such as JWT authentication without a secret
+func (c GatewayConfig) ValidateAuth() error {
+	if c.EnableAuth && c.AuthType == "jwt" && c.JWTSecret == "" {
+		return fmt.Errorf("jwt_secret is required when jwt auth is enabled")
+	}
+	return nil
+}
+
 // Start starts the API gateway
 func (g *Gateway) Start() error {
+	if err := g.config.ValidateAuth(); err != nil {
+		return err
+	}
 	log.Printf("Starting API gateway on %s", g.server.Addr)
 	return g.server.ListenAndServe()
 }
@@ -284,6 +466,8 @@ func (g *Gateway) Start() error {
 // Stop stops the API gateway
 func (g *Gateway) Stop(ctx context.Context) error {
 	log.Printf("Stopping API gateway")
+	g.recalcJobs.cancelAll()
+	g.streams.close()
 	return g.server.Shutdown(ctx)
 }
 
@@ -292,16 +476,43 @@ func (g *Gateway) AddMiddleware(middleware Middleware) {
 	g.middleware = append(g.middleware, middleware)
 }
 
+// SetAttackPathFinder enables selector-based attack path discovery
+func (g *Gateway) SetAttackPathFinder(finder AttackPathFinder) {
+	g.attackPaths = finder
+}
+
+// SetPolicyCatalog sets the source of policy categories; the built-in
+// taxonomy is served until one is set
+func (g *Gateway) SetPolicyCatalog(catalog PolicyCatalog) {
+	g.policyCatalog = catalog
+}
+
+// SetEventHandlerRegistry enables the event handler listing for the event
+// processor running alongside the gateway
+func (g *Gateway) SetEventHandlerRegistry(registry EventHandlerRegistry) {
+	g.eventHandlers = registry
+}
+
+// SetQuotaStore replaces the in-memory quota counters, e.g. with a store
+// shared by all gateway replicas. It has no effect when quotas are disabled.
+func (g *Gateway) SetQuotaStore(store QuotaStore) {
+	if g.quotas != nil {
+		g.quotas.store = store
+	}
+}
+
 // Request/Response types
 
 type ListAssetsRequest struct {
-	Types         []models.AssetType  `json:"types,omitempty"`
-	Providers     []models.Provider   `json:"providers,omitempty"`
-	Environments  []models.Environment `json:"environments,omitempty"`
-	MinRiskScore  float64             `json:"min_risk_score,omitempty"`
-	MaxRiskScore  float64             `json:"max_risk_score,omitempty"`
-	Limit         int                 `json:"limit,omitempty"`
-	Offset        int                 `json:"offset,omitempty"`
+	Types           []models.AssetType   `json:"types,omitempty"`
+	Providers       []models.Provider    `json:"providers,omitempty"`
+	Environments    []models.Environment `json:"environments,omitempty"`
+	MinRiskScore    float64              `json:"min_risk_score,omitempty"`
+	MaxRiskScore    float64              `json:"max_risk_score,omitempty"`
+	FirstSeenAfter  time.Time            `json:"first_seen_after"`
+	FirstSeenBefore time.Time            `json:"first_seen_before"`
+	Limit           int                  `json:"limit,omitempty"`
+	Offset          int                  `json:"offset,omitempty"`
 }
 
 type SearchAssetsRequest struct {
@@ -314,6 +525,14 @@ type SearchAssetsRequest struct {
 
 type CreateAssetRequest struct {
 	Asset models.Asset `json:"asset"`
+	// Upsert updates an existing asset with the same id instead of
+	// failing with a conflict
+	Upsert bool `json:"upsert,omitempty"`
+}
+
+type UpsertAssetResponse struct {
+	Asset   models.Asset `json:"asset"`
+	Created bool         `json:"created"`
 }
 
 type UpdateAssetRequest struct {
@@ -336,10 +555,12 @@ type UpdateRelationshipRequest struct {
 type SearchRelationshipsRequest struct {
 	FromAssetID   string                     `json:"from_asset_id,omitempty"`
 	ToAssetID     string                     `json:"to_asset_id,omitempty"`
+	AssetIDs      []string                   `json:"asset_ids,omitempty"`
 	Types         []models.RelationshipType  `json:"types,omitempty"`
 	MinStrength   float64                    `json:"min_strength,omitempty"`
 	MaxStrength   float64                    `json:"max_strength,omitempty"`
 	Limit         int                        `json:"limit,omitempty"`
+	Offset        int                        `json:"offset,omitempty"`
 }
 
 type CreateFindingRequest struct {
@@ -350,6 +571,20 @@ type UpdateFindingRequest struct {
 	Finding models.Finding `json:"finding"`
 }
 
+// DiscoverAttackPathsRequest selects entry points and targets by label and
+// property predicates, e.g. paths from the partner network to PCI data stores
+type DiscoverAttackPathsRequest struct {
+	EntryPoints []graph.NodeSelector `json:"entry_points"`
+	Targets     []graph.NodeSelector `json:"targets"`
+	MaxHops     int                  `json:"max_hops"`
+}
+
+type FindingFeedbackRequest struct {
+	Verdict     models.FeedbackVerdict `json:"verdict"`
+	Reason      string                 `json:"reason,omitempty"`
+	SubmittedBy string                 `json:"submitted_by,omitempty"`
+}
+
 type FindAttackPathsRequest struct {
 	EntryPoints    []string                   `json:"entry_points"`
 	Targets        []string                   `json:"targets"`
@@ -389,10 +624,56 @@ type APIError struct {
 }
 
 type APIMeta struct {
-	Total   int `json:"total,omitempty"`
-	Limit   int `json:"limit,omitempty"`
-	Offset  int `json:"offset,omitempty"`
-	HasMore bool `json:"has_more,omitempty"`
+	// Pagination is set on every list response; its fields are inlined
+	*Pagination
+	Explain *graph.QueryExplanation `json:"explain,omitempty"`
+	// Truncated is set when the result hit the server's result cap
+	Truncated bool   `json:"truncated,omitempty"`
+	Warning   string `json:"warning,omitempty"`
+}
+
+// Pagination is the envelope shared by all list responses. Offset-paged
+// listings report the offset of the page; cursor-paged listings echo the
+// cursor they were given and return NextCursor to fetch the next page.
+type Pagination struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Cursor     string `json:"cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// newPagination describes a page of count items at offset of a listing of
+// total items. A negative total means the store could not count the
+// listing; the total is then the items seen so far and a full page is
+// assumed to have more.
+func newPagination(total, limit, offset, count int) *Pagination {
+	page := &Pagination{Total: total, Limit: limit, Offset: offset}
+	if total < 0 {
+		page.Total = offset + count
+		page.HasMore = limit > 0 && count == limit
+	} else {
+		page.HasMore = offset+count < total
+	}
+	return page
+}
+
+// applyResultInfo flags meta when a list query was truncated by the result cap
+func applyResultInfo(meta *APIMeta, info *graph.ResultInfo) *APIMeta {
+	if info == nil || !info.Truncated {
+		return meta
+	}
+	if meta == nil {
+		meta = &APIMeta{}
+	}
+	if meta.Pagination == nil {
+		meta.Pagination = &Pagination{}
+	}
+	meta.Truncated = true
+	meta.HasMore = true
+	meta.Warning = fmt.Sprintf("result truncated to %d items, refine your query", info.Limit)
+	return meta
 }
 
 // Helper functions
@@ -406,11 +687,11 @@ func writeJSONResponse(w http.ResponseWriter, status int, response APIResponse)
 	}
 }
 
-func writeErrorResponse(w http.ResponseWriter, status int, code, message, details string) {
+func writeErrorResponse(w http.ResponseWriter, status int, code apperrors.Code, message, details string) {
 	response := APIResponse{
 		Success: false,
 		Error: &APIError{
-			Code:    code,
+			Code:    string(code),
 			Message: message,
 			Details: details,
 		},
@@ -418,6 +699,18 @@ func writeErrorResponse(w http.ResponseWriter, status int, code, message, detail
 	writeJSONResponse(w, status, response)
 }
 
+// writeError writes a store or engine error with the status and code of its
+// type. Only client-safe messages are returned as details; anything else is
+// logged so driver internals do not leak to clients.
+func writeError(w http.ResponseWriter, err error, message string) {
+	code := apperrors.CodeOf(err)
+	details := apperrors.MessageOf(err)
+	if details == "" {
+		log.Printf("%s: %v", message, err)
+	}
+	writeErrorResponse(w, apperrors.HTTPStatus(code), code, message, details)
+}
+
 func writeSuccessResponse(w http.ResponseWriter, data interface{}, meta *APIMeta) {
 	response := APIResponse{
 		Success: true,
@@ -427,6 +720,77 @@ func writeSuccessResponse(w http.ResponseWriter, data interface{}, meta *APIMeta
 	writeJSONResponse(w, http.StatusOK, response)
 }
 
+// writeListResponse writes a page of a listing with its pagination envelope,
+// flagging results truncated by the store's result cap
+func writeListResponse(w http.ResponseWriter, data interface{}, page *Pagination, info *graph.ResultInfo) {
+	writeSuccessResponse(w, data, applyResultInfo(&APIMeta{Pagination: page}, info))
+}
+
+// streamFlushInterval is the number of streamed items written between flushes
+const streamFlushInterval = 100
+
+// streamWriter writes an APIResponse whose data array is encoded item by item
+// instead of being buffered. The status is committed before the first item, so
+// a failure mid-stream is reported through the trailing error field.
+type streamWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	encoder *json.Encoder
+	count   int
+}
+
+func newStreamWriter(w http.ResponseWriter) *streamWriter {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, `{"data":[`)
+	
+	flusher, _ := w.(http.Flusher)
+	return &streamWriter{w: w, flusher: flusher, encoder: json.NewEncoder(w)}
+}
+
+// Write encodes a single item of the data array
+func (sw *streamWriter) Write(item interface{}) error {
+	if sw.count > 0 {
+		if _, err := io.WriteString(sw.w, ","); err != nil {
+			return err
+		}
+	}
+	if err := sw.encoder.Encode(item); err != nil {
+		return err
+	}
+	
+	sw.count++
+	if sw.flusher != nil && sw.count%streamFlushInterval == 0 {
+		sw.flusher.Flush()
+	}
+	return nil
+}
+
+// Close terminates the response, reporting err if the stream failed with
+// the code and client-safe message writeError would use
+func (sw *streamWriter) Close(err error) {
+	meta, _ := json.Marshal(APIMeta{Pagination: newPagination(sw.count, 0, 0, sw.count)})
+	
+	if err != nil {
+		details := apperrors.MessageOf(err)
+		if details == "" {
+			log.Printf("Stream interrupted: %v", err)
+		}
+		apiErr, _ := json.Marshal(APIError{
+			Code:    string(apperrors.CodeOf(err)),
+			Message: "Stream interrupted",
+			Details: details,
+		})
+		fmt.Fprintf(sw.w, `],"success":false,"error":%s,"meta":%s}`, apiErr, meta)
+	} else {
+		fmt.Fprintf(sw.w, `],"success":true,"meta":%s}`, meta)
+	}
+	
+	if sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+}
+
 func parseRequestBody(r *http.Request, target interface{}) error {
 	defer r.Body.Close()
 	return json.NewDecoder(r.Body).Decode(target)
@@ -437,28 +801,37 @@ func parseRequestBody(r *http.Request, target interface{}) error {
 func (g *Gateway) metricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		g.trackActive(1)
+		defer g.trackActive(-1)
 		
 		// Wrap response writer to capture status code
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		matched := &matchedRoute{template: "unknown"}
 		
 		// Process request
-		next.ServeHTTP(wrapped, r)
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), matchedRouteContextKey{}, matched)))
 		
 		// Update metrics
 		duration := time.Since(start)
-		g.updateMetrics(r, wrapped.statusCode, duration)
+		g.updateMetrics(r, matched.template, wrapped.statusCode, duration)
 	})
 }
 
-func (g *Gateway) updateMetrics(r *http.Request, statusCode int, duration time.Duration) {
+// updateMetrics counts a request by its route template, not its raw path,
+// so IDs in paths cannot grow the metrics without bound
+func (g *Gateway) updateMetrics(r *http.Request, route string, statusCode int, duration time.Duration) {
 	g.metrics.mu.Lock()
 	defer g.metrics.mu.Unlock()
 	
 	g.metrics.RequestsTotal++
-	g.metrics.RequestsByPath[r.URL.Path]++
+	g.metrics.RequestsByPath[route]++
 	g.metrics.RequestsByMethod[r.Method]++
 	g.metrics.RequestsByStatus[statusCode]++
+	if statusCode >= http.StatusInternalServerError {
+		g.metrics.RequestsFailed++
+	}
 	g.metrics.LastRequest = time.Now()
+	g.recordRequest(r.Method, route, statusCode, duration)
 	
 	// Update average latency
 	if g.metrics.AverageLatency == 0 {
@@ -468,6 +841,13 @@ func (g *Gateway) updateMetrics(r *http.Request, statusCode int, duration time.D
 	}
 }
 
+// trackActive adjusts the number of requests in flight
+func (g *Gateway) trackActive(delta int64) {
+	g.metrics.mu.Lock()
+	defer g.metrics.mu.Unlock()
+	g.metrics.RequestsActive += delta
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -479,31 +859,32 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// Placeholder middleware implementations
-func (g *Gateway) jwtAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// JWT authentication implementation
-		next.ServeHTTP(w, r)
-	})
+// Flush lets streaming handlers flush through the metrics middleware
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
 }
 
-func (g *Gateway) oauth2AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// OAuth2 authentication implementation
-		next.ServeHTTP(w, r)
-	})
+// Hijack lets WebSocket handlers take over the connection through the
+// metrics middleware
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
 }
 
-func (g *Gateway) apiKeyAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// API key authentication implementation
-		next.ServeHTTP(w, r)
-	})
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
 
-func (g *Gateway) rateLimitMiddleware(next http.Handler) http.Handler {
+// Placeholder middleware implementations
+func (g *Gateway) oauth2AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Rate limiting implementation
+		// OAuth2 authentication implementation
 		next.ServeHTTP(w, r)
 	})
 }
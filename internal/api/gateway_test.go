@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/securizon/pkg/apperrors"
+)
+
+func TestStreamWriterReportsClientSafeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    string
+		details string
+	}{
+		{"driver error", errors.New("Neo.ClientError.Statement.SyntaxError at bolt://10.0.0.5:7687"), "INTERNAL_ERROR", ""},
+		{"timeout", apperrors.Wrap(apperrors.CodeTimeout, errors.New("i/o timeout"), "query timed out"), "TIMEOUT", ""},
+		{"invalid filter", apperrors.Invalid("unknown environment %q", "qa"), "INVALID_REQUEST", `unknown environment "qa"`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		sw := newStreamWriter(w)
+		sw.Write(map[string]string{"id": "vm-1"})
+		sw.Close(tt.err)
+
+		var response APIResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: failed to decode %s: %v", tt.name, w.Body.String(), err)
+		}
+		if response.Success || response.Error == nil {
+			t.Fatalf("%s: response = %s, want a failed stream", tt.name, w.Body.String())
+		}
+		if response.Error.Code != tt.code || response.Error.Details != tt.details {
+			t.Errorf("%s: error = %+v, want code %s and details %q", tt.name, response.Error, tt.code, tt.details)
+		}
+		if strings.Contains(w.Body.String(), "bolt://") {
+			t.Errorf("%s: the store error leaked to the client: %s", tt.name, w.Body.String())
+		}
+	}
+}
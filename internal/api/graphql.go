@@ -0,0 +1,921 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+const (
+	graphQLDefaultLimit      = 50
+	graphQLMaxLimit          = 500
+	graphQLMaxNeighborDepth  = 3
+	graphQLLoaderConcurrency = 4
+
+	// graphQLNeighborFanout is the number of neighbors per hop assumed when
+	// estimating the cost of a query
+	graphQLNeighborFanout = 10
+)
+
+// graphQLSchema maps each type to its fields and the object type they
+// return; scalar fields map to "". Every type also has __typename.
+var graphQLSchema = map[string]map[string]string{
+	"Query": {
+		"asset":     "Asset",
+		"assets":    "Asset",
+		"neighbors": "Asset",
+	},
+	"Asset": {
+		"id":                    "",
+		"name":                  "",
+		"type":                  "",
+		"provider":              "",
+		"environment":           "",
+		"description":           "",
+		"createdAt":             "",
+		"updatedAt":             "",
+		"firstSeen":             "",
+		"lastSeen":              "",
+		"reachableFromInternet": "",
+		"tags":                  "",
+		"findings":              "Finding",
+		"risk":                  "Risk",
+		"neighbors":             "Asset",
+	},
+	"Finding": {
+		"id":             "",
+		"name":           "",
+		"policyId":       "",
+		"assetId":        "",
+		"severity":       "",
+		"riskScore":      "",
+		"status":         "",
+		"description":    "",
+		"recommendation": "",
+		"cves":           "",
+		"firstSeen":      "",
+		"lastSeen":       "",
+		"resolvedAt":     "",
+	},
+	"Risk": {
+		"assetId":          "",
+		"score":            "",
+		"level":            "",
+		"baseSeverity":     "",
+		"exposureMult":     "",
+		"environmentMult":  "",
+		"threatIntelMult":  "",
+		"reachabilityMult": "",
+		"lastCalculated":   "",
+	},
+}
+
+// graphQLArgs lists the arguments each field accepts
+var graphQLArgs = map[string][]string{
+	"Query.asset":     {"id"},
+	"Query.assets":    {"filter", "limit", "offset"},
+	"Query.neighbors": {"id", "depth", "direction"},
+	"Asset.neighbors": {"depth", "direction"},
+}
+
+// GraphQLRequest is a GraphQL query sent over HTTP
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLResponse is a GraphQL result. Data is omitted when the query was
+// rejected before it ran.
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLError reports a rejected query, or a field that failed to resolve
+// and was returned as null
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (g *Gateway) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeGraphQLError(w, "Invalid variables: "+err.Error())
+				return
+			}
+		}
+	} else if err := parseRequestBody(r, &req); err != nil {
+		writeGraphQLError(w, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		writeGraphQLError(w, "query is required")
+		return
+	}
+
+	op, err := parseGraphQL(req.Query, req.OperationName)
+	if err != nil {
+		writeGraphQLError(w, "Syntax error: "+err.Error())
+		return
+	}
+	if depth := gqlDepth(op.Selections); depth > g.config.GraphQLMaxDepth {
+		writeGraphQLError(w, fmt.Sprintf("query depth %d exceeds the maximum of %d", depth, g.config.GraphQLMaxDepth))
+		return
+	}
+	if err := validateGraphQL("Query", op.Selections); err != nil {
+		writeGraphQLError(w, err.Error())
+		return
+	}
+
+	exec := &graphQLExecutor{
+		loader:    newGraphQLLoader(g.graphStore),
+		variables: req.Variables,
+		defaults:  op.Defaults,
+	}
+	if cost := exec.cost("Query", op.Selections); cost > float64(g.config.GraphQLMaxCost) {
+		writeGraphQLError(w, fmt.Sprintf("query cost %.0f exceeds the maximum of %d", cost, g.config.GraphQLMaxCost))
+		return
+	}
+	data := exec.executeQuery(r.Context(), op.Selections)
+	writeGraphQLResponse(w, http.StatusOK, GraphQLResponse{Data: data, Errors: exec.errors})
+}
+
+// writeGraphQLError rejects a query that could not be run
+func writeGraphQLError(w http.ResponseWriter, message string) {
+	writeGraphQLResponse(w, http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: message}}})
+}
+
+func writeGraphQLResponse(w http.ResponseWriter, status int, response GraphQLResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode GraphQL response: %v", err)
+	}
+}
+
+// validateGraphQL checks the fields and arguments of a selection set on a
+// type, so that queries are rejected as a whole before any store call
+func validateGraphQL(typeName string, fields []*gqlField) error {
+	for _, field := range fields {
+		if field.Name == "__typename" {
+			if len(field.Args) > 0 || len(field.Selections) > 0 {
+				return fmt.Errorf("field \"__typename\" takes no arguments or selections")
+			}
+			continue
+		}
+
+		fieldType, ok := graphQLSchema[typeName][field.Name]
+		if !ok {
+			return fmt.Errorf("cannot query field %q on type %q", field.Name, typeName)
+		}
+
+		allowed := graphQLArgs[typeName+"."+field.Name]
+		for arg := range field.Args {
+			if !containsString(allowed, arg) {
+				return fmt.Errorf("unknown argument %q on field %q of type %q", arg, field.Name, typeName)
+			}
+		}
+
+		if fieldType == "" {
+			if len(field.Selections) > 0 {
+				return fmt.Errorf("field %q of type %q is a scalar and takes no selections", field.Name, typeName)
+			}
+			continue
+		}
+		if len(field.Selections) == 0 {
+			return fmt.Errorf("field %q of type %q must have a selection of subfields", field.Name, typeName)
+		}
+		if err := validateGraphQL(fieldType, field.Selections); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cost estimates the number of objects a selection set on a type loads.
+// Each object field counts once per item it is expected to return, plus the
+// cost of its selection on each item: assets count their limit and
+// neighbors graphQLNeighborFanout per hop, so nested lists multiply.
+func (e *graphQLExecutor) cost(typeName string, fields []*gqlField) float64 {
+	total := 0.0
+	for _, field := range fields {
+		fieldType := graphQLSchema[typeName][field.Name]
+		if fieldType == "" {
+			continue
+		}
+
+		items := 1.0
+		switch field.Name {
+		case "assets":
+			items = graphQLDefaultLimit
+			if limit, ok := e.intArg(field, "limit"); ok && limit > 0 {
+				items = math.Min(float64(limit), graphQLMaxLimit)
+			}
+		case "neighbors":
+			depth := 1
+			if value, ok := e.intArg(field, "depth"); ok && value > 0 {
+				depth = value
+			}
+			if depth > graphQLMaxNeighborDepth {
+				depth = graphQLMaxNeighborDepth
+			}
+			items = math.Pow(graphQLNeighborFanout, float64(depth))
+		}
+		total += items * (1 + e.cost(fieldType, field.Selections))
+	}
+	return total
+}
+
+// graphQLExecutor resolves one query. Fields that fail resolve to null and
+// are reported in errors, leaving the rest of the result intact.
+type graphQLExecutor struct {
+	loader    *graphQLLoader
+	variables map[string]interface{}
+	defaults  map[string]interface{}
+	errors    []GraphQLError
+}
+
+func (e *graphQLExecutor) executeQuery(ctx context.Context, fields []*gqlField) *graphQLObject {
+	result := &graphQLObject{}
+	for _, field := range fields {
+		path := []interface{}{field.ResponseKey()}
+
+		switch field.Name {
+		case "__typename":
+			result.set(field.ResponseKey(), "Query")
+
+		case "asset":
+			id, _ := e.stringArg(field, "id")
+			if id == "" {
+				e.fail(path, apperrors.Invalid("argument \"id\" is required"), "")
+				result.set(field.ResponseKey(), nil)
+				continue
+			}
+			asset, err := e.loader.asset(ctx, id)
+			if err != nil {
+				// A missing asset is null rather than an error
+				if apperrors.CodeOf(err) != apperrors.CodeNotFound {
+					e.fail(path, err, "Failed to get asset")
+				}
+				result.set(field.ResponseKey(), nil)
+				continue
+			}
+			result.set(field.ResponseKey(), e.resolveAssets(ctx, field.Selections, []models.Asset{asset}, path, false)[0])
+
+		case "assets":
+			filter, err := e.assetFilter(field)
+			if err != nil {
+				e.fail(path, err, "")
+				result.set(field.ResponseKey(), nil)
+				continue
+			}
+			assets, err := e.loader.store.ListAssets(ctx, filter)
+			if err != nil {
+				e.fail(path, err, "Failed to list assets")
+				result.set(field.ResponseKey(), nil)
+				continue
+			}
+			result.set(field.ResponseKey(), e.resolveAssetList(ctx, field.Selections, assets, path))
+
+		case "neighbors":
+			id, _ := e.stringArg(field, "id")
+			if id == "" {
+				e.fail(path, apperrors.Invalid("argument \"id\" is required"), "")
+				result.set(field.ResponseKey(), nil)
+				continue
+			}
+			result.set(field.ResponseKey(), e.resolveNeighbors(ctx, field, id, path))
+		}
+	}
+	return result
+}
+
+// resolveAssetList resolves a list of assets
+func (e *graphQLExecutor) resolveAssetList(ctx context.Context, fields []*gqlField, assets []models.Asset, path []interface{}) []interface{} {
+	if len(assets) == 0 {
+		return []interface{}{}
+	}
+	return e.resolveAssets(ctx, fields, assets, path, true)
+}
+
+// resolveAssets resolves the selection on each asset, indexing their error
+// paths for lists. The findings, risk and neighbors of all of them are
+// loaded together first; see graphQLLoader.prefetch.
+func (e *graphQLExecutor) resolveAssets(ctx context.Context, fields []*gqlField, assets []models.Asset, path []interface{}, indexed bool) []interface{} {
+	ids := make([]string, len(assets))
+	for i, asset := range assets {
+		ids[i] = asset.GetID()
+	}
+	e.loader.prefetch(ctx, ids, selects(fields, "findings"), selects(fields, "risk"))
+	for _, field := range fields {
+		if field.Name != "neighbors" {
+			continue
+		}
+		// Invalid arguments are reported when the field is resolved
+		if direction, depth, err := e.neighborArgs(field); err == nil {
+			e.loader.prefetchNeighbors(ctx, ids, direction, depth)
+		}
+	}
+
+	results := make([]interface{}, len(assets))
+	for i, asset := range assets {
+		itemPath := path
+		if indexed {
+			itemPath = appendPath(path, i)
+		}
+		results[i] = e.resolveAsset(ctx, fields, asset, itemPath)
+	}
+	return results
+}
+
+func (e *graphQLExecutor) resolveAsset(ctx context.Context, fields []*gqlField, asset models.Asset, path []interface{}) *graphQLObject {
+	base := asset.GetBaseAsset()
+	result := &graphQLObject{}
+	for _, field := range fields {
+		key := field.ResponseKey()
+		fieldPath := appendPath(path, key)
+
+		switch field.Name {
+		case "__typename":
+			result.set(key, "Asset")
+		case "id":
+			result.set(key, base.ID)
+		case "name":
+			result.set(key, base.Name)
+		case "type":
+			result.set(key, string(base.Type))
+		case "provider":
+			result.set(key, string(base.Provider))
+		case "environment":
+			result.set(key, string(base.Environment))
+		case "description":
+			result.set(key, base.Description)
+		case "createdAt":
+			result.set(key, graphQLTime(base.CreatedAt))
+		case "updatedAt":
+			result.set(key, graphQLTime(base.UpdatedAt))
+		case "firstSeen":
+			result.set(key, graphQLTime(base.FirstSeen))
+		case "lastSeen":
+			result.set(key, graphQLTime(base.LastSeen))
+		case "reachableFromInternet":
+			result.set(key, base.ReachableFromInternet)
+		case "tags":
+			result.set(key, base.Tags)
+
+		case "findings":
+			findings, err := e.loader.findings(ctx, base.ID)
+			if err != nil {
+				e.fail(fieldPath, err, "Failed to get asset findings")
+				result.set(key, nil)
+				continue
+			}
+			list := make([]interface{}, len(findings))
+			for i, finding := range findings {
+				list[i] = resolveFinding(field.Selections, finding)
+			}
+			result.set(key, list)
+
+		case "risk":
+			risk, err := e.loader.risk(ctx, base.ID)
+			if err != nil {
+				e.fail(fieldPath, err, "Failed to get asset risk")
+				result.set(key, nil)
+				continue
+			}
+			// Assets that have not been scored have no risk
+			if risk.LastCalculated.IsZero() {
+				result.set(key, nil)
+				continue
+			}
+			result.set(key, resolveRisk(field.Selections, risk))
+
+		case "neighbors":
+			result.set(key, e.resolveNeighbors(ctx, field, base.ID, fieldPath))
+		}
+	}
+	return result
+}
+
+func (e *graphQLExecutor) resolveNeighbors(ctx context.Context, field *gqlField, assetID string, path []interface{}) interface{} {
+	direction, depth, err := e.neighborArgs(field)
+	if err != nil {
+		e.fail(path, err, "")
+		return nil
+	}
+
+	neighbors, err := e.loader.neighbors(ctx, assetID, direction, depth)
+	if err != nil {
+		e.fail(path, err, "Failed to get neighbors")
+		return nil
+	}
+	return e.resolveAssetList(ctx, field.Selections, neighbors, path)
+}
+
+// neighborArgs returns the direction and depth of a neighbors field
+func (e *graphQLExecutor) neighborArgs(field *gqlField) (string, int, error) {
+	depth := 1
+	if value, ok := e.intArg(field, "depth"); ok {
+		depth = value
+	}
+	if depth < 1 || depth > graphQLMaxNeighborDepth {
+		return "", 0, apperrors.Invalid("depth must be between 1 and %d", graphQLMaxNeighborDepth)
+	}
+
+	direction := "both"
+	if value, ok := e.stringArg(field, "direction"); ok {
+		direction = strings.ToLower(value)
+	}
+	if direction != "both" && direction != "incoming" && direction != "outgoing" {
+		return "", 0, apperrors.Invalid("direction must be one of incoming, outgoing or both")
+	}
+	return direction, depth, nil
+}
+
+func resolveFinding(fields []*gqlField, finding models.Finding) *graphQLObject {
+	result := &graphQLObject{}
+	for _, field := range fields {
+		key := field.ResponseKey()
+		switch field.Name {
+		case "__typename":
+			result.set(key, "Finding")
+		case "id":
+			result.set(key, finding.ID)
+		case "name":
+			result.set(key, finding.Name)
+		case "policyId":
+			result.set(key, finding.PolicyID)
+		case "assetId":
+			result.set(key, finding.AssetID)
+		case "severity":
+			result.set(key, finding.Severity)
+		case "riskScore":
+			result.set(key, finding.RiskScore)
+		case "status":
+			result.set(key, finding.Status)
+		case "description":
+			result.set(key, finding.Description)
+		case "recommendation":
+			result.set(key, finding.Recommendation)
+		case "cves":
+			cves := finding.CVEs
+			if cves == nil {
+				cves = []string{}
+			}
+			result.set(key, cves)
+		case "firstSeen":
+			result.set(key, graphQLTime(finding.FirstSeen))
+		case "lastSeen":
+			result.set(key, graphQLTime(finding.LastSeen))
+		case "resolvedAt":
+			if finding.ResolvedAt == nil {
+				result.set(key, nil)
+			} else {
+				result.set(key, graphQLTime(*finding.ResolvedAt))
+			}
+		}
+	}
+	return result
+}
+
+func resolveRisk(fields []*gqlField, risk models.RiskScore) *graphQLObject {
+	result := &graphQLObject{}
+	for _, field := range fields {
+		key := field.ResponseKey()
+		switch field.Name {
+		case "__typename":
+			result.set(key, "Risk")
+		case "assetId":
+			result.set(key, risk.AssetID)
+		case "score":
+			result.set(key, risk.Score)
+		case "level":
+			result.set(key, string(risk.Level))
+		case "baseSeverity":
+			result.set(key, risk.BaseSeverity)
+		case "exposureMult":
+			result.set(key, risk.ExposureMult)
+		case "environmentMult":
+			result.set(key, risk.EnvironmentMult)
+		case "threatIntelMult":
+			result.set(key, risk.ThreatIntelMult)
+		case "reachabilityMult":
+			result.set(key, risk.ReachabilityMult)
+		case "lastCalculated":
+			result.set(key, graphQLTime(risk.LastCalculated))
+		}
+	}
+	return result
+}
+
+// fail records a field error. Only client-safe messages are returned;
+// anything else is logged and reported as message.
+func (e *graphQLExecutor) fail(path []interface{}, err error, message string) {
+	details := apperrors.MessageOf(err)
+	if details == "" {
+		log.Printf("GraphQL: %s: %v", message, err)
+		details = message
+	}
+	e.errors = append(e.errors, GraphQLError{Message: details, Path: path})
+}
+
+// assetFilter builds the store filter of an assets field
+func (e *graphQLExecutor) assetFilter(field *gqlField) (models.AssetFilter, error) {
+	filter := models.AssetFilter{Limit: graphQLDefaultLimit}
+	if limit, ok := e.intArg(field, "limit"); ok {
+		if limit < 1 || limit > graphQLMaxLimit {
+			return filter, apperrors.Invalid("limit must be between 1 and %d", graphQLMaxLimit)
+		}
+		filter.Limit = limit
+	}
+	if offset, ok := e.intArg(field, "offset"); ok {
+		if offset < 0 {
+			return filter, apperrors.Invalid("offset must not be negative")
+		}
+		filter.Offset = offset
+	}
+
+	value := e.resolveValue(field.Args["filter"])
+	if value == nil {
+		return filter, nil
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return filter, apperrors.Invalid("filter must be an object")
+	}
+	for key, value := range object {
+		switch key {
+		case "types":
+			for _, item := range graphQLStrings(value) {
+				filter.Types = append(filter.Types, models.AssetType(item))
+			}
+		case "providers":
+			for _, item := range graphQLStrings(value) {
+				filter.Providers = append(filter.Providers, models.Provider(item))
+			}
+		case "environments":
+			for _, item := range graphQLStrings(value) {
+				filter.Environments = append(filter.Environments, models.Environment(item))
+			}
+		case "minRiskScore":
+			filter.MinRiskScore, _ = graphQLFloat(value)
+		case "maxRiskScore":
+			filter.MaxRiskScore, _ = graphQLFloat(value)
+		default:
+			return filter, apperrors.Invalid("unknown filter field %q", key)
+		}
+	}
+	return filter, nil
+}
+
+// resolveValue substitutes the variables referenced by an argument value
+func (e *graphQLExecutor) resolveValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case gqlVariable:
+		if variable, ok := e.variables[string(v)]; ok {
+			return variable
+		}
+		return e.defaults[string(v)]
+	case gqlEnum:
+		return string(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = e.resolveValue(item)
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			object[key] = e.resolveValue(item)
+		}
+		return object
+	}
+	return value
+}
+
+func (e *graphQLExecutor) stringArg(field *gqlField, name string) (string, bool) {
+	s, ok := e.resolveValue(field.Args[name]).(string)
+	return s, ok
+}
+
+func (e *graphQLExecutor) intArg(field *gqlField, name string) (int, bool) {
+	f, ok := graphQLFloat(e.resolveValue(field.Args[name]))
+	if !ok || f != math.Trunc(f) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// graphQLFloat converts query literals (int64) and JSON variables (float64)
+func graphQLFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// graphQLStrings converts a string or list of strings
+func graphQLStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func graphQLTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
+
+// selects reports whether a selection set includes a field
+func selects(fields []*gqlField, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func appendPath(path []interface{}, elem interface{}) []interface{} {
+	next := make([]interface{}, len(path), len(path)+1)
+	copy(next, path)
+	return append(next, elem)
+}
+
+// graphQLObject is a result object that keeps its fields in query order
+type graphQLObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *graphQLObject) set(key string, value interface{}) {
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler
+func (o *graphQLObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// graphQLLoader memoizes the store calls of one query, so an asset, its
+// findings, risk or neighbors are loaded once however often they appear
+// in the result. Concurrent loads of the same key share one call.
+type graphQLLoader struct {
+	store GraphStore
+
+	mu    sync.Mutex
+	loads map[string]*graphQLLoad
+}
+
+type graphQLLoad struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newGraphQLLoader(store GraphStore) *graphQLLoader {
+	return &graphQLLoader{
+		store: store,
+		loads: make(map[string]*graphQLLoad),
+	}
+}
+
+// load returns the memoized result for key, calling fetch on first use
+func (l *graphQLLoader) load(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	l.mu.Lock()
+	if load, ok := l.loads[key]; ok {
+		l.mu.Unlock()
+		<-load.done
+		return load.value, load.err
+	}
+	load := &graphQLLoad{done: make(chan struct{})}
+	l.loads[key] = load
+	l.mu.Unlock()
+
+	load.value, load.err = fetch()
+	close(load.done)
+	return load.value, load.err
+}
+
+func (l *graphQLLoader) asset(ctx context.Context, id string) (models.Asset, error) {
+	value, err := l.load("asset|"+id, func() (interface{}, error) {
+		return l.store.GetAsset(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	asset, ok := value.(models.Asset)
+	if !ok || asset == nil {
+		return nil, apperrors.NotFound("asset %s not found", id)
+	}
+	return asset, nil
+}
+
+func (l *graphQLLoader) findings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	value, err := l.load("findings|"+assetID, func() (interface{}, error) {
+		return l.store.GetAssetFindings(ctx, assetID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	findings, _ := value.([]models.Finding)
+	return findings, nil
+}
+
+func (l *graphQLLoader) risk(ctx context.Context, assetID string) (models.RiskScore, error) {
+	value, err := l.load("risk|"+assetID, func() (interface{}, error) {
+		return l.store.GetAssetRisk(ctx, assetID)
+	})
+	if err != nil {
+		return models.RiskScore{}, err
+	}
+	risk, _ := value.(models.RiskScore)
+	return risk, nil
+}
+
+func (l *graphQLLoader) neighbors(ctx context.Context, assetID, direction string, depth int) ([]models.Asset, error) {
+	key := fmt.Sprintf("neighbors|%s|%s|%d", assetID, direction, depth)
+	value, err := l.load(key, func() (interface{}, error) {
+		neighbors, _, err := l.store.GetNeighbors(ctx, assetID, direction, depth)
+		return neighbors, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	neighbors, _ := value.([]models.Asset)
+	return neighbors, nil
+}
+
+// loadBatch memoizes the results for several ids under prefix, fetched in
+// one call. Ids already loaded or loading are skipped; ids the fetch
+// returns no value for are not found.
+func (l *graphQLLoader) loadBatch(prefix string, ids []string, fetch func(ids []string) (map[string]interface{}, error)) {
+	l.mu.Lock()
+	pending := make(map[string]*graphQLLoad, len(ids))
+	fetchIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := l.loads[prefix+id]; ok || pending[id] != nil {
+			continue
+		}
+		load := &graphQLLoad{done: make(chan struct{})}
+		l.loads[prefix+id] = load
+		pending[id] = load
+		fetchIDs = append(fetchIDs, id)
+	}
+	l.mu.Unlock()
+	if len(fetchIDs) == 0 {
+		return
+	}
+
+	values, err := fetch(fetchIDs)
+	for _, id := range fetchIDs {
+		load := pending[id]
+		if err != nil {
+			load.err = err
+		} else if value, ok := values[id]; ok {
+			load.value = value
+		} else {
+			load.err = apperrors.NotFound("asset %s not found", id)
+		}
+		close(load.done)
+	}
+}
+
+// prefetch loads the findings and risk of several assets, each distinct
+// asset once. Stores implementing AssetBatchLoader load each in a single
+// query; otherwise the assets are loaded concurrently. Errors are memoized
+// and reported when the fields are resolved.
+func (l *graphQLLoader) prefetch(ctx context.Context, assetIDs []string, findings, risk bool) {
+	if !findings && !risk {
+		return
+	}
+
+	batch, ok := l.store.(AssetBatchLoader)
+	if !ok {
+		l.each(assetIDs, func(id string) {
+			if findings {
+				l.findings(ctx, id)
+			}
+			if risk {
+				l.risk(ctx, id)
+			}
+		})
+		return
+	}
+
+	if findings {
+		l.loadBatch("findings|", assetIDs, func(ids []string) (map[string]interface{}, error) {
+			found, err := batch.GetFindingsForAssets(ctx, ids)
+			values := make(map[string]interface{}, len(ids))
+			for _, id := range ids {
+				values[id] = found[id]
+			}
+			return values, err
+		})
+	}
+	if risk {
+		l.loadBatch("risk|", assetIDs, func(ids []string) (map[string]interface{}, error) {
+			found, err := batch.GetAssetRisks(ctx, ids)
+			values := make(map[string]interface{}, len(found))
+			for id, risk := range found {
+				values[id] = risk
+			}
+			return values, err
+		})
+	}
+}
+
+// prefetchNeighbors loads the neighbors of several assets concurrently,
+// each distinct asset once
+func (l *graphQLLoader) prefetchNeighbors(ctx context.Context, assetIDs []string, direction string, depth int) {
+	l.each(assetIDs, func(id string) {
+		l.neighbors(ctx, id, direction, depth)
+	})
+}
+
+// each calls load once for each distinct id, graphQLLoaderConcurrency at
+// a time
+func (l *graphQLLoader) each(ids []string, load func(id string)) {
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, graphQLLoaderConcurrency)
+		seen = make(map[string]bool, len(ids))
+	)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			load(id)
+		}(id)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+func TestParseGraphQL(t *testing.T) {
+	query := `
+		# assets worth a look
+		query Risky($limit: Int = 5, $env: String) {
+			top: assets(limit: $limit, filter: {environments: [$env], minRiskScore: 7.5}) {
+				id
+				neighbors(depth: 2, direction: OUTGOING) { id }
+			}
+		}
+		query Other { asset(id: "a\"1") { name } }
+	`
+	op, err := parseGraphQL(query, "Risky")
+	if err != nil {
+		t.Fatalf("parseGraphQL() error = %v", err)
+	}
+	if op.Defaults["limit"] != int64(5) {
+		t.Errorf("default of $limit = %#v, want 5", op.Defaults["limit"])
+	}
+	if len(op.Selections) != 1 {
+		t.Fatalf("parsed %d root fields, want 1", len(op.Selections))
+	}
+
+	assets := op.Selections[0]
+	if assets.Name != "assets" || assets.ResponseKey() != "top" {
+		t.Errorf("root field = %s as %s, want assets as top", assets.Name, assets.ResponseKey())
+	}
+	if assets.Args["limit"] != gqlVariable("limit") {
+		t.Errorf("limit argument = %#v, want the $limit variable", assets.Args["limit"])
+	}
+	filter, _ := assets.Args["filter"].(map[string]interface{})
+	if filter["minRiskScore"] != 7.5 {
+		t.Errorf("filter.minRiskScore = %#v, want 7.5", filter["minRiskScore"])
+	}
+	if envs, _ := filter["environments"].([]interface{}); len(envs) != 1 || envs[0] != gqlVariable("env") {
+		t.Errorf("filter.environments = %#v, want [$env]", filter["environments"])
+	}
+	neighbors := assets.Selections[1]
+	if neighbors.Args["direction"] != gqlEnum("OUTGOING") || neighbors.Args["depth"] != int64(2) {
+		t.Errorf("neighbors arguments = %#v", neighbors.Args)
+	}
+	if depth := gqlDepth(op.Selections); depth != 3 {
+		t.Errorf("gqlDepth() = %d, want 3", depth)
+	}
+
+	other, err := parseGraphQL(query, "Other")
+	if err != nil {
+		t.Fatalf("parseGraphQL(Other) error = %v", err)
+	}
+	if other.Selections[0].Args["id"] != `a"1` {
+		t.Errorf("id argument = %#v, want the unescaped string", other.Selections[0].Args["id"])
+	}
+}
+
+func TestParseGraphQLRejectsUnsupportedDocuments(t *testing.T) {
+	tests := map[string]string{
+		"mutation":           `mutation { deleteAsset(id: "a") { id } }`,
+		"fragment spread":    `{ assets { ...fields } }`,
+		"fragment":           `fragment fields on Asset { id }`,
+		"directive":          `{ assets @include(if: true) { id } }`,
+		"empty selection":    `{ assets { } }`,
+		"unterminated":       `{ asset(id: "a) { id } }`,
+		"unclosed selection": `{ assets { id }`,
+		"variable default":   `query ($a: Int = $b) { assets { id } }`,
+		"several operations": `query A { assets { id } } query B { assets { id } }`,
+	}
+	for name, query := range tests {
+		if _, err := parseGraphQL(query, ""); err == nil {
+			t.Errorf("%s: parseGraphQL(%q) succeeded, want an error", name, query)
+		}
+	}
+	if _, err := parseGraphQL(`query A { assets { id } }`, "B"); err == nil {
+		t.Error("parseGraphQL with an unknown operation name succeeded, want an error")
+	}
+}
+
+// graphQLStore serves a fixed set of assets and counts the store calls made
+type graphQLStore struct {
+	GraphStore
+	assets []models.Asset
+
+	assetFindings int32 // GetAssetFindings calls
+	assetRisk     int32 // GetAssetRisk calls
+	batchFindings int32 // GetFindingsForAssets calls
+	batchRisks    int32 // GetAssetRisks calls
+	neighborLoads int32 // GetNeighbors calls
+}
+
+func (s *graphQLStore) ListAssets(ctx context.Context, filter models.AssetFilter) ([]models.Asset, error) {
+	return s.assets, nil
+}
+
+func (s *graphQLStore) GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	atomic.AddInt32(&s.assetFindings, 1)
+	return nil, nil
+}
+
+func (s *graphQLStore) GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error) {
+	atomic.AddInt32(&s.assetRisk, 1)
+	return models.RiskScore{}, nil
+}
+
+func (s *graphQLStore) GetFindingsForAssets(ctx context.Context, assetIDs []string) (map[string][]models.Finding, error) {
+	atomic.AddInt32(&s.batchFindings, 1)
+	findings := make(map[string][]models.Finding)
+	for _, id := range assetIDs {
+		finding := models.Finding{AssetID: id, Severity: 5}
+		finding.ID = "finding-" + id
+		findings[id] = []models.Finding{finding}
+	}
+	return findings, nil
+}
+
+func (s *graphQLStore) GetAssetRisks(ctx context.Context, assetIDs []string) (map[string]models.RiskScore, error) {
+	atomic.AddInt32(&s.batchRisks, 1)
+	risks := make(map[string]models.RiskScore)
+	for _, id := range assetIDs {
+		risks[id] = models.RiskScore{AssetID: id, Score: 6, LastCalculated: time.Now()}
+	}
+	return risks, nil
+}
+
+func (s *graphQLStore) GetNeighbors(ctx context.Context, assetID string, direction string, maxDepth int) ([]models.Asset, []models.Relationship, error) {
+	atomic.AddInt32(&s.neighborLoads, 1)
+	return nil, nil, nil
+}
+
+func newGraphQLStore(ids ...string) *graphQLStore {
+	store := &graphQLStore{}
+	for _, id := range ids {
+		asset := &models.Compute{}
+		asset.ID = id
+		asset.Type = models.AssetTypeCompute
+		store.assets = append(store.assets, asset)
+	}
+	return store
+}
+
+func runGraphQL(t *testing.T, g *Gateway, query string) (int, GraphQLResponse) {
+	t.Helper()
+	body, _ := json.Marshal(GraphQLRequest{Query: query})
+	w := httptest.NewRecorder()
+	g.handleGraphQL(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+
+	var response GraphQLResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response %s: %v", w.Body.String(), err)
+	}
+	return w.Code, response
+}
+
+func TestGraphQLBatchesFindingsAndRisk(t *testing.T) {
+	store := newGraphQLStore("vm-1", "vm-2", "vm-3")
+	g := NewGateway(DefaultGatewayConfig(), store, nil, nil)
+
+	status, response := runGraphQL(t, g, `{ assets { id findings { id } risk { score } neighbors { id } } }`)
+	if status != http.StatusOK || len(response.Errors) > 0 {
+		t.Fatalf("status = %d, errors = %v", status, response.Errors)
+	}
+	if store.batchFindings != 1 || store.batchRisks != 1 {
+		t.Errorf("batched loads = %d findings and %d risk, want one of each", store.batchFindings, store.batchRisks)
+	}
+	if store.assetFindings != 0 || store.assetRisk != 0 {
+		t.Errorf("per-asset loads = %d findings and %d risk, want none", store.assetFindings, store.assetRisk)
+	}
+	if store.neighborLoads != 3 {
+		t.Errorf("neighbor loads = %d, want one per asset (3)", store.neighborLoads)
+	}
+
+	data, _ := response.Data.(map[string]interface{})
+	assets, _ := data["assets"].([]interface{})
+	if len(assets) != 3 {
+		t.Fatalf("returned %d assets, want 3", len(assets))
+	}
+	second, _ := assets[1].(map[string]interface{})
+	findings, _ := second["findings"].([]interface{})
+	if len(findings) != 1 || findings[0].(map[string]interface{})["id"] != "finding-vm-2" {
+		t.Errorf("findings of vm-2 = %v", second["findings"])
+	}
+	if risk, _ := second["risk"].(map[string]interface{}); risk["score"] != 6.0 {
+		t.Errorf("risk of vm-2 = %v", second["risk"])
+	}
+}
+
+func TestGraphQLRejectsDeepQueries(t *testing.T) {
+	store := newGraphQLStore("vm-1")
+	config := DefaultGatewayConfig()
+	config.GraphQLMaxDepth = 3
+	g := NewGateway(config, store, nil, nil)
+
+	status, response := runGraphQL(t, g, `{ assets { neighbors { neighbors { id } } } }`)
+	if status != http.StatusBadRequest || len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, "depth 4") {
+		t.Errorf("status = %d, errors = %v, want the query rejected for its depth", status, response.Errors)
+	}
+	if store.neighborLoads != 0 {
+		t.Errorf("a rejected query loaded neighbors %d times", store.neighborLoads)
+	}
+}
+
+func TestGraphQLRejectsCostlyQueries(t *testing.T) {
+	store := newGraphQLStore("vm-1")
+	g := NewGateway(DefaultGatewayConfig(), store, nil, nil)
+
+	status, response := runGraphQL(t, g, `{ assets(limit: 500) { neighbors(depth: 3) { neighbors(depth: 3) { id } } } }`)
+	if status != http.StatusBadRequest || len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, "cost") {
+		t.Errorf("status = %d, errors = %v, want the query rejected for its cost", status, response.Errors)
+	}
+	if store.neighborLoads != 0 {
+		t.Errorf("a rejected query loaded neighbors %d times", store.neighborLoads)
+	}
+
+	// The same shape within the budget runs
+	if status, response := runGraphQL(t, g, `{ assets(limit: 50) { neighbors { id } } }`); status != http.StatusOK {
+		t.Errorf("status = %d, errors = %v, want a query within the cost limit to run", status, response.Errors)
+	}
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/pkg/models"
+)
+
+const (
+	defaultGraphViewNodes = 200
+	maxGraphViewNodes     = 1000
+)
+
+// GraphView is a layout-ready subgraph for the frontend
+type GraphView struct {
+	Nodes     []GraphViewNode `json:"nodes"`
+	Edges     []GraphViewEdge `json:"edges"`
+	Focus     string          `json:"focus,omitempty"`
+	Truncated bool            `json:"truncated"`
+}
+
+// GraphViewNode is a compact node with layout hints. Group clusters nodes of
+// the same type and Ring is the hop distance from the focus asset, so the
+// frontend can lay out concentric rings without recomputing the topology.
+type GraphViewNode struct {
+	ID          string             `json:"id"`
+	Label       string             `json:"label"`
+	Type        models.AssetType   `json:"type"`
+	Provider    models.Provider    `json:"provider,omitempty"`
+	Environment models.Environment `json:"environment,omitempty"`
+	Group       string             `json:"group"`
+	Ring        int                `json:"ring"`
+	Degree      int                `json:"degree"`
+	RiskScore   float64            `json:"risk_score"`
+	RiskBand    models.RiskLevel   `json:"risk_band"`
+}
+
+// GraphViewEdge is a compact edge. Weight combines relationship strength with
+// the risk of the endpoints and drives edge coloring.
+type GraphViewEdge struct {
+	ID       string                  `json:"id"`
+	Source   string                  `json:"source"`
+	Target   string                  `json:"target"`
+	Type     models.RelationshipType `json:"type"`
+	Weight   float64                 `json:"weight"`
+	RiskBand models.RiskLevel        `json:"risk_band"`
+}
+
+func (g *Gateway) handleGraphView(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultGraphViewNodes
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > maxGraphViewNodes {
+		limit = maxGraphViewNodes
+	}
+
+	ctx, resultInfo := graph.WithResultInfo(r.Context())
+
+	var (
+		assets        []models.Asset
+		relationships []models.Relationship
+		focus         = query.Get("asset_id")
+		err           error
+	)
+
+	if focus != "" {
+		direction := query.Get("direction")
+		if direction == "" {
+			direction = "both"
+		}
+
+		maxDepth := 1
+		if depth := query.Get("max_depth"); depth != "" {
+			if d, err := strconv.Atoi(depth); err == nil && d > 0 {
+				maxDepth = d
+			}
+		}
+
+		root, err := g.graphStore.GetAsset(r.Context(), focus)
+		if err != nil {
+			writeError(w, err, "Failed to get asset")
+			return
+		}
+
+		assets, relationships, err = g.graphStore.GetNeighbors(r.Context(), focus, direction, maxDepth)
+		if err != nil {
+			writeError(w, err, "Failed to get neighbors")
+			return
+		}
+		assets = append([]models.Asset{root}, assets...)
+	} else {
+		filter := models.AssetFilter{Limit: limit + 1}
+		for _, t := range query["type"] {
+			filter.Types = append(filter.Types, models.AssetType(t))
+		}
+		for _, p := range query["provider"] {
+			filter.Providers = append(filter.Providers, models.Provider(p))
+		}
+		for _, e := range query["environment"] {
+			filter.Environments = append(filter.Environments, models.Environment(e))
+		}
+		if minRisk := query.Get("min_risk_score"); minRisk != "" {
+			if score, err := strconv.ParseFloat(minRisk, 64); err == nil {
+				filter.MinRiskScore = score
+			}
+		}
+
+		assets, err = g.graphStore.ListAssets(r.Context(), filter)
+		if err != nil {
+			writeError(w, err, "Failed to list assets")
+			return
+		}
+
+		if len(assets) > 0 {
+			ids := make([]string, len(assets))
+			for i, asset := range assets {
+				ids[i] = asset.GetID()
+			}
+			relationships, err = g.graphStore.ListRelationships(ctx, models.RelationshipFilter{AssetIDs: ids, ActiveOnly: true})
+			if err != nil {
+				writeError(w, err, "Failed to list relationships")
+				return
+			}
+		}
+	}
+
+	view := g.buildGraphView(focus, assets, relationships, limit)
+	view.Truncated = view.Truncated || resultInfo.Truncated
+	writeSuccessResponse(w, view, nil)
+}
+
+// buildGraphView assembles a view of at most limit nodes, keeping the nodes
+// closest to the focus asset and dropping edges to nodes that were cut
+func (g *Gateway) buildGraphView(focus string, assets []models.Asset, relationships []models.Relationship, limit int) GraphView {
+	view := GraphView{Focus: focus, Nodes: []GraphViewNode{}, Edges: []GraphViewEdge{}}
+
+	rings := graphViewRings(focus, relationships)
+	outerRing := 0
+	for _, ring := range rings {
+		if ring >= outerRing {
+			outerRing = ring + 1
+		}
+	}
+
+	seen := make(map[string]bool, len(assets))
+	for _, asset := range assets {
+		id := asset.GetID()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		// Nodes unreachable through the returned edges go on an outer ring
+		ring, ok := rings[id]
+		if !ok {
+			ring = outerRing
+		}
+
+		view.Nodes = append(view.Nodes, GraphViewNode{
+			ID:          id,
+			Label:       asset.GetName(),
+			Type:        asset.GetType(),
+			Provider:    asset.GetProvider(),
+			Environment: asset.GetEnvironment(),
+			Group:       string(asset.GetType()),
+			Ring:        ring,
+			RiskScore:   asset.GetBaseAsset().RiskScore,
+		})
+	}
+
+	sort.SliceStable(view.Nodes, func(i, j int) bool {
+		return view.Nodes[i].Ring < view.Nodes[j].Ring
+	})
+	if len(view.Nodes) > limit {
+		view.Nodes = view.Nodes[:limit]
+		view.Truncated = true
+	}
+
+	index := make(map[string]int, len(view.Nodes))
+	for i := range view.Nodes {
+		node := &view.Nodes[i]
+		index[node.ID] = i
+		node.RiskBand = g.riskLevel(node.Type, node.RiskScore)
+	}
+
+	edges := make(map[string]bool, len(relationships))
+	for _, rel := range relationships {
+		from, fromOK := index[rel.FromAssetID]
+		to, toOK := index[rel.ToAssetID]
+		if !fromOK || !toOK {
+			if fromOK || toOK {
+				view.Truncated = true
+			}
+			continue
+		}
+		if edges[rel.ID] {
+			continue
+		}
+		edges[rel.ID] = true
+
+		view.Nodes[from].Degree++
+		view.Nodes[to].Degree++
+
+		strength := rel.Strength
+		if strength == 0 {
+			strength = 1
+		}
+		risk := view.Nodes[from].RiskScore
+		if view.Nodes[to].RiskScore > risk {
+			risk = view.Nodes[to].RiskScore
+		}
+		weight := strength * risk / 100
+
+		view.Edges = append(view.Edges, GraphViewEdge{
+			ID:       rel.ID,
+			Source:   rel.FromAssetID,
+			Target:   rel.ToAssetID,
+			Type:     rel.Type,
+			Weight:   weight,
+			RiskBand: g.riskLevel("", weight*100),
+		})
+	}
+
+	return view
+}
+
+// riskLevel maps a score to a risk level with the risk engine's mapping for
+// the asset type when it has one, and the default thresholds otherwise
+func (g *Gateway) riskLevel(assetType models.AssetType, score float64) models.RiskLevel {
+	if mapper, ok := g.riskEngine.(interface {
+		RiskLevel(assetType models.AssetType, score float64) models.RiskLevel
+	}); ok {
+		return mapper.RiskLevel(assetType, score)
+	}
+	return models.GetRiskLevel(score)
+}
+
+// graphViewRings computes the undirected hop distance of each node from focus
+func graphViewRings(focus string, relationships []models.Relationship) map[string]int {
+	rings := make(map[string]int)
+	if focus == "" {
+		return rings
+	}
+
+	adjacency := make(map[string][]string)
+	for _, rel := range relationships {
+		adjacency[rel.FromAssetID] = append(adjacency[rel.FromAssetID], rel.ToAssetID)
+		adjacency[rel.ToAssetID] = append(adjacency[rel.ToAssetID], rel.FromAssetID)
+	}
+
+	rings[focus] = 0
+	frontier := []string{focus}
+	for len(frontier) > 0 {
+		var next []string
+		for _, id := range frontier {
+			for _, neighbor := range adjacency[id] {
+				if _, ok := rings[neighbor]; ok {
+					continue
+				}
+				rings[neighbor] = rings[id] + 1
+				next = append(next, neighbor)
+			}
+		}
+		frontier = next
+	}
+
+	return rings
+}
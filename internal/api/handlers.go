@@ -2,106 +2,149 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
+	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/pkg/apperrors"
 	"github.com/securizon/pkg/models"
 )
 
 // Asset handlers
 
-func (g *Gateway) handleListAssets(w http.ResponseWriter, r *http.Request) {
-	var req ListAssetsRequest
+// assetFilterFromQuery reads an asset filter from list query parameters
+func assetFilterFromQuery(query url.Values) (models.AssetFilter, error) {
+	var filter models.AssetFilter
 	
-	// Parse query parameters
-	if types := r.URL.Query()["type"]; len(types) > 0 {
-		req.Types = make([]models.AssetType, len(types))
-		for i, t := range types {
-			req.Types[i] = models.AssetType(t)
-		}
+	for _, t := range query["type"] {
+		filter.Types = append(filter.Types, models.AssetType(t))
 	}
-	
-	if providers := r.URL.Query()["provider"]; len(providers) > 0 {
-		req.Providers = make([]models.Provider, len(providers))
-		for i, p := range providers {
-			req.Providers[i] = models.Provider(p)
-		}
+	for _, p := range query["provider"] {
+		filter.Providers = append(filter.Providers, models.Provider(p))
 	}
-	
-	if environments := r.URL.Query()["environment"]; len(environments) > 0 {
-		req.Environments = make([]models.Environment, len(environments))
-		for i, e := range environments {
-			req.Environments[i] = models.Environment(e)
-		}
+	for _, e := range query["environment"] {
+		filter.Environments = append(filter.Environments, models.Environment(e))
 	}
 	
-	if minRisk := r.URL.Query().Get("min_risk_score"); minRisk != "" {
+	if minRisk := query.Get("min_risk_score"); minRisk != "" {
 		if score, err := strconv.ParseFloat(minRisk, 64); err == nil {
-			req.MinRiskScore = score
+			filter.MinRiskScore = score
 		}
 	}
 	
-	if maxRisk := r.URL.Query().Get("max_risk_score"); maxRisk != "" {
+	if maxRisk := query.Get("max_risk_score"); maxRisk != "" {
 		if score, err := strconv.ParseFloat(maxRisk, 64); err == nil {
-			req.MaxRiskScore = score
+			filter.MaxRiskScore = score
 		}
 	}
 	
-	if limit := r.URL.Query().Get("limit"); limit != "" {
+	// First-seen bounds are RFC 3339 timestamps, e.g. to find assets that
+	// appeared in the last week
+	for param, target := range map[string]*time.Time{
+		"first_seen_after":  &filter.FirstSeenAfter,
+		"first_seen_before": &filter.FirstSeenBefore,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, apperrors.Invalid("invalid %s: %v", param, err)
+		}
+		*target = t
+	}
+	
+	if limit := query.Get("limit"); limit != "" {
 		if l, err := strconv.Atoi(limit); err == nil {
-			req.Limit = l
+			filter.Limit = l
 		}
 	}
 	
-	if offset := r.URL.Query().Get("offset"); offset != "" {
+	if offset := query.Get("offset"); offset != "" {
 		if o, err := strconv.Atoi(offset); err == nil {
-			req.Offset = o
+			filter.Offset = o
 		}
 	}
 	
-	// Create filter
-	filter := models.AssetFilter{
-		Types:        req.Types,
-		Providers:    req.Providers,
-		Environments: req.Environments,
-		MinRiskScore: req.MinRiskScore,
-		MaxRiskScore: req.MaxRiskScore,
-		Limit:        req.Limit,
-		Offset:       req.Offset,
+	return filter, nil
+}
+
+func (g *Gateway) handleListAssets(w http.ResponseWriter, r *http.Request) {
+	filter, err := assetFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid asset filter", apperrors.MessageOf(err))
+		return
 	}
 	
-	// Get assets
-	assets, err := g.graphStore.ListAssets(r.Context(), filter)
-	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list assets", err.Error())
+	// Unbounded listings are streamed rather than buffered in memory
+	if streamer, ok := g.graphStore.(GraphStreamer); ok && filter.Limit == 0 {
+		assets, errs := streamer.StreamAssets(r.Context(), filter)
+		sw := newStreamWriter(w)
+		for asset := range assets {
+			if err := sw.Write(asset); err != nil {
+				log.Printf("Failed to stream assets: %v", err)
+				return
+			}
+		}
+		sw.Close(<-errs)
 		return
 	}
 	
-	// Prepare response meta
-	meta := &APIMeta{
-		Total:  len(assets),
-		Limit:  req.Limit,
-		Offset: req.Offset,
+	// Get assets
+	ctx, resultInfo := graph.WithResultInfo(r.Context())
+	assets, err := g.graphStore.ListAssets(ctx, filter)
+	if err != nil {
+		writeError(w, err, "Failed to list assets")
+		return
 	}
 	
-	if req.Limit > 0 && len(assets) == req.Limit {
-		meta.HasMore = true
+	total := -1
+	if counter, ok := g.graphStore.(GraphCounter); ok {
+		if total, err = counter.CountAssets(r.Context(), filter); err != nil {
+			log.Printf("Failed to count assets: %v", err)
+			total = -1
+		}
 	}
 	
-	writeSuccessResponse(w, assets, meta)
+	writeListResponse(w, assets, newPagination(total, filter.Limit, filter.Offset, len(assets)), resultInfo)
 }
 
 func (g *Gateway) handleCreateAsset(w http.ResponseWriter, r *http.Request) {
 	var req CreateAssetRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
+		return
+	}
+	
+	if req.Upsert {
+		upserter, ok := g.graphStore.(AssetUpserter)
+		if !ok {
+			writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Asset upsert is not supported", "")
+			return
+		}
+		
+		created, err := upserter.UpsertAsset(r.Context(), req.Asset)
+		if err != nil {
+			writeError(w, err, "Failed to upsert asset")
+			return
+		}
+		
+		writeSuccessResponse(w, UpsertAssetResponse{Asset: req.Asset, Created: created}, nil)
 		return
 	}
 	
-	// Create asset
+	// Create asset; an existing id is reported as a conflict
 	if err := g.graphStore.CreateAsset(r.Context(), req.Asset); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create asset", err.Error())
+		writeError(w, err, "Failed to create asset")
 		return
 	}
 	
@@ -114,10 +157,17 @@ func (g *Gateway) handleGetAsset(w http.ResponseWriter, r *http.Request) {
 	
 	asset, err := g.graphStore.GetAsset(r.Context(), assetID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "Asset not found", err.Error())
+		writeError(w, err, "Failed to get asset")
 		return
 	}
 	
+	// The version lets clients make conditional patches
+	if patcher, ok := g.graphStore.(DocumentPatcher); ok {
+		if version, err := patcher.GetVersion(r.Context(), assetID); err == nil {
+			setVersionETag(w, version)
+		}
+	}
+	
 	writeSuccessResponse(w, asset, nil)
 }
 
@@ -127,19 +177,19 @@ func (g *Gateway) handleUpdateAsset(w http.ResponseWriter, r *http.Request) {
 	
 	var req UpdateAssetRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
 	// Verify asset ID matches
 	if req.Asset.GetID() != assetID {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Asset ID mismatch", "")
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Asset ID mismatch", "")
 		return
 	}
 	
 	// Update asset
 	if err := g.graphStore.UpdateAsset(r.Context(), req.Asset); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update asset", err.Error())
+		writeError(w, err, "Failed to update asset")
 		return
 	}
 	
@@ -151,7 +201,7 @@ func (g *Gateway) handleDeleteAsset(w http.ResponseWriter, r *http.Request) {
 	assetID := vars["id"]
 	
 	if err := g.graphStore.DeleteAsset(r.Context(), assetID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete asset", err.Error())
+		writeError(w, err, "Failed to delete asset")
 		return
 	}
 	
@@ -161,7 +211,7 @@ func (g *Gateway) handleDeleteAsset(w http.ResponseWriter, r *http.Request) {
 func (g *Gateway) handleSearchAssets(w http.ResponseWriter, r *http.Request) {
 	var req SearchAssetsRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
@@ -179,7 +229,7 @@ func (g *Gateway) handleSearchAssets(w http.ResponseWriter, r *http.Request) {
 	// Search assets
 	assets, err := g.graphStore.SearchAssets(r.Context(), query)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to search assets", err.Error())
+		writeError(w, err, "Failed to search assets")
 		return
 	}
 	
@@ -206,7 +256,7 @@ func (g *Gateway) handleGetNeighbors(w http.ResponseWriter, r *http.Request) {
 	// Get neighbors
 	assets, relationships, err := g.graphStore.GetNeighbors(r.Context(), assetID, direction, maxDepth)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get neighbors", err.Error())
+		writeError(w, err, "Failed to get neighbors")
 		return
 	}
 	
@@ -224,20 +274,36 @@ func (g *Gateway) handleGetAssetRisk(w http.ResponseWriter, r *http.Request) {
 	
 	risk, err := g.graphStore.GetAssetRisk(r.Context(), assetID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get asset risk", err.Error())
+		writeError(w, err, "Failed to get asset risk")
 		return
 	}
+	if !risk.LastCalculated.IsZero() {
+		risk.Level = g.riskLevel(risk.AssetType, risk.Score)
+	}
 	
 	writeSuccessResponse(w, risk, nil)
 }
 
+func (g *Gateway) handleExplainAssetRisk(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	assetID := vars["id"]
+	
+	explanation, err := g.riskEngine.ExplainRisk(r.Context(), assetID)
+	if err != nil {
+		writeError(w, err, "Failed to explain asset risk")
+		return
+	}
+	
+	writeSuccessResponse(w, explanation, nil)
+}
+
 func (g *Gateway) handleGetAssetFindings(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	assetID := vars["id"]
 	
 	findings, err := g.graphStore.GetAssetFindings(r.Context(), assetID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get asset findings", err.Error())
+		writeError(w, err, "Failed to get asset findings")
 		return
 	}
 	
@@ -279,6 +345,28 @@ func (g *Gateway) handleListRelationships(w http.ResponseWriter, r *http.Request
 		}
 	}
 	
+	if offset := r.URL.Query().Get("offset"); offset != "" {
+		if o, err := strconv.Atoi(offset); err == nil {
+			req.Offset = o
+		}
+	}
+	
+	// A cursor continues a previous page and replaces the offset
+	cursor := r.URL.Query().Get("cursor")
+	var after *models.RelationshipCursor
+	if cursor != "" {
+		position, err := decodeRelationshipCursor(cursor)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid cursor", err.Error())
+			return
+		}
+		after = &position
+		req.Offset = 0
+		if req.Limit == 0 {
+			req.Limit = defaultCursorPageSize
+		}
+	}
+	
 	// Create filter
 	filter := models.RelationshipFilter{
 		AssetIDs:    req.AssetIDs,
@@ -286,28 +374,92 @@ func (g *Gateway) handleListRelationships(w http.ResponseWriter, r *http.Request
 		MinStrength: req.MinStrength,
 		MaxStrength: req.MaxStrength,
 		ActiveOnly:  true,
+		Limit:       req.Limit,
+		Offset:      req.Offset,
+		After:       after,
+	}
+	
+	// Unbounded listings are streamed rather than buffered in memory
+	if streamer, ok := g.graphStore.(GraphStreamer); ok && req.Limit == 0 {
+		relationships, errs := streamer.StreamRelationships(r.Context(), filter)
+		sw := newStreamWriter(w)
+		for rel := range relationships {
+			if err := sw.Write(rel); err != nil {
+				log.Printf("Failed to stream relationships: %v", err)
+				return
+			}
+		}
+		sw.Close(<-errs)
+		return
 	}
 	
 	// Get relationships
-	relationships, err := g.graphStore.ListRelationships(r.Context(), filter)
+	ctx, resultInfo := graph.WithResultInfo(r.Context())
+	relationships, err := g.graphStore.ListRelationships(ctx, filter)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list relationships", err.Error())
+		writeError(w, err, "Failed to list relationships")
 		return
 	}
 	
-	writeSuccessResponse(w, relationships, nil)
+	total := -1
+	if counter, ok := g.graphStore.(GraphCounter); ok {
+		if total, err = counter.CountRelationships(r.Context(), filter); err != nil {
+			log.Printf("Failed to count relationships: %v", err)
+			total = -1
+		}
+	}
+	
+	page := newPagination(total, req.Limit, req.Offset, len(relationships))
+	if after != nil {
+		// The total counts the whole listing, so only a short page tells
+		// that a cursor has reached its end
+		page.Cursor = cursor
+		page.Offset = 0
+		page.HasMore = len(relationships) == req.Limit
+	}
+	if page.HasMore && len(relationships) > 0 {
+		page.NextCursor = encodeRelationshipCursor(models.CursorOf(relationships[len(relationships)-1]))
+	}
+	
+	writeListResponse(w, relationships, page, resultInfo)
+}
+
+// defaultCursorPageSize is the page size of cursor-paged listings that do
+// not set a limit
+const defaultCursorPageSize = 100
+
+// encodeRelationshipCursor encodes a listing position as an opaque cursor
+func encodeRelationshipCursor(position models.RelationshipCursor) string {
+	data, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeRelationshipCursor decodes a cursor from encodeRelationshipCursor
+func decodeRelationshipCursor(cursor string) (models.RelationshipCursor, error) {
+	var position models.RelationshipCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return position, fmt.Errorf("cursor is not valid base64")
+	}
+	if err := json.Unmarshal(data, &position); err != nil || position.ID == "" {
+		return position, fmt.Errorf("cursor is malformed")
+	}
+	return position, nil
 }
 
 func (g *Gateway) handleCreateRelationship(w http.ResponseWriter, r *http.Request) {
 	var req CreateRelationshipRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
+	// The store keys relationships by endpoints and type
+	req.Relationship.ID = req.Relationship.CanonicalID()
+	
 	// Create relationship
 	if err := g.graphStore.CreateRelationship(r.Context(), req.Relationship); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create relationship", err.Error())
+		writeError(w, err, "Failed to create relationship")
 		return
 	}
 	
@@ -320,7 +472,7 @@ func (g *Gateway) handleGetRelationship(w http.ResponseWriter, r *http.Request)
 	
 	relationship, err := g.graphStore.GetRelationship(r.Context(), relationshipID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "Relationship not found", err.Error())
+		writeError(w, err, "Failed to get relationship")
 		return
 	}
 	
@@ -333,19 +485,19 @@ func (g *Gateway) handleUpdateRelationship(w http.ResponseWriter, r *http.Reques
 	
 	var req UpdateRelationshipRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
 	// Verify relationship ID matches
 	if req.Relationship.ID != relationshipID {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Relationship ID mismatch", "")
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Relationship ID mismatch", "")
 		return
 	}
 	
 	// Update relationship
 	if err := g.graphStore.UpdateRelationship(r.Context(), req.Relationship); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update relationship", err.Error())
+		writeError(w, err, "Failed to update relationship")
 		return
 	}
 	
@@ -357,7 +509,7 @@ func (g *Gateway) handleDeleteRelationship(w http.ResponseWriter, r *http.Reques
 	relationshipID := vars["id"]
 	
 	if err := g.graphStore.DeleteRelationship(r.Context(), relationshipID); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to delete relationship", err.Error())
+		writeError(w, err, "Failed to delete relationship")
 		return
 	}
 	
@@ -367,7 +519,7 @@ func (g *Gateway) handleDeleteRelationship(w http.ResponseWriter, r *http.Reques
 func (g *Gateway) handleSearchRelationships(w http.ResponseWriter, r *http.Request) {
 	var req SearchRelationshipsRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
@@ -385,7 +537,7 @@ func (g *Gateway) handleSearchRelationships(w http.ResponseWriter, r *http.Reque
 	// Search relationships
 	relationships, err := g.graphStore.SearchRelationships(r.Context(), query)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to search relationships", err.Error())
+		writeError(w, err, "Failed to search relationships")
 		return
 	}
 	
@@ -402,41 +554,65 @@ func (g *Gateway) handleListFindings(w http.ResponseWriter, r *http.Request) {
 		filter.Statuses = statuses
 	}
 	
-	if severities := r.URL.Query()["severity"]; len(severities) > 0 {
-		filter.Severities = make([]float64, len(severities))
-		for i, s := range severities {
-			if severity, err := strconv.ParseFloat(s, 64); err == nil {
-				filter.Severities[i] = severity
-			}
+	// severity is the older name for severity_min and is still accepted
+	minParam := "severity_min"
+	if r.URL.Query().Get(minParam) == "" && r.URL.Query().Get("severity") != "" {
+		minParam = "severity"
+	}
+	for param, target := range map[string]*float64{
+		minParam:       &filter.MinSeverity,
+		"severity_max": &filter.MaxSeverity,
+	} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
 		}
+		severity, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid "+param, err.Error())
+			return
+		}
+		*target = severity
 	}
 	
 	if assetIDs := r.URL.Query()["asset_id"]; len(assetIDs) > 0 {
 		filter.AssetIDs = assetIDs
 	}
 	
+	if policyIDs := r.URL.Query()["policy_id"]; len(policyIDs) > 0 {
+		filter.PolicyIDs = policyIDs
+	}
+	
 	if limit := r.URL.Query().Get("limit"); limit != "" {
 		if l, err := strconv.Atoi(limit); err == nil {
 			filter.Limit = l
 		}
 	}
 	
-	// Get findings (this would need to be implemented in the graph store)
-	findings := []models.Finding{} // Placeholder
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		filter.Offset = o
+	}
 	
-	writeSuccessResponse(w, findings, nil)
+	ctx, resultInfo := graph.WithResultInfo(r.Context())
+	findings, err := g.graphStore.ListFindings(ctx, filter)
+	if err != nil {
+		writeError(w, err, "Failed to list findings")
+		return
+	}
+	
+	writeListResponse(w, findings, newPagination(-1, filter.Limit, filter.Offset, len(findings)), resultInfo)
 }
 
 func (g *Gateway) handleCreateFinding(w http.ResponseWriter, r *http.Request) {
 	var req CreateFindingRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
 	// Create finding
 	if err := g.graphStore.CreateFinding(r.Context(), req.Finding); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create finding", err.Error())
+		writeError(w, err, "Failed to create finding")
 		return
 	}
 	
@@ -447,8 +623,11 @@ func (g *Gateway) handleGetFinding(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	findingID := vars["id"]
 	
-	// Get finding (this would need to be implemented in the graph store)
-	finding := models.Finding{ID: findingID} // Placeholder
+	finding, err := g.graphStore.GetFinding(r.Context(), findingID)
+	if err != nil {
+		writeError(w, err, "Failed to get finding")
+		return
+	}
 	
 	writeSuccessResponse(w, finding, nil)
 }
@@ -459,19 +638,19 @@ func (g *Gateway) handleUpdateFinding(w http.ResponseWriter, r *http.Request) {
 	
 	var req UpdateFindingRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
 	// Verify finding ID matches
 	if req.Finding.ID != findingID {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Finding ID mismatch", "")
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Finding ID mismatch", "")
 		return
 	}
 	
 	// Update finding
 	if err := g.graphStore.UpdateFinding(r.Context(), req.Finding); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to update finding", err.Error())
+		writeError(w, err, "Failed to update finding")
 		return
 	}
 	
@@ -482,23 +661,139 @@ func (g *Gateway) handleResolveFinding(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	findingID := vars["id"]
 	
-	// Get finding
-	finding, err := g.graphStore.GetAssetFindings(r.Context(), findingID)
+	finding, err := g.graphStore.ResolveFinding(r.Context(), findingID)
 	if err != nil {
-		writeErrorResponse(w, http.StatusNotFound, "NOT_FOUND", "Finding not found", err.Error())
+		writeError(w, err, "Failed to resolve finding")
 		return
 	}
 	
-	// Update status to resolved
-	if len(finding) > 0 {
-		finding[0].Status = "resolved"
-		if err := g.graphStore.UpdateFinding(r.Context(), finding[0]); err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to resolve finding", err.Error())
-			return
+	// The finding is resolved either way; a lost event only delays the
+	// asset's risk recalculation until its next update
+	if err := g.publishFindingResolved(r.Context(), finding); err != nil {
+		log.Printf("Failed to publish resolution of finding %s: %v", finding.ID, err)
+	}
+	
+	writeSuccessResponse(w, finding, nil)
+}
+
+// publishFindingResolved emits a finding.resolved event so the event
+// processor recalculates the risk of the finding's asset
+func (g *Gateway) publishFindingResolved(ctx context.Context, finding models.Finding) error {
+	event := models.FindingEvent{
+		BaseEvent: models.NewBaseEvent(models.EventTypeFindingResolved, finding.Provider, finding.Environment, "api", "Finding resolved"),
+		Finding:   finding,
+	}
+	event.AssetID = finding.AssetID
+	
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	event.RawData = data
+	return g.eventBus.PublishEvent(ctx, events.TopicFindings, event.BaseEvent)
+}
+
+func (g *Gateway) handleGetFindingGroups(w http.ResponseWriter, r *http.Request) {
+	grouper, ok := g.graphStore.(FindingGrouper)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Finding grouping is not supported", "")
+		return
+	}
+	
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "open"
+	} else if status == "all" {
+		status = ""
+	}
+	
+	minSize := 2
+	if size := r.URL.Query().Get("min_size"); size != "" {
+		if s, err := strconv.Atoi(size); err == nil && s > 0 {
+			minSize = s
 		}
 	}
 	
-	writeSuccessResponse(w, map[string]string{"id": findingID, "status": "resolved"}, nil)
+	groups, err := grouper.GetFindingGroups(r.Context(), status, minSize)
+	if err != nil {
+		writeError(w, err, "Failed to group findings")
+		return
+	}
+	
+	writeListResponse(w, groups, newPagination(len(groups), 0, 0, len(groups)), nil)
+}
+
+func (g *Gateway) handleFindingFeedback(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	findingID := vars["id"]
+	
+	store, ok := g.graphStore.(FindingFeedbackStore)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Finding feedback is not supported", "")
+		return
+	}
+	
+	var req FindingFeedbackRequest
+	if err := parseRequestBody(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
+		return
+	}
+	
+	feedback := models.FindingFeedback{
+		FindingID:   findingID,
+		Verdict:     req.Verdict,
+		Reason:      req.Reason,
+		SubmittedBy: req.SubmittedBy,
+		CreatedAt:   time.Now(),
+	}
+	if err := feedback.Validate(); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid feedback", err.Error())
+		return
+	}
+	
+	finding, err := store.RecordFindingFeedback(r.Context(), feedback)
+	if err != nil {
+		writeError(w, err, "Failed to record feedback")
+		return
+	}
+	
+	writeSuccessResponse(w, finding, nil)
+}
+
+// Policy handlers
+
+func (g *Gateway) handleListPolicyCategories(w http.ResponseWriter, r *http.Request) {
+	if g.policyCatalog != nil {
+		writeSuccessResponse(w, g.policyCatalog.Categories(), nil)
+		return
+	}
+	
+	registry, err := models.NewPolicyCategoryRegistry(nil)
+	if err != nil {
+		writeError(w, err, "Failed to load policy categories")
+		return
+	}
+	
+	writeSuccessResponse(w, registry.List(), nil)
+}
+
+func (g *Gateway) handleGetPolicyAccuracy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	policyID := vars["id"]
+	
+	store, ok := g.graphStore.(FindingFeedbackStore)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Policy accuracy is not supported", "")
+		return
+	}
+	
+	accuracy, err := store.GetPolicyAccuracy(r.Context(), policyID)
+	if err != nil {
+		writeError(w, err, "Failed to get policy accuracy")
+		return
+	}
+	
+	writeSuccessResponse(w, accuracy, nil)
 }
 
 // Risk handlers
@@ -506,7 +801,7 @@ func (g *Gateway) handleResolveFinding(w http.ResponseWriter, r *http.Request) {
 func (g *Gateway) handleGetRiskSummary(w http.ResponseWriter, r *http.Request) {
 	summary, err := g.riskEngine.GetRiskSummary(r.Context())
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get risk summary", err.Error())
+		writeError(w, err, "Failed to get risk summary")
 		return
 	}
 	
@@ -515,7 +810,7 @@ func (g *Gateway) handleGetRiskSummary(w http.ResponseWriter, r *http.Request) {
 
 func (g *Gateway) handleGetRiskTrends(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	assetID := vars["id"]
+	assetID := vars["assetId"]
 	
 	// Parse time range
 	startTime := time.Now().AddDate(0, -1, 0) // Default: last month
@@ -540,58 +835,54 @@ func (g *Gateway) handleGetRiskTrends(w http.ResponseWriter, r *http.Request) {
 	
 	trends, err := g.graphStore.GetRiskTrends(r.Context(), assetID, timeRange)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get risk trends", err.Error())
+		writeError(w, err, "Failed to get risk trends")
 		return
 	}
 	
 	writeSuccessResponse(w, trends, nil)
 }
 
+// handleRecalculateRisk starts an asynchronous recalculation of the given
+// assets, or of every asset, and returns the job to poll for progress
 func (g *Gateway) handleRecalculateRisk(w http.ResponseWriter, r *http.Request) {
 	var req RecalculateRiskRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
-	// If no asset IDs specified, recalculate all
-	if len(req.AssetIDs) == 0 {
-		// Get all assets and recalculate
-		assets, err := g.graphStore.ListAssets(r.Context(), models.AssetFilter{})
-		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to list assets", err.Error())
-			return
-		}
-		
-		for _, asset := range assets {
-			if _, err := g.riskEngine.RecalculateRisk(r.Context(), asset.GetID()); err != nil {
-				writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to recalculate risk", err.Error())
-				return
-			}
-		}
-	} else {
-		// Recalculate specified assets
-		for _, assetID := range req.AssetIDs {
-			if _, err := g.riskEngine.RecalculateRisk(r.Context(), assetID); err != nil {
-				writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to recalculate risk", err.Error())
-				return
-			}
-		}
+	// The job outlives the request but keeps its values, such as the tenant,
+	// and its concurrency slot until it finishes or is cancelled
+	job, ctx, err := g.recalcJobs.create(context.WithoutCancel(r.Context()), requestTenantID(r))
+	if err != nil {
+		writeError(w, err, "Failed to start recalculation")
+		return
 	}
+	snapshot, _ := g.recalcJobs.get(job.ID, requestTenantID(r))
 	
-	writeSuccessResponse(w, map[string]string{"message": "Risk recalculation started"}, nil)
+	release := holdQuotaSlot(r)
+	go func() {
+		defer release()
+		g.runRecalcJob(ctx, job, req.AssetIDs)
+	}()
+	
+	w.Header().Set("Location", "/api/v1/risk/recalculate/"+job.ID)
+	writeJSONResponse(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Data:    snapshot,
+	})
 }
 
 func (g *Gateway) handleBatchRecalculateRisk(w http.ResponseWriter, r *http.Request) {
 	var req BatchRecalculateRiskRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
 		return
 	}
 	
 	results, err := g.riskEngine.BatchRecalculateRisk(r.Context(), req.AssetIDs)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to batch recalculate risk", err.Error())
+		writeError(w, err, "Failed to batch recalculate risk")
 		return
 	}
 	
@@ -603,35 +894,121 @@ func (g *Gateway) handleBatchRecalculateRisk(w http.ResponseWriter, r *http.Requ
 func (g *Gateway) handleFindAttackPaths(w http.ResponseWriter, r *http.Request) {
 	var req FindAttackPathsRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
+		return
+	}
+	
+	ctx, explain, ok := g.explainContext(w, r)
+	if !ok {
 		return
 	}
 	
 	// Find attack paths
-	paths, err := g.graphStore.FindAttackPaths(r.Context(), req.EntryPoints, req.Targets, req.MaxDepth)
+	paths, err := g.graphStore.FindAttackPaths(ctx, req.EntryPoints, req.Targets, req.MaxDepth)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to find attack paths", err.Error())
+		writeError(w, err, "Failed to find attack paths")
 		return
 	}
+	if g.acceptedPaths != nil {
+		g.acceptedPaths.AnnotateGraphPaths(ctx, paths)
+	}
 	
-	writeSuccessResponse(w, paths, nil)
+	writeSuccessResponse(w, paths, explainMeta(explain))
 }
 
 func (g *Gateway) handleFindPath(w http.ResponseWriter, r *http.Request) {
 	var req FindPathRequest
 	if err := parseRequestBody(r, &req); err != nil {
-		writeErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse request body", err.Error())
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
+		return
+	}
+	
+	ctx, explain, ok := g.explainContext(w, r)
+	if !ok {
 		return
 	}
 	
 	// Find path
-	path, err := g.graphStore.FindPath(r.Context(), req.FromAssetID, req.ToAssetID, req.MaxDepth)
+	path, err := g.graphStore.FindPath(ctx, req.FromAssetID, req.ToAssetID, req.MaxDepth)
+	if err != nil {
+		// A missing path is exactly what explain mode is meant to diagnose
+		if explain != nil {
+			code := apperrors.CodeOf(err)
+			writeJSONResponse(w, apperrors.HTTPStatus(code), APIResponse{
+				Success: false,
+				Error:   &APIError{Code: string(code), Message: "Failed to find path", Details: err.Error()},
+				Meta:    explainMeta(explain),
+			})
+			return
+		}
+		writeError(w, err, "Failed to find path")
+		return
+	}
+	
+	writeSuccessResponse(w, path, explainMeta(explain))
+}
+
+func (g *Gateway) handleDiscoverAttackPaths(w http.ResponseWriter, r *http.Request) {
+	if g.attackPaths == nil {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Attack path discovery is not supported", "")
+		return
+	}
+	
+	var req DiscoverAttackPathsRequest
+	if err := parseRequestBody(r, &req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
+		return
+	}
+	
+	if err := graph.ValidateSelectors(req.EntryPoints); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid entry points", err.Error())
+		return
+	}
+	if err := graph.ValidateSelectors(req.Targets); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid targets", err.Error())
+		return
+	}
+	if req.MaxHops <= 0 {
+		req.MaxHops = 5
+	}
+	
+	ctx, explain, ok := g.explainContext(w, r)
+	if !ok {
+		return
+	}
+	
+	paths, err := g.attackPaths.FindPathsMatching(ctx, req.EntryPoints, req.Targets, req.MaxHops)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to find path", err.Error())
+		writeError(w, err, "Failed to discover attack paths")
 		return
 	}
 	
-	writeSuccessResponse(w, path, nil)
+	writeSuccessResponse(w, paths, explainMeta(explain))
+}
+
+// explainContext returns a context collecting a query explanation when the
+// request asks for explain=true. Explain mode exposes generated queries, so it
+// is only available when debug is enabled; otherwise the request is rejected.
+func (g *Gateway) explainContext(w http.ResponseWriter, r *http.Request) (context.Context, *graph.QueryExplanation, bool) {
+	if r.URL.Query().Get("explain") != "true" {
+		return r.Context(), nil, true
+	}
+	
+	if !g.config.EnableDebug {
+		writeErrorResponse(w, http.StatusForbidden, apperrors.CodeForbidden, "Explain mode requires debug to be enabled", "")
+		return nil, nil, false
+	}
+	
+	ctx, explain := graph.WithExplain(r.Context())
+	return ctx, explain, true
+}
+
+// explainMeta wraps a query explanation in response metadata
+func explainMeta(explain *graph.QueryExplanation) *APIMeta {
+	if explain == nil {
+		return nil
+	}
+	return &APIMeta{Explain: explain}
 }
 
 // Health and metrics handlers
@@ -656,6 +1033,24 @@ func (g *Gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
 		health["graph_store"] = map[string]string{"status": "ok"}
 	}
 	
+	// Report the graph schema version when the store is versioned
+	if versioned, ok := g.graphStore.(interface {
+		SchemaStatus(ctx context.Context) (graph.SchemaMigrationStatus, error)
+	}); ok {
+		if status, err := versioned.SchemaStatus(ctx); err != nil {
+			// The error can name internal queries, so it is only logged
+			log.Printf("Failed to read graph schema status: %v", err)
+			health["schema"] = map[string]string{"status": "error"}
+		} else {
+			health["schema"] = map[string]interface{}{
+				"status":  "ok",
+				"version": status.Version,
+				"latest":  status.Latest,
+				"pending": status.Version < status.Latest,
+			}
+		}
+	}
+	
 	// Check event bus
 	if err := g.eventBus.Ping(ctx); err != nil {
 		health["event_bus"] = map[string]string{
@@ -701,9 +1096,134 @@ func (g *Gateway) handleCacheStats(w http.ResponseWriter, r *http.Request) {
 	writeSuccessResponse(w, stats, nil)
 }
 
-// GetMetrics returns gateway metrics
-func (g *Gateway) GetMetrics() GatewayMetrics {
+// handleListEventHandlers lists the event handlers registered per event
+// type with their success and failure counts
+func (g *Gateway) handleListEventHandlers(w http.ResponseWriter, r *http.Request) {
+	if g.eventHandlers == nil {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Event processor is not running in this process", "")
+		return
+	}
+
+	writeSuccessResponse(w, g.eventHandlers.Handlers(), nil)
+}
+
+// handleDeleteRelationshipsByFilter removes every relationship matching the
+// filter in the request body, e.g. to clear edges before re-inference or
+// when an account is offboarded
+func (g *Gateway) handleDeleteRelationshipsByFilter(w http.ResponseWriter, r *http.Request) {
+	deleter, ok := g.graphStore.(interface {
+		DeleteRelationshipsByFilter(ctx context.Context, filter models.RelationshipDeleteFilter) (int, error)
+	})
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Bulk relationship deletion is not supported", "")
+		return
+	}
+	
+	var filter models.RelationshipDeleteFilter
+	if err := parseRequestBody(r, &filter); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to parse request body", err.Error())
+		return
+	}
+	
+	deleted, err := deleter.DeleteRelationshipsByFilter(r.Context(), filter)
+	if err != nil {
+		writeError(w, err, "Failed to delete relationships")
+		return
+	}
+	
+	writeSuccessResponse(w, map[string]interface{}{"deleted": deleted, "hard": filter.Hard}, nil)
+}
+
+// handleReencodeAssets rewrites stored asset documents in the configured
+// data encoding
+func (g *Gateway) handleReencodeAssets(w http.ResponseWriter, r *http.Request) {
+	reencoder, ok := g.graphStore.(interface {
+		ReencodeAssets(ctx context.Context) (int, error)
+	})
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Asset re-encoding is not supported", "")
+		return
+	}
+	
+	rewritten, err := reencoder.ReencodeAssets(r.Context())
+	if err != nil {
+		writeError(w, err, "Failed to re-encode assets")
+		return
+	}
+	
+	writeSuccessResponse(w, map[string]int{"rewritten": rewritten}, nil)
+}
+
+func (g *Gateway) handleExportGraph(w http.ResponseWriter, r *http.Request) {
+	snapshotter, ok := g.graphStore.(GraphSnapshotter)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Graph export is not supported", "")
+		return
+	}
+	
+	tenant := r.URL.Query().Get("tenant")
+	
+	snapshot, err := snapshotter.ExportGraph(r.Context(), tenant)
+	if err != nil {
+		writeError(w, err, "Failed to export graph")
+		return
+	}
+	
+	filename := fmt.Sprintf("graph-%s.ndjson", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	
+	// Headers are already sent, so a failure mid-stream can only be logged
+	if _, err := io.Copy(w, snapshot); err != nil {
+		log.Printf("Failed to stream graph export for tenant %q: %v", tenant, err)
+	}
+}
+
+func (g *Gateway) handleImportGraph(w http.ResponseWriter, r *http.Request) {
+	snapshotter, ok := g.graphStore.(GraphSnapshotter)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Graph import is not supported", "")
+		return
+	}
+	defer r.Body.Close()
+	
+	tenant := r.URL.Query().Get("tenant")
+	labels := r.URL.Query()["label"]
+	
+	result, err := snapshotter.ImportGraph(r.Context(), tenant, r.Body, labels)
+	if err != nil {
+		writeError(w, err, "Failed to import graph")
+		return
+	}
+	
+	writeSuccessResponse(w, result, nil)
+}
+
+// GetMetrics returns a snapshot of the gateway metrics that is safe to use
+// while requests are still being counted
+func (g *Gateway) GetMetrics() GatewayMetricsSnapshot {
 	g.metrics.mu.RLock()
 	defer g.metrics.mu.RUnlock()
-	return *g.metrics
+	
+	snapshot := GatewayMetricsSnapshot{
+		RequestsTotal:    g.metrics.RequestsTotal,
+		RequestsActive:   g.metrics.RequestsActive,
+		RequestsFailed:   g.metrics.RequestsFailed,
+		AverageLatency:   g.metrics.AverageLatency,
+		RequestsByPath:   make(map[string]int64, len(g.metrics.RequestsByPath)),
+		RequestsByMethod: make(map[string]int64, len(g.metrics.RequestsByMethod)),
+		RequestsByStatus: make(map[int]int64, len(g.metrics.RequestsByStatus)),
+		LastRequest:      g.metrics.LastRequest,
+	}
+	for path, count := range g.metrics.RequestsByPath {
+		snapshot.RequestsByPath[path] = count
+	}
+	for method, count := range g.metrics.RequestsByMethod {
+		snapshot.RequestsByMethod[method] = count
+	}
+	for status, count := range g.metrics.RequestsByStatus {
+		snapshot.RequestsByStatus[status] = count
+	}
+	return snapshot
 }
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// LLMSpendReporter reports what tenants have spent on LLM calls this month.
+// *knowledgebase.KnowledgeBaseService satisfies it; both methods return
+// false when LLM budgets are disabled.
+type LLMSpendReporter interface {
+	LLMSpend(tenantID string) (models.LLMSpend, bool)
+	LLMSpendReport() ([]models.LLMSpend, bool)
+}
+
+// SetLLMSpendReporter enables the LLM spend report for administrators
+func (g *Gateway) SetLLMSpendReporter(reporter LLMSpendReporter) {
+	g.llmSpend = reporter
+}
+
+// handleLLMSpend returns the month's LLM spend of every tenant, or of the
+// tenant given by the tenant query parameter
+func (g *Gateway) handleLLMSpend(w http.ResponseWriter, r *http.Request) {
+	if g.llmSpend == nil {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "LLM spend reporting is not supported", "")
+		return
+	}
+
+	if tenantID := r.URL.Query().Get("tenant"); tenantID != "" {
+		spend, ok := g.llmSpend.LLMSpend(tenantID)
+		if !ok {
+			writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "LLM budgets are disabled", "")
+			return
+		}
+		writeSuccessResponse(w, spend, nil)
+		return
+	}
+
+	report, ok := g.llmSpend.LLMSpendReport()
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "LLM budgets are disabled", "")
+		return
+	}
+	writeSuccessResponse(w, report, nil)
+}
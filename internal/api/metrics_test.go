@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestGetMetricsWhileRequestsAreCounted(t *testing.T) {
+	g := NewGateway(DefaultGatewayConfig(), nil, nil, nil)
+	handler := g.metricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	const requests = 200
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			path := "/ok"
+			if i%4 == 0 {
+				path = "/fail"
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}(i)
+		go func() {
+			defer wg.Done()
+			snapshot := g.GetMetrics()
+			// Reading the snapshot's maps must not race with later requests
+			for range snapshot.RequestsByStatus {
+			}
+		}()
+	}
+	wg.Wait()
+
+	metrics := g.GetMetrics()
+	if metrics.RequestsTotal != requests {
+		t.Errorf("RequestsTotal = %d, want %d", metrics.RequestsTotal, requests)
+	}
+	if metrics.RequestsFailed != requests/4 {
+		t.Errorf("RequestsFailed = %d, want %d", metrics.RequestsFailed, requests/4)
+	}
+	if metrics.RequestsByStatus[http.StatusOK] != requests-requests/4 {
+		t.Errorf("RequestsByStatus[200] = %d, want %d", metrics.RequestsByStatus[http.StatusOK], requests-requests/4)
+	}
+	if metrics.RequestsActive != 0 {
+		t.Errorf("RequestsActive = %d, want 0", metrics.RequestsActive)
+	}
+}
+
+func TestMetricsCountUnmatchedAndRejectedRequests(t *testing.T) {
+	config := DefaultGatewayConfig()
+	config.EnableAuth = true
+	config.AuthType = "jwt"
+	config.JWTSecret = testJWTSecret
+	g := NewGateway(config, nil, nil, nil)
+
+	requests := []struct {
+		method, path string
+		wantStatus   int
+		wantRoute    string
+	}{
+		{http.MethodGet, "/api/v1/no-such-resource", http.StatusNotFound, "unknown"},
+		{http.MethodPost, "/api/v1/assets/vm-1/risk", http.StatusMethodNotAllowed, "unknown"},
+		{http.MethodGet, "/api/v1/assets/vm-1", http.StatusUnauthorized, "/api/v1/assets/{id}"},
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		g.server.Handler.ServeHTTP(w, httptest.NewRequest(req.method, req.path, nil))
+		if w.Code != req.wantStatus {
+			t.Fatalf("%s %s status = %d, want %d", req.method, req.path, w.Code, req.wantStatus)
+		}
+	}
+
+	metrics := g.GetMetrics()
+	if metrics.RequestsTotal != int64(len(requests)) {
+		t.Errorf("RequestsTotal = %d, want %d", metrics.RequestsTotal, len(requests))
+	}
+	for _, req := range requests {
+		if metrics.RequestsByStatus[req.wantStatus] != 1 {
+			t.Errorf("RequestsByStatus[%d] = %d, want 1", req.wantStatus, metrics.RequestsByStatus[req.wantStatus])
+		}
+	}
+	if metrics.RequestsByPath["unknown"] != 2 || metrics.RequestsByPath["/api/v1/assets/{id}"] != 1 {
+		t.Errorf("RequestsByPath = %v, want 2 unknown and 1 for the asset route", metrics.RequestsByPath)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// maxPatchBytes bounds the size of a merge patch body
+const maxPatchBytes = 1 << 20
+
+// DocumentPatcher is implemented by graph stores that apply JSON merge
+// patches to assets and findings with an optimistic version check
+type DocumentPatcher interface {
+	PatchAsset(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Asset, int64, error)
+	PatchFinding(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Finding, int64, error)
+	GetVersion(ctx context.Context, id string) (int64, error)
+}
+
+// handlePatchAsset applies a JSON merge patch to an asset. The version from
+// a previous response's ETag may be sent in If-Match to fail with a
+// conflict if the asset changed since.
+func (g *Gateway) handlePatchAsset(w http.ResponseWriter, r *http.Request) {
+	patcher, ok := g.graphStore.(DocumentPatcher)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Asset patching is not supported", "")
+		return
+	}
+
+	patch, expectedVersion, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	asset, version, err := patcher.PatchAsset(r.Context(), mux.Vars(r)["id"], patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, "Failed to patch asset")
+		return
+	}
+
+	setVersionETag(w, version)
+	writeSuccessResponse(w, asset, nil)
+}
+
+// handlePatchFinding applies a JSON merge patch to a finding, e.g. to change
+// only its status, with the same version check as handlePatchAsset
+func (g *Gateway) handlePatchFinding(w http.ResponseWriter, r *http.Request) {
+	patcher, ok := g.graphStore.(DocumentPatcher)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Finding patching is not supported", "")
+		return
+	}
+
+	patch, expectedVersion, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	finding, version, err := patcher.PatchFinding(r.Context(), mux.Vars(r)["id"], patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, "Failed to patch finding")
+		return
+	}
+
+	setVersionETag(w, version)
+	writeSuccessResponse(w, finding, nil)
+}
+
+// readMergePatch reads the patch body and the version in If-Match, writing
+// an error response and returning false if either is invalid
+func readMergePatch(w http.ResponseWriter, r *http.Request) ([]byte, int64, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "" && !strings.HasPrefix(contentType, "application/merge-patch+json") &&
+		!strings.HasPrefix(contentType, "application/json") {
+		writeErrorResponse(w, http.StatusUnsupportedMediaType, apperrors.CodeInvalidRequest,
+			"Patches must be sent as application/merge-patch+json", "")
+		return nil, 0, false
+	}
+
+	defer r.Body.Close()
+	patch, err := io.ReadAll(io.LimitReader(r.Body, maxPatchBytes+1))
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Failed to read request body", err.Error())
+		return nil, 0, false
+	}
+	if len(patch) > maxPatchBytes {
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge, apperrors.CodeInvalidRequest, "Patch is too large", "")
+		return nil, 0, false
+	}
+
+	var expectedVersion int64
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" {
+		match = strings.Trim(strings.TrimPrefix(match, "W/"), `"`)
+		expectedVersion, err = strconv.ParseInt(match, 10, 64)
+		if err != nil || expectedVersion <= 0 {
+			writeErrorResponse(w, http.StatusBadRequest, apperrors.CodeInvalidRequest, "Invalid If-Match version", match)
+			return nil, 0, false
+		}
+	}
+
+	return patch, expectedVersion, true
+}
+
+// setVersionETag reports a document version as the response ETag
+func setVersionETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
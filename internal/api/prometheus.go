@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/securizon/internal/events"
+	"github.com/securizon/internal/risk"
+	"github.com/securizon/pkg/models"
+)
+
+// prometheusContentType is the content type of the text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram buckets
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// ProcessorMetricsSource exposes the metrics of an event processor, such as
+// one running alongside the gateway
+type ProcessorMetricsSource interface {
+	GetMetrics() events.ProcessorMetricsSnapshot
+}
+
+// SetProcessorMetrics includes the processor's metrics in the Prometheus
+// metrics endpoint
+func (g *Gateway) SetProcessorMetrics(source ProcessorMetricsSource) {
+	g.processorMetrics = source
+}
+
+// requestKey identifies a request counter series
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+// latencyKey identifies a request latency histogram series
+type latencyKey struct {
+	method string
+	route  string
+}
+
+// latencyHistogram counts observations per bucket of latencyBuckets
+type latencyHistogram struct {
+	buckets []int64 // non-cumulative
+	count   int64
+	sum     float64
+}
+
+func (h *latencyHistogram) observe(seconds float64) {
+	if h.buckets == nil {
+		h.buckets = make([]int64, len(latencyBuckets))
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// routeTemplate returns the path template of the route a request matched,
+// so that requests for different IDs are counted together
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
+
+type matchedRouteContextKey struct{}
+
+// matchedRoute receives the template of the route a request matched. The
+// router only sets the route on the request it passes down, so the metrics
+// middleware wrapping the router learns it through this.
+type matchedRoute struct {
+	template string
+}
+
+// routeMiddleware reports the matched route to the metrics middleware. It
+// runs first on matched routes, so requests the other middleware rejects are
+// still counted by route.
+func (g *Gateway) routeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if matched, ok := r.Context().Value(matchedRouteContextKey{}).(*matchedRoute); ok {
+			matched.template = routeTemplate(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recordRequest counts a request for the Prometheus series. The caller
+// holds g.metrics.mu.
+func (g *Gateway) recordRequest(method, route string, status int, duration time.Duration) {
+	m := g.metrics
+	if m.requests == nil {
+		m.requests = make(map[requestKey]int64)
+		m.latencies = make(map[latencyKey]*latencyHistogram)
+	}
+	m.requests[requestKey{method: method, route: route, status: status}]++
+
+	key := latencyKey{method: method, route: route}
+	histogram, ok := m.latencies[key]
+	if !ok {
+		histogram = &latencyHistogram{}
+		m.latencies[key] = histogram
+	}
+	histogram.observe(duration.Seconds())
+}
+
+func (g *Gateway) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	w.WriteHeader(http.StatusOK)
+
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	g.writeGatewayMetrics(out)
+	if g.riskEngine != nil {
+		metrics := g.riskEngine.GetMetrics()
+		writeEngineMetrics(out, &metrics)
+	}
+	if g.processorMetrics != nil {
+		metrics := g.processorMetrics.GetMetrics()
+		writeProcessorMetrics(out, &metrics)
+	}
+}
+
+// writeGatewayMetrics writes the request counters and latency histograms
+func (g *Gateway) writeGatewayMetrics(out *bufio.Writer) {
+	g.metrics.mu.RLock()
+	defer g.metrics.mu.RUnlock()
+
+	writeHeader(out, "securizon_api_requests_total", "counter", "API requests by method, route and status.")
+	requests := make([]requestKey, 0, len(g.metrics.requests))
+	for key := range g.metrics.requests {
+		requests = append(requests, key)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, key := range requests {
+		writeSample(out, "securizon_api_requests_total",
+			labels("method", key.method, "route", key.route, "status", strconv.Itoa(key.status)),
+			float64(g.metrics.requests[key]))
+	}
+
+	writeHeader(out, "securizon_api_requests_in_flight", "gauge", "API requests being served.")
+	writeSample(out, "securizon_api_requests_in_flight", "", float64(g.metrics.RequestsActive))
+
+	writeHeader(out, "securizon_api_request_duration_seconds", "histogram", "API request latency by method and route.")
+	latencies := make([]latencyKey, 0, len(g.metrics.latencies))
+	for key := range g.metrics.latencies {
+		latencies = append(latencies, key)
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		a, b := latencies[i], latencies[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.method < b.method
+	})
+	for _, key := range latencies {
+		histogram := g.metrics.latencies[key]
+		var cumulative int64
+		for i, bound := range latencyBuckets {
+			cumulative += histogram.buckets[i]
+			writeSample(out, "securizon_api_request_duration_seconds_bucket",
+				labels("method", key.method, "route", key.route, "le", formatFloat(bound)), float64(cumulative))
+		}
+		writeSample(out, "securizon_api_request_duration_seconds_bucket",
+			labels("method", key.method, "route", key.route, "le", "+Inf"), float64(histogram.count))
+		writeSample(out, "securizon_api_request_duration_seconds_sum",
+			labels("method", key.method, "route", key.route), histogram.sum)
+		writeSample(out, "securizon_api_request_duration_seconds_count",
+			labels("method", key.method, "route", key.route), float64(histogram.count))
+	}
+}
+
+// writeEngineMetrics writes the risk engine's counters. The engine only
+// tracks an average calculation time, which is exposed as a gauge.
+func writeEngineMetrics(out *bufio.Writer, m *risk.EngineMetricsSnapshot) {
+	writeCounter(out, "securizon_risk_calculations_total", "Risk calculations performed.", float64(m.CalculationsPerformed))
+	writeCounter(out, "securizon_risk_calculation_failures_total", "Risk calculations that failed.", float64(m.CalculationsFailed))
+	writeCounter(out, "securizon_risk_cache_hits_total", "Risk cache hits.", float64(m.CacheHits))
+	writeCounter(out, "securizon_risk_cache_misses_total", "Risk cache misses.", float64(m.CacheMisses))
+
+	hitRate := 0.0
+	if lookups := m.CacheHits + m.CacheMisses; lookups > 0 {
+		hitRate = float64(m.CacheHits) / float64(lookups)
+	}
+	writeHeader(out, "securizon_risk_cache_hit_ratio", "gauge", "Share of risk lookups served from the cache.")
+	writeSample(out, "securizon_risk_cache_hit_ratio", "", hitRate)
+
+	writeHeader(out, "securizon_risk_calculation_duration_seconds_average", "gauge", "Moving average of risk calculation time.")
+	writeSample(out, "securizon_risk_calculation_duration_seconds_average", "", m.AverageCalculationTime.Seconds())
+
+	writeHeader(out, "securizon_risk_calculations_by_level_total", "counter", "Risk calculations by resulting risk level.")
+	levels := make([]string, 0, len(m.RiskDistribution))
+	for level := range m.RiskDistribution {
+		levels = append(levels, string(level))
+	}
+	sort.Strings(levels)
+	for _, level := range levels {
+		writeSample(out, "securizon_risk_calculations_by_level_total", labels("level", level),
+			float64(m.RiskDistribution[models.RiskLevel(level)]))
+	}
+}
+
+// writeProcessorMetrics writes the event processor's counters. The
+// processor only tracks an average latency, which is exposed as a gauge.
+func writeProcessorMetrics(out *bufio.Writer, m *events.ProcessorMetricsSnapshot) {
+	writeCounter(out, "securizon_events_processed_total", "Events processed.", float64(m.EventsProcessed))
+	writeCounter(out, "securizon_events_failed_total", "Events that failed processing.", float64(m.EventsFailed))
+	writeCounter(out, "securizon_events_retried_total", "Event processing retries.", float64(m.EventsRetried))
+	writeCounter(out, "securizon_events_deduplicated_total", "Duplicate events dropped.", float64(m.EventsDeduplicated))
+	writeCounter(out, "securizon_events_dead_lettered_total", "Events published to the dead letter topic.", float64(m.EventsDeadLettered))
+
+	writeHeader(out, "securizon_event_processing_duration_seconds_average", "gauge", "Moving average of event processing time.")
+	writeSample(out, "securizon_event_processing_duration_seconds_average", "", m.AverageLatency.Seconds())
+
+	writeHeader(out, "securizon_events_by_type_total", "counter", "Events processed by event type.")
+	eventTypes := make([]string, 0, len(m.EventsByType))
+	for eventType := range m.EventsByType {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	sort.Strings(eventTypes)
+	for _, eventType := range eventTypes {
+		writeSample(out, "securizon_events_by_type_total", labels("type", eventType),
+			float64(m.EventsByType[models.EventType(eventType)]))
+	}
+
+	writeHeader(out, "securizon_event_worker_utilization", "gauge", "Share of the last metrics interval each worker spent handling events.")
+	workers := make([]int, 0, len(m.WorkerUtilization))
+	for worker := range m.WorkerUtilization {
+		workers = append(workers, worker)
+	}
+	sort.Ints(workers)
+	for _, worker := range workers {
+		writeSample(out, "securizon_event_worker_utilization", labels("worker", strconv.Itoa(worker)), m.WorkerUtilization[worker])
+	}
+
+	writeHeader(out, "securizon_event_errors_total", "counter", "Event processing errors by error type.")
+	errorTypes := make([]string, 0, len(m.ErrorsByType))
+	for errorType := range m.ErrorsByType {
+		errorTypes = append(errorTypes, errorType)
+	}
+	sort.Strings(errorTypes)
+	for _, errorType := range errorTypes {
+		writeSample(out, "securizon_event_errors_total", labels("type", errorType), float64(m.ErrorsByType[errorType]))
+	}
+}
+
+func writeCounter(out *bufio.Writer, name, help string, value float64) {
+	writeHeader(out, name, "counter", help)
+	writeSample(out, name, "", value)
+}
+
+func writeHeader(out *bufio.Writer, name, metricType, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeSample(out *bufio.Writer, name, labels string, value float64) {
+	fmt.Fprintf(out, "%s%s %s\n", name, labels, formatFloat(value))
+}
+
+// labels formats name/value pairs as a label set
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
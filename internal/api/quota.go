@@ -0,0 +1,264 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/apperrors"
+)
+
+// Endpoint classes subject to per-tenant quotas
+const (
+	QuotaAttackPaths = "attack_paths"
+	QuotaRecalculate = "recalculate"
+	QuotaExport      = "export"
+	QuotaImport      = "import"
+)
+
+// quotaBuckets is the number of counters a rolling window is split into
+const quotaBuckets = 24
+
+// QuotaConfig represents per-tenant quota configuration for expensive endpoints
+type QuotaConfig struct {
+	Enabled bool                  `json:"enabled" yaml:"enabled"`
+	Window  time.Duration         `json:"window" yaml:"window"` // rolling window the request limits apply to
+	Limits  map[string]QuotaLimit `json:"limits" yaml:"limits"` // by endpoint class
+}
+
+// QuotaLimit limits one endpoint class for a single tenant. Zero values mean
+// no limit.
+type QuotaLimit struct {
+	Requests      int `json:"requests" yaml:"requests"`             // per rolling window
+	MaxConcurrent int `json:"max_concurrent" yaml:"max_concurrent"` // requests in flight, including the jobs they start
+}
+
+// DefaultQuotaConfig returns default quota configuration
+func DefaultQuotaConfig() QuotaConfig {
+	return QuotaConfig{
+		Enabled: false,
+		Window:  24 * time.Hour,
+		Limits: map[string]QuotaLimit{
+			QuotaAttackPaths: {Requests: 1000, MaxConcurrent: 2},
+			QuotaRecalculate: {Requests: 100, MaxConcurrent: 1},
+			QuotaExport:      {Requests: 20, MaxConcurrent: 1},
+			QuotaImport:      {Requests: 20, MaxConcurrent: 1},
+		},
+	}
+}
+
+// QuotaUsage is a tenant's usage of one endpoint class
+type QuotaUsage struct {
+	Used    int       `json:"used"`
+	Limit   int       `json:"limit"`
+	ResetAt time.Time `json:"reset_at"` // when the oldest counted request leaves the window
+	Allowed bool      `json:"allowed"`
+}
+
+// QuotaStore counts requests per key over a rolling window. Deployments with
+// several gateway replicas should share a store between them.
+type QuotaStore interface {
+	// Consume counts a request for key if fewer than limit requests were
+	// counted within the trailing window
+	Consume(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (QuotaUsage, error)
+}
+
+// MemoryQuotaStore is an in-process QuotaStore that splits each window into
+// fixed buckets
+type MemoryQuotaStore struct {
+	mu       sync.Mutex
+	counters map[string]*quotaCounter
+}
+
+type quotaCounter struct {
+	buckets [quotaBuckets]int
+	starts  [quotaBuckets]time.Time
+}
+
+// NewMemoryQuotaStore creates a new in-memory quota store
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{counters: make(map[string]*quotaCounter)}
+}
+
+// Consume implements QuotaStore
+func (s *MemoryQuotaStore) Consume(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (QuotaUsage, error) {
+	if window <= 0 {
+		return QuotaUsage{}, fmt.Errorf("quota window must be positive")
+	}
+
+	bucketSize := window / quotaBuckets
+	if bucketSize <= 0 {
+		bucketSize = window
+	}
+	start := now.Truncate(bucketSize)
+	index := int(start.UnixNano()/int64(bucketSize)) % quotaBuckets
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &quotaCounter{}
+		s.counters[key] = counter
+	}
+
+	// Reuse the bucket once its previous period has left the window
+	if !counter.starts[index].Equal(start) {
+		counter.buckets[index] = 0
+		counter.starts[index] = start
+	}
+
+	usage := QuotaUsage{Limit: limit, ResetAt: start.Add(window)}
+	for i, count := range counter.buckets {
+		if count == 0 || now.Sub(counter.starts[i]) >= window {
+			continue
+		}
+		usage.Used += count
+		if reset := counter.starts[i].Add(window); reset.Before(usage.ResetAt) {
+			usage.ResetAt = reset
+		}
+	}
+
+	if usage.Used < limit {
+		counter.buckets[index]++
+		usage.Used++
+		usage.Allowed = true
+	}
+	return usage, nil
+}
+
+// quotaEnforcer applies quotas and concurrency caps per tenant
+type quotaEnforcer struct {
+	config   QuotaConfig
+	store    QuotaStore
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+func newQuotaEnforcer(config QuotaConfig, store QuotaStore) *quotaEnforcer {
+	return &quotaEnforcer{
+		config:   config,
+		store:    store,
+		inFlight: make(map[string]int),
+	}
+}
+
+// acquire reserves a concurrency slot for key, returning false if the cap
+// has been reached
+func (q *quotaEnforcer) acquire(key string, max int) bool {
+	if max <= 0 {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.inFlight[key] >= max {
+		return false
+	}
+	q.inFlight[key]++
+	return true
+}
+
+func (q *quotaEnforcer) release(key string, max int) {
+	if max <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.inFlight[key]--; q.inFlight[key] <= 0 {
+		delete(q.inFlight, key)
+	}
+}
+
+// quotaSlotKey is the context key holding the concurrency slot of a request
+type quotaSlotKey struct{}
+
+// quotaSlot is a concurrency slot reserved for a request. It is released
+// when the handler returns unless the handler holds it.
+type quotaSlot struct {
+	release func()
+	held    bool
+}
+
+// holdQuotaSlot keeps the concurrency slot of r reserved after its handler
+// returns, for work that outlives the request. The returned function
+// releases the slot once that work is done.
+func holdQuotaSlot(r *http.Request) func() {
+	slot, ok := r.Context().Value(quotaSlotKey{}).(*quotaSlot)
+	if !ok {
+		return func() {}
+	}
+	slot.held = true
+	return slot.release
+}
+
+// requestTenantID returns the tenant a request is made on behalf of.
+// Requests without a tenant context share the default tenant's quota.
+func requestTenantID(r *http.Request) string {
+	if tenantCtx, err := tenant.GetTenantContext(r.Context()); err == nil && tenantCtx.TenantID != "" {
+		return tenantCtx.TenantID
+	}
+	return "default"
+}
+
+// withQuota enforces the quota of an endpoint class on a handler
+func (g *Gateway) withQuota(class string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if g.quotas == nil {
+			next(w, r)
+			return
+		}
+
+		limit, ok := g.quotas.config.Limits[class]
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		key := requestTenantID(r) + ":" + class
+
+		if !g.quotas.acquire(key, limit.MaxConcurrent) {
+			w.Header().Set("Retry-After", "1")
+			writeErrorResponse(w, http.StatusTooManyRequests, apperrors.CodeQuotaExceeded, "Too many concurrent requests",
+				fmt.Sprintf("at most %d concurrent %s requests are allowed per tenant", limit.MaxConcurrent, class))
+			return
+		}
+		slot := &quotaSlot{release: func() { g.quotas.release(key, limit.MaxConcurrent) }}
+		r = r.WithContext(context.WithValue(r.Context(), quotaSlotKey{}, slot))
+		defer func() {
+			if !slot.held {
+				slot.release()
+			}
+		}()
+
+		if limit.Requests > 0 {
+			now := time.Now()
+			usage, err := g.quotas.store.Consume(r.Context(), key, limit.Requests, g.quotas.config.Window, now)
+			if err != nil {
+				writeError(w, err, "Failed to check quota")
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(usage.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(usage.Limit-usage.Used))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(usage.ResetAt.Unix(), 10))
+
+			if !usage.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(usage.ResetAt.Sub(now).Seconds())+1))
+				writeErrorResponse(w, http.StatusTooManyRequests, apperrors.CodeQuotaExceeded, "Request quota exceeded",
+					fmt.Sprintf("%d of %d %s requests used in the last %s, resets at %s",
+						usage.Used, usage.Limit, class, g.quotas.config.Window, usage.ResetAt.UTC().Format(time.RFC3339)))
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/securizon/pkg/apperrors"
+)
+
+// rateLimitIdleTTL is how long a client's bucket is kept after its last
+// request. An idle bucket refills completely well within it, so dropping it
+// loses nothing.
+const rateLimitIdleTTL = 10 * time.Minute
+
+// rateLimiter is a per-client token bucket limiter. Each client may burst up
+// to burst requests, refilled at rate requests per second.
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+func newRateLimiter(rps, burst int) *rateLimiter {
+	if burst <= 0 {
+		burst = rps
+	}
+	return &rateLimiter{
+		rate:    float64(rps),
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from client's bucket and returns the whole tokens
+// left. When none is left it returns false and how long until one is.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitIdleTTL {
+		for key, bucket := range l.buckets {
+			if now.Sub(bucket.lastSeen) >= rateLimitIdleTTL {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*l.rate)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, 0, wait
+	}
+	bucket.tokens--
+	return true, int(bucket.tokens), 0
+}
+
+// requestAPIKey returns the API key a request was made with, from the
+// X-API-Key header or an "Authorization: ApiKey" header
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "ApiKey ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// rateLimitClient identifies the client a request is counted against: the
+// API key or token subject it was authenticated with, or its remote IP for
+// unauthenticated requests. Only identities the auth middleware verified are
+// used, so a client cannot mint a fresh bucket per request.
+func rateLimitClient(r *http.Request) string {
+	if info, ok := APIKeyFromContext(r.Context()); ok && info.ID != "" {
+		return "key:" + info.ID
+	}
+	if claims, ok := ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func (g *Gateway) rateLimitMiddleware(next http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(g.config.RateLimitExempt))
+	for _, path := range g.config.RateLimitExempt {
+		exempt[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.rateLimiter == nil || exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, remaining, wait := g.rateLimiter.allow(rateLimitClient(r), time.Now())
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(int(g.rateLimiter.burst)))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeErrorResponse(w, http.StatusTooManyRequests, apperrors.CodeQuotaExceeded, "Rate limit exceeded",
+				fmt.Sprintf("at most %d requests per second are allowed per client", g.config.RateLimitRPS))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefillsTokenBucket(t *testing.T) {
+	l := newRateLimiter(2, 3)
+	now := time.Unix(1700000000, 0)
+
+	for i := 2; i >= 0; i-- {
+		ok, remaining, _ := l.allow("a", now)
+		if !ok || remaining != i {
+			t.Fatalf("request within burst = %v with %d left, want allowed with %d left", ok, remaining, i)
+		}
+	}
+	ok, _, wait := l.allow("a", now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("request past burst = %v waiting %v, want rejected waiting 500ms", ok, wait)
+	}
+	if ok, _, _ := l.allow("b", now); !ok {
+		t.Error("another client shares the exhausted bucket")
+	}
+
+	// Half a second refills one token at two per second
+	now = now.Add(500 * time.Millisecond)
+	if ok, remaining, _ := l.allow("a", now); !ok || remaining != 0 {
+		t.Errorf("request after refill = %v with %d left, want allowed with 0 left", ok, remaining)
+	}
+
+	// A long idle period refills no more than the burst
+	now = now.Add(time.Minute)
+	if _, remaining, _ := l.allow("a", now); remaining != 2 {
+		t.Errorf("tokens left after idling = %d, want the burst of 3 less one", remaining)
+	}
+}
+
+func TestRateLimiterDropsIdleBuckets(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	now := time.Unix(1700000000, 0)
+
+	l.allow("idle", now)
+	l.allow("active", now)
+	l.allow("active", now.Add(rateLimitIdleTTL/2))
+
+	l.allow("active", now.Add(rateLimitIdleTTL))
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("bucket idle for the TTL was kept")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("bucket used within the TTL was dropped")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	config := DefaultGatewayConfig()
+	config.RateLimitEnabled = true
+	config.RateLimitRPS = 1
+	config.RateLimitBurst = 1
+	g := NewGateway(config, nil, nil, nil)
+	handler := g.rateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("/api/v1/assets"); w.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", w.Code, http.StatusOK)
+	}
+	w := request("/api/v1/assets")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+
+	for _, path := range []string{"/api/v1/health", "/api/v1/metrics", "/api/v1/metrics/prometheus"} {
+		if w := request(path); w.Code != http.StatusOK {
+			t.Errorf("%s status = %d, want it exempt from the limit", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// recalcJobChunkSize is the number of assets handed to the risk engine at a
+// time; progress is reported after each chunk
+const recalcJobChunkSize = 100
+
+// recalcJobRetention is how long finished jobs remain queryable
+const recalcJobRetention = time.Hour
+
+// recalcJobMaxQueued is how many jobs may wait for a slot; further requests
+// are rejected until jobs finish
+const recalcJobMaxQueued = 50
+
+// recalcJobMaxRetained bounds the jobs kept for querying. The oldest finished
+// jobs are dropped first.
+const recalcJobMaxRetained = 1000
+
+// recalcJobMaxChunkErrors bounds the chunk failures listed on a job; further
+// failures are still counted
+const recalcJobMaxChunkErrors = 100
+
+// Recalculation job states
+const (
+	RecalcJobPending   = "pending"
+	RecalcJobRunning   = "running"
+	RecalcJobCompleted = "completed"
+	RecalcJobFailed    = "failed"
+	RecalcJobCancelled = "cancelled"
+)
+
+// RecalcJob is an asynchronous risk recalculation and its progress
+type RecalcJob struct {
+	ID          string             `json:"id"`
+	Status      string             `json:"status"`
+	Total       int                `json:"total"`
+	Processed   int                `json:"processed"`
+	Failed      int                `json:"failed"`
+	ChunkErrors []RecalcChunkError `json:"chunk_errors,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	StartedAt   *time.Time         `json:"started_at,omitempty"`
+	FinishedAt  *time.Time         `json:"finished_at,omitempty"`
+
+	tenantID string
+	cancel   context.CancelFunc
+}
+
+// RecalcChunkError is a chunk of assets that could not be recalculated
+type RecalcChunkError struct {
+	Offset int    `json:"offset"` // position of the chunk's first asset
+	Count  int    `json:"count"`
+	Error  string `json:"error"`
+}
+
+// recalcJobs tracks recalculation jobs and bounds how many run at once
+type recalcJobs struct {
+	mu    sync.Mutex
+	jobs  map[string]*RecalcJob
+	slots chan struct{}
+}
+
+func newRecalcJobs(maxConcurrent int) *recalcJobs {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &recalcJobs{
+		jobs:  make(map[string]*RecalcJob),
+		slots: make(chan struct{}, maxConcurrent),
+	}
+}
+
+// create registers a pending job for tenantID and returns it with the
+// context it runs under, which cancel stops. Expired and, past the retention
+// bound, the oldest finished jobs are dropped. Too many unfinished jobs is a
+// quota error.
+func (rj *recalcJobs) create(ctx context.Context, tenantID string) (*RecalcJob, context.Context, error) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+
+	now := time.Now()
+	var finished []*RecalcJob
+	active := 0
+	for id, job := range rj.jobs {
+		switch {
+		case job.FinishedAt == nil:
+			active++
+		case now.Sub(*job.FinishedAt) > recalcJobRetention:
+			delete(rj.jobs, id)
+		default:
+			finished = append(finished, job)
+		}
+	}
+	if active >= cap(rj.slots)+recalcJobMaxQueued {
+		return nil, nil, apperrors.New(apperrors.CodeQuotaExceeded, "too many recalculation jobs in progress, retry later")
+	}
+	if excess := len(rj.jobs) + 1 - recalcJobMaxRetained; excess > 0 {
+		sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+		for i := 0; i < excess && i < len(finished); i++ {
+			delete(rj.jobs, finished[i].ID)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	job := &RecalcJob{
+		ID:        uuid.New().String(),
+		Status:    RecalcJobPending,
+		CreatedAt: now,
+		tenantID:  tenantID,
+		cancel:    cancel,
+	}
+	rj.jobs[job.ID] = job
+	return job, ctx, nil
+}
+
+// get returns a copy of the job if it exists and belongs to tenantID
+func (rj *recalcJobs) get(id, tenantID string) (RecalcJob, bool) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+
+	job, ok := rj.jobs[id]
+	if !ok || job.tenantID != tenantID {
+		return RecalcJob{}, false
+	}
+	return job.snapshot(), true
+}
+
+// cancel stops a job of tenantID that has not finished. The job reports
+// itself cancelled once its current chunk is done.
+func (rj *recalcJobs) cancel(id, tenantID string) (RecalcJob, error) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+
+	job, ok := rj.jobs[id]
+	if !ok || job.tenantID != tenantID {
+		return RecalcJob{}, apperrors.NotFound("recalculation job not found: %s", id)
+	}
+	if job.FinishedAt != nil {
+		return RecalcJob{}, apperrors.Conflict("recalculation job %s already %s", id, job.Status)
+	}
+	job.cancel()
+	return job.snapshot(), nil
+}
+
+// cancelAll stops every unfinished job
+func (rj *recalcJobs) cancelAll() {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+
+	for _, job := range rj.jobs {
+		if job.FinishedAt == nil {
+			job.cancel()
+		}
+	}
+}
+
+// update applies fn to the job under the lock
+func (rj *recalcJobs) update(job *RecalcJob, fn func(job *RecalcJob)) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	fn(job)
+}
+
+// snapshot copies the job for reading outside the lock
+func (job *RecalcJob) snapshot() RecalcJob {
+	copied := *job
+	copied.ChunkErrors = append([]RecalcChunkError(nil), job.ChunkErrors...)
+	return copied
+}
+
+// recalcErrorMessage is the client-safe description of a recalculation error
+func recalcErrorMessage(err error) string {
+	if message := apperrors.MessageOf(err); message != "" {
+		return message
+	}
+	return "risk recalculation failed"
+}
+
+// runRecalcJob recalculates risk for assetIDs, or every asset when empty,
+// once a job slot is free. A chunk that fails is recorded on the job and the
+// remaining chunks still run. The job stops between chunks when ctx is
+// cancelled.
+func (g *Gateway) runRecalcJob(ctx context.Context, job *RecalcJob, assetIDs []string) {
+	defer job.cancel()
+
+	finish := func(status string, err error) {
+		finished := time.Now()
+		g.recalcJobs.update(job, func(job *RecalcJob) {
+			job.FinishedAt = &finished
+			job.Status = status
+			if err != nil {
+				job.Error = recalcErrorMessage(err)
+			}
+		})
+	}
+
+	select {
+	case g.recalcJobs.slots <- struct{}{}:
+		defer func() { <-g.recalcJobs.slots }()
+	case <-ctx.Done():
+		finish(RecalcJobCancelled, nil)
+		return
+	}
+
+	started := time.Now()
+	g.recalcJobs.update(job, func(job *RecalcJob) {
+		job.Status = RecalcJobRunning
+		job.StartedAt = &started
+	})
+
+	if len(assetIDs) == 0 {
+		ids, err := g.allAssetIDs(ctx)
+		if ctx.Err() != nil {
+			finish(RecalcJobCancelled, nil)
+			return
+		}
+		if err != nil {
+			log.Printf("Recalculation job %s failed to list assets: %v", job.ID, err)
+			finish(RecalcJobFailed, err)
+			return
+		}
+		assetIDs = ids
+	}
+	g.recalcJobs.update(job, func(job *RecalcJob) { job.Total = len(assetIDs) })
+
+	failed := 0
+	for start := 0; start < len(assetIDs); start += recalcJobChunkSize {
+		if ctx.Err() != nil {
+			log.Printf("Recalculation job %s cancelled after %d of %d assets", job.ID, start, len(assetIDs))
+			finish(RecalcJobCancelled, nil)
+			return
+		}
+
+		end := start + recalcJobChunkSize
+		if end > len(assetIDs) {
+			end = len(assetIDs)
+		}
+		chunk := assetIDs[start:end]
+
+		result, err := g.riskEngine.BatchRecalculateRisk(ctx, chunk)
+		if err != nil && ctx.Err() != nil {
+			finish(RecalcJobCancelled, nil)
+			return
+		}
+		if err != nil {
+			log.Printf("Recalculation job %s failed on assets %d to %d: %v", job.ID, start, end, err)
+			failed += len(chunk)
+		} else {
+			failed += len(result.Errors)
+		}
+
+		g.recalcJobs.update(job, func(job *RecalcJob) {
+			job.Processed += len(chunk)
+			if err != nil {
+				job.Failed += len(chunk)
+				if len(job.ChunkErrors) < recalcJobMaxChunkErrors {
+					job.ChunkErrors = append(job.ChunkErrors, RecalcChunkError{
+						Offset: start,
+						Count:  len(chunk),
+						Error:  recalcErrorMessage(err),
+					})
+				}
+			} else {
+				job.Failed += len(result.Errors)
+			}
+		})
+	}
+
+	// The job fails only when no asset could be recalculated
+	if len(assetIDs) > 0 && failed == len(assetIDs) {
+		finish(RecalcJobFailed, apperrors.New(apperrors.CodeInternal, "no asset could be recalculated"))
+	} else {
+		finish(RecalcJobCompleted, nil)
+	}
+	log.Printf("Recalculation job %s completed for %d assets, %d failed, in %v", job.ID, len(assetIDs), failed, time.Since(started))
+}
+
+// allAssetIDs lists the ID of every asset, streaming when the store supports
+// it so the listing is not capped
+func (g *Gateway) allAssetIDs(ctx context.Context) ([]string, error) {
+	var ids []string
+
+	if streamer, ok := g.graphStore.(GraphStreamer); ok {
+		assets, errs := streamer.StreamAssets(ctx, models.AssetFilter{})
+		for asset := range assets {
+			ids = append(ids, asset.GetID())
+		}
+		return ids, <-errs
+	}
+
+	assets, err := g.graphStore.ListAssets(ctx, models.AssetFilter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range assets {
+		ids = append(ids, asset.GetID())
+	}
+	return ids, nil
+}
+
+// handleGetRecalculationJob reports the progress of a recalculation job
+func (g *Gateway) handleGetRecalculationJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["jobId"]
+
+	job, ok := g.recalcJobs.get(jobID, requestTenantID(r))
+	if !ok {
+		writeError(w, apperrors.NotFound("recalculation job not found: %s", jobID), "Failed to get recalculation job")
+		return
+	}
+
+	writeSuccessResponse(w, job, nil)
+}
+
+// handleCancelRecalculationJob stops a recalculation job that has not finished
+func (g *Gateway) handleCancelRecalculationJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["jobId"]
+
+	job, err := g.recalcJobs.cancel(jobID, requestTenantID(r))
+	if err != nil {
+		writeError(w, err, "Failed to cancel recalculation job")
+		return
+	}
+
+	writeJSONResponse(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Data:    job,
+	})
+}
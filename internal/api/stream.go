@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/securizon/internal/events"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// EventSubscriber is implemented by event buses that deliver the events
+// published to a topic after subscribing to a handler until the context is
+// canceled
+type EventSubscriber interface {
+	Subscribe(ctx context.Context, topic string, handler events.EventHandler) error
+}
+
+// riskStreamTopics are the topics streamed by /stream/risk
+var riskStreamTopics = []string{events.TopicRiskScores, events.TopicFindings}
+
+// riskStreamBuffer is the number of events held for a slow client before
+// it is disconnected
+const riskStreamBuffer = 64
+
+// handleRiskStream streams risk score changes and finding events to the
+// client as server-sent events. Clients share the gateway's subscriptions;
+// a client that falls behind is disconnected and can reconnect.
+func (g *Gateway) handleRiskStream(w http.ResponseWriter, r *http.Request) {
+	subscriber, ok := g.eventBus.(EventSubscriber)
+	if !ok {
+		writeErrorResponse(w, http.StatusNotImplemented, apperrors.CodeNotImplemented, "Event streaming is not available", "")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorResponse(w, http.StatusInternalServerError, apperrors.CodeInternal, "Streaming is not supported", "")
+		return
+	}
+
+	filter := &riskStreamFilter{
+		assetID:      r.URL.Query().Get("asset_id"),
+		environment:  models.Environment(r.URL.Query().Get("environment")),
+		store:        g.graphStore,
+		environments: make(map[string]models.Environment),
+	}
+
+	ctx := r.Context()
+	client, err := g.streams.join(subscriber, riskStreamTopics)
+	if err != nil {
+		writeError(w, apperrors.Wrap(apperrors.CodeUnavailable, err, "failed to open event stream"), "Failed to open event stream")
+		return
+	}
+	defer g.streams.leave(client)
+
+	// The stream outlives the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear write deadline for risk stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(g.config.StreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.lagged:
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event := <-client.events:
+			if !filter.matches(ctx, event) {
+				continue
+			}
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeStreamEvent writes an event's payload as a server-sent event named
+// after its type
+func writeStreamEvent(w io.Writer, event models.BaseEvent) error {
+	data := event.RawData
+	if data == nil {
+		var err error
+		if data, err = json.Marshal(event); err != nil {
+			log.Printf("Failed to encode stream event %s: %v", event.ID, err)
+			return nil
+		}
+	}
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// riskStreamFilter selects the events sent to one client
+type riskStreamFilter struct {
+	assetID     string
+	environment models.Environment
+
+	store        GraphStore
+	environments map[string]models.Environment // asset environments looked up so far
+}
+
+// streamEventFields are the fields of risk and finding payloads events
+// are filtered on
+type streamEventFields struct {
+	AssetID     string             `json:"asset_id"`
+	Environment models.Environment `json:"environment"`
+	Finding     struct {
+		AssetID     string             `json:"asset_id"`
+		Environment models.Environment `json:"environment"`
+	} `json:"finding"`
+}
+
+// matches reports whether an event concerns the asset and environment the
+// client asked for. Events that do not carry an environment, such as risk
+// score changes, are matched by their asset's environment.
+func (f *riskStreamFilter) matches(ctx context.Context, event models.BaseEvent) bool {
+	if f.assetID == "" && f.environment == "" {
+		return true
+	}
+
+	var fields streamEventFields
+	if event.RawData != nil {
+		// Payloads that fail to decode are matched on the envelope alone
+		json.Unmarshal(event.RawData, &fields)
+	}
+
+	assetID := event.AssetID
+	for _, id := range []string{fields.AssetID, fields.Finding.AssetID} {
+		if assetID == "" {
+			assetID = id
+		}
+	}
+	if f.assetID != "" && assetID != f.assetID {
+		return false
+	}
+	if f.environment == "" {
+		return true
+	}
+
+	environment := event.Environment
+	for _, env := range []models.Environment{fields.Environment, fields.Finding.Environment} {
+		if environment == "" {
+			environment = env
+		}
+	}
+	if environment == "" && assetID != "" {
+		environment = f.assetEnvironment(ctx, assetID)
+	}
+	return environment == f.environment
+}
+
+func (f *riskStreamFilter) assetEnvironment(ctx context.Context, assetID string) models.Environment {
+	if environment, ok := f.environments[assetID]; ok {
+		return environment
+	}
+	asset, err := f.store.GetAsset(ctx, assetID)
+	if err != nil {
+		return ""
+	}
+	environment := asset.GetBaseAsset().Environment
+	f.environments[assetID] = environment
+	return environment
+}
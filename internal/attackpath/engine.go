@@ -6,26 +6,33 @@ import (
 	"sync"
 	"time"
 
-	"github.com/securazion/event-processor/internal/graph"
-	"github.com/securazion/event-processor/internal/risk"
-	"github.com/securazion/event-processor/pkg/models"
+	"github.com/google/uuid"
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/internal/risk"
+	"github.com/securizon/pkg/models"
 )
 
 // AttackPathEngine discovers and analyzes attack paths through the asset graph
 type AttackPathEngine struct {
-	graphClient  *graph.Client
+	graphStore   graph.GraphStore
 	riskEngine   *risk.Engine
 	pathCache    map[string]*models.AttackPath
 	cacheTTL     time.Duration
 	cacheExpiry  map[string]time.Time
 	mu           sync.RWMutex
 	maxPathDepth int
+	allowlist    PathAllowlist
+}
+
+// PathAllowlist matches attack paths against accepted paths
+type PathAllowlist interface {
+	Match(ctx context.Context, sourceID, targetID string, edgeTypes []string) (models.AcceptedPath, bool)
 }
 
 // NewAttackPathEngine creates a new attack path engine
-func NewAttackPathEngine(graphClient *graph.Client, riskEngine *risk.Engine) *AttackPathEngine {
+func NewAttackPathEngine(graphStore graph.GraphStore, riskEngine *risk.Engine) *AttackPathEngine {
 	return &AttackPathEngine{
-		graphClient:  graphClient,
+		graphStore:   graphStore,
 		riskEngine:   riskEngine,
 		pathCache:    make(map[string]*models.AttackPath),
 		cacheExpiry:  make(map[string]time.Time),
@@ -34,17 +41,40 @@ func NewAttackPathEngine(graphClient *graph.Client, riskEngine *risk.Engine) *At
 	}
 }
 
+// SetAllowlist flags paths matching an accepted path as accepted and leaves
+// them out of critical path reports
+func (ape *AttackPathEngine) SetAllowlist(allowlist PathAllowlist) {
+	ape.allowlist = allowlist
+}
+
+// markAccepted flags the paths matching the allowlist of the tenant in ctx
+func (ape *AttackPathEngine) markAccepted(ctx context.Context, paths []models.AttackPath) {
+	if ape.allowlist == nil {
+		return
+	}
+	for i := range paths {
+		entry, ok := ape.allowlist.Match(ctx, paths[i].SourceID, paths[i].TargetID, paths[i].EdgeTypes)
+		paths[i].Accepted = ok
+		paths[i].AcceptedPathID = entry.ID
+	}
+}
+
 // DiscoverPaths finds attack paths between source and target assets
 func (ape *AttackPathEngine) DiscoverPaths(ctx context.Context, sourceID, targetID string, maxHops int) ([]models.AttackPath, error) {
 	cacheKey := fmt.Sprintf("%s->%s:%d", sourceID, targetID, maxHops)
 	
 	// Check cache
 	if path, ok := ape.getFromCache(cacheKey); ok {
-		return []models.AttackPath{*path}, nil
+		paths := []models.AttackPath{*path}
+		ape.markAccepted(ctx, paths)
+		return paths, nil
 	}
 	
 	// Find paths using BFS (Breadth-First Search)
-	paths := ape.findPathsBFS(ctx, sourceID, targetID, maxHops)
+	paths, err := ape.findPathsBFS(ctx, sourceID, targetID, maxHops)
+	if err != nil {
+		return nil, err
+	}
 	
 	if len(paths) == 0 {
 		return []models.AttackPath{}, nil
@@ -52,14 +82,19 @@ func (ape *AttackPathEngine) DiscoverPaths(ctx context.Context, sourceID, target
 	
 	// Enrich paths with vulnerability information
 	for i := range paths {
-		ape.enrichPathWithVulnerabilities(ctx, &paths[i])
+		if err := ape.enrichPathWithVulnerabilities(ctx, &paths[i]); err != nil {
+			return nil, err
+		}
 		paths[i].CumulativeRisk = ape.calculatePathRisk(&paths[i])
+		// Relationship trust is not known to the BFS, so it counts as full trust
+		paths[i].Score(nil)
 	}
+	ape.markAccepted(ctx, paths)
 	
-	// Sort by risk (highest first)
+	// Sort by priority, impact x likelihood (highest first)
 	for i := 0; i < len(paths); i++ {
 		for j := i + 1; j < len(paths); j++ {
-			if paths[j].CumulativeRisk > paths[i].CumulativeRisk {
+			if paths[j].Priority > paths[i].Priority {
 				paths[i], paths[j] = paths[j], paths[i]
 			}
 		}
@@ -73,40 +108,46 @@ func (ape *AttackPathEngine) DiscoverPaths(ctx context.Context, sourceID, target
 	return paths, nil
 }
 
-// findPathsBFS performs breadth-first search to find shortest paths
-func (ape *AttackPathEngine) findPathsBFS(ctx context.Context, sourceID, targetID string, maxHops int) []models.AttackPath {
+// findPathsBFS performs breadth-first search to find shortest paths. The
+// search expands one level at a time so that each level costs a single
+// neighbor query no matter how many assets it contains.
+func (ape *AttackPathEngine) findPathsBFS(ctx context.Context, sourceID, targetID string, maxHops int) ([]models.AttackPath, error) {
 	var paths []models.AttackPath
 	
 	// Initialize BFS
-	queue := [][]string{{sourceID}}
+	frontier := []bfsPath{{assetIDs: []string{sourceID}}}
 	visited := make(map[string]bool)
 	visited[sourceID] = true
 	
-	for len(queue) > 0 && len(queue[0]) <= maxHops {
-		currentPath := queue[0]
-		queue = queue[1:]
-		
-		currentAssetID := currentPath[len(currentPath)-1]
+	for depth := 1; depth <= maxHops && len(frontier) > 0; depth++ {
+		assetIDs := make([]string, 0, len(frontier))
+		for _, currentPath := range frontier {
+			assetIDs = append(assetIDs, currentPath.last())
+		}
 		
-		// Get neighbors of current asset
-		neighbors, err := ape.graphClient.GetAssetNeighbors(ctx, currentAssetID)
+		// Get neighbors of the whole frontier
+		neighbors, err := ape.graphStore.GetNeighborsBatch(ctx, assetIDs)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("failed to get neighbors at depth %d: %w", depth, err)
 		}
 		
-		for _, neighbor := range neighbors {
-			if neighbor.ID == targetID {
-				// Found a path to target
-				fullPath := append(currentPath, neighbor.ID)
-				paths = append(paths, ape.constructAttackPath(fullPath))
-				continue
-			}
-			
-			// Continue BFS if not visited and within depth limit
-			if !visited[neighbor.ID] && len(currentPath) < maxHops {
-				visited[neighbor.ID] = true
-				newPath := append(append([]string{}, currentPath...), neighbor.ID)
-				queue = append(queue, newPath)
+		var next []bfsPath
+		for _, currentPath := range frontier {
+			for _, neighbor := range neighbors[currentPath.last()] {
+				if neighbor.ID == targetID {
+					// Found a path to target
+					fullPath := currentPath.extend(neighbor)
+					path := ape.constructAttackPath(fullPath.assetIDs)
+					path.EdgeTypes = fullPath.edgeTypes
+					paths = append(paths, path)
+					continue
+				}
+				
+				// Continue BFS if not visited and within depth limit
+				if !visited[neighbor.ID] && depth < maxHops {
+					visited[neighbor.ID] = true
+					next = append(next, currentPath.extend(neighbor))
+				}
 			}
 		}
 		
@@ -114,30 +155,53 @@ func (ape *AttackPathEngine) findPathsBFS(ctx context.Context, sourceID, targetI
 		if len(paths) >= 5 {
 			break
 		}
+		
+		frontier = next
 	}
 	
-	return paths
+	return paths, nil
+}
+
+// bfsPath is a partial path explored by findPathsBFS
+type bfsPath struct {
+	assetIDs  []string
+	edgeTypes []string
+}
+
+// last returns the asset at the end of the path
+func (p bfsPath) last() string {
+	return p.assetIDs[len(p.assetIDs)-1]
+}
+
+// extend returns a copy of the path continued to neighbor
+func (p bfsPath) extend(neighbor graph.Neighbor) bfsPath {
+	return bfsPath{
+		assetIDs:  append(append([]string{}, p.assetIDs...), neighbor.ID),
+		edgeTypes: append(append([]string{}, p.edgeTypes...), neighbor.RelationshipType),
+	}
 }
 
 // enrichPathWithVulnerabilities adds finding information to path nodes
-func (ape *AttackPathEngine) enrichPathWithVulnerabilities(ctx context.Context, path *models.AttackPath) {
+func (ape *AttackPathEngine) enrichPathWithVulnerabilities(ctx context.Context, path *models.AttackPath) error {
 	for _, node := range path.Path {
-		findings, err := ape.graphClient.GetAssetFindings(ctx, node.ID)
+		findings, err := ape.graphStore.GetAssetFindings(ctx, node.ID)
 		if err != nil {
-			continue
+			return fmt.Errorf("failed to get findings of %s: %w", node.ID, err)
 		}
 		
 		// Add findings as vulnerabilities to the path
-		for _, finding := range findings {
+		for i := range findings {
+			finding := &findings[i]
 			vuln := models.AttackPathVulnerability{
 				FindingID:       finding.ID,
 				Severity:        finding.Severity,
-				Description:     finding.Title,
+				Description:     finding.Name,
 				ExploitedInPath: ape.isVulnerabilityExploitedInPath(finding, path),
 			}
 			path.Vulnerabilities = append(path.Vulnerabilities, vuln)
 		}
 	}
+	return nil
 }
 
 // isVulnerabilityExploitedInPath checks if a vulnerability can be exploited to traverse the path
@@ -152,7 +216,7 @@ func (ape *AttackPathEngine) isVulnerabilityExploitedInPath(finding *models.Find
 		targetNode := path.Path[i+1]
 		
 		// Determine if this vulnerability enables the transition
-		if ape.canVulnerabilityEnableTransition(finding, sourceNode, targetNode) {
+		if ape.canVulnerabilityEnableTransition(finding, sourceNode.ID, targetNode.ID) {
 			return true
 		}
 	}
@@ -164,9 +228,10 @@ func (ape *AttackPathEngine) isVulnerabilityExploitedInPath(finding *models.Find
 func (ape *AttackPathEngine) canVulnerabilityEnableTransition(finding *models.Finding, sourceNodeID, targetNodeID string) bool {
 	// Check if the finding's category relates to a privilege escalation or access path
 	categories := []string{"privilege_escalation", "lateral_movement", "access_control", "authentication"}
+	category, _ := finding.Metadata["category"].(string)
 	
 	for _, cat := range categories {
-		if finding.Category == cat {
+		if category == cat {
 			return true
 		}
 	}
@@ -213,7 +278,7 @@ func (ape *AttackPathEngine) calculatePathRisk(path *models.AttackPath) float64
 // constructAttackPath creates an AttackPath model from an asset ID chain
 func (ape *AttackPathEngine) constructAttackPath(assetIDs []string) models.AttackPath {
 	path := models.AttackPath{
-		ID:                 generateUUID(),
+		ID:                 uuid.New().String(),
 		SourceID:           assetIDs[0],
 		TargetID:           assetIDs[len(assetIDs)-1],
 		Hops:               len(assetIDs) - 1,
@@ -244,18 +309,18 @@ func (ape *AttackPathEngine) constructAttackPath(assetIDs []string) models.Attac
 // FindPathsFromInternet discovers attack paths from the internet to internal assets
 func (ape *AttackPathEngine) FindPathsFromInternet(ctx context.Context, targetAssetID string, maxHops int) ([]models.AttackPath, error) {
 	// Get all internet-exposed assets (entry points)
-	entryPoints, err := ape.graphClient.FindInternetExposedAssets(ctx)
+	entryPoints, err := ape.internetExposedAssets(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find entry points: %v", err)
+		return nil, fmt.Errorf("failed to find entry points: %w", err)
 	}
 	
 	var allPaths []models.AttackPath
 	
 	// Find paths from each entry point to the target
 	for _, entryPoint := range entryPoints {
-		paths, err := ape.DiscoverPaths(ctx, entryPoint.ID, targetAssetID, maxHops)
+		paths, err := ape.DiscoverPaths(ctx, entryPoint, targetAssetID, maxHops)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("failed to discover paths from %s: %w", entryPoint, err)
 		}
 		allPaths = append(allPaths, paths...)
 	}
@@ -263,17 +328,62 @@ func (ape *AttackPathEngine) FindPathsFromInternet(ctx context.Context, targetAs
 	return allPaths, nil
 }
 
+// internetExposedAssets pages through the compute assets and returns the IDs
+// of those exposed to the internet
+func (ape *AttackPathEngine) internetExposedAssets(ctx context.Context) ([]string, error) {
+	const pageSize = 500
+	
+	var ids []string
+	for offset := 0; ; offset += pageSize {
+		assets, err := ape.graphStore.ListAssets(ctx, models.AssetFilter{
+			Types:  []models.AssetType{models.AssetTypeCompute},
+			Limit:  pageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, asset := range assets {
+			if compute, ok := asset.(*models.Compute); ok && compute.InternetExposed {
+				ids = append(ids, compute.ID)
+			}
+		}
+		if len(assets) < pageSize {
+			return ids, nil
+		}
+	}
+}
+
 // FindCriticalPaths identifies the most dangerous attack paths
 func (ape *AttackPathEngine) FindCriticalPaths(ctx context.Context, minRiskScore float64) ([]models.AttackPath, error) {
-	paths, err := ape.graphClient.GetAllAttackPaths(ctx)
+	entryPoints, err := ape.internetExposedAssets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entry points: %w", err)
+	}
+	if len(entryPoints) == 0 {
+		return []models.AttackPath{}, nil
+	}
+	
+	graphPaths, err := ape.graphStore.FindAttackPaths(ctx, entryPoints, nil, ape.maxPathDepth)
 	if err != nil {
 		return nil, err
 	}
 	
+	paths := make([]models.AttackPath, 0, len(graphPaths))
+	for _, graphPath := range graphPaths {
+		path, err := ape.attackPathFromGraph(ctx, graphPath)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	
+	ape.markAccepted(ctx, paths)
+	
 	var criticalPaths []models.AttackPath
 	
 	for _, path := range paths {
-		if path.CumulativeRisk >= minRiskScore {
+		if path.CumulativeRisk >= minRiskScore && !path.Accepted {
 			criticalPaths = append(criticalPaths, path)
 		}
 	}
@@ -314,7 +424,7 @@ func (ape *AttackPathEngine) SimulateAttack(ctx context.Context, sourceID, targe
 	var totalRisk float64
 	for _, path := range paths {
 		totalRisk += path.CumulativeRisk
-		if path.CumulativeRisk >= 80 {
+		if path.CumulativeRisk >= 80 && !path.Accepted {
 			simulation.CriticalPaths++
 		}
 		if path.CumulativeRisk > simulation.HighestRisk {
@@ -416,3 +526,34 @@ func (ape *AttackPathEngine) ClearCache() {
 	ape.pathCache = make(map[string]*models.AttackPath)
 	ape.cacheExpiry = make(map[string]time.Time)
 }
+
+// attackPathFromGraph converts a path found by the graph store into a
+// scored attack path, using the trust scores of the relationships it
+// traverses
+func (ape *AttackPathEngine) attackPathFromGraph(ctx context.Context, graphPath models.GraphPath) (models.AttackPath, error) {
+	assetIDs := make([]string, 0, len(graphPath.Nodes))
+	for _, node := range graphPath.Nodes {
+		assetIDs = append(assetIDs, node.GetID())
+	}
+	if len(assetIDs) == 0 {
+		return models.AttackPath{}, fmt.Errorf("graph path has no nodes")
+	}
+	
+	path := ape.constructAttackPath(assetIDs)
+	if err := ape.enrichPathWithVulnerabilities(ctx, &path); err != nil {
+		return models.AttackPath{}, err
+	}
+	path.CumulativeRisk = ape.calculatePathRisk(&path)
+	
+	path.EdgeTypes = make([]string, len(graphPath.Edges))
+	trustScores := make([]float64, len(graphPath.Edges))
+	for i, edge := range graphPath.Edges {
+		path.EdgeTypes[i] = string(edge.Relationship.Type)
+		trustScores[i] = 1.0
+		if edge.Relationship.TrustScore != nil {
+			trustScores[i] = *edge.Relationship.TrustScore
+		}
+	}
+	path.Score(trustScores)
+	return path, nil
+}
@@ -21,6 +21,28 @@ type BillingService struct {
 	emailService     *email.Service
 	plans            map[string]Plan
 	featureTiers     map[string]FeatureTier
+	usageReporter    UsageReporter
+}
+
+// UsageReporter reports metered usage to the payment provider
+type UsageReporter interface {
+	ReportUsage(ctx context.Context, subscriptionItemID string, quantity int64, timestamp time.Time) error
+}
+
+// stripeUsageReporter reports usage as Stripe usage records
+type stripeUsageReporter struct{}
+
+func (stripeUsageReporter) ReportUsage(ctx context.Context, subscriptionItemID string, quantity int64, timestamp time.Time) error {
+	params := &stripe.UsageRecordParams{
+		SubscriptionItem: stripe.String(subscriptionItemID),
+		Quantity:         stripe.Int64(quantity),
+		Timestamp:        stripe.Int64(timestamp.Unix()),
+		Action:           stripe.String("set"), // or "increment"
+	}
+	params.Context = ctx
+
+	_, err := usage.New(params)
+	return err
 }
 
 type Plan struct {
@@ -93,9 +115,16 @@ func NewBillingService(stripeKey, webhookSecret string, tenantStore tenant.Store
 		emailService:  email.NewService(),
 		plans:         loadPlans(),
 		featureTiers:  loadFeatureTiers(),
+		usageReporter: stripeUsageReporter{},
 	}
 }
 
+// SetUsageReporter replaces Stripe as the destination of metered usage, e.g.
+// with a recording fake in sandbox mode
+func (bs *BillingService) SetUsageReporter(reporter UsageReporter) {
+	bs.usageReporter = reporter
+}
+
 // RecordUsage records usage for a tenant and creates Stripe usage records
 func (bs *BillingService) RecordUsage(ctx context.Context, usageRecord *UsageRecord) error {
 	// Get tenant
@@ -118,14 +147,7 @@ func (bs *BillingService) RecordUsage(ctx context.Context, usageRecord *UsageRec
 	}
 	
 	// Record usage in Stripe
-	params := &stripe.UsageRecordParams{
-		SubscriptionItem: stripe.String(subItemID),
-		Quantity:         stripe.Int64(usageRecord.Quantity),
-		Timestamp:        stripe.Int64(usageRecord.Timestamp.Unix()),
-		Action:           stripe.String("set"), // or "increment"
-	}
-	
-	if _, err := usage.New(params); err != nil {
+	if err := bs.usageReporter.ReportUsage(ctx, subItemID, usageRecord.Quantity, usageRecord.Timestamp); err != nil {
 		return fmt.Errorf("failed to record usage in Stripe: %v", err)
 	}
 	
@@ -2,8 +2,13 @@ package collector
 
 import (
 	"context"
+	"encoding/json"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/prompt-general/securizon/internal/config"
 	"github.com/prompt-general/securizon/internal/kafka"
@@ -18,17 +23,144 @@ type Manager struct {
 	wg       sync.WaitGroup
 	running  bool
 	mu       sync.Mutex
+
+	throttleMu   sync.Mutex
+	throttles    map[string]*ProviderThrottle
+	resumePoints map[string]string // page tokens of interrupted collections
+
+	listersMu sync.Mutex
+	listers   map[string]map[string][]ResourceLister // by provider and account
+
+	statusMu sync.Mutex
+	statuses map[string]CollectionResult // latest result by provider:account
 }
 
 // NewManager creates a new collector manager
 func NewManager(ctx context.Context, cfg *config.Config, producer kafka.Producer) *Manager {
 	childCtx, cancel := context.WithCancel(ctx)
-	return &Manager{
+	m := &Manager{
 		ctx:      childCtx,
 		cancel:   cancel,
 		cfg:      cfg,
 		producer: producer,
 		running:  false,
+
+		throttles:    make(map[string]*ProviderThrottle),
+		resumePoints: make(map[string]string),
+		listers:      make(map[string]map[string][]ResourceLister),
+		statuses:     make(map[string]CollectionResult),
+	}
+	m.loadResumePoints()
+	return m
+}
+
+// ResourceLister lists one resource type of a provider account a page at a
+// time. Provider clients implement it: ListPage publishes the resources on
+// the page at token and returns how many it collected and the next page
+// token, empty on the last page. Throttling responses are recognised from
+// the returned error and retried.
+type ResourceLister interface {
+	ResourceType() string
+	ListPage(ctx context.Context, token string) (collected int, next string, err error)
+}
+
+// Register adds a lister to the collections of a provider account. Listers
+// must be registered before Start.
+func (m *Manager) Register(provider, account string, lister ResourceLister) {
+	m.listersMu.Lock()
+	defer m.listersMu.Unlock()
+
+	if m.listers[provider] == nil {
+		m.listers[provider] = make(map[string][]ResourceLister)
+	}
+	m.listers[provider][account] = append(m.listers[provider][account], lister)
+}
+
+// Throttle returns the rate limiter and concurrency bound for provider's APIs
+func (m *Manager) Throttle(provider string) *ProviderThrottle {
+	m.throttleMu.Lock()
+	defer m.throttleMu.Unlock()
+
+	throttle, ok := m.throttles[provider]
+	if !ok {
+		throttle = NewProviderThrottle(provider, m.cfg.Collector)
+		m.throttles[provider] = throttle
+	}
+	return throttle
+}
+
+// Metrics returns API call, throttle, retry and failure counts by provider
+func (m *Manager) Metrics() map[string]ThrottleStats {
+	m.throttleMu.Lock()
+	defer m.throttleMu.Unlock()
+
+	metrics := make(map[string]ThrottleStats, len(m.throttles))
+	for provider, throttle := range m.throttles {
+		metrics[provider] = throttle.Stats()
+	}
+	return metrics
+}
+
+func (m *Manager) resumePoint(key string) string {
+	m.throttleMu.Lock()
+	defer m.throttleMu.Unlock()
+	return m.resumePoints[key]
+}
+
+func (m *Manager) saveResumePoint(key, token string) {
+	m.throttleMu.Lock()
+	defer m.throttleMu.Unlock()
+
+	if m.resumePoints[key] == token {
+		return
+	}
+	if token == "" {
+		delete(m.resumePoints, key)
+	} else {
+		m.resumePoints[key] = token
+	}
+	m.writeResumePoints()
+}
+
+// loadResumePoints reads the resume points saved by a previous run
+func (m *Manager) loadResumePoints() {
+	path := m.cfg.Collector.ResumeStatePath
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &m.resumePoints)
+	}
+	if err != nil {
+		log.Printf("Failed to load collection resume points from %s: %v", path, err)
+	}
+}
+
+// writeResumePoints saves the resume points so a restarted collector
+// resumes interrupted collections. The file is replaced atomically. The
+// caller must hold throttleMu.
+func (m *Manager) writeResumePoints() {
+	path := m.cfg.Collector.ResumeStatePath
+	if path == "" {
+		return
+	}
+
+	data, err := json.Marshal(m.resumePoints)
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			if err = os.WriteFile(tmp, data, 0o600); err == nil {
+				err = os.Rename(tmp, path)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to save collection resume points to %s: %v", path, err)
 	}
 }
 
@@ -43,10 +175,19 @@ func (m *Manager) Start() {
 	m.mu.Unlock()
 
 	log.Println("Starting collector manager...")
-	
-	// Start AWS collector routine
-	m.wg.Add(1)
-	go m.runAWSCollector()
+
+	// Start a collection routine per provider with registered listers
+	m.listersMu.Lock()
+	providers := make([]string, 0, len(m.listers))
+	for provider := range m.listers {
+		providers = append(providers, provider)
+	}
+	m.listersMu.Unlock()
+
+	for _, provider := range providers {
+		m.wg.Add(1)
+		go m.runCollector(provider)
+	}
 }
 
 // Stop gracefully shuts down the collector
@@ -65,13 +206,73 @@ func (m *Manager) Stop() {
 	log.Println("Collector manager stopped")
 }
 
-// runAWSCollector runs the AWS collection routine
-func (m *Manager) runAWSCollector() {
+// runCollector collects every account of a provider once at start and then
+// every full sync interval
+func (m *Manager) runCollector(provider string) {
 	defer m.wg.Done()
-	
-	log.Println("AWS collector routine started")
-	
-	<-m.ctx.Done()
-	
-	log.Println("AWS collector routine stopped")
+
+	interval := durationOr(m.cfg.Collector.FullSyncInterval, defaultFullSyncInterval)
+	log.Printf("%s collector routine started, syncing every %v", provider, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.collectProvider(m.ctx, provider)
+
+		select {
+		case <-m.ctx.Done():
+			log.Printf("%s collector routine stopped", provider)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectProvider collects each account of a provider in turn
+func (m *Manager) collectProvider(ctx context.Context, provider string) {
+	m.listersMu.Lock()
+	accounts := make([]string, 0, len(m.listers[provider]))
+	for account := range m.listers[provider] {
+		accounts = append(accounts, account)
+	}
+	m.listersMu.Unlock()
+	sort.Strings(accounts)
+
+	for _, account := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		m.Collect(ctx, provider, account)
+	}
+}
+
+// Collect runs every lister registered for a provider account through the
+// provider's throttle and records the result. A lister that fails is
+// recorded as skipped and resumes from its failed page next time; the
+// collection fails only when it is cancelled.
+func (m *Manager) Collect(ctx context.Context, provider, account string) CollectionResult {
+	m.listersMu.Lock()
+	listers := append([]ResourceLister(nil), m.listers[provider][account]...)
+	m.listersMu.Unlock()
+
+	collection := m.BeginCollection(provider, account)
+	collectCtx := WithCollection(ctx, collection)
+	for _, lister := range listers {
+		resourceType := lister.ResourceType()
+		err := m.CollectPages(collectCtx, provider, account+"/"+resourceType, func(ctx context.Context, token string) (string, error) {
+			collected, next, err := lister.ListPage(ctx, token)
+			if err != nil {
+				return "", err
+			}
+			collection.Collected(resourceType, collected)
+			return next, nil
+		})
+		if ctx.Err() != nil {
+			return m.FinishCollection(context.Background(), collection, ctx.Err())
+		}
+		if err != nil {
+			collection.Skipped(resourceType, "", "list", err)
+		}
+	}
+	return m.FinishCollection(ctx, collection, nil)
 }
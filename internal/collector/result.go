@@ -0,0 +1,283 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultStatusTopic receives collection results when the collector
+// configuration does not name a topic
+const defaultStatusTopic = "metrics"
+
+// maxResourceErrors bounds the errors kept in detail per collection; further
+// errors are still counted by resource type and cause
+const maxResourceErrors = 500
+
+// Collection statuses
+const (
+	CollectionSucceeded = "succeeded"
+	CollectionPartial   = "partial"
+	CollectionFailed    = "failed"
+)
+
+// ResourceError records a resource, or a whole resource type when
+// ResourceID is empty, that a collection skipped
+type ResourceError struct {
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id,omitempty"`
+	Operation    string `json:"operation,omitempty"`
+	Cause        string `json:"cause"` // provider error code, e.g. AccessDenied
+	Message      string `json:"message"`
+}
+
+// SkippedResources counts the resources of one type skipped for one cause
+type SkippedResources struct {
+	ResourceType string `json:"resource_type"`
+	Cause        string `json:"cause"`
+	Count        int    `json:"count"`
+}
+
+// CollectionResult is the outcome of one collection of a provider account
+type CollectionResult struct {
+	Type        string             `json:"type"` // always "collection_result"
+	CollectorID string             `json:"collector_id,omitempty"`
+	Provider    string             `json:"provider"`
+	Account     string             `json:"account"`
+	Status      string             `json:"status"`
+	StartedAt   time.Time          `json:"started_at"`
+	FinishedAt  time.Time          `json:"finished_at"`
+	Duration    string             `json:"duration"`
+	Collected   map[string]int     `json:"collected"` // by resource type
+	Pages       int                `json:"pages"`
+	Skipped     []SkippedResources `json:"skipped,omitempty"`
+	Errors      []ResourceError    `json:"errors,omitempty"`
+	// ErrorsTruncated is set when more errors occurred than are listed
+	ErrorsTruncated bool   `json:"errors_truncated,omitempty"`
+	Error           string `json:"error,omitempty"` // why the collection stopped
+}
+
+// Summary describes the result in one line, e.g. "collection of aws account
+// 1234 skipped 12 s3_bucket due to AccessDenied"
+func (r CollectionResult) Summary() string {
+	total := 0
+	for _, n := range r.Collected {
+		total += n
+	}
+
+	summary := fmt.Sprintf("collection of %s account %s %s: %d resources in %d pages", r.Provider, r.Account, r.Status, total, r.Pages)
+	if len(r.Skipped) > 0 {
+		parts := make([]string, len(r.Skipped))
+		for i, s := range r.Skipped {
+			parts[i] = fmt.Sprintf("%d %s due to %s", s.Count, s.ResourceType, s.Cause)
+		}
+		summary += ", skipped " + strings.Join(parts, ", ")
+	}
+	if r.Error != "" {
+		summary += ", stopped: " + r.Error
+	}
+	return summary
+}
+
+// Collection records what one collection of a provider account fetched and
+// skipped. It is safe for concurrent use by the collection's workers.
+type Collection struct {
+	mu        sync.Mutex
+	result    CollectionResult
+	skipped   map[SkippedResources]int
+	truncated bool
+}
+
+// Collected records count resources of resourceType as collected
+func (c *Collection) Collected(resourceType string, count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.result.Collected[resourceType] += count
+}
+
+// Skipped records a resource skipped because of err. An empty resourceID
+// means the whole listing of resourceType failed.
+func (c *Collection) Skipped(resourceType, resourceID, operation string, err error) {
+	cause := ErrorCause(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.skipped[SkippedResources{ResourceType: resourceType, Cause: cause}]++
+	if len(c.result.Errors) >= maxResourceErrors {
+		c.truncated = true
+		return
+	}
+	c.result.Errors = append(c.result.Errors, ResourceError{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Operation:    operation,
+		Cause:        cause,
+		Message:      err.Error(),
+	})
+}
+
+// pageFetched counts an API page fetched by CollectPages
+func (c *Collection) pageFetched() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.result.Pages++
+}
+
+// Result returns the result so far; err is the error that ended the
+// collection, if any
+func (c *Collection) Result(err error) CollectionResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := c.result
+	result.FinishedAt = time.Now()
+	result.Duration = result.FinishedAt.Sub(result.StartedAt).String()
+	result.Collected = make(map[string]int, len(c.result.Collected))
+	for resourceType, n := range c.result.Collected {
+		result.Collected[resourceType] = n
+	}
+	result.Errors = append([]ResourceError(nil), c.result.Errors...)
+	result.ErrorsTruncated = c.truncated
+
+	result.Skipped = make([]SkippedResources, 0, len(c.skipped))
+	for key, n := range c.skipped {
+		key.Count = n
+		result.Skipped = append(result.Skipped, key)
+	}
+	sort.Slice(result.Skipped, func(i, j int) bool {
+		if result.Skipped[i].Count != result.Skipped[j].Count {
+			return result.Skipped[i].Count > result.Skipped[j].Count
+		}
+		return result.Skipped[i].ResourceType < result.Skipped[j].ResourceType
+	})
+
+	switch {
+	case err != nil:
+		result.Status = CollectionFailed
+		result.Error = err.Error()
+	case len(result.Skipped) > 0:
+		result.Status = CollectionPartial
+	default:
+		result.Status = CollectionSucceeded
+	}
+	return result
+}
+
+// ErrorCause returns the provider error code of err, such as AccessDenied,
+// for grouping skipped resources. Provider SDK errors expose it through an
+// ErrorCode method.
+func ErrorCause(err error) string {
+	var coded interface{ ErrorCode() string }
+	switch {
+	case errors.As(err, &coded) && coded.ErrorCode() != "":
+		return coded.ErrorCode()
+	case IsThrottled(err):
+		return "Throttled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Timeout"
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	default:
+		return "Unknown"
+	}
+}
+
+type collectionKey struct{}
+
+// WithCollection returns a context under which CollectPages counts the pages
+// it fetches in c
+func WithCollection(ctx context.Context, c *Collection) context.Context {
+	return context.WithValue(ctx, collectionKey{}, c)
+}
+
+func collectionFromContext(ctx context.Context) *Collection {
+	c, _ := ctx.Value(collectionKey{}).(*Collection)
+	return c
+}
+
+// BeginCollection starts recording a collection of a provider account
+func (m *Manager) BeginCollection(provider, account string) *Collection {
+	return &Collection{
+		result: CollectionResult{
+			Type:        "collection_result",
+			CollectorID: m.cfg.Collector.ID,
+			Provider:    provider,
+			Account:     account,
+			StartedAt:   time.Now(),
+			Collected:   make(map[string]int),
+		},
+		skipped: make(map[SkippedResources]int),
+	}
+}
+
+// FinishCollection logs the collection's result, publishes it to the status
+// topic and keeps it as the account's latest status. err is the error that
+// ended the collection, if any.
+func (m *Manager) FinishCollection(ctx context.Context, c *Collection, err error) CollectionResult {
+	result := c.Result(err)
+
+	fields, _ := json.Marshal(result)
+	log.Printf("%s collection_result=%s", result.Summary(), fields)
+
+	m.statusMu.Lock()
+	m.statuses[result.Provider+":"+result.Account] = result
+	m.statusMu.Unlock()
+
+	topic := m.cfg.Collector.StatusTopic
+	if topic == "" {
+		topic = defaultStatusTopic
+	}
+	if m.producer != nil {
+		key := []byte(result.Provider + ":" + result.Account)
+		if err := m.producer.Send(ctx, topic, key, fields); err != nil {
+			log.Printf("Failed to publish collection result for %s account %s: %v", result.Provider, result.Account, err)
+		}
+	}
+	return result
+}
+
+// CollectionStatus returns the latest result of each account collected,
+// optionally only those of provider
+func (m *Manager) CollectionStatus(provider string) []CollectionResult {
+	m.statusMu.Lock()
+	results := make([]CollectionResult, 0, len(m.statuses))
+	for _, result := range m.statuses {
+		if provider == "" || result.Provider == provider {
+			results = append(results, result)
+		}
+	}
+	m.statusMu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Provider != results[j].Provider {
+			return results[i].Provider < results[j].Provider
+		}
+		return results[i].Account < results[j].Account
+	})
+	return results
+}
+
+// StatusHandler serves CollectionStatus as JSON, filtered by the provider
+// query parameter
+func (m *Manager) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"collections": m.CollectionStatus(r.URL.Query().Get("provider")),
+			"throttles":   m.Metrics(),
+		})
+	})
+}
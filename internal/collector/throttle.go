@@ -0,0 +1,335 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prompt-general/securizon/internal/config"
+)
+
+// Defaults used when neither the provider nor the collector-wide settings
+// set a value
+const (
+	defaultRequestsPerSecond = 10
+	defaultMaxConcurrency    = 5
+	defaultMaxRetries        = 5
+	defaultInitialBackoff    = 500 * time.Millisecond
+	defaultMaxBackoff        = 30 * time.Second
+	defaultFullSyncInterval  = 24 * time.Hour
+)
+
+// ErrThrottled marks a provider API error as a throttling response
+var ErrThrottled = errors.New("request throttled by provider")
+
+// ThrottleError is returned by provider API calls that were throttled.
+// RetryAfter is the delay the provider asked for, if any.
+type ThrottleError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ThrottleError) Error() string {
+	if e.Err == nil {
+		return ErrThrottled.Error()
+	}
+	return fmt.Sprintf("%s: %v", ErrThrottled, e.Err)
+}
+
+func (e *ThrottleError) Unwrap() error { return e.Err }
+
+// Is reports throttle errors as ErrThrottled
+func (e *ThrottleError) Is(target error) bool { return target == ErrThrottled }
+
+// IsThrottled reports whether err is a throttling response
+func IsThrottled(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
+
+// throttleCodes are the error codes providers return when they throttle a
+// request
+var throttleCodes = map[string]bool{
+	// AWS
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"RequestLimitExceeded":                   true,
+	"RequestThrottled":                       true,
+	"TooManyRequestsException":               true,
+	"SlowDown":                               true,
+	"ProvisionedThroughputExceededException": true,
+	// Azure
+	"TooManyRequests": true,
+	// GCP
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+	"RESOURCE_EXHAUSTED":    true,
+}
+
+// asThrottleError returns err as a ThrottleError when it is a provider's
+// throttling response. SDK errors are recognised by their error code, from
+// an ErrorCode method, or by an HTTP 429 status, from an HTTPStatusCode or
+// StatusCode method. A RetryAfter method supplies the requested delay.
+func asThrottleError(err error) error {
+	if err == nil || IsThrottled(err) {
+		return err
+	}
+
+	var coded interface{ ErrorCode() string }
+	var httpStatus interface{ HTTPStatusCode() int }
+	var status interface{ StatusCode() int }
+	throttled := (errors.As(err, &coded) && throttleCodes[coded.ErrorCode()]) ||
+		(errors.As(err, &httpStatus) && httpStatus.HTTPStatusCode() == http.StatusTooManyRequests) ||
+		(errors.As(err, &status) && status.StatusCode() == http.StatusTooManyRequests)
+	if !throttled {
+		return err
+	}
+
+	throttleErr := &ThrottleError{Err: err}
+	var retry interface{ RetryAfter() time.Duration }
+	if errors.As(err, &retry) {
+		throttleErr.RetryAfter = retry.RetryAfter()
+	}
+	return throttleErr
+}
+
+// ThrottleStats are the call counters of one provider
+type ThrottleStats struct {
+	Calls     int64 `json:"calls"`
+	Throttled int64 `json:"throttled"`
+	Retries   int64 `json:"retries"`
+	Failures  int64 `json:"failures"`
+	InFlight  int64 `json:"in_flight"`
+}
+
+// ProviderThrottle rate limits, bounds the concurrency of and retries
+// throttled calls to one provider's APIs
+type ProviderThrottle struct {
+	provider       string
+	limiter        *tokenBucket
+	slots          chan struct{}
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	calls     int64
+	throttled int64
+	retries   int64
+	failures  int64
+	inFlight  int64
+}
+
+// NewProviderThrottle creates the throttle for provider from the collector
+// configuration
+func NewProviderThrottle(provider string, cfg config.CollectorConfig) *ProviderThrottle {
+	limits := cfg.Providers[provider]
+
+	rps := limits.RequestsPerSecond
+	if rps <= 0 {
+		rps = cfg.RateLimit.RequestsPerSecond
+	}
+	if rps <= 0 {
+		rps = defaultRequestsPerSecond
+	}
+	burst := limits.BurstSize
+	if burst <= 0 {
+		burst = cfg.RateLimit.BurstSize
+	}
+	if burst <= 0 {
+		burst = rps
+	}
+	concurrency := limits.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = cfg.MaxConcurrentCollections
+	}
+	if concurrency <= 0 {
+		concurrency = defaultMaxConcurrency
+	}
+
+	maxRetries := cfg.Retry.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+	initialBackoff := durationOr(cfg.Retry.InitialBackoff, defaultInitialBackoff)
+	maxBackoff := durationOr(cfg.Retry.MaxBackoff, defaultMaxBackoff)
+
+	return &ProviderThrottle{
+		provider:       provider,
+		limiter:        newTokenBucket(float64(rps), burst),
+		slots:          make(chan struct{}, concurrency),
+		maxRetries:     maxRetries,
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+	}
+}
+
+// Do calls fn once a concurrency slot and a rate limit token are available.
+// Throttled calls, recognised by asThrottleError, are retried with
+// exponential backoff, honouring the provider's requested delay; other errors
+// are returned immediately.
+func (t *ProviderThrottle) Do(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	select {
+	case t.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	atomic.AddInt64(&t.inFlight, 1)
+	defer func() {
+		atomic.AddInt64(&t.inFlight, -1)
+		<-t.slots
+	}()
+
+	backoff := t.initialBackoff
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		atomic.AddInt64(&t.calls, 1)
+		err := asThrottleError(fn(ctx))
+		if err == nil {
+			return nil
+		}
+		if !IsThrottled(err) {
+			atomic.AddInt64(&t.failures, 1)
+			return err
+		}
+
+		atomic.AddInt64(&t.throttled, 1)
+		if attempt >= t.maxRetries {
+			atomic.AddInt64(&t.failures, 1)
+			return fmt.Errorf("%s %s still throttled after %d retries: %w", t.provider, operation, attempt, err)
+		}
+
+		delay := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		var throttleErr *ThrottleError
+		if errors.As(err, &throttleErr) && throttleErr.RetryAfter > delay {
+			delay = throttleErr.RetryAfter
+		}
+		log.Printf("%s %s throttled, retrying in %v (attempt %d/%d)", t.provider, operation, delay, attempt+1, t.maxRetries)
+		atomic.AddInt64(&t.retries, 1)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if backoff *= 2; backoff > t.maxBackoff {
+			backoff = t.maxBackoff
+		}
+	}
+}
+
+// Stats returns the provider's call counters
+func (t *ProviderThrottle) Stats() ThrottleStats {
+	return ThrottleStats{
+		Calls:     atomic.LoadInt64(&t.calls),
+		Throttled: atomic.LoadInt64(&t.throttled),
+		Retries:   atomic.LoadInt64(&t.retries),
+		Failures:  atomic.LoadInt64(&t.failures),
+		InFlight:  atomic.LoadInt64(&t.inFlight),
+	}
+}
+
+// CollectPages walks a paginated provider API through the throttle. fetch
+// is called with the page token and returns the next token, empty on the
+// last page. When a page fails its token is saved under key, and the next
+// call for key resumes from that page instead of starting over, also after a
+// restart when the collector has a resume state path. Pages are counted in
+// the Collection of ctx, if any.
+func (m *Manager) CollectPages(ctx context.Context, provider, key string, fetch func(ctx context.Context, token string) (string, error)) error {
+	throttle := m.Throttle(provider)
+	resumeKey := provider + ":" + key
+
+	collection := collectionFromContext(ctx)
+	token := m.resumePoint(resumeKey)
+	if token != "" {
+		log.Printf("Resuming %s collection of %s from saved page", provider, key)
+	}
+
+	for {
+		var next string
+		err := throttle.Do(ctx, key, func(ctx context.Context) error {
+			var err error
+			next, err = fetch(ctx, token)
+			return err
+		})
+		if err != nil {
+			m.saveResumePoint(resumeKey, token)
+			return fmt.Errorf("failed to collect %s %s: %w", provider, key, err)
+		}
+		if collection != nil {
+			collection.pageFetched()
+		}
+
+		if next == "" {
+			m.saveResumePoint(resumeKey, "")
+			return nil
+		}
+		token = next
+	}
+}
+
+// tokenBucket is a token bucket rate limiter
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// durationOr parses value, falling back to def when it is empty or invalid
+func durationOr(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := config.GetDuration(value)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prompt-general/securizon/internal/config"
+)
+
+type sdkError struct {
+	code       string
+	retryAfter time.Duration
+}
+
+func (e *sdkError) Error() string             { return e.code }
+func (e *sdkError) ErrorCode() string         { return e.code }
+func (e *sdkError) RetryAfter() time.Duration { return e.retryAfter }
+
+type statusError int
+
+func (e statusError) Error() string   { return "request failed" }
+func (e statusError) StatusCode() int { return int(e) }
+
+func TestAsThrottleErrorMapsProviderErrors(t *testing.T) {
+	err := asThrottleError(&sdkError{code: "ThrottlingException", retryAfter: 3 * time.Second})
+	var throttleErr *ThrottleError
+	if !errors.As(err, &throttleErr) || throttleErr.RetryAfter != 3*time.Second {
+		t.Errorf("asThrottleError(ThrottlingException) = %v, want a ThrottleError retrying after 3s", err)
+	}
+	if !IsThrottled(asThrottleError(statusError(429))) {
+		t.Error("an HTTP 429 response is not mapped to a throttle error")
+	}
+	for _, err := range []error{&sdkError{code: "AccessDenied"}, statusError(500), errors.New("boom")} {
+		if IsThrottled(asThrottleError(err)) {
+			t.Errorf("asThrottleError(%v) is throttled", err)
+		}
+	}
+}
+
+// pagedLister serves three pages and fails once on the page named failOn
+type pagedLister struct {
+	failOn string
+	tokens []string
+}
+
+func (l *pagedLister) ResourceType() string { return "s3_bucket" }
+
+func (l *pagedLister) ListPage(ctx context.Context, token string) (int, string, error) {
+	l.tokens = append(l.tokens, token)
+	if token == l.failOn {
+		l.failOn = ""
+		return 0, "", &sdkError{code: "AccessDenied"}
+	}
+	next := map[string]string{"": "page-2", "page-2": "page-3"}[token]
+	return 10, next, nil
+}
+
+func TestCollectResumesFromSavedPageAfterRestart(t *testing.T) {
+	cfg := &config.Config{Collector: config.CollectorConfig{
+		ResumeStatePath: filepath.Join(t.TempDir(), "resume.json"),
+		Retry:           config.RetryConfig{InitialBackoff: "1ms", MaxBackoff: "1ms"},
+	}}
+
+	lister := &pagedLister{failOn: "page-2"}
+	m := NewManager(context.Background(), cfg, nil)
+	m.Register("aws", "1234", lister)
+	if result := m.Collect(context.Background(), "aws", "1234"); result.Status != CollectionPartial {
+		t.Fatalf("first collection status = %s, want %s", result.Status, CollectionPartial)
+	}
+
+	restarted := NewManager(context.Background(), cfg, nil)
+	restarted.Register("aws", "1234", lister)
+	result := restarted.Collect(context.Background(), "aws", "1234")
+	if result.Status != CollectionSucceeded || result.Collected["s3_bucket"] != 20 {
+		t.Errorf("resumed collection = %s with %d collected, want %s with 20", result.Status, result.Collected["s3_bucket"], CollectionSucceeded)
+	}
+	if want := []string{"", "page-2", "page-2", "page-3"}; !reflect.DeepEqual(lister.tokens, want) {
+		t.Errorf("pages fetched = %q, want %q", lister.tokens, want)
+	}
+}
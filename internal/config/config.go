@@ -35,6 +35,31 @@ type CollectorConfig struct {
 	EventPollInterval        string           `yaml:"event_poll_interval"`
 	MaxConcurrentCollections int              `yaml:"max_concurrent_collections"`
 	RateLimit                RateLimitConfig  `yaml:"rate_limit"`
+	Retry                    RetryConfig      `yaml:"retry"`
+	// Providers overrides the rate limit and concurrency per provider
+	// (aws, azure, gcp, github)
+	Providers map[string]ProviderLimitConfig `yaml:"providers"`
+	// StatusTopic receives the result of each collection; defaults to metrics
+	StatusTopic string `yaml:"status_topic"`
+	// ResumeStatePath is the file the page tokens of interrupted collections
+	// are saved to, so a restarted collector resumes them. Empty keeps them
+	// in memory only.
+	ResumeStatePath string `yaml:"resume_state_path"`
+}
+
+// ProviderLimitConfig limits API calls made to a single provider. Zero values
+// fall back to the collector-wide settings.
+type ProviderLimitConfig struct {
+	RequestsPerSecond int `yaml:"requests_per_second"`
+	BurstSize         int `yaml:"burst_size"`
+	MaxConcurrency    int `yaml:"max_concurrency"`
+}
+
+// RetryConfig controls backoff when a provider throttles requests
+type RetryConfig struct {
+	MaxRetries     int    `yaml:"max_retries"`
+	InitialBackoff string `yaml:"initial_backoff"`
+	MaxBackoff     string `yaml:"max_backoff"`
 }
 
 type AWSConfig struct {
@@ -182,6 +207,8 @@ type HealthConfig struct {
 	Path          string `yaml:"path"`
 	ReadinessPath string `yaml:"readiness_path"`
 	LivenessPath  string `yaml:"liveness_path"`
+	// CollectionStatusPath serves the latest collection results on collectors
+	CollectionStatusPath string `yaml:"collection_status_path"`
 }
 
 type TracingConfig struct {
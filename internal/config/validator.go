@@ -12,6 +12,11 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("version is required")
 	}
 
+	// Validate Collector configuration
+	if err := c.validateCollector(); err != nil {
+		return fmt.Errorf("collector config error: %v", err)
+	}
+
 	// Validate Kafka configuration
 	if err := c.validateKafka(); err != nil {
 		return fmt.Errorf("kafka config error: %v", err)
@@ -40,6 +45,40 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+func (c *Config) validateCollector() error {
+	if c.Collector.MaxConcurrentCollections < 0 {
+		return fmt.Errorf("max_concurrent_collections must not be negative")
+	}
+
+	if c.Collector.RateLimit.RequestsPerSecond < 0 || c.Collector.RateLimit.BurstSize < 0 {
+		return fmt.Errorf("rate_limit values must not be negative")
+	}
+
+	for provider, limits := range c.Collector.Providers {
+		if limits.RequestsPerSecond < 0 || limits.BurstSize < 0 || limits.MaxConcurrency < 0 {
+			return fmt.Errorf("limits for provider %s must not be negative", provider)
+		}
+	}
+
+	if c.Collector.Retry.MaxRetries < 0 {
+		return fmt.Errorf("retry.max_retries must not be negative")
+	}
+
+	for name, value := range map[string]string{
+		"retry.initial_backoff": c.Collector.Retry.InitialBackoff,
+		"retry.max_backoff":     c.Collector.Retry.MaxBackoff,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := GetDuration(value); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
 func (c *Config) validateKafka() error {
 	if len(c.Kafka.BootstrapServers) == 0 {
 		return fmt.Errorf("bootstrap_servers is required")
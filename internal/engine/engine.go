@@ -2,6 +2,7 @@ package engine
 
 import (
     "context"
+    "encoding/json"
     "fmt"
     "log"
     "sync"
@@ -19,14 +20,79 @@ type RemediationEngine struct {
     playbookManager *playbook.Manager
     store           store.Store
     workQueue       chan RemediationWorkItem
-    workers         int
+    config          EngineConfig
+    findingEvents   FindingEventPublisher
     mu              sync.RWMutex
     metrics         *RemediationMetrics
 }
 
+// EngineConfig controls remediation throughput and retry behaviour
+type EngineConfig struct {
+    Workers       int           `json:"workers"`
+    QueueSize     int           `json:"queue_size"`
+    WorkerTimeout time.Duration `json:"worker_timeout"` // per work item, 0 disables
+    MaxRetries    int           `json:"max_retries"`
+    RetryBackoff  time.Duration `json:"retry_backoff"`
+    // Finished remediations older than JobRetention are purged every CleanupInterval
+    JobRetention     time.Duration `json:"job_retention"`
+    CleanupInterval  time.Duration `json:"cleanup_interval"`
+    CleanupBatchSize int           `json:"cleanup_batch_size"`
+    // AutoResolveFindings emits a finding-resolved event for every finding a
+    // successful remediation addressed
+    AutoResolveFindings bool `json:"auto_resolve_findings"`
+    // Sandbox records remediation steps and approval notifications instead
+    // of running and sending them
+    Sandbox bool `json:"sandbox"`
+}
+
+// DefaultEngineConfig returns default remediation engine configuration
+func DefaultEngineConfig() EngineConfig {
+    return EngineConfig{
+        Workers:       5,
+        QueueSize:     1000,
+        WorkerTimeout: 15 * time.Minute,
+        MaxRetries:    2,
+        RetryBackoff:  5 * time.Second,
+        JobRetention:     30 * 24 * time.Hour,
+        CleanupInterval:  time.Hour,
+        CleanupBatchSize: 500,
+        AutoResolveFindings: true,
+    }
+}
+
+// Validate checks the configuration for invalid values
+func (c EngineConfig) Validate() error {
+    if c.Workers <= 0 {
+        return fmt.Errorf("workers must be positive, got %d", c.Workers)
+    }
+    if c.QueueSize <= 0 {
+        return fmt.Errorf("queue size must be positive, got %d", c.QueueSize)
+    }
+    if c.WorkerTimeout < 0 {
+        return fmt.Errorf("worker timeout must not be negative, got %v", c.WorkerTimeout)
+    }
+    if c.MaxRetries < 0 {
+        return fmt.Errorf("max retries must not be negative, got %d", c.MaxRetries)
+    }
+    if c.RetryBackoff < 0 {
+        return fmt.Errorf("retry backoff must not be negative, got %v", c.RetryBackoff)
+    }
+    if c.JobRetention <= 0 {
+        return fmt.Errorf("job retention must be positive, got %v", c.JobRetention)
+    }
+    if c.CleanupInterval <= 0 {
+        return fmt.Errorf("cleanup interval must be positive, got %v", c.CleanupInterval)
+    }
+    if c.CleanupBatchSize <= 0 {
+        return fmt.Errorf("cleanup batch size must be positive, got %d", c.CleanupBatchSize)
+    }
+    return nil
+}
+
 type RemediationWorkItem struct {
     ID         string                 `json:"id"`
     FindingID  string                 `json:"finding_id"`
+    FindingIDs []string               `json:"finding_ids,omitempty"` // further findings addressed by the playbook
     PlaybookID string                 `json:"playbook_id"`
     Parameters map[string]interface{} `json:"parameters"`
     Requestor  string                 `json:"requestor"`
@@ -34,6 +100,74 @@ type RemediationWorkItem struct {
     CreatedAt  time.Time              `json:"created_at"`
 }
 
+// ResolvedFindingIDs returns every finding the work item addresses, without
+// duplicates
+func (w RemediationWorkItem) ResolvedFindingIDs() []string {
+    seen := make(map[string]bool)
+    var ids []string
+    for _, id := range append([]string{w.FindingID}, w.FindingIDs...) {
+        if id == "" || seen[id] {
+            continue
+        }
+        seen[id] = true
+        ids = append(ids, id)
+    }
+    return ids
+}
+
+// FindingEventPublisher publishes finding events to the platform's event bus
+type FindingEventPublisher interface {
+    PublishFindingResolved(ctx context.Context, event FindingResolvedEvent) error
+}
+
+// MessageSender sends a keyed message to a topic
+type MessageSender interface {
+    Send(ctx context.Context, topic string, key []byte, value []byte) error
+}
+
+// topicFindingPublisher publishes finding events as JSON messages to a topic
+type topicFindingPublisher struct {
+    sender MessageSender
+    topic  string
+}
+
+// NewFindingEventPublisher creates a publisher that sends finding events to
+// topic, keyed by finding ID so events for one finding stay ordered
+func NewFindingEventPublisher(sender MessageSender, topic string) FindingEventPublisher {
+    return &topicFindingPublisher{sender: sender, topic: topic}
+}
+
+func (p *topicFindingPublisher) PublishFindingResolved(ctx context.Context, event FindingResolvedEvent) error {
+    data, err := json.Marshal(event)
+    if err != nil {
+        return fmt.Errorf("failed to marshal event: %v", err)
+    }
+    findingID, _ := event.Metadata["finding_id"].(string)
+    return p.sender.Send(ctx, p.topic, []byte(findingID), data)
+}
+
+// FindingResolvedEvent is a finding.resolved event in the platform's event
+// format. RawData carries the finding reference the event processor reads.
+type FindingResolvedEvent struct {
+    ID          string                 `json:"id"`
+    Type        string                 `json:"type"`
+    Timestamp   time.Time              `json:"timestamp"`
+    Source      string                 `json:"source"`
+    Actor       string                 `json:"actor,omitempty"`
+    Description string                 `json:"description"`
+    Metadata    map[string]interface{} `json:"metadata,omitempty"`
+    RawData     []byte                 `json:"raw_data"`
+}
+
+// resolvedFindingRef is the finding payload of a FindingResolvedEvent. It only
+// identifies the finding; the processor loads the rest from the graph.
+type resolvedFindingRef struct {
+    Finding struct {
+        ID     string `json:"id"`
+        Status string `json:"status"`
+    } `json:"finding"`
+}
+
 type RemediationStatus string
 
 const (
@@ -47,22 +181,34 @@ const (
 )
 
 func NewRemediationEngine(exec *executor.Executor, approval *workflow.ApprovalManager, 
-    playbookMgr *playbook.Manager, store store.Store) *RemediationEngine {
+    playbookMgr *playbook.Manager, store store.Store, config EngineConfig) (*RemediationEngine, error) {
+    
+    if err := config.Validate(); err != nil {
+        return nil, fmt.Errorf("invalid engine config: %w", err)
+    }
     
     return &RemediationEngine{
         executor:        exec,
         approvalManager: approval,
         playbookManager: playbookMgr,
         store:           store,
-        workQueue:       make(chan RemediationWorkItem, 1000),
-        workers:         5,
+        workQueue:       make(chan RemediationWorkItem, config.QueueSize),
+        config:          config,
         metrics:         NewRemediationMetrics(),
-    }
+    }, nil
+}
+
+// SetFindingEventPublisher sets where finding-resolved events are published.
+// Without a publisher findings are left for the next collection to resolve.
+func (re *RemediationEngine) SetFindingEventPublisher(publisher FindingEventPublisher) {
+    re.mu.Lock()
+    defer re.mu.Unlock()
+    re.findingEvents = publisher
 }
 
 func (re *RemediationEngine) Start(ctx context.Context) {
     // Start worker pool
-    for i := 0; i < re.workers; i++ {
+    for i := 0; i < re.config.Workers; i++ {
         go re.worker(ctx, i)
     }
 
@@ -72,7 +218,7 @@ func (re *RemediationEngine) Start(ctx context.Context) {
     // Start periodic cleanup of old jobs
     go re.cleanupOldJobs(ctx)
     
-    log.Printf("Remediation engine started with %d workers", re.workers)
+    log.Printf("Remediation engine started with %d workers (queue size %d)", re.config.Workers, re.config.QueueSize)
 }
 
 func (re *RemediationEngine) worker(ctx context.Context, id int) {
@@ -83,7 +229,82 @@ func (re *RemediationEngine) worker(ctx context.Context, id int) {
         case <-ctx.Done():
             return
         case work := <-re.workQueue:
-            re.processWorkItem(ctx, work)
+            re.processWorkItemWithTimeout(ctx, work)
+        }
+    }
+}
+
+// processWorkItemWithTimeout bounds a single work item by the configured worker timeout
+func (re *RemediationEngine) processWorkItemWithTimeout(ctx context.Context, work RemediationWorkItem) {
+    if re.config.WorkerTimeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, re.config.WorkerTimeout)
+        defer cancel()
+    }
+    
+    re.processWorkItem(ctx, work)
+}
+
+// finishedStatuses are the terminal remediation states eligible for cleanup
+var finishedStatuses = []string{
+    string(StatusCompleted),
+    string(StatusFailed),
+    string(StatusRolledBack),
+    string(StatusCancelled),
+}
+
+// cleanupOldJobs periodically purges finished remediations older than the
+// configured retention, along with their rollback checkpoints
+func (re *RemediationEngine) cleanupOldJobs(ctx context.Context) {
+    ticker := time.NewTicker(re.config.CleanupInterval)
+    defer ticker.Stop()
+    
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            cleaned, err := re.purgeOldJobs(ctx)
+            if err != nil {
+                log.Printf("Failed to clean up old remediations: %v", err)
+            }
+            if cleaned > 0 {
+                log.Printf("Cleaned up %d remediations older than %v", cleaned, re.config.JobRetention)
+            }
+        }
+    }
+}
+
+// purgeOldJobs deletes finished remediations past retention in batches and
+// returns the number of records removed
+func (re *RemediationEngine) purgeOldJobs(ctx context.Context) (int, error) {
+    cutoff := time.Now().Add(-re.config.JobRetention)
+    cleaned := 0
+    
+    for {
+        ids, err := re.store.ListRemediationsBefore(ctx, finishedStatuses, cutoff, re.config.CleanupBatchSize)
+        if err != nil {
+            return cleaned, fmt.Errorf("failed to list old remediations: %v", err)
+        }
+        if len(ids) == 0 {
+            return cleaned, nil
+        }
+        
+        for _, id := range ids {
+            // Checkpoints go first so a partial failure never orphans them
+            if err := re.store.DeleteCheckpoints(ctx, id); err != nil {
+                return cleaned, fmt.Errorf("failed to delete checkpoints for %s: %v", id, err)
+            }
+            if err := re.store.DeleteRemediation(ctx, id); err != nil {
+                return cleaned, fmt.Errorf("failed to delete remediation %s: %v", id, err)
+            }
+            
+            cleaned++
+            re.metrics.RemediationsCleaned(1)
+        }
+        
+        if len(ids) < re.config.CleanupBatchSize {
+            return cleaned, nil
         }
     }
 }
@@ -103,8 +324,26 @@ func (re *RemediationEngine) processWorkItem(ctx context.Context, work Remediati
         return
     }
     
-    // Execute the playbook
-    result, err := re.executePlaybook(ctx, pb, work)
+    // Execute the playbook, retrying failures that happened before any step
+    // completed so that partially applied changes are never re-applied
+    var result *playbook.ExecutionResult
+    for attempt := 0; attempt <= re.config.MaxRetries; attempt++ {
+        if attempt > 0 {
+            log.Printf("Retrying remediation %s (attempt %d/%d)", work.ID, attempt, re.config.MaxRetries)
+            select {
+            case <-ctx.Done():
+            case <-time.After(re.config.RetryBackoff):
+            }
+        }
+        
+        result, err = re.executePlaybook(ctx, pb, work)
+        if err == nil || ctx.Err() != nil {
+            break
+        }
+        if result != nil && len(result.Outputs) > 0 {
+            break
+        }
+    }
     if err != nil {
         log.Printf("Playbook execution failed: %v", err)
         re.metrics.RemediationFailed(work.PlaybookID, "execution_failed")
@@ -132,6 +371,11 @@ func (re *RemediationEngine) processWorkItem(ctx context.Context, work Remediati
     // Emit event
     re.emitRemediationEvent(work, "completed", result)
     
+    // Close the loop on the findings the remediation addressed
+    if re.config.AutoResolveFindings {
+        re.resolveFindings(ctx, work)
+    }
+    
     // Update metrics
     duration := time.Since(startTime)
     re.metrics.RemediationCompleted(work.PlaybookID, duration)
@@ -139,6 +383,49 @@ func (re *RemediationEngine) processWorkItem(ctx context.Context, work Remediati
     log.Printf("Remediation %s completed in %v", work.ID, duration)
 }
 
+// resolveFindings emits a finding-resolved event for each finding addressed by
+// a completed remediation so the platform resolves it and recalculates risk
+func (re *RemediationEngine) resolveFindings(ctx context.Context, work RemediationWorkItem) {
+    re.mu.RLock()
+    publisher := re.findingEvents
+    re.mu.RUnlock()
+    if publisher == nil {
+        return
+    }
+    
+    for _, findingID := range work.ResolvedFindingIDs() {
+        var ref resolvedFindingRef
+        ref.Finding.ID = findingID
+        ref.Finding.Status = "resolved"
+        data, err := json.Marshal(ref)
+        if err != nil {
+            log.Printf("Failed to encode resolution of finding %s: %v", findingID, err)
+            continue
+        }
+        
+        event := FindingResolvedEvent{
+            ID:          generateUUID(),
+            Type:        "finding.resolved",
+            Timestamp:   time.Now(),
+            Source:      "remediation-engine",
+            Actor:       work.Requestor,
+            Description: fmt.Sprintf("Resolved by remediation %s (playbook %s)", work.ID, work.PlaybookID),
+            Metadata: map[string]interface{}{
+                "remediation_id": work.ID,
+                "playbook_id":    work.PlaybookID,
+                "finding_id":     findingID,
+            },
+            RawData: data,
+        }
+        
+        if err := publisher.PublishFindingResolved(ctx, event); err != nil {
+            log.Printf("Failed to publish resolution of finding %s for remediation %s: %v", findingID, work.ID, err)
+            continue
+        }
+        log.Printf("Finding %s resolved by remediation %s", findingID, work.ID)
+    }
+}
+
 func (re *RemediationEngine) executePlaybook(ctx context.Context, pb playbook.Playbook, 
     work RemediationWorkItem) (*playbook.ExecutionResult, error) {
     
@@ -289,6 +576,18 @@ func (re *RemediationEngine) preFlightChecks(ctx context.Context, pb playbook.Pl
 func (re *RemediationEngine) RequestRemediation(ctx context.Context, findingID string, 
     playbookID string, parameters map[string]interface{}, requestor string) (string, error) {
     
+    return re.RequestFindingsRemediation(ctx, []string{findingID}, playbookID, parameters, requestor)
+}
+
+// RequestFindingsRemediation requests a playbook run that addresses several
+// findings at once. All of them are resolved when the remediation succeeds.
+func (re *RemediationEngine) RequestFindingsRemediation(ctx context.Context, findingIDs []string, 
+    playbookID string, parameters map[string]interface{}, requestor string) (string, error) {
+    
+    if len(findingIDs) == 0 {
+        return "", fmt.Errorf("at least one finding is required")
+    }
+    
     // Generate remediation ID
     remediationID := generateUUID()
     
@@ -300,7 +599,8 @@ func (re *RemediationEngine) RequestRemediation(ctx context.Context, findingID s
     
     workItem := RemediationWorkItem{
         ID:         remediationID,
-        FindingID:  findingID,
+        FindingID:  findingIDs[0],
+        FindingIDs: findingIDs[1:],
         PlaybookID: playbookID,
         Parameters: parameters,
         Requestor:  requestor,
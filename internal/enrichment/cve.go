@@ -0,0 +1,291 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// cvePattern matches CVE identifiers in finding text
+var cvePattern = regexp.MustCompile(`(?i)\bCVE-\d{4}-\d{4,}\b`)
+
+// CVEFeed looks up the scores of CVEs. IDs the feed does not know are left
+// out of the result.
+type CVEFeed interface {
+	Lookup(ctx context.Context, cveIDs []string) (map[string]models.CVEScore, error)
+}
+
+// CVEConfig configures CVE enrichment of findings
+type CVEConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// FeedURL is queried with the CVE IDs comma separated in the cve
+	// parameter, as served by the FIRST EPSS API
+	FeedURL     string        `yaml:"feed_url"`
+	APIKey      string        `yaml:"api_key"`
+	Timeout     time.Duration `yaml:"timeout"`
+	MaxPerQuery int           `yaml:"max_per_query"`
+	// CacheTTL is how long looked up scores, and CVEs the feed does not
+	// know, are reused; EPSS scores are republished daily
+	CacheTTL  time.Duration `yaml:"cache_ttl"`
+	CacheSize int           `yaml:"cache_size"`
+	// AssetTypes are the types of the assets whose findings are enriched
+	AssetTypes []models.AssetType `yaml:"asset_types"`
+}
+
+// DefaultCVEConfig returns default CVE enrichment configuration
+func DefaultCVEConfig() CVEConfig {
+	return CVEConfig{
+		FeedURL:     "https://api.first.org/data/v1/epss",
+		Timeout:     10 * time.Second,
+		MaxPerQuery: 100,
+		CacheTTL:    24 * time.Hour,
+		CacheSize:   100000,
+		AssetTypes:  []models.AssetType{models.AssetTypeCompute},
+	}
+}
+
+// HTTPCVEFeed reads CVE scores from a JSON feed of the form
+// {"data": [{"cve": "CVE-...", "epss": "0.97", "percentile": "0.99", "cvss": 9.8}]}.
+// Scores may be numbers or numeric strings.
+type HTTPCVEFeed struct {
+	config CVEConfig
+	client *http.Client
+}
+
+// NewHTTPCVEFeed creates a feed client
+func NewHTTPCVEFeed(config CVEConfig) *HTTPCVEFeed {
+	return &HTTPCVEFeed{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+type feedResponse struct {
+	Data []struct {
+		CVE        string    `json:"cve"`
+		CVSS       feedScore `json:"cvss"`
+		EPSS       feedScore `json:"epss"`
+		Percentile feedScore `json:"percentile"`
+	} `json:"data"`
+}
+
+// feedScore is a score sent as a number or a numeric string
+type feedScore float64
+
+func (s *feedScore) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*s = 0
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid score %s: %w", data, err)
+	}
+	*s = feedScore(value)
+	return nil
+}
+
+// Lookup queries the feed in chunks of MaxPerQuery IDs
+func (f *HTTPCVEFeed) Lookup(ctx context.Context, cveIDs []string) (map[string]models.CVEScore, error) {
+	scores := make(map[string]models.CVEScore, len(cveIDs))
+	chunk := f.config.MaxPerQuery
+	if chunk <= 0 {
+		chunk = len(cveIDs)
+	}
+
+	for start := 0; start < len(cveIDs); start += chunk {
+		end := start + chunk
+		if end > len(cveIDs) {
+			end = len(cveIDs)
+		}
+		if err := f.lookup(ctx, cveIDs[start:end], scores); err != nil {
+			return nil, err
+		}
+	}
+	return scores, nil
+}
+
+func (f *HTTPCVEFeed) lookup(ctx context.Context, cveIDs []string, scores map[string]models.CVEScore) error {
+	query := url.Values{"cve": {strings.Join(cveIDs, ",")}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.config.FeedURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create CVE feed request: %w", err)
+	}
+	if f.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+f.config.APIKey)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query CVE feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("CVE feed returned status %d", resp.StatusCode)
+	}
+
+	var body feedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode CVE feed response: %w", err)
+	}
+
+	now := time.Now()
+	for _, entry := range body.Data {
+		id := strings.ToUpper(entry.CVE)
+		scores[id] = models.CVEScore{
+			CVEID:          id,
+			CVSS:           float64(entry.CVSS),
+			EPSS:           float64(entry.EPSS),
+			EPSSPercentile: float64(entry.Percentile),
+			UpdatedAt:      now,
+		}
+	}
+	return nil
+}
+
+// CVEEnricher attaches CVSS and EPSS scores to findings that reference CVEs.
+// Lookups are cached by CVE ID, including misses, so a CVE shared by many
+// findings is looked up once per CacheTTL.
+type CVEEnricher struct {
+	config     CVEConfig
+	feed       CVEFeed
+	assetTypes map[models.AssetType]bool
+
+	mu    sync.Mutex
+	cache map[string]cachedCVE
+}
+
+type cachedCVE struct {
+	score     models.CVEScore
+	known     bool
+	expiresAt time.Time
+}
+
+// NewCVEEnricher creates an enricher reading scores from feed
+func NewCVEEnricher(config CVEConfig, feed CVEFeed) *CVEEnricher {
+	assetTypes := make(map[models.AssetType]bool, len(config.AssetTypes))
+	for _, assetType := range config.AssetTypes {
+		assetTypes[assetType] = true
+	}
+	return &CVEEnricher{
+		config:     config,
+		feed:       feed,
+		assetTypes: assetTypes,
+		cache:      make(map[string]cachedCVE),
+	}
+}
+
+// EnrichFinding sets the CVEs referenced by a finding on an asset of a
+// configured type and attaches their scores. Findings without CVEs, or on
+// other assets, are left unchanged. A nil asset is not filtered by type.
+func (e *CVEEnricher) EnrichFinding(ctx context.Context, asset models.Asset, finding *models.Finding) error {
+	if asset != nil && len(e.assetTypes) > 0 && !e.assetTypes[asset.GetType()] {
+		return nil
+	}
+
+	cveIDs := FindingCVEs(*finding)
+	if len(cveIDs) == 0 {
+		return nil
+	}
+	finding.CVEs = cveIDs
+
+	scores, err := e.scores(ctx, cveIDs)
+	if err != nil {
+		return err
+	}
+
+	finding.CVEScores = finding.CVEScores[:0]
+	for _, id := range cveIDs {
+		if score, ok := scores[id]; ok {
+			finding.CVEScores = append(finding.CVEScores, score)
+		}
+	}
+	return nil
+}
+
+// scores returns the scores of cveIDs, looking up those not cached
+func (e *CVEEnricher) scores(ctx context.Context, cveIDs []string) (map[string]models.CVEScore, error) {
+	now := time.Now()
+	scores := make(map[string]models.CVEScore, len(cveIDs))
+	var missing []string
+
+	e.mu.Lock()
+	for _, id := range cveIDs {
+		cached, ok := e.cache[id]
+		switch {
+		case !ok || now.After(cached.expiresAt):
+			missing = append(missing, id)
+		case cached.known:
+			scores[id] = cached.score
+		}
+	}
+	e.mu.Unlock()
+
+	if len(missing) == 0 {
+		return scores, nil
+	}
+
+	found, err := e.feed.Lookup(ctx, missing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up CVE scores: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.config.CacheSize > 0 && len(e.cache)+len(missing) > e.config.CacheSize {
+		e.evictExpired(now)
+	}
+	for _, id := range missing {
+		score, known := found[id]
+		if known {
+			scores[id] = score
+		}
+		if e.config.CacheSize <= 0 || len(e.cache) < e.config.CacheSize {
+			e.cache[id] = cachedCVE{score: score, known: known, expiresAt: now.Add(e.config.CacheTTL)}
+		}
+	}
+	return scores, nil
+}
+
+// evictExpired drops expired cache entries
+func (e *CVEEnricher) evictExpired(now time.Time) {
+	for id, cached := range e.cache {
+		if now.After(cached.expiresAt) {
+			delete(e.cache, id)
+		}
+	}
+}
+
+// FindingCVEs returns the CVE IDs a finding references, from its CVEs or
+// else its policy ID and description, upper-cased, deduplicated and sorted
+func FindingCVEs(finding models.Finding) []string {
+	refs := finding.CVEs
+	if len(refs) == 0 {
+		refs = cvePattern.FindAllString(finding.PolicyID+" "+finding.Description, -1)
+	}
+
+	seen := make(map[string]bool, len(refs))
+	var cveIDs []string
+	for _, ref := range refs {
+		id := strings.ToUpper(strings.TrimSpace(ref))
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		cveIDs = append(cveIDs, id)
+	}
+	sort.Strings(cveIDs)
+	return cveIDs
+}
@@ -0,0 +1,326 @@
+package events
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// AnomalyPolicyID identifies findings raised by relationship anomaly detection
+const AnomalyPolicyID = "anomaly.relationship-spike"
+
+// AnomalyConfig configures detection of sudden spikes in the relationships
+// created from an asset, such as an identity suddenly gaining access to many
+// resources
+type AnomalyConfig struct {
+	Enabled bool `json:"enabled"`
+	// Window is the period new relationships are counted over, and
+	// BaselineWindows how many preceding windows form the baseline
+	Window          time.Duration `json:"window"`
+	BaselineWindows int           `json:"baseline_windows"`
+	// MinBaselineWindows is how many windows must have been observed before
+	// an asset is checked, so new assets are not flagged on their first sync
+	MinBaselineWindows int `json:"min_baseline_windows"`
+	// ZScoreThreshold is how many standard deviations above the baseline mean
+	// the current window must be, and MinRelationships the fewest new
+	// relationships in it, to be anomalous
+	ZScoreThreshold  float64 `json:"z_score_threshold"`
+	MinRelationships int     `json:"min_relationships"`
+	// RelationshipTypes are the relationship types counted; empty counts all
+	RelationshipTypes []models.RelationshipType `json:"relationship_types"`
+	Severity          float64                   `json:"severity"` // 0-10, of raised findings
+	MaxTrackedAssets  int                       `json:"max_tracked_assets"`
+}
+
+// DefaultAnomalyConfig returns default anomaly detection configuration
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		Enabled:            true,
+		Window:             time.Hour,
+		BaselineWindows:    24,
+		MinBaselineWindows: 6,
+		ZScoreThreshold:    3.0,
+		MinRelationships:   10,
+		RelationshipTypes: []models.RelationshipType{
+			models.RelationshipHasAccessTo,
+			models.RelationshipAssumesRole,
+			models.RelationshipManages,
+		},
+		Severity:         8.0,
+		MaxTrackedAssets: 100000,
+	}
+}
+
+// RelationshipBaselineStore keeps rolling counts of new relationships per
+// asset. Implementations shared by several processors let them detect
+// spikes across all partitions.
+type RelationshipBaselineStore interface {
+	// Increment counts a new relationship from assetID at the given time,
+	// counting a relationship ID at most once per window so that retried
+	// events are not counted again. It returns the count of the window
+	// containing it and the counts of the preceding windows observed for the
+	// asset, newest first.
+	Increment(assetID, relationshipID string, at time.Time) (current int, baseline []int)
+	// Alerted reports whether the window containing at was reported for
+	// assetID
+	Alerted(assetID string, at time.Time) bool
+	// MarkAlerted records that the window containing at was reported for
+	// assetID, returning false if it already had been
+	MarkAlerted(assetID string, at time.Time) bool
+}
+
+// AnomalyScore describes how far a window deviates from its baseline
+type AnomalyScore struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+	ZScore float64 `json:"z_score"`
+}
+
+// RelationshipAnomalyDetector raises findings when an asset gains new
+// relationships much faster than its baseline
+type RelationshipAnomalyDetector struct {
+	config AnomalyConfig
+	store  RelationshipBaselineStore
+	types  map[models.RelationshipType]bool
+}
+
+// NewRelationshipAnomalyDetector creates a detector; a nil store keeps the
+// baseline in memory
+func NewRelationshipAnomalyDetector(config AnomalyConfig, store RelationshipBaselineStore) *RelationshipAnomalyDetector {
+	if store == nil {
+		store = newMemoryBaselineStore(config.Window, config.BaselineWindows, config.MaxTrackedAssets)
+	}
+	types := make(map[models.RelationshipType]bool, len(config.RelationshipTypes))
+	for _, relType := range config.RelationshipTypes {
+		types[relType] = true
+	}
+	return &RelationshipAnomalyDetector{config: config, store: store, types: types}
+}
+
+// Observe records a new relationship and reports whether it makes the
+// current window of its source asset anomalous. Windows already marked
+// reported are not reported again.
+func (d *RelationshipAnomalyDetector) Observe(rel models.Relationship, at time.Time) (AnomalyScore, bool) {
+	if len(d.types) > 0 && !d.types[rel.Type] {
+		return AnomalyScore{}, false
+	}
+
+	current, baseline := d.store.Increment(rel.FromAssetID, rel.ID, at)
+	score := scoreWindow(current, baseline)
+	if len(baseline) < d.config.MinBaselineWindows || current < d.config.MinRelationships {
+		return score, false
+	}
+	if score.ZScore < d.config.ZScoreThreshold {
+		return score, false
+	}
+
+	return score, !d.store.Alerted(rel.FromAssetID, at)
+}
+
+// MarkReported records that the anomalous window of rel's source asset was
+// reported, once its finding has been written
+func (d *RelationshipAnomalyDetector) MarkReported(rel models.Relationship, at time.Time) {
+	d.store.MarkAlerted(rel.FromAssetID, at)
+}
+
+// scoreWindow compares a window count with the mean and standard deviation
+// of the baseline. A flat baseline is treated as having a deviation of one
+// so that a jump from a steady rate still scores by its size.
+func scoreWindow(current int, baseline []int) AnomalyScore {
+	score := AnomalyScore{Count: current}
+	if len(baseline) == 0 {
+		return score
+	}
+
+	var sum float64
+	for _, count := range baseline {
+		sum += float64(count)
+	}
+	score.Mean = sum / float64(len(baseline))
+
+	var variance float64
+	for _, count := range baseline {
+		diff := float64(count) - score.Mean
+		variance += diff * diff
+	}
+	score.StdDev = math.Sqrt(variance / float64(len(baseline)))
+
+	stdDev := score.StdDev
+	if stdDev < 1 {
+		stdDev = 1
+	}
+	score.ZScore = (float64(current) - score.Mean) / stdDev
+	return score
+}
+
+// memoryBaselineStore keeps per-asset window counts in memory. The least
+// recently updated assets are dropped once maxAssets are tracked.
+type memoryBaselineStore struct {
+	mu        sync.Mutex
+	window    time.Duration
+	windows   int
+	maxAssets int
+	assets    map[string]*list.Element
+	order     *list.List // of *assetWindows, most recently updated first
+}
+
+// assetWindows are the counts of the most recent windows of one asset,
+// newest first, with the start of the newest window and the relationships
+// counted in it
+type assetWindows struct {
+	assetID string
+	start   time.Time
+	counts  []int
+	counted map[string]bool
+	alerted bool
+}
+
+func newMemoryBaselineStore(window time.Duration, windows, maxAssets int) *memoryBaselineStore {
+	if window <= 0 {
+		window = time.Hour
+	}
+	if windows <= 0 {
+		windows = 1
+	}
+	return &memoryBaselineStore{
+		window:    window,
+		windows:   windows,
+		maxAssets: maxAssets,
+		assets:    make(map[string]*list.Element),
+		order:     list.New(),
+	}
+}
+
+// Increment implements RelationshipBaselineStore
+func (s *memoryBaselineStore) Increment(assetID, relationshipID string, at time.Time) (int, []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.windowsFor(assetID, at)
+	if relationshipID == "" || !w.counted[relationshipID] {
+		w.counts[0]++
+		if relationshipID != "" {
+			w.counted[relationshipID] = true
+		}
+	}
+
+	baseline := make([]int, len(w.counts)-1)
+	copy(baseline, w.counts[1:])
+	return w.counts[0], baseline
+}
+
+// Alerted implements RelationshipBaselineStore
+func (s *memoryBaselineStore) Alerted(assetID string, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.windowsFor(assetID, at).alerted
+}
+
+// MarkAlerted implements RelationshipBaselineStore
+func (s *memoryBaselineStore) MarkAlerted(assetID string, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.windowsFor(assetID, at)
+	if w.alerted {
+		return false
+	}
+	w.alerted = true
+	return true
+}
+
+// windowsFor returns the windows of an asset rolled forward to the window
+// containing at, recording empty windows for periods without relationships
+func (s *memoryBaselineStore) windowsFor(assetID string, at time.Time) *assetWindows {
+	start := at.Truncate(s.window)
+
+	elem, ok := s.assets[assetID]
+	if !ok {
+		s.evict()
+		w := &assetWindows{assetID: assetID, start: start, counts: []int{0}, counted: make(map[string]bool)}
+		s.assets[assetID] = s.order.PushFront(w)
+		return w
+	}
+	s.order.MoveToFront(elem)
+	w := elem.Value.(*assetWindows)
+
+	// Late events are counted in the current window
+	if !start.After(w.start) {
+		return w
+	}
+
+	elapsed := int(start.Sub(w.start) / s.window)
+	if elapsed > s.windows {
+		elapsed = s.windows + 1
+	}
+	w.counts = append(make([]int, elapsed), w.counts...)
+	if len(w.counts) > s.windows+1 {
+		w.counts = w.counts[:s.windows+1]
+	}
+	w.start = start
+	w.counted = make(map[string]bool)
+	w.alerted = false
+	return w
+}
+
+// evict drops the asset updated least recently when the store is full
+func (s *memoryBaselineStore) evict() {
+	if s.maxAssets <= 0 || len(s.assets) < s.maxAssets {
+		return
+	}
+	oldest := s.order.Back()
+	s.order.Remove(oldest)
+	delete(s.assets, oldest.Value.(*assetWindows).assetID)
+}
+
+// handleRelationshipAnomaly raises a finding when a new relationship makes
+// its source asset's rate of new relationships anomalous
+func (p *EventProcessor) handleRelationshipAnomaly(ctx context.Context, event models.BaseEvent) error {
+	var relEvent models.RelationshipEvent
+	if err := p.unmarshalEvent(event, &relEvent); err != nil {
+		return err
+	}
+
+	at := event.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	rel := relEvent.Relationship
+	score, anomalous := p.anomalies.Observe(rel, at)
+	if !anomalous {
+		return nil
+	}
+
+	finding := models.Finding{
+		BaseAsset: models.NewBaseAsset(
+			event.Provider,
+			models.AssetTypeFinding,
+			event.Environment,
+			fmt.Sprintf("Anomalous relationship growth: %s", rel.FromAssetID),
+		),
+		PolicyID: AnomalyPolicyID,
+		Severity: p.anomalies.config.Severity,
+		Status:   "open",
+		Description: fmt.Sprintf("%s gained %d new relationships within %v against a baseline of %.1f (std dev %.1f, z-score %.1f), which can indicate compromise",
+			rel.FromAssetID, score.Count, p.anomalies.config.Window, score.Mean, score.StdDev, score.ZScore),
+		Recommendation: "Review the relationships recently granted to this asset and revoke any that are not expected",
+		AssetID:        rel.FromAssetID,
+	}
+
+	if err := p.graphStore.CreateFinding(ctx, finding); err != nil {
+		return fmt.Errorf("failed to create anomaly finding: %w", err)
+	}
+	p.anomalies.MarkReported(rel, at)
+
+	log.Printf("Relationship anomaly for asset %s: %d new relationships (z-score %.1f)", rel.FromAssetID, score.Count, score.ZScore)
+	return nil
+}
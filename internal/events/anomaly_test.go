@@ -0,0 +1,70 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+func TestMemoryBaselineStoreCountsRetriedRelationshipsOnce(t *testing.T) {
+	s := newMemoryBaselineStore(time.Hour, 3, 10)
+	at := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	s.Increment("asset-1", "rel-1", at)
+	if current, _ := s.Increment("asset-1", "rel-1", at); current != 1 {
+		t.Errorf("count after a retried relationship = %d, want 1", current)
+	}
+	if current, _ := s.Increment("asset-1", "rel-2", at); current != 2 {
+		t.Errorf("count after a second relationship = %d, want 2", current)
+	}
+}
+
+func TestRelationshipAnomalyIsReportedUntilMarked(t *testing.T) {
+	config := DefaultAnomalyConfig()
+	config.MinBaselineWindows = 1
+	config.MinRelationships = 2
+	d := NewRelationshipAnomalyDetector(config, nil)
+
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	d.Observe(models.Relationship{ID: "rel-0", FromAssetID: "asset-1", Type: models.RelationshipHasAccessTo}, start)
+
+	at := start.Add(time.Hour)
+	var rel models.Relationship
+	var anomalous bool
+	for i := 1; i <= 5; i++ {
+		rel = models.Relationship{ID: fmt.Sprintf("rel-%d", i), FromAssetID: "asset-1", Type: models.RelationshipHasAccessTo}
+		_, anomalous = d.Observe(rel, at)
+	}
+	if !anomalous {
+		t.Fatal("spike was not reported")
+	}
+
+	// A retry after a failed finding write reports the window again
+	if _, anomalous := d.Observe(rel, at); !anomalous {
+		t.Error("spike was not reported again before being marked")
+	}
+
+	d.MarkReported(rel, at)
+	if _, anomalous := d.Observe(models.Relationship{ID: "rel-6", FromAssetID: "asset-1", Type: models.RelationshipHasAccessTo}, at); anomalous {
+		t.Error("spike was reported again after being marked")
+	}
+}
+
+func TestMemoryBaselineStoreEvictsLeastRecentlyUpdated(t *testing.T) {
+	s := newMemoryBaselineStore(time.Hour, 3, 2)
+	at := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	s.Increment("asset-1", "rel-1", at)
+	s.Increment("asset-2", "rel-2", at)
+	s.Increment("asset-1", "rel-3", at)
+	s.Increment("asset-3", "rel-4", at)
+
+	if _, ok := s.assets["asset-2"]; ok {
+		t.Error("asset-2 was not evicted")
+	}
+	if current, _ := s.Increment("asset-1", "rel-5", at); current != 3 {
+		t.Errorf("asset-1 count = %d, want 3 after it was kept", current)
+	}
+}
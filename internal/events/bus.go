@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
+	"github.com/securizon/internal/graph"
 	"github.com/securizon/pkg/models"
 )
 
@@ -53,29 +55,53 @@ type KafkaEventBus struct {
 	brokers []string
 	config  KafkaConfig
 	producer *kafka.Writer
-	consumers map[string]*kafka.Reader
+	changes  *kafka.Writer
+
+	// consumers holds the readers of every subscription, keyed by topic,
+	// group and a sequence number so that subscriptions to the same topic
+	// and group keep their own readers
+	mu            sync.Mutex
+	consumers     map[string]messageReader
+	consumerCount int
+
+	// tailReaders opens a reader positioned at the latest offset for each
+	// partition of a topic
+	tailReaders func(ctx context.Context, topic string) ([]messageReader, error)
+}
+
+// messageReader reads the messages of a subscription. *kafka.Reader
+// implements it.
+type messageReader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
 }
 
 // KafkaConfig represents Kafka configuration
 type KafkaConfig struct {
-	Brokers            []string `json:"brokers"`
-	ClientID           string   `json:"client_id"`
-	ConsumerGroup      string   `json:"consumer_group"`
-	BatchSize          int      `json:"batch_size"`
-	BatchTimeout       time.Duration `json:"batch_timeout"`
-	CommitInterval     time.Duration `json:"commit_interval"`
-	HeartbeatInterval  time.Duration `json:"heartbeat_interval"`
-	SessionTimeout     time.Duration `json:"session_timeout"`
-	RebalanceTimeout   time.Duration `json:"rebalance_timeout"`
-	StartOffset        int64    `json:"start_offset"` // -1 for latest, -2 for earliest
-	MinBytes           int      `json:"min_bytes"`
-	MaxBytes           int      `json:"max_bytes"`
-	MaxWait            time.Duration `json:"max_wait"`
-	CompressionType    string   `json:"compression_type"`
-	SecurityProtocol   string   `json:"security_protocol"`
-	SASLMechanism      string   `json:"sasl_mechanism"`
-	SASLUsername       string   `json:"sasl_username"`
-	SASLPassword       string   `json:"sasl_password"`
+	Brokers            []string `json:"brokers" yaml:"brokers"`
+	ClientID           string   `json:"client_id" yaml:"client_id"`
+	ConsumerGroup      string   `json:"consumer_group" yaml:"consumer_group"`
+	BatchSize          int      `json:"batch_size" yaml:"batch_size"`
+	BatchTimeout       time.Duration `json:"batch_timeout" yaml:"batch_timeout"`
+	CommitInterval     time.Duration `json:"commit_interval" yaml:"commit_interval"`
+	HeartbeatInterval  time.Duration `json:"heartbeat_interval" yaml:"heartbeat_interval"`
+	SessionTimeout     time.Duration `json:"session_timeout" yaml:"session_timeout"`
+	RebalanceTimeout   time.Duration `json:"rebalance_timeout" yaml:"rebalance_timeout"`
+	StartOffset        int64    `json:"start_offset" yaml:"start_offset"` // -1 for latest, -2 for earliest
+	MinBytes           int      `json:"min_bytes" yaml:"min_bytes"`
+	MaxBytes           int      `json:"max_bytes" yaml:"max_bytes"`
+	MaxWait            time.Duration `json:"max_wait" yaml:"max_wait"`
+	CompressionType    string   `json:"compression_type" yaml:"compression_type"`
+	SecurityProtocol   string   `json:"security_protocol" yaml:"security_protocol"`
+	SASLMechanism      string   `json:"sasl_mechanism" yaml:"sasl_mechanism"`
+	SASLUsername       string   `json:"sasl_username" yaml:"sasl_username"`
+	SASLPassword       string   `json:"sasl_password" yaml:"sasl_password"`
+	// Ordering is OrderingPerAsset or OrderingNone, see ordering.go.
+	// DispatchWorkers is how many events a subscription handles at once;
+	// with per-asset ordering, events of one asset still run in order.
+	Ordering           string   `json:"ordering" yaml:"ordering"`
+	DispatchWorkers    int      `json:"dispatch_workers" yaml:"dispatch_workers"`
+	DispatchBuffer     int      `json:"dispatch_buffer" yaml:"dispatch_buffer"`
 }
 
 // DefaultKafkaConfig returns default Kafka configuration
@@ -96,6 +122,9 @@ func DefaultKafkaConfig() KafkaConfig {
 		MaxWait:           500 * time.Millisecond,
 		CompressionType:   "gzip",
 		SecurityProtocol:  "PLAINTEXT",
+		Ordering:          OrderingPerAsset,
+		DispatchWorkers:   1,
+		DispatchBuffer:    100,
 	}
 }
 
@@ -105,7 +134,7 @@ func NewKafkaEventBus(config KafkaConfig) (*KafkaEventBus, error) {
 	producerConfig := kafka.WriterConfig{
 		Brokers:          config.Brokers,
 		Topic:            "", // Will be set per message
-		Balancer:         &kafka.LeastBytes{},
+		Balancer:         newBalancer(config.Ordering),
 		BatchSize:        config.BatchSize,
 		BatchTimeout:     config.BatchTimeout,
 		Compression:      kafka.Compression(config.CompressionType),
@@ -118,12 +147,25 @@ func NewKafkaEventBus(config KafkaConfig) (*KafkaEventBus, error) {
 
 	producer := kafka.NewWriter(producerConfig)
 
-	return &KafkaEventBus{
+	// Change events are always partitioned by entity and acknowledged by
+	// every replica, whatever the event ordering mode
+	changes := &kafka.Writer{
+		Addr:         kafka.TCP(config.Brokers...),
+		Topic:        TopicGraphChanges,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: config.BatchTimeout,
+	}
+
+	bus := &KafkaEventBus{
 		brokers:  config.Brokers,
 		config:   config,
 		producer: producer,
-		consumers: make(map[string]*kafka.Reader),
-	}, nil
+		changes:  changes,
+		consumers: make(map[string]messageReader),
+	}
+	bus.tailReaders = bus.latestPartitionReaders
+	return bus, nil
 }
 
 // PublishEvent publishes a single event
@@ -135,7 +177,7 @@ func (bus *KafkaEventBus) PublishEvent(ctx context.Context, topic string, event
 
 	message := kafka.Message{
 		Topic: topic,
-		Key:   []byte(event.ID),
+		Key:   messageKey(bus.config.Ordering, event),
 		Value: data,
 		Headers: []kafka.Header{
 			{Key: "event_type", Value: []byte(string(event.Type))},
@@ -162,7 +204,7 @@ func (bus *KafkaEventBus) PublishBatch(ctx context.Context, topic string, batch
 
 		messages[i] = kafka.Message{
 			Topic: topic,
-			Key:   []byte(event.ID),
+			Key:   messageKey(bus.config.Ordering, event),
 			Value: data,
 			Headers: []kafka.Header{
 				{Key: "event_type", Value: []byte(string(event.Type))},
@@ -178,9 +220,90 @@ func (bus *KafkaEventBus) PublishBatch(ctx context.Context, topic string, batch
 	return bus.producer.WriteMessages(ctx, messages...)
 }
 
-// Subscribe subscribes to a topic
+// PublishChanges publishes graph change events to TopicGraphChanges, keyed
+// by entity so changes to one entity are consumed in order. It returns once
+// every replica has acknowledged the batch.
+func (bus *KafkaEventBus) PublishChanges(ctx context.Context, changes []graph.ChangeEvent) error {
+	messages := make([]kafka.Message, len(changes))
+
+	for i, change := range changes {
+		data, err := json.Marshal(change)
+		if err != nil {
+			return fmt.Errorf("failed to marshal change %s: %w", change.ID, err)
+		}
+
+		messages[i] = kafka.Message{
+			Key:   []byte(change.Key()),
+			Value: data,
+			Headers: []kafka.Header{
+				{Key: "op", Value: []byte(change.Op)},
+				{Key: "entity", Value: []byte(change.Entity)},
+				{Key: "timestamp", Value: []byte(change.Timestamp.Format(time.RFC3339Nano))},
+			},
+			Time: change.Timestamp,
+		}
+	}
+
+	return bus.changes.WriteMessages(ctx, messages...)
+}
+
+// Subscribe follows a topic from its latest offset without a consumer
+// group, so every subscriber receives every event published from now on,
+// across all partitions, and nothing published before. Events of one
+// partition are handled in order, partitions concurrently. Partitions added
+// to the topic later are not followed.
 func (bus *KafkaEventBus) Subscribe(ctx context.Context, topic string, handler EventHandler) error {
-	return bus.SubscribeGroup(ctx, topic, "", handler)
+	readers, err := bus.tailReaders(ctx, topic)
+	if err != nil {
+		return fmt.Errorf("failed to open readers for %s: %w", topic, err)
+	}
+
+	for _, reader := range readers {
+		go bus.consume(ctx, bus.addConsumer(topic, "", reader), reader, topic, handler, nil)
+	}
+
+	log.Printf("Subscribed to the %d partitions of topic %s with handler %s", len(readers), topic, handler.GetName())
+	return nil
+}
+
+// latestPartitionReaders opens a reader for each partition of topic,
+// positioned at the partition's latest offset
+func (bus *KafkaEventBus) latestPartitionReaders(ctx context.Context, topic string) ([]messageReader, error) {
+	conn, err := kafka.DialContext(ctx, "tcp", bus.config.Brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partitions: %w", err)
+	}
+
+	readers := make([]messageReader, 0, len(partitions))
+	for _, partition := range partitions {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:        bus.config.Brokers,
+			Topic:          topic,
+			Partition:      partition.ID,
+			MinBytes:       bus.config.MinBytes,
+			MaxBytes:       bus.config.MaxBytes,
+			MaxWait:        bus.config.MaxWait,
+			ReadBackoffMin: 100 * time.Millisecond,
+			ReadBackoffMax: 1 * time.Second,
+		})
+		// Without a group the reader ignores StartOffset, so the offset is set
+		// on the reader itself
+		if err := reader.SetOffset(kafka.LastOffset); err != nil {
+			reader.Close()
+			for _, opened := range readers {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to seek partition %d: %w", partition.ID, err)
+		}
+		readers = append(readers, reader)
+	}
+	return readers, nil
 }
 
 // SubscribeGroup subscribes to a topic with a consumer group
@@ -204,50 +327,80 @@ func (bus *KafkaEventBus) SubscribeGroup(ctx context.Context, topic, group strin
 	consumer := kafka.NewReader(consumerConfig)
 	
 	// Store consumer for cleanup
-	consumerKey := fmt.Sprintf("%s:%s", topic, group)
-	bus.consumers[consumerKey] = consumer
+	consumerKey := bus.addConsumer(topic, group, consumer)
+
+	// Events are handled inline unless several dispatch workers are
+	// configured, in which case they are spread across workers by key
+	var dispatcher *orderedDispatcher
+	if bus.config.DispatchWorkers > 1 {
+		dispatcher = newOrderedDispatcher(ctx, bus.config.Ordering, bus.config.DispatchWorkers, bus.config.DispatchBuffer)
+	}
 
 	// Start consuming in a goroutine
-	go func() {
-		defer func() {
-			if err := consumer.Close(); err != nil {
-				log.Printf("Error closing consumer for %s: %v", topic, err)
-			}
-			delete(bus.consumers, consumerKey)
-		}()
-
-		for {
-			select {
-			case <-ctx.Done():
-				log.Printf("Stopping consumer for %s: %v", topic, ctx.Err())
-				return
-			default:
-				message, err := consumer.ReadMessage(ctx)
-				if err != nil {
-					log.Printf("Error reading message from %s: %v", topic, err)
-					continue
-				}
-
-				// Parse event
-				var event models.BaseEvent
-				if err := json.Unmarshal(message.Value, &event); err != nil {
-					log.Printf("Error unmarshaling event from %s: %v", topic, err)
-					continue
-				}
-
-				// Handle event
-				if err := handler.Handle(ctx, event); err != nil {
-					log.Printf("Error handling event %s in %s: %v", event.ID, handler.GetName(), err)
-					// Continue processing other events
-				}
-			}
-		}
-	}()
+	go bus.consume(ctx, consumerKey, consumer, topic, handler, dispatcher)
 
 	log.Printf("Subscribed to topic %s with handler %s", topic, handler.GetName())
 	return nil
 }
 
+// addConsumer stores a subscription's reader for cleanup and returns its key
+func (bus *KafkaEventBus) addConsumer(topic, group string, consumer messageReader) string {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	bus.consumerCount++
+	consumerKey := fmt.Sprintf("%s:%s:%d", topic, group, bus.consumerCount)
+	bus.consumers[consumerKey] = consumer
+	return consumerKey
+}
+
+// consume hands the events read by consumer to handler until ctx is done,
+// through dispatcher unless it is nil, then closes the reader
+func (bus *KafkaEventBus) consume(ctx context.Context, consumerKey string, consumer messageReader, topic string, handler EventHandler, dispatcher *orderedDispatcher) {
+	defer func() {
+		if dispatcher != nil {
+			dispatcher.close()
+		}
+		if err := consumer.Close(); err != nil {
+			log.Printf("Error closing consumer for %s: %v", topic, err)
+		}
+		bus.mu.Lock()
+		delete(bus.consumers, consumerKey)
+		bus.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Stopping consumer for %s: %v", topic, ctx.Err())
+			return
+		default:
+			message, err := consumer.ReadMessage(ctx)
+			if err != nil {
+				log.Printf("Error reading message from %s: %v", topic, err)
+				continue
+			}
+
+			// Parse event
+			var event models.BaseEvent
+			if err := json.Unmarshal(message.Value, &event); err != nil {
+				log.Printf("Error unmarshaling event from %s: %v", topic, err)
+				continue
+			}
+
+			if dispatcher != nil {
+				dispatcher.dispatch(ctx, handler, event)
+				continue
+			}
+
+			// Handle event
+			if err := handler.Handle(ctx, event); err != nil {
+				log.Printf("Error handling event %s in %s: %v", event.ID, handler.GetName(), err)
+				// Continue processing other events
+			}
+		}
+	}
+}
+
 // CreateTopic creates a new topic
 func (bus *KafkaEventBus) CreateTopic(ctx context.Context, topic string, partitions int, replicationFactor int) error {
 	conn, err := kafka.Dial("tcp", bus.config.Brokers[0])
@@ -332,17 +485,22 @@ func (bus *KafkaEventBus) Ping(ctx context.Context) error {
 func (bus *KafkaEventBus) Close() error {
 	var errors []error
 
-	// Close producer
+	// Close producers
 	if err := bus.producer.Close(); err != nil {
 		errors = append(errors, fmt.Errorf("failed to close producer: %w", err))
 	}
+	if err := bus.changes.Close(); err != nil {
+		errors = append(errors, fmt.Errorf("failed to close change producer: %w", err))
+	}
 
 	// Close all consumers
+	bus.mu.Lock()
 	for key, consumer := range bus.consumers {
 		if err := consumer.Close(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to close consumer %s: %w", key, err))
 		}
 	}
+	bus.mu.Unlock()
 
 	if len(errors) > 0 {
 		return fmt.Errorf("errors closing event bus: %v", errors)
@@ -361,6 +519,7 @@ const (
 	TopicThreatIntel        = "threat.intel"
 	TopicFindings           = "findings"
 	TopicAuditLogs          = "audit.logs"
+	TopicGraphChanges       = "graph.changes"
 )
 
 // GetAllTopics returns all predefined topics
@@ -374,6 +533,7 @@ func GetAllTopics() []string {
 		TopicThreatIntel,
 		TopicFindings,
 		TopicAuditLogs,
+		TopicGraphChanges,
 	}
 }
 
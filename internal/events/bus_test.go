@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/models"
+	"github.com/segmentio/kafka-go"
+)
+
+// partitionReader is a messageReader over the messages of one partition
+type partitionReader struct {
+	messages chan kafka.Message
+}
+
+func (r *partitionReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case message := <-r.messages:
+		return message, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *partitionReader) Close() error {
+	return nil
+}
+
+// tailingBus returns a bus whose subscriptions follow the given partitions
+func tailingBus(partitions ...*partitionReader) *KafkaEventBus {
+	bus := &KafkaEventBus{consumers: make(map[string]messageReader)}
+	bus.tailReaders = func(ctx context.Context, topic string) ([]messageReader, error) {
+		readers := make([]messageReader, len(partitions))
+		for i, partition := range partitions {
+			readers[i] = partition
+		}
+		return readers, nil
+	}
+	return bus
+}
+
+func (r *partitionReader) publish(t *testing.T, event models.BaseEvent) {
+	t.Helper()
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to encode event: %v", err)
+	}
+	r.messages <- kafka.Message{Value: data}
+}
+
+func TestSubscribeFollowsEveryPartition(t *testing.T) {
+	partitions := []*partitionReader{
+		{messages: make(chan kafka.Message, 1)},
+		{messages: make(chan kafka.Message, 1)},
+	}
+	bus := tailingBus(partitions...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	received := make(map[string]bool)
+	done := make(chan struct{})
+	handler := EventHandlerFunc(func(ctx context.Context, event models.BaseEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received[event.ID] = true
+		if len(received) == len(partitions) {
+			close(done)
+		}
+		return nil
+	})
+	if err := bus.Subscribe(ctx, TopicRiskScores, handler); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	partitions[0].publish(t, models.BaseEvent{ID: "event-0"})
+	partitions[1].publish(t, models.BaseEvent{ID: "event-1"})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		mu.Lock()
+		defer mu.Unlock()
+		t.Fatalf("received %v, want the events of both partitions", received)
+	}
+}
+
+func TestRegionalSubscribeFollowsEveryRegion(t *testing.T) {
+	eu := []*partitionReader{{messages: make(chan kafka.Message, 1)}, {messages: make(chan kafka.Message, 1)}}
+	us := []*partitionReader{{messages: make(chan kafka.Message, 1)}}
+	bus, err := NewRegionalEventBus(map[string]EventBus{
+		"eu": tailingBus(eu...),
+		"us": tailingBus(us...),
+	}, "us")
+	if err != nil {
+		t.Fatalf("NewRegionalEventBus() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type delivery struct{ id, region string }
+	received := make(chan delivery, 3)
+	handler := EventHandlerFunc(func(ctx context.Context, event models.BaseEvent) error {
+		region, _ := tenant.RegionFromContext(ctx)
+		received <- delivery{id: event.ID, region: region}
+		return nil
+	})
+	if err := bus.Subscribe(ctx, TopicAssetRelationships, handler); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	eu[1].publish(t, models.BaseEvent{ID: "eu-change"})
+	us[0].publish(t, models.BaseEvent{ID: "us-change"})
+
+	want := map[string]string{"eu-change": "eu", "us-change": "us"}
+	for range want {
+		select {
+		case got := <-received:
+			if want[got.id] != got.region {
+				t.Errorf("event %s handled in region %q, want %q", got.id, got.region, want[got.id])
+			}
+			delete(want, got.id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("events %v were not delivered", want)
+		}
+	}
+}
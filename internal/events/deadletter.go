@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// Metadata keys describing why an event was dead-lettered. The event is
+// otherwise published unchanged, raw data included, so it can be replayed
+// to its original topic once the failure is fixed.
+const (
+	DeadLetterErrorKey    = "dlq_error"
+	DeadLetterHandlerKey  = "dlq_handler"
+	DeadLetterAttemptsKey = "dlq_attempts"
+	DeadLetterTopicKey    = "dlq_original_topic"
+	DeadLetterFailedAtKey = "dlq_failed_at"
+)
+
+// deadLetter publishes an event a handler failed to process to the dead
+// letter topic
+func (p *EventProcessor) deadLetter(ctx context.Context, topic string, event models.BaseEvent, handler string, attempts int, cause error) error {
+	metadata := make(map[string]interface{}, len(event.Metadata)+5)
+	for key, value := range event.Metadata {
+		metadata[key] = value
+	}
+	metadata[DeadLetterErrorKey] = cause.Error()
+	metadata[DeadLetterHandlerKey] = handler
+	metadata[DeadLetterAttemptsKey] = attempts
+	metadata[DeadLetterTopicKey] = topic
+	metadata[DeadLetterFailedAtKey] = time.Now().UTC().Format(time.RFC3339)
+	event.Metadata = metadata
+
+	if err := p.bus.PublishEvent(ctx, p.config.DeadLetterTopic, event); err != nil {
+		return fmt.Errorf("failed to publish event %s to dead letter topic %s: %w", event.ID, p.config.DeadLetterTopic, err)
+	}
+
+	p.metrics.mu.Lock()
+	p.metrics.EventsDeadLettered++
+	p.metrics.mu.Unlock()
+
+	log.Printf("Dead-lettered event %s (%s) from %s after handler %s failed %d time(s): %v",
+		event.ID, event.Type, topic, handler, attempts, cause)
+	return nil
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// recordingBus records published events. Methods other than PublishEvent
+// are not used by the processor's handling path and panic if called.
+type recordingBus struct {
+	EventBus
+	mu        sync.Mutex
+	published map[string][]models.BaseEvent
+}
+
+func (b *recordingBus) PublishEvent(ctx context.Context, topic string, event models.BaseEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.published == nil {
+		b.published = make(map[string][]models.BaseEvent)
+	}
+	b.published[topic] = append(b.published[topic], event)
+	return nil
+}
+
+func TestFailedHandlerEventReachesDeadLetterTopic(t *testing.T) {
+	config := DefaultProcessorConfig()
+	config.RetryAttempts = 2
+	config.RetryDelay = time.Millisecond
+	bus := &recordingBus{}
+	p := NewEventProcessor(bus, nil, nil, nil, config)
+
+	const eventType models.EventType = "test.failing"
+	calls := 0
+	p.RegisterHandler(eventType, NamedHandler("failing", func(ctx context.Context, event models.BaseEvent) error {
+		calls++
+		return errors.New("store unavailable")
+	}))
+
+	event := models.BaseEvent{ID: "event-1", Type: eventType, Metadata: map[string]interface{}{"source": "test"}}
+	if err := p.handleEvent(context.Background(), TopicAssetUpserts, event); err != nil {
+		t.Fatalf("handleEvent() = %v, want nil once the event is dead-lettered", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+	dead := bus.published[config.DeadLetterTopic]
+	if len(dead) != 1 {
+		t.Fatalf("%d events published to the dead letter topic, want 1", len(dead))
+	}
+	if dead[0].ID != event.ID {
+		t.Errorf("dead-lettered event ID = %q, want %q", dead[0].ID, event.ID)
+	}
+	for key, want := range map[string]interface{}{
+		DeadLetterHandlerKey:  "failing",
+		DeadLetterAttemptsKey: 3,
+		DeadLetterTopicKey:    TopicAssetUpserts,
+		DeadLetterErrorKey:    "store unavailable",
+		"source":              "test",
+	} {
+		if got := dead[0].Metadata[key]; got != want {
+			t.Errorf("metadata[%s] = %v, want %v", key, got, want)
+		}
+	}
+	if got := p.GetMetrics().EventsDeadLettered; got != 1 {
+		t.Errorf("EventsDeadLettered = %d, want 1", got)
+	}
+}
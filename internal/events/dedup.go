@@ -0,0 +1,91 @@
+package events
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Deduper tracks processed event IDs so events redelivered by the bus are
+// skipped. Implementations forget IDs after their own TTL; a shared store
+// lets several processors skip each other's events.
+type Deduper interface {
+	// Claim records the event ID as processed and reports whether it was
+	// not already recorded, in one atomic step so concurrent duplicates
+	// cannot both claim it
+	Claim(id string) bool
+	// Release forgets a claimed event ID whose processing failed, so its
+	// redelivery is processed
+	Release(id string)
+}
+
+// eventDeduper remembers recently processed event IDs so redelivered events
+// are skipped. Entries expire after window and the oldest entries are evicted
+// once maxSize is reached.
+type eventDeduper struct {
+	mu      sync.Mutex
+	window  time.Duration
+	maxSize int
+	seen    map[string]*list.Element
+	order   *list.List
+}
+
+type seenEvent struct {
+	id     string
+	seenAt time.Time
+}
+
+func newEventDeduper(window time.Duration, maxSize int) *eventDeduper {
+	return &eventDeduper{
+		window:  window,
+		maxSize: maxSize,
+		seen:    make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Claim records the event ID and reports whether it was not already
+// recorded within the window
+func (d *eventDeduper) Claim(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.evictExpired(now)
+
+	if _, exists := d.seen[id]; exists {
+		return false
+	}
+	d.seen[id] = d.order.PushBack(seenEvent{id: id, seenAt: now})
+
+	for d.maxSize > 0 && d.order.Len() > d.maxSize {
+		d.remove(d.order.Front())
+	}
+	return true
+}
+
+// Release forgets the event ID
+func (d *eventDeduper) Release(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if elem, exists := d.seen[id]; exists {
+		d.remove(elem)
+	}
+}
+
+// evictExpired drops entries older than the window. Entries are kept in
+// insertion order, so eviction stops at the first unexpired entry.
+func (d *eventDeduper) evictExpired(now time.Time) {
+	for elem := d.order.Front(); elem != nil; elem = d.order.Front() {
+		if now.Sub(elem.Value.(seenEvent).seenAt) < d.window {
+			return
+		}
+		d.remove(elem)
+	}
+}
+
+func (d *eventDeduper) remove(elem *list.Element) {
+	d.order.Remove(elem)
+	delete(d.seen, elem.Value.(seenEvent).id)
+}
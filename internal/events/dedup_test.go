@@ -0,0 +1,44 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventDeduperClaimsOnceUnderConcurrency(t *testing.T) {
+	d := newEventDeduper(time.Minute, 100)
+
+	var claimed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if d.Claim("event-1") {
+				atomic.AddInt32(&claimed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if claimed != 1 {
+		t.Fatalf("event claimed %d times, want 1", claimed)
+	}
+}
+
+func TestEventDeduperReleaseAllowsRedelivery(t *testing.T) {
+	d := newEventDeduper(time.Minute, 100)
+
+	if !d.Claim("event-1") {
+		t.Fatal("first Claim returned false")
+	}
+	d.Release("event-1")
+	if !d.Claim("event-1") {
+		t.Fatal("Claim after Release returned false")
+	}
+	if d.Claim("event-1") {
+		t.Fatal("second Claim returned true")
+	}
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// namedHandler is an EventHandlerFunc reported under a readable name
+type namedHandler struct {
+	name string
+	fn   EventHandlerFunc
+}
+
+// NamedHandler adapts fn to an EventHandler named name, so it can be told
+// apart in logs and handler listings
+func NamedHandler(name string, fn EventHandlerFunc) EventHandler {
+	return &namedHandler{name: name, fn: fn}
+}
+
+func (h *namedHandler) Handle(ctx context.Context, event models.BaseEvent) error {
+	return h.fn(ctx, event)
+}
+
+func (h *namedHandler) GetName() string {
+	return h.name
+}
+
+// HandlerStats are the outcomes of one handler for one event type since the
+// processor started
+type HandlerStats struct {
+	Name        string     `json:"name"`
+	Succeeded   int64      `json:"succeeded"`
+	Failed      int64      `json:"failed"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// EventTypeHandlers lists the handlers registered for an event type.
+// Unhandled counts events of the type that arrived with no handler
+// registered and were dropped.
+type EventTypeHandlers struct {
+	EventType models.EventType `json:"event_type"`
+	Handlers  []HandlerStats   `json:"handlers"`
+	Unhandled int64            `json:"unhandled,omitempty"`
+}
+
+// handlerStats records handler outcomes per event type
+type handlerStats struct {
+	mu        sync.Mutex
+	stats     map[models.EventType]map[string]*HandlerStats
+	unhandled map[models.EventType]int64
+}
+
+func newHandlerStats() *handlerStats {
+	return &handlerStats{
+		stats:     make(map[models.EventType]map[string]*HandlerStats),
+		unhandled: make(map[models.EventType]int64),
+	}
+}
+
+// record counts one run of the named handler for eventType
+func (s *handlerStats) record(eventType models.EventType, name string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byName := s.stats[eventType]
+	if byName == nil {
+		byName = make(map[string]*HandlerStats)
+		s.stats[eventType] = byName
+	}
+	stats := byName[name]
+	if stats == nil {
+		stats = &HandlerStats{Name: name}
+		byName[name] = stats
+	}
+
+	now := time.Now()
+	if err != nil {
+		stats.Failed++
+		stats.LastFailure = &now
+		stats.LastError = err.Error()
+		return
+	}
+	stats.Succeeded++
+	stats.LastSuccess = &now
+}
+
+// recordUnhandled counts an event of eventType that no handler processed
+func (s *handlerStats) recordUnhandled(eventType models.EventType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unhandled[eventType]++
+}
+
+// Handlers returns the handlers registered per event type, in registration
+// order, with their outcomes. Event types that arrived without any handler
+// registered are included with an empty handler list.
+func (p *EventProcessor) Handlers() []EventTypeHandlers {
+	p.mu.RLock()
+	registered := make(map[models.EventType][]string, len(p.handlers))
+	for eventType, handlers := range p.handlers {
+		for _, handler := range handlers {
+			registered[eventType] = append(registered[eventType], handler.GetName())
+		}
+	}
+	p.mu.RUnlock()
+
+	p.handlerStats.mu.Lock()
+	defer p.handlerStats.mu.Unlock()
+
+	for eventType := range p.handlerStats.unhandled {
+		if _, ok := registered[eventType]; !ok {
+			registered[eventType] = nil
+		}
+	}
+
+	result := make([]EventTypeHandlers, 0, len(registered))
+	for eventType, names := range registered {
+		entry := EventTypeHandlers{
+			EventType: eventType,
+			Handlers:  make([]HandlerStats, 0, len(names)),
+			Unhandled: p.handlerStats.unhandled[eventType],
+		}
+		for _, name := range names {
+			stats := HandlerStats{Name: name}
+			if recorded := p.handlerStats.stats[eventType][name]; recorded != nil {
+				stats = *recorded
+			}
+			entry.Handlers = append(entry.Handlers, stats)
+		}
+		result = append(result, entry)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].EventType < result[j].EventType })
+	return result
+}
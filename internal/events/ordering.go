@@ -0,0 +1,177 @@
+package events
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/securizon/pkg/models"
+	"github.com/segmentio/kafka-go"
+)
+
+// Event ordering modes
+//
+// With OrderingPerAsset, every event about an asset is published with the
+// asset ID as its message key, so the hash balancer writes all of them to the
+// same partition, and consumers dispatch events with the same key to the same
+// worker. Events for one asset are therefore handled one at a time in the
+// order they were published (create before update before delete), while
+// events for different assets may be handled concurrently. Events without an
+// asset ID are keyed by their own ID and carry no ordering guarantee.
+//
+// With OrderingNone, events are spread across partitions and workers to
+// balance load and may be handled in any order.
+const (
+	OrderingPerAsset = "per_asset"
+	OrderingNone     = "none"
+)
+
+// PartitionKey returns the key event is partitioned and dispatched by under
+// OrderingPerAsset: its asset ID, or its own ID if it is not about an asset
+func PartitionKey(event models.BaseEvent) string {
+	if event.AssetID != "" {
+		return event.AssetID
+	}
+	return event.ID
+}
+
+// messageKey returns the Kafka message key of event for an ordering mode
+func messageKey(ordering string, event models.BaseEvent) []byte {
+	if ordering == OrderingNone {
+		return []byte(event.ID)
+	}
+	return []byte(PartitionKey(event))
+}
+
+// newBalancer returns the producer balancer for an ordering mode. Hashing
+// the key keeps every event of an asset on one partition.
+func newBalancer(ordering string) kafka.Balancer {
+	if ordering == OrderingNone {
+		return &kafka.LeastBytes{}
+	}
+	return &kafka.Hash{}
+}
+
+// orderedDispatcher hands events to a fixed set of workers, always sending
+// events with the same key to the same worker so they are handled in the
+// order they were dispatched. Each event is dispatched with the handler to
+// run it, so one dispatcher can serve several subscriptions.
+type orderedDispatcher struct {
+	lanes  []chan dispatchedEvent
+	key    func(models.BaseEvent) string
+	mu     sync.RWMutex // held for writing once the lanes are closed
+	closed bool
+	wg     sync.WaitGroup
+
+	statsMu     sync.Mutex
+	busy        []time.Duration // per worker, since windowStart
+	windowStart time.Time
+}
+
+// dispatchedEvent is an event queued on a worker with its handler
+type dispatchedEvent struct {
+	handler EventHandler
+	event   models.BaseEvent
+}
+
+// newOrderedDispatcher starts workers goroutines running the handlers of the
+// events dispatched to them with ctx, logging the errors they return. Each
+// worker queues up to buffer events before dispatch blocks.
+func newOrderedDispatcher(ctx context.Context, ordering string, workers, buffer int) *orderedDispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	d := &orderedDispatcher{
+		lanes:       make([]chan dispatchedEvent, workers),
+		key:         PartitionKey,
+		busy:        make([]time.Duration, workers),
+		windowStart: time.Now(),
+	}
+	if ordering == OrderingNone {
+		d.key = func(event models.BaseEvent) string { return event.ID }
+	}
+
+	for i := range d.lanes {
+		lane := make(chan dispatchedEvent, buffer)
+		d.lanes[i] = lane
+
+		d.wg.Add(1)
+		go func(worker int) {
+			defer d.wg.Done()
+			for queued := range lane {
+				start := time.Now()
+				if err := queued.handler.Handle(ctx, queued.event); err != nil {
+					log.Printf("Error handling event %s in %s: %v", queued.event.ID, queued.handler.GetName(), err)
+				}
+				d.addBusy(worker, time.Since(start))
+			}
+		}(i)
+	}
+
+	return d
+}
+
+// dispatch queues event for handler on the worker owning its key, blocking
+// while that worker is full. It returns false if ctx is done first or the
+// dispatcher is closed.
+func (d *orderedDispatcher) dispatch(ctx context.Context, handler EventHandler, event models.BaseEvent) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return false
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(d.key(event)))
+	lane := d.lanes[h.Sum32()%uint32(len(d.lanes))]
+
+	select {
+	case lane <- dispatchedEvent{handler: handler, event: event}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// close stops accepting events and waits for queued events to be handled.
+// Dispatches blocked on a full worker must be released by canceling their
+// context first.
+func (d *orderedDispatcher) close() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, lane := range d.lanes {
+			close(lane)
+		}
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+func (d *orderedDispatcher) addBusy(worker int, busy time.Duration) {
+	d.statsMu.Lock()
+	defer d.statsMu.Unlock()
+	d.busy[worker] += busy
+}
+
+// utilization returns the share of time each worker spent handling events
+// since the last call, and starts a new window
+func (d *orderedDispatcher) utilization(now time.Time) map[int]float64 {
+	d.statsMu.Lock()
+	defer d.statsMu.Unlock()
+
+	window := now.Sub(d.windowStart)
+	result := make(map[int]float64, len(d.busy))
+	for worker, busy := range d.busy {
+		if window > 0 {
+			result[worker] = math.Min(1, busy.Seconds()/window.Seconds())
+		}
+		d.busy[worker] = 0
+	}
+	d.windowStart = now
+	return result
+}
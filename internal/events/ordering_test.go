@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/securizon/pkg/models"
+)
+
+func TestOrderedDispatcherKeepsPerAssetOrderAndDrainsOnClose(t *testing.T) {
+	d := newOrderedDispatcher(context.Background(), OrderingPerAsset, 4, 8)
+
+	var mu sync.Mutex
+	handled := make(map[string][]int)
+	handler := NamedHandler("recorder", func(ctx context.Context, event models.BaseEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seq, _ := event.Metadata["seq"].(int)
+		handled[event.AssetID] = append(handled[event.AssetID], seq)
+		if seq%3 == 0 {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	const assets, perAsset = 5, 20
+	for seq := 0; seq < perAsset; seq++ {
+		for a := 0; a < assets; a++ {
+			event := models.BaseEvent{
+				ID:       fmt.Sprintf("event-%d-%d", a, seq),
+				AssetID:  fmt.Sprintf("asset-%d", a),
+				Metadata: map[string]interface{}{"seq": seq},
+			}
+			if !d.dispatch(context.Background(), handler, event) {
+				t.Fatalf("dispatch(%s) = false before close", event.ID)
+			}
+		}
+	}
+	d.close()
+
+	for a := 0; a < assets; a++ {
+		got := handled[fmt.Sprintf("asset-%d", a)]
+		if len(got) != perAsset {
+			t.Fatalf("asset-%d: %d events handled, want %d", a, len(got), perAsset)
+		}
+		for i, seq := range got {
+			if seq != i {
+				t.Fatalf("asset-%d: events handled in order %v", a, got)
+			}
+		}
+	}
+
+	if d.dispatch(context.Background(), handler, models.BaseEvent{ID: "late"}) {
+		t.Error("dispatch after close = true, want false")
+	}
+}
@@ -0,0 +1,200 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/models"
+)
+
+// PathRecomputer recomputes the attack paths running through a batch of
+// changed assets and stores them in bulk
+type PathRecomputer interface {
+	RecomputeAffectedPaths(ctx context.Context, assetIDs []string) error
+}
+
+// PathRecomputeConfig configures the debounced attack path recompute run
+// after relationship changes
+type PathRecomputeConfig struct {
+	// Window is how long the batch waits for further changes after the last
+	// one; MaxWait bounds how long a steady stream of changes can delay it
+	Window  time.Duration `json:"window"`
+	MaxWait time.Duration `json:"max_wait"`
+	// MaxBatch recomputes as soon as this many assets have changed
+	MaxBatch int           `json:"max_batch"`
+	Timeout  time.Duration `json:"timeout"` // of each recompute
+	// MaxRetries is how many times the assets of a failed recompute are
+	// queued again before they are dropped
+	MaxRetries int `json:"max_retries"`
+}
+
+// DefaultPathRecomputeConfig returns default path recompute configuration
+func DefaultPathRecomputeConfig() PathRecomputeConfig {
+	return PathRecomputeConfig{
+		Window:     5 * time.Second,
+		MaxWait:    30 * time.Second,
+		MaxBatch:   500,
+		Timeout:    2 * time.Minute,
+		MaxRetries: 3,
+	}
+}
+
+// pathRecomputeBatcher collects the assets touched by relationship changes
+// and recomputes their attack paths once the changes settle, so a large
+// collection run causes a few bulk recomputes instead of one per change.
+// Batches are kept per data region and recomputed in their region. The
+// assets of a failed recompute are queued again, and pending batches are
+// recomputed when the processor stops.
+type pathRecomputeBatcher struct {
+	recomputer PathRecomputer
+	config     PathRecomputeConfig
+	mu         sync.Mutex
+	pending    map[string]*pendingRecompute
+	running    sync.WaitGroup
+	stopped    bool
+}
+
+// pendingRecompute is the batch of one region waiting to be recomputed.
+// attempts counts the failed recomputes of the assets queued again in it.
+type pendingRecompute struct {
+	assets   map[string]bool
+	attempts int
+	started  time.Time
+	timer    *time.Timer
+}
+
+func newPathRecomputeBatcher(recomputer PathRecomputer, config PathRecomputeConfig) *pathRecomputeBatcher {
+	return &pathRecomputeBatcher{
+		recomputer: recomputer,
+		config:     config,
+		pending:    make(map[string]*pendingRecompute),
+	}
+}
+
+// add queues assetIDs for recompute, restarting the batch's window
+func (b *pathRecomputeBatcher) add(ctx context.Context, assetIDs ...string) error {
+	region, err := tenant.RegionFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.stopped {
+		b.queue(region, assetIDs, 0)
+	}
+	return nil
+}
+
+// queue adds assetIDs to the pending batch of region, creating it if needed.
+// It must be called with b.mu held.
+func (b *pathRecomputeBatcher) queue(region string, assetIDs []string, attempts int) {
+	batch, ok := b.pending[region]
+	if !ok {
+		batch = &pendingRecompute{
+			assets:  make(map[string]bool),
+			started: time.Now(),
+		}
+		batch.timer = time.AfterFunc(b.config.Window, func() { b.flush(region, batch) })
+		b.pending[region] = batch
+	}
+	if attempts > batch.attempts {
+		batch.attempts = attempts
+	}
+	for _, id := range assetIDs {
+		if id != "" {
+			batch.assets[id] = true
+		}
+	}
+
+	switch {
+	case b.config.MaxBatch > 0 && len(batch.assets) >= b.config.MaxBatch:
+		batch.timer.Reset(0)
+	case b.config.MaxWait > 0 && time.Since(batch.started)+b.config.Window > b.config.MaxWait:
+		// Let the batch run out at MaxWait instead of extending it
+	default:
+		batch.timer.Reset(b.config.Window)
+	}
+}
+
+// flush recomputes a batch, unless it was already taken by an earlier flush
+func (b *pathRecomputeBatcher) flush(region string, batch *pendingRecompute) {
+	b.mu.Lock()
+	if b.pending[region] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, region)
+	b.running.Add(1)
+	b.mu.Unlock()
+	defer b.running.Done()
+
+	assetIDs := make([]string, 0, len(batch.assets))
+	for id := range batch.assets {
+		assetIDs = append(assetIDs, id)
+	}
+
+	ctx := tenant.WithRegion(context.Background(), region)
+	if b.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.config.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	if err := b.recomputer.RecomputeAffectedPaths(ctx, assetIDs); err != nil {
+		b.retry(region, assetIDs, batch.attempts+1, err)
+		return
+	}
+	log.Printf("Recomputed attack paths for %d changed assets in %v", len(assetIDs), time.Since(start))
+}
+
+// retry queues the assets of a failed recompute again, unless they have
+// failed too often or the batcher has stopped
+func (b *pathRecomputeBatcher) retry(region string, assetIDs []string, attempts int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped || attempts > b.config.MaxRetries {
+		log.Printf("Failed to recompute attack paths for %d changed assets after %d attempts, dropping them: %v", len(assetIDs), attempts, err)
+		return
+	}
+	log.Printf("Failed to recompute attack paths for %d changed assets (attempt %d), retrying: %v", len(assetIDs), attempts, err)
+	b.queue(region, assetIDs, attempts)
+}
+
+// stop recomputes the pending batches and waits for running recomputes to
+// finish. Assets added after stop are not recomputed.
+func (b *pathRecomputeBatcher) stop() {
+	b.mu.Lock()
+	b.stopped = true
+	pending := make(map[string]*pendingRecompute, len(b.pending))
+	for region, batch := range b.pending {
+		pending[region] = batch
+	}
+	b.mu.Unlock()
+
+	for region, batch := range pending {
+		batch.timer.Stop()
+		b.flush(region, batch)
+	}
+	b.running.Wait()
+}
+
+// handleRelationshipPaths queues both ends of a changed relationship for the
+// debounced attack path recompute
+func (p *EventProcessor) handleRelationshipPaths(ctx context.Context, event models.BaseEvent) error {
+	if p.pathBatcher == nil {
+		return nil
+	}
+
+	var relEvent models.RelationshipEvent
+	if err := p.unmarshalEvent(event, &relEvent); err != nil {
+		return err
+	}
+	return p.pathBatcher.add(ctx, relEvent.Relationship.FromAssetID, relEvent.Relationship.ToAssetID)
+}
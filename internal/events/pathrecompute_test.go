@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyRecomputer fails its first failures recomputes and records the
+// batches it recomputed
+type flakyRecomputer struct {
+	mu       sync.Mutex
+	failures int
+	batches  [][]string
+}
+
+func (r *flakyRecomputer) RecomputeAffectedPaths(ctx context.Context, assetIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("graph unavailable")
+	}
+	sorted := append([]string(nil), assetIDs...)
+	sort.Strings(sorted)
+	r.batches = append(r.batches, sorted)
+	return nil
+}
+
+func (r *flakyRecomputer) recomputed() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.batches
+}
+
+func TestPathRecomputeBatcherRetriesFailedBatches(t *testing.T) {
+	recomputer := &flakyRecomputer{failures: 1}
+	b := newPathRecomputeBatcher(recomputer, PathRecomputeConfig{Window: time.Millisecond, MaxRetries: 2})
+
+	if err := b.add(context.Background(), "asset-1", "asset-2"); err != nil {
+		t.Fatalf("add returned error: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(recomputer.recomputed()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	batches := recomputer.recomputed()
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("recomputed batches = %v, want the failed batch retried once", batches)
+	}
+}
+
+func TestPathRecomputeBatcherFlushesOnStop(t *testing.T) {
+	recomputer := &flakyRecomputer{}
+	b := newPathRecomputeBatcher(recomputer, PathRecomputeConfig{Window: time.Hour})
+
+	if err := b.add(context.Background(), "asset-1"); err != nil {
+		t.Fatalf("add returned error: %v", err)
+	}
+	b.stop()
+
+	if batches := recomputer.recomputed(); len(batches) != 1 || batches[0][0] != "asset-1" {
+		t.Fatalf("recomputed batches = %v, want the pending batch", batches)
+	}
+
+	// Assets added after stop are dropped
+	b.add(context.Background(), "asset-2")
+	if batches := recomputer.recomputed(); len(batches) != 1 {
+		t.Errorf("recomputed batches after stop = %v, want no more", batches)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -21,11 +22,25 @@ type EventProcessor struct {
 	mu            sync.RWMutex
 	metrics       *ProcessorMetrics
 	config        ProcessorConfig
+	deduper       Deduper
+	handlerStats  *handlerStats
+	anomalies     *RelationshipAnomalyDetector
+	pathBatcher   *pathRecomputeBatcher
+	enricher      FindingEnricher
+	workers       *orderedDispatcher
+	drained       chan struct{}
+}
+
+// FindingEnricher attaches external context, such as CVE exploit scores, to
+// findings before they are stored
+type FindingEnricher interface {
+	EnrichFinding(ctx context.Context, asset models.Asset, finding *models.Finding) error
 }
 
 // GraphStore interface for graph operations
 type GraphStore interface {
 	CreateAsset(ctx context.Context, asset models.Asset) error
+	UpsertAsset(ctx context.Context, asset models.Asset) (bool, error)
 	GetAsset(ctx context.Context, id string) (models.Asset, error)
 	UpdateAsset(ctx context.Context, asset models.Asset) error
 	DeleteAsset(ctx context.Context, id string) error
@@ -34,9 +49,12 @@ type GraphStore interface {
 	DeleteRelationship(ctx context.Context, id string) error
 	CreateFinding(ctx context.Context, finding models.Finding) error
 	UpdateFinding(ctx context.Context, finding models.Finding) error
+	ResolveFinding(ctx context.Context, findingID string) (models.Finding, error)
 	GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error)
+	GetUnresolvedPolicyFindings(ctx context.Context, assetID string) ([]models.Finding, error)
 	GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error)
 	UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error
+	RecomputeInternetExposure(ctx context.Context, assetID string, maxHops int) ([]string, error)
 }
 
 // RiskEngine interface for risk calculations
@@ -89,23 +107,32 @@ type PolicyFilter struct {
 // ProcessorConfig represents event processor configuration
 type ProcessorConfig struct {
 	WorkerCount       int           `json:"worker_count"`
+	WorkerQueueSize   int           `json:"worker_queue_size"` // events queued per worker before consumers block
 	BatchSize         int           `json:"batch_size"`
 	BatchTimeout      time.Duration `json:"batch_timeout"`
-	RetryAttempts     int           `json:"retry_attempts"`
-	RetryDelay        time.Duration `json:"retry_delay"`
+	RetryAttempts     int           `json:"retry_attempts"` // retries of a failing handler before the event is dead-lettered
+	RetryDelay        time.Duration `json:"retry_delay"`    // delay before the first retry, doubled for each one after
+	MaxRetryDelay     time.Duration `json:"max_retry_delay"` // cap on the doubled delay; 0 leaves it uncapped
 	EnableMetrics     bool          `json:"enable_metrics"`
 	MetricsInterval   time.Duration `json:"metrics_interval"`
 	DeadLetterTopic   string        `json:"dead_letter_topic"`
 	EnableDLQ         bool          `json:"enable_dlq"`
+	ExposureMaxHops   int           `json:"exposure_max_hops"` // 0 disables exposure recomputation
+	DedupWindow       time.Duration `json:"dedup_window"`      // 0 disables event deduplication
+	DedupCacheSize    int           `json:"dedup_cache_size"`
+	Anomaly           AnomalyConfig `json:"anomaly"` // spikes in new relationships per asset
+	PathRecompute     PathRecomputeConfig `json:"path_recompute"`
 }
 
 // ProcessorMetrics represents processor metrics
 type ProcessorMetrics struct {
 	EventsProcessed    int64     `json:"events_processed"`
 	EventsFailed       int64     `json:"events_failed"`
-	EventsRetried      int64     `json:"events_retried"
+	EventsRetried      int64     `json:"events_retried"`
 	AverageLatency     time.Duration `json:"average_latency"`
 	LastProcessed      time.Time `json:"last_processed"`
+	EventsDeduplicated int64     `json:"events_deduplicated"`
+	EventsDeadLettered int64     `json:"events_dead_lettered"`
 	EventsByType       map[models.EventType]int64 `json:"events_by_type"`
 	ErrorsByType       map[string]int64 `json:"errors_by_type"`
 	WorkerUtilization  map[int]float64 `json:"worker_utilization"`
@@ -116,14 +143,21 @@ type ProcessorMetrics struct {
 func DefaultProcessorConfig() ProcessorConfig {
 	return ProcessorConfig{
 		WorkerCount:     10,
+		WorkerQueueSize: 100,
 		BatchSize:       100,
 		BatchTimeout:    5 * time.Second,
 		RetryAttempts:   3,
 		RetryDelay:      1 * time.Second,
+		MaxRetryDelay:   30 * time.Second,
 		EnableMetrics:   true,
 		MetricsInterval: 30 * time.Second,
 		DeadLetterTopic: "events.dlq",
 		EnableDLQ:       true,
+		ExposureMaxHops: 3,
+		DedupWindow:     10 * time.Minute,
+		DedupCacheSize:  100000,
+		Anomaly:         DefaultAnomalyConfig(),
+		PathRecompute:   DefaultPathRecomputeConfig(),
 	}
 }
 
@@ -136,6 +170,8 @@ func NewEventProcessor(bus EventBus, graphStore GraphStore, riskEngine RiskEngin
 		policyEngine: policyEngine,
 		handlers:     make(map[models.EventType][]EventHandler),
 		config:       config,
+		handlerStats: newHandlerStats(),
+		drained:      make(chan struct{}),
 		metrics:      &ProcessorMetrics{
 			EventsByType: make(map[models.EventType]int64),
 			ErrorsByType: make(map[string]int64),
@@ -143,6 +179,14 @@ func NewEventProcessor(bus EventBus, graphStore GraphStore, riskEngine RiskEngin
 		},
 	}
 
+	if config.DedupWindow > 0 {
+		processor.deduper = newEventDeduper(config.DedupWindow, config.DedupCacheSize)
+	}
+
+	if config.Anomaly.Enabled {
+		processor.anomalies = NewRelationshipAnomalyDetector(config.Anomaly, nil)
+	}
+
 	// Register default handlers
 	processor.registerDefaultHandlers()
 
@@ -152,28 +196,85 @@ func NewEventProcessor(bus EventBus, graphStore GraphStore, riskEngine RiskEngin
 // registerDefaultHandlers registers default event handlers
 func (p *EventProcessor) registerDefaultHandlers() {
 	// Asset event handlers
-	p.RegisterHandler(models.EventTypeAssetCreated, EventHandlerFunc(p.handleAssetCreated))
-	p.RegisterHandler(models.EventTypeAssetUpdated, EventHandlerFunc(p.handleAssetUpdated))
-	p.RegisterHandler(models.EventTypeAssetDeleted, EventHandlerFunc(p.handleAssetDeleted))
+	p.RegisterHandler(models.EventTypeAssetCreated, NamedHandler("asset_created", p.handleAssetCreated))
+	p.RegisterHandler(models.EventTypeAssetUpdated, NamedHandler("asset_updated", p.handleAssetUpdated))
+	p.RegisterHandler(models.EventTypeAssetDeleted, NamedHandler("asset_deleted", p.handleAssetDeleted))
 
 	// Relationship event handlers
-	p.RegisterHandler(models.EventTypeRelationshipCreated, EventHandlerFunc(p.handleRelationshipCreated))
-	p.RegisterHandler(models.EventTypeRelationshipUpdated, EventHandlerFunc(p.handleRelationshipUpdated))
-	p.RegisterHandler(models.EventTypeRelationshipDeleted, EventHandlerFunc(p.handleRelationshipDeleted))
+	p.RegisterHandler(models.EventTypeRelationshipCreated, NamedHandler("relationship_created", p.handleRelationshipCreated))
+	p.RegisterHandler(models.EventTypeRelationshipUpdated, NamedHandler("relationship_updated", p.handleRelationshipUpdated))
+	p.RegisterHandler(models.EventTypeRelationshipDeleted, NamedHandler("relationship_deleted", p.handleRelationshipDeleted))
+
+	// Exposure handlers run after the graph change has been applied
+	p.RegisterHandler(models.EventTypeAssetUpdated, NamedHandler("exposure_change", p.handleExposureChange))
+	p.RegisterHandler(models.EventTypeRelationshipCreated, NamedHandler("exposure_change", p.handleExposureChange))
+	p.RegisterHandler(models.EventTypeRelationshipDeleted, NamedHandler("exposure_change", p.handleExposureChange))
+
+	// Attack paths are recomputed in batches once relationship changes settle
+	p.RegisterHandler(models.EventTypeRelationshipCreated, NamedHandler("relationship_paths", p.handleRelationshipPaths))
+	p.RegisterHandler(models.EventTypeRelationshipUpdated, NamedHandler("relationship_paths", p.handleRelationshipPaths))
+	p.RegisterHandler(models.EventTypeRelationshipDeleted, NamedHandler("relationship_paths", p.handleRelationshipPaths))
+
+	// Anomaly detection runs on new relationships once they are stored
+	if p.anomalies != nil {
+		p.RegisterHandler(models.EventTypeRelationshipCreated, NamedHandler("relationship_anomaly", p.handleRelationshipAnomaly))
+	}
 
 	// Finding event handlers
-	p.RegisterHandler(models.EventTypeFindingCreated, EventHandlerFunc(p.handleFindingCreated))
-	p.RegisterHandler(models.EventTypeFindingUpdated, EventHandlerFunc(p.handleFindingUpdated))
-	p.RegisterHandler(models.EventTypeFindingResolved, EventHandlerFunc(p.handleFindingResolved))
+	p.RegisterHandler(models.EventTypeFindingCreated, NamedHandler("finding_created", p.handleFindingCreated))
+	p.RegisterHandler(models.EventTypeFindingUpdated, NamedHandler("finding_updated", p.handleFindingUpdated))
+	p.RegisterHandler(models.EventTypeFindingResolved, NamedHandler("finding_resolved", p.handleFindingResolved))
 
 	// Policy violation handlers
-	p.RegisterHandler(models.EventTypePolicyViolation, EventHandlerFunc(p.handlePolicyViolation))
+	p.RegisterHandler(models.EventTypePolicyViolation, NamedHandler("policy_violation", p.handlePolicyViolation))
 
 	// Threat event handlers
-	p.RegisterHandler(models.EventTypeThreatDetected, EventHandlerFunc(p.handleThreatDetected))
+	p.RegisterHandler(models.EventTypeThreatDetected, NamedHandler("threat_detected", p.handleThreatDetected))
 
 	// Risk score change handlers
-	p.RegisterHandler(models.EventTypeRiskScoreChanged, EventHandlerFunc(p.handleRiskScoreChanged))
+	p.RegisterHandler(models.EventTypeRiskScoreChanged, NamedHandler("risk_score_changed", p.handleRiskScoreChanged))
+}
+
+// SetAnomalyBaselineStore replaces the in-memory relationship baseline used
+// for anomaly detection, e.g. with a store shared by all processors. It has
+// no effect when anomaly detection is disabled.
+func (p *EventProcessor) SetAnomalyBaselineStore(store RelationshipBaselineStore) {
+	if p.anomalies != nil {
+		p.anomalies.store = store
+	}
+}
+
+// SetPathRecomputer enables the debounced attack path recompute after
+// relationship changes, run by recomputer
+func (p *EventProcessor) SetPathRecomputer(recomputer PathRecomputer) {
+	p.pathBatcher = newPathRecomputeBatcher(recomputer, p.config.PathRecompute)
+}
+
+// SetDeduper replaces the in-memory deduplication of processed events, e.g.
+// with a store shared by all processors. A nil deduper disables it.
+func (p *EventProcessor) SetDeduper(deduper Deduper) {
+	p.deduper = deduper
+}
+
+// SetFindingEnricher enables enrichment of findings before they are stored
+func (p *EventProcessor) SetFindingEnricher(enricher FindingEnricher) {
+	p.enricher = enricher
+}
+
+// enrichFinding enriches finding if an enricher is set. Enrichment failures
+// are logged and the finding is stored without it.
+func (p *EventProcessor) enrichFinding(ctx context.Context, asset models.Asset, finding *models.Finding) {
+	if p.enricher == nil {
+		return
+	}
+	if asset == nil && finding.AssetID != "" {
+		if stored, err := p.graphStore.GetAsset(ctx, finding.AssetID); err == nil {
+			asset = stored
+		}
+	}
+	if err := p.enricher.EnrichFinding(ctx, asset, finding); err != nil {
+		log.Printf("Failed to enrich finding %s: %v", finding.ID, err)
+	}
 }
 
 // RegisterHandler registers a handler for an event type
@@ -202,8 +303,31 @@ func (p *EventProcessor) Start(ctx context.Context) error {
 		TopicFindings,
 	}
 
+	// Consumers hand events to a dispatcher shared by all topics, keyed by
+	// asset so each asset's events are handled in order. It keeps handling
+	// the events already queued after ctx is canceled.
+	p.workers = newOrderedDispatcher(context.WithoutCancel(ctx), OrderingPerAsset, p.config.WorkerCount, p.config.WorkerQueueSize)
+	go func() {
+		<-ctx.Done()
+		p.workers.close()
+		// Recompute the attack paths of the changes already handled
+		if p.pathBatcher != nil {
+			p.pathBatcher.stop()
+		}
+		close(p.drained)
+	}()
+
 	for _, topic := range topics {
-		handler := EventHandlerFunc(p.handleEvent)
+		topic := topic
+		process := NamedHandler("processor-"+topic, func(ctx context.Context, event models.BaseEvent) error {
+			return p.handleEvent(ctx, topic, event)
+		})
+		handler := EventHandlerFunc(func(ctx context.Context, event models.BaseEvent) error {
+			if !p.workers.dispatch(ctx, process, event) {
+				return fmt.Errorf("event %s not dispatched: processor is stopping", event.ID)
+			}
+			return nil
+		})
 		if err := p.bus.SubscribeGroup(ctx, topic, fmt.Sprintf("processor-%s", topic), handler); err != nil {
 			return fmt.Errorf("failed to subscribe to topic %s: %w", topic, err)
 		}
@@ -218,14 +342,36 @@ func (p *EventProcessor) Start(ctx context.Context) error {
 	return nil
 }
 
-// handleEvent is the main event handler
-func (p *EventProcessor) handleEvent(ctx context.Context, event models.BaseEvent) error {
+// Wait blocks until the processor has been stopped by canceling the context
+// it was started with and the events it had queued have been handled
+func (p *EventProcessor) Wait() {
+	<-p.drained
+}
+
+// handleEvent is the main event handler for events consumed from topic.
+// Each handler is retried on transient failures; events a handler still
+// fails on are published to the dead letter topic when it is enabled, and
+// failures that cannot be dead-lettered are returned.
+func (p *EventProcessor) handleEvent(ctx context.Context, topic string, event models.BaseEvent) error {
 	start := time.Now()
+	var failed error
 	defer func() {
 		latency := time.Since(start)
-		p.updateMetrics(event.Type, latency, nil)
+		p.updateMetrics(event.Type, latency, failed)
 	}()
 
+	// Skip events already processed, or being processed, within the dedup
+	// window; redelivery is expected with at-least-once consumption and
+	// retries
+	claimed := p.deduper != nil && event.ID != ""
+	if claimed && !p.deduper.Claim(event.ID) {
+		log.Printf("Skipping duplicate event %s (%s)", event.ID, event.Type)
+		p.metrics.mu.Lock()
+		p.metrics.EventsDeduplicated++
+		p.metrics.mu.Unlock()
+		return nil
+	}
+
 	// Get handlers for this event type
 	p.mu.RLock()
 	handlers := p.handlers[event.Type]
@@ -233,19 +379,39 @@ func (p *EventProcessor) handleEvent(ctx context.Context, event models.BaseEvent
 
 	if len(handlers) == 0 {
 		log.Printf("No handlers registered for event type: %s", event.Type)
+		p.handlerStats.recordUnhandled(event.Type)
 		return nil
 	}
 
 	// Execute all handlers for this event type
 	var errors []error
 	for _, handler := range handlers {
-		if err := handler.Handle(ctx, event); err != nil {
-			log.Printf("Handler %s failed for event %s: %v", handler.GetName(), event.ID, err)
-			errors = append(errors, err)
+		attempts, err := p.runHandler(ctx, handler, event)
+		p.handlerStats.record(event.Type, handler.GetName(), err)
+		if err == nil {
+			continue
+		}
+		log.Printf("Handler %s failed for event %s after %d attempt(s): %v", handler.GetName(), event.ID, attempts, err)
+		if failed == nil {
+			failed = err
 		}
+
+		// Events interrupted by shutdown are left to be redelivered
+		if p.config.EnableDLQ && p.config.DeadLetterTopic != "" && ctx.Err() == nil {
+			dlqErr := p.deadLetter(ctx, topic, event, handler.GetName(), attempts, err)
+			if dlqErr == nil {
+				continue
+			}
+			log.Printf("%v", dlqErr)
+		}
+		errors = append(errors, err)
 	}
 
+	// Failed events are released so their redelivery is processed
 	if len(errors) > 0 {
+		if claimed {
+			p.deduper.Release(event.ID)
+		}
 		return fmt.Errorf("handlers failed: %v", errors)
 	}
 
@@ -255,79 +421,171 @@ func (p *EventProcessor) handleEvent(ctx context.Context, event models.BaseEvent
 // Asset event handlers
 
 func (p *EventProcessor) handleAssetCreated(ctx context.Context, event models.BaseEvent) error {
-	var assetEvent models.AssetEvent
-	if err := p.unmarshalEvent(event, &assetEvent); err != nil {
+	assetEvent, err := p.unmarshalAssetEvent(event)
+	if err != nil {
 		return err
 	}
 
-	// Create asset in graph store
-	if err := p.graphStore.CreateAsset(ctx, assetEvent.Asset); err != nil {
-		return fmt.Errorf("failed to create asset: %w", err)
+	// Upsert the asset so a redelivered creation is applied as an update.
+	// Collectors also re-emit creations for known assets.
+	if _, err := p.graphStore.UpsertAsset(ctx, assetEvent.Asset); err != nil {
+		return fmt.Errorf("failed to upsert asset: %w", err)
 	}
 
-	// Evaluate policies for new asset
-	findings, err := p.policyEngine.EvaluateAsset(ctx, assetEvent.Asset)
+	// Evaluate policies for the asset and score it on its findings
+	if err := p.refreshAssetFindings(ctx, assetEvent.Asset); err != nil {
+		return err
+	}
+
+	log.Printf("Processed asset creation: %s", assetEvent.Asset.GetID())
+	return nil
+}
+
+func (p *EventProcessor) handleAssetUpdated(ctx context.Context, event models.BaseEvent) error {
+	assetEvent, err := p.unmarshalAssetEvent(event)
 	if err != nil {
-		log.Printf("Failed to evaluate policies for asset %s: %v", assetEvent.Asset.GetID(), err)
+		return err
 	}
 
-	// Create findings
-	for _, finding := range findings {
-		if err := p.graphStore.CreateFinding(ctx, finding); err != nil {
-			log.Printf("Failed to create finding %s: %v", finding.ID, err)
-		}
+	// Upsert the asset so an update delivered before its creation is kept
+	if _, err := p.graphStore.UpsertAsset(ctx, assetEvent.Asset); err != nil {
+		return fmt.Errorf("failed to upsert asset: %w", err)
 	}
 
-	// Calculate initial risk score
-	if len(findings) > 0 {
-		risk := p.riskEngine.CalculateRisk(assetEvent.Asset, findings, []models.ThreatEvent{})
-		if err := p.graphStore.UpdateAssetRisk(ctx, risk); err != nil {
-			log.Printf("Failed to update risk for asset %s: %v", assetEvent.Asset.GetID(), err)
-		}
+	// Re-evaluate policies and rescore the asset
+	if err := p.refreshAssetFindings(ctx, assetEvent.Asset); err != nil {
+		return err
 	}
 
-	log.Printf("Processed asset creation: %s", assetEvent.Asset.GetID())
+	log.Printf("Processed asset update: %s", assetEvent.Asset.GetID())
 	return nil
 }
 
-func (p *EventProcessor) handleAssetUpdated(ctx context.Context, event models.BaseEvent) error {
-	var assetEvent models.AssetEvent
-	if err := p.unmarshalEvent(event, &assetEvent); err != nil {
-		return err
+// refreshAssetFindings evaluates the policies for asset, reconciles its
+// stored findings with the result and updates its risk score. Without an
+// evaluation the stored findings are left as they are and the asset is
+// scored on them.
+func (p *EventProcessor) refreshAssetFindings(ctx context.Context, asset models.Asset) error {
+	var findings []models.Finding
+	evaluated, err := p.policyEngine.EvaluateAsset(ctx, asset)
+	if err != nil {
+		log.Printf("Failed to evaluate policies for asset %s: %v", asset.GetID(), err)
+		findings, err = p.graphStore.GetUnresolvedPolicyFindings(ctx, asset.GetID())
+		if err != nil {
+			return fmt.Errorf("failed to get findings of asset %s: %w", asset.GetID(), err)
+		}
+	} else {
+		findings, err = p.reconcileFindings(ctx, asset, evaluated)
+		if err != nil {
+			return err
+		}
+	}
+
+	risk := p.riskEngine.CalculateRisk(asset, findings, []models.ThreatEvent{})
+	if err := p.graphStore.UpdateAssetRisk(ctx, risk); err != nil {
+		log.Printf("Failed to update risk for asset %s: %v", asset.GetID(), err)
 	}
+	return nil
+}
 
-	// Update asset in graph store
-	if err := p.graphStore.UpdateAsset(ctx, assetEvent.Asset); err != nil {
-		return fmt.Errorf("failed to update asset: %w", err)
+// findingKey identifies the finding a policy raised on an asset across
+// evaluations: its policy and, for policies raising several findings per
+// asset, the resource it is about
+func findingKey(finding models.Finding) string {
+	resource, _ := finding.Metadata[models.FindingResourceKey].(string)
+	return finding.PolicyID + "\x00" + resource
+}
+
+// takeFinding removes and returns the stored finding with key an evaluated
+// finding updates: the one with its ID, else the oldest
+func takeFinding(byKey map[string][]models.Finding, key, id string) (models.Finding, bool) {
+	findings := byKey[key]
+	if len(findings) == 0 {
+		return models.Finding{}, false
+	}
+	match := 0
+	for i, finding := range findings {
+		if finding.ID == id {
+			match = i
+			break
+		}
 	}
+	taken := findings[match]
+	byKey[key] = append(findings[:match:match], findings[match+1:]...)
+	return taken, true
+}
 
-	// Re-evaluate policies
-	findings, err := p.policyEngine.EvaluateAsset(ctx, assetEvent.Asset)
+// reconcileFindings brings the stored policy findings of asset in line with
+// a new policy evaluation. Findings are matched by policy and resource (see
+// findingKey), and by ID among those: findings still raised are updated in
+// place, keeping their ID, first sighting and triage, new ones are created
+// and stored findings no longer raised are resolved. It returns the asset's current findings.
+// Every write is attempted; the first that failed is returned so the event
+// is retried, which finds the findings already written in place.
+func (p *EventProcessor) reconcileFindings(ctx context.Context, asset models.Asset, evaluated []models.Finding) ([]models.Finding, error) {
+	existing, err := p.graphStore.GetUnresolvedPolicyFindings(ctx, asset.GetID())
 	if err != nil {
-		log.Printf("Failed to evaluate policies for asset %s: %v", assetEvent.Asset.GetID(), err)
-	}
+		return nil, fmt.Errorf("failed to get findings of asset %s: %w", asset.GetID(), err)
+	}
+
+	// A policy may raise several findings with the same key; they are
+	// matched oldest first, and those left over are resolved
+	sort.SliceStable(existing, func(i, j int) bool {
+		return existing[i].FirstSeen.Before(existing[j].FirstSeen)
+	})
+	byKey := make(map[string][]models.Finding, len(existing))
+	for _, finding := range existing {
+		key := findingKey(finding)
+		byKey[key] = append(byKey[key], finding)
+	}
+
+	var writeErr error
+	current := make([]models.Finding, 0, len(evaluated))
+	for i := range evaluated {
+		finding := &evaluated[i]
+		p.enrichFinding(ctx, asset, finding)
+
+		previous, exists := takeFinding(byKey, findingKey(*finding), finding.ID)
+		if !exists {
+			if err := p.graphStore.CreateFinding(ctx, *finding); err != nil && writeErr == nil {
+				writeErr = fmt.Errorf("failed to create finding %s: %w", finding.ID, err)
+			}
+			current = append(current, *finding)
+			continue
+		}
 
-	// Update findings (this would be more sophisticated in practice)
-	// For now, we'll just create new findings
-	for _, finding := range findings {
-		if err := p.graphStore.CreateFinding(ctx, finding); err != nil {
-			log.Printf("Failed to create finding %s: %v", finding.ID, err)
+		finding.ID = previous.ID
+		finding.FirstSeen = previous.FirstSeen
+		finding.Status = previous.Status
+		finding.Suppressed = previous.Suppressed
+		finding.SuppressedReason = previous.SuppressedReason
+		finding.FalsePositive = previous.FalsePositive
+		finding.Feedback = previous.Feedback
+		if err := p.graphStore.UpdateFinding(ctx, *finding); err != nil && writeErr == nil {
+			writeErr = fmt.Errorf("failed to update finding %s: %w", finding.ID, err)
 		}
+		current = append(current, *finding)
 	}
 
-	// Recalculate risk score
-	risk := p.riskEngine.CalculateRisk(assetEvent.Asset, findings, []models.ThreatEvent{})
-	if err := p.graphStore.UpdateAssetRisk(ctx, risk); err != nil {
-		log.Printf("Failed to update risk for asset %s: %v", assetEvent.Asset.GetID(), err)
+	var stale []models.Finding
+	for _, findings := range byKey {
+		stale = append(stale, findings...)
+	}
+	now := time.Now()
+	for _, finding := range stale {
+		finding.Status = "resolved"
+		finding.ResolvedAt = &now
+		if err := p.graphStore.UpdateFinding(ctx, finding); err != nil && writeErr == nil {
+			writeErr = fmt.Errorf("failed to resolve finding %s: %w", finding.ID, err)
+		}
 	}
 
-	log.Printf("Processed asset update: %s", assetEvent.Asset.GetID())
-	return nil
+	return current, writeErr
 }
 
 func (p *EventProcessor) handleAssetDeleted(ctx context.Context, event models.BaseEvent) error {
-	var assetEvent models.AssetEvent
-	if err := p.unmarshalEvent(event, &assetEvent); err != nil {
+	assetEvent, err := p.unmarshalAssetEvent(event)
+	if err != nil {
 		return err
 	}
 
@@ -387,6 +645,57 @@ func (p *EventProcessor) handleRelationshipDeleted(ctx context.Context, event mo
 	return nil
 }
 
+// handleExposureChange recomputes internet reachability downstream of a
+// changed asset or relationship and recalculates risk where it changed
+func (p *EventProcessor) handleExposureChange(ctx context.Context, event models.BaseEvent) error {
+	if p.config.ExposureMaxHops <= 0 {
+		return nil
+	}
+
+	var assetID string
+	switch event.Type {
+	case models.EventTypeAssetUpdated:
+		assetEvent, err := p.unmarshalAssetEvent(event)
+		if err != nil {
+			return err
+		}
+		assetID = assetEvent.Asset.GetID()
+	default:
+		var relEvent models.RelationshipEvent
+		if err := p.unmarshalEvent(event, &relEvent); err != nil {
+			return err
+		}
+		assetID = relEvent.Relationship.ToAssetID
+	}
+
+	if assetID == "" {
+		log.Printf("Skipping exposure recomputation for event %s: no target asset", event.ID)
+		return nil
+	}
+
+	changed, err := p.graphStore.RecomputeInternetExposure(ctx, assetID, p.config.ExposureMaxHops)
+	if err != nil {
+		return fmt.Errorf("failed to recompute internet exposure: %w", err)
+	}
+
+	for _, id := range changed {
+		risk, err := p.riskEngine.RecalculateRisk(id)
+		if err != nil {
+			log.Printf("Failed to recalculate risk for asset %s: %v", id, err)
+			continue
+		}
+
+		if err := p.graphStore.UpdateAssetRisk(ctx, risk); err != nil {
+			log.Printf("Failed to update risk for asset %s: %v", id, err)
+		}
+	}
+
+	if len(changed) > 0 {
+		log.Printf("Internet exposure changed for %d assets downstream of %s", len(changed), assetID)
+	}
+	return nil
+}
+
 // Finding event handlers
 
 func (p *EventProcessor) handleFindingCreated(ctx context.Context, event models.BaseEvent) error {
@@ -396,6 +705,7 @@ func (p *EventProcessor) handleFindingCreated(ctx context.Context, event models.
 	}
 
 	// Create finding in graph store
+	p.enrichFinding(ctx, findingEvent.Asset, &findingEvent.Finding)
 	if err := p.graphStore.CreateFinding(ctx, findingEvent.Finding); err != nil {
 		return fmt.Errorf("failed to create finding: %w", err)
 	}
@@ -411,6 +721,7 @@ func (p *EventProcessor) handleFindingUpdated(ctx context.Context, event models.
 	}
 
 	// Update finding in graph store
+	p.enrichFinding(ctx, findingEvent.Asset, &findingEvent.Finding)
 	if err := p.graphStore.UpdateFinding(ctx, findingEvent.Finding); err != nil {
 		return fmt.Errorf("failed to update finding: %w", err)
 	}
@@ -425,10 +736,20 @@ func (p *EventProcessor) handleFindingResolved(ctx context.Context, event models
 		return err
 	}
 
-	// Update finding status to resolved
-	findingEvent.Finding.Status = "resolved"
-	if err := p.graphStore.UpdateFinding(ctx, findingEvent.Finding); err != nil {
-		return fmt.Errorf("failed to resolve finding: %w", err)
+	// Update finding status to resolved. Events that only reference the
+	// finding, such as those from completed remediations, resolve the stored
+	// finding instead of overwriting it.
+	if findingEvent.Finding.AssetID == "" {
+		finding, err := p.graphStore.ResolveFinding(ctx, findingEvent.Finding.ID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve finding: %w", err)
+		}
+		findingEvent.Finding = finding
+	} else {
+		findingEvent.Finding.Status = "resolved"
+		if err := p.graphStore.UpdateFinding(ctx, findingEvent.Finding); err != nil {
+			return fmt.Errorf("failed to resolve finding: %w", err)
+		}
 	}
 
 	// Recalculate risk for the asset
@@ -525,17 +846,30 @@ func (p *EventProcessor) handleRiskScoreChanged(ctx context.Context, event model
 // Helper methods
 
 func (p *EventProcessor) unmarshalEvent(event models.BaseEvent, target interface{}) error {
+	// Malformed events never succeed, so they are not retried
 	if event.RawData == nil {
-		return fmt.Errorf("event has no raw data")
+		return Permanent(fmt.Errorf("event has no raw data"))
 	}
 	
 	if err := json.Unmarshal(event.RawData, target); err != nil {
-		return fmt.Errorf("failed to unmarshal event data: %w", err)
+		return Permanent(fmt.Errorf("failed to unmarshal event data: %w", err))
 	}
 	
 	return nil
 }
 
+// unmarshalAssetEvent decodes an asset event, which must carry its asset
+func (p *EventProcessor) unmarshalAssetEvent(event models.BaseEvent) (models.AssetEvent, error) {
+	var assetEvent models.AssetEvent
+	if err := p.unmarshalEvent(event, &assetEvent); err != nil {
+		return assetEvent, err
+	}
+	if assetEvent.Asset == nil {
+		return assetEvent, Permanent(fmt.Errorf("asset event has no asset"))
+	}
+	return assetEvent, nil
+}
+
 func (p *EventProcessor) updateMetrics(eventType models.EventType, latency time.Duration, err error) {
 	if !p.config.EnableMetrics {
 		return
@@ -571,15 +905,19 @@ func (p *EventProcessor) collectMetrics(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			if p.workers != nil {
+				utilization := p.workers.utilization(time.Now())
+				p.metrics.mu.Lock()
+				p.metrics.WorkerUtilization = utilization
+				p.metrics.mu.Unlock()
+			}
 			p.logMetrics()
 		}
 	}
 }
 
 func (p *EventProcessor) logMetrics() {
-	p.metrics.mu.RLock()
-	metrics := *p.metrics
-	p.metrics.mu.RUnlock()
+	metrics := p.metrics.snapshot()
 
 	log.Printf("Event Processor Metrics: Processed=%d, Failed=%d, AvgLatency=%v, LastProcessed=%v",
 		metrics.EventsProcessed,
@@ -589,9 +927,55 @@ func (p *EventProcessor) logMetrics() {
 	)
 }
 
-// GetMetrics returns current processor metrics
-func (p *EventProcessor) GetMetrics() ProcessorMetrics {
-	p.metrics.mu.RLock()
-	defer p.metrics.mu.RUnlock()
-	return *p.metrics
+// ProcessorMetricsSnapshot is a copy of the processor metrics taken under
+// their lock. It holds no lock itself, so it can be copied and encoded freely.
+type ProcessorMetricsSnapshot struct {
+	EventsProcessed    int64                      `json:"events_processed"`
+	EventsFailed       int64                      `json:"events_failed"`
+	EventsRetried      int64                      `json:"events_retried"`
+	AverageLatency     time.Duration              `json:"average_latency"`
+	LastProcessed      time.Time                  `json:"last_processed"`
+	EventsDeduplicated int64                      `json:"events_deduplicated"`
+	EventsDeadLettered int64                      `json:"events_dead_lettered"`
+	EventsByType       map[models.EventType]int64 `json:"events_by_type"`
+	ErrorsByType       map[string]int64           `json:"errors_by_type"`
+	WorkerUtilization  map[int]float64            `json:"worker_utilization"`
+}
+
+// GetMetrics returns a snapshot of the processor metrics that is safe to
+// use while events are still being processed
+func (p *EventProcessor) GetMetrics() ProcessorMetricsSnapshot {
+	return p.metrics.snapshot()
+}
+
+// snapshot copies the metrics, maps included, without copying the lock
+func (m *ProcessorMetrics) snapshot() ProcessorMetricsSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	eventsByType := make(map[models.EventType]int64, len(m.EventsByType))
+	for eventType, count := range m.EventsByType {
+		eventsByType[eventType] = count
+	}
+	errorsByType := make(map[string]int64, len(m.ErrorsByType))
+	for errorType, count := range m.ErrorsByType {
+		errorsByType[errorType] = count
+	}
+	workerUtilization := make(map[int]float64, len(m.WorkerUtilization))
+	for worker, utilization := range m.WorkerUtilization {
+		workerUtilization[worker] = utilization
+	}
+
+	return ProcessorMetricsSnapshot{
+		EventsProcessed:    m.EventsProcessed,
+		EventsFailed:       m.EventsFailed,
+		EventsRetried:      m.EventsRetried,
+		AverageLatency:     m.AverageLatency,
+		LastProcessed:      m.LastProcessed,
+		EventsDeduplicated: m.EventsDeduplicated,
+		EventsDeadLettered: m.EventsDeadLettered,
+		EventsByType:       eventsByType,
+		ErrorsByType:       errorsByType,
+		WorkerUtilization:  workerUtilization,
+	}
 }
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+func TestProcessorMetricsJSONRoundTrip(t *testing.T) {
+	metrics := &ProcessorMetrics{
+		EventsProcessed:    42,
+		EventsFailed:       3,
+		EventsRetried:      7,
+		AverageLatency:     150 * time.Millisecond,
+		LastProcessed:      time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+		EventsDeduplicated: 5,
+		EventsDeadLettered: 2,
+		EventsByType:       map[models.EventType]int64{models.EventTypeAssetCreated: 30, models.EventTypeFindingResolved: 12},
+		ErrorsByType:       map[string]int64{"timeout": 3},
+		WorkerUtilization:  map[int]float64{0: 0.5, 1: 0.25},
+	}
+	want := metrics.snapshot()
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unmarshal() into map error = %v", err)
+	}
+	if fields["events_retried"] != float64(7) {
+		t.Errorf("events_retried = %v, want 7 in %s", fields["events_retried"], data)
+	}
+
+	var got ProcessorMetricsSnapshot
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestProcessorMetricsSnapshotIsIndependent(t *testing.T) {
+	metrics := &ProcessorMetrics{EventsByType: map[models.EventType]int64{models.EventTypeAssetCreated: 1}}
+	snapshot := metrics.snapshot()
+
+	metrics.mu.Lock()
+	metrics.EventsByType[models.EventTypeAssetCreated]++
+	metrics.mu.Unlock()
+
+	if got := snapshot.EventsByType[models.EventTypeAssetCreated]; got != 1 {
+		t.Errorf("snapshot EventsByType = %d after a later update, want 1", got)
+	}
+}
@@ -0,0 +1,166 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// findingStore keeps findings in memory. Methods other than the finding
+// reads and writes used by reconciliation are not implemented.
+type findingStore struct {
+	GraphStore
+	findings map[string]models.Finding
+	created  int
+}
+
+func (s *findingStore) GetUnresolvedPolicyFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	var findings []models.Finding
+	for _, finding := range s.findings {
+		if finding.AssetID == assetID && finding.Status != "resolved" {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+func (s *findingStore) CreateFinding(ctx context.Context, finding models.Finding) error {
+	s.created++
+	s.findings[finding.ID] = finding
+	return nil
+}
+
+func (s *findingStore) UpdateFinding(ctx context.Context, finding models.Finding) error {
+	s.findings[finding.ID] = finding
+	return nil
+}
+
+func policyFinding(id, resource string) models.Finding {
+	finding := models.Finding{PolicyID: "sg-open-ingress", AssetID: "sg-1", Status: "open"}
+	finding.ID = id
+	finding.FirstSeen = time.Now()
+	finding.Metadata = map[string]interface{}{models.FindingResourceKey: resource}
+	return finding
+}
+
+func TestReconcileFindingsKeepsSeveralFindingsPerPolicy(t *testing.T) {
+	store := &findingStore{findings: make(map[string]models.Finding)}
+	p := &EventProcessor{graphStore: store}
+	asset := &models.Compute{}
+	asset.ID = "sg-1"
+
+	evaluate := func(ids []string, resources ...string) []models.Finding {
+		var findings []models.Finding
+		for i, resource := range resources {
+			findings = append(findings, policyFinding(ids[i], resource))
+		}
+		current, err := p.reconcileFindings(context.Background(), asset, findings)
+		if err != nil {
+			t.Fatalf("reconcileFindings() error = %v", err)
+		}
+		return current
+	}
+
+	first := evaluate([]string{"a1", "b1"}, "rule-22", "rule-3389")
+	if store.created != 2 {
+		t.Fatalf("created %d findings, want 2", store.created)
+	}
+
+	// Each delivery evaluates with fresh IDs; the stored findings are kept
+	second := evaluate([]string{"a2", "b2"}, "rule-3389", "rule-22")
+	if store.created != 2 {
+		t.Errorf("created %d findings after re-evaluation, want still 2", store.created)
+	}
+	ids := map[string]bool{first[0].ID: true, first[1].ID: true}
+	for _, finding := range second {
+		if !ids[finding.ID] {
+			t.Errorf("re-evaluated finding got ID %s, want one of the stored IDs", finding.ID)
+		}
+	}
+
+	// A resource no longer failing resolves its finding only
+	evaluate([]string{"a3"}, "rule-22")
+	unresolved, _ := store.GetUnresolvedPolicyFindings(context.Background(), "sg-1")
+	if len(unresolved) != 1 || unresolved[0].ID != "a1" {
+		t.Errorf("unresolved findings = %+v, want only a1", unresolved)
+	}
+}
+
+// assetFindingStore adds the asset writes of an asset event to findingStore
+type assetFindingStore struct {
+	*findingStore
+	upserts int
+}
+
+func (s *assetFindingStore) UpsertAsset(ctx context.Context, asset models.Asset) (bool, error) {
+	s.upserts++
+	return s.upserts == 1, nil
+}
+
+func (s *assetFindingStore) UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error {
+	return nil
+}
+
+// freshIDPolicies raises one finding per evaluation, with a new ID each time
+// like a real evaluation
+type freshIDPolicies struct {
+	PolicyEngine
+	evaluations int
+}
+
+func (e *freshIDPolicies) EvaluateAsset(ctx context.Context, asset models.Asset) ([]models.Finding, error) {
+	e.evaluations++
+	finding := policyFinding(fmt.Sprintf("eval-%d", e.evaluations), "rule-22")
+	finding.AssetID = asset.GetID()
+	return []models.Finding{finding}, nil
+}
+
+// flatRisk scores every asset the same
+type flatRisk struct {
+	RiskEngine
+}
+
+func (flatRisk) CalculateRisk(asset models.Asset, findings []models.Finding, threats []models.ThreatEvent) models.RiskScore {
+	return models.RiskScore{AssetID: asset.GetID(), Score: 5}
+}
+
+func TestReplayedAssetCreationCreatesNoDuplicateFindings(t *testing.T) {
+	store := &assetFindingStore{findingStore: &findingStore{findings: make(map[string]models.Finding)}}
+	policies := &freshIDPolicies{}
+	p := &EventProcessor{graphStore: store, policyEngine: policies, riskEngine: flatRisk{}}
+
+	asset := &models.Compute{}
+	asset.ID = "sg-1"
+	asset.Type = models.AssetTypeCompute
+	created := models.AssetEvent{
+		BaseEvent: models.BaseEvent{ID: "event-1", Type: models.EventTypeAssetCreated},
+		Asset:     asset,
+	}
+	raw, err := json.Marshal(created)
+	if err != nil {
+		t.Fatalf("failed to encode event: %v", err)
+	}
+	event := created.BaseEvent
+	event.RawData = raw
+
+	for delivery := 1; delivery <= 2; delivery++ {
+		if err := p.handleAssetCreated(context.Background(), event); err != nil {
+			t.Fatalf("delivery %d: handleAssetCreated() error = %v", delivery, err)
+		}
+	}
+
+	if store.upserts != 2 || policies.evaluations != 2 {
+		t.Errorf("upserted %d times and evaluated %d times, want the replay to do both again", store.upserts, policies.evaluations)
+	}
+	if store.created != 1 {
+		t.Errorf("created %d findings, want 1", store.created)
+	}
+	unresolved, _ := store.GetUnresolvedPolicyFindings(context.Background(), "sg-1")
+	if len(unresolved) != 1 || unresolved[0].ID != "eval-1" {
+		t.Errorf("unresolved findings = %+v, want only eval-1", unresolved)
+	}
+}
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/securizon/internal/graph"
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/models"
+)
+
+// RegionalEventBus keeps each tenant's events on the Kafka cluster of the
+// region its data is pinned to. Events are published to the bus of the region
+// resolved from the context by tenant.RegionFromContext, or of the default
+// region. Subscriptions consume every region, handling each region's events
+// with a context scoped to that region so that the work they trigger stays
+// there too.
+type RegionalEventBus struct {
+	regions       map[string]EventBus
+	defaultRegion string
+}
+
+// NewRegionalEventBus creates a bus routing to the given regional buses
+func NewRegionalEventBus(regions map[string]EventBus, defaultRegion string) (*RegionalEventBus, error) {
+	if _, ok := regions[defaultRegion]; !ok {
+		return nil, fmt.Errorf("default region %s has no event bus", defaultRegion)
+	}
+	return &RegionalEventBus{
+		regions:       regions,
+		defaultRegion: defaultRegion,
+	}, nil
+}
+
+// bus returns the bus of the region ctx resolves to. A region without a bus
+// is rejected rather than served by another region's cluster.
+func (r *RegionalEventBus) bus(ctx context.Context) (EventBus, error) {
+	region, err := tenant.RegionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		region = r.defaultRegion
+	}
+
+	bus, ok := r.regions[region]
+	if !ok {
+		return nil, tenant.RegionNotServed(region, "event bus")
+	}
+	return bus, nil
+}
+
+// PublishEvent publishes an event to the bus of the resolved region
+func (r *RegionalEventBus) PublishEvent(ctx context.Context, topic string, event models.BaseEvent) error {
+	bus, err := r.bus(ctx)
+	if err != nil {
+		return err
+	}
+	return bus.PublishEvent(ctx, topic, event)
+}
+
+// PublishBatch publishes a batch to the bus of the resolved region
+func (r *RegionalEventBus) PublishBatch(ctx context.Context, topic string, batch models.EventBatch) error {
+	bus, err := r.bus(ctx)
+	if err != nil {
+		return err
+	}
+	return bus.PublishBatch(ctx, topic, batch)
+}
+
+// PublishChanges publishes graph changes to the change stream of the resolved
+// region, which is the region the changes were written to
+func (r *RegionalEventBus) PublishChanges(ctx context.Context, changes []graph.ChangeEvent) error {
+	bus, err := r.bus(ctx)
+	if err != nil {
+		return err
+	}
+	publisher, ok := bus.(graph.ChangePublisher)
+	if !ok {
+		return fmt.Errorf("event bus does not publish graph changes")
+	}
+	return publisher.PublishChanges(ctx, changes)
+}
+
+// Subscribe follows topic from its latest offset in every region
+func (r *RegionalEventBus) Subscribe(ctx context.Context, topic string, handler EventHandler) error {
+	for region, bus := range r.regions {
+		if err := bus.Subscribe(tenant.WithRegion(ctx, region), topic, handler); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// SubscribeGroup subscribes handler to topic with a consumer group in every
+// region
+func (r *RegionalEventBus) SubscribeGroup(ctx context.Context, topic, group string, handler EventHandler) error {
+	for region, bus := range r.regions {
+		if err := bus.SubscribeGroup(tenant.WithRegion(ctx, region), topic, group, handler); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// CreateTopic creates a topic in every region
+func (r *RegionalEventBus) CreateTopic(ctx context.Context, topic string, partitions int, replicationFactor int) error {
+	for region, bus := range r.regions {
+		if err := bus.CreateTopic(ctx, topic, partitions, replicationFactor); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// DeleteTopic deletes a topic in every region
+func (r *RegionalEventBus) DeleteTopic(ctx context.Context, topic string) error {
+	for region, bus := range r.regions {
+		if err := bus.DeleteTopic(ctx, topic); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// ListTopics lists the topics of the resolved region
+func (r *RegionalEventBus) ListTopics(ctx context.Context) ([]string, error) {
+	bus, err := r.bus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bus.ListTopics(ctx)
+}
+
+// Ping checks every regional bus
+func (r *RegionalEventBus) Ping(ctx context.Context) error {
+	for region, bus := range r.regions {
+		if err := bus.Ping(ctx); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// Close closes every regional bus
+func (r *RegionalEventBus) Close() error {
+	var firstErr error
+	for region, bus := range r.regions {
+		if err := bus.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return firstErr
+}
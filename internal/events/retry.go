@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// ErrPermanent marks a handler failure that retrying cannot fix, such as a
+// malformed event. Handlers wrap it, directly or with Permanent, to send the
+// event straight to the dead letter topic.
+var ErrPermanent = errors.New("permanent failure")
+
+// Permanent marks err as a failure that must not be retried
+func Permanent(err error) error {
+	if err == nil || errors.Is(err, ErrPermanent) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+// runHandler runs handler on event, retrying transient failures up to
+// RetryAttempts times. The delay between attempts starts at RetryDelay and
+// doubles after each retry, up to MaxRetryDelay. It returns the number of
+// attempts made.
+func (p *EventProcessor) runHandler(ctx context.Context, handler EventHandler, event models.BaseEvent) (int, error) {
+	delay := p.config.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := handler.Handle(ctx, event)
+		if err == nil || errors.Is(err, ErrPermanent) || attempt > p.config.RetryAttempts {
+			return attempt, err
+		}
+
+		log.Printf("Handler %s failed for event %s (attempt %d), retrying in %v: %v",
+			handler.GetName(), event.ID, attempt, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, fmt.Errorf("%w (retry canceled: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+		delay = nextRetryDelay(delay, p.config.MaxRetryDelay)
+
+		p.metrics.mu.Lock()
+		p.metrics.EventsRetried++
+		p.metrics.mu.Unlock()
+	}
+}
+
+// nextRetryDelay doubles delay, capped at max when max is positive
+func nextRetryDelay(delay, max time.Duration) time.Duration {
+	delay *= 2
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+func TestPermanentFailureIsDeadLetteredWithoutRetry(t *testing.T) {
+	config := DefaultProcessorConfig()
+	config.RetryDelay = time.Millisecond
+	bus := &recordingBus{}
+	p := NewEventProcessor(bus, nil, nil, nil, config)
+
+	const eventType models.EventType = "test.malformed"
+	calls := 0
+	p.RegisterHandler(eventType, NamedHandler("malformed", func(ctx context.Context, event models.BaseEvent) error {
+		calls++
+		return Permanent(errors.New("malformed event"))
+	}))
+
+	if err := p.handleEvent(context.Background(), TopicAssetUpserts, models.BaseEvent{ID: "event-2", Type: eventType}); err != nil {
+		t.Fatalf("handleEvent() = %v, want nil once the event is dead-lettered", err)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+	if got := len(bus.published[config.DeadLetterTopic]); got != 1 {
+		t.Errorf("%d events published to the dead letter topic, want 1", got)
+	}
+}
+
+func TestRetryDelayIsCapped(t *testing.T) {
+	delay := time.Second
+	for i := 0; i < 10; i++ {
+		delay = nextRetryDelay(delay, 30*time.Second)
+	}
+	if delay != 30*time.Second {
+		t.Errorf("delay after 10 retries = %v, want the 30s cap", delay)
+	}
+	if got := nextRetryDelay(time.Second, 0); got != 2*time.Second {
+		t.Errorf("uncapped nextRetryDelay(1s) = %v, want 2s", got)
+	}
+}
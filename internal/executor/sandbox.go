@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"context"
+	"strings"
+)
+
+// StepRecorder keeps the steps a SandboxRunner skips. *sandbox.Recorder
+// satisfies it.
+type StepRecorder interface {
+	Record(service, operation string, params map[string]interface{})
+}
+
+// SandboxRunner stands in for a cloud or script runner in sandbox mode. It
+// records each step and reports success without touching infrastructure;
+// verification steps report the remediated state so playbooks complete.
+type SandboxRunner struct {
+	name     string
+	recorder StepRecorder
+}
+
+// NewSandboxRunner creates a runner recording the steps of runner name to
+// recorder
+func NewSandboxRunner(name string, recorder StepRecorder) *SandboxRunner {
+	return &SandboxRunner{name: name, recorder: recorder}
+}
+
+func (r *SandboxRunner) ExecuteStep(ctx context.Context, step Step, params map[string]interface{}) (map[string]interface{}, error) {
+	recorded := make(map[string]interface{}, len(params)+1)
+	for key, value := range params {
+		recorded[key] = value
+	}
+	recorded["runner"] = r.name
+	r.recorder.Record("remediation", step.Action, recorded)
+
+	output := map[string]interface{}{
+		"sandbox": true,
+		"action":  step.Action,
+	}
+	if strings.HasPrefix(step.Action, "verify:") {
+		output["verified"] = true
+	}
+	return output, nil
+}
@@ -11,14 +11,41 @@ type GraphAlgorithms struct {
 	mu sync.RWMutex
 }
 
+// Default bounds of a depth-first path search
+const (
+	DefaultDFSMaxPaths  = 100
+	DefaultDFSMaxVisits = 10000
+)
+
+// DFSLimits bound a depth-first path search, which otherwise grows
+// combinatorially with the density of the graph. Zero values use the
+// defaults.
+type DFSLimits struct {
+	MaxDepth  int `json:"max_depth"`  // nodes in a path, including the source
+	MaxPaths  int `json:"max_paths"`  // paths found before the search stops
+	MaxVisits int `json:"max_visits"` // nodes expanded before the search stops
+}
+
+// DefaultDFSLimits returns the default depth-first search bounds
+func DefaultDFSLimits() DFSLimits {
+	return DFSLimits{
+		MaxDepth:  5,
+		MaxPaths:  DefaultDFSMaxPaths,
+		MaxVisits: DefaultDFSMaxVisits,
+	}
+}
+
 // PathFinder contains state for path finding operations
 type PathFinder struct {
-	visited map[string]bool
-	paths   [][]string
-	maxPath int
+	visited   map[string]bool
+	paths     [][]string
+	maxPath   int
+	maxVisits int
+	visits    int
+	truncated bool
 }
 
-// NewPathFinder creates a new path finder
+// NewPathFinder creates a new path finder that stops after maxPath paths
 func NewPathFinder(maxPath int) *PathFinder {
 	return &PathFinder{
 		visited: make(map[string]bool),
@@ -27,6 +54,18 @@ func NewPathFinder(maxPath int) *PathFinder {
 	}
 }
 
+// exhausted reports whether the search must stop, marking the result as
+// truncated when a limit was hit
+func (pf *PathFinder) exhausted() bool {
+	if pf.maxPath > 0 && len(pf.paths) >= pf.maxPath {
+		pf.truncated = true
+	}
+	if pf.maxVisits > 0 && pf.visits >= pf.maxVisits {
+		pf.truncated = true
+	}
+	return pf.truncated
+}
+
 // BFSPaths finds shortest paths between two nodes using Breadth-First Search
 func (ga *GraphAlgorithms) BFSPaths(ctx context.Context, gc *Client, sourceID, targetID string, maxDepth int) ([][]string, error) {
 	paths := make([][]string, 0)
@@ -76,26 +115,41 @@ func (ga *GraphAlgorithms) BFSPaths(ctx context.Context, gc *Client, sourceID, t
 	return paths, nil
 }
 
-// DFSPaths finds all paths between two nodes using Depth-First Search
-func (ga *GraphAlgorithms) DFSPaths(ctx context.Context, gc *Client, sourceID, targetID string, maxDepth int) ([][]string, error) {
-	paths := make([][]string, 0)
-	visited := make(map[string]bool)
+// DFSPaths finds paths between two nodes using Depth-First Search. The
+// search stops once limits.MaxPaths paths are found or limits.MaxVisits nodes
+// have been expanded, reporting the result as truncated. When ctx is done the
+// paths found so far are returned with its error.
+func (ga *GraphAlgorithms) DFSPaths(ctx context.Context, gc *Client, sourceID, targetID string, limits DFSLimits) ([][]string, bool, error) {
+	defaults := DefaultDFSLimits()
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = defaults.MaxDepth
+	}
+	if limits.MaxPaths <= 0 {
+		limits.MaxPaths = defaults.MaxPaths
+	}
+	if limits.MaxVisits <= 0 {
+		limits.MaxVisits = defaults.MaxVisits
+	}
+
+	pf := NewPathFinder(limits.MaxPaths)
+	pf.maxVisits = limits.MaxVisits
 	currentPath := []string{sourceID}
 	
-	ga.dfSearchHelper(ctx, gc, sourceID, targetID, &currentPath, visited, maxDepth, &paths)
+	ga.dfSearchHelper(ctx, gc, pf, sourceID, targetID, &currentPath, limits.MaxDepth)
 	
-	return paths, nil
+	if err := ctx.Err(); err != nil {
+		return pf.paths, true, err
+	}
+	return pf.paths, pf.truncated, nil
 }
 
 // dfSearchHelper is the recursive helper for DFS
-func (ga *GraphAlgorithms) dfSearchHelper(ctx context.Context, gc *Client, currentID, targetID string, 
-	currentPath *[]string, visited map[string]bool, maxDepth int, paths *[][]string) {
+func (ga *GraphAlgorithms) dfSearchHelper(ctx context.Context, gc *Client, pf *PathFinder, currentID, targetID string,
+	currentPath *[]string, maxDepth int) {
 	
-	// Check context
-	select {
-	case <-ctx.Done():
+	// Check context and budgets before doing any work
+	if ctx.Err() != nil || pf.exhausted() {
 		return
-	default:
 	}
 	
 	// Check depth limit
@@ -107,31 +161,35 @@ func (ga *GraphAlgorithms) dfSearchHelper(ctx context.Context, gc *Client, curre
 	if currentID == targetID {
 		pathCopy := make([]string, len(*currentPath))
 		copy(pathCopy, *currentPath)
-		*paths = append(*paths, pathCopy)
+		pf.paths = append(pf.paths, pathCopy)
 		return
 	}
 	
 	// Mark as visited
-	visited[currentID] = true
+	pf.visited[currentID] = true
+	pf.visits++
 	
 	// Get neighbors
 	neighbors, err := gc.GetAssetNeighbors(ctx, currentID)
 	if err != nil {
-		visited[currentID] = false
+		pf.visited[currentID] = false
 		return
 	}
 	
 	// Explore neighbors
 	for _, neighbor := range neighbors {
-		if !visited[neighbor.ID] {
+		if ctx.Err() != nil || pf.exhausted() {
+			break
+		}
+		if !pf.visited[neighbor.ID] {
 			*currentPath = append(*currentPath, neighbor.ID)
-			ga.dfSearchHelper(ctx, gc, neighbor.ID, targetID, currentPath, visited, maxDepth, paths)
+			ga.dfSearchHelper(ctx, gc, pf, neighbor.ID, targetID, currentPath, maxDepth)
 			*currentPath = (*currentPath)[:len(*currentPath)-1]
 		}
 	}
 	
 	// Unmark for backtracking
-	visited[currentID] = false
+	pf.visited[currentID] = false
 }
 
 // FindStrongestPaths finds paths with highest cumulative risk scores
@@ -0,0 +1,273 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// AcceptedPathStore persists the accepted attack path allowlist.
+// *Neo4jStore satisfies it.
+type AcceptedPathStore interface {
+	SaveAcceptedPath(ctx context.Context, entry models.AcceptedPath) error
+	DeleteAcceptedPath(ctx context.Context, id string) error
+	ListAcceptedPaths(ctx context.Context) ([]models.AcceptedPath, error)
+}
+
+// PathAllowlist holds the accepted attack paths that path engines flag as
+// accepted and leave out of critical path reports and alerts. Entries are
+// matched in memory and written through to the store, if any. Each entry
+// belongs to the tenant in the context it was created with and is only
+// visible to, and only matches paths for, that tenant.
+type PathAllowlist struct {
+	mu      sync.RWMutex
+	entries map[string]models.AcceptedPath
+	store   AcceptedPathStore
+	now     func() time.Time
+}
+
+// NewPathAllowlist creates an allowlist, loading the entries in store. The
+// store may be nil to keep entries in memory only.
+func NewPathAllowlist(ctx context.Context, store AcceptedPathStore) (*PathAllowlist, error) {
+	a := &PathAllowlist{
+		entries: make(map[string]models.AcceptedPath),
+		store:   store,
+		now:     time.Now,
+	}
+	if store == nil {
+		return a, nil
+	}
+
+	entries, err := store.ListAcceptedPaths(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load accepted paths: %w", err)
+	}
+	for _, entry := range entries {
+		a.entries[entry.ID] = entry
+	}
+	return a, nil
+}
+
+// List returns the tenant's entries oldest first, leaving out expired ones
+// unless includeExpired is set
+func (a *PathAllowlist) List(ctx context.Context, includeExpired bool) []models.AcceptedPath {
+	tenantID := contextTenant(ctx)
+	now := a.now()
+
+	a.mu.RLock()
+	entries := make([]models.AcceptedPath, 0, len(a.entries))
+	for _, entry := range a.entries {
+		if entry.TenantID == tenantID && (includeExpired || !entry.Expired(now)) {
+			entries = append(entries, entry)
+		}
+	}
+	a.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// Get returns one of the tenant's entries by ID
+func (a *PathAllowlist) Get(ctx context.Context, id string) (models.AcceptedPath, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	entry, ok := a.entries[id]
+	if !ok || entry.TenantID != contextTenant(ctx) {
+		return models.AcceptedPath{}, apperrors.NotFound("accepted path not found: %s", id)
+	}
+	return entry, nil
+}
+
+// Create adds an entry with a new ID
+func (a *PathAllowlist) Create(ctx context.Context, entry models.AcceptedPath) (models.AcceptedPath, error) {
+	entry.ID = uuid.New().String()
+	entry.TenantID = contextTenant(ctx)
+	entry.CreatedAt = a.now()
+	if err := a.validate(entry); err != nil {
+		return models.AcceptedPath{}, err
+	}
+	return entry, a.put(ctx, entry)
+}
+
+// Update replaces the match, reason and expiry of an existing entry
+func (a *PathAllowlist) Update(ctx context.Context, id string, entry models.AcceptedPath) (models.AcceptedPath, error) {
+	existing, err := a.Get(ctx, id)
+	if err != nil {
+		return models.AcceptedPath{}, err
+	}
+
+	entry.ID = existing.ID
+	entry.TenantID = existing.TenantID
+	entry.CreatedAt = existing.CreatedAt
+	if entry.CreatedBy == "" {
+		entry.CreatedBy = existing.CreatedBy
+	}
+	if err := a.validate(entry); err != nil {
+		return models.AcceptedPath{}, err
+	}
+	return entry, a.put(ctx, entry)
+}
+
+// Delete removes an entry
+func (a *PathAllowlist) Delete(ctx context.Context, id string) error {
+	if _, err := a.Get(ctx, id); err != nil {
+		return err
+	}
+	if a.store != nil {
+		if err := a.store.DeleteAcceptedPath(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	delete(a.entries, id)
+	a.mu.Unlock()
+	return nil
+}
+
+// Match returns the tenant's unexpired entry accepting a path from sourceID
+// to targetID over edgeTypes, if any
+func (a *PathAllowlist) Match(ctx context.Context, sourceID, targetID string, edgeTypes []string) (models.AcceptedPath, bool) {
+	tenantID := contextTenant(ctx)
+	now := a.now()
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, entry := range a.entries {
+		if entry.TenantID == tenantID && entry.Matches(sourceID, targetID, edgeTypes, now) {
+			return entry, true
+		}
+	}
+	return models.AcceptedPath{}, false
+}
+
+// AnnotateGraphPaths flags the tenant's accepted paths among paths
+func (a *PathAllowlist) AnnotateGraphPaths(ctx context.Context, paths []models.GraphPath) {
+	for i := range paths {
+		path := &paths[i]
+		if len(path.Nodes) == 0 || path.Nodes[0] == nil || path.Nodes[len(path.Nodes)-1] == nil {
+			continue
+		}
+
+		edgeTypes := make([]string, len(path.Edges))
+		for j, edge := range path.Edges {
+			edgeTypes[j] = string(edge.Relationship.Type)
+		}
+
+		entry, ok := a.Match(ctx, path.Nodes[0].GetID(), path.Nodes[len(path.Nodes)-1].GetID(), edgeTypes)
+		path.Accepted = ok
+		path.AcceptedPathID = entry.ID
+	}
+}
+
+// contextTenant returns the ID of the tenant in ctx, or "" for requests
+// made outside any tenant
+func contextTenant(ctx context.Context) string {
+	if tenantCtx, err := tenant.GetTenantContext(ctx); err == nil {
+		return tenantCtx.TenantID
+	}
+	return ""
+}
+
+// validate checks an entry before it is stored
+func (a *PathAllowlist) validate(entry models.AcceptedPath) error {
+	if entry.SourceID == "" || entry.TargetID == "" {
+		return apperrors.Invalid("source_id and target_id are required; use %q to match any asset", models.AcceptedPathWildcard)
+	}
+	if entry.SourceID == models.AcceptedPathWildcard && entry.TargetID == models.AcceptedPathWildcard {
+		return apperrors.Invalid("an accepted path must name its source or target asset")
+	}
+	if entry.Reason == "" {
+		return apperrors.Invalid("a reason is required to accept a path")
+	}
+	if entry.ExpiresAt != nil && !entry.ExpiresAt.After(a.now()) {
+		return apperrors.Invalid("expires_at must be in the future")
+	}
+	return nil
+}
+
+// put stores an entry and makes it visible to matching
+func (a *PathAllowlist) put(ctx context.Context, entry models.AcceptedPath) error {
+	if a.store != nil {
+		if err := a.store.SaveAcceptedPath(ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	a.entries[entry.ID] = entry
+	a.mu.Unlock()
+	return nil
+}
+
+// SaveAcceptedPath creates or replaces an accepted path node
+func (s *Neo4jStore) SaveAcceptedPath(ctx context.Context, entry models.AcceptedPath) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal accepted path: %w", err)
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err = session.Run(ctx, "MERGE (a:AcceptedPath {id: $id}) SET a.data = $data, a.updated_at = datetime()",
+		map[string]interface{}{"id": entry.ID, "data": string(data)}, s.txTimeout())
+	if err != nil {
+		return classifyError(err)
+	}
+	return nil
+}
+
+// DeleteAcceptedPath deletes an accepted path node
+func (s *Neo4jStore) DeleteAcceptedPath(ctx context.Context, id string) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.Run(ctx, "MATCH (a:AcceptedPath {id: $id}) DELETE a",
+		map[string]interface{}{"id": id}, s.txTimeout())
+	if err != nil {
+		return classifyError(err)
+	}
+	return nil
+}
+
+// ListAcceptedPaths returns every accepted path node
+func (s *Neo4jStore) ListAcceptedPaths(ctx context.Context) ([]models.AcceptedPath, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, "MATCH (a:AcceptedPath) RETURN a.data as data", nil, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	var entries []models.AcceptedPath
+	for result.Next(ctx) {
+		data, _ := result.Record().AsMap()["data"].(string)
+		var entry models.AcceptedPath
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal accepted path: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := result.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+	return entries, nil
+}
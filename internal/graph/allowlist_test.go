@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/models"
+)
+
+func TestPathAllowlistIsScopedToTenant(t *testing.T) {
+	allowlist, err := NewPathAllowlist(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("NewPathAllowlist returned error: %v", err)
+	}
+	tenantA := tenant.WithTenantContext(context.Background(), &tenant.TenantContext{TenantID: "tenant-a"})
+	tenantB := tenant.WithTenantContext(context.Background(), &tenant.TenantContext{TenantID: "tenant-b"})
+
+	entry, err := allowlist.Create(tenantA, models.AcceptedPath{
+		SourceID:  "jump-box",
+		TargetID:  "prod-db",
+		EdgeTypes: []string{"CONNECTS_TO"},
+		Reason:    "jump box reaches prod by design",
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if _, ok := allowlist.Match(tenantA, "jump-box", "prod-db", []string{"CONNECTS_TO"}); !ok {
+		t.Error("path was not accepted for the tenant that accepted it")
+	}
+	if _, ok := allowlist.Match(tenantA, "jump-box", "prod-db", []string{"HAS_ACCESS_TO"}); ok {
+		t.Error("path over other edge types was accepted")
+	}
+	if _, ok := allowlist.Match(tenantB, "jump-box", "prod-db", []string{"CONNECTS_TO"}); ok {
+		t.Error("path was accepted for another tenant")
+	}
+	if entries := allowlist.List(tenantB, true); len(entries) != 0 {
+		t.Errorf("another tenant listed %d entries, want 0", len(entries))
+	}
+	if _, err := allowlist.Get(tenantB, entry.ID); err == nil {
+		t.Error("another tenant got the entry")
+	}
+	if err := allowlist.Delete(tenantB, entry.ID); err == nil {
+		t.Error("another tenant deleted the entry")
+	}
+}
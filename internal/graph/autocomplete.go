@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// AssetSuggestion is an autocomplete match for an asset
+type AssetSuggestion struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	RiskScore float64 `json:"risk_score"`
+}
+
+// AutocompleteAssets returns up to limit assets whose name (case-insensitive)
+// or id starts with prefix. Exact matches rank first, then riskier assets.
+// Each branch is a prefix match on an indexed property so lookups stay fast.
+func (s *Neo4jStore) AutocompleteAssets(ctx context.Context, prefix string, limit int) ([]AssetSuggestion, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	branches := make([]string, 0, 2*len(assetLabels))
+	for _, label := range assetLabels {
+		branches = append(branches,
+			"MATCH (n:"+label+") WHERE n.search_name STARTS WITH $prefix RETURN n LIMIT $limit",
+			"MATCH (n:"+label+") WHERE n.id STARTS WITH $rawPrefix RETURN n LIMIT $limit",
+		)
+	}
+
+	query := `
+		CALL {
+			` + strings.Join(branches, "\n\t\t\tUNION\n\t\t\t") + `
+		}
+		WITH n, CASE WHEN n.search_name = $prefix OR n.id = $rawPrefix THEN 0 ELSE 1 END as rank
+		RETURN n.id as id, coalesce(n.name, '') as name, labels(n) as labels,
+			coalesce(n.risk_score, 0.0) as riskScore
+		ORDER BY rank, riskScore DESC, size(name)
+		LIMIT $limit
+	`
+
+	params := map[string]interface{}{
+		"prefix":    strings.ToLower(prefix),
+		"rawPrefix": prefix,
+		"limit":     limit,
+	}
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	suggestions := make([]AssetSuggestion, 0, limit)
+	for result.Next(ctx) {
+		row := result.Record().AsMap()
+		suggestion := AssetSuggestion{}
+		suggestion.ID, _ = row["id"].(string)
+		suggestion.Name, _ = row["name"].(string)
+		labels, _ := row["labels"].([]interface{})
+		suggestion.Type = string(assetTypeOfLabels(labels))
+		suggestion.RiskScore, _ = row["riskScore"].(float64)
+		suggestions = append(suggestions, suggestion)
+	}
+	if err := result.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,613 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// Change operations
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// Changed entities
+const (
+	ChangeEntityAsset        = "asset"
+	ChangeEntityRelationship = "relationship"
+	ChangeEntityFinding      = "finding"
+	ChangeEntityRisk         = "risk"
+)
+
+// ErrChangeNotPublished is returned by CDCStore when a mutation was applied
+// but its change event could not be published
+var ErrChangeNotPublished = errors.New("change event not published")
+
+// ChangeEvent is a normalized record of one graph mutation. Before and After
+// hold the full entity as returned by the store; Before is omitted for
+// creates and After for deletes. ID is unique per event so consumers can
+// discard redelivered events.
+type ChangeEvent struct {
+	ID        string          `json:"id"`
+	Op        string          `json:"op"`
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entity_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// Key returns the key change events are partitioned by. Changes to one
+// entity share a key so they are delivered in the order they were made.
+func (c ChangeEvent) Key() string {
+	return c.Entity + ":" + c.EntityID
+}
+
+// ChangePublisher delivers change events, e.g. to a Kafka topic
+type ChangePublisher interface {
+	PublishChanges(ctx context.Context, changes []ChangeEvent) error
+}
+
+// CDCConfig configures the change-data-capture stream
+type CDCConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// IncludeBefore reads each entity before it is updated or deleted so the
+	// change carries its previous state, at the cost of an extra read
+	IncludeBefore  bool          `json:"include_before" yaml:"include_before"`
+	PublishRetries int           `json:"publish_retries" yaml:"publish_retries"`
+	RetryBackoff   time.Duration `json:"retry_backoff" yaml:"retry_backoff"`
+}
+
+// DefaultCDCConfig returns default change-data-capture configuration
+func DefaultCDCConfig() CDCConfig {
+	return CDCConfig{
+		Enabled:        false,
+		IncludeBefore:  true,
+		PublishRetries: 3,
+		RetryBackoff:   200 * time.Millisecond,
+	}
+}
+
+// CDCStore wraps a GraphStore and publishes a ChangeEvent for every asset,
+// relationship, finding and risk mutation once the store has applied it.
+//
+// Delivery is at least once: if a change cannot be published after the
+// configured retries, the mutation returns an error wrapping
+// ErrChangeNotPublished so the caller retries it, and since store writes are
+// idempotent the retry publishes the change again. A retried create that
+// conflicts with the entity it already created publishes the creation
+// instead of failing. Changes to one entity are published in the order they
+// were made.
+//
+// The optional capabilities of the wrapped store, such as autocomplete and
+// schema status, are forwarded and report NOT_IMPLEMENTED when it lacks them.
+//
+// Bulk maintenance operations (ImportGraph, ExpireStaleAssets and
+// PruneRiskSnapshots) and schema migrations do not report which entities
+// they changed and are not captured; consumers should resynchronize after
+// running them.
+type CDCStore struct {
+	GraphStore
+	publisher ChangePublisher
+	config    CDCConfig
+
+	mu          sync.Mutex
+	unpublished map[string]bool // keys of creates applied but not published
+}
+
+// NewCDCStore creates a store publishing the changes made through store
+func NewCDCStore(store GraphStore, publisher ChangePublisher, config CDCConfig) *CDCStore {
+	return &CDCStore{
+		GraphStore:  store,
+		publisher:   publisher,
+		config:      config,
+		unpublished: make(map[string]bool),
+	}
+}
+
+// newChange builds a change event, encoding before and after when set
+func newChange(op, entity, entityID string, before, after interface{}) (ChangeEvent, error) {
+	change := ChangeEvent{
+		ID:        uuid.New().String(),
+		Op:        op,
+		Entity:    entity,
+		EntityID:  entityID,
+		Timestamp: time.Now().UTC(),
+	}
+
+	var err error
+	if before != nil {
+		if change.Before, err = json.Marshal(before); err != nil {
+			return change, fmt.Errorf("failed to encode %s %s: %w", entity, entityID, err)
+		}
+	}
+	if after != nil {
+		if change.After, err = json.Marshal(after); err != nil {
+			return change, fmt.Errorf("failed to encode %s %s: %w", entity, entityID, err)
+		}
+	}
+	return change, nil
+}
+
+// publish delivers changes, retrying with a growing backoff
+func (s *CDCStore) publish(ctx context.Context, changes ...ChangeEvent) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	backoff := s.config.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= s.config.PublishRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return fmt.Errorf("%w: %v", ErrChangeNotPublished, ctx.Err())
+			}
+		}
+		if err = s.publisher.PublishChanges(ctx, changes); err == nil {
+			return nil
+		}
+		log.Printf("Failed to publish %d change events (attempt %d): %v", len(changes), attempt+1, err)
+	}
+	return fmt.Errorf("%w: %v", ErrChangeNotPublished, err)
+}
+
+// record builds and publishes a single change
+func (s *CDCStore) record(ctx context.Context, op, entity, entityID string, before, after interface{}) error {
+	change, err := newChange(op, entity, entityID, before, after)
+	if err != nil {
+		return err
+	}
+	return s.publish(ctx, change)
+}
+
+// recordCreate publishes a creation, remembering it until it is published
+// so that a retried create can publish it
+func (s *CDCStore) recordCreate(ctx context.Context, entity, entityID string, after interface{}) error {
+	err := s.record(ctx, ChangeCreate, entity, entityID, nil, after)
+
+	key := ChangeEvent{Entity: entity, EntityID: entityID}.Key()
+	s.mu.Lock()
+	if errors.Is(err, ErrChangeNotPublished) {
+		s.unpublished[key] = true
+	} else {
+		delete(s.unpublished, key)
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// retryCreate handles a create that failed with createErr. A conflict with
+// an entity whose creation was never published is a retry after a publish
+// failure, and publishes the creation; other errors are returned.
+func (s *CDCStore) retryCreate(ctx context.Context, createErr error, entity, entityID string, after interface{}) error {
+	if !errors.Is(createErr, apperrors.ErrConflict) {
+		return createErr
+	}
+	s.mu.Lock()
+	retry := s.unpublished[ChangeEvent{Entity: entity, EntityID: entityID}.Key()]
+	s.mu.Unlock()
+	if !retry {
+		return createErr
+	}
+	return s.recordCreate(ctx, entity, entityID, after)
+}
+
+// assetBefore returns the stored asset when before images are enabled, or
+// nil if it does not exist or could not be read
+func (s *CDCStore) assetBefore(ctx context.Context, id string) models.Asset {
+	if !s.config.IncludeBefore {
+		return nil
+	}
+	asset, err := s.GraphStore.GetAsset(ctx, id)
+	if err != nil {
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			log.Printf("Failed to read asset %s for change capture: %v", id, err)
+		}
+		return nil
+	}
+	return asset
+}
+
+// assetsBefore returns the stored assets by ID when before images are
+// enabled, or nil if they could not be read
+func (s *CDCStore) assetsBefore(ctx context.Context, assets []models.Asset) map[string]models.Asset {
+	if !s.config.IncludeBefore {
+		return nil
+	}
+	ids := make([]string, len(assets))
+	for i, asset := range assets {
+		ids[i] = asset.GetID()
+	}
+	befores, err := s.GraphStore.GetAssets(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to read %d assets for change capture: %v", len(ids), err)
+		return nil
+	}
+	return befores
+}
+
+// relationshipBefore returns the stored relationship when before images are
+// enabled, or nil if it could not be read
+func (s *CDCStore) relationshipBefore(ctx context.Context, id string) interface{} {
+	if !s.config.IncludeBefore {
+		return nil
+	}
+	rel, err := s.GraphStore.GetRelationship(ctx, id)
+	if err != nil {
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			log.Printf("Failed to read relationship %s for change capture: %v", id, err)
+		}
+		return nil
+	}
+	return rel
+}
+
+// Asset operations
+
+// CreateAsset creates an asset and publishes its creation
+func (s *CDCStore) CreateAsset(ctx context.Context, asset models.Asset) error {
+	if err := s.GraphStore.CreateAsset(ctx, asset); err != nil {
+		return s.retryCreate(ctx, err, ChangeEntityAsset, asset.GetID(), asset)
+	}
+	return s.recordCreate(ctx, ChangeEntityAsset, asset.GetID(), asset)
+}
+
+// UpsertAsset creates or updates an asset and publishes the change
+func (s *CDCStore) UpsertAsset(ctx context.Context, asset models.Asset) (bool, error) {
+	before := s.assetBefore(ctx, asset.GetID())
+	created, err := s.GraphStore.UpsertAsset(ctx, asset)
+	if err != nil {
+		return created, err
+	}
+
+	op := ChangeUpdate
+	if created {
+		op, before = ChangeCreate, nil
+	}
+	return created, s.record(ctx, op, ChangeEntityAsset, asset.GetID(), before, asset)
+}
+
+// UpdateAsset updates an asset and publishes the change
+func (s *CDCStore) UpdateAsset(ctx context.Context, asset models.Asset) error {
+	before := s.assetBefore(ctx, asset.GetID())
+	if err := s.GraphStore.UpdateAsset(ctx, asset); err != nil {
+		return err
+	}
+	return s.record(ctx, ChangeUpdate, ChangeEntityAsset, asset.GetID(), before, asset)
+}
+
+// PatchAsset patches an asset and publishes the change
+func (s *CDCStore) PatchAsset(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Asset, int64, error) {
+	before := s.assetBefore(ctx, id)
+	asset, version, err := s.GraphStore.PatchAsset(ctx, id, patch, expectedVersion)
+	if err != nil {
+		return asset, version, err
+	}
+	return asset, version, s.record(ctx, ChangeUpdate, ChangeEntityAsset, id, before, asset)
+}
+
+// DeleteAsset deletes an asset and publishes its deletion
+func (s *CDCStore) DeleteAsset(ctx context.Context, id string) error {
+	before := s.assetBefore(ctx, id)
+	if err := s.GraphStore.DeleteAsset(ctx, id); err != nil {
+		return err
+	}
+	return s.record(ctx, ChangeDelete, ChangeEntityAsset, id, before, nil)
+}
+
+// Relationship operations
+
+// CreateRelationship creates a relationship and publishes its creation
+func (s *CDCStore) CreateRelationship(ctx context.Context, rel models.Relationship) error {
+	if err := s.GraphStore.CreateRelationship(ctx, rel); err != nil {
+		return err
+	}
+	rel.ID = rel.CanonicalID()
+	return s.record(ctx, ChangeCreate, ChangeEntityRelationship, rel.ID, nil, rel)
+}
+
+// UpdateRelationship updates a relationship and publishes the change
+func (s *CDCStore) UpdateRelationship(ctx context.Context, rel models.Relationship) error {
+	before := s.relationshipBefore(ctx, rel.ID)
+	if err := s.GraphStore.UpdateRelationship(ctx, rel); err != nil {
+		return err
+	}
+	return s.record(ctx, ChangeUpdate, ChangeEntityRelationship, rel.ID, before, rel)
+}
+
+// DeleteRelationship deletes a relationship and publishes its deletion
+func (s *CDCStore) DeleteRelationship(ctx context.Context, id string) error {
+	before := s.relationshipBefore(ctx, id)
+	if err := s.GraphStore.DeleteRelationship(ctx, id); err != nil {
+		return err
+	}
+	return s.record(ctx, ChangeDelete, ChangeEntityRelationship, id, before, nil)
+}
+
+// DeleteRelationshipsByFilter deletes matching relationships and publishes a
+// change for each. Soft deletes are published as updates closing the
+// relationship's validity.
+func (s *CDCStore) DeleteRelationshipsByFilter(ctx context.Context, filter models.RelationshipDeleteFilter) (int, error) {
+	listFilter := filter.RelationshipFilter
+	if !filter.Hard {
+		listFilter.ActiveOnly = true
+	}
+	matched, err := s.GraphStore.ListRelationships(ctx, listFilter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list relationships for change capture: %w", err)
+	}
+
+	deleted, err := s.GraphStore.DeleteRelationshipsByFilter(ctx, filter)
+	if err != nil {
+		return deleted, err
+	}
+
+	now := time.Now().UTC()
+	changes := make([]ChangeEvent, 0, len(matched))
+	for _, rel := range matched {
+		var change ChangeEvent
+		if filter.Hard {
+			change, err = newChange(ChangeDelete, ChangeEntityRelationship, rel.ID, rel, nil)
+		} else {
+			after := rel
+			after.ValidTo = &now
+			after.UpdatedAt = now
+			change, err = newChange(ChangeUpdate, ChangeEntityRelationship, rel.ID, rel, after)
+		}
+		if err != nil {
+			return deleted, err
+		}
+		changes = append(changes, change)
+	}
+	return deleted, s.publish(ctx, changes...)
+}
+
+// Risk and finding operations
+
+// UpdateAssetRisk stores a risk score and publishes it
+func (s *CDCStore) UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error {
+	if err := s.GraphStore.UpdateAssetRisk(ctx, risk); err != nil {
+		return err
+	}
+	return s.record(ctx, ChangeUpdate, ChangeEntityRisk, risk.AssetID, nil, risk)
+}
+
+// BulkUpdateAssetRisk stores risk scores and publishes each
+func (s *CDCStore) BulkUpdateAssetRisk(ctx context.Context, risks []models.RiskScore) error {
+	if err := s.GraphStore.BulkUpdateAssetRisk(ctx, risks); err != nil {
+		return err
+	}
+
+	changes := make([]ChangeEvent, 0, len(risks))
+	for _, risk := range risks {
+		change, err := newChange(ChangeUpdate, ChangeEntityRisk, risk.AssetID, nil, risk)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+	}
+	return s.publish(ctx, changes...)
+}
+
+// CreateFinding creates a finding and publishes its creation
+func (s *CDCStore) CreateFinding(ctx context.Context, finding models.Finding) error {
+	if err := s.GraphStore.CreateFinding(ctx, finding); err != nil {
+		return s.retryCreate(ctx, err, ChangeEntityFinding, finding.ID, finding)
+	}
+	return s.recordCreate(ctx, ChangeEntityFinding, finding.ID, finding)
+}
+
+// UpdateFinding updates a finding and publishes the change
+func (s *CDCStore) UpdateFinding(ctx context.Context, finding models.Finding) error {
+	if err := s.GraphStore.UpdateFinding(ctx, finding); err != nil {
+		return err
+	}
+	return s.record(ctx, ChangeUpdate, ChangeEntityFinding, finding.ID, nil, finding)
+}
+
+// PatchFinding patches a finding and publishes the change
+func (s *CDCStore) PatchFinding(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Finding, int64, error) {
+	finding, version, err := s.GraphStore.PatchFinding(ctx, id, patch, expectedVersion)
+	if err != nil {
+		return finding, version, err
+	}
+	return finding, version, s.record(ctx, ChangeUpdate, ChangeEntityFinding, id, nil, finding)
+}
+
+// ResolveFinding resolves a finding and publishes the change
+func (s *CDCStore) ResolveFinding(ctx context.Context, findingID string) (models.Finding, error) {
+	finding, err := s.GraphStore.ResolveFinding(ctx, findingID)
+	if err != nil {
+		return finding, err
+	}
+	return finding, s.record(ctx, ChangeUpdate, ChangeEntityFinding, findingID, nil, finding)
+}
+
+// RecordFindingFeedback records feedback on a finding and publishes the
+// updated finding
+func (s *CDCStore) RecordFindingFeedback(ctx context.Context, feedback models.FindingFeedback) (models.Finding, error) {
+	finding, err := s.GraphStore.RecordFindingFeedback(ctx, feedback)
+	if err != nil {
+		return finding, err
+	}
+	return finding, s.record(ctx, ChangeUpdate, ChangeEntityFinding, finding.ID, nil, finding)
+}
+
+// Bulk operations
+
+// BulkCreateAssets creates assets and publishes each creation, including
+// those created when others failed
+func (s *CDCStore) BulkCreateAssets(ctx context.Context, assets []models.Asset) error {
+	if err := s.GraphStore.BulkCreateAssets(ctx, assets); err != nil {
+		var bulkErr *BulkWriteError
+		if !errors.As(err, &bulkErr) {
+			return err
+		}
+		if pubErr := s.publishAssets(ctx, ChangeCreate, nil, bulkErr.Succeeded(assets)); pubErr != nil {
+			log.Printf("Failed to publish bulk created assets: %v", pubErr)
+		}
+		return err
+	}
+	return s.publishAssets(ctx, ChangeCreate, nil, assets)
+}
+
+// BulkUpdateAssets updates assets and publishes each change, including
+// those updated when others failed
+func (s *CDCStore) BulkUpdateAssets(ctx context.Context, assets []models.Asset) error {
+	befores := s.assetsBefore(ctx, assets)
+
+	if err := s.GraphStore.BulkUpdateAssets(ctx, assets); err != nil {
+		var bulkErr *BulkWriteError
+		if !errors.As(err, &bulkErr) {
+			return err
+		}
+		if pubErr := s.publishAssets(ctx, ChangeUpdate, befores, bulkErr.Succeeded(assets)); pubErr != nil {
+			log.Printf("Failed to publish bulk updated assets: %v", pubErr)
+		}
+		return err
+	}
+	return s.publishAssets(ctx, ChangeUpdate, befores, assets)
+}
+
+// publishAssets publishes one change per asset
+func (s *CDCStore) publishAssets(ctx context.Context, op string, befores map[string]models.Asset, assets []models.Asset) error {
+	changes := make([]ChangeEvent, 0, len(assets))
+	for _, asset := range assets {
+		var before interface{}
+		if b, ok := befores[asset.GetID()]; ok {
+			before = b
+		}
+		change, err := newChange(op, ChangeEntityAsset, asset.GetID(), before, asset)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+	}
+	return s.publish(ctx, changes...)
+}
+
+// BulkCreateRelationships creates relationships and publishes each creation
+func (s *CDCStore) BulkCreateRelationships(ctx context.Context, relationships []models.Relationship) error {
+	if err := s.GraphStore.BulkCreateRelationships(ctx, relationships); err != nil {
+		return err
+	}
+
+	changes := make([]ChangeEvent, 0, len(relationships))
+	for _, rel := range relationships {
+		rel.ID = rel.CanonicalID()
+		change, err := newChange(ChangeCreate, ChangeEntityRelationship, rel.ID, nil, rel)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+	}
+	return s.publish(ctx, changes...)
+}
+
+// BulkDeleteAssets deletes assets and publishes each deletion
+func (s *CDCStore) BulkDeleteAssets(ctx context.Context, assetIDs []string) error {
+	befores := make(map[string]models.Asset, len(assetIDs))
+	for _, id := range assetIDs {
+		if before := s.assetBefore(ctx, id); before != nil {
+			befores[id] = before
+		}
+	}
+
+	if err := s.GraphStore.BulkDeleteAssets(ctx, assetIDs); err != nil {
+		return err
+	}
+
+	changes := make([]ChangeEvent, 0, len(assetIDs))
+	for _, id := range assetIDs {
+		var before interface{}
+		if b, ok := befores[id]; ok {
+			before = b
+		}
+		change, err := newChange(ChangeDelete, ChangeEntityAsset, id, before, nil)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, change)
+	}
+	return s.publish(ctx, changes...)
+}
+
+// Optional capabilities
+
+// AutocompleteAssets forwards to the wrapped store
+func (s *CDCStore) AutocompleteAssets(ctx context.Context, prefix string, limit int) ([]AssetSuggestion, error) {
+	completer, ok := s.GraphStore.(assetAutocompleter)
+	if !ok {
+		return nil, notSupported("asset autocomplete")
+	}
+	return completer.AutocompleteAssets(ctx, prefix, limit)
+}
+
+// ExportFeatures forwards to the wrapped store
+func (s *CDCStore) ExportFeatures(ctx context.Context) (*FeatureSet, error) {
+	exporter, ok := s.GraphStore.(featureExporter)
+	if !ok {
+		return nil, notSupported("feature export")
+	}
+	return exporter.ExportFeatures(ctx)
+}
+
+// SchemaStatus forwards to the wrapped store
+func (s *CDCStore) SchemaStatus(ctx context.Context) (SchemaMigrationStatus, error) {
+	versioned, ok := s.GraphStore.(schemaVersioner)
+	if !ok {
+		return SchemaMigrationStatus{}, notSupported("schema status")
+	}
+	return versioned.SchemaStatus(ctx)
+}
+
+// ReencodeAssets forwards to the wrapped store. Re-encoding does not change
+// the assets and publishes no changes.
+func (s *CDCStore) ReencodeAssets(ctx context.Context) (int, error) {
+	reencoder, ok := s.GraphStore.(assetReencoder)
+	if !ok {
+		return 0, notSupported("asset re-encoding")
+	}
+	return reencoder.ReencodeAssets(ctx)
+}
+
+// SaveAcceptedPath forwards to the wrapped store. Accepted paths are not
+// graph entities and publish no changes.
+func (s *CDCStore) SaveAcceptedPath(ctx context.Context, entry models.AcceptedPath) error {
+	paths, ok := s.GraphStore.(AcceptedPathStore)
+	if !ok {
+		return notSupported("accepted path storage")
+	}
+	return paths.SaveAcceptedPath(ctx, entry)
+}
+
+// DeleteAcceptedPath forwards to the wrapped store
+func (s *CDCStore) DeleteAcceptedPath(ctx context.Context, id string) error {
+	paths, ok := s.GraphStore.(AcceptedPathStore)
+	if !ok {
+		return notSupported("accepted path storage")
+	}
+	return paths.DeleteAcceptedPath(ctx, id)
+}
+
+// ListAcceptedPaths forwards to the wrapped store
+func (s *CDCStore) ListAcceptedPaths(ctx context.Context) ([]models.AcceptedPath, error) {
+	paths, ok := s.GraphStore.(AcceptedPathStore)
+	if !ok {
+		return nil, notSupported("accepted path storage")
+	}
+	return paths.ListAcceptedPaths(ctx)
+}
@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// createOnceStore creates each asset once and conflicts afterwards
+type createOnceStore struct {
+	GraphStore
+	created map[string]bool
+}
+
+func (s *createOnceStore) CreateAsset(ctx context.Context, asset models.Asset) error {
+	if s.created[asset.GetID()] {
+		return apperrors.Conflict("asset already exists: %s", asset.GetID())
+	}
+	s.created[asset.GetID()] = true
+	return nil
+}
+
+// flakyPublisher fails its first failures publishes
+type flakyPublisher struct {
+	failures  int
+	published []ChangeEvent
+}
+
+func (p *flakyPublisher) PublishChanges(ctx context.Context, changes []ChangeEvent) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, changes...)
+	return nil
+}
+
+func TestCDCStorePublishesRetriedCreate(t *testing.T) {
+	publisher := &flakyPublisher{failures: 1}
+	store := NewCDCStore(&createOnceStore{created: make(map[string]bool)}, publisher, CDCConfig{})
+	asset := &models.Compute{BaseAsset: models.BaseAsset{ID: "asset-1"}}
+
+	if err := store.CreateAsset(context.Background(), asset); !errors.Is(err, ErrChangeNotPublished) {
+		t.Fatalf("first CreateAsset error = %v, want ErrChangeNotPublished", err)
+	}
+	if err := store.CreateAsset(context.Background(), asset); err != nil {
+		t.Fatalf("retried CreateAsset returned error: %v", err)
+	}
+	if len(publisher.published) != 1 || publisher.published[0].Op != ChangeCreate {
+		t.Fatalf("published %+v, want one create", publisher.published)
+	}
+
+	// Once published, creating the asset again is a conflict
+	if err := store.CreateAsset(context.Background(), asset); !errors.Is(err, apperrors.ErrConflict) {
+		t.Errorf("third CreateAsset error = %v, want a conflict", err)
+	}
+}
+
+func TestCDCStoreReportsMissingCapabilities(t *testing.T) {
+	store := NewCDCStore(&createOnceStore{}, &flakyPublisher{}, CDCConfig{})
+
+	if _, err := store.AutocompleteAssets(context.Background(), "web", 10); apperrors.CodeOf(err) != apperrors.CodeNotImplemented {
+		t.Errorf("AutocompleteAssets error = %v, want not implemented", err)
+	}
+	if _, err := store.SchemaStatus(context.Background()); apperrors.CodeOf(err) != apperrors.CodeNotImplemented {
+		t.Errorf("SchemaStatus error = %v, want not implemented", err)
+	}
+}
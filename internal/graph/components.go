@@ -0,0 +1,257 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// maxComponentVisits bounds the assets the traversal fallback visits per
+// call; assets not reached by then are reported in components of their own
+const maxComponentVisits = 100000
+
+// gdsProjectionTTL is how long a projection serves component lookups before
+// it is rebuilt from the graph
+const gdsProjectionTTL = 5 * time.Minute
+
+// gdsCheckTimeout bounds the check for the Graph Data Science library
+const gdsCheckTimeout = 10 * time.Second
+
+// GetConnectedComponents groups assets by undirected reachability over
+// active relationships, which may pass through assets not in assetIDs. Each
+// given asset appears in exactly one component, in the order of assetIDs;
+// assets not in the graph are components of their own. Weakly connected
+// components from the Graph Data Science library are used when it is
+// installed, otherwise the graph is traversed from each asset.
+func (s *Neo4jStore) GetConnectedComponents(ctx context.Context, assetIDs []string) ([][]string, error) {
+	if len(assetIDs) == 0 {
+		return [][]string{}, nil
+	}
+
+	var componentOf map[string]int64
+	var err error
+	if s.hasGDS() {
+		componentOf, err = s.gdsComponents(ctx, assetIDs)
+		if err != nil {
+			log.Printf("GDS connected components failed, traversing instead: %v", err)
+		}
+	}
+	if componentOf == nil {
+		componentOf, err = s.traversalComponents(ctx, assetIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var components [][]string
+	index := make(map[int64]int)
+	seen := make(map[string]bool, len(assetIDs))
+	for _, id := range assetIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		component, ok := componentOf[id]
+		if !ok {
+			components = append(components, []string{id})
+			continue
+		}
+		if i, ok := index[component]; ok {
+			components[i] = append(components[i], id)
+			continue
+		}
+		index[component] = len(components)
+		components = append(components, []string{id})
+	}
+	return components, nil
+}
+
+// hasGDS reports whether the Graph Data Science library is installed,
+// checking once per store. The check runs on its own context so that a
+// cancelled request cannot disable GDS for the life of the store.
+func (s *Neo4jStore) hasGDS() bool {
+	s.gdsOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), gdsCheckTimeout)
+		defer cancel()
+
+		session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+		defer session.Close(ctx)
+
+		result, err := session.Run(ctx, "RETURN gds.version() as version", nil)
+		if err == nil {
+			_, err = result.Single(ctx)
+		}
+		s.gdsAvailable = err == nil
+	})
+	return s.gdsAvailable
+}
+
+// gdsComponents returns the weakly connected component of each of assetIDs
+// found in the store's cached projection, reading only the given assets
+func (s *Neo4jStore) gdsComponents(ctx context.Context, assetIDs []string) (map[string]int64, error) {
+	s.gdsMu.Lock()
+	defer s.gdsMu.Unlock()
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	graphName, err := s.gdsProjection(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		MATCH (n)
+		WHERE n.id IN $assetIds AND NOT n:Finding AND NOT n:RiskSnapshot
+		RETURN n.id as id, gds.util.nodeProperty($graphName, n, 'componentId') as componentId
+	`
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"graphName": graphName, "assetIds": assetIDs}, s.txTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read components: %w", classifyError(err))
+	}
+
+	componentOf := make(map[string]int64, len(assetIDs))
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		id, _ := values["id"].(string)
+		component, ok := values["componentId"].(int64)
+		if id == "" || !ok {
+			continue
+		}
+		componentOf[id] = component
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read components: %w", classifyError(err))
+	}
+	return componentOf, nil
+}
+
+// gdsProjection returns the name of the store's projection of the assets and
+// their active relationships, with each node's weakly connected component
+// stored as componentId. The projection is rebuilt once it is older than
+// gdsProjectionTTL, so relationships changed since are picked up then. The
+// caller holds gdsMu.
+func (s *Neo4jStore) gdsProjection(ctx context.Context, session neo4j.SessionWithContext) (string, error) {
+	if s.gdsGraph != "" && time.Since(s.gdsProjectedAt) < gdsProjectionTTL {
+		return s.gdsGraph, nil
+	}
+
+	graphName := "components-" + uuid.New().String()
+	project := `
+		MATCH (source)
+		WHERE NOT source:Finding AND NOT source:RiskSnapshot AND source.id IS NOT NULL
+		OPTIONAL MATCH (source)-[r]->(target)
+		WHERE NOT target:Finding AND NOT target:RiskSnapshot
+			AND (r.valid_to IS NULL OR r.valid_to > datetime())
+		WITH gds.graph.project($graphName, source, target) as g
+		RETURN g.graphName as graphName
+	`
+	if _, err := session.Run(ctx, project, map[string]interface{}{"graphName": graphName}, s.txTimeout()); err != nil {
+		return "", fmt.Errorf("failed to project graph: %w", classifyError(err))
+	}
+
+	mutate := "CALL gds.wcc.mutate($graphName, {mutateProperty: 'componentId'}) YIELD componentCount RETURN componentCount"
+	if _, err := session.Run(ctx, mutate, map[string]interface{}{"graphName": graphName}, s.txTimeout()); err != nil {
+		s.dropGDSGraph(ctx, session, graphName)
+		return "", fmt.Errorf("failed to compute components: %w", classifyError(err))
+	}
+
+	if s.gdsGraph != "" {
+		s.dropGDSGraph(ctx, session, s.gdsGraph)
+	}
+	s.gdsGraph = graphName
+	s.gdsProjectedAt = time.Now()
+	return graphName, nil
+}
+
+// dropGDSGraph drops a projection, logging rather than returning failures
+func (s *Neo4jStore) dropGDSGraph(ctx context.Context, session neo4j.SessionWithContext, graphName string) {
+	if _, err := session.Run(ctx, "CALL gds.graph.drop($graphName, false)", map[string]interface{}{"graphName": graphName}); err != nil {
+		log.Printf("Failed to drop projected graph %s: %v", graphName, err)
+	}
+}
+
+// dropGDSProjection drops the store's cached projection, if any
+func (s *Neo4jStore) dropGDSProjection(ctx context.Context) {
+	s.gdsMu.Lock()
+	defer s.gdsMu.Unlock()
+
+	if s.gdsGraph == "" {
+		return
+	}
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	s.dropGDSGraph(ctx, session, s.gdsGraph)
+	s.gdsGraph = ""
+}
+
+// traversalComponents expands breadth first from each asset not yet placed
+// in a component, one hop per query, and numbers the components found. A
+// traversal stops early once every asset has been placed.
+func (s *Neo4jStore) traversalComponents(ctx context.Context, assetIDs []string) (map[string]int64, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (n)-[r]-(m)
+		WHERE n.id IN $frontier AND NOT m:Finding AND NOT m:RiskSnapshot
+			AND (r.valid_to IS NULL OR r.valid_to > datetime())
+		RETURN DISTINCT m.id as id
+	`
+
+	wanted := make(map[string]bool, len(assetIDs))
+	for _, id := range assetIDs {
+		wanted[id] = true
+	}
+	componentOf := make(map[string]int64, len(assetIDs))
+	visits := 0
+
+	for _, start := range assetIDs {
+		if _, placed := componentOf[start]; placed {
+			continue
+		}
+		component := int64(len(componentOf))
+		componentOf[start] = component
+		remaining := len(wanted) - len(componentOf)
+
+		visited := map[string]bool{start: true}
+		frontier := []string{start}
+		for len(frontier) > 0 && remaining > 0 && visits < maxComponentVisits {
+			result, err := session.Run(ctx, query, map[string]interface{}{"frontier": frontier}, s.txTimeout())
+			if err != nil {
+				return nil, fmt.Errorf("failed to traverse components: %w", classifyError(err))
+			}
+
+			var next []string
+			for result.Next(ctx) {
+				id, _ := result.Record().AsMap()["id"].(string)
+				if id == "" || visited[id] {
+					continue
+				}
+				visited[id] = true
+				visits++
+				next = append(next, id)
+				if _, placed := componentOf[id]; wanted[id] && !placed {
+					componentOf[id] = component
+					remaining--
+				}
+			}
+			if err := result.Err(); err != nil {
+				return nil, fmt.Errorf("failed to traverse components: %w", classifyError(err))
+			}
+			frontier = next
+		}
+		if visits >= maxComponentVisits {
+			log.Printf("Connected components traversal stopped after visiting %d assets", visits)
+			break
+		}
+	}
+	return componentOf, nil
+}
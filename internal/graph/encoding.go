@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Asset data encodings. Stored documents are decoded by their content, so
+// nodes written with either encoding stay readable after switching.
+const (
+	DataEncodingJSON = "json" // JSON string
+	DataEncodingGzip = "gzip" // gzip-compressed JSON bytes
+)
+
+// gzipWriters reuses compressors, which are costly to allocate per write
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// encodeData converts a marshalled asset document to its stored form in the
+// configured encoding
+func (s *Neo4jStore) encodeData(data []byte) (interface{}, error) {
+	if s.config.DataEncoding != DataEncodingGzip {
+		return string(data), nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(&buf)
+
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress asset data: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress asset data: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeData returns the JSON document of a stored data property in either
+// encoding
+func decodeData(value interface{}) ([]byte, error) {
+	switch data := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("missing data")
+	case string:
+		return []byte(data), nil
+	case []byte:
+		if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+			return data, nil
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress data: %w", err)
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	default:
+		return nil, fmt.Errorf("unexpected data type %T", value)
+	}
+}
+
+// ReencodeAssets rewrites asset documents not yet stored in the configured
+// encoding, for use after changing it. It returns the number rewritten.
+func (s *Neo4jStore) ReencodeAssets(ctx context.Context) (int, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	// Strings are JSON; byte arrays are gzip
+	wantCompressed := s.config.DataEncoding == DataEncodingGzip
+
+	query := `
+		MATCH (n)
+		WHERE n.data IS NOT NULL AND any(label IN labels(n) WHERE label IN $labels)
+			AND (n.data IS :: STRING) = $compressed
+		RETURN elementId(n) as elementId, n.data as data
+		LIMIT $batchSize
+	`
+	update := `
+		UNWIND $rows AS row
+		MATCH (n)
+		WHERE elementId(n) = row.elementId
+		SET n.data = row.data
+	`
+	params := map[string]interface{}{
+		"labels":     assetLabels,
+		"compressed": wantCompressed,
+		"batchSize":  writeBatchSize,
+	}
+
+	total := 0
+	for {
+		result, err := session.Run(ctx, query, params)
+		if err != nil {
+			return total, fmt.Errorf("failed to read assets: %w", classifyError(err))
+		}
+
+		var rows []map[string]interface{}
+		for result.Next(ctx) {
+			values := result.Record().AsMap()
+			raw, err := decodeData(values["data"])
+			if err != nil {
+				return total, fmt.Errorf("failed to decode asset data: %w", err)
+			}
+			data, err := s.encodeData(raw)
+			if err != nil {
+				return total, err
+			}
+			rows = append(rows, map[string]interface{}{
+				"elementId": values["elementId"],
+				"data":      data,
+			})
+		}
+		if err := result.Err(); err != nil {
+			return total, fmt.Errorf("failed to read assets: %w", classifyError(err))
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		if _, err := session.Run(ctx, update, map[string]interface{}{"rows": rows}); err != nil {
+			return total, fmt.Errorf("failed to rewrite asset data: %w", classifyError(err))
+		}
+		total += len(rows)
+	}
+
+	log.Printf("Re-encoded %d assets as %s", total, s.config.DataEncoding)
+	return total, nil
+}
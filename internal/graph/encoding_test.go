@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// benchmarkAsset is a compute asset of typical size, with the tags and
+// metadata collectors attach
+func benchmarkAsset() *models.Compute {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	asset := &models.Compute{
+		BaseAsset: models.BaseAsset{
+			ID:          "arn:aws:ec2:us-east-1:123456789012:instance/i-0abc123def4567890",
+			Provider:    models.ProviderAWS,
+			Type:        models.AssetTypeCompute,
+			Environment: models.EnvironmentProduction,
+			Name:        "payments-api-prod-1",
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			FirstSeen:   now,
+			LastSeen:    now,
+			Tags:        make(map[string]string),
+			Metadata:    make(map[string]interface{}),
+		},
+		SubType:         "VM",
+		OS:              "Amazon Linux 2",
+		ExposedPorts:    []int{22, 443, 8080},
+		InternetExposed: true,
+		PublicIP:        "203.0.113.10",
+		PrivateIP:       "10.0.1.25",
+		InstanceType:    "m5.xlarge",
+		Region:          "us-east-1",
+	}
+	for i := 0; i < 20; i++ {
+		asset.Tags[fmt.Sprintf("tag-%d", i)] = fmt.Sprintf("value-%d", i)
+		asset.Metadata[fmt.Sprintf("attribute-%d", i)] = fmt.Sprintf("collected attribute %d of the instance", i)
+	}
+	return asset
+}
+
+func TestDataEncodingsRoundTrip(t *testing.T) {
+	want, err := json.Marshal(benchmarkAsset())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoding := range []string{DataEncodingJSON, DataEncodingGzip} {
+		s := &Neo4jStore{config: GraphConfig{DataEncoding: encoding}}
+		stored, err := s.encodeData(want)
+		if err != nil {
+			t.Fatalf("%s: encodeData returned error: %v", encoding, err)
+		}
+		got, err := decodeData(stored)
+		if err != nil {
+			t.Fatalf("%s: decodeData returned error: %v", encoding, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s: decoded document differs from the original", encoding)
+		}
+	}
+}
+
+// BenchmarkAssetWrite measures marshalling an asset into its stored form
+func BenchmarkAssetWrite(b *testing.B) {
+	asset := benchmarkAsset()
+	for _, encoding := range []string{DataEncodingJSON, DataEncodingGzip} {
+		s := &Neo4jStore{config: GraphConfig{DataEncoding: encoding}}
+		b.Run(encoding, func(b *testing.B) {
+			b.ReportAllocs()
+			size := 0
+			for i := 0; i < b.N; i++ {
+				data, err := json.Marshal(asset)
+				if err != nil {
+					b.Fatal(err)
+				}
+				stored, err := s.encodeData(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = storedSize(stored)
+			}
+			b.ReportMetric(float64(size), "stored-bytes")
+		})
+	}
+}
+
+// BenchmarkAssetRead measures decoding a stored asset back into its type
+func BenchmarkAssetRead(b *testing.B) {
+	data, err := json.Marshal(benchmarkAsset())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, encoding := range []string{DataEncodingJSON, DataEncodingGzip} {
+		s := &Neo4jStore{config: GraphConfig{DataEncoding: encoding}}
+		stored, err := s.encodeData(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(encoding, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				raw, err := decodeData(stored)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var asset models.Compute
+				if err := json.Unmarshal(raw, &asset); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func storedSize(stored interface{}) int {
+	switch data := stored.(type) {
+	case string:
+		return len(data)
+	case []byte:
+		return len(data)
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/apperrors"
+)
+
+// classifyError gives driver errors an apperrors code so callers can handle
+// them without inspecting driver types. Unrecognized errors are returned
+// unchanged and treated as internal.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var typed *apperrors.Error
+	if errors.As(err, &typed) {
+		return err
+	}
+
+	var neoErr *neo4j.Neo4jError
+	if errors.As(err, &neoErr) {
+		switch {
+		case strings.Contains(neoErr.Code, "ConstraintValidationFailed"):
+			return apperrors.Wrap(apperrors.CodeConflict, err, "a conflicting record already exists")
+		case strings.Contains(neoErr.Code, "TransactionTimedOut"):
+			return apperrors.Wrap(apperrors.CodeTimeout, err, "graph query timed out")
+		case neoErr.Classification() == "TransientError":
+			return apperrors.Wrap(apperrors.CodeUnavailable, err, "graph database is temporarily unavailable")
+		}
+	}
+
+	var connErr *neo4j.ConnectivityError
+	if errors.As(err, &connErr) {
+		return apperrors.Wrap(apperrors.CodeUnavailable, err, "graph database is unavailable")
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return apperrors.Wrap(apperrors.CodeTimeout, err, "graph query timed out")
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/models"
+)
+
+// ExpiryConfig represents asset expiry configuration
+type ExpiryConfig struct {
+	// TTLs is how long an asset of each type stays fresh without being
+	// re-collected. Types without a TTL never expire.
+	TTLs map[models.AssetType]time.Duration `json:"ttls" yaml:"ttls"`
+	// DecayPeriod is how long the risk of a stale asset decays linearly to
+	// zero before the asset is soft-deleted
+	DecayPeriod   time.Duration `json:"decay_period" yaml:"decay_period"`
+	SweepInterval time.Duration `json:"sweep_interval" yaml:"sweep_interval"`
+}
+
+// DefaultExpiryConfig returns default expiry configuration
+func DefaultExpiryConfig() ExpiryConfig {
+	return ExpiryConfig{
+		TTLs:          make(map[models.AssetType]time.Duration),
+		DecayPeriod:   24 * time.Hour,
+		SweepInterval: 15 * time.Minute,
+	}
+}
+
+// ExpiryResult summarizes an expiry sweep
+type ExpiryResult struct {
+	Decayed int `json:"decayed"`
+	Expired int `json:"expired"`
+}
+
+// ExpireStaleAssets decays the risk score of assets that have not been
+// collected within their type's TTL and soft-deletes those that stayed stale
+// for the whole decay period. The undecayed score is kept so it can be
+// restored when the asset is observed again.
+func (s *Neo4jStore) ExpireStaleAssets(ctx context.Context, ttls map[models.AssetType]time.Duration, decayPeriod time.Duration) (*ExpiryResult, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result := &ExpiryResult{}
+	for assetType, ttl := range ttls {
+		if ttl <= 0 {
+			continue
+		}
+		if _, err := assetLabel(assetType); err != nil {
+			return result, err
+		}
+
+		query := fmt.Sprintf(`
+			MATCH (n:%s)
+			WHERE n.expired_at IS NULL
+			WITH n, duration.inSeconds(coalesce(n.last_collected_at, n.updated_at), datetime()).seconds - $ttl as overdue
+			WHERE overdue > 0
+			WITH n, CASE WHEN overdue >= $decayPeriod THEN 0.0
+				ELSE 1.0 - toFloat(overdue) / $decayPeriod END as decay
+			SET n.undecayed_risk_score = coalesce(n.undecayed_risk_score, n.risk_score),
+				n.risk_decay = decay
+			SET n.risk_score = n.undecayed_risk_score * decay,
+				n.expired_at = CASE WHEN decay = 0.0 THEN datetime() ELSE null END
+			RETURN count(n) as decayed, sum(CASE WHEN decay = 0.0 THEN 1 ELSE 0 END) as expired
+		`, assetType)
+
+		params := map[string]interface{}{
+			"ttl":         int64(ttl.Seconds()),
+			"decayPeriod": int64(decayPeriod.Seconds()),
+		}
+
+		res, err := session.Run(ctx, query, params)
+		if err != nil {
+			return result, fmt.Errorf("failed to expire %s assets: %w", assetType, err)
+		}
+
+		record, err := res.Single(ctx)
+		if err != nil {
+			return result, fmt.Errorf("failed to expire %s assets: %w", assetType, err)
+		}
+
+		values := record.AsMap()
+		decayed, _ := values["decayed"].(int64)
+		expired, _ := values["expired"].(int64)
+		result.Decayed += int(decayed - expired)
+		result.Expired += int(expired)
+	}
+
+	return result, nil
+}
+
+// ExpirySweeper periodically expires assets that are no longer collected
+type ExpirySweeper struct {
+	store  GraphStore
+	config ExpiryConfig
+}
+
+// NewExpirySweeper creates a new expiry sweeper
+func NewExpirySweeper(store GraphStore, config ExpiryConfig) *ExpirySweeper {
+	return &ExpirySweeper{
+		store:  store,
+		config: config,
+	}
+}
+
+// Run sweeps stale assets every sweep interval until the context is cancelled
+func (e *ExpirySweeper) Run(ctx context.Context) {
+	if len(e.config.TTLs) == 0 || e.config.SweepInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := e.store.ExpireStaleAssets(ctx, e.config.TTLs, e.config.DecayPeriod)
+			if err != nil {
+				log.Printf("Asset expiry sweep failed: %v", err)
+				continue
+			}
+			if result.Decayed > 0 || result.Expired > 0 {
+				log.Printf("Asset expiry sweep decayed %d and expired %d assets", result.Decayed, result.Expired)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// explainKey is the context key holding a QueryExplanation
+type explainKey struct{}
+
+// QueryExplanation records how path queries were executed so unexpected
+// results can be diagnosed. It is filled in by store and engine methods called
+// with a context returned by WithExplain.
+type QueryExplanation struct {
+	Queries        []ExplainedQuery    `json:"queries"`
+	Duration       time.Duration       `json:"duration"`
+	PathsEvaluated int                 `json:"paths_evaluated"`
+	PathsReturned  int                 `json:"paths_returned"`
+	Paths          []PathRiskBreakdown `json:"paths,omitempty"`
+	mu             sync.Mutex
+}
+
+// ExplainedQuery is a single parameterized Cypher query and its execution time
+type ExplainedQuery struct {
+	Cypher     string                 `json:"cypher"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Duration   time.Duration          `json:"duration"`
+}
+
+// PathRiskBreakdown shows how the risk of a returned path was calculated
+type PathRiskBreakdown struct {
+	NodeIDs        []string           `json:"node_ids"`
+	Components     map[string]float64 `json:"components"`
+	CumulativeRisk float64            `json:"cumulative_risk"`
+	Likelihood     float64            `json:"likelihood,omitempty"`
+}
+
+// WithExplain returns a context that collects an explanation of the path
+// queries executed with it
+func WithExplain(ctx context.Context) (context.Context, *QueryExplanation) {
+	explain := &QueryExplanation{}
+	return context.WithValue(ctx, explainKey{}, explain), explain
+}
+
+// explanationFrom returns the explanation collected for ctx, or nil when the
+// caller did not ask for one. All recording methods are nil-safe.
+func explanationFrom(ctx context.Context) *QueryExplanation {
+	explain, _ := ctx.Value(explainKey{}).(*QueryExplanation)
+	return explain
+}
+
+// recordQuery records an executed query
+func (e *QueryExplanation) recordQuery(cypher string, params map[string]interface{}, duration time.Duration) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.Queries = append(e.Queries, ExplainedQuery{
+		Cypher:     cypher,
+		Parameters: params,
+		Duration:   duration,
+	})
+	e.Duration += duration
+}
+
+// recordPaths records how many candidate paths were evaluated and returned
+func (e *QueryExplanation) recordPaths(evaluated, returned int, breakdowns ...PathRiskBreakdown) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.PathsEvaluated += evaluated
+	e.PathsReturned += returned
+	e.Paths = append(e.Paths, breakdowns...)
+}
@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/models"
+)
+
+// exposureRelationships are the relationship types an attacker can follow
+// from an internet-facing asset
+const exposureRelationships = "CONNECTED_TO|RUNS_ON|HAS_ACCESS_TO|ASSUMES_ROLE"
+
+// RecomputeInternetExposure re-evaluates whether the asset and everything
+// downstream of it within maxHops is reachable from a directly internet-exposed
+// asset, and updates the reachable_from_internet flag where it changed. It
+// returns the IDs of the assets whose flag changed.
+func (s *Neo4jStore) RecomputeInternetExposure(ctx context.Context, assetID string, maxHops int) ([]string, error) {
+	if maxHops <= 0 {
+		return nil, nil
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	query := fmt.Sprintf(`
+		MATCH (start {id: $assetId})-[:%[1]s*0..%[2]d]->(n)
+		WHERE NOT n:Finding
+		WITH DISTINCT n
+		RETURN n.id as id, n.data as data,
+			EXISTS {
+				MATCH path = (entry)-[:%[1]s*1..%[2]d]->(n)
+				WHERE entry.internet_exposed = true AND entry <> n
+					AND ALL(r IN relationships(path) WHERE r.valid_to IS NULL OR r.valid_to > datetime())
+			} as reachable
+	`, exposureRelationships, maxHops)
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"assetId": assetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute internet exposure: %w", err)
+	}
+
+	var changed []string
+	var rows []map[string]interface{}
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		id, _ := values["id"].(string)
+		reachable, _ := values["reachable"].(bool)
+
+		// The stored asset document is authoritative since asset updates
+		// from collectors overwrite it without the derived flag
+		var doc map[string]interface{}
+		data, err := decodeData(values["data"])
+		if err == nil {
+			err = json.Unmarshal(data, &doc)
+		}
+		if err != nil {
+			log.Printf("Failed to unmarshal asset %s: %v", id, err)
+			continue
+		}
+
+		previous, _ := doc["reachable_from_internet"].(bool)
+		if previous == reachable {
+			continue
+		}
+
+		if reachable {
+			doc["reachable_from_internet"] = true
+		} else {
+			delete(doc, "reachable_from_internet")
+		}
+
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			log.Printf("Failed to marshal asset %s: %v", id, err)
+			continue
+		}
+		updated, err := s.encodeData(raw)
+		if err != nil {
+			log.Printf("Failed to encode asset %s: %v", id, err)
+			continue
+		}
+
+		changed = append(changed, id)
+		rows = append(rows, map[string]interface{}{
+			"id":        id,
+			"data":      updated,
+			"reachable": reachable,
+		})
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to compute internet exposure: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	query = `
+		UNWIND $rows AS row
+		MATCH (n {id: row.id})
+		SET n.data = row.data, n.reachable_from_internet = row.reachable, n.updated_at = datetime(),
+			n.version = coalesce(n.version, 0) + 1
+	`
+
+	if _, err := session.Run(ctx, query, map[string]interface{}{"rows": rows}); err != nil {
+		return nil, fmt.Errorf("failed to update internet exposure: %w", err)
+	}
+
+	return changed, nil
+}
+
+// isInternetExposed reports whether an asset is directly exposed to the internet
+func isInternetExposed(asset models.Asset) bool {
+	if compute, ok := asset.(*models.Compute); ok {
+		return compute.InternetExposed
+	}
+	return false
+}
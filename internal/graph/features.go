@@ -0,0 +1,247 @@
+package graph
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// NodeFeatures is one row of the node feature table used for graph ML
+type NodeFeatures struct {
+	AssetID          string  `json:"asset_id"`
+	Type             string  `json:"type"`
+	Provider         string  `json:"provider"`
+	Environment      string  `json:"environment"`
+	RiskScore        float64 `json:"risk_score"`
+	InternetExposed  bool    `json:"internet_exposed"`
+	InDegree         int     `json:"in_degree"`
+	OutDegree        int     `json:"out_degree"`
+	DegreeCentrality float64 `json:"degree_centrality"`
+	PathCentrality   float64 `json:"path_centrality"` // share of attack paths from internet-exposed assets through the node
+	OpenFindings     int     `json:"open_findings"`
+	TotalFindings    int     `json:"total_findings"`
+}
+
+// EdgeFeatures is one row of the edge feature table used for graph ML
+type EdgeFeatures struct {
+	ID         string  `json:"id"`
+	Source     string  `json:"source"`
+	Target     string  `json:"target"`
+	Type       string  `json:"type"`
+	Strength   float64 `json:"strength"`
+	SourceRisk float64 `json:"source_risk"`
+	TargetRisk float64 `json:"target_risk"`
+	// PathWeight is the strength scaled by the riskier endpoint, the same
+	// weighting used for attack path ranking
+	PathWeight float64 `json:"path_weight"`
+}
+
+// featurePathDepth bounds the attack paths used for path centrality
+const featurePathDepth = 5
+
+// FeatureSet is the graph as node and edge feature tables
+type FeatureSet struct {
+	Nodes       []NodeFeatures `json:"nodes"`
+	Edges       []EdgeFeatures `json:"edges"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// ExportFeatures computes node and edge features for every asset in the
+// graph. Like the streaming reads, it is a bulk export and is not subject to
+// the query timeout or result cap.
+func (s *Neo4jStore) ExportFeatures(ctx context.Context) (*FeatureSet, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	nodeQuery := `
+		MATCH (n)
+		WHERE n.id IS NOT NULL AND n.data IS NOT NULL AND NOT n:Finding AND NOT n:RiskSnapshot
+		RETURN n.id as id, labels(n) as labels, coalesce(n.provider, '') as provider,
+			coalesce(n.environment, '') as environment, coalesce(n.risk_score, 0.0) as riskScore,
+			coalesce(n.internet_exposed, false) as internetExposed,
+			COUNT { (m)-[]->(n) WHERE NOT m:Finding } as inDegree,
+			COUNT { (n)-[]->(m) WHERE NOT m:Finding AND NOT m:RiskSnapshot } as outDegree,
+			COUNT { (f:Finding)-[:GENERATES]->(n) WHERE f.status = 'open' } as openFindings,
+			COUNT { (f:Finding)-[:GENERATES]->(n) } as totalFindings
+		ORDER BY id
+	`
+
+	result, err := session.Run(ctx, nodeQuery, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node features: %w", classifyError(err))
+	}
+
+	features := &FeatureSet{GeneratedAt: time.Now()}
+	for result.Next(ctx) {
+		row := result.Record().AsMap()
+		node := NodeFeatures{}
+		node.AssetID, _ = row["id"].(string)
+		labels, _ := row["labels"].([]interface{})
+		node.Type = string(assetTypeOfLabels(labels))
+		node.Provider, _ = row["provider"].(string)
+		node.Environment, _ = row["environment"].(string)
+		node.RiskScore, _ = row["riskScore"].(float64)
+		node.InternetExposed, _ = row["internetExposed"].(bool)
+		inDegree, _ := row["inDegree"].(int64)
+		outDegree, _ := row["outDegree"].(int64)
+		openFindings, _ := row["openFindings"].(int64)
+		totalFindings, _ := row["totalFindings"].(int64)
+		node.InDegree = int(inDegree)
+		node.OutDegree = int(outDegree)
+		node.OpenFindings = int(openFindings)
+		node.TotalFindings = int(totalFindings)
+		features.Nodes = append(features.Nodes, node)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read node features: %w", classifyError(err))
+	}
+
+	// Normalized degree centrality: the share of other nodes a node touches
+	if n := len(features.Nodes); n > 1 {
+		for i := range features.Nodes {
+			node := &features.Nodes[i]
+			node.DegreeCentrality = float64(node.InDegree+node.OutDegree) / float64(n-1)
+		}
+	}
+
+	if err := s.applyPathCentrality(ctx, features); err != nil {
+		return nil, err
+	}
+
+	edgeQuery := `
+		MATCH (from)-[r]->(to)
+		WHERE r.id IS NOT NULL AND NOT from:Finding
+		RETURN r.id as id, type(r) as type, from.id as source, to.id as target,
+			coalesce(r.strength, 0.0) as strength,
+			coalesce(from.risk_score, 0.0) as sourceRisk, coalesce(to.risk_score, 0.0) as targetRisk
+		ORDER BY id
+	`
+
+	result, err = session.Run(ctx, edgeQuery, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query edge features: %w", classifyError(err))
+	}
+
+	for result.Next(ctx) {
+		row := result.Record().AsMap()
+		edge := EdgeFeatures{}
+		edge.ID, _ = row["id"].(string)
+		edge.Type, _ = row["type"].(string)
+		edge.Source, _ = row["source"].(string)
+		edge.Target, _ = row["target"].(string)
+		edge.Strength, _ = row["strength"].(float64)
+		edge.SourceRisk, _ = row["sourceRisk"].(float64)
+		edge.TargetRisk, _ = row["targetRisk"].(float64)
+
+		maxRisk := edge.SourceRisk
+		if edge.TargetRisk > maxRisk {
+			maxRisk = edge.TargetRisk
+		}
+		edge.PathWeight = edge.Strength * maxRisk / 100
+		features.Edges = append(features.Edges, edge)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read edge features: %w", classifyError(err))
+	}
+
+	return features, nil
+}
+
+// applyPathCentrality sets path centrality from the attack paths starting at
+// the internet-exposed nodes
+func (s *Neo4jStore) applyPathCentrality(ctx context.Context, features *FeatureSet) error {
+	var entryPoints []string
+	for _, node := range features.Nodes {
+		if node.InternetExposed {
+			entryPoints = append(entryPoints, node.AssetID)
+		}
+	}
+	if len(entryPoints) == 0 {
+		return nil
+	}
+
+	paths, err := s.FindAttackPaths(ctx, entryPoints, nil, featurePathDepth)
+	if err != nil {
+		return fmt.Errorf("failed to find attack paths for path centrality: %w", err)
+	}
+
+	nodePaths := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		ids := make([]string, len(path.Nodes))
+		for i, node := range path.Nodes {
+			ids[i] = node.GetID()
+		}
+		nodePaths = append(nodePaths, ids)
+	}
+	features.ApplyPathCentrality(ctx, nodePaths)
+	return nil
+}
+
+// ApplyPathCentrality sets each node's path centrality to the share of paths
+// (as node ID lists) that pass through it
+func (f *FeatureSet) ApplyPathCentrality(ctx context.Context, paths [][]string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	counts := (&GraphAlgorithms{}).FindBottlenecks(ctx, paths)
+	for i := range f.Nodes {
+		f.Nodes[i].PathCentrality = float64(counts[f.Nodes[i].AssetID]) / float64(len(paths))
+	}
+}
+
+// WriteNodesCSV writes the node feature table as CSV with a header row
+func (f *FeatureSet) WriteNodesCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"asset_id", "type", "provider", "environment", "risk_score", "internet_exposed",
+		"in_degree", "out_degree", "degree_centrality", "path_centrality", "open_findings", "total_findings",
+	})
+	for _, node := range f.Nodes {
+		cw.Write([]string{
+			node.AssetID,
+			node.Type,
+			node.Provider,
+			node.Environment,
+			formatFeature(node.RiskScore),
+			strconv.FormatBool(node.InternetExposed),
+			strconv.Itoa(node.InDegree),
+			strconv.Itoa(node.OutDegree),
+			formatFeature(node.DegreeCentrality),
+			formatFeature(node.PathCentrality),
+			strconv.Itoa(node.OpenFindings),
+			strconv.Itoa(node.TotalFindings),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteEdgesCSV writes the edge feature table as CSV with a header row
+func (f *FeatureSet) WriteEdgesCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "source", "target", "type", "strength", "source_risk", "target_risk", "path_weight"})
+	for _, edge := range f.Edges {
+		cw.Write([]string{
+			edge.ID,
+			edge.Source,
+			edge.Target,
+			edge.Type,
+			formatFeature(edge.Strength),
+			formatFeature(edge.SourceRisk),
+			formatFeature(edge.TargetRisk),
+			formatFeature(edge.PathWeight),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFeature(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
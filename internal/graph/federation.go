@@ -0,0 +1,1355 @@
+package graph
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// FederationConfig represents multi-region federation configuration
+type FederationConfig struct {
+	// DefaultRegion receives writes for assets without a known home region
+	DefaultRegion string `json:"default_region"`
+	// RegionAliases maps cloud regions (e.g. eu-west-1) to federation regions
+	RegionAliases map[string]string `json:"region_aliases"`
+	// AllowPartialResults lets reads succeed when some regions are unavailable
+	AllowPartialResults bool `json:"allow_partial_results"`
+	// HomeCacheSize bounds how many asset home regions are remembered;
+	// assets not remembered are looked up in every region
+	HomeCacheSize int `json:"home_cache_size"`
+}
+
+// defaultHomeCacheSize is used when HomeCacheSize is not set
+const defaultHomeCacheSize = 100000
+
+// FederatedStore implements GraphStore over several regional stores. Reads
+// fan out to every region and are merged by ID; writes are routed to the
+// home region of the asset they concern.
+type FederatedStore struct {
+	regions map[string]GraphStore
+	order   []string
+	config  FederationConfig
+	homes   *homeCache
+}
+
+// NewFederatedStore creates a graph store federating the given regional stores
+func NewFederatedStore(regions map[string]GraphStore, config FederationConfig) (*FederatedStore, error) {
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("federation requires at least one region")
+	}
+
+	order := make([]string, 0, len(regions))
+	for region := range regions {
+		order = append(order, region)
+	}
+	sort.Strings(order)
+
+	if config.DefaultRegion == "" {
+		config.DefaultRegion = order[0]
+	}
+	if _, ok := regions[config.DefaultRegion]; !ok {
+		return nil, fmt.Errorf("default region %s is not federated", config.DefaultRegion)
+	}
+
+	if config.HomeCacheSize <= 0 {
+		config.HomeCacheSize = defaultHomeCacheSize
+	}
+
+	return &FederatedStore{
+		regions: regions,
+		order:   order,
+		config:  config,
+		homes:   newHomeCache(config.HomeCacheSize),
+	}, nil
+}
+
+// Asset operations
+
+// CreateAsset creates the asset in its home region
+func (f *FederatedStore) CreateAsset(ctx context.Context, asset models.Asset) error {
+	region := f.homeRegion(asset)
+	previous, err := f.previousHome(ctx, asset.GetID())
+	if err != nil {
+		return err
+	}
+	if previous != "" && previous != region {
+		return apperrors.Conflict("asset already exists in region %s: %s", previous, asset.GetID())
+	}
+	if err := f.regions[region].CreateAsset(ctx, asset); err != nil {
+		return fmt.Errorf("region %s: %w", region, err)
+	}
+	f.homes.Store(asset.GetID(), region)
+	return nil
+}
+
+// UpsertAsset creates or updates the asset in its home region, moving it
+// there if its region changed
+func (f *FederatedStore) UpsertAsset(ctx context.Context, asset models.Asset) (bool, error) {
+	region := f.homeRegion(asset)
+	previous, err := f.previousHome(ctx, asset.GetID())
+	if err != nil {
+		return false, err
+	}
+	created, err := f.regions[region].UpsertAsset(ctx, asset)
+	if err != nil {
+		return false, fmt.Errorf("region %s: %w", region, err)
+	}
+	f.homes.Store(asset.GetID(), region)
+	return created, f.leaveRegion(ctx, asset.GetID(), previous, region)
+}
+
+// GetAsset returns the asset from whichever region holds it
+func (f *FederatedStore) GetAsset(ctx context.Context, id string) (models.Asset, error) {
+	region, err := f.regionOf(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return f.regions[region].GetAsset(ctx, id)
+}
+
+// GetAssets merges the assets found in every region
+func (f *FederatedStore) GetAssets(ctx context.Context, ids []string) (map[string]models.Asset, error) {
+	results := make([]map[string]models.Asset, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		assets, err := store.GetAssets(ctx, ids)
+		results[i] = assets
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]models.Asset, len(ids))
+	for _, regionAssets := range results {
+		for id, asset := range regionAssets {
+			merged[id] = asset
+		}
+	}
+	return merged, nil
+}
+
+// UpdateAsset updates the asset in its home region. An asset whose region
+// changed is moved: it is written to its new region and removed from the
+// old one.
+func (f *FederatedStore) UpdateAsset(ctx context.Context, asset models.Asset) error {
+	region := f.homeRegion(asset)
+	previous, err := f.regionOf(ctx, asset.GetID())
+	if err != nil {
+		return err
+	}
+	if previous == region {
+		if err := f.regions[region].UpdateAsset(ctx, asset); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+		return nil
+	}
+
+	if _, err := f.regions[region].UpsertAsset(ctx, asset); err != nil {
+		return fmt.Errorf("region %s: %w", region, err)
+	}
+	f.homes.Store(asset.GetID(), region)
+	return f.leaveRegion(ctx, asset.GetID(), previous, region)
+}
+
+// PatchAsset patches the asset in the region holding it
+func (f *FederatedStore) PatchAsset(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Asset, int64, error) {
+	region, err := f.regionOf(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return f.regions[region].PatchAsset(ctx, id, patch, expectedVersion)
+}
+
+// GetVersion returns the version of a document in the first region holding it
+func (f *FederatedStore) GetVersion(ctx context.Context, id string) (int64, error) {
+	var lastErr error
+	for _, region := range f.order {
+		version, err := f.regions[region].GetVersion(ctx, id)
+		if err == nil {
+			return version, nil
+		}
+		lastErr = fmt.Errorf("region %s: %w", region, err)
+	}
+	return 0, lastErr
+}
+
+// DeleteAsset deletes the asset from every region
+func (f *FederatedStore) DeleteAsset(ctx context.Context, id string) error {
+	defer f.homes.Delete(id)
+	return f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		return store.DeleteAsset(ctx, id)
+	})
+}
+
+// ListAssets lists assets across all regions
+func (f *FederatedStore) ListAssets(ctx context.Context, filter models.AssetFilter) ([]models.Asset, error) {
+	regional := filter
+	if filter.Limit > 0 {
+		regional.Limit = filter.Limit + filter.Offset
+	}
+	regional.Offset = 0
+
+	results := make([][]models.Asset, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		assets, err := store.ListAssets(ctx, regional)
+		results[i] = assets
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paginateAssets(f.mergeAssets(results), filter.Offset, filter.Limit), nil
+}
+
+// CountAssets sums the matching assets of every region. Assets replicated to
+// several regions are counted once per region.
+func (f *FederatedStore) CountAssets(ctx context.Context, filter models.AssetFilter) (int, error) {
+	var mu sync.Mutex
+	total := 0
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		count, err := store.CountAssets(ctx, filter)
+		mu.Lock()
+		total += count
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
+
+// SearchAssets searches assets across all regions
+func (f *FederatedStore) SearchAssets(ctx context.Context, query models.AssetQuery) ([]models.Asset, error) {
+	regional := query
+	if query.Limit > 0 {
+		regional.Limit = query.Limit + query.Offset
+	}
+	regional.Offset = 0
+
+	results := make([][]models.Asset, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		assets, err := store.SearchAssets(ctx, regional)
+		results[i] = assets
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paginateAssets(f.mergeAssets(results), query.Offset, query.Limit), nil
+}
+
+// StreamAssets streams assets from all regions, skipping duplicate IDs
+func (f *FederatedStore) StreamAssets(ctx context.Context, filter models.AssetFilter) (<-chan models.Asset, <-chan error) {
+	assets := make(chan models.Asset, streamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(assets)
+
+		seen := make(map[string]bool)
+		for _, region := range f.order {
+			regionAssets, regionErrs := f.regions[region].StreamAssets(ctx, filter)
+			for asset := range regionAssets {
+				if seen[asset.GetID()] {
+					continue
+				}
+				seen[asset.GetID()] = true
+
+				select {
+				case assets <- asset:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+
+			if err := <-regionErrs; err != nil {
+				if !f.config.AllowPartialResults {
+					errs <- fmt.Errorf("region %s: %w", region, err)
+					return
+				}
+				log.Printf("Skipping region %s in federated stream: %v", region, err)
+			}
+		}
+	}()
+
+	return assets, errs
+}
+
+// Relationship operations
+
+// CreateRelationship creates the relationship in the region holding both
+// of its assets
+func (f *FederatedStore) CreateRelationship(ctx context.Context, rel models.Relationship) error {
+	region, err := f.relationshipRegion(ctx, rel)
+	if err != nil {
+		return err
+	}
+	return f.regions[region].CreateRelationship(ctx, rel)
+}
+
+// GetRelationship returns the relationship from whichever region holds it
+func (f *FederatedStore) GetRelationship(ctx context.Context, id string) (models.Relationship, error) {
+	var mu sync.Mutex
+	var found *models.Relationship
+
+	f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		rel, err := store.GetRelationship(ctx, id)
+		if err != nil {
+			return nil
+		}
+		mu.Lock()
+		if found == nil {
+			found = &rel
+		}
+		mu.Unlock()
+		return nil
+	})
+
+	if found == nil {
+		return models.Relationship{}, apperrors.NotFound("relationship not found: %s", id)
+	}
+	return *found, nil
+}
+
+// UpdateRelationship updates the relationship in the region holding both
+// of its assets
+func (f *FederatedStore) UpdateRelationship(ctx context.Context, rel models.Relationship) error {
+	region, err := f.relationshipRegion(ctx, rel)
+	if err != nil {
+		return err
+	}
+	return f.regions[region].UpdateRelationship(ctx, rel)
+}
+
+// DeleteRelationship deletes the relationship from every region
+func (f *FederatedStore) DeleteRelationship(ctx context.Context, id string) error {
+	return f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		return store.DeleteRelationship(ctx, id)
+	})
+}
+
+// DeleteRelationshipsByFilter deletes matching relationships in every region
+func (f *FederatedStore) DeleteRelationshipsByFilter(ctx context.Context, filter models.RelationshipDeleteFilter) (int, error) {
+	var mu sync.Mutex
+	total := 0
+	err := f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		deleted, err := store.DeleteRelationshipsByFilter(ctx, filter)
+		mu.Lock()
+		total += deleted
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
+
+// CountRelationships sums the matching relationships of every region
+func (f *FederatedStore) CountRelationships(ctx context.Context, filter models.RelationshipFilter) (int, error) {
+	var mu sync.Mutex
+	total := 0
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		count, err := store.CountRelationships(ctx, filter)
+		mu.Lock()
+		total += count
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
+
+// ListRelationships lists relationships across all regions
+func (f *FederatedStore) ListRelationships(ctx context.Context, filter models.RelationshipFilter) ([]models.Relationship, error) {
+	regional := filter
+	if filter.Limit > 0 {
+		regional.Limit = filter.Limit + filter.Offset
+	}
+	regional.Offset = 0
+
+	results := make([][]models.Relationship, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		rels, err := store.ListRelationships(ctx, regional)
+		results[i] = rels
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	merged := mergeRelationships(results)
+	sort.SliceStable(merged, func(i, j int) bool {
+		return models.CursorOf(merged[i]).Before(models.CursorOf(merged[j]))
+	})
+	return paginateRelationships(merged, filter.Offset, filter.Limit), nil
+}
+
+// SearchRelationships searches relationships across all regions
+func (f *FederatedStore) SearchRelationships(ctx context.Context, query models.RelationshipQuery) ([]models.Relationship, error) {
+	results := make([][]models.Relationship, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		rels, err := store.SearchRelationships(ctx, query)
+		results[i] = rels
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rels := mergeRelationships(results)
+	if query.Limit > 0 && len(rels) > query.Limit {
+		rels = rels[:query.Limit]
+	}
+	return rels, nil
+}
+
+// StreamRelationships streams relationships from all regions, skipping duplicate IDs
+func (f *FederatedStore) StreamRelationships(ctx context.Context, filter models.RelationshipFilter) (<-chan models.Relationship, <-chan error) {
+	relationships := make(chan models.Relationship, streamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(relationships)
+
+		seen := make(map[string]bool)
+		for _, region := range f.order {
+			regionRels, regionErrs := f.regions[region].StreamRelationships(ctx, filter)
+			for rel := range regionRels {
+				if seen[rel.ID] {
+					continue
+				}
+				seen[rel.ID] = true
+
+				select {
+				case relationships <- rel:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+
+			if err := <-regionErrs; err != nil {
+				if !f.config.AllowPartialResults {
+					errs <- fmt.Errorf("region %s: %w", region, err)
+					return
+				}
+				log.Printf("Skipping region %s in federated stream: %v", region, err)
+			}
+		}
+	}()
+
+	return relationships, errs
+}
+
+// Graph traversal operations. Regional graphs are disjoint, so traversals run
+// in the region holding the starting asset.
+
+// GetNeighbors retrieves neighbors from the asset's region
+func (f *FederatedStore) GetNeighbors(ctx context.Context, assetID string, direction string, maxDepth int) ([]models.Asset, []models.Relationship, error) {
+	region, err := f.regionOf(ctx, assetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f.regions[region].GetNeighbors(ctx, assetID, direction, maxDepth)
+}
+
+// GetNeighborsByType finds typed neighbors within the asset's region
+func (f *FederatedStore) GetNeighborsByType(ctx context.Context, assetID string, direction string, maxDepth int, relTypes []models.RelationshipType) ([]models.Asset, error) {
+	region, err := f.regionOf(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return f.regions[region].GetNeighborsByType(ctx, assetID, direction, maxDepth, relTypes)
+}
+
+// GetFrontierNeighbors finds the typed neighbors of each asset within its
+// region, with one query per region holding any of the assets
+func (f *FederatedStore) GetFrontierNeighbors(ctx context.Context, assetIDs []string, direction string, relTypes []models.RelationshipType) ([]models.Asset, error) {
+	byRegion := make(map[string][]string)
+	for _, assetID := range assetIDs {
+		region, err := f.regionOf(ctx, assetID)
+		if err != nil {
+			return nil, err
+		}
+		byRegion[region] = append(byRegion[region], assetID)
+	}
+
+	var neighbors []models.Asset
+	for _, region := range f.order {
+		ids, ok := byRegion[region]
+		if !ok {
+			continue
+		}
+		assets, err := f.regions[region].GetFrontierNeighbors(ctx, ids, direction, relTypes)
+		if err != nil {
+			return nil, err
+		}
+		neighbors = append(neighbors, assets...)
+	}
+	return neighbors, nil
+}
+
+// GetNeighborsBatch merges the neighbors found in every region
+func (f *FederatedStore) GetNeighborsBatch(ctx context.Context, assetIDs []string) (map[string][]Neighbor, error) {
+	results := make([]map[string][]Neighbor, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		neighbors, err := store.GetNeighborsBatch(ctx, assetIDs)
+		results[i] = neighbors
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string][]Neighbor, len(assetIDs))
+	for _, regionNeighbors := range results {
+		for assetID, neighbors := range regionNeighbors {
+			merged[assetID] = append(merged[assetID], neighbors...)
+		}
+	}
+	return merged, nil
+}
+
+// FindPath finds a path within the source asset's region
+func (f *FederatedStore) FindPath(ctx context.Context, fromAssetID, toAssetID string, maxDepth int) (*models.GraphPath, error) {
+	region, err := f.regionOf(ctx, fromAssetID)
+	if err != nil {
+		return nil, err
+	}
+	return f.regions[region].FindPath(ctx, fromAssetID, toAssetID, maxDepth)
+}
+
+// FindAttackPaths finds attack paths in every region
+func (f *FederatedStore) FindAttackPaths(ctx context.Context, entryPoints []string, targets []string, maxDepth int) ([]models.GraphPath, error) {
+	results := make([][]models.GraphPath, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		paths, err := store.FindAttackPaths(ctx, entryPoints, targets, maxDepth)
+		results[i] = paths
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []models.GraphPath
+	for _, regionPaths := range results {
+		paths = append(paths, regionPaths...)
+	}
+	return paths, nil
+}
+
+// GetConnectedComponents finds connected components in every region
+func (f *FederatedStore) GetConnectedComponents(ctx context.Context, assetIDs []string) ([][]string, error) {
+	results := make([][][]string, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		components, err := store.GetConnectedComponents(ctx, assetIDs)
+		results[i] = components
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var components [][]string
+	for _, regionComponents := range results {
+		components = append(components, regionComponents...)
+	}
+	return components, nil
+}
+
+// RecomputeInternetExposure recomputes exposure in the asset's region
+func (f *FederatedStore) RecomputeInternetExposure(ctx context.Context, assetID string, maxHops int) ([]string, error) {
+	region, err := f.regionOf(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return f.regions[region].RecomputeInternetExposure(ctx, assetID, maxHops)
+}
+
+// Risk and finding operations
+
+// GetAssetRisk retrieves risk from the asset's region
+func (f *FederatedStore) GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error) {
+	region, err := f.regionOf(ctx, assetID)
+	if err != nil {
+		return models.RiskScore{}, err
+	}
+	return f.regions[region].GetAssetRisk(ctx, assetID)
+}
+
+// GetAssetRisks merges the risk scores found in every region
+func (f *FederatedStore) GetAssetRisks(ctx context.Context, assetIDs []string) (map[string]models.RiskScore, error) {
+	results := make([]map[string]models.RiskScore, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		risks, err := store.GetAssetRisks(ctx, assetIDs)
+		results[i] = risks
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]models.RiskScore, len(assetIDs))
+	for _, regionRisks := range results {
+		for assetID, risk := range regionRisks {
+			merged[assetID] = risk
+		}
+	}
+	return merged, nil
+}
+
+// UpdateAssetRisk updates risk in the asset's region
+func (f *FederatedStore) UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error {
+	region, err := f.regionOf(ctx, risk.AssetID)
+	if err != nil {
+		return err
+	}
+	return f.regions[region].UpdateAssetRisk(ctx, risk)
+}
+
+// BulkUpdateAssetRisk updates risk scores in each asset's region
+func (f *FederatedStore) BulkUpdateAssetRisk(ctx context.Context, risks []models.RiskScore) error {
+	byRegion := make(map[string][]models.RiskScore)
+	for _, risk := range risks {
+		region, err := f.regionOf(ctx, risk.AssetID)
+		if err != nil {
+			return err
+		}
+		byRegion[region] = append(byRegion[region], risk)
+	}
+
+	for region, regionRisks := range byRegion {
+		if err := f.regions[region].BulkUpdateAssetRisk(ctx, regionRisks); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// GetFinding retrieves the finding from the region holding it
+func (f *FederatedStore) GetFinding(ctx context.Context, id string) (models.Finding, error) {
+	for _, region := range f.order {
+		finding, err := f.regions[region].GetFinding(ctx, id)
+		if err == nil {
+			return finding, nil
+		}
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			return models.Finding{}, fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return models.Finding{}, apperrors.NotFound("finding not found: %s", id)
+}
+
+// ListFindings lists findings across all regions, most severe first
+func (f *FederatedStore) ListFindings(ctx context.Context, filter models.FindingFilter) ([]models.Finding, error) {
+	regional := filter
+	if filter.Limit > 0 {
+		regional.Limit = filter.Limit + filter.Offset
+	}
+	regional.Offset = 0
+
+	results := make([][]models.Finding, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		findings, err := store.ListFindings(ctx, regional)
+		results[i] = findings
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var merged []models.Finding
+	for _, regionFindings := range results {
+		for _, finding := range regionFindings {
+			if seen[finding.ID] {
+				continue
+			}
+			seen[finding.ID] = true
+			merged = append(merged, finding)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Severity != merged[j].Severity {
+			return merged[i].Severity > merged[j].Severity
+		}
+		return merged[i].ID < merged[j].ID
+	})
+
+	if filter.Offset >= len(merged) {
+		return []models.Finding{}, nil
+	}
+	merged = merged[filter.Offset:]
+	if filter.Limit > 0 && len(merged) > filter.Limit {
+		merged = merged[:filter.Limit]
+	}
+	return merged, nil
+}
+
+// GetAssetFindings retrieves findings from the asset's region
+func (f *FederatedStore) GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	region, err := f.regionOf(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return f.regions[region].GetAssetFindings(ctx, assetID)
+}
+
+// GetFindingsForAssets merges the findings found in every region
+func (f *FederatedStore) GetFindingsForAssets(ctx context.Context, assetIDs []string) (map[string][]models.Finding, error) {
+	results := make([]map[string][]models.Finding, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		findings, err := store.GetFindingsForAssets(ctx, assetIDs)
+		results[i] = findings
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string][]models.Finding, len(assetIDs))
+	for _, regionFindings := range results {
+		for assetID, findings := range regionFindings {
+			merged[assetID] = append(merged[assetID], findings...)
+		}
+	}
+	return merged, nil
+}
+
+// GetUnresolvedPolicyFindings retrieves policy findings from the asset's region
+func (f *FederatedStore) GetUnresolvedPolicyFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	region, err := f.regionOf(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return f.regions[region].GetUnresolvedPolicyFindings(ctx, assetID)
+}
+
+// CreateFinding creates the finding in its asset's region
+func (f *FederatedStore) CreateFinding(ctx context.Context, finding models.Finding) error {
+	region, err := f.regionOf(ctx, finding.AssetID)
+	if err != nil {
+		return err
+	}
+	return f.regions[region].CreateFinding(ctx, finding)
+}
+
+// UpdateFinding updates the finding in its asset's region
+func (f *FederatedStore) UpdateFinding(ctx context.Context, finding models.Finding) error {
+	region, err := f.regionOf(ctx, finding.AssetID)
+	if err != nil {
+		return err
+	}
+	return f.regions[region].UpdateFinding(ctx, finding)
+}
+
+// GetFindingGroups correlates findings across regions. Regional groups are
+// fetched without a size limit so groups spanning regions are merged first.
+func (f *FederatedStore) GetFindingGroups(ctx context.Context, status string, minSize int) ([]models.FindingGroup, error) {
+	results := make([][]models.FindingGroup, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		groups, err := store.GetFindingGroups(ctx, status, 1)
+		results[i] = groups
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []models.FindingGroup
+	for _, regional := range results {
+		groups = append(groups, regional...)
+	}
+	return models.MergeFindingGroups(groups, minSize), nil
+}
+
+// ResolveFinding resolves the finding in the region holding it
+func (f *FederatedStore) ResolveFinding(ctx context.Context, findingID string) (models.Finding, error) {
+	var lastErr error
+	for _, region := range f.order {
+		finding, err := f.regions[region].ResolveFinding(ctx, findingID)
+		if err == nil {
+			return finding, nil
+		}
+		lastErr = fmt.Errorf("region %s: %w", region, err)
+	}
+	return models.Finding{}, lastErr
+}
+
+// PatchFinding patches the finding in the region holding it
+func (f *FederatedStore) PatchFinding(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Finding, int64, error) {
+	var lastErr error
+	for _, region := range f.order {
+		finding, version, err := f.regions[region].PatchFinding(ctx, id, patch, expectedVersion)
+		if err == nil {
+			return finding, version, nil
+		}
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			return models.Finding{}, 0, fmt.Errorf("region %s: %w", region, err)
+		}
+		lastErr = fmt.Errorf("region %s: %w", region, err)
+	}
+	return models.Finding{}, 0, lastErr
+}
+
+// RecordFindingFeedback records feedback in the region holding the finding.
+// The finding is only reported missing when no region has it.
+func (f *FederatedStore) RecordFindingFeedback(ctx context.Context, feedback models.FindingFeedback) (models.Finding, error) {
+	for _, region := range f.order {
+		finding, err := f.regions[region].RecordFindingFeedback(ctx, feedback)
+		if err == nil {
+			return finding, nil
+		}
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			return models.Finding{}, fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return models.Finding{}, apperrors.NotFound("finding not found: %s", feedback.FindingID)
+}
+
+// GetPolicyAccuracy merges the policy accuracy of all regions
+func (f *FederatedStore) GetPolicyAccuracy(ctx context.Context, policyID string) (*models.PolicyAccuracy, error) {
+	results := make([]*models.PolicyAccuracy, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		accuracy, err := store.GetPolicyAccuracy(ctx, policyID)
+		results[i] = accuracy
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := models.NewPolicyAccuracy(policyID, 0, 0, 0, nil, nil)
+	for _, accuracy := range results {
+		if accuracy != nil {
+			merged.Merge(accuracy)
+		}
+	}
+	return merged, nil
+}
+
+// Analytics and aggregation
+
+// GetRiskSummary merges the risk summaries of all regions
+func (f *FederatedStore) GetRiskSummary(ctx context.Context, filter models.AssetFilter) (*models.RiskSummary, error) {
+	results := make([]*models.RiskSummary, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		summary, err := store.GetRiskSummary(ctx, filter)
+		results[i] = summary
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &models.RiskSummary{
+		AssetsByType:     make(map[models.AssetType]int),
+		AssetsByEnv:      make(map[models.Environment]int),
+		RiskDistribution: make(map[models.RiskLevel]int),
+	}
+
+	totalRisk := 0.0
+	for _, summary := range results {
+		if summary == nil {
+			continue
+		}
+
+		merged.TotalAssets += summary.TotalAssets
+		merged.CriticalFindings += summary.CriticalFindings
+		merged.HighRiskAssets = append(merged.HighRiskAssets, summary.HighRiskAssets...)
+		totalRisk += summary.AverageRisk * float64(summary.TotalAssets)
+
+		for assetType, count := range summary.AssetsByType {
+			merged.AssetsByType[assetType] += count
+		}
+		for env, count := range summary.AssetsByEnv {
+			merged.AssetsByEnv[env] += count
+		}
+		for level, count := range summary.RiskDistribution {
+			merged.RiskDistribution[level] += count
+		}
+		if summary.LastUpdated.After(merged.LastUpdated) {
+			merged.LastUpdated = summary.LastUpdated
+		}
+	}
+
+	if merged.TotalAssets > 0 {
+		merged.AverageRisk = totalRisk / float64(merged.TotalAssets)
+	}
+
+	return merged, nil
+}
+
+// GetRiskTrends retrieves risk trends from the asset's region
+func (f *FederatedStore) GetRiskTrends(ctx context.Context, assetID string, timeRange models.TimeRange) (*models.RiskTrend, error) {
+	region, err := f.regionOf(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return f.regions[region].GetRiskTrends(ctx, assetID, timeRange)
+}
+
+// GetAssetStatistics returns statistics keyed by region
+func (f *FederatedStore) GetAssetStatistics(ctx context.Context) (map[string]interface{}, error) {
+	results := make([]map[string]interface{}, len(f.order))
+	err := f.fanOut(ctx, true, func(i int, store GraphStore) error {
+		stats, err := store.GetAssetStatistics(ctx)
+		results[i] = stats
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	regions := make(map[string]interface{}, len(f.order))
+	for i, region := range f.order {
+		regions[region] = results[i]
+	}
+	return map[string]interface{}{"regions": regions}, nil
+}
+
+// Bulk operations
+
+// BulkCreateAssets creates assets in their home regions
+func (f *FederatedStore) BulkCreateAssets(ctx context.Context, assets []models.Asset) error {
+	for _, asset := range assets {
+		previous, err := f.previousHome(ctx, asset.GetID())
+		if err != nil {
+			return err
+		}
+		if region := f.homeRegion(asset); previous != "" && previous != region {
+			return apperrors.Conflict("asset already exists in region %s: %s", previous, asset.GetID())
+		}
+	}
+
+	byRegion := f.groupAssets(assets)
+	for region, regionAssets := range byRegion {
+		if err := f.regions[region].BulkCreateAssets(ctx, regionAssets); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+		for _, asset := range regionAssets {
+			f.homes.Store(asset.GetID(), region)
+		}
+	}
+	return nil
+}
+
+// BulkUpdateAssets updates assets in their home regions. Assets whose
+// region changed are moved like in UpdateAsset.
+func (f *FederatedStore) BulkUpdateAssets(ctx context.Context, assets []models.Asset) error {
+	var moved []models.Asset
+	previous := make(map[string]string)
+	staying := make([]models.Asset, 0, len(assets))
+	for _, asset := range assets {
+		region, err := f.regionOf(ctx, asset.GetID())
+		if err != nil {
+			return err
+		}
+		if region == f.homeRegion(asset) {
+			staying = append(staying, asset)
+			continue
+		}
+		previous[asset.GetID()] = region
+		moved = append(moved, asset)
+	}
+
+	byRegion := f.groupAssets(staying)
+	for region, regionAssets := range byRegion {
+		if err := f.regions[region].BulkUpdateAssets(ctx, regionAssets); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+
+	for _, asset := range moved {
+		region := f.homeRegion(asset)
+		if _, err := f.regions[region].UpsertAsset(ctx, asset); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+		f.homes.Store(asset.GetID(), region)
+		if err := f.leaveRegion(ctx, asset.GetID(), previous[asset.GetID()], region); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkCreateRelationships creates relationships in their source assets' regions
+func (f *FederatedStore) BulkCreateRelationships(ctx context.Context, relationships []models.Relationship) error {
+	byRegion := make(map[string][]models.Relationship)
+	for _, rel := range relationships {
+		region, err := f.relationshipRegion(ctx, rel)
+		if err != nil {
+			return err
+		}
+		byRegion[region] = append(byRegion[region], rel)
+	}
+
+	for region, regionRels := range byRegion {
+		if err := f.regions[region].BulkCreateRelationships(ctx, regionRels); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// BulkDeleteAssets deletes assets from every region
+func (f *FederatedStore) BulkDeleteAssets(ctx context.Context, assetIDs []string) error {
+	for _, id := range assetIDs {
+		f.homes.Delete(id)
+	}
+	return f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		return store.BulkDeleteAssets(ctx, assetIDs)
+	})
+}
+
+// Backup and restore
+
+// ExportGraph concatenates the snapshots of every region
+func (f *FederatedStore) ExportGraph(ctx context.Context, tenant string) (io.Reader, error) {
+	readers := make([]io.Reader, 0, len(f.order))
+	for _, region := range f.order {
+		r, err := f.regions[region].ExportGraph(ctx, tenant)
+		if err != nil {
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		readers = append(readers, r)
+	}
+	return io.MultiReader(readers...), nil
+}
+
+// ImportGraph is not supported across regions; restore into a regional store
+func (f *FederatedStore) ImportGraph(ctx context.Context, tenant string, r io.Reader, labels []string) (*ImportResult, error) {
+	return nil, fmt.Errorf("graph import must target a regional store")
+}
+
+// Health and maintenance
+
+// ExpireStaleAssets expires stale assets in every region
+func (f *FederatedStore) ExpireStaleAssets(ctx context.Context, ttls map[models.AssetType]time.Duration, decayPeriod time.Duration) (*ExpiryResult, error) {
+	var mu sync.Mutex
+	total := &ExpiryResult{}
+	err := f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		result, err := store.ExpireStaleAssets(ctx, ttls, decayPeriod)
+		if result != nil {
+			mu.Lock()
+			total.Decayed += result.Decayed
+			total.Expired += result.Expired
+			mu.Unlock()
+		}
+		return err
+	})
+	return total, err
+}
+
+// PruneRiskSnapshots prunes risk snapshots in every region
+func (f *FederatedStore) PruneRiskSnapshots(ctx context.Context, before time.Time) (int, error) {
+	var mu sync.Mutex
+	total := 0
+	err := f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		pruned, err := store.PruneRiskSnapshots(ctx, before)
+		mu.Lock()
+		total += pruned
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
+
+// Ping checks connectivity to every region
+func (f *FederatedStore) Ping(ctx context.Context) error {
+	return f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		return store.Ping(ctx)
+	})
+}
+
+// Close closes every regional store
+func (f *FederatedStore) Close() error {
+	var firstErr error
+	for _, region := range f.order {
+		if err := f.regions[region].Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return firstErr
+}
+
+// Helper methods
+
+// fanOut runs fn against every region concurrently. Reads may tolerate
+// unavailable regions when partial results are allowed.
+func (f *FederatedStore) fanOut(ctx context.Context, read bool, fn func(i int, store GraphStore) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(f.order))
+
+	for i, region := range f.order {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			if err := fn(i, f.regions[region]); err != nil {
+				errs[i] = fmt.Errorf("region %s: %w", region, err)
+			}
+		}(i, region)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if read && f.config.AllowPartialResults {
+			log.Printf("Partial federated result: %v", err)
+			continue
+		}
+		return err
+	}
+	return nil
+}
+
+// homeRegion returns the region an asset is written to
+func (f *FederatedStore) homeRegion(asset models.Asset) string {
+	region := assetRegion(asset)
+	if alias, ok := f.config.RegionAliases[region]; ok {
+		region = alias
+	}
+	if _, ok := f.regions[region]; ok {
+		return region
+	}
+	return f.config.DefaultRegion
+}
+
+// regionOf returns the region holding an existing asset. Lookup failures
+// are returned unless the asset is found in a region that did answer.
+func (f *FederatedStore) regionOf(ctx context.Context, assetID string) (string, error) {
+	if region, ok := f.homes.Load(assetID); ok {
+		return region, nil
+	}
+
+	var mu sync.Mutex
+	found := ""
+	err := f.fanOut(ctx, false, func(i int, store GraphStore) error {
+		if _, err := store.GetAsset(ctx, assetID); err != nil {
+			if errors.Is(err, apperrors.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+		mu.Lock()
+		if found == "" || i < f.regionIndex(found) {
+			found = f.order[i]
+		}
+		mu.Unlock()
+		return nil
+	})
+
+	if found == "" {
+		if err != nil {
+			return "", err
+		}
+		return "", apperrors.NotFound("asset not found in any region: %s", assetID)
+	}
+
+	f.homes.Store(assetID, found)
+	return found, nil
+}
+
+// regionIndex returns the position of a region in the region order
+func (f *FederatedStore) regionIndex(region string) int {
+	return sort.SearchStrings(f.order, region)
+}
+
+// previousHome returns the region holding an asset, or "" if no region
+// holds it yet
+func (f *FederatedStore) previousHome(ctx context.Context, assetID string) (string, error) {
+	region, err := f.regionOf(ctx, assetID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return "", nil
+	}
+	return region, err
+}
+
+// leaveRegion removes an asset that moved to region from the region it was
+// stored in before, if any
+func (f *FederatedStore) leaveRegion(ctx context.Context, assetID, previous, region string) error {
+	if previous == "" || previous == region {
+		return nil
+	}
+	if err := f.regions[previous].DeleteAsset(ctx, assetID); err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		return fmt.Errorf("region %s: failed to remove asset %s moved to region %s: %w", previous, assetID, region, err)
+	}
+	log.Printf("Moved asset %s from region %s to %s", assetID, previous, region)
+	return nil
+}
+
+// relationshipRegion returns the region holding both assets of a
+// relationship. Regional graphs are disjoint, so relationships between
+// regions are rejected.
+func (f *FederatedStore) relationshipRegion(ctx context.Context, rel models.Relationship) (string, error) {
+	from, err := f.regionOf(ctx, rel.FromAssetID)
+	if err != nil {
+		return "", err
+	}
+	to, err := f.regionOf(ctx, rel.ToAssetID)
+	if err != nil {
+		return "", err
+	}
+	if from != to {
+		return "", apperrors.Invalid("relationship from %s in region %s to %s in region %s crosses regions",
+			rel.FromAssetID, from, rel.ToAssetID, to)
+	}
+	return from, nil
+}
+
+// groupAssets groups assets by home region
+func (f *FederatedStore) groupAssets(assets []models.Asset) map[string][]models.Asset {
+	byRegion := make(map[string][]models.Asset)
+	for _, asset := range assets {
+		region := f.homeRegion(asset)
+		byRegion[region] = append(byRegion[region], asset)
+	}
+	return byRegion
+}
+
+// mergeAssets concatenates regional results in region order, dropping duplicate IDs
+func (f *FederatedStore) mergeAssets(results [][]models.Asset) []models.Asset {
+	seen := make(map[string]bool)
+	var merged []models.Asset
+	for i, regionAssets := range results {
+		for _, asset := range regionAssets {
+			if seen[asset.GetID()] {
+				continue
+			}
+			seen[asset.GetID()] = true
+			f.homes.Store(asset.GetID(), f.order[i])
+			merged = append(merged, asset)
+		}
+	}
+	return merged
+}
+
+// mergeRelationships concatenates regional results, dropping duplicate IDs
+func mergeRelationships(results [][]models.Relationship) []models.Relationship {
+	seen := make(map[string]bool)
+	var merged []models.Relationship
+	for _, regionRels := range results {
+		for _, rel := range regionRels {
+			if seen[rel.ID] {
+				continue
+			}
+			seen[rel.ID] = true
+			merged = append(merged, rel)
+		}
+	}
+	return merged
+}
+
+// paginateAssets applies offset and limit to a merged result
+func paginateAssets(assets []models.Asset, offset, limit int) []models.Asset {
+	if offset >= len(assets) {
+		return nil
+	}
+	assets = assets[offset:]
+	if limit > 0 && len(assets) > limit {
+		assets = assets[:limit]
+	}
+	return assets
+}
+
+// paginateRelationships applies offset and limit to a merged result
+func paginateRelationships(rels []models.Relationship, offset, limit int) []models.Relationship {
+	if offset >= len(rels) {
+		return nil
+	}
+	rels = rels[offset:]
+	if limit > 0 && len(rels) > limit {
+		rels = rels[:limit]
+	}
+	return rels
+}
+
+// assetRegion returns the cloud region an asset lives in, if known
+func assetRegion(asset models.Asset) string {
+	switch a := asset.(type) {
+	case *models.Compute:
+		if a.Region != "" {
+			return a.Region
+		}
+	case *models.Data:
+		if a.Region != "" {
+			return a.Region
+		}
+	}
+	return asset.GetBaseAsset().Tags["region"]
+}
+
+// homeCache remembers the home regions of recently used assets, evicting
+// the least recently used once it is full
+type homeCache struct {
+	mu      sync.Mutex
+	maxSize int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type assetHome struct {
+	assetID string
+	region  string
+}
+
+func newHomeCache(maxSize int) *homeCache {
+	return &homeCache{
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Load returns the home region of an asset
+func (c *homeCache) Load(assetID string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[assetID]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToBack(elem)
+	return elem.Value.(*assetHome).region, true
+}
+
+// Store records the home region of an asset
+func (c *homeCache) Store(assetID, region string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[assetID]; ok {
+		elem.Value.(*assetHome).region = region
+		c.order.MoveToBack(elem)
+		return
+	}
+	c.entries[assetID] = c.order.PushBack(&assetHome{assetID: assetID, region: region})
+
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*assetHome).assetID)
+	}
+}
+
+// Delete forgets the home region of an asset
+func (c *homeCache) Delete(assetID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[assetID]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, assetID)
+	}
+}
@@ -0,0 +1,183 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// regionStore is an in-memory regional store implementing the asset,
+// relationship and finding feedback writes the federation tests use
+type regionStore struct {
+	GraphStore
+	mu       sync.Mutex
+	assets   map[string]models.Asset
+	rels     []models.Relationship
+	findings map[string]models.Finding
+	lookErr  error
+}
+
+func newRegionStore() *regionStore {
+	return &regionStore{assets: make(map[string]models.Asset)}
+}
+
+func (r *regionStore) GetAsset(ctx context.Context, id string) (models.Asset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lookErr != nil {
+		return nil, r.lookErr
+	}
+	asset, ok := r.assets[id]
+	if !ok {
+		return nil, apperrors.NotFound("asset not found: %s", id)
+	}
+	return asset, nil
+}
+
+func (r *regionStore) UpsertAsset(ctx context.Context, asset models.Asset) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, exists := r.assets[asset.GetID()]
+	r.assets[asset.GetID()] = asset
+	return !exists, nil
+}
+
+func (r *regionStore) UpdateAsset(ctx context.Context, asset models.Asset) error {
+	_, err := r.UpsertAsset(ctx, asset)
+	return err
+}
+
+func (r *regionStore) DeleteAsset(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.assets, id)
+	return nil
+}
+
+func (r *regionStore) CreateRelationship(ctx context.Context, rel models.Relationship) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rels = append(r.rels, rel)
+	return nil
+}
+
+func (r *regionStore) RecordFindingFeedback(ctx context.Context, feedback models.FindingFeedback) (models.Finding, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lookErr != nil {
+		return models.Finding{}, r.lookErr
+	}
+	finding, ok := r.findings[feedback.FindingID]
+	if !ok {
+		return models.Finding{}, apperrors.NotFound("finding not found: %s", feedback.FindingID)
+	}
+	applyFeedback(&finding, feedback)
+	r.findings[feedback.FindingID] = finding
+	return finding, nil
+}
+
+func computeIn(id, region string) *models.Compute {
+	asset := &models.Compute{}
+	asset.ID = id
+	asset.Type = models.AssetTypeCompute
+	asset.Region = region
+	return asset
+}
+
+func newTestFederation(t *testing.T) (*FederatedStore, *regionStore, *regionStore) {
+	t.Helper()
+	eu, us := newRegionStore(), newRegionStore()
+	f, err := NewFederatedStore(map[string]GraphStore{"eu": eu, "us": us}, FederationConfig{DefaultRegion: "us"})
+	if err != nil {
+		t.Fatalf("NewFederatedStore: %v", err)
+	}
+	return f, eu, us
+}
+
+func TestFederatedStoreMovesAssetWhenRegionChanges(t *testing.T) {
+	f, eu, us := newTestFederation(t)
+	ctx := context.Background()
+
+	if _, err := f.UpsertAsset(ctx, computeIn("vm-1", "eu")); err != nil {
+		t.Fatalf("UpsertAsset: %v", err)
+	}
+	if err := f.UpdateAsset(ctx, computeIn("vm-1", "us")); err != nil {
+		t.Fatalf("UpdateAsset: %v", err)
+	}
+
+	if _, ok := eu.assets["vm-1"]; ok {
+		t.Error("asset left behind in its old region")
+	}
+	if _, ok := us.assets["vm-1"]; !ok {
+		t.Error("asset not written to its new region")
+	}
+	if region, _ := f.regionOf(ctx, "vm-1"); region != "us" {
+		t.Errorf("regionOf = %q, want us", region)
+	}
+}
+
+func TestFederatedStoreRejectsCrossRegionRelationships(t *testing.T) {
+	f, eu, us := newTestFederation(t)
+	ctx := context.Background()
+	eu.assets["vm-1"] = computeIn("vm-1", "eu")
+	us.assets["vm-2"] = computeIn("vm-2", "us")
+
+	err := f.CreateRelationship(ctx, models.Relationship{FromAssetID: "vm-1", ToAssetID: "vm-2", Type: models.RelationshipConnectedTo})
+	if apperrors.CodeOf(err) != apperrors.CodeInvalidRequest {
+		t.Fatalf("CreateRelationship error = %v, want an invalid request", err)
+	}
+	if len(eu.rels)+len(us.rels) != 0 {
+		t.Error("cross-region relationship was written")
+	}
+}
+
+func TestFederatedStoreRegionOfReturnsLookupErrors(t *testing.T) {
+	f, eu, _ := newTestFederation(t)
+	eu.lookErr = fmt.Errorf("connection refused")
+
+	_, err := f.regionOf(context.Background(), "vm-1")
+	if err == nil || errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("regionOf error = %v, want the lookup failure", err)
+	}
+}
+
+func TestHomeCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newHomeCache(2)
+	c.Store("a", "eu")
+	c.Store("b", "eu")
+	c.Load("a")
+	c.Store("c", "us")
+
+	if _, ok := c.Load("b"); ok {
+		t.Error("least recently used entry was kept")
+	}
+	if region, ok := c.Load("a"); !ok || region != "eu" {
+		t.Errorf("Load(a) = %q, %v, want eu, true", region, ok)
+	}
+}
+
+func TestFederatedStoreFindingFeedbackNotFound(t *testing.T) {
+	f, eu, us := newTestFederation(t)
+	ctx := context.Background()
+	us.findings = map[string]models.Finding{"f-1": {Status: "open"}}
+
+	feedback := models.FindingFeedback{FindingID: "f-1", Verdict: models.FeedbackTruePositive}
+	if _, err := f.RecordFindingFeedback(ctx, feedback); err != nil {
+		t.Fatalf("RecordFindingFeedback: %v", err)
+	}
+
+	feedback.FindingID = "f-2"
+	if _, err := f.RecordFindingFeedback(ctx, feedback); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("RecordFindingFeedback of a missing finding = %v, want not found", err)
+	}
+
+	eu.lookErr = fmt.Errorf("connection refused")
+	if _, err := f.RecordFindingFeedback(ctx, feedback); err == nil || errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("RecordFindingFeedback error = %v, want the region failure", err)
+	}
+}
@@ -0,0 +1,213 @@
+//go:build integration
+
+package graph
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/models"
+)
+
+// integrationStore connects to the Neo4j database named by NEO4J_TEST_URI,
+// skipping the test when it is not set. Tests must clean up what they write.
+// Run with make test-integration.
+func integrationStore(t *testing.T) *Neo4jStore {
+	t.Helper()
+
+	uri := os.Getenv("NEO4J_TEST_URI")
+	if uri == "" {
+		t.Skip("NEO4J_TEST_URI not set")
+	}
+
+	config := DefaultGraphConfig()
+	config.URI = uri
+	config.Username = os.Getenv("NEO4J_TEST_USERNAME")
+	config.Password = os.Getenv("NEO4J_TEST_PASSWORD")
+	if config.Username == "" {
+		config.Username = "neo4j"
+	}
+	config.MigrateOnStartup = false
+
+	store, err := NewNeo4jStore(config)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", uri, err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestRelationshipRevokeRegrantCycle(t *testing.T) {
+	store := integrationStore(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	role := &models.Identity{BaseAsset: models.NewBaseAsset(models.ProviderAWS, models.AssetTypeIdentity, models.EnvironmentDevelopment, "test-role")}
+	bucket := &models.Data{BaseAsset: models.NewBaseAsset(models.ProviderAWS, models.AssetTypeData, models.EnvironmentDevelopment, "test-bucket")}
+	for _, asset := range []models.Asset{role, bucket} {
+		if err := store.CreateAsset(ctx, asset); err != nil {
+			t.Fatalf("CreateAsset(%s) returned error: %v", asset.GetID(), err)
+		}
+		id := asset.GetID()
+		t.Cleanup(func() { store.DeleteAsset(context.Background(), id) })
+	}
+
+	rel := models.NewRelationship(role.ID, bucket.ID, models.RelationshipHasAccessTo)
+	grant := func() models.Relationship {
+		t.Helper()
+		if err := store.CreateRelationship(ctx, rel); err != nil {
+			t.Fatalf("CreateRelationship returned error: %v", err)
+		}
+		got, err := store.GetRelationship(ctx, rel.ID)
+		if err != nil {
+			t.Fatalf("GetRelationship returned error: %v", err)
+		}
+		return got
+	}
+	revoke := func() {
+		t.Helper()
+		filter := models.RelationshipDeleteFilter{RelationshipFilter: models.RelationshipFilter{
+			AssetIDs: []string{role.ID},
+			Types:    []models.RelationshipType{models.RelationshipHasAccessTo},
+		}}
+		if deleted, err := store.DeleteRelationshipsByFilter(ctx, filter); err != nil || deleted != 1 {
+			t.Fatalf("DeleteRelationshipsByFilter = %d, %v, want 1 soft delete", deleted, err)
+		}
+	}
+
+	if got := grant(); got.ValidTo != nil || got.ReactivationCount != 0 {
+		t.Fatalf("new relationship: valid_to = %v, reactivations = %d", got.ValidTo, got.ReactivationCount)
+	}
+
+	for cycle := 1; cycle <= 2; cycle++ {
+		revoke()
+		revoked, err := store.GetRelationship(ctx, rel.ID)
+		if err != nil {
+			t.Fatalf("GetRelationship after revoke returned error: %v", err)
+		}
+		if revoked.ValidTo == nil {
+			t.Fatalf("cycle %d: revoked relationship has no valid_to", cycle)
+		}
+
+		got := grant()
+		if got.ValidTo != nil {
+			t.Errorf("cycle %d: regranted relationship still closed at %v", cycle, got.ValidTo)
+		}
+		if got.ReactivationCount != cycle || got.ReactivatedAt == nil {
+			t.Errorf("cycle %d: reactivations = %d, reactivated at %v", cycle, got.ReactivationCount, got.ReactivatedAt)
+		}
+		if got.LastRevokedAt == nil || !got.LastRevokedAt.Equal(*revoked.ValidTo) {
+			t.Errorf("cycle %d: last revoked at %v, want %v", cycle, got.LastRevokedAt, revoked.ValidTo)
+		}
+	}
+
+	edges, err := store.ListRelationships(ctx, models.RelationshipFilter{AssetIDs: []string{role.ID}})
+	if err != nil {
+		t.Fatalf("ListRelationships returned error: %v", err)
+	}
+	if len(edges) != 1 {
+		t.Errorf("relationships after two cycles = %d, want the one edge reopened", len(edges))
+	}
+}
+
+func TestRiskSnapshotsAreBucketedPerDayAndDeletedWithAsset(t *testing.T) {
+	store := integrationStore(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	vm := &models.Compute{BaseAsset: models.NewBaseAsset(models.ProviderAWS, models.AssetTypeCompute, models.EnvironmentDevelopment, "test-vm")}
+	if err := store.CreateAsset(ctx, vm); err != nil {
+		t.Fatalf("CreateAsset returned error: %v", err)
+	}
+	t.Cleanup(func() { store.DeleteAsset(context.Background(), vm.ID) })
+
+	for _, score := range []float64{20, 60, 40} {
+		if err := store.UpdateAssetRisk(ctx, models.RiskScore{AssetID: vm.ID, Score: score}); err != nil {
+			t.Fatalf("UpdateAssetRisk returned error: %v", err)
+		}
+	}
+
+	snapshots := func() int64 {
+		t.Helper()
+		total, err := store.count(ctx, "MATCH (s:RiskSnapshot {asset_id: $id}) RETURN count(s) as total", map[string]interface{}{"id": vm.ID})
+		if err != nil {
+			t.Fatalf("counting snapshots returned error: %v", err)
+		}
+		return int64(total)
+	}
+	if got := snapshots(); got != 1 {
+		t.Errorf("snapshots after three updates in a day = %d, want 1", got)
+	}
+
+	trend, err := store.GetRiskTrends(ctx, vm.ID, models.TimeRange{Start: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("GetRiskTrends returned error: %v", err)
+	}
+	if len(trend.Scores) != 1 {
+		t.Fatalf("trend has %d points, want 1", len(trend.Scores))
+	}
+	if point := trend.Scores[0]; point.Score != 40 || point.Min != 20 || point.Max != 60 || point.Samples != 3 {
+		t.Errorf("trend point = %+v, want average 40 between 20 and 60 over 3 samples", point)
+	}
+
+	if err := store.DeleteAsset(ctx, vm.ID); err != nil {
+		t.Fatalf("DeleteAsset returned error: %v", err)
+	}
+	if got := snapshots(); got != 0 {
+		t.Errorf("snapshots after deleting the asset = %d, want 0", got)
+	}
+}
+
+func TestGetNeighborsSkipsFindings(t *testing.T) {
+	store := integrationStore(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	vm := &models.Compute{BaseAsset: models.NewBaseAsset(models.ProviderAWS, models.AssetTypeCompute, models.EnvironmentDevelopment, "test-vm")}
+	bucket := &models.Data{BaseAsset: models.NewBaseAsset(models.ProviderAWS, models.AssetTypeData, models.EnvironmentDevelopment, "test-bucket")}
+	for _, asset := range []models.Asset{vm, bucket} {
+		if err := store.CreateAsset(ctx, asset); err != nil {
+			t.Fatalf("CreateAsset(%s) returned error: %v", asset.GetID(), err)
+		}
+		id := asset.GetID()
+		t.Cleanup(func() { store.DeleteAsset(context.Background(), id) })
+	}
+	if err := store.CreateRelationship(ctx, models.NewRelationship(vm.ID, bucket.ID, models.RelationshipHasAccessTo)); err != nil {
+		t.Fatalf("CreateRelationship returned error: %v", err)
+	}
+
+	finding := models.Finding{
+		BaseAsset: models.NewBaseAsset(models.ProviderAWS, models.AssetTypeFinding, models.EnvironmentDevelopment, "test-finding"),
+		AssetID:   vm.ID,
+		Severity:  7,
+		Status:    "open",
+	}
+	if err := store.CreateFinding(ctx, finding); err != nil {
+		t.Fatalf("CreateFinding returned error: %v", err)
+	}
+	t.Cleanup(func() {
+		session := store.driver.NewSession(context.Background(), neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+		defer session.Close(context.Background())
+		session.Run(context.Background(), "MATCH (f:Finding {id: $id}) DETACH DELETE f", map[string]interface{}{"id": finding.ID})
+	})
+
+	assets, rels, err := store.GetNeighbors(ctx, vm.ID, "both", 2)
+	if err != nil {
+		t.Fatalf("GetNeighbors returned error: %v", err)
+	}
+	if len(assets) != 1 || assets[0].GetID() != bucket.ID {
+		ids := make([]string, 0, len(assets))
+		for _, asset := range assets {
+			ids = append(ids, asset.GetID())
+		}
+		t.Errorf("neighbors = %v, want only the bucket %s", ids, bucket.ID)
+	}
+	for _, rel := range rels {
+		if rel.Type == models.RelationshipGenerates {
+			t.Errorf("neighbor relationships include the finding edge %+v", rel)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package graph
 
 import (
 	"context"
+	"io"
 	"time"
+	"github.com/securizon/pkg/apperrors"
 	"github.com/securizon/pkg/models"
 )
 
@@ -10,32 +12,56 @@ import (
 type GraphStore interface {
 	// Asset operations
 	CreateAsset(ctx context.Context, asset models.Asset) error
+	UpsertAsset(ctx context.Context, asset models.Asset) (bool, error)
 	GetAsset(ctx context.Context, id string) (models.Asset, error)
+	GetAssets(ctx context.Context, ids []string) (map[string]models.Asset, error)
 	UpdateAsset(ctx context.Context, asset models.Asset) error
 	DeleteAsset(ctx context.Context, id string) error
+	PatchAsset(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Asset, int64, error)
+	GetVersion(ctx context.Context, id string) (int64, error)
 	ListAssets(ctx context.Context, filter models.AssetFilter) ([]models.Asset, error)
+	CountAssets(ctx context.Context, filter models.AssetFilter) (int, error)
 	SearchAssets(ctx context.Context, query models.AssetQuery) ([]models.Asset, error)
+	StreamAssets(ctx context.Context, filter models.AssetFilter) (<-chan models.Asset, <-chan error)
 	
 	// Relationship operations
 	CreateRelationship(ctx context.Context, rel models.Relationship) error
 	GetRelationship(ctx context.Context, id string) (models.Relationship, error)
 	UpdateRelationship(ctx context.Context, rel models.Relationship) error
 	DeleteRelationship(ctx context.Context, id string) error
+	DeleteRelationshipsByFilter(ctx context.Context, filter models.RelationshipDeleteFilter) (int, error)
 	ListRelationships(ctx context.Context, filter models.RelationshipFilter) ([]models.Relationship, error)
+	CountRelationships(ctx context.Context, filter models.RelationshipFilter) (int, error)
 	SearchRelationships(ctx context.Context, query models.RelationshipQuery) ([]models.Relationship, error)
+	StreamRelationships(ctx context.Context, filter models.RelationshipFilter) (<-chan models.Relationship, <-chan error)
 	
 	// Graph traversal operations
 	GetNeighbors(ctx context.Context, assetID string, direction string, maxDepth int) ([]models.Asset, []models.Relationship, error)
+	GetNeighborsByType(ctx context.Context, assetID string, direction string, maxDepth int, relTypes []models.RelationshipType) ([]models.Asset, error)
+	GetFrontierNeighbors(ctx context.Context, assetIDs []string, direction string, relTypes []models.RelationshipType) ([]models.Asset, error)
+	GetNeighborsBatch(ctx context.Context, assetIDs []string) (map[string][]Neighbor, error)
 	FindPath(ctx context.Context, fromAssetID, toAssetID string, maxDepth int) (*models.GraphPath, error)
 	FindAttackPaths(ctx context.Context, entryPoints []string, targets []string, maxDepth int) ([]models.GraphPath, error)
 	GetConnectedComponents(ctx context.Context, assetIDs []string) ([][]string, error)
+	RecomputeInternetExposure(ctx context.Context, assetID string, maxHops int) ([]string, error)
 	
 	// Risk and finding operations
 	GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error)
+	GetAssetRisks(ctx context.Context, assetIDs []string) (map[string]models.RiskScore, error)
 	UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error
+	BulkUpdateAssetRisk(ctx context.Context, risks []models.RiskScore) error
 	GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error)
+	GetFindingsForAssets(ctx context.Context, assetIDs []string) (map[string][]models.Finding, error)
+	GetUnresolvedPolicyFindings(ctx context.Context, assetID string) ([]models.Finding, error)
+	GetFinding(ctx context.Context, id string) (models.Finding, error)
+	ListFindings(ctx context.Context, filter models.FindingFilter) ([]models.Finding, error)
 	CreateFinding(ctx context.Context, finding models.Finding) error
 	UpdateFinding(ctx context.Context, finding models.Finding) error
+	PatchFinding(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Finding, int64, error)
+	ResolveFinding(ctx context.Context, findingID string) (models.Finding, error)
+	GetFindingGroups(ctx context.Context, status string, minSize int) ([]models.FindingGroup, error)
+	RecordFindingFeedback(ctx context.Context, feedback models.FindingFeedback) (models.Finding, error)
+	GetPolicyAccuracy(ctx context.Context, policyID string) (*models.PolicyAccuracy, error)
 	
 	// Analytics and aggregation
 	GetRiskSummary(ctx context.Context, filter models.AssetFilter) (*models.RiskSummary, error)
@@ -48,34 +74,91 @@ type GraphStore interface {
 	BulkCreateRelationships(ctx context.Context, relationships []models.Relationship) error
 	BulkDeleteAssets(ctx context.Context, assetIDs []string) error
 	
+	// Backup and restore
+	ExportGraph(ctx context.Context, tenant string) (io.Reader, error)
+	ImportGraph(ctx context.Context, tenant string, r io.Reader, labels []string) (*ImportResult, error)
+	
 	// Health and maintenance
+	ExpireStaleAssets(ctx context.Context, ttls map[models.AssetType]time.Duration, decayPeriod time.Duration) (*ExpiryResult, error)
+	PruneRiskSnapshots(ctx context.Context, before time.Time) (int, error)
 	Ping(ctx context.Context) error
 	Close() error
 }
 
+// Optional capabilities of a GraphStore, forwarded by the stores that wrap
+// another store
+
+type assetAutocompleter interface {
+	AutocompleteAssets(ctx context.Context, prefix string, limit int) ([]AssetSuggestion, error)
+}
+
+type featureExporter interface {
+	ExportFeatures(ctx context.Context) (*FeatureSet, error)
+}
+
+type schemaVersioner interface {
+	SchemaStatus(ctx context.Context) (SchemaMigrationStatus, error)
+}
+
+type assetReencoder interface {
+	ReencodeAssets(ctx context.Context) (int, error)
+}
+
+// notSupported is the error of a wrapping store whose wrapped store lacks an
+// optional capability
+func notSupported(capability string) error {
+	return apperrors.New(apperrors.CodeNotImplemented, "%s is not supported", capability)
+}
+
 // GraphConfig represents graph database configuration
 type GraphConfig struct {
-	URI          string        `json:"uri"`
-	Database     string        `json:"database"`
-	Username     string        `json:"username"`
-	Password     string        `json:"password"`
-	MaxPoolSize  int           `json:"max_pool_size"`
-	MaxIdleConns int           `json:"max_idle_conns"`
-	ConnTimeout  time.Duration `json:"conn_timeout"`
-	ReadTimeout  time.Duration `json:"read_timeout"`
-	WriteTimeout time.Duration `json:"write_timeout"`
+	URI          string        `json:"uri" yaml:"uri"`
+	Database     string        `json:"database" yaml:"database"`
+	Username     string        `json:"username" yaml:"username"`
+	Password     string        `json:"password" yaml:"password"`
+	MaxPoolSize  int           `json:"max_pool_size" yaml:"max_pool_size"`
+	MaxIdleConns int           `json:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnTimeout  time.Duration `json:"conn_timeout" yaml:"conn_timeout"`
+	ReadTimeout  time.Duration `json:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout"`
+	// QueryTimeout is the server-side transaction timeout for interactive
+	// queries. 0 leaves the server default in place.
+	QueryTimeout time.Duration `json:"query_timeout" yaml:"query_timeout"`
+	// MaxResults caps the rows list queries return to API requests. 0 means
+	// no cap.
+	MaxResults int `json:"max_results" yaml:"max_results"`
+	// MigrateOnStartup applies pending schema migrations when the store is
+	// created, waiting up to MigrationTimeout for them and for the lock
+	MigrateOnStartup bool          `json:"migrate_on_startup" yaml:"migrate_on_startup"`
+	MigrationTimeout time.Duration `json:"migration_timeout" yaml:"migration_timeout"`
+	// DataEncoding is how asset documents are stored: json or gzip.
+	// Documents in either encoding are read, so it can be changed on a
+	// live graph and existing nodes rewritten with ReencodeAssets.
+	DataEncoding string `json:"data_encoding" yaml:"data_encoding"`
+	// BulkBatchSize is the number of assets written per transaction by bulk
+	// writes
+	BulkBatchSize int `json:"bulk_batch_size" yaml:"bulk_batch_size"`
+	// TenantDatabases are the databases a graph snapshot may be exported
+	// from or restored into by tenant, besides the default Database
+	TenantDatabases []string `json:"tenant_databases" yaml:"tenant_databases"`
 }
 
 // DefaultGraphConfig returns default graph configuration
 func DefaultGraphConfig() GraphConfig {
 	return GraphConfig{
-		URI:          "bolt://localhost:7687",
-		Database:     "neo4j",
-		MaxPoolSize:  50,
-		MaxIdleConns: 10,
-		ConnTimeout:  30 * time.Second,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		URI:              "bolt://localhost:7687",
+		Database:         "neo4j",
+		MaxPoolSize:      50,
+		MaxIdleConns:     10,
+		ConnTimeout:      30 * time.Second,
+		ReadTimeout:      30 * time.Second,
+		WriteTimeout:     30 * time.Second,
+		QueryTimeout:     30 * time.Second,
+		MaxResults:       10000,
+		MigrateOnStartup: true,
+		MigrationTimeout: 30 * time.Minute,
+		DataEncoding:     DataEncodingJSON,
+		BulkBatchSize:    500,
 	}
 }
 
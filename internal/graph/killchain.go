@@ -0,0 +1,151 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/securizon/pkg/models"
+)
+
+// MITRE ATT&CK tactics that attack path hops are mapped to
+const (
+	TacticInitialAccess       = "initial-access"
+	TacticPrivilegeEscalation = "privilege-escalation"
+	TacticLateralMovement     = "lateral-movement"
+	TacticExfiltration        = "exfiltration"
+)
+
+// tacticIDs are the ATT&CK identifiers of the tactics
+var tacticIDs = map[string]string{
+	TacticInitialAccess:       "TA0001",
+	TacticPrivilegeEscalation: "TA0004",
+	TacticLateralMovement:     "TA0008",
+	TacticExfiltration:        "TA0010",
+}
+
+// AttackTechnique is a MITRE ATT&CK technique
+type AttackTechnique struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// TechniqueMapping is the tactic and techniques an attacker uses to traverse
+// one relationship type
+type TechniqueMapping struct {
+	Tactic     string            `json:"tactic" yaml:"tactic"`
+	Techniques []AttackTechnique `json:"techniques" yaml:"techniques"`
+}
+
+// KillChainStep annotates one hop of an attack path with the ATT&CK tactic
+// and techniques it represents
+type KillChainStep struct {
+	Hop          int               `json:"hop"`
+	FromID       string            `json:"from_id"`
+	ToID         string            `json:"to_id"`
+	Relationship string            `json:"relationship"`
+	Tactic       string            `json:"tactic"`
+	TacticID     string            `json:"tactic_id"`
+	Techniques   []AttackTechnique `json:"techniques"`
+}
+
+// DefaultTechniqueMapping returns the ATT&CK mapping of each relationship
+// type traversed by attack paths
+func DefaultTechniqueMapping() map[string]TechniqueMapping {
+	return map[string]TechniqueMapping{
+		string(models.RelationshipAssumesRole): {
+			Tactic:     TacticPrivilegeEscalation,
+			Techniques: []AttackTechnique{{ID: "T1078.004", Name: "Valid Accounts: Cloud Accounts"}},
+		},
+		string(models.RelationshipManages): {
+			Tactic:     TacticPrivilegeEscalation,
+			Techniques: []AttackTechnique{{ID: "T1098", Name: "Account Manipulation"}},
+		},
+		string(models.RelationshipHasAccessTo): {
+			Tactic:     TacticLateralMovement,
+			Techniques: []AttackTechnique{{ID: "T1550.001", Name: "Use Alternate Authentication Material: Application Access Token"}},
+		},
+		string(models.RelationshipConnectedTo): {
+			Tactic:     TacticLateralMovement,
+			Techniques: []AttackTechnique{{ID: "T1021", Name: "Remote Services"}},
+		},
+		string(models.RelationshipRunsOn): {
+			Tactic:     TacticPrivilegeEscalation,
+			Techniques: []AttackTechnique{{ID: "T1611", Name: "Escape to Host"}},
+		},
+		string(models.RelationshipDependsOn): {
+			Tactic:     TacticLateralMovement,
+			Techniques: []AttackTechnique{{ID: "T1210", Name: "Exploitation of Remote Services"}},
+		},
+		string(models.RelationshipStores): {
+			Tactic:     TacticExfiltration,
+			Techniques: []AttackTechnique{{ID: "T1537", Name: "Transfer Data to Cloud Account"}},
+		},
+	}
+}
+
+// Techniques of a first hop whose relationship type has no mapping, by the
+// type of the asset the path enters through
+var (
+	initialAccessTechniques = map[string][]AttackTechnique{
+		string(models.AssetTypeIdentity): {{ID: "T1078.004", Name: "Valid Accounts: Cloud Accounts"}},
+	}
+	defaultInitialAccessTechniques = []AttackTechnique{{ID: "T1190", Name: "Exploit Public-Facing Application"}}
+)
+
+// ValidateTechniqueMapping checks that every mapping names a known tactic
+func ValidateTechniqueMapping(mapping map[string]TechniqueMapping) error {
+	for relType, m := range mapping {
+		if relType == "" {
+			return fmt.Errorf("technique mapping has an empty relationship type")
+		}
+		if _, ok := tacticIDs[m.Tactic]; !ok {
+			return fmt.Errorf("relationship type %s: unknown tactic %q", relType, m.Tactic)
+		}
+	}
+	return nil
+}
+
+// AnnotateKillChain maps each hop of path to an ATT&CK tactic. relTypes are
+// the relationship types of the hops in order. Each hop takes the tactic and
+// techniques of its relationship type from mapping; a first hop over an
+// unmapped relationship is initial access into the environment and later
+// ones are lateral movement. Paths whose hops cannot be matched to relTypes
+// are left unannotated.
+func AnnotateKillChain(path *AttackPath, relTypes []string, mapping map[string]TechniqueMapping) {
+	if len(path.Path) < 2 || len(relTypes) != len(path.Path)-1 {
+		return
+	}
+
+	chain := make([]KillChainStep, 0, len(relTypes))
+	for i, relType := range relTypes {
+		from, to := path.Path[i], path.Path[i+1]
+
+		step := KillChainStep{
+			Hop:          i + 1,
+			FromID:       from.ID,
+			ToID:         to.ID,
+			Relationship: relType,
+		}
+
+		if m, ok := mapping[relType]; ok {
+			step.Tactic = m.Tactic
+			step.Techniques = m.Techniques
+		} else if i == 0 {
+			step.Tactic = TacticInitialAccess
+			step.Techniques = defaultInitialAccessTechniques
+			if techniques, ok := initialAccessTechniques[strings.ToLower(from.Type)]; ok {
+				step.Techniques = techniques
+			}
+		} else {
+			step.Tactic = TacticLateralMovement
+		}
+		step.TacticID = tacticIDs[step.Tactic]
+		if step.Techniques == nil {
+			step.Techniques = []AttackTechnique{}
+		}
+
+		chain = append(chain, step)
+	}
+
+	path.KillChain = chain
+}
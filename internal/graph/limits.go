@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// resultInfoKey is the context key holding a ResultInfo
+type resultInfoKey struct{}
+
+// ResultInfo reports whether list queries executed with a context returned by
+// WithResultInfo hit the configured result cap
+type ResultInfo struct {
+	Truncated bool `json:"truncated"`
+	Limit     int  `json:"limit,omitempty"`
+	mu        sync.Mutex
+}
+
+// WithResultInfo returns a context that records whether list queries executed
+// with it were truncated
+func WithResultInfo(ctx context.Context) (context.Context, *ResultInfo) {
+	info := &ResultInfo{}
+	return context.WithValue(ctx, resultInfoKey{}, info), info
+}
+
+// resultInfoFrom returns the result info collected for ctx, or nil when the
+// caller did not ask for it. markTruncated is nil-safe.
+func resultInfoFrom(ctx context.Context) *ResultInfo {
+	info, _ := ctx.Value(resultInfoKey{}).(*ResultInfo)
+	return info
+}
+
+// markTruncated records that a query returned only the first limit rows
+func (i *ResultInfo) markTruncated(limit int) {
+	if i == nil {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.Truncated = true
+	i.Limit = limit
+}
+
+// txTimeout applies the configured server-side timeout to a transaction.
+// Streaming and maintenance queries run without it.
+func (s *Neo4jStore) txTimeout() func(*neo4j.TransactionConfig) {
+	if s.config.QueryTimeout <= 0 {
+		return func(*neo4j.TransactionConfig) {}
+	}
+	return neo4j.WithTxTimeout(s.config.QueryTimeout)
+}
+
+// resultCap returns the row limit for a list query that asked for requested
+// rows (0 meaning all), and whether that limit is the configured cap rather
+// than the caller's own. The cap only applies to API requests, whose contexts
+// come from WithResultInfo; internal callers get every row they ask for. A
+// capped query should fetch one extra row so truncation can be detected.
+func (s *Neo4jStore) resultCap(ctx context.Context, requested int) (int, bool) {
+	max := s.config.MaxResults
+	if max <= 0 || resultInfoFrom(ctx) == nil || (requested > 0 && requested <= max) {
+		return requested, false
+	}
+	return max, true
+}
@@ -0,0 +1,591 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/models"
+)
+
+// Migration is a numbered schema change. Migrations are applied once, in
+// version order, and recorded as :SchemaMigration nodes. Up must be safe to
+// re-run in case a runner dies before recording it.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, s *Neo4jStore) error
+}
+
+// migrations is the ordered list of schema migrations. Append new migrations
+// with the next version number; never renumber or remove applied ones.
+var migrations = []Migration{
+	{Version: 1, Description: "backfill asset search names", Up: backfillSearchNames},
+	{Version: 2, Description: "backfill asset first and last seen times", Up: backfillSeenTimes},
+	{Version: 3, Description: "relabel assets stored under their asset type", Up: relabelLegacyAssets},
+	{Version: 4, Description: "deduplicate relationships and rewrite their IDs", Up: deduplicateRelationships},
+	{Version: 5, Description: "link risk snapshots to their assets", Up: linkRiskSnapshots},
+}
+
+// defaultMigrationTimeout bounds startup migrations when no migration
+// timeout is configured
+const defaultMigrationTimeout = 30 * time.Minute
+
+// migrationLockLease is how long the migration lock is held without being
+// renewed. A live runner renews it while migrating, so a runner that died
+// holding it blocks others for at most one lease.
+const migrationLockLease = time.Minute
+
+// migrationLockPoll is how often a runner waiting on the lock retries
+const migrationLockPoll = 2 * time.Second
+
+// SchemaMigrationStatus is the migration state of the graph schema
+type SchemaMigrationStatus struct {
+	Version int `json:"version"` // highest applied migration
+	Latest  int `json:"latest"`  // highest migration known to this build
+}
+
+// LatestSchemaVersion returns the highest migration version known to this build
+func LatestSchemaVersion() int {
+	latest := 0
+	for _, m := range migrations {
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest
+}
+
+// SchemaVersion returns the highest applied migration version
+func (s *Neo4jStore) SchemaVersion(ctx context.Context) (int, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, "MATCH (m:SchemaMigration) RETURN coalesce(max(m.version), 0) as version", nil, s.txTimeout())
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", classifyError(err))
+	}
+	record, err := result.Single(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", classifyError(err))
+	}
+
+	version, _ := record.AsMap()["version"].(int64)
+	return int(version), nil
+}
+
+// SchemaStatus returns the applied and latest schema versions
+func (s *Neo4jStore) SchemaStatus(ctx context.Context) (SchemaMigrationStatus, error) {
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return SchemaMigrationStatus{}, err
+	}
+	return SchemaMigrationStatus{Version: version, Latest: LatestSchemaVersion()}, nil
+}
+
+// Migrate applies pending migrations in order and returns the number
+// applied. A lock node keeps concurrent runners from applying the same
+// migration; runners that find it held wait for it to be released.
+func (s *Neo4jStore) Migrate(ctx context.Context) (int, error) {
+	owner := uuid.New().String()
+	if err := s.acquireMigrationLock(ctx, owner); err != nil {
+		return 0, err
+	}
+	defer s.releaseMigrationLock(owner)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.renewMigrationLock(ctx, owner, cancel)
+
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+
+		start := time.Now()
+		log.Printf("Applying schema migration %d: %s", m.Version, m.Description)
+		if err := m.Up(ctx, s); err != nil {
+			return applied, fmt.Errorf("schema migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+		if err := s.recordMigration(ctx, m, time.Since(start)); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// acquireMigrationLock takes the migration lock for owner, waiting while
+// another live runner holds it
+func (s *Neo4jStore) acquireMigrationLock(ctx context.Context, owner string) error {
+	// Setting a property first takes the node's write lock, so the owner
+	// check below sees the latest committed holder
+	query := `
+		MERGE (l:SchemaMigrationLock {id: 'schema'})
+		SET l.touched_at = datetime()
+		WITH l
+		WHERE l.owner IS NULL OR l.owner = $owner OR l.expires_at < datetime()
+		SET l.owner = $owner, l.expires_at = datetime() + duration({seconds: $ttl})
+		RETURN l.owner as owner
+	`
+	params := map[string]interface{}{"owner": owner, "ttl": int64(migrationLockLease.Seconds())}
+
+	for {
+		session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+		acquired, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+			result, err := tx.Run(ctx, query, params)
+			if err != nil {
+				return false, err
+			}
+			return result.Next(ctx), result.Err()
+		})
+		session.Close(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", classifyError(err))
+		}
+		if acquired.(bool) {
+			return nil
+		}
+
+		log.Printf("Schema migrations are being applied by another instance, waiting")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for migration lock: %w", ctx.Err())
+		case <-time.After(migrationLockPoll):
+		}
+	}
+}
+
+// renewMigrationLock extends owner's lease on the migration lock until ctx
+// is done. If the lease cannot be renewed, cancel stops the migration rather
+// than letting it run alongside another runner.
+func (s *Neo4jStore) renewMigrationLock(ctx context.Context, owner string, cancel context.CancelFunc) {
+	query := `
+		MATCH (l:SchemaMigrationLock {id: 'schema', owner: $owner})
+		SET l.expires_at = datetime() + duration({seconds: $ttl})
+		RETURN l.owner as owner
+	`
+	params := map[string]interface{}{"owner": owner, "ttl": int64(migrationLockLease.Seconds())}
+
+	ticker := time.NewTicker(migrationLockLease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+		renewed, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+			result, err := tx.Run(ctx, query, params)
+			if err != nil {
+				return false, err
+			}
+			return result.Next(ctx), result.Err()
+		})
+		session.Close(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil || !renewed.(bool) {
+			log.Printf("Lost the schema migration lock, stopping migrations: %v", err)
+			cancel()
+			return
+		}
+	}
+}
+
+// releaseMigrationLock releases the lock if owner still holds it. It uses
+// its own context so the lock is released even when migration was cancelled.
+func (s *Neo4jStore) releaseMigrationLock(owner string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (l:SchemaMigrationLock {id: 'schema', owner: $owner})
+		REMOVE l.owner, l.expires_at
+	`
+	if _, err := session.Run(ctx, query, map[string]interface{}{"owner": owner}); err != nil {
+		log.Printf("Failed to release migration lock: %v", err)
+	}
+}
+
+// recordMigration marks a migration as applied
+func (s *Neo4jStore) recordMigration(ctx context.Context, m Migration, duration time.Duration) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	query := `
+		MERGE (m:SchemaMigration {version: $version})
+		SET m.description = $description, m.applied_at = datetime(), m.duration_ms = $durationMs
+	`
+	params := map[string]interface{}{
+		"version":     m.Version,
+		"description": m.Description,
+		"durationMs":  duration.Milliseconds(),
+	}
+
+	if _, err := session.Run(ctx, query, params); err != nil {
+		return fmt.Errorf("failed to record schema migration %d: %w", m.Version, classifyError(err))
+	}
+	return nil
+}
+
+// backfillSearchNames sets search_name on assets written before it was
+// stored, so autocomplete matches them by name. Assets are read and written
+// in batches, and an asset whose data cannot be decoded stops the migration
+// rather than being skipped.
+func backfillSearchNames(ctx context.Context, s *Neo4jStore) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	readQuery := `
+		MATCH (n)
+		WHERE n.search_name IS NULL AND n.data IS NOT NULL AND any(label IN labels(n) WHERE label IN $labels)
+		RETURN elementId(n) as elementId, n.id as id, n.data as data
+		LIMIT $batchSize
+	`
+	writeQuery := `
+		UNWIND $rows AS row
+		MATCH (n)
+		WHERE elementId(n) = row.elementId
+		SET n.name = row.name, n.search_name = row.searchName
+	`
+	params := map[string]interface{}{"labels": assetLabels, "batchSize": writeBatchSize}
+
+	total := 0
+	for {
+		result, err := session.Run(ctx, readQuery, params)
+		if err != nil {
+			return fmt.Errorf("failed to read assets: %w", err)
+		}
+
+		var rows []map[string]interface{}
+		for result.Next(ctx) {
+			values := result.Record().AsMap()
+			name, err := assetName(values["data"])
+			if err != nil {
+				return fmt.Errorf("failed to decode asset %v: %w", values["id"], err)
+			}
+
+			rows = append(rows, map[string]interface{}{
+				"elementId":  values["elementId"],
+				"name":       name,
+				"searchName": strings.ToLower(name),
+			})
+		}
+		if err := result.Err(); err != nil {
+			return fmt.Errorf("failed to read assets: %w", err)
+		}
+
+		if len(rows) > 0 {
+			if _, err := session.Run(ctx, writeQuery, map[string]interface{}{"rows": rows}); err != nil {
+				return fmt.Errorf("failed to backfill search names: %w", err)
+			}
+			total += len(rows)
+		}
+		if len(rows) < writeBatchSize {
+			break
+		}
+	}
+
+	log.Printf("Backfilled search names on %d assets", total)
+	return nil
+}
+
+// assetName returns the name in a stored asset document
+func assetName(raw interface{}) (string, error) {
+	data, err := decodeData(raw)
+	if err != nil {
+		return "", err
+	}
+
+	var named struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil {
+		return "", err
+	}
+	return named.Name, nil
+}
+
+// deduplicateRelationships collapses relationships sharing the same
+// endpoints and type into the most recently updated one, and rewrites
+// relationship IDs to their deterministic form. Both run in batches so the
+// graph is never read into memory at once.
+func deduplicateRelationships(ctx context.Context, s *Neo4jStore) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (from)-[r]->(to)
+		WHERE type(r) <> 'GENERATES'
+		WITH from, to, type(r) as relType, r
+		ORDER BY r.updated_at DESC
+		WITH from, to, relType, collect(r) as rels
+		WHERE size(rels) > 1
+		WITH rels LIMIT $batchSize
+		FOREACH (duplicate IN tail(rels) | DELETE duplicate)
+		RETURN count(rels) as groups, sum(size(rels) - 1) as removed
+	`
+
+	removed := 0
+	for {
+		result, err := session.Run(ctx, query, map[string]interface{}{"batchSize": writeBatchSize})
+		if err != nil {
+			return fmt.Errorf("failed to remove duplicate relationships: %w", err)
+		}
+		record, err := result.Single(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to remove duplicate relationships: %w", err)
+		}
+
+		values := record.AsMap()
+		groups, _ := values["groups"].(int64)
+		count, _ := values["removed"].(int64)
+		removed += int(count)
+		if groups < writeBatchSize {
+			break
+		}
+	}
+
+	// Relationships are paged by element ID, and only those whose stored ID
+	// is not yet deterministic are rewritten
+	readQuery := `
+		MATCH (from)-[r]->(to)
+		WHERE type(r) <> 'GENERATES' AND elementId(r) > $after
+		RETURN elementId(r) as elementId, r.id as id,
+			from.id as fromId, type(r) as type, to.id as toId
+		ORDER BY elementId(r)
+		LIMIT $batchSize
+	`
+	writeQuery := `
+		UNWIND $rows AS row
+		MATCH ()-[r]->()
+		WHERE elementId(r) = row.elementId
+		SET r.id = row.id, r.updated_at = datetime()
+	`
+
+	rewritten := 0
+	after := ""
+	for {
+		result, err := session.Run(ctx, readQuery, map[string]interface{}{"after": after, "batchSize": writeBatchSize})
+		if err != nil {
+			return fmt.Errorf("failed to read relationships: %w", err)
+		}
+
+		read := 0
+		var rows []map[string]interface{}
+		for result.Next(ctx) {
+			values := result.Record().AsMap()
+			read++
+			after, _ = values["elementId"].(string)
+
+			fromID, _ := values["fromId"].(string)
+			toID, _ := values["toId"].(string)
+			relType, _ := values["type"].(string)
+			currentID, _ := values["id"].(string)
+
+			id := models.RelationshipID(fromID, models.RelationshipType(relType), toID)
+			if id == currentID {
+				continue
+			}
+
+			// The edge id property takes precedence over any id left in the
+			// data blob, so only the property needs rewriting
+			rows = append(rows, map[string]interface{}{
+				"elementId": values["elementId"],
+				"id":        id,
+			})
+		}
+		if err := result.Err(); err != nil {
+			return fmt.Errorf("failed to read relationships: %w", err)
+		}
+
+		if len(rows) > 0 {
+			if _, err := session.Run(ctx, writeQuery, map[string]interface{}{"rows": rows}); err != nil {
+				return fmt.Errorf("failed to rewrite relationship IDs: %w", err)
+			}
+			rewritten += len(rows)
+		}
+		if read < writeBatchSize {
+			break
+		}
+	}
+
+	log.Printf("Relationship deduplication removed %d duplicates and rewrote %d IDs", removed, rewritten)
+	return nil
+}
+
+// backfillSeenTimes sets first_seen and last_seen on assets written before
+// they were recorded, from their creation and last collection times. Asset
+// documents are left as they are: reads take seen times from the node.
+func backfillSeenTimes(ctx context.Context, s *Neo4jStore) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (n)
+		WHERE n.first_seen IS NULL AND n.data IS NOT NULL AND any(label IN labels(n) WHERE label IN $labels)
+		WITH n LIMIT $batchSize
+		SET n.first_seen = coalesce(n.created_at, datetime()),
+			n.last_seen = coalesce(n.last_collected_at, n.updated_at, n.created_at, datetime())
+		RETURN count(n) as updated
+	`
+	params := map[string]interface{}{"labels": assetLabels, "batchSize": writeBatchSize}
+
+	total := 0
+	for {
+		result, err := session.Run(ctx, query, params)
+		if err != nil {
+			return fmt.Errorf("failed to backfill seen times: %w", err)
+		}
+		record, err := result.Single(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to backfill seen times: %w", err)
+		}
+
+		updated, _ := record.AsMap()["updated"].(int64)
+		total += int(updated)
+		if updated < writeBatchSize {
+			break
+		}
+	}
+
+	log.Printf("Backfilled seen times on %d assets", total)
+	return nil
+}
+
+// relabelLegacyAssets moves assets stored under their lowercase asset type,
+// such as :compute, to the node label of that type. Assets written since
+// under the canonical label are left alone and reported. The earlier
+// backfills matched only canonical labels, so they are re-run for the
+// relabelled assets.
+func relabelLegacyAssets(ctx context.Context, s *Neo4jStore) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	total := 0
+	for assetType, label := range assetTypeLabels {
+		// Both labels come from assetTypeLabels, never from input
+		query := fmt.Sprintf(`
+			MATCH (n:%[1]s)
+			WHERE NOT n:%[2]s AND NOT EXISTS { MATCH (m:%[2]s {id: n.id}) }
+			WITH n LIMIT $batchSize
+			SET n:%[2]s
+			REMOVE n:%[1]s
+			RETURN count(n) as relabelled
+		`, "`"+string(assetType)+"`", label)
+
+		for {
+			result, err := session.Run(ctx, query, map[string]interface{}{"batchSize": writeBatchSize})
+			if err != nil {
+				return fmt.Errorf("failed to relabel %s assets: %w", assetType, err)
+			}
+			record, err := result.Single(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to relabel %s assets: %w", assetType, err)
+			}
+
+			relabelled, _ := record.AsMap()["relabelled"].(int64)
+			total += int(relabelled)
+			if relabelled < writeBatchSize {
+				break
+			}
+		}
+
+		result, err := session.Run(ctx, fmt.Sprintf("MATCH (n:`%s`) RETURN count(n) as remaining", assetType), nil)
+		if err != nil {
+			return fmt.Errorf("failed to count %s assets: %w", assetType, err)
+		}
+		record, err := result.Single(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count %s assets: %w", assetType, err)
+		}
+		if remaining, _ := record.AsMap()["remaining"].(int64); remaining > 0 {
+			log.Printf("Warning: %d %s assets also exist as :%s and were not relabelled", remaining, assetType, label)
+		}
+	}
+	log.Printf("Relabelled %d assets", total)
+
+	if err := backfillSearchNames(ctx, s); err != nil {
+		return err
+	}
+	return backfillSeenTimes(ctx, s)
+}
+
+// linkRiskSnapshots links risk snapshots recorded before snapshots were
+// attached to their asset, so they are deleted along with it. Snapshots of
+// assets that no longer exist are deleted first, so every batch of the
+// linking pass makes progress.
+func linkRiskSnapshots(ctx context.Context, s *Neo4jStore) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	orphanQuery := `
+		MATCH (s:RiskSnapshot)
+		WHERE NOT ()-[:HAS_RISK_SNAPSHOT]->(s)
+			AND NOT EXISTS { MATCH (n {id: s.asset_id}) WHERE any(label IN labels(n) WHERE label IN $labels) }
+		WITH s LIMIT $batchSize
+		DELETE s
+		RETURN count(*) as count
+	`
+	linkQuery := `
+		MATCH (s:RiskSnapshot)
+		WHERE NOT ()-[:HAS_RISK_SNAPSHOT]->(s)
+		WITH s LIMIT $batchSize
+		MATCH (n {id: s.asset_id})
+		WHERE any(label IN labels(n) WHERE label IN $labels)
+		MERGE (n)-[:HAS_RISK_SNAPSHOT]->(s)
+		RETURN count(DISTINCT s) as count
+	`
+	params := map[string]interface{}{"labels": assetLabels, "batchSize": writeBatchSize}
+
+	run := func(query, action string) (int, error) {
+		total := 0
+		for {
+			result, err := session.Run(ctx, query, params)
+			if err != nil {
+				return total, fmt.Errorf("failed to %s risk snapshots: %w", action, err)
+			}
+			record, err := result.Single(ctx)
+			if err != nil {
+				return total, fmt.Errorf("failed to %s risk snapshots: %w", action, err)
+			}
+
+			count, _ := record.AsMap()["count"].(int64)
+			total += int(count)
+			if count < writeBatchSize {
+				return total, nil
+			}
+		}
+	}
+
+	deleted, err := run(orphanQuery, "delete orphaned")
+	if err != nil {
+		return err
+	}
+	linked, err := run(linkQuery, "link")
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Linked %d risk snapshots to their assets and deleted %d orphaned ones", linked, deleted)
+	return nil
+}
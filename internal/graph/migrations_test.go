@@ -0,0 +1,15 @@
+package graph
+
+import "testing"
+
+func TestAssetNameRejectsUndecodableData(t *testing.T) {
+	name, err := assetName(`{"id":"i-1","name":"Web Server"}`)
+	if err != nil || name != "Web Server" {
+		t.Errorf("assetName = %q, %v, want %q", name, err, "Web Server")
+	}
+	for _, raw := range []interface{}{nil, "not json", 42} {
+		if _, err := assetName(raw); err == nil {
+			t.Errorf("assetName(%v) returned no error", raw)
+		}
+	}
+}
@@ -3,18 +3,39 @@ package graph
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/apperrors"
 	"github.com/securizon/pkg/models"
 )
 
+// streamBufferSize is the channel buffer used by streaming reads
+const streamBufferSize = 100
+
 // Neo4jStore implements GraphStore interface using Neo4j
 type Neo4jStore struct {
 	driver neo4j.DriverWithContext
 	config GraphConfig
+
+	// gds caches whether the Graph Data Science library is installed
+	gdsOnce      sync.Once
+	gdsAvailable bool
+
+	// gdsGraph names the cached components projection, built at
+	// gdsProjectedAt; both are guarded by gdsMu
+	gdsMu          sync.Mutex
+	gdsGraph       string
+	gdsProjectedAt time.Time
+
+	// levels maps risk scores to levels; see SetRiskLevels
+	levels RiskLevelFunc
 }
 
 // NewNeo4jStore creates a new Neo4j graph store
@@ -22,11 +43,7 @@ func NewNeo4jStore(config GraphConfig) (*Neo4jStore, error) {
 	driver, err := neo4j.NewDriverWithContext(
 		config.URI,
 		neo4j.BasicAuth(config.Username, config.Password, ""),
-		func(config *neo4j.Config) {
-			config.MaxConnectionPoolSize = config.MaxPoolSize
-			config.MaxConnectionLifetime = time.Hour
-			config.ConnectionAcquisitionTimeout = config.ConnTimeout
-		},
+		driverConfig(config),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Neo4j driver: %w", err)
@@ -50,9 +67,56 @@ func NewNeo4jStore(config GraphConfig) (*Neo4jStore, error) {
 		log.Printf("Warning: failed to initialize schema: %v", err)
 	}
 
+	if config.MigrateOnStartup {
+		timeout := config.MigrationTimeout
+		if timeout <= 0 {
+			timeout = defaultMigrationTimeout
+		}
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), timeout)
+		defer cancelMigrate()
+
+		applied, err := store.Migrate(migrateCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate graph schema: %w", err)
+		}
+		if applied > 0 {
+			log.Printf("Applied %d schema migrations", applied)
+		}
+	}
+
 	return store, nil
 }
 
+// driverConfig applies the pool settings of config to the driver
+// configuration, leaving the driver defaults for unset values
+func driverConfig(config GraphConfig) func(*neo4j.Config) {
+	return func(driver *neo4j.Config) {
+		if config.MaxPoolSize > 0 {
+			driver.MaxConnectionPoolSize = config.MaxPoolSize
+		}
+		driver.MaxConnectionLifetime = time.Hour
+		if config.ConnTimeout > 0 {
+			driver.ConnectionAcquisitionTimeout = config.ConnTimeout
+			driver.SocketConnectTimeout = config.ConnTimeout
+		}
+	}
+}
+
+// VerifyConnectivity checks that the database in config can be reached and
+// authenticated against, without creating a store or touching the schema
+func VerifyConnectivity(ctx context.Context, config GraphConfig) error {
+	driver, err := neo4j.NewDriverWithContext(config.URI, neo4j.BasicAuth(config.Username, config.Password, ""), driverConfig(config))
+	if err != nil {
+		return fmt.Errorf("failed to create Neo4j driver: %w", err)
+	}
+	defer driver.Close(ctx)
+
+	if err := driver.VerifyConnectivity(ctx); err != nil {
+		return fmt.Errorf("failed to verify Neo4j connectivity: %w", err)
+	}
+	return nil
+}
+
 // initializeSchema creates the graph schema
 func (s *Neo4jStore) initializeSchema(ctx context.Context) error {
 	schema := s.getSchema()
@@ -75,6 +139,10 @@ func (s *Neo4jStore) initializeSchema(ctx context.Context) error {
 	for _, index := range schema.Indexes {
 		query := fmt.Sprintf("CREATE INDEX %s IF NOT EXISTS FOR (n:%s) ON (n.%s)",
 			index.Name, index.Label, index.Properties[0])
+		if index.Type == "relationship" {
+			query = fmt.Sprintf("CREATE INDEX %s IF NOT EXISTS FOR ()-[r:%s]-() ON (r.%s)",
+				index.Name, index.Label, index.Properties[0])
+		}
 		
 		_, err := session.Run(ctx, query, nil)
 		if err != nil {
@@ -93,6 +161,7 @@ func (s *Neo4jStore) getSchema() GraphSchema {
 				Name: "Identity",
 				Properties: []Property{
 					{Name: "id", Type: "string", Required: true, Indexed: true, Unique: true},
+					{Name: "search_name", Type: "string", Indexed: true},
 					{Name: "provider", Type: "string", Required: true, Indexed: true},
 					{Name: "type", Type: "string", Required: true, Indexed: true},
 					{Name: "privilege_level", Type: "string", Indexed: true},
@@ -105,6 +174,7 @@ func (s *Neo4jStore) getSchema() GraphSchema {
 				Name: "Compute",
 				Properties: []Property{
 					{Name: "id", Type: "string", Required: true, Indexed: true, Unique: true},
+					{Name: "search_name", Type: "string", Indexed: true},
 					{Name: "provider", Type: "string", Required: true, Indexed: true},
 					{Name: "internet_exposed", Type: "boolean", Indexed: true},
 					{Name: "environment", Type: "string", Indexed: true},
@@ -115,6 +185,7 @@ func (s *Neo4jStore) getSchema() GraphSchema {
 				Name: "Network",
 				Properties: []Property{
 					{Name: "id", Type: "string", Required: true, Indexed: true, Unique: true},
+					{Name: "search_name", Type: "string", Indexed: true},
 					{Name: "provider", Type: "string", Required: true, Indexed: true},
 					{Name: "environment", Type: "string", Indexed: true},
 					{Name: "risk_score", Type: "float", Indexed: true},
@@ -124,6 +195,7 @@ func (s *Neo4jStore) getSchema() GraphSchema {
 				Name: "Data",
 				Properties: []Property{
 					{Name: "id", Type: "string", Required: true, Indexed: true, Unique: true},
+					{Name: "search_name", Type: "string", Indexed: true},
 					{Name: "provider", Type: "string", Required: true, Indexed: true},
 					{Name: "data_sensitivity", Type: "string", Indexed: true},
 					{Name: "external_sharing", Type: "boolean", Indexed: true},
@@ -135,6 +207,7 @@ func (s *Neo4jStore) getSchema() GraphSchema {
 				Name: "SaaS",
 				Properties: []Property{
 					{Name: "id", Type: "string", Required: true, Indexed: true, Unique: true},
+					{Name: "search_name", Type: "string", Indexed: true},
 					{Name: "provider", Type: "string", Required: true, Indexed: true},
 					{Name: "platform", Type: "string", Indexed: true},
 					{Name: "external_sharing", Type: "boolean", Indexed: true},
@@ -152,7 +225,22 @@ func (s *Neo4jStore) getSchema() GraphSchema {
 					{Name: "policy_id", Type: "string", Indexed: true},
 				},
 			},
+			{
+				Name: "RiskSnapshot",
+				Properties: []Property{
+					{Name: "asset_id", Type: "string", Required: true, Indexed: true},
+					{Name: "day", Type: "date"},
+					{Name: "score", Type: "float"}, // latest of the day
+					{Name: "level", Type: "string"},
+					{Name: "min_score", Type: "float"},
+					{Name: "max_score", Type: "float"},
+					{Name: "score_sum", Type: "float"},
+					{Name: "samples", Type: "int"},
+					{Name: "timestamp", Type: "datetime", Required: true}, // last update
+				},
+			},
 		},
+		EdgeTypes: edgeTypes(),
 		Constraints: []Constraint{
 			{Name: "identity_id_unique", Type: "UNIQUE", Label: "Identity", Properties: []string{"id"}},
 			{Name: "compute_id_unique", Type: "UNIQUE", Label: "Compute", Properties: []string{"id"}},
@@ -160,15 +248,203 @@ func (s *Neo4jStore) getSchema() GraphSchema {
 			{Name: "data_id_unique", Type: "UNIQUE", Label: "Data", Properties: []string{"id"}},
 			{Name: "saas_id_unique", Type: "UNIQUE", Label: "SaaS", Properties: []string{"id"}},
 			{Name: "finding_id_unique", Type: "UNIQUE", Label: "Finding", Properties: []string{"id"}},
+			{Name: "schema_migration_version_unique", Type: "UNIQUE", Label: "SchemaMigration", Properties: []string{"version"}},
+			{Name: "schema_migration_lock_unique", Type: "UNIQUE", Label: "SchemaMigrationLock", Properties: []string{"id"}},
 		},
-		Indexes: []Index{
+		Indexes: append([]Index{
 			{Name: "identity_provider_idx", Label: "Identity", Properties: []string{"provider"}},
 			{Name: "identity_environment_idx", Label: "Identity", Properties: []string{"environment"}},
 			{Name: "compute_exposed_idx", Label: "Compute", Properties: []string{"internet_exposed"}},
 			{Name: "data_sensitivity_idx", Label: "Data", Properties: []string{"data_sensitivity"}},
 			{Name: "finding_severity_idx", Label: "Finding", Properties: []string{"severity"}},
-		},
+			{Name: "risk_snapshot_asset_idx", Label: "RiskSnapshot", Properties: []string{"asset_id"}},
+			{Name: "risk_snapshot_timestamp_idx", Label: "RiskSnapshot", Properties: []string{"timestamp"}},
+			{Name: "risk_snapshot_asset_day_idx", Label: "RiskSnapshot", Properties: []string{"asset_id", "day"}},
+		}, append(relationshipIndexes(), searchNameIndexes()...)...),
+	}
+}
+
+// assetLabels are the node labels of asset types
+var assetLabels = assetLabelsOf([]models.AssetType{
+	models.AssetTypeIdentity,
+	models.AssetTypeCompute,
+	models.AssetTypeNetwork,
+	models.AssetTypeData,
+	models.AssetTypeSaaS,
+})
+
+// schemaNodeLabels and schemaRelationshipTypes allow-list the labels and
+// relationship types interpolated into Cypher
+var schemaNodeLabels, schemaRelationshipTypes = schemaNames()
+
+// schemaNames returns the asset labels and edge types of the schema, plus
+// the finding relationship
+func schemaNames() (map[string]bool, map[string]bool) {
+	labels := make(map[string]bool)
+	relTypes := map[string]bool{string(models.RelationshipGenerates): true}
+
+	schema := (&Neo4jStore{}).getSchema()
+	for _, label := range schema.NodeLabels {
+		if label.Name != "RiskSnapshot" {
+			labels[label.Name] = true
+		}
+	}
+	for _, edge := range schema.EdgeTypes {
+		relTypes[edge.Name] = true
+	}
+	return labels, relTypes
+}
+
+// assetRelationshipTypes are the edge types between assets, which
+// relationship queries are restricted to so that they never match finding
+// or snapshot edges
+var assetRelationshipTypes = func() []string {
+	relTypes := make([]string, 0, len(schemaRelationshipTypes))
+	for relType := range schemaRelationshipTypes {
+		if relType != string(models.RelationshipGenerates) {
+			relTypes = append(relTypes, relType)
+		}
+	}
+	sort.Strings(relTypes)
+	return relTypes
+}()
+
+// assetTypeLabels maps asset types to the node labels they are stored
+// under. Only these labels are interpolated into asset queries.
+var assetTypeLabels = map[models.AssetType]string{
+	models.AssetTypeIdentity: "Identity",
+	models.AssetTypeCompute:  "Compute",
+	models.AssetTypeNetwork:  "Network",
+	models.AssetTypeData:     "Data",
+	models.AssetTypeSaaS:     "SaaS",
+}
+
+// assetLabel returns the node label of an asset's type, rejecting types that
+// are not asset types
+func assetLabel(assetType models.AssetType) (string, error) {
+	label, ok := assetTypeLabels[assetType]
+	if !ok {
+		return "", apperrors.Invalid("invalid asset type: %q", assetType)
+	}
+	return label, nil
+}
+
+// assetTypeOfLabel returns the asset type stored under a node label, or ""
+// for labels that are not asset labels
+func assetTypeOfLabel(label string) models.AssetType {
+	for assetType, l := range assetTypeLabels {
+		if l == label {
+			return assetType
+		}
+	}
+	return ""
+}
+
+// assetTypeOfLabels returns the asset type of the first asset label in a
+// node's labels, as returned by labels(n)
+func assetTypeOfLabels(labels []interface{}) models.AssetType {
+	for _, label := range labels {
+		if l, ok := label.(string); ok {
+			if assetType := assetTypeOfLabel(l); assetType != "" {
+				return assetType
+			}
+		}
+	}
+	return ""
+}
+
+// assetLabelsOf returns the node labels of asset types, skipping types that
+// are not asset types
+func assetLabelsOf(types []models.AssetType) []string {
+	labels := make([]string, 0, len(types))
+	for _, assetType := range types {
+		if label, ok := assetTypeLabels[assetType]; ok {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// checkRelationshipType rejects relationship types that are not edge types
+// of the schema
+func checkRelationshipType(relType models.RelationshipType) error {
+	if !schemaRelationshipTypes[string(relType)] {
+		return apperrors.Invalid("invalid relationship type: %q", relType)
+	}
+	return nil
+}
+
+// searchNameIndexes returns a search_name index for every asset label so
+// autocomplete prefix matches are index-backed
+func searchNameIndexes() []Index {
+	indexes := make([]Index, 0, len(assetLabels))
+	for _, label := range assetLabels {
+		indexes = append(indexes, Index{
+			Name:       strings.ToLower(label) + "_search_name_idx",
+			Label:      label,
+			Properties: []string{"search_name"},
+		})
 	}
+	return indexes
+}
+
+// relationshipProperties are the relationship fields stored as edge
+// properties rather than inside the data blob, so they can be indexed
+var relationshipProperties = []Property{
+	{Name: "id", Type: "string", Required: true, Indexed: true},
+	{Name: "strength", Type: "float", Indexed: true},
+	{Name: "trust_score", Type: "float"},
+	{Name: "valid_from", Type: "datetime", Required: true},
+	{Name: "valid_to", Type: "datetime", Indexed: true},
+	{Name: "created_at", Type: "datetime", Indexed: true},
+	{Name: "updated_at", Type: "datetime"},
+	{Name: "reactivated_at", Type: "datetime"},
+	{Name: "last_revoked_at", Type: "datetime"},
+	{Name: "reactivation_count", Type: "int"},
+}
+
+// edgeTypes returns the edge type definitions for all asset relationship types
+func edgeTypes() []EdgeType {
+	relTypes := []models.RelationshipType{
+		models.RelationshipAssumesRole,
+		models.RelationshipHasAccessTo,
+		models.RelationshipConnectedTo,
+		models.RelationshipRunsOn,
+		models.RelationshipStores,
+		models.RelationshipContains,
+		models.RelationshipDependsOn,
+		models.RelationshipManages,
+		models.RelationshipOwns,
+	}
+
+	edges := make([]EdgeType, 0, len(relTypes))
+	for _, relType := range relTypes {
+		edges = append(edges, EdgeType{
+			Name:       string(relType),
+			Properties: relationshipProperties,
+		})
+	}
+	return edges
+}
+
+// relationshipIndexes returns an index for every indexed edge property.
+// Neo4j relationship indexes are per type, so one is created for each type.
+func relationshipIndexes() []Index {
+	var indexes []Index
+	for _, edge := range edgeTypes() {
+		for _, prop := range edge.Properties {
+			if !prop.Indexed {
+				continue
+			}
+			indexes = append(indexes, Index{
+				Name:       strings.ToLower(edge.Name) + "_" + prop.Name + "_idx",
+				Label:      edge.Name,
+				Properties: []string{prop.Name},
+				Type:       "relationship",
+			})
+		}
+	}
+	return indexes
 }
 
 // CreateAsset creates a new asset node
@@ -176,29 +452,154 @@ func (s *Neo4jStore) CreateAsset(ctx context.Context, asset models.Asset) error
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
 
-	label := string(asset.GetType())
-	data, err := json.Marshal(asset)
+	now := time.Now().UTC()
+	asset.SetSeen(now, now)
+
+	label, err := assetLabel(asset.GetType())
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(asset)
 	if err != nil {
 		return fmt.Errorf("failed to marshal asset: %w", err)
 	}
+	data, err := s.encodeData(raw)
+	if err != nil {
+		return err
+	}
 
 	query := fmt.Sprintf(`
 		CREATE (n:%s {id: $id, data: $data, provider: $provider, environment: $env, risk_score: $riskScore})
-		SET n.created_at = datetime(), n.updated_at = datetime()
+		SET n.internet_exposed = $internetExposed, n.name = $name, n.search_name = $searchName,
+			n.created_at = datetime(), n.updated_at = datetime(), n.version = 1,
+			n.last_collected_at = datetime(), n.first_seen = $seenAt, n.last_seen = $seenAt
 	`, label)
 
 	params := map[string]interface{}{
-		"id":        asset.GetID(),
-		"data":      string(data),
-		"provider":  string(asset.GetProvider()),
-		"env":       string(asset.GetEnvironment()),
-		"riskScore": 0.0, // Initial risk score
+		"id":              asset.GetID(),
+		"data":            data,
+		"provider":        string(asset.GetProvider()),
+		"env":             string(asset.GetEnvironment()),
+		"riskScore":       0.0, // Initial risk score
+		"internetExposed": isInternetExposed(asset),
+		"name":            asset.GetName(),
+		"searchName":      strings.ToLower(asset.GetName()),
+		"seenAt":          now,
 	}
 
-	_, err = session.Run(ctx, query, params)
+	_, err = session.Run(ctx, query, params, s.txTimeout())
+	if err = classifyError(err); errors.Is(err, apperrors.ErrConflict) {
+		return apperrors.Wrap(apperrors.CodeConflict, err, "asset already exists: %s", asset.GetID())
+	}
 	return err
 }
 
+// UpsertAsset creates an asset or, if one with the same id exists, updates it
+// like UpdateAsset. It reports whether the asset was created.
+func (s *Neo4jStore) UpsertAsset(ctx context.Context, asset models.Asset) (bool, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	label, err := assetLabel(asset.GetType())
+	if err != nil {
+		return false, err
+	}
+
+	// datetime() is fixed for the statement, so created_at only equals
+	// updated_at when the node was created by this query
+	query := relabelAssetClause(label) + fmt.Sprintf(`
+		MERGE (n:%s {id: $id})
+		ON CREATE SET n.risk_score = $riskScore, n.created_at = datetime()
+		SET n.data = $data, n.provider = $provider, n.environment = $env,
+			n.internet_exposed = $internetExposed, n.name = $name, n.search_name = $searchName,
+			n.updated_at = datetime(), n.first_seen = coalesce(n.first_seen, n.created_at, $seenAt),
+			n.last_seen = $seenAt, n.last_collected_at = datetime(),
+			n.risk_score = coalesce(n.undecayed_risk_score, n.risk_score),
+			n.version = coalesce(n.version, 0) + 1
+		REMOVE n.undecayed_risk_score, n.risk_decay, n.expired_at
+		RETURN n.created_at = n.updated_at as created, n.first_seen as firstSeen
+	`, label)
+
+	now := time.Now().UTC()
+	params, err := s.assetWriteParams(asset, now)
+	if err != nil {
+		return false, err
+	}
+	params["provider"] = string(asset.GetProvider())
+	params["env"] = string(asset.GetEnvironment())
+	params["riskScore"] = 0.0 // Initial risk score
+	params["assetLabels"] = assetLabels
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return false, classifyError(err)
+	}
+	record, err := result.Single(ctx)
+	if err != nil {
+		return false, classifyError(err)
+	}
+
+	values := record.AsMap()
+	setSeenFrom(asset, values["firstSeen"], now)
+	created, _ := values["created"].(bool)
+	return created, nil
+}
+
+// relabelAssetClause moves a node stored under another asset type's label
+// to label, so that changing an asset's type updates its node rather than
+// creating a second one. It precedes a write matching (n:label {id: $id})
+// and needs the assetLabels parameter. The node is left alone if one with
+// the new label already exists.
+func relabelAssetClause(label string) string {
+	return fmt.Sprintf(`
+		OPTIONAL MATCH (existing {id: $id})
+		WHERE NOT existing:%[1]s AND any(l IN labels(existing) WHERE l IN $assetLabels)
+			AND NOT EXISTS { MATCH (:%[1]s {id: $id}) }
+		FOREACH (e IN CASE WHEN existing IS NULL THEN [] ELSE [existing] END |
+			REMOVE e:%[2]s SET e:%[1]s)
+		WITH count(*) AS relabeled
+	`, label, strings.Join(assetLabels, ":"))
+}
+
+// assetWriteParams returns the parameters shared by asset writes. The
+// stored first_seen is kept by the write itself, so the document is
+// written with the asset's own first-seen time; reads take both seen times
+// from the node properties.
+func (s *Neo4jStore) assetWriteParams(asset models.Asset, now time.Time) (map[string]interface{}, error) {
+	base := asset.GetBaseAsset()
+	firstSeen := base.FirstSeen
+	if firstSeen.IsZero() {
+		firstSeen = now
+	}
+	asset.SetSeen(firstSeen, now)
+
+	raw, err := json.Marshal(asset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal asset: %w", err)
+	}
+	data, err := s.encodeData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"id":              asset.GetID(),
+		"data":            data,
+		"internetExposed": isInternetExposed(asset),
+		"name":            asset.GetName(),
+		"searchName":      strings.ToLower(asset.GetName()),
+		"seenAt":          now,
+	}, nil
+}
+
+// setSeenFrom sets an asset's seen times from the first_seen stored for
+// it, as returned by a write
+func setSeenFrom(asset models.Asset, firstSeen interface{}, now time.Time) {
+	if t, ok := firstSeen.(time.Time); ok {
+		asset.SetSeen(t.UTC(), now)
+	}
+}
+
 // GetAsset retrieves an asset by ID
 func (s *Neo4jStore) GetAsset(ctx context.Context, id string) (models.Asset, error) {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
@@ -206,57 +607,96 @@ func (s *Neo4jStore) GetAsset(ctx context.Context, id string) (models.Asset, err
 
 	query := `
 		MATCH (n {id: $id})
-		RETURN n.data as data, labels(n) as labels
+		RETURN n.data as data, labels(n) as labels,
+			n.first_seen as firstSeen, n.last_seen as lastSeen, n.risk_score as riskScore
 	`
 
-	result, err := session.Run(ctx, query, map[string]interface{}{"id": id})
+	result, err := session.Run(ctx, query, map[string]interface{}{"id": id}, s.txTimeout())
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
 	record, err := result.Single(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("asset not found: %w", err)
+		return nil, apperrors.Wrap(apperrors.CodeNotFound, err, "asset not found: %s", id)
 	}
 
-	data := record.AsMap()["data"].(string)
-	labels := record.AsMap()["labels"].([]string)
-	
-	// Determine asset type from labels
-	assetType := models.AssetType("")
-	for _, label := range labels {
-		if label != "" {
-			assetType = models.AssetType(label)
-			break
-		}
+	return s.recordToAsset(record)
+}
+
+// GetAssets retrieves several assets in one query, keyed by ID. Unknown
+// assets are left out.
+func (s *Neo4jStore) GetAssets(ctx context.Context, ids []string) (map[string]models.Asset, error) {
+	assets := make(map[string]models.Asset, len(ids))
+	if len(ids) == 0 {
+		return assets, nil
 	}
 
-	return s.unmarshalAsset(data, assetType)
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (n)
+		WHERE n.id IN $ids AND any(label IN labels(n) WHERE label IN $assetLabels)
+		RETURN n.data as data, labels(n) as labels,
+			n.first_seen as firstSeen, n.last_seen as lastSeen, n.risk_score as riskScore
+	`
+
+	params := map[string]interface{}{"ids": ids, "assetLabels": assetLabels}
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	for result.Next(ctx) {
+		asset, err := s.recordToAsset(result.Record())
+		if err != nil {
+			return nil, err
+		}
+		assets[asset.GetID()] = asset
+	}
+	if err := result.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+	return assets, nil
 }
 
-// UpdateAsset updates an existing asset
+// UpdateAsset updates an existing asset. The stored first-seen time is kept
+// and last seen is set to now. A change of type relabels the asset's node.
 func (s *Neo4jStore) UpdateAsset(ctx context.Context, asset models.Asset) error {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
 
-	label := string(asset.GetType())
-	data, err := json.Marshal(asset)
+	label, err := assetLabel(asset.GetType())
 	if err != nil {
-		return fmt.Errorf("failed to marshal asset: %w", err)
+		return err
 	}
 
-	query := fmt.Sprintf(`
+	query := relabelAssetClause(label) + fmt.Sprintf(`
 		MATCH (n:%s {id: $id})
-		SET n.data = $data, n.updated_at = datetime()
+		SET n.data = $data, n.internet_exposed = $internetExposed, n.name = $name, n.search_name = $searchName,
+			n.updated_at = datetime(), n.first_seen = coalesce(n.first_seen, n.created_at, $seenAt),
+			n.last_seen = $seenAt, n.last_collected_at = datetime(),
+			n.risk_score = coalesce(n.undecayed_risk_score, n.risk_score),
+			n.version = coalesce(n.version, 0) + 1
+		REMOVE n.undecayed_risk_score, n.risk_decay, n.expired_at
+		RETURN n.first_seen as firstSeen
 	`, label)
 
-	params := map[string]interface{}{
-		"id":   asset.GetID(),
-		"data": string(data),
+	now := time.Now().UTC()
+	params, err := s.assetWriteParams(asset, now)
+	if err != nil {
+		return err
 	}
+	params["assetLabels"] = assetLabels
 
-	_, err = session.Run(ctx, query, params)
-	return err
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return classifyError(err)
+	}
+	if result.Next(ctx) {
+		setSeenFrom(asset, result.Record().AsMap()["firstSeen"], now)
+	}
+	return classifyError(result.Err())
 }
 
 // DeleteAsset deletes an asset and its relationships
@@ -266,11 +706,12 @@ func (s *Neo4jStore) DeleteAsset(ctx context.Context, id string) error {
 
 	query := `
 		MATCH (n {id: $id})
-		DETACH DELETE n
+		OPTIONAL MATCH (n)-[:HAS_RISK_SNAPSHOT]->(s:RiskSnapshot)
+		DETACH DELETE n, s
 	`
 
-	_, err := session.Run(ctx, query, map[string]interface{}{"id": id})
-	return err
+	_, err := session.Run(ctx, query, map[string]interface{}{"id": id}, s.txTimeout())
+	return classifyError(err)
 }
 
 // ListAssets retrieves assets based on filter
@@ -278,17 +719,125 @@ func (s *Neo4jStore) ListAssets(ctx context.Context, filter models.AssetFilter)
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
+	// Fetch one row past the cap to detect truncation
+	limit, capped := s.resultCap(ctx, filter.Limit)
+	if capped {
+		filter.Limit = limit + 1
+	}
+
+	query, params := buildAssetQuery(filter)
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	var assets []models.Asset
+	for result.Next(ctx) {
+		asset, err := s.recordToAsset(result.Record())
+		if err != nil {
+			log.Printf("Failed to unmarshal asset: %v", err)
+			continue
+		}
+		assets = append(assets, asset)
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	if capped && len(assets) > limit {
+		resultInfoFrom(ctx).markTruncated(limit)
+		assets = assets[:limit]
+	}
+
+	return assets, nil
+}
+
+// StreamAssets streams assets matching filter without buffering the result.
+// The asset channel is closed when the result is exhausted; at most one error
+// is delivered on the error channel before it is closed.
+func (s *Neo4jStore) StreamAssets(ctx context.Context, filter models.AssetFilter) (<-chan models.Asset, <-chan error) {
+	assets := make(chan models.Asset, streamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(assets)
+
+		session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+		defer session.Close(ctx)
+
+		query, params := buildAssetQuery(filter)
+
+		result, err := session.Run(ctx, query, params)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		for result.Next(ctx) {
+			asset, err := s.recordToAsset(result.Record())
+			if err != nil {
+				log.Printf("Failed to unmarshal asset: %v", err)
+				continue
+			}
+
+			select {
+			case assets <- asset:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := result.Err(); err != nil {
+			errs <- err
+		}
+	}()
+
+	return assets, errs
+}
+
+// buildAssetQuery builds the Cypher query and parameters for an asset filter
+func buildAssetQuery(filter models.AssetFilter) (string, map[string]interface{}) {
+	query, params := assetMatch(filter)
+
+	query += " RETURN n.data as data, labels(n) as labels, n.first_seen as firstSeen, n.last_seen as lastSeen, n.risk_score as riskScore"
+
+	// Pages are ordered so that consecutive pages neither repeat nor skip
+	// assets
+	if filter.Offset > 0 || filter.Limit > 0 {
+		query += " ORDER BY n.id"
+	}
+
+	if filter.Offset > 0 {
+		query += " SKIP $offset"
+		params["offset"] = filter.Offset
+	}
+
+	if filter.Limit > 0 {
+		query += " LIMIT $limit"
+		params["limit"] = filter.Limit
+	}
+
+	return query, params
+}
+
+// assetMatch builds the MATCH and WHERE clauses selecting the assets n
+// matching filter, ignoring its limit and offset. Only nodes with an asset
+// label match, so findings, snapshots and bookkeeping nodes such as schema
+// migrations and accepted paths are never taken for assets.
+func assetMatch(filter models.AssetFilter) (string, map[string]interface{}) {
 	query := `
 		MATCH (n)
-		WHERE 1=1
+		WHERE n.expired_at IS NULL AND any(label IN labels(n) WHERE label IN $types)
 	`
 
-	params := make(map[string]interface{})
+	params := map[string]interface{}{"types": assetLabels}
 
 	// Build WHERE clause based on filter
 	if len(filter.Types) > 0 {
-		query += " AND labels(n)[0] IN $types"
-		params["types"] = filter.Types
+		params["types"] = assetLabelsOf(filter.Types)
 	}
 
 	if len(filter.Providers) > 0 {
@@ -311,41 +860,41 @@ func (s *Neo4jStore) ListAssets(ctx context.Context, filter models.AssetFilter)
 		params["maxRiskScore"] = filter.MaxRiskScore
 	}
 
-	query += " RETURN n.data as data, labels(n) as labels"
-
-	if filter.Limit > 0 {
-		query += " LIMIT $limit"
-		params["limit"] = filter.Limit
+	if !filter.FirstSeenAfter.IsZero() {
+		query += " AND n.first_seen >= $firstSeenAfter"
+		params["firstSeenAfter"] = filter.FirstSeenAfter
 	}
 
-	result, err := session.Run(ctx, query, params)
-	if err != nil {
-		return nil, err
+	if !filter.FirstSeenBefore.IsZero() {
+		query += " AND n.first_seen < $firstSeenBefore"
+		params["firstSeenBefore"] = filter.FirstSeenBefore
 	}
 
-	var assets []models.Asset
-	for result.Next(ctx) {
-		record := result.Record()
-		data := record.AsMap()["data"].(string)
-		labels := record.AsMap()["labels"].([]string)
-		
-		assetType := models.AssetType("")
-		for _, label := range labels {
-			if label != "" {
-				assetType = models.AssetType(label)
-				break
-			}
-		}
+	return query, params
+}
 
-		asset, err := s.unmarshalAsset(data, assetType)
-		if err != nil {
-			log.Printf("Failed to unmarshal asset: %v", err)
-			continue
-		}
-		assets = append(assets, asset)
-	}
+// CountAssets returns the number of assets matching filter, ignoring its
+// limit and offset, so list responses can report a total
+func (s *Neo4jStore) CountAssets(ctx context.Context, filter models.AssetFilter) (int, error) {
+	query, params := assetMatch(filter)
+	return s.count(ctx, query+" RETURN count(n) as total", params)
+}
 
-	return assets, nil
+// count runs a query returning a single total column
+func (s *Neo4jStore) count(ctx context.Context, query string, params map[string]interface{}) (int, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	record, err := result.Single(ctx)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	total, _ := record.AsMap()["total"].(int64)
+	return int(total), nil
 }
 
 // SearchAssets performs text search on assets
@@ -360,31 +909,61 @@ func (s *Neo4jStore) CreateRelationship(ctx context.Context, rel models.Relation
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
 
-	data, err := json.Marshal(rel)
+	// Relationships are identified by their endpoints and type so that
+	// re-emitting the same edge updates it instead of duplicating it
+	rel.ID = rel.CanonicalID()
+
+	if err := checkRelationshipType(rel.Type); err != nil {
+		return err
+	}
+	params, err := relationshipParams(rel)
 	if err != nil {
-		return fmt.Errorf("failed to marshal relationship: %w", err)
+		return err
 	}
 
-	query := `
-		MATCH (from {id: $fromId}), (to {id: $toId})
-		CREATE (from)-[r:%s {id: $id, data: $data, strength: $strength}]->(to)
-		SET r.valid_from = datetime($validFrom), r.created_at = datetime(), r.updated_at = datetime()
-	`
-
-	relType := string(rel.Type)
-	formattedQuery := fmt.Sprintf(query, relType)
+	_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		return nil, upsertRelationships(ctx, tx, rel.Type, []map[string]interface{}{params})
+	}, s.txTimeout())
+	return classifyError(err)
+}
 
-	params := map[string]interface{}{
-		"fromId":     rel.FromAssetID,
-		"toId":       rel.ToAssetID,
-		"id":         rel.ID,
-		"data":       string(data),
-		"strength":   rel.Strength,
-		"validFrom":  rel.ValidFrom.Format(time.RFC3339),
+// relationshipUpsertQuery merges a batch of relationships of one type. An
+// edge that was soft-deleted (valid_to in the past) and is written again as
+// valid is reopened rather than left closed, recording when it was revoked,
+// when it was reactivated and how many times that has happened.
+const relationshipUpsertQuery = `
+	UNWIND $rows AS row
+	MATCH (from {id: row.fromId}), (to {id: row.toId})
+	MERGE (from)-[r:%s]->(to)
+	ON CREATE SET r.created_at = datetime()
+	WITH r, r.valid_to IS NOT NULL AND r.valid_to <= datetime()
+		AND (row.validTo IS NULL OR datetime(row.validTo) > datetime()) AS reactivated, row
+	FOREACH (_ IN CASE WHEN reactivated THEN [1] ELSE [] END |
+		SET r.last_revoked_at = r.valid_to, r.reactivated_at = datetime(),
+			r.reactivation_count = coalesce(r.reactivation_count, 0) + 1)
+	SET r.id = row.id, r.data = row.data, r.strength = row.strength, r.trust_score = row.trustScore,
+		r.valid_from = datetime(row.validFrom), r.valid_to = datetime(row.validTo), r.updated_at = datetime()
+	WITH r, reactivated
+	WHERE reactivated
+	RETURN r.id as id
+`
+
+// upsertRelationships writes rows built by relationshipParams for
+// relationships of relType, logging any soft-deleted edges they reopen
+func upsertRelationships(ctx context.Context, tx neo4j.ManagedTransaction, relType models.RelationshipType, rows []map[string]interface{}) error {
+	if err := checkRelationshipType(relType); err != nil {
+		return err
 	}
-
-	_, err = session.Run(ctx, formattedQuery, params)
-	return err
+	result, err := tx.Run(ctx, fmt.Sprintf(relationshipUpsertQuery, relType), map[string]interface{}{"rows": rows})
+	if err != nil {
+		return err
+	}
+	for result.Next(ctx) {
+		if id, ok := result.Record().Values[0].(string); ok {
+			log.Printf("Reactivated relationship %s", id)
+		}
+	}
+	return result.Err()
 }
 
 // GetRelationship retrieves a relationship by ID
@@ -393,27 +972,20 @@ func (s *Neo4jStore) GetRelationship(ctx context.Context, id string) (models.Rel
 	defer session.Close(ctx)
 
 	query := `
-		MATCH ()-[r {id: $id}]->()
-		RETURN r.data as data
-	`
+		MATCH (from)-[r {id: $id}]->(to)
+		RETURN ` + relationshipColumns
 
-	result, err := session.Run(ctx, query, map[string]interface{}{"id": id})
+	result, err := session.Run(ctx, query, map[string]interface{}{"id": id}, s.txTimeout())
 	if err != nil {
-		return models.Relationship{}, err
+		return models.Relationship{}, classifyError(err)
 	}
 
 	record, err := result.Single(ctx)
 	if err != nil {
-		return models.Relationship{}, fmt.Errorf("relationship not found: %w", err)
-	}
-
-	data := record.AsMap()["data"].(string)
-	var rel models.Relationship
-	if err := json.Unmarshal([]byte(data), &rel); err != nil {
-		return models.Relationship{}, fmt.Errorf("failed to unmarshal relationship: %w", err)
+		return models.Relationship{}, apperrors.Wrap(apperrors.CodeNotFound, err, "relationship not found: %s", id)
 	}
 
-	return rel, nil
+	return recordToRelationship(record.AsMap())
 }
 
 // UpdateRelationship updates an existing relationship
@@ -421,23 +993,19 @@ func (s *Neo4jStore) UpdateRelationship(ctx context.Context, rel models.Relation
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
 	defer session.Close(ctx)
 
-	data, err := json.Marshal(rel)
+	params, err := relationshipParams(rel)
 	if err != nil {
-		return fmt.Errorf("failed to marshal relationship: %w", err)
+		return err
 	}
 
 	query := `
 		MATCH ()-[r {id: $id}]->()
-		SET r.data = $data, r.updated_at = datetime()
+		SET r.data = $data, r.strength = $strength, r.trust_score = $trustScore, r.valid_from = datetime($validFrom),
+			r.valid_to = datetime($validTo), r.updated_at = datetime()
 	`
 
-	params := map[string]interface{}{
-		"id":   rel.ID,
-		"data": string(data),
-	}
-
-	_, err = session.Run(ctx, query, params)
-	return err
+	_, err = session.Run(ctx, query, params, s.txTimeout())
+	return classifyError(err)
 }
 
 // DeleteRelationship deletes a relationship
@@ -450,8 +1018,46 @@ func (s *Neo4jStore) DeleteRelationship(ctx context.Context, id string) error {
 		DELETE r
 	`
 
-	_, err := session.Run(ctx, query, map[string]interface{}{"id": id})
-	return err
+	_, err := session.Run(ctx, query, map[string]interface{}{"id": id}, s.txTimeout())
+	return classifyError(err)
+}
+
+// DeleteRelationshipsByFilter deletes every relationship matching filter in
+// a single statement and returns how many were deleted. Soft deletes close
+// the validity of active relationships and leave already closed ones alone.
+// A filter without asset IDs or types is rejected so a mistake cannot clear
+// the whole graph.
+func (s *Neo4jStore) DeleteRelationshipsByFilter(ctx context.Context, filter models.RelationshipDeleteFilter) (int, error) {
+	if len(filter.AssetIDs) == 0 && len(filter.Types) == 0 {
+		return 0, apperrors.Invalid("relationship delete filter requires asset IDs or types")
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	query, params := relationshipMatch(filter.RelationshipFilter)
+	if filter.Hard {
+		query += `
+		DELETE r
+		RETURN count(*) as deleted`
+	} else {
+		query += ` AND (r.valid_to IS NULL OR r.valid_to > datetime())
+		SET r.valid_to = datetime(), r.updated_at = datetime()
+		RETURN count(r) as deleted`
+	}
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	record, err := result.Single(ctx)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	deleted, _ := record.AsMap()["deleted"].(int64)
+
+	log.Printf("Deleted %d relationships by filter (hard: %t)", deleted, filter.Hard)
+	return int(deleted), nil
 }
 
 // ListRelationships retrieves relationships based on filter
@@ -459,12 +1065,140 @@ func (s *Neo4jStore) ListRelationships(ctx context.Context, filter models.Relati
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
+	// Fetch one row past the cap to detect truncation
+	limit, capped := s.resultCap(ctx, filter.Limit)
+	if capped {
+		filter.Limit = limit + 1
+	}
+
+	query, params := buildRelationshipQuery(filter)
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	var relationships []models.Relationship
+	for result.Next(ctx) {
+		rel, err := recordToRelationship(result.Record().AsMap())
+		if err != nil {
+			log.Printf("Failed to read relationship: %v", err)
+			continue
+		}
+		relationships = append(relationships, rel)
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	if capped && len(relationships) > limit {
+		resultInfoFrom(ctx).markTruncated(limit)
+		relationships = relationships[:limit]
+	}
+
+	return relationships, nil
+}
+
+// CountRelationships returns the number of relationships matching filter
+func (s *Neo4jStore) CountRelationships(ctx context.Context, filter models.RelationshipFilter) (int, error) {
+	query, params := relationshipMatch(filter)
+	return s.count(ctx, query+" RETURN count(r) as total", params)
+}
+
+// StreamRelationships streams relationships matching filter without
+// buffering the result. Channel semantics match StreamAssets.
+func (s *Neo4jStore) StreamRelationships(ctx context.Context, filter models.RelationshipFilter) (<-chan models.Relationship, <-chan error) {
+	relationships := make(chan models.Relationship, streamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(relationships)
+
+		session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+		defer session.Close(ctx)
+
+		query, params := buildRelationshipQuery(filter)
+
+		result, err := session.Run(ctx, query, params)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		for result.Next(ctx) {
+			rel, err := recordToRelationship(result.Record().AsMap())
+			if err != nil {
+				log.Printf("Failed to read relationship: %v", err)
+				continue
+			}
+
+			select {
+			case relationships <- rel:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := result.Err(); err != nil {
+			errs <- err
+		}
+	}()
+
+	return relationships, errs
+}
+
+// relationshipCreatedAt orders relationships created before creation times
+// were recorded first, matching the zero CreatedAt they are read with
+const relationshipCreatedAt = "coalesce(r.created_at, datetime('0001-01-01T00:00:00Z'))"
+
+// buildRelationshipQuery builds the Cypher query and parameters for a relationship filter
+func buildRelationshipQuery(filter models.RelationshipFilter) (string, map[string]interface{}) {
+	query, params := relationshipMatch(filter)
+
+	if filter.After != nil {
+		query += " AND (" + relationshipCreatedAt + " > datetime($afterCreatedAt) OR (" +
+			relationshipCreatedAt + " = datetime($afterCreatedAt) AND r.id > $afterId))"
+		params["afterCreatedAt"] = filter.After.CreatedAt.UTC().Format(time.RFC3339Nano)
+		params["afterId"] = filter.After.ID
+	}
+
+	query += " RETURN " + relationshipColumns
+
+	// An edge between two queried assets is oriented from its source
+	if len(filter.AssetIDs) > 0 {
+		query += ", CASE WHEN from.id IN $assetIds THEN 'outgoing' ELSE 'incoming' END as direction"
+	}
+
+	// Pages are taken in a fixed order so consecutive pages neither skip
+	// nor repeat relationships; unpaged listings are streamed unsorted
+	if filter.Limit > 0 || filter.Offset > 0 || filter.After != nil {
+		query += " ORDER BY " + relationshipCreatedAt + ", r.id"
+	}
+
+	if filter.Offset > 0 {
+		query += " SKIP $offset"
+		params["offset"] = filter.Offset
+	}
+
+	if filter.Limit > 0 {
+		query += " LIMIT $limit"
+		params["limit"] = filter.Limit
+	}
+
+	return query, params
+}
+
+// relationshipMatch builds the MATCH and WHERE clauses selecting the
+// relationships r from (from) to (to) matching filter
+func relationshipMatch(filter models.RelationshipFilter) (string, map[string]interface{}) {
 	query := `
 		MATCH (from)-[r]->(to)
-		WHERE 1=1
+		WHERE type(r) IN $relationshipTypes
 	`
 
-	params := make(map[string]interface{})
+	params := map[string]interface{}{"relationshipTypes": assetRelationshipTypes}
 
 	if len(filter.AssetIDs) > 0 {
 		query += " AND (from.id IN $assetIds OR to.id IN $assetIds)"
@@ -481,27 +1215,120 @@ func (s *Neo4jStore) ListRelationships(ctx context.Context, filter models.Relati
 		params["now"] = time.Now().Format(time.RFC3339)
 	}
 
-	query += " RETURN r.data as data"
+	if !filter.ValidAt.IsZero() {
+		query += " AND r.valid_from <= datetime($validAt) AND (r.valid_to IS NULL OR r.valid_to > datetime($validAt))"
+		params["validAt"] = filter.ValidAt.Format(time.RFC3339)
+	}
+
+	if filter.MinStrength > 0 {
+		query += " AND r.strength >= $minStrength"
+		params["minStrength"] = filter.MinStrength
+	}
+
+	if filter.MaxStrength > 0 {
+		query += " AND r.strength <= $maxStrength"
+		params["maxStrength"] = filter.MaxStrength
+	}
+
+	return query, params
+}
+
+// relationshipColumns are the columns read for a relationship matched as
+// (from)-[r]->(to)
+const relationshipColumns = `r.id as id, type(r) as type, from.id as fromId, to.id as toId,
+		r.data as data, r.strength as strength, r.trust_score as trustScore,
+		r.valid_from as validFrom, r.valid_to as validTo, r.created_at as createdAt, r.updated_at as updatedAt, r.reactivated_at as reactivatedAt,
+		r.last_revoked_at as lastRevokedAt, r.reactivation_count as reactivationCount`
+
+// relationshipData is the part of a relationship kept in the data blob
+type relationshipData struct {
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+	Description string                 `json:"description,omitempty"`
+}
 
-	result, err := session.Run(ctx, query, params)
+// relationshipParams returns the write parameters for a relationship
+func relationshipParams(rel models.Relationship) (map[string]interface{}, error) {
+	data, err := json.Marshal(relationshipData{
+		Properties:  rel.Properties,
+		Description: rel.Description,
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to marshal relationship: %w", err)
 	}
 
-	var relationships []models.Relationship
-	for result.Next(ctx) {
-		record := result.Record()
-		data := record.AsMap()["data"].(string)
-		
-		var rel models.Relationship
+	var validTo interface{}
+	if rel.ValidTo != nil {
+		validTo = rel.ValidTo.Format(time.RFC3339)
+	}
+
+	return map[string]interface{}{
+		"id":        rel.ID,
+		"fromId":    rel.FromAssetID,
+		"toId":      rel.ToAssetID,
+		"data":      string(data),
+		"strength":   rel.Strength,
+		"trustScore": rel.TrustScore,
+		"validFrom":  rel.ValidFrom.Format(time.RFC3339),
+		"validTo":    validTo,
+	}, nil
+}
+
+// recordToRelationship builds a relationship from relationshipColumns. Edges
+// written before properties were promoted carry everything in the data blob,
+// so edge properties only override blob fields when present. Results of
+// asset-filtered queries also carry a direction used to orient them.
+func recordToRelationship(values map[string]interface{}) (models.Relationship, error) {
+	var rel models.Relationship
+	if data, _ := values["data"].(string); data != "" {
 		if err := json.Unmarshal([]byte(data), &rel); err != nil {
-			log.Printf("Failed to unmarshal relationship: %v", err)
-			continue
+			return rel, fmt.Errorf("failed to unmarshal relationship: %w", err)
 		}
-		relationships = append(relationships, rel)
 	}
 
-	return relationships, nil
+	if id, ok := values["id"].(string); ok {
+		rel.ID = id
+	}
+	if relType, ok := values["type"].(string); ok {
+		rel.Type = models.RelationshipType(relType)
+	}
+	if fromID, ok := values["fromId"].(string); ok {
+		rel.FromAssetID = fromID
+	}
+	if toID, ok := values["toId"].(string); ok {
+		rel.ToAssetID = toID
+	}
+	if strength, ok := values["strength"].(float64); ok {
+		rel.Strength = strength
+	}
+	if trustScore, ok := values["trustScore"].(float64); ok {
+		rel.TrustScore = &trustScore
+	}
+	if validFrom, ok := values["validFrom"].(time.Time); ok {
+		rel.ValidFrom = validFrom
+	}
+	if validTo, ok := values["validTo"].(time.Time); ok {
+		rel.ValidTo = &validTo
+	}
+	if createdAt, ok := values["createdAt"].(time.Time); ok {
+		rel.CreatedAt = createdAt
+	}
+	if updatedAt, ok := values["updatedAt"].(time.Time); ok {
+		rel.UpdatedAt = updatedAt
+	}
+	if reactivatedAt, ok := values["reactivatedAt"].(time.Time); ok {
+		rel.ReactivatedAt = &reactivatedAt
+	}
+	if lastRevokedAt, ok := values["lastRevokedAt"].(time.Time); ok {
+		rel.LastRevokedAt = &lastRevokedAt
+	}
+	if count, ok := values["reactivationCount"].(int64); ok {
+		rel.ReactivationCount = int(count)
+	}
+	if direction, ok := values["direction"].(string); ok {
+		rel.Orient(models.RelationshipDirection(direction))
+	}
+
+	return rel, nil
 }
 
 // SearchRelationships performs search on relationships
@@ -510,241 +1337,1422 @@ func (s *Neo4jStore) SearchRelationships(ctx context.Context, query models.Relat
 	return nil, fmt.Errorf("not implemented")
 }
 
+// maxNeighborDepth bounds the hops GetNeighbors expands, as every path
+// within it is returned
+const maxNeighborDepth = 5
+
 // GetNeighbors retrieves neighboring assets and relationships
 func (s *Neo4jStore) GetNeighbors(ctx context.Context, assetID string, direction string, maxDepth int) ([]models.Asset, []models.Relationship, error) {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
-	var query string
-	switch direction {
-	case "outgoing":
-		query = `
-			MATCH (start {id: $assetId})-[r*1..$maxDepth]->(neighbor)
-			RETURN DISTINCT neighbor.data as neighborData, labels(neighbor) as labels, r as relationships
-		`
-	case "incoming":
-		query = `
-			MATCH (start {id: $assetId})<-[r*1..$maxDepth]-(neighbor)
-			RETURN DISTINCT neighbor.data as neighborData, labels(neighbor) as labels, r as relationships
-		`
-	default: // both
-		query = `
-			MATCH (start {id: $assetId})-[r*1..$maxDepth]-(neighbor)
-			RETURN DISTINCT neighbor.data as neighborData, labels(neighbor) as labels, r as relationships
-		`
-	}
-
+	query := neighborsQuery(direction, maxDepth)
 	params := map[string]interface{}{
-		"assetId":  assetID,
-		"maxDepth": maxDepth,
+		"assetId":           assetID,
+		"relationshipTypes": assetRelationshipTypes,
 	}
 
-	result, err := session.Run(ctx, query, params)
+	result, err := session.Run(ctx, query, params, s.txTimeout())
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, classifyError(err)
 	}
 
 	var assets []models.Asset
 	var relationships []models.Relationship
+	seenAssets := make(map[string]bool)
+	seenRels := make(map[string]bool)
 
 	for result.Next(ctx) {
 		record := result.Record()
-		
-		// Process neighbor asset
-		neighborData := record.AsMap()["neighborData"].(string)
-		labels := record.AsMap()["labels"].([]string)
-		
-		assetType := models.AssetType("")
-		for _, label := range labels {
-			if label != "" {
-				assetType = models.AssetType(label)
-				break
-			}
-		}
 
-		asset, err := s.unmarshalAsset(neighborData, assetType)
+		asset, err := s.recordToAsset(record)
 		if err != nil {
 			log.Printf("Failed to unmarshal neighbor asset: %v", err)
-			continue
+		} else if !seenAssets[asset.GetID()] {
+			seenAssets[asset.GetID()] = true
+			assets = append(assets, asset)
 		}
-		assets = append(assets, asset)
+
+		// Every path repeats the relationships of the shorter paths it
+		// extends, so they are deduplicated by ID
+		rels, _ := record.AsMap()["relationships"].([]interface{})
+		for _, value := range rels {
+			values, _ := value.(map[string]interface{})
+			rel, err := recordToRelationship(values)
+			if err != nil {
+				log.Printf("Failed to unmarshal neighbor relationship: %v", err)
+				continue
+			}
+			if seenRels[rel.ID] {
+				continue
+			}
+			seenRels[rel.ID] = true
+			relationships = append(relationships, rel)
+		}
+	}
+	if err := result.Err(); err != nil {
+		return nil, nil, classifyError(err)
 	}
 
 	return assets, relationships, nil
 }
 
-// FindPath finds a path between two assets
-func (s *Neo4jStore) FindPath(ctx context.Context, fromAssetID, toAssetID string, maxDepth int) (*models.GraphPath, error) {
-	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
-
-	query := `
-		MATCH path = shortestPath((start {id: $fromId})-[*1..$maxDepth]-(end {id: $toId}))
-		RETURN path
-	`
-
-	params := map[string]interface{}{
-		"fromId":   fromAssetID,
-		"toId":     toAssetID,
-		"maxDepth": maxDepth,
+// neighborsQuery builds the query for GetNeighbors. Paths only follow asset
+// relationships, so they never pass through findings, and findings and risk
+// snapshots are never returned as neighbors.
+func neighborsQuery(direction string, maxDepth int) string {
+	if maxDepth <= 0 {
+		maxDepth = 1
 	}
-
-	result, err := session.Run(ctx, query, params)
-	if err != nil {
-		return nil, err
+	if maxDepth > maxNeighborDepth {
+		maxDepth = maxNeighborDepth
 	}
 
-	record, err := result.Single(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("no path found: %w", err)
-	}
+	// Variable-length bounds cannot be parameters, so the validated depth is
+	// formatted into the pattern
+	pattern := fmt.Sprintf("[rels*1..%d]", maxDepth)
 
-	// Process the path result
-	path := record.AsMap()["path"]
-	// This would need to be processed to extract nodes and relationships
-	// For now, return a placeholder
-	return &models.GraphPath{
-		TotalWeight: 0,
-		Length:      0,
-	}, nil
-}
+	var match string
+	switch direction {
+	case "outgoing":
+		match = "(start {id: $assetId})-" + pattern + "->(neighbor)"
+	case "incoming":
+		match = "(start {id: $assetId})<-" + pattern + "-(neighbor)"
+	default: // both
+		match = "(start {id: $assetId})-" + pattern + "-(neighbor)"
+	}
 
-// FindAttackPaths finds potential attack paths
-func (s *Neo4jStore) FindAttackPaths(ctx context.Context, entryPoints []string, targets []string, maxDepth int) ([]models.GraphPath, error) {
-	// Implementation for attack path analysis
-	return nil, fmt.Errorf("not implemented")
+	return `
+		MATCH ` + match + `
+		WHERE neighbor.id <> $assetId AND NOT neighbor:Finding AND NOT neighbor:RiskSnapshot
+			AND ALL(r IN rels WHERE type(r) IN $relationshipTypes)
+		RETURN neighbor.data as data, labels(neighbor) as labels,
+			neighbor.first_seen as firstSeen, neighbor.last_seen as lastSeen, neighbor.risk_score as riskScore,
+			[r IN rels | {id: r.id, type: type(r), fromId: startNode(r).id, toId: endNode(r).id,
+				data: r.data, strength: r.strength, trustScore: r.trust_score, validFrom: r.valid_from, validTo: r.valid_to,
+				createdAt: r.created_at, updatedAt: r.updated_at}] as relationships
+	`
 }
 
-// GetConnectedComponents finds connected components
-func (s *Neo4jStore) GetConnectedComponents(ctx context.Context, assetIDs []string) ([][]string, error) {
-	// Implementation for connected components analysis
-	return nil, fmt.Errorf("not implemented")
-}
+// GetNeighborsByType retrieves the assets reachable from an asset within
+// maxDepth hops using only the given relationship types. No types means every
+// relationship is followed.
+func (s *Neo4jStore) GetNeighborsByType(ctx context.Context, assetID string, direction string, maxDepth int, relTypes []models.RelationshipType) ([]models.Asset, error) {
+	query, err := neighborsByTypeQuery(direction, maxDepth, relTypes)
+	if err != nil {
+		return nil, err
+	}
 
-// GetAssetRisk retrieves asset risk score
-func (s *Neo4jStore) GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error) {
-	// Implementation for risk retrieval
-	return models.RiskScore{}, fmt.Errorf("not implemented")
+	return s.runNeighborsByType(ctx, query, []string{assetID})
 }
 
-// UpdateAssetRisk updates asset risk score
-func (s *Neo4jStore) UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error {
-	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	query := `
-		MATCH (n {id: $assetId})
-		SET n.risk_score = $riskScore, n.risk_updated_at = datetime()
-	`
+// GetFrontierNeighbors retrieves the direct neighbors of any of the given
+// assets over the given relationship types in a single query, so a
+// breadth-first walk costs one round trip per level. The starting assets are
+// never returned.
+func (s *Neo4jStore) GetFrontierNeighbors(ctx context.Context, assetIDs []string, direction string, relTypes []models.RelationshipType) ([]models.Asset, error) {
+	if len(assetIDs) == 0 {
+		return nil, nil
+	}
 
-	params := map[string]interface{}{
-		"assetId":    risk.AssetID,
-		"riskScore":  risk.Score,
+	query, err := neighborsByTypeQuery(direction, 1, relTypes)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := session.Run(ctx, query, params)
-	return err
+	return s.runNeighborsByType(ctx, query, assetIDs)
 }
 
-// GetAssetFindings retrieves findings for an asset
-func (s *Neo4jStore) GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+// runNeighborsByType runs a query built by neighborsByTypeQuery from the
+// given starting assets
+func (s *Neo4jStore) runNeighborsByType(ctx context.Context, query string, assetIDs []string) ([]models.Asset, error) {
 	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
-	query := `
-		MATCH (asset {id: $assetId})<-[:GENERATES]-(finding:Finding)
-		RETURN finding.data as data
-	`
-
-	result, err := session.Run(ctx, query, map[string]interface{}{"assetId": assetID})
+	result, err := session.Run(ctx, query, map[string]interface{}{"assetIds": assetIDs}, s.txTimeout())
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
-	var findings []models.Finding
+	var assets []models.Asset
 	for result.Next(ctx) {
-		record := result.Record()
-		data := record.AsMap()["data"].(string)
-		
-		var finding models.Finding
-		if err := json.Unmarshal([]byte(data), &finding); err != nil {
-			log.Printf("Failed to unmarshal finding: %v", err)
+		asset, err := s.recordToAsset(result.Record())
+		if err != nil {
+			log.Printf("Failed to unmarshal neighbor asset: %v", err)
 			continue
 		}
-		findings = append(findings, finding)
+		assets = append(assets, asset)
+	}
+	if err := result.Err(); err != nil {
+		return nil, classifyError(err)
 	}
 
-	return findings, nil
+	return assets, nil
 }
 
-// CreateFinding creates a new finding
-func (s *Neo4jStore) CreateFinding(ctx context.Context, finding models.Finding) error {
-	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
+// neighborsByTypeQuery builds the query for GetNeighborsByType and
+// GetFrontierNeighbors, starting from the assets in $assetIds. Findings and
+// risk snapshots are not assets, so they are never returned as neighbors.
+func neighborsByTypeQuery(direction string, maxDepth int, relTypes []models.RelationshipType) (string, error) {
+	if maxDepth <= 0 {
+		return "", apperrors.Invalid("maxDepth must be positive, got %d", maxDepth)
+	}
 
-	data, err := json.Marshal(finding)
-	if err != nil {
-		return fmt.Errorf("failed to marshal finding: %w", err)
+	types := make([]string, 0, len(relTypes))
+	for _, relType := range relTypes {
+		if !identifierPattern.MatchString(string(relType)) {
+			return "", apperrors.Invalid("invalid relationship type: %q", relType)
+		}
+		types = append(types, string(relType))
 	}
 
-	query := `
-		MATCH (asset {id: $assetId})
-		CREATE (f:Finding {id: $id, data: $data, severity: $severity, risk_score: $riskScore, status: $status, policy_id: $policyId})
-		CREATE (f)-[:GENERATES]->(asset)
-		SET f.created_at = datetime(), f.updated_at = datetime()
-	`
+	// Variable-length bounds cannot be parameters, so the validated depth and
+	// types are formatted into the pattern
+	pattern := fmt.Sprintf("[*1..%d]", maxDepth)
+	if len(types) > 0 {
+		pattern = fmt.Sprintf("[:%s*1..%d]", strings.Join(types, "|"), maxDepth)
+	}
 
-	params := map[string]interface{}{
-		"id":        finding.ID,
-		"assetId":   finding.AssetID,
-		"data":      string(data),
-		"severity":  finding.Severity,
-		"riskScore": finding.RiskScore,
-		"status":    finding.Status,
-		"policyId":  finding.PolicyID,
+	var match string
+	switch direction {
+	case "outgoing":
+		match = "(start)-" + pattern + "->(neighbor)"
+	case "incoming":
+		match = "(start)<-" + pattern + "-(neighbor)"
+	default: // both
+		match = "(start)-" + pattern + "-(neighbor)"
 	}
 
-	_, err = session.Run(ctx, query, params)
-	return err
+	return `
+		MATCH ` + match + `
+		WHERE start.id IN $assetIds AND NOT neighbor.id IN $assetIds AND NOT neighbor:Finding AND NOT neighbor:RiskSnapshot
+		RETURN DISTINCT neighbor.data as data, labels(neighbor) as labels,
+			neighbor.first_seen as firstSeen, neighbor.last_seen as lastSeen, neighbor.risk_score as riskScore
+	`, nil
 }
 
-// UpdateFinding updates an existing finding
-func (s *Neo4jStore) UpdateFinding(ctx context.Context, finding models.Finding) error {
-	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
+// Neighbor is an asset directly reachable from another asset
+type Neighbor struct {
+	ID               string  `json:"id"`
+	Type             string  `json:"type"`
+	RelationshipType string  `json:"relationship_type"`
+	RiskScore        float64 `json:"risk_score"`
+}
 
-	data, err := json.Marshal(finding)
-	if err != nil {
-		return fmt.Errorf("failed to marshal finding: %w", err)
+// GetNeighborsBatch returns the outgoing neighbors over active relationships
+// of every given asset in a single query, keyed by asset ID
+func (s *Neo4jStore) GetNeighborsBatch(ctx context.Context, assetIDs []string) (map[string][]Neighbor, error) {
+	neighbors := make(map[string][]Neighbor, len(assetIDs))
+	if len(assetIDs) == 0 {
+		return neighbors, nil
 	}
 
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
 	query := `
-		MATCH (f:Finding {id: $id})
-		SET f.data = $data, f.severity = $severity, f.risk_score = $riskScore, f.status = $status, f.updated_at = datetime()
+		UNWIND $assetIds AS assetId
+		MATCH (n {id: assetId})-[r]->(m)
+		WHERE NOT m:Finding AND NOT m:RiskSnapshot
+			AND (r.valid_to IS NULL OR r.valid_to > datetime())
+		RETURN assetId, m.id as id, labels(m) as labels, type(r) as relType, m.risk_score as riskScore
 	`
 
-	params := map[string]interface{}{
-		"id":        finding.ID,
-		"data":      string(data),
-		"severity":  finding.Severity,
-		"riskScore": finding.RiskScore,
-		"status":    finding.Status,
+	result, err := session.Run(ctx, query, map[string]interface{}{"assetIds": assetIDs}, s.txTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get neighbors: %w", classifyError(err))
 	}
 
-	_, err = session.Run(ctx, query, params)
-	return err
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		assetID, _ := values["assetId"].(string)
+
+		neighbor := Neighbor{}
+		neighbor.ID, _ = values["id"].(string)
+		labels, _ := values["labels"].([]interface{})
+		neighbor.Type = string(assetTypeOfLabels(labels))
+		neighbor.RelationshipType, _ = values["relType"].(string)
+		neighbor.RiskScore, _ = values["riskScore"].(float64)
+
+		neighbors[assetID] = append(neighbors[assetID], neighbor)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get neighbors: %w", classifyError(err))
+	}
+
+	return neighbors, nil
+}
+
+// FindPath finds the shortest path of at most maxDepth hops between two
+// assets, in either direction along each relationship. It returns a not
+// found error when the assets are not connected within maxDepth.
+func (s *Neo4jStore) FindPath(ctx context.Context, fromAssetID, toAssetID string, maxDepth int) (*models.GraphPath, error) {
+	if maxDepth <= 0 {
+		maxDepth = defaultAttackPathDepth
+	}
+	if maxDepth > maxAttackPathDepth {
+		maxDepth = maxAttackPathDepth
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	// Variable-length bounds cannot be parameters, so the bounded depth is
+	// formatted into the pattern
+	query := fmt.Sprintf(`
+		MATCH path = shortestPath((start {id: $fromId})-[*1..%d]-(end {id: $toId}))
+		RETURN path
+	`, maxDepth)
+
+	params := map[string]interface{}{
+		"fromId": fromAssetID,
+		"toId":   toAssetID,
+	}
+
+	explain := explanationFrom(ctx)
+	start := time.Now()
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	if !result.Next(ctx) {
+		explain.recordQuery(query, params, time.Since(start))
+		explain.recordPaths(0, 0)
+		if err := result.Err(); err != nil {
+			return nil, fmt.Errorf("failed to find path: %w", classifyError(err))
+		}
+		return nil, apperrors.NotFound("no path found from %s to %s within %d hops", fromAssetID, toAssetID, maxDepth)
+	}
+	explain.recordQuery(query, params, time.Since(start))
+	explain.recordPaths(1, 1)
+
+	value, _ := result.Record().Get("path")
+	neoPath, ok := value.(neo4j.Path)
+	if !ok {
+		return nil, fmt.Errorf("unexpected path result %T", value)
+	}
+
+	path, err := s.pathToGraphPath(neoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode path: %w", err)
+	}
+	return &path, nil
+}
+
+// pathToGraphPath builds a path from a driver path. Relationships are
+// reported in their stored direction, which on an undirected match may be
+// against the direction of the walk.
+func (s *Neo4jStore) pathToGraphPath(neoPath neo4j.Path) (models.GraphPath, error) {
+	assetIDs := make(map[string]interface{}, len(neoPath.Nodes))
+	nodes := make([]interface{}, 0, len(neoPath.Nodes))
+	for _, node := range neoPath.Nodes {
+		labels := make([]interface{}, 0, len(node.Labels))
+		for _, label := range node.Labels {
+			labels = append(labels, label)
+		}
+		nodes = append(nodes, map[string]interface{}{"data": node.Props["data"], "labels": labels})
+		assetIDs[node.ElementId] = node.Props["id"]
+	}
+
+	rels := make([]interface{}, 0, len(neoPath.Relationships))
+	for _, rel := range neoPath.Relationships {
+		rels = append(rels, map[string]interface{}{
+			"id":        rel.Props["id"],
+			"type":      rel.Type,
+			"fromId":    assetIDs[rel.StartElementId],
+			"toId":      assetIDs[rel.EndElementId],
+			"data":      rel.Props["data"],
+			"strength":  rel.Props["strength"],
+			"validFrom": rel.Props["valid_from"],
+			"validTo":   rel.Props["valid_to"],
+			"createdAt": rel.Props["created_at"],
+			"updatedAt": rel.Props["updated_at"],
+		})
+	}
+
+	path, _, err := s.recordToGraphPath(nodes, rels)
+	return path, err
+}
+
+// Attack path search limits
+const (
+	defaultAttackPathDepth = 4
+	maxAttackPathDepth     = 8
+	maxAttackPaths         = 100
+)
+
+// attackPathRelTypes are the relationship types an attacker can move along
+var attackPathRelTypes = []models.RelationshipType{
+	models.RelationshipAssumesRole,
+	models.RelationshipHasAccessTo,
+	models.RelationshipConnectedTo,
+	models.RelationshipRunsOn,
+	models.RelationshipManages,
+	models.RelationshipDependsOn,
+	models.RelationshipStores,
+}
+
+// FindAttackPaths finds paths of at most maxDepth hops from any entry point
+// to any target along active relationships of the types an attacker can move
+// along, up to maxAttackPaths. With targets, the shortest path of each entry
+// point and target pair is returned. No targets means simple paths to any
+// asset; the search stops at the limit rather than enumerating every path,
+// so these are the first paths found, not the shortest overall. Paths are
+// returned shortest first.
+func (s *Neo4jStore) FindAttackPaths(ctx context.Context, entryPoints []string, targets []string, maxDepth int) ([]models.GraphPath, error) {
+	if len(entryPoints) == 0 {
+		return nil, apperrors.Invalid("at least one entry point is required")
+	}
+	if maxDepth <= 0 {
+		maxDepth = defaultAttackPathDepth
+	}
+	if maxDepth > maxAttackPathDepth {
+		maxDepth = maxAttackPathDepth
+	}
+	if targets == nil {
+		targets = []string{}
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := attackPathQuery(maxDepth, len(targets) > 0)
+	params := map[string]interface{}{
+		"entryPoints": entryPoints,
+		"targets":     targets,
+		"limit":       maxAttackPaths,
+	}
+
+	explain := explanationFrom(ctx)
+	start := time.Now()
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find attack paths: %w", classifyError(err))
+	}
+
+	var paths []models.GraphPath
+	seen := make(map[string]bool)
+	evaluated := 0
+	for result.Next(ctx) {
+		evaluated++
+		values := result.Record().AsMap()
+		path, key, err := s.recordToGraphPath(values["nodes"], values["relationships"])
+		if err != nil {
+			log.Printf("Failed to decode attack path: %v", err)
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		path.Likelihood = graphPathLikelihood(path, values["findings"])
+		paths = append(paths, path)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find attack paths: %w", classifyError(err))
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i].Edges) < len(paths[j].Edges)
+	})
+
+	explain.recordQuery(query, params, time.Since(start))
+	explain.recordPaths(evaluated, len(paths))
+
+	return paths, nil
+}
+
+// attackPathQuery returns the attack path query for paths of at most
+// maxDepth hops. Variable-length bounds cannot be parameters, so the depth
+// is formatted into the pattern. With targets, one shortest path is found
+// per entry point and target pair, which Neo4j searches breadth first
+// instead of enumerating every path between them. Without targets, the
+// match streams simple paths and stops at the limit; sorting them here would
+// make Neo4j enumerate every path first. Nodes are checked for repeats to
+// keep those paths simple, as Cypher only guarantees relationships are not
+// repeated.
+func attackPathQuery(maxDepth int, withTargets bool) string {
+	types := make([]string, 0, len(attackPathRelTypes))
+	for _, relType := range attackPathRelTypes {
+		types = append(types, string(relType))
+	}
+	pattern := fmt.Sprintf("(entry)-[:%s*1..%d]->(target)", strings.Join(types, "|"), maxDepth)
+
+	var match string
+	if withTargets {
+		match = fmt.Sprintf(`
+		MATCH (entry) WHERE entry.id IN $entryPoints
+		MATCH (target) WHERE target.id IN $targets AND target <> entry
+			AND NOT target:Finding AND NOT target:RiskSnapshot
+		MATCH path = shortestPath(%s)
+		WHERE all(r IN relationships(path) WHERE r.valid_to IS NULL OR r.valid_to > datetime())
+		WITH path ORDER BY length(path) LIMIT $limit`, pattern)
+	} else {
+		match = fmt.Sprintf(`
+		MATCH (entry) WHERE entry.id IN $entryPoints
+		MATCH path = %s
+		WHERE NOT target:Finding AND NOT target:RiskSnapshot
+			AND all(r IN relationships(path) WHERE r.valid_to IS NULL OR r.valid_to > datetime())
+			AND all(i IN range(0, size(nodes(path)) - 2) WHERE NOT nodes(path)[i] IN nodes(path)[i+1..])
+		WITH path LIMIT $limit`, pattern)
+	}
+
+	return match + `
+		RETURN [n IN nodes(path) | {data: n.data, labels: labels(n)}] as nodes,
+			[n IN nodes(path) | [(n)<-[:GENERATES]-(f:Finding) WHERE f.status = 'open' | {id: f.id, severity: f.severity}]] as findings,
+			[r IN relationships(path) | {id: r.id, type: type(r), fromId: startNode(r).id, toId: endNode(r).id,
+				data: r.data, strength: r.strength, trustScore: r.trust_score, validFrom: r.valid_from, validTo: r.valid_to,
+				createdAt: r.created_at, updatedAt: r.updated_at}] as relationships
+	`
+}
+
+// graphPathLikelihood scores a path with the attack path likelihood model
+// from the open findings on its nodes, as lists of maps with id and
+// severity, and the trust scores of its relationships
+func graphPathLikelihood(path models.GraphPath, findings interface{}) float64 {
+	var vulns []models.AttackPathVulnerability
+	nodeFindings, _ := findings.([]interface{})
+	for _, value := range nodeFindings {
+		list, _ := value.([]interface{})
+		for _, item := range list {
+			finding, _ := item.(map[string]interface{})
+			id, _ := finding["id"].(string)
+			vulns = append(vulns, models.AttackPathVulnerability{
+				FindingID: id,
+				Severity:  toFloat(finding["severity"]),
+			})
+		}
+	}
+
+	trustScores := make([]float64, 0, len(path.Edges))
+	for _, edge := range path.Edges {
+		trust := 1.0
+		if edge.Relationship.TrustScore != nil {
+			trust = *edge.Relationship.TrustScore
+		}
+		trustScores = append(trustScores, trust)
+	}
+
+	return models.PathLikelihood(vulns, trustScores, len(path.Edges))
+}
+
+// recordToGraphPath builds a path from lists of node maps with data and
+// labels and of relationship maps with relationshipColumns, returning it
+// with a key identifying its hops
+func (s *Neo4jStore) recordToGraphPath(nodeValues, relValues interface{}) (models.GraphPath, string, error) {
+	var path models.GraphPath
+	nodes, _ := nodeValues.([]interface{})
+	rels, _ := relValues.([]interface{})
+	if len(nodes) != len(rels)+1 {
+		return path, "", fmt.Errorf("path has %d nodes and %d relationships", len(nodes), len(rels))
+	}
+
+	assets := make([]models.Asset, 0, len(nodes))
+	for _, value := range nodes {
+		node, _ := value.(map[string]interface{})
+		labels, _ := node["labels"].([]interface{})
+
+		asset, err := s.unmarshalAsset(node["data"], assetTypeOfLabels(labels))
+		if err != nil {
+			return path, "", err
+		}
+		assets = append(assets, asset)
+	}
+
+	hops := make([]string, 0, len(rels))
+	for i, value := range rels {
+		values, _ := value.(map[string]interface{})
+		rel, err := recordToRelationship(values)
+		if err != nil {
+			return path, "", err
+		}
+
+		// Edges written before strength was recorded count as certain
+		weight := rel.Strength
+		if _, ok := values["strength"].(float64); !ok {
+			weight = 1.0
+		}
+
+		path.AddEdge(models.RelationshipEdge{
+			Relationship: rel,
+			FromAsset:    assets[i],
+			ToAsset:      assets[i+1],
+			PathWeight:   weight,
+		})
+		hops = append(hops, fmt.Sprintf("%s-%s->%s", rel.FromAssetID, rel.Type, rel.ToAssetID))
+	}
+
+	return path, strings.Join(hops, "|"), nil
+}
+
+// GetAssetRisk retrieves asset risk score. An asset the engine has never
+// scored is returned with a zero LastCalculated rather than an error, so
+// callers can tell it apart from an unknown asset.
+func (s *Neo4jStore) GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (n {id: $assetId})
+		WHERE NOT n:RiskSnapshot
+		RETURN n.risk_score as score, n.risk_updated_at as updatedAt, n.risk_data as riskData, labels(n) as labels
+		LIMIT 1
+	`
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"assetId": assetID}, s.txTimeout())
+	if err != nil {
+		return models.RiskScore{}, classifyError(err)
+	}
+	if !result.Next(ctx) {
+		if err := result.Err(); err != nil {
+			return models.RiskScore{}, classifyError(err)
+		}
+		return models.RiskScore{}, apperrors.NotFound("asset not found: %s", assetID)
+	}
+	return s.riskFromValues(assetID, result.Record().AsMap())
+}
+
+// GetAssetRisks retrieves the risk scores of several assets in one query,
+// keyed by asset ID. Unknown assets are left out; assets that have never
+// been scored have a zero LastCalculated, as in GetAssetRisk.
+func (s *Neo4jStore) GetAssetRisks(ctx context.Context, assetIDs []string) (map[string]models.RiskScore, error) {
+	risks := make(map[string]models.RiskScore, len(assetIDs))
+	if len(assetIDs) == 0 {
+		return risks, nil
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (n)
+		WHERE n.id IN $assetIds AND NOT n:RiskSnapshot AND NOT n:Finding
+		RETURN n.id as id, n.risk_score as score, n.risk_updated_at as updatedAt, n.risk_data as riskData,
+			labels(n) as labels
+	`
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"assetIds": assetIDs}, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		id, _ := values["id"].(string)
+		risk, err := s.riskFromValues(id, values)
+		if err != nil {
+			return nil, err
+		}
+		risks[id] = risk
+	}
+	if err := result.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+	return risks, nil
+}
+
+// riskFromValues builds an asset's risk score from its score, updatedAt,
+// riskData and labels query values
+func (s *Neo4jStore) riskFromValues(assetID string, values map[string]interface{}) (models.RiskScore, error) {
+	risk := models.RiskScore{AssetID: assetID}
+	if data, ok := values["riskData"].(string); ok && data != "" {
+		if err := json.Unmarshal([]byte(data), &risk); err != nil {
+			return models.RiskScore{}, fmt.Errorf("failed to unmarshal risk data: %w", err)
+		}
+	}
+
+	if labels, ok := values["labels"].([]interface{}); ok && risk.AssetType == "" {
+		risk.AssetType = assetTypeOfLabels(labels)
+	}
+
+	// The scalar properties are authoritative: decay and propagation update
+	// them without rewriting the breakdown, whose level is then stale. The
+	// level is always mapped again when a mapping is configured, so stored
+	// levels follow it.
+	score, ok := values["score"].(float64)
+	if !ok {
+		return risk, nil
+	}
+	if s.levels != nil || risk.Level == "" || risk.Score != score {
+		risk.Level = s.riskLevel(risk.AssetType, score)
+	}
+	risk.AssetID = assetID
+	risk.Score = score
+	if updatedAt, ok := values["updatedAt"].(time.Time); ok {
+		risk.LastCalculated = updatedAt
+	}
+	return risk, nil
+}
+
+// riskData serializes the full risk breakdown stored in an asset's
+// risk_data property, filling in the level when the engine left it unset
+func (s *Neo4jStore) riskData(risk models.RiskScore) (string, models.RiskLevel, error) {
+	if risk.Level == "" {
+		risk.Level = s.riskLevel(risk.AssetType, risk.Score)
+	}
+	data, err := json.Marshal(risk)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal risk for asset %s: %w", risk.AssetID, err)
+	}
+	return string(data), risk.Level, nil
+}
+
+// UpdateAssetRisk updates asset risk score and breakdown and records it in
+// the asset's risk snapshot for the day. The score is also kept as a scalar
+// property for filtering.
+func (s *Neo4jStore) UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	data, level, err := s.riskData(risk)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		MATCH (n {id: $assetId})
+		WHERE NOT n:RiskSnapshot
+		SET n.risk_score = $riskScore, n.risk_data = $riskData, n.risk_updated_at = datetime()
+		REMOVE n.undecayed_risk_score
+		MERGE (n)-[:HAS_RISK_SNAPSHOT]->(s:RiskSnapshot {asset_id: $assetId, day: date()})
+		ON CREATE SET s.min_score = $riskScore, s.max_score = $riskScore, s.score_sum = 0.0, s.samples = 0
+		SET s.score = $riskScore, s.level = $level, s.timestamp = datetime(),
+			s.min_score = CASE WHEN $riskScore < s.min_score THEN $riskScore ELSE s.min_score END,
+			s.max_score = CASE WHEN $riskScore > s.max_score THEN $riskScore ELSE s.max_score END,
+			s.score_sum = s.score_sum + $riskScore, s.samples = s.samples + 1
+	`
+
+	params := map[string]interface{}{
+		"assetId":    risk.AssetID,
+		"riskScore":  risk.Score,
+		"riskData":   data,
+		"level":      string(level),
+	}
+
+	_, err = session.Run(ctx, query, params, s.txTimeout())
+	return classifyError(err)
+}
+
+// BulkUpdateAssetRisk updates the risk scores of many assets in a single
+// transaction and records each in its asset's risk snapshot for the day
+func (s *Neo4jStore) BulkUpdateAssetRisk(ctx context.Context, risks []models.RiskScore) error {
+	if len(risks) == 0 {
+		return nil
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	rows := make([]map[string]interface{}, 0, len(risks))
+	for _, risk := range risks {
+		data, level, err := s.riskData(risk)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, map[string]interface{}{
+			"assetId":  risk.AssetID,
+			"score":    risk.Score,
+			"riskData": data,
+			"level":    string(level),
+		})
+	}
+
+	query := `
+		UNWIND $rows AS row
+		MATCH (n {id: row.assetId})
+		WHERE NOT n:RiskSnapshot
+		SET n.risk_score = row.score, n.risk_data = row.riskData, n.risk_updated_at = datetime()
+		REMOVE n.undecayed_risk_score
+		MERGE (n)-[:HAS_RISK_SNAPSHOT]->(s:RiskSnapshot {asset_id: row.assetId, day: date()})
+		ON CREATE SET s.min_score = row.score, s.max_score = row.score, s.score_sum = 0.0, s.samples = 0
+		SET s.score = row.score, s.level = row.level, s.timestamp = datetime(),
+			s.min_score = CASE WHEN row.score < s.min_score THEN row.score ELSE s.min_score END,
+			s.max_score = CASE WHEN row.score > s.max_score THEN row.score ELSE s.max_score END,
+			s.score_sum = s.score_sum + row.score, s.samples = s.samples + 1
+	`
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		result, err := tx.Run(ctx, query, map[string]interface{}{"rows": rows})
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume(ctx)
+	}, s.txTimeout())
+	if err != nil {
+		return fmt.Errorf("failed to update risk for %d assets: %w", len(risks), classifyError(err))
+	}
+
+	return nil
+}
+
+// GetAssetFindings retrieves findings for an asset
+func (s *Neo4jStore) GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (asset {id: $assetId})<-[:GENERATES]-(finding:Finding)
+		RETURN finding.data as data
+	`
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"assetId": assetID}, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	var findings []models.Finding
+	for result.Next(ctx) {
+		record := result.Record()
+		data := record.AsMap()["data"].(string)
+		
+		var finding models.Finding
+		if err := json.Unmarshal([]byte(data), &finding); err != nil {
+			log.Printf("Failed to unmarshal finding: %v", err)
+			continue
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+// GetFindingsForAssets retrieves the findings of several assets in one
+// query, keyed by asset ID. Assets without findings are left out.
+func (s *Neo4jStore) GetFindingsForAssets(ctx context.Context, assetIDs []string) (map[string][]models.Finding, error) {
+	findings := make(map[string][]models.Finding, len(assetIDs))
+	if len(assetIDs) == 0 {
+		return findings, nil
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (asset)<-[:GENERATES]-(finding:Finding)
+		WHERE asset.id IN $assetIds
+		RETURN asset.id as assetId, finding.data as data
+	`
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"assetIds": assetIDs}, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		assetID, _ := values["assetId"].(string)
+		data, _ := values["data"].(string)
+
+		var finding models.Finding
+		if err := json.Unmarshal([]byte(data), &finding); err != nil {
+			log.Printf("Failed to unmarshal finding: %v", err)
+			continue
+		}
+		findings[assetID] = append(findings[assetID], finding)
+	}
+	if err := result.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+	return findings, nil
+}
+
+// GetUnresolvedPolicyFindings retrieves the open and suppressed findings
+// policies raised for an asset, oldest first, so they can be matched to a
+// new evaluation by policy ID
+func (s *Neo4jStore) GetUnresolvedPolicyFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (asset {id: $assetId})<-[:GENERATES]-(finding:Finding)
+		WHERE coalesce(finding.policy_id, '') <> '' AND finding.status <> 'resolved'
+		RETURN finding.data as data
+		ORDER BY finding.created_at, finding.id
+	`
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"assetId": assetID}, s.txTimeout())
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	var findings []models.Finding
+	for result.Next(ctx) {
+		data, _ := result.Record().AsMap()["data"].(string)
+
+		var finding models.Finding
+		if err := json.Unmarshal([]byte(data), &finding); err != nil {
+			log.Printf("Failed to unmarshal finding: %v", err)
+			continue
+		}
+		findings = append(findings, finding)
+	}
+	if err := result.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+
+	return findings, nil
+}
+
+// GetFinding retrieves a finding by ID
+func (s *Neo4jStore) GetFinding(ctx context.Context, id string) (models.Finding, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, "MATCH (f:Finding {id: $id}) RETURN f.data as data", map[string]interface{}{"id": id}, s.txTimeout())
+	if err != nil {
+		return models.Finding{}, classifyError(err)
+	}
+
+	if !result.Next(ctx) {
+		if err := result.Err(); err != nil {
+			return models.Finding{}, classifyError(err)
+		}
+		return models.Finding{}, apperrors.NotFound("finding not found: %s", id)
+	}
+
+	var finding models.Finding
+	data, _ := result.Record().AsMap()["data"].(string)
+	if err := json.Unmarshal([]byte(data), &finding); err != nil {
+		return models.Finding{}, fmt.Errorf("failed to unmarshal finding: %w", err)
+	}
+	return finding, nil
+}
+
+// ListFindings lists the findings matching filter, most severe first
+func (s *Neo4jStore) ListFindings(ctx context.Context, filter models.FindingFilter) ([]models.Finding, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	// Fetch one row past the cap to detect truncation
+	limit, capped := s.resultCap(ctx, filter.Limit)
+	if capped {
+		filter.Limit = limit + 1
+	}
+
+	query := `
+		MATCH (finding:Finding)
+		WHERE 1=1
+	`
+	params := make(map[string]interface{})
+
+	if len(filter.Statuses) > 0 {
+		query += " AND finding.status IN $statuses"
+		params["statuses"] = filter.Statuses
+	}
+	if filter.MinSeverity > 0 {
+		query += " AND finding.severity >= $minSeverity"
+		params["minSeverity"] = filter.MinSeverity
+	}
+	if filter.MaxSeverity > 0 {
+		query += " AND finding.severity <= $maxSeverity"
+		params["maxSeverity"] = filter.MaxSeverity
+	}
+	if len(filter.PolicyIDs) > 0 {
+		query += " AND finding.policy_id IN $policyIds"
+		params["policyIds"] = filter.PolicyIDs
+	}
+	if len(filter.AssetIDs) > 0 {
+		query += " AND EXISTS { MATCH (finding)-[:GENERATES]->(asset) WHERE asset.id IN $assetIds }"
+		params["assetIds"] = filter.AssetIDs
+	}
+
+	query += " RETURN finding.data as data ORDER BY finding.severity DESC, finding.id"
+
+	if filter.Offset > 0 {
+		query += " SKIP $offset"
+		params["offset"] = filter.Offset
+	}
+	if filter.Limit > 0 {
+		query += " LIMIT $limit"
+		params["limit"] = filter.Limit
+	}
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list findings: %w", classifyError(err))
+	}
+
+	findings := make([]models.Finding, 0)
+	for result.Next(ctx) {
+		data, _ := result.Record().AsMap()["data"].(string)
+
+		var finding models.Finding
+		if err := json.Unmarshal([]byte(data), &finding); err != nil {
+			log.Printf("Failed to unmarshal finding: %v", err)
+			continue
+		}
+		findings = append(findings, finding)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list findings: %w", classifyError(err))
+	}
+
+	if capped && len(findings) > limit {
+		resultInfoFrom(ctx).markTruncated(limit)
+		findings = findings[:limit]
+	}
+
+	return findings, nil
+}
+
+// CreateFinding creates a new finding
+func (s *Neo4jStore) CreateFinding(ctx context.Context, finding models.Finding) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	data, err := json.Marshal(finding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal finding: %w", err)
+	}
+
+	query := `
+		MATCH (asset {id: $assetId})
+		CREATE (f:Finding {id: $id, data: $data, severity: $severity, risk_score: $riskScore, status: $status, policy_id: $policyId})
+		CREATE (f)-[:GENERATES]->(asset)
+		SET f.created_at = datetime(), f.updated_at = datetime(), f.version = 1
+	`
+
+	params := map[string]interface{}{
+		"id":        finding.ID,
+		"assetId":   finding.AssetID,
+		"data":      string(data),
+		"severity":  finding.Severity,
+		"riskScore": finding.RiskScore,
+		"status":    finding.Status,
+		"policyId":  finding.PolicyID,
+	}
+
+	_, err = session.Run(ctx, query, params, s.txTimeout())
+	return classifyError(err)
+}
+
+// UpdateFinding updates an existing finding
+func (s *Neo4jStore) UpdateFinding(ctx context.Context, finding models.Finding) error {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	data, err := json.Marshal(finding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal finding: %w", err)
+	}
+
+	query := `
+		MATCH (f:Finding {id: $id})
+		SET f.data = $data, f.severity = $severity, f.risk_score = $riskScore, f.status = $status, f.updated_at = datetime(),
+			f.version = coalesce(f.version, 0) + 1
+	`
+
+	params := map[string]interface{}{
+		"id":        finding.ID,
+		"data":      string(data),
+		"severity":  finding.Severity,
+		"riskScore": finding.RiskScore,
+		"status":    finding.Status,
+	}
+
+	_, err = session.Run(ctx, query, params, s.txTimeout())
+	return classifyError(err)
+}
+
+// ResolveFinding marks a stored finding resolved and returns it. It is used
+// when a resolution only references the finding by ID.
+func (s *Neo4jStore) ResolveFinding(ctx context.Context, findingID string) (models.Finding, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(ctx, "MATCH (f:Finding {id: $id}) RETURN f.data as data", map[string]interface{}{"id": findingID})
+		if err != nil {
+			return nil, err
+		}
+		record, err := res.Single(ctx)
+		if err != nil {
+			return nil, apperrors.NotFound("finding not found: %s", findingID)
+		}
+
+		var finding models.Finding
+		data, _ := record.AsMap()["data"].(string)
+		if err := json.Unmarshal([]byte(data), &finding); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal finding: %w", err)
+		}
+
+		now := time.Now()
+		finding.Status = "resolved"
+		finding.ResolvedAt = &now
+		updated, err := json.Marshal(finding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal finding: %w", err)
+		}
+
+		_, err = tx.Run(ctx, `
+			MATCH (f:Finding {id: $id})
+			SET f.data = $data, f.status = $status, f.updated_at = datetime(), f.version = coalesce(f.version, 0) + 1
+		`, map[string]interface{}{
+			"id":     findingID,
+			"data":   string(updated),
+			"status": finding.Status,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return finding, nil
+	}, s.txTimeout())
+	if err != nil {
+		return models.Finding{}, classifyError(err)
+	}
+
+	return result.(models.Finding), nil
+}
+
+// GetFindingGroups correlates findings with the given status, or all
+// findings when status is empty, into groups of at least minSize
+func (s *Neo4jStore) GetFindingGroups(ctx context.Context, status string, minSize int) ([]models.FindingGroup, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (f:Finding)-[:GENERATES]->(asset)
+		WHERE $status = '' OR f.status = $status
+		RETURN f.data as data, asset.data as assetData, labels(asset) as assetLabels
+	`
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"status": status}, s.txTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query findings: %w", classifyError(err))
+	}
+
+	var findings []models.CorrelatedFinding
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+
+		var cf models.CorrelatedFinding
+		data, _ := values["data"].(string)
+		if err := json.Unmarshal([]byte(data), &cf.Finding); err != nil {
+			log.Printf("Failed to unmarshal finding: %v", err)
+			continue
+		}
+
+		var asset models.BaseAsset
+		if assetData, err := decodeData(values["assetData"]); err == nil {
+			if err := json.Unmarshal(assetData, &asset); err != nil {
+				log.Printf("Failed to unmarshal asset of finding %s: %v", cf.Finding.ID, err)
+			}
+		}
+		cf.AssetName = asset.Name
+		assetLabels, _ := values["assetLabels"].([]interface{})
+		cf.AssetType = assetTypeOfLabels(assetLabels)
+
+		findings = append(findings, cf)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read findings: %w", err)
+	}
+
+	return models.GroupFindings(findings, minSize), nil
+}
+
+// RecordFindingFeedback stores analyst feedback on a finding. False positives
+// are suppressed with the feedback reason.
+func (s *Neo4jStore) RecordFindingFeedback(ctx context.Context, feedback models.FindingFeedback) (models.Finding, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(ctx, "MATCH (f:Finding {id: $id}) RETURN f.data as data", map[string]interface{}{"id": feedback.FindingID})
+		if err != nil {
+			return nil, err
+		}
+		if !res.Next(ctx) {
+			if err := res.Err(); err != nil {
+				return nil, err
+			}
+			return nil, apperrors.NotFound("finding not found: %s", feedback.FindingID)
+		}
+
+		var finding models.Finding
+		data, _ := res.Record().AsMap()["data"].(string)
+		if err := json.Unmarshal([]byte(data), &finding); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal finding: %w", err)
+		}
+
+		applyFeedback(&finding, feedback)
+
+		updated, err := json.Marshal(finding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal finding: %w", err)
+		}
+
+		_, err = tx.Run(ctx, `
+			MATCH (f:Finding {id: $id})
+			SET f.data = $data, f.status = $status, f.false_positive = $falsePositive,
+				f.feedback_verdict = $verdict, f.feedback_reason = $reason,
+				f.feedback_at = datetime(), f.updated_at = datetime(), f.version = coalesce(f.version, 0) + 1
+		`, map[string]interface{}{
+			"id":            feedback.FindingID,
+			"data":          string(updated),
+			"status":        finding.Status,
+			"falsePositive": finding.FalsePositive,
+			"verdict":       string(feedback.Verdict),
+			"reason":        feedback.Reason,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return finding, nil
+	}, s.txTimeout())
+	if err != nil {
+		return models.Finding{}, classifyError(err)
+	}
+
+	return result.(models.Finding), nil
+}
+
+// applyFeedback records feedback on a finding. A false positive suppresses
+// the finding; a later true positive lifts that suppression again.
+func applyFeedback(finding *models.Finding, feedback models.FindingFeedback) {
+	wasFalsePositive := finding.FalsePositive
+
+	finding.Feedback = append(finding.Feedback, feedback)
+	finding.FalsePositive = feedback.Verdict == models.FeedbackFalsePositive
+	switch {
+	case finding.FalsePositive:
+		finding.Status = "suppressed"
+		finding.Suppressed = true
+		finding.SuppressedReason = feedback.Reason
+	case wasFalsePositive:
+		finding.Status = "open"
+		finding.Suppressed = false
+		finding.SuppressedReason = ""
+	}
+}
+
+// GetPolicyAccuracy aggregates feedback on the findings of a policy
+func (s *Neo4jStore) GetPolicyAccuracy(ctx context.Context, policyID string) (*models.PolicyAccuracy, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (f:Finding {policy_id: $policyId})
+		OPTIONAL MATCH (f)-[:GENERATES]->(asset)
+		WITH f, head(collect(asset)) as asset
+		RETURN count(f) as total,
+			sum(CASE WHEN f.feedback_verdict = 'true_positive' THEN 1 ELSE 0 END) as truePositives,
+			sum(CASE WHEN f.feedback_verdict = 'false_positive' THEN 1 ELSE 0 END) as falsePositives,
+			collect(CASE WHEN f.feedback_verdict = 'false_positive' THEN asset.id END) as fpAssets,
+			collect(CASE WHEN f.feedback_verdict = 'false_positive' THEN f.feedback_reason END) as fpReasons
+	`
+
+	result, err := session.Run(ctx, query, map[string]interface{}{"policyId": policyID}, s.txTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy accuracy: %w", classifyError(err))
+	}
+
+	record, err := result.Single(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy accuracy: %w", err)
+	}
+
+	values := record.AsMap()
+	total, _ := values["total"].(int64)
+	truePositives, _ := values["truePositives"].(int64)
+	falsePositives, _ := values["falsePositives"].(int64)
+
+	return models.NewPolicyAccuracy(policyID, int(total), int(truePositives), int(falsePositives),
+		toStringSlice(values["fpAssets"]), toStringSlice(values["fpReasons"])), nil
+}
+
+// toStringSlice converts a list value returned by Neo4j to strings
+func toStringSlice(value interface{}) []string {
+	values, _ := value.([]interface{})
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if str, ok := v.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+	return strs
 }
 
-// GetRiskSummary retrieves risk summary
+// criticalFindingSeverity is the severity from which an open finding counts
+// as critical in risk summaries
+const criticalFindingSeverity = 9.0
+
+// GetRiskSummary aggregates the risk of the assets matching filter in a
+// single query. Risk levels use the default thresholds; high risk assets are
+// those at high level or above.
 func (s *Neo4jStore) GetRiskSummary(ctx context.Context, filter models.AssetFilter) (*models.RiskSummary, error) {
-	// Implementation for risk summary
-	return nil, fmt.Errorf("not implemented")
+	query, params := assetMatch(filter)
+
+	// Rows are grouped by label and environment, so the result stays small
+	// however many assets match
+	query += `
+		WITH n, coalesce(n.risk_score, 0.0) as score
+		OPTIONAL MATCH (n)<-[:GENERATES]-(f:Finding)
+		WHERE f.status = 'open' AND f.severity >= $criticalSeverity
+		WITH n, score, count(f) as criticalFindings
+		RETURN labels(n)[0] as label, n.environment as environment, count(n) as total,
+			sum(score) as totalRisk, sum(criticalFindings) as criticalFindings,
+			sum(CASE WHEN score >= $critical THEN 1 ELSE 0 END) as critical,
+			sum(CASE WHEN score >= $high AND score < $critical THEN 1 ELSE 0 END) as high,
+			sum(CASE WHEN score >= $medium AND score < $high THEN 1 ELSE 0 END) as medium,
+			sum(CASE WHEN score >= $low AND score < $medium THEN 1 ELSE 0 END) as low,
+			sum(CASE WHEN score < $low THEN 1 ELSE 0 END) as info,
+			collect(CASE WHEN score >= $high THEN n.id END) as highRiskIds
+	`
+
+	thresholds := models.DefaultRiskLevelThresholds()
+	params["criticalSeverity"] = criticalFindingSeverity
+	params["critical"] = thresholds.Critical
+	params["high"] = thresholds.High
+	params["medium"] = thresholds.Medium
+	params["low"] = thresholds.Low
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk summary: %w", classifyError(err))
+	}
+
+	summary := &models.RiskSummary{
+		AssetsByType:     make(map[models.AssetType]int),
+		AssetsByEnv:      make(map[models.Environment]int),
+		RiskDistribution: make(map[models.RiskLevel]int),
+		HighRiskAssets:   make([]string, 0),
+		LastUpdated:      time.Now(),
+	}
+
+	levels := map[string]models.RiskLevel{
+		"critical": models.RiskLevelCritical,
+		"high":     models.RiskLevelHigh,
+		"medium":   models.RiskLevelMedium,
+		"low":      models.RiskLevelLow,
+		"info":     models.RiskLevelInfo,
+	}
+
+	var totalRisk float64
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+
+		total, _ := values["total"].(int64)
+		label, _ := values["label"].(string)
+		env, _ := values["environment"].(string)
+		risk, _ := values["totalRisk"].(float64)
+		criticalFindings, _ := values["criticalFindings"].(int64)
+
+		summary.TotalAssets += int(total)
+		summary.AssetsByType[assetTypeOfLabel(label)] += int(total)
+		summary.AssetsByEnv[models.Environment(env)] += int(total)
+		summary.CriticalFindings += int(criticalFindings)
+		totalRisk += risk
+
+		for column, level := range levels {
+			if count, _ := values[column].(int64); count > 0 {
+				summary.RiskDistribution[level] += int(count)
+			}
+		}
+		summary.HighRiskAssets = append(summary.HighRiskAssets, toStringSlice(values["highRiskIds"])...)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get risk summary: %w", classifyError(err))
+	}
+
+	if summary.TotalAssets > 0 {
+		summary.AverageRisk = totalRisk / float64(summary.TotalAssets)
+	}
+
+	return summary, nil
 }
 
-// GetRiskTrends retrieves risk trends
+// trendStableChangeRate is the score change per day below which a trend is
+// stable
+const trendStableChangeRate = 0.5
+
+// GetRiskTrends aggregates an asset's risk snapshots within timeRange into
+// one point per day, holding the day's average score and its minimum and
+// maximum. The trend and change rate compare the first and last day.
 func (s *Neo4jStore) GetRiskTrends(ctx context.Context, assetID string, timeRange models.TimeRange) (*models.RiskTrend, error) {
-	// Implementation for risk trends
-	return nil, fmt.Errorf("not implemented")
+	if timeRange.End.IsZero() {
+		timeRange.End = time.Now()
+	}
+	if !timeRange.Start.Before(timeRange.End) {
+		return nil, apperrors.Invalid("time range start must be before its end")
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	// Snapshots are daily buckets; those recorded before bucketing hold a
+	// single score each. The asset's labels give the type its points are
+	// leveled for.
+	query := `
+		OPTIONAL MATCH (a {id: $assetId})
+		WHERE NOT a:RiskSnapshot AND NOT a:Finding
+		WITH head(collect(labels(a))) as labels
+		MATCH (s:RiskSnapshot {asset_id: $assetId})
+		WHERE s.timestamp >= $start AND s.timestamp <= $end
+		WITH labels, coalesce(s.day, date(s.timestamp)) as day,
+			coalesce(s.score_sum, s.score) as total, coalesce(s.samples, 1) as samples,
+			coalesce(s.min_score, s.score) as low, coalesce(s.max_score, s.score) as high
+		RETURN labels, day, sum(total) / sum(samples) as avg, min(low) as min, max(high) as max, sum(samples) as samples
+		ORDER BY day
+	`
+
+	params := map[string]interface{}{
+		"assetId": assetID,
+		"start":   timeRange.Start.UTC(),
+		"end":     timeRange.End.UTC(),
+	}
+
+	result, err := session.Run(ctx, query, params, s.txTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk trends: %w", classifyError(err))
+	}
+
+	trend := &models.RiskTrend{
+		AssetID:   assetID,
+		Scores:    make([]models.RiskScorePoint, 0),
+		TimeRange: timeRange,
+		Trend:     "stable",
+	}
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		day, ok := values["day"].(neo4j.Date)
+		if !ok {
+			continue
+		}
+		point := models.RiskScorePoint{Timestamp: day.Time()}
+		point.Score, _ = values["avg"].(float64)
+		point.Min, _ = values["min"].(float64)
+		point.Max, _ = values["max"].(float64)
+		if samples, ok := values["samples"].(int64); ok {
+			point.Samples = int(samples)
+		}
+		labels, _ := values["labels"].([]interface{})
+		point.Level = s.riskLevel(assetTypeOfLabels(labels), point.Score)
+		trend.Scores = append(trend.Scores, point)
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get risk trends: %w", classifyError(err))
+	}
+
+	if n := len(trend.Scores); n > 1 {
+		first, last := trend.Scores[0], trend.Scores[n-1]
+		if days := last.Timestamp.Sub(first.Timestamp).Hours() / 24; days > 0 {
+			trend.ChangeRate = (last.Score - first.Score) / days
+		}
+		switch {
+		case trend.ChangeRate >= trendStableChangeRate:
+			trend.Trend = "worsening"
+		case trend.ChangeRate <= -trendStableChangeRate:
+			trend.Trend = "improving"
+		}
+	}
+
+	return trend, nil
 }
 
 // GetAssetStatistics retrieves asset statistics
@@ -753,22 +2761,302 @@ func (s *Neo4jStore) GetAssetStatistics(ctx context.Context) (map[string]interfa
 	return nil, fmt.Errorf("not implemented")
 }
 
-// BulkCreateAssets creates multiple assets
+// BulkWriteError reports the assets a bulk write could not store; the rest
+// were stored, so callers can retry only the failed IDs
+type BulkWriteError struct {
+	FailedIDs []string
+	Total     int
+	Err       error // cause of the first failure
+}
+
+func (e *BulkWriteError) Error() string {
+	return fmt.Sprintf("%d of %d assets failed: %v", len(e.FailedIDs), e.Total, e.Err)
+}
+
+func (e *BulkWriteError) Unwrap() error {
+	return e.Err
+}
+
+// Succeeded returns the assets that are not among the failed IDs
+func (e *BulkWriteError) Succeeded(assets []models.Asset) []models.Asset {
+	failed := make(map[string]bool, len(e.FailedIDs))
+	for _, id := range e.FailedIDs {
+		failed[id] = true
+	}
+	succeeded := make([]models.Asset, 0, len(assets))
+	for _, asset := range assets {
+		if !failed[asset.GetID()] {
+			succeeded = append(succeeded, asset)
+		}
+	}
+	return succeeded
+}
+
+// bulkCreateAssetQuery creates a batch of assets with one label, setting the
+// same properties as CreateAsset
+const bulkCreateAssetQuery = `
+	UNWIND $rows AS row
+	CREATE (n:%s {id: row.id, data: row.data, provider: row.provider, environment: row.env, risk_score: 0.0})
+	SET n.internet_exposed = row.internetExposed, n.name = row.name, n.search_name = row.searchName,
+		n.created_at = datetime(), n.updated_at = datetime(), n.version = 1,
+		n.last_collected_at = datetime(), n.first_seen = row.seenAt, n.last_seen = row.seenAt
+`
+
+// BulkCreateAssets creates assets in batches of BulkBatchSize, each written
+// in one transaction with a statement per asset label. A batch that fails is
+// rolled back as a whole and the others are still written; the IDs of the
+// assets not created are returned in a BulkWriteError.
 func (s *Neo4jStore) BulkCreateAssets(ctx context.Context, assets []models.Asset) error {
-	// Implementation for bulk asset creation
-	return fmt.Errorf("not implemented")
+	if len(assets) == 0 {
+		return nil
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	batchSize := s.config.BulkBatchSize
+	if batchSize <= 0 {
+		batchSize = writeBatchSize
+	}
+
+	bulkErr := &BulkWriteError{Total: len(assets)}
+	fail := func(err error, ids ...string) {
+		if bulkErr.Err == nil {
+			bulkErr.Err = err
+		}
+		bulkErr.FailedIDs = append(bulkErr.FailedIDs, ids...)
+	}
+
+	for start := 0; start < len(assets); start += batchSize {
+		end := start + batchSize
+		if end > len(assets) {
+			end = len(assets)
+		}
+
+		// The label is part of the CREATE pattern, so rows are grouped by
+		// asset type
+		var labels []string
+		rowsByLabel := make(map[string][]map[string]interface{})
+		var batchIDs []string
+		now := time.Now().UTC()
+		for _, asset := range assets[start:end] {
+			label, err := assetLabel(asset.GetType())
+			if err != nil {
+				fail(err, asset.GetID())
+				continue
+			}
+			asset.SetSeen(now, now)
+			raw, err := json.Marshal(asset)
+			if err != nil {
+				fail(fmt.Errorf("failed to marshal asset %s: %w", asset.GetID(), err), asset.GetID())
+				continue
+			}
+			data, err := s.encodeData(raw)
+			if err != nil {
+				fail(err, asset.GetID())
+				continue
+			}
+
+			if _, ok := rowsByLabel[label]; !ok {
+				labels = append(labels, label)
+			}
+			rowsByLabel[label] = append(rowsByLabel[label], map[string]interface{}{
+				"id":              asset.GetID(),
+				"data":            data,
+				"provider":        string(asset.GetProvider()),
+				"env":             string(asset.GetEnvironment()),
+				"internetExposed": isInternetExposed(asset),
+				"name":            asset.GetName(),
+				"searchName":      strings.ToLower(asset.GetName()),
+				"seenAt":          now,
+			})
+			batchIDs = append(batchIDs, asset.GetID())
+		}
+		if len(batchIDs) == 0 {
+			continue
+		}
+
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+			for _, label := range labels {
+				query := fmt.Sprintf(bulkCreateAssetQuery, label)
+				if _, err := tx.Run(ctx, query, map[string]interface{}{"rows": rowsByLabel[label]}); err != nil {
+					return nil, fmt.Errorf("failed to create %s assets: %w", label, err)
+				}
+			}
+			return nil, nil
+		}, s.txTimeout())
+		if err != nil {
+			fail(classifyError(err), batchIDs...)
+		}
+	}
+
+	if len(bulkErr.FailedIDs) > 0 {
+		return bulkErr
+	}
+	return nil
 }
 
-// BulkUpdateAssets updates multiple assets
+// bulkUpdateAssetQuery updates a batch of assets with one label, setting the
+// same properties as UpdateAsset
+const bulkUpdateAssetQuery = `
+	UNWIND $rows AS row
+	MATCH (n:%s {id: row.id})
+	SET n.data = row.data, n.internet_exposed = row.internetExposed, n.name = row.name, n.search_name = row.searchName,
+		n.updated_at = datetime(), n.first_seen = coalesce(n.first_seen, n.created_at, row.seenAt),
+		n.last_seen = row.seenAt, n.last_collected_at = datetime(),
+		n.risk_score = coalesce(n.undecayed_risk_score, n.risk_score),
+		n.version = coalesce(n.version, 0) + 1
+	REMOVE n.undecayed_risk_score, n.risk_decay, n.expired_at
+	RETURN n.id as id, n.first_seen as firstSeen
+`
+
+// BulkUpdateAssets updates existing assets in batches of BulkBatchSize, each
+// written in one transaction with a statement per asset label. Unlike
+// UpdateAsset it does not relabel: an asset that is not stored with its
+// type is not updated. The IDs of the assets not updated, whether missing
+// or in a failed batch, are returned in a BulkWriteError.
 func (s *Neo4jStore) BulkUpdateAssets(ctx context.Context, assets []models.Asset) error {
-	// Implementation for bulk asset updates
-	return fmt.Errorf("not implemented")
+	if len(assets) == 0 {
+		return nil
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	batchSize := s.config.BulkBatchSize
+	if batchSize <= 0 {
+		batchSize = writeBatchSize
+	}
+
+	bulkErr := &BulkWriteError{Total: len(assets)}
+	fail := func(err error, ids ...string) {
+		if bulkErr.Err == nil {
+			bulkErr.Err = err
+		}
+		bulkErr.FailedIDs = append(bulkErr.FailedIDs, ids...)
+	}
+
+	for start := 0; start < len(assets); start += batchSize {
+		end := start + batchSize
+		if end > len(assets) {
+			end = len(assets)
+		}
+
+		// The label is part of the MATCH pattern, so rows are grouped by
+		// asset type
+		var labels []string
+		rowsByLabel := make(map[string][]map[string]interface{})
+		batch := make(map[string]models.Asset)
+		var batchIDs []string
+		now := time.Now().UTC()
+		for _, asset := range assets[start:end] {
+			label, err := assetLabel(asset.GetType())
+			if err != nil {
+				fail(err, asset.GetID())
+				continue
+			}
+			params, err := s.assetWriteParams(asset, now)
+			if err != nil {
+				fail(err, asset.GetID())
+				continue
+			}
+
+			if _, ok := rowsByLabel[label]; !ok {
+				labels = append(labels, label)
+			}
+			rowsByLabel[label] = append(rowsByLabel[label], params)
+			batch[asset.GetID()] = asset
+			batchIDs = append(batchIDs, asset.GetID())
+		}
+		if len(batchIDs) == 0 {
+			continue
+		}
+
+		var firstSeen map[string]interface{}
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+			// The transaction may be retried, so only its last run counts
+			firstSeen = make(map[string]interface{}, len(batchIDs))
+			for _, label := range labels {
+				query := fmt.Sprintf(bulkUpdateAssetQuery, label)
+				result, err := tx.Run(ctx, query, map[string]interface{}{"rows": rowsByLabel[label]})
+				if err != nil {
+					return nil, fmt.Errorf("failed to update %s assets: %w", label, err)
+				}
+				for result.Next(ctx) {
+					values := result.Record().AsMap()
+					id, _ := values["id"].(string)
+					firstSeen[id] = values["firstSeen"]
+				}
+				if err := result.Err(); err != nil {
+					return nil, fmt.Errorf("failed to update %s assets: %w", label, err)
+				}
+			}
+			return nil, nil
+		}, s.txTimeout())
+		if err != nil {
+			fail(classifyError(err), batchIDs...)
+			continue
+		}
+
+		for _, id := range batchIDs {
+			seen, ok := firstSeen[id]
+			if !ok {
+				fail(apperrors.NotFound("asset not found: %s", id), id)
+				continue
+			}
+			setSeenFrom(batch[id], seen, now)
+		}
+	}
+
+	if len(bulkErr.FailedIDs) > 0 {
+		return bulkErr
+	}
+	return nil
 }
 
 // BulkCreateRelationships creates multiple relationships
 func (s *Neo4jStore) BulkCreateRelationships(ctx context.Context, relationships []models.Relationship) error {
-	// Implementation for bulk relationship creation
-	return fmt.Errorf("not implemented")
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	// The relationship type is part of the MERGE pattern, so rows are
+	// written one type at a time
+	var types []models.RelationshipType
+	rowsByType := make(map[models.RelationshipType][]map[string]interface{})
+	for _, rel := range relationships {
+		if err := checkRelationshipType(rel.Type); err != nil {
+			return err
+		}
+		rel.ID = rel.CanonicalID()
+		params, err := relationshipParams(rel)
+		if err != nil {
+			return err
+		}
+		if _, ok := rowsByType[rel.Type]; !ok {
+			types = append(types, rel.Type)
+		}
+		rowsByType[rel.Type] = append(rowsByType[rel.Type], params)
+	}
+
+	for _, relType := range types {
+		rows := rowsByType[relType]
+		for start := 0; start < len(rows); start += writeBatchSize {
+			end := start + writeBatchSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			batch := rows[start:end]
+
+			_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+				return nil, upsertRelationships(ctx, tx, relType, batch)
+			}, s.txTimeout())
+			if err != nil {
+				return fmt.Errorf("failed to create %s relationships: %w", relType, classifyError(err))
+			}
+		}
+	}
+
+	return nil
 }
 
 // BulkDeleteAssets deletes multiple assets
@@ -784,36 +3072,67 @@ func (s *Neo4jStore) Ping(ctx context.Context) error {
 
 // Close closes the database connection
 func (s *Neo4jStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), gdsCheckTimeout)
+	defer cancel()
+	s.dropGDSProjection(ctx)
 	return s.driver.Close(context.Background())
 }
 
 // Helper methods
 
-func (s *Neo4jStore) unmarshalAsset(data string, assetType models.AssetType) (models.Asset, error) {
+// recordToAsset decodes an asset from a record with data and labels columns.
+// Seen times returned as firstSeen and lastSeen columns and the risk score
+// returned as riskScore take precedence over the document's, which may
+// predate them.
+func (s *Neo4jStore) recordToAsset(record *neo4j.Record) (models.Asset, error) {
+	values := record.AsMap()
+	labels, _ := values["labels"].([]interface{})
+
+	asset, err := s.unmarshalAsset(values["data"], assetTypeOfLabels(labels))
+	if err != nil {
+		return nil, err
+	}
+	firstSeen, hasFirst := values["firstSeen"].(time.Time)
+	lastSeen, hasLast := values["lastSeen"].(time.Time)
+	if hasFirst && hasLast {
+		asset.SetSeen(firstSeen.UTC(), lastSeen.UTC())
+	}
+	if riskScore, ok := values["riskScore"].(float64); ok {
+		asset.SetRiskScore(riskScore)
+	}
+	return asset, nil
+}
+
+func (s *Neo4jStore) unmarshalAsset(value interface{}, assetType models.AssetType) (models.Asset, error) {
+	data, err := decodeData(value)
+	if err != nil {
+		return nil, err
+	}
+
 	switch assetType {
 	case models.AssetTypeIdentity:
 		var asset models.Identity
-		err := json.Unmarshal([]byte(data), &asset)
+		err = json.Unmarshal(data, &asset)
 		return &asset, err
 	case models.AssetTypeCompute:
 		var asset models.Compute
-		err := json.Unmarshal([]byte(data), &asset)
+		err = json.Unmarshal(data, &asset)
 		return &asset, err
 	case models.AssetTypeNetwork:
 		var asset models.Network
-		err := json.Unmarshal([]byte(data), &asset)
+		err = json.Unmarshal(data, &asset)
 		return &asset, err
 	case models.AssetTypeData:
 		var asset models.Data
-		err := json.Unmarshal([]byte(data), &asset)
+		err = json.Unmarshal(data, &asset)
 		return &asset, err
 	case models.AssetTypeSaaS:
 		var asset models.SaaS
-		err := json.Unmarshal([]byte(data), &asset)
+		err = json.Unmarshal(data, &asset)
 		return &asset, err
 	case models.AssetTypeFinding:
 		var asset models.Finding
-		err := json.Unmarshal([]byte(data), &asset)
+		err = json.Unmarshal(data, &asset)
 		return &asset, err
 	default:
 		return nil, fmt.Errorf("unknown asset type: %s", assetType)
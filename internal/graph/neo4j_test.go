@@ -0,0 +1,244 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+func TestAssetLabelMapsAssetTypes(t *testing.T) {
+	for assetType, want := range map[models.AssetType]string{
+		models.AssetTypeIdentity: "Identity",
+		models.AssetTypeCompute:  "Compute",
+		models.AssetTypeNetwork:  "Network",
+		models.AssetTypeData:     "Data",
+		models.AssetTypeSaaS:     "SaaS",
+	} {
+		label, err := assetLabel(assetType)
+		if err != nil {
+			t.Errorf("assetLabel(%q) returned error: %v", assetType, err)
+			continue
+		}
+		if label != want {
+			t.Errorf("assetLabel(%q) = %q, want %q", assetType, label, want)
+		}
+		if got := assetTypeOfLabel(label); got != assetType {
+			t.Errorf("assetTypeOfLabel(%q) = %q, want %q", label, got, assetType)
+		}
+	}
+}
+
+func TestAssetLabelRejectsMaliciousTypes(t *testing.T) {
+	for _, assetType := range []models.AssetType{
+		"",
+		"Compute",
+		"COMPUTE",
+		"compute ",
+		"compute) DETACH DELETE n //",
+		"compute {id: 'x'}) MATCH (m",
+		"compute`:Admin",
+		"Compute:Finding",
+		models.AssetTypeFinding,
+		"Finding",
+		"RiskSnapshot",
+		"SchemaMigration",
+	} {
+		label, err := assetLabel(assetType)
+		if err == nil {
+			t.Errorf("assetLabel(%q) = %q, want an error", assetType, label)
+			continue
+		}
+		if apperrors.CodeOf(err) != apperrors.CodeInvalidRequest {
+			t.Errorf("assetLabel(%q) error code = %q, want %q", assetType, apperrors.CodeOf(err), apperrors.CodeInvalidRequest)
+		}
+	}
+}
+
+func TestAssetTypeOfLabelsSkipsOtherLabels(t *testing.T) {
+	labels := []interface{}{"Tenant", 42, "Network"}
+	if got := assetTypeOfLabels(labels); got != models.AssetTypeNetwork {
+		t.Errorf("assetTypeOfLabels(%v) = %q, want %q", labels, got, models.AssetTypeNetwork)
+	}
+	if got := assetTypeOfLabels([]interface{}{"Finding"}); got != "" {
+		t.Errorf("assetTypeOfLabels([Finding]) = %q, want empty", got)
+	}
+}
+
+func TestGraphPathLikelihoodUsesFindingsAndTrust(t *testing.T) {
+	half := 0.5
+	path := models.GraphPath{Edges: []models.RelationshipEdge{
+		{Relationship: models.Relationship{TrustScore: &half}},
+		{Relationship: models.Relationship{}},
+	}}
+	findings := []interface{}{
+		[]interface{}{},
+		[]interface{}{map[string]interface{}{"id": "f-1", "severity": 8.0}},
+		[]interface{}{},
+	}
+
+	got := graphPathLikelihood(path, findings)
+	want := models.PathLikelihood(
+		[]models.AttackPathVulnerability{{FindingID: "f-1", Severity: 8.0}},
+		[]float64{0.5, 1.0},
+		2,
+	)
+	if got != want {
+		t.Errorf("graphPathLikelihood = %v, want %v", got, want)
+	}
+	if unscored := graphPathLikelihood(path, nil); unscored >= got {
+		t.Errorf("likelihood without findings = %v, want less than %v", unscored, got)
+	}
+}
+
+func TestApplyFeedbackLiftsFalsePositiveSuppression(t *testing.T) {
+	finding := models.Finding{Status: "open"}
+
+	applyFeedback(&finding, models.FindingFeedback{Verdict: models.FeedbackFalsePositive, Reason: "test account"})
+	if !finding.Suppressed || finding.Status != "suppressed" {
+		t.Fatalf("after false positive: suppressed = %v, status = %q", finding.Suppressed, finding.Status)
+	}
+
+	applyFeedback(&finding, models.FindingFeedback{Verdict: models.FeedbackTruePositive})
+	if finding.Suppressed || finding.FalsePositive || finding.Status != "open" || finding.SuppressedReason != "" {
+		t.Errorf("after true positive: suppressed = %v, false positive = %v, status = %q, reason = %q",
+			finding.Suppressed, finding.FalsePositive, finding.Status, finding.SuppressedReason)
+	}
+	if len(finding.Feedback) != 2 {
+		t.Errorf("feedback entries = %d, want 2", len(finding.Feedback))
+	}
+}
+
+func TestResultCapOnlyAppliesToAPIRequests(t *testing.T) {
+	s := &Neo4jStore{config: GraphConfig{MaxResults: 10}}
+
+	if limit, capped := s.resultCap(context.Background(), 0); limit != 0 || capped {
+		t.Errorf("internal resultCap(0) = %d, %v, want 0, false", limit, capped)
+	}
+
+	ctx, _ := WithResultInfo(context.Background())
+	if limit, capped := s.resultCap(ctx, 0); limit != 10 || !capped {
+		t.Errorf("API resultCap(0) = %d, %v, want 10, true", limit, capped)
+	}
+	if limit, capped := s.resultCap(ctx, 5); limit != 5 || capped {
+		t.Errorf("API resultCap(5) = %d, %v, want 5, false", limit, capped)
+	}
+}
+
+func TestRelabelAssetClauseRemovesEveryAssetLabel(t *testing.T) {
+	clause := relabelAssetClause("Compute")
+	want := "REMOVE e:Identity:Compute:Network:Data:SaaS SET e:Compute"
+	if !strings.Contains(clause, want) {
+		t.Errorf("relabelAssetClause(Compute) = %s, want it to contain %q", clause, want)
+	}
+}
+
+func TestNeighborsByTypeQueryValidatesAndExcludesNonAssets(t *testing.T) {
+	for _, depth := range []int{0, -1} {
+		if _, err := neighborsByTypeQuery("both", depth, nil); apperrors.CodeOf(err) != apperrors.CodeInvalidRequest {
+			t.Errorf("neighborsByTypeQuery(depth %d) error = %v, want an invalid request", depth, err)
+		}
+	}
+	if _, err := neighborsByTypeQuery("both", 1, []models.RelationshipType{"RUNS_ON]-(x) DETACH DELETE x //"}); apperrors.CodeOf(err) != apperrors.CodeInvalidRequest {
+		t.Errorf("neighborsByTypeQuery with a malicious type error = %v, want an invalid request", err)
+	}
+
+	query, err := neighborsByTypeQuery("outgoing", 2, []models.RelationshipType{models.RelationshipRunsOn, models.RelationshipStores})
+	if err != nil {
+		t.Fatalf("neighborsByTypeQuery returned error: %v", err)
+	}
+	for _, want := range []string{"-[:RUNS_ON|STORES*1..2]->", "NOT neighbor:Finding", "NOT neighbor:RiskSnapshot"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query %s does not contain %q", query, want)
+		}
+	}
+}
+
+func TestNeighborsQueryOnlyFollowsAssetRelationships(t *testing.T) {
+	query := neighborsQuery("both", maxNeighborDepth+1)
+	for _, want := range []string{
+		fmt.Sprintf("[rels*1..%d]", maxNeighborDepth),
+		"NOT neighbor:Finding",
+		"NOT neighbor:RiskSnapshot",
+		"ALL(r IN rels WHERE type(r) IN $relationshipTypes)",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query %s does not contain %q", query, want)
+		}
+	}
+}
+
+func TestRelationshipMatchExcludesFindingEdges(t *testing.T) {
+	query, params := relationshipMatch(models.RelationshipFilter{AssetIDs: []string{"asset-1"}})
+	if !strings.Contains(query, "type(r) IN $relationshipTypes") {
+		t.Fatalf("query %s does not restrict relationship types", query)
+	}
+	relTypes, _ := params["relationshipTypes"].([]string)
+	runsOn := false
+	for _, relType := range relTypes {
+		if relType == string(models.RelationshipGenerates) {
+			t.Errorf("relationship types %v include %s", relTypes, relType)
+		}
+		runsOn = runsOn || relType == string(models.RelationshipRunsOn)
+	}
+	if !runsOn {
+		t.Errorf("relationship types %v do not include %s", relTypes, models.RelationshipRunsOn)
+	}
+}
+
+func TestAttackPathQueryAvoidsSortingEveryPath(t *testing.T) {
+	targeted := attackPathQuery(4, true)
+	if !strings.Contains(targeted, "shortestPath(") {
+		t.Errorf("query with targets does not search shortest paths per pair: %s", targeted)
+	}
+
+	untargeted := attackPathQuery(4, false)
+	if strings.Contains(untargeted, "ORDER BY") {
+		t.Errorf("query without targets sorts every path before limiting: %s", untargeted)
+	}
+	if !strings.Contains(untargeted, "*1..4") || !strings.Contains(untargeted, "LIMIT $limit") {
+		t.Errorf("query without targets is not bounded: %s", untargeted)
+	}
+}
+
+func TestAssetMatchOnlyMatchesAssetLabels(t *testing.T) {
+	query, params := assetMatch(models.AssetFilter{})
+	if !strings.Contains(query, "label IN $types") {
+		t.Fatalf("query %s does not restrict node labels", query)
+	}
+	labels, _ := params["types"].([]string)
+	if len(labels) == 0 {
+		t.Fatal("no asset labels are matched")
+	}
+	for _, label := range labels {
+		switch label {
+		case "Finding", "RiskSnapshot", "SchemaMigration", "SchemaMigrationLock", "AcceptedPath":
+			t.Errorf("asset labels %v include %s", labels, label)
+		}
+	}
+}
+
+func TestDriverConfigAppliesPoolSettings(t *testing.T) {
+	var driver neo4j.Config
+	driver.MaxConnectionPoolSize = 100
+	driverConfig(GraphConfig{MaxPoolSize: 25, ConnTimeout: 7 * time.Second})(&driver)
+
+	if driver.MaxConnectionPoolSize != 25 {
+		t.Errorf("max connection pool size = %d, want 25", driver.MaxConnectionPoolSize)
+	}
+	if driver.ConnectionAcquisitionTimeout != 7*time.Second || driver.SocketConnectTimeout != 7*time.Second {
+		t.Errorf("timeouts = %v and %v, want 7s", driver.ConnectionAcquisitionTimeout, driver.SocketConnectTimeout)
+	}
+
+	// Unset values keep the driver defaults
+	driver.MaxConnectionPoolSize = 100
+	driverConfig(GraphConfig{})(&driver)
+	if driver.MaxConnectionPoolSize != 100 {
+		t.Errorf("max connection pool size = %d, want the default 100", driver.MaxConnectionPoolSize)
+	}
+}
@@ -0,0 +1,285 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// Asset and finding nodes carry a version property counting writes of their
+// document. Patches may name the version they were based on so that a
+// concurrent write is reported as a conflict instead of being overwritten.
+
+// Fields a patch may not change, as they identify the document or, for
+// findings, the asset it is attached to
+var (
+	assetPatchProtected   = []string{"id", "type"}
+	findingPatchProtected = []string{"id", "type", "asset_id"}
+)
+
+// patchResult is the outcome of a patch transaction
+type patchResult struct {
+	doc     interface{}
+	version int64
+}
+
+// GetVersion returns the version of an asset or finding document. Documents
+// written before versions were recorded are at version 0.
+func (s *Neo4jStore) GetVersion(ctx context.Context, id string) (int64, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, "MATCH (n {id: $id}) RETURN coalesce(n.version, 0) as version",
+		map[string]interface{}{"id": id}, s.txTimeout())
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	record, err := result.Single(ctx)
+	if err != nil {
+		return 0, apperrors.NotFound("not found: %s", id)
+	}
+	version, _ := record.AsMap()["version"].(int64)
+	return version, nil
+}
+
+// PatchAsset applies a JSON merge patch (RFC 7396) to the stored asset
+// document in a single transaction and returns the patched asset and its new
+// version. A positive expectedVersion must match the stored version.
+func (s *Neo4jStore) PatchAsset(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Asset, int64, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(ctx, `
+			MATCH (n {id: $id})
+			WHERE n.data IS NOT NULL AND NOT n:Finding AND NOT n:RiskSnapshot
+			RETURN n.data as data, labels(n) as labels, coalesce(n.version, 0) as version
+		`, map[string]interface{}{"id": id})
+		if err != nil {
+			return nil, err
+		}
+		record, err := res.Single(ctx)
+		if err != nil {
+			return nil, apperrors.NotFound("asset not found: %s", id)
+		}
+		values := record.AsMap()
+
+		version, _ := values["version"].(int64)
+		if err := checkVersion(id, expectedVersion, version); err != nil {
+			return nil, err
+		}
+
+		labels, _ := values["labels"].([]interface{})
+		assetType := assetTypeOfLabels(labels)
+
+		raw, err := decodeData(values["data"])
+		if err != nil {
+			return nil, err
+		}
+		patched, err := applyMergePatch(raw, patch, assetPatchProtected)
+		if err != nil {
+			return nil, err
+		}
+		asset, err := s.unmarshalAsset(string(patched), assetType)
+		if err != nil {
+			return nil, apperrors.Invalid("patched asset is invalid: %v", err)
+		}
+
+		updated, err := json.Marshal(asset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal asset: %w", err)
+		}
+		data, err := s.encodeData(updated)
+		if err != nil {
+			return nil, err
+		}
+
+		err = writeVersion(ctx, tx, id, version, `
+			MATCH (n {id: $id})
+			WHERE coalesce(n.version, 0) = $storedVersion
+			SET n.data = $data, n.provider = $provider, n.environment = $env,
+				n.internet_exposed = $internetExposed, n.name = $name, n.search_name = $searchName,
+				n.updated_at = datetime(), n.version = $version
+			RETURN n.version as version
+		`, map[string]interface{}{
+			"id":              id,
+			"data":            data,
+			"provider":        string(asset.GetProvider()),
+			"env":             string(asset.GetEnvironment()),
+			"internetExposed": isInternetExposed(asset),
+			"name":            asset.GetName(),
+			"searchName":      strings.ToLower(asset.GetName()),
+			"version":         version + 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return patchResult{doc: asset, version: version + 1}, nil
+	}, s.txTimeout())
+	if err != nil {
+		return nil, 0, classifyError(err)
+	}
+
+	patched := result.(patchResult)
+	return patched.doc.(models.Asset), patched.version, nil
+}
+
+// PatchFinding applies a JSON merge patch to the stored finding like
+// PatchAsset, e.g. to change only its status
+func (s *Neo4jStore) PatchFinding(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Finding, int64, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		res, err := tx.Run(ctx, "MATCH (f:Finding {id: $id}) RETURN f.data as data, coalesce(f.version, 0) as version",
+			map[string]interface{}{"id": id})
+		if err != nil {
+			return nil, err
+		}
+		record, err := res.Single(ctx)
+		if err != nil {
+			return nil, apperrors.NotFound("finding not found: %s", id)
+		}
+		values := record.AsMap()
+
+		version, _ := values["version"].(int64)
+		if err := checkVersion(id, expectedVersion, version); err != nil {
+			return nil, err
+		}
+
+		raw, err := decodeData(values["data"])
+		if err != nil {
+			return nil, err
+		}
+		patched, err := applyMergePatch(raw, patch, findingPatchProtected)
+		if err != nil {
+			return nil, err
+		}
+
+		var finding models.Finding
+		if err := json.Unmarshal(patched, &finding); err != nil {
+			return nil, apperrors.Invalid("patched finding is invalid: %v", err)
+		}
+		updated, err := json.Marshal(finding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal finding: %w", err)
+		}
+
+		err = writeVersion(ctx, tx, id, version, `
+			MATCH (f:Finding {id: $id})
+			WHERE coalesce(f.version, 0) = $storedVersion
+			SET f.data = $data, f.severity = $severity, f.risk_score = $riskScore, f.status = $status,
+				f.policy_id = $policyId, f.updated_at = datetime(), f.version = $version
+			RETURN f.version as version
+		`, map[string]interface{}{
+			"id":        id,
+			"data":      string(updated),
+			"severity":  finding.Severity,
+			"riskScore": finding.RiskScore,
+			"status":    finding.Status,
+			"policyId":  finding.PolicyID,
+			"version":   version + 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return patchResult{doc: finding, version: version + 1}, nil
+	}, s.txTimeout())
+	if err != nil {
+		return models.Finding{}, 0, classifyError(err)
+	}
+
+	patched := result.(patchResult)
+	return patched.doc.(models.Finding), patched.version, nil
+}
+
+// checkVersion reports a conflict when a patch was based on an older version
+func checkVersion(id string, expected, stored int64) error {
+	if expected > 0 && expected != stored {
+		return apperrors.Conflict("%s was modified: at version %d, patch is based on version %d", id, stored, expected)
+	}
+	return nil
+}
+
+// writeVersion runs a patch write conditioned on the document still being at
+// the version it was read at. The write reports a conflict when another
+// write has changed the version since, so concurrent patches of the same
+// version cannot both succeed.
+func writeVersion(ctx context.Context, tx neo4j.ManagedTransaction, id string, version int64, query string, params map[string]interface{}) error {
+	params["storedVersion"] = version
+	res, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return err
+	}
+	if res.Next(ctx) {
+		return nil
+	}
+	if err := res.Err(); err != nil {
+		return err
+	}
+	return apperrors.Conflict("%s was modified concurrently: no longer at version %d", id, version)
+}
+
+// applyMergePatch merges patch into the JSON document doc. Fields listed in
+// protected may only be patched to their current value.
+func applyMergePatch(doc, patch []byte, protected []string) ([]byte, error) {
+	var target, changes interface{}
+	if err := decodeJSON(doc, &target); err != nil {
+		return nil, fmt.Errorf("failed to decode stored document: %w", err)
+	}
+	if err := decodeJSON(patch, &changes); err != nil {
+		return nil, apperrors.Invalid("invalid merge patch: %v", err)
+	}
+
+	fields, ok := changes.(map[string]interface{})
+	if !ok {
+		return nil, apperrors.Invalid("merge patch must be a JSON object")
+	}
+	current, _ := target.(map[string]interface{})
+	for _, field := range protected {
+		if value, ok := fields[field]; ok && !reflect.DeepEqual(value, current[field]) {
+			return nil, apperrors.Invalid("field %s cannot be patched", field)
+		}
+	}
+
+	return json.Marshal(mergePatch(target, changes))
+}
+
+// mergePatch applies an RFC 7396 merge patch: objects are merged
+// recursively, null removes a field and any other value replaces it
+func mergePatch(target, patch interface{}) interface{} {
+	fields, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	merged, ok := target.(map[string]interface{})
+	if !ok {
+		merged = make(map[string]interface{}, len(fields))
+	}
+	for field, value := range fields {
+		if value == nil {
+			delete(merged, field)
+			continue
+		}
+		merged[field] = mergePatch(merged[field], value)
+	}
+	return merged
+}
+
+// decodeJSON decodes data keeping numbers exact, so patching a document does
+// not change the representation of fields the patch leaves alone
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
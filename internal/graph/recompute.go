@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// AffectedPath is an attack path from an internet-exposed asset to sensitive
+// data or an admin identity that runs through a changed asset
+type AffectedPath struct {
+	NodeIDs  []string `json:"node_ids"`
+	PathRisk float64  `json:"path_risk"`
+}
+
+// key identifies the path by the nodes it visits
+func (p AffectedPath) key() string {
+	return strings.Join(p.NodeIDs, ",")
+}
+
+// AffectedPathCache holds the attack paths through each asset as of the last
+// recompute that included it
+type AffectedPathCache struct {
+	mu       sync.RWMutex
+	byAsset  map[string][]AffectedPath
+	computed map[string]time.Time
+}
+
+// NewAffectedPathCache creates an empty cache
+func NewAffectedPathCache() *AffectedPathCache {
+	return &AffectedPathCache{
+		byAsset:  make(map[string][]AffectedPath),
+		computed: make(map[string]time.Time),
+	}
+}
+
+// Get returns the cached paths through assetID and whether any recompute
+// has included it
+func (c *AffectedPathCache) Get(assetID string) ([]AffectedPath, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.computed[assetID]
+	return c.byAsset[assetID], ok
+}
+
+// ReplaceMany sets the cached paths of every asset in assetIDs to those of
+// paths running through it, including none, under a single lock
+func (c *AffectedPathCache) ReplaceMany(assetIDs []string, paths []AffectedPath) {
+	byAsset := make(map[string][]AffectedPath, len(assetIDs))
+	for _, id := range assetIDs {
+		byAsset[id] = nil
+	}
+	for _, path := range paths {
+		for _, id := range path.NodeIDs {
+			if assetPaths, ok := byAsset[id]; ok {
+				byAsset[id] = append(assetPaths, path)
+			}
+		}
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, assetPaths := range byAsset {
+		if len(assetPaths) == 0 {
+			delete(c.byAsset, id)
+		} else {
+			c.byAsset[id] = assetPaths
+		}
+		c.computed[id] = now
+	}
+}
@@ -0,0 +1,656 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/models"
+)
+
+// RegionalStore keeps each tenant's graph in the region its data is pinned
+// to. Every operation is routed to the store of the region resolved from the
+// context by tenant.RegionFromContext, or to the default region when neither
+// the tenant nor the context names one. Unlike FederatedStore, which places
+// assets by their cloud region and answers queries from all regions, an
+// operation only ever reaches one region's store.
+type RegionalStore struct {
+	regions       map[string]GraphStore
+	defaultRegion string
+}
+
+// NewRegionalStore creates a store routing to the given regional stores
+func NewRegionalStore(regions map[string]GraphStore, defaultRegion string) (*RegionalStore, error) {
+	if _, ok := regions[defaultRegion]; !ok {
+		return nil, fmt.Errorf("default region %s has no graph store", defaultRegion)
+	}
+	return &RegionalStore{
+		regions:       regions,
+		defaultRegion: defaultRegion,
+	}, nil
+}
+
+// store returns the store of the region ctx resolves to. A region without a
+// store is rejected rather than served from another region.
+func (s *RegionalStore) store(ctx context.Context) (GraphStore, error) {
+	region, err := tenant.RegionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		region = s.defaultRegion
+	}
+
+	store, ok := s.regions[region]
+	if !ok {
+		return nil, tenant.RegionNotServed(region, "graph")
+	}
+	return store, nil
+}
+
+// Graph operations run unchanged against the store of the resolved region
+
+func (s *RegionalStore) CreateAsset(ctx context.Context, asset models.Asset) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.CreateAsset(ctx, asset)
+}
+
+func (s *RegionalStore) UpsertAsset(ctx context.Context, asset models.Asset) (bool, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return false, err
+	}
+	return store.UpsertAsset(ctx, asset)
+}
+
+func (s *RegionalStore) GetAsset(ctx context.Context, id string) (models.Asset, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetAsset(ctx, id)
+}
+
+func (s *RegionalStore) GetAssets(ctx context.Context, ids []string) (map[string]models.Asset, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetAssets(ctx, ids)
+}
+
+func (s *RegionalStore) UpdateAsset(ctx context.Context, asset models.Asset) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.UpdateAsset(ctx, asset)
+}
+
+func (s *RegionalStore) DeleteAsset(ctx context.Context, id string) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.DeleteAsset(ctx, id)
+}
+
+func (s *RegionalStore) PatchAsset(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Asset, int64, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return store.PatchAsset(ctx, id, patch, expectedVersion)
+}
+
+func (s *RegionalStore) GetVersion(ctx context.Context, id string) (int64, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return store.GetVersion(ctx, id)
+}
+
+func (s *RegionalStore) ListAssets(ctx context.Context, filter models.AssetFilter) ([]models.Asset, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.ListAssets(ctx, filter)
+}
+
+func (s *RegionalStore) CountAssets(ctx context.Context, filter models.AssetFilter) (int, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return store.CountAssets(ctx, filter)
+}
+
+func (s *RegionalStore) SearchAssets(ctx context.Context, query models.AssetQuery) ([]models.Asset, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchAssets(ctx, query)
+}
+
+func (s *RegionalStore) StreamAssets(ctx context.Context, filter models.AssetFilter) (<-chan models.Asset, <-chan error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		assets := make(chan models.Asset)
+		errs := make(chan error, 1)
+		close(assets)
+		errs <- err
+		close(errs)
+		return assets, errs
+	}
+	return store.StreamAssets(ctx, filter)
+}
+
+func (s *RegionalStore) CreateRelationship(ctx context.Context, rel models.Relationship) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.CreateRelationship(ctx, rel)
+}
+
+func (s *RegionalStore) GetRelationship(ctx context.Context, id string) (models.Relationship, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return models.Relationship{}, err
+	}
+	return store.GetRelationship(ctx, id)
+}
+
+func (s *RegionalStore) UpdateRelationship(ctx context.Context, rel models.Relationship) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.UpdateRelationship(ctx, rel)
+}
+
+func (s *RegionalStore) DeleteRelationship(ctx context.Context, id string) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.DeleteRelationship(ctx, id)
+}
+
+func (s *RegionalStore) DeleteRelationshipsByFilter(ctx context.Context, filter models.RelationshipDeleteFilter) (int, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return store.DeleteRelationshipsByFilter(ctx, filter)
+}
+
+func (s *RegionalStore) ListRelationships(ctx context.Context, filter models.RelationshipFilter) ([]models.Relationship, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.ListRelationships(ctx, filter)
+}
+
+func (s *RegionalStore) CountRelationships(ctx context.Context, filter models.RelationshipFilter) (int, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return store.CountRelationships(ctx, filter)
+}
+
+func (s *RegionalStore) SearchRelationships(ctx context.Context, query models.RelationshipQuery) ([]models.Relationship, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchRelationships(ctx, query)
+}
+
+func (s *RegionalStore) StreamRelationships(ctx context.Context, filter models.RelationshipFilter) (<-chan models.Relationship, <-chan error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		rels := make(chan models.Relationship)
+		errs := make(chan error, 1)
+		close(rels)
+		errs <- err
+		close(errs)
+		return rels, errs
+	}
+	return store.StreamRelationships(ctx, filter)
+}
+
+func (s *RegionalStore) GetNeighbors(ctx context.Context, assetID string, direction string, maxDepth int) ([]models.Asset, []models.Relationship, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store.GetNeighbors(ctx, assetID, direction, maxDepth)
+}
+
+func (s *RegionalStore) GetNeighborsByType(ctx context.Context, assetID string, direction string, maxDepth int, relTypes []models.RelationshipType) ([]models.Asset, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetNeighborsByType(ctx, assetID, direction, maxDepth, relTypes)
+}
+
+func (s *RegionalStore) GetFrontierNeighbors(ctx context.Context, assetIDs []string, direction string, relTypes []models.RelationshipType) ([]models.Asset, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetFrontierNeighbors(ctx, assetIDs, direction, relTypes)
+}
+
+func (s *RegionalStore) GetNeighborsBatch(ctx context.Context, assetIDs []string) (map[string][]Neighbor, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetNeighborsBatch(ctx, assetIDs)
+}
+
+func (s *RegionalStore) FindPath(ctx context.Context, fromAssetID, toAssetID string, maxDepth int) (*models.GraphPath, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.FindPath(ctx, fromAssetID, toAssetID, maxDepth)
+}
+
+func (s *RegionalStore) FindAttackPaths(ctx context.Context, entryPoints []string, targets []string, maxDepth int) ([]models.GraphPath, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.FindAttackPaths(ctx, entryPoints, targets, maxDepth)
+}
+
+func (s *RegionalStore) GetConnectedComponents(ctx context.Context, assetIDs []string) ([][]string, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetConnectedComponents(ctx, assetIDs)
+}
+
+func (s *RegionalStore) RecomputeInternetExposure(ctx context.Context, assetID string, maxHops int) ([]string, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.RecomputeInternetExposure(ctx, assetID, maxHops)
+}
+
+func (s *RegionalStore) GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return models.RiskScore{}, err
+	}
+	return store.GetAssetRisk(ctx, assetID)
+}
+
+func (s *RegionalStore) GetAssetRisks(ctx context.Context, assetIDs []string) (map[string]models.RiskScore, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetAssetRisks(ctx, assetIDs)
+}
+
+func (s *RegionalStore) UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.UpdateAssetRisk(ctx, risk)
+}
+
+func (s *RegionalStore) BulkUpdateAssetRisk(ctx context.Context, risks []models.RiskScore) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.BulkUpdateAssetRisk(ctx, risks)
+}
+
+func (s *RegionalStore) GetFinding(ctx context.Context, id string) (models.Finding, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return models.Finding{}, err
+	}
+	return store.GetFinding(ctx, id)
+}
+
+func (s *RegionalStore) ListFindings(ctx context.Context, filter models.FindingFilter) ([]models.Finding, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.ListFindings(ctx, filter)
+}
+
+func (s *RegionalStore) GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetAssetFindings(ctx, assetID)
+}
+
+func (s *RegionalStore) GetFindingsForAssets(ctx context.Context, assetIDs []string) (map[string][]models.Finding, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetFindingsForAssets(ctx, assetIDs)
+}
+
+func (s *RegionalStore) GetUnresolvedPolicyFindings(ctx context.Context, assetID string) ([]models.Finding, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetUnresolvedPolicyFindings(ctx, assetID)
+}
+
+func (s *RegionalStore) CreateFinding(ctx context.Context, finding models.Finding) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.CreateFinding(ctx, finding)
+}
+
+func (s *RegionalStore) UpdateFinding(ctx context.Context, finding models.Finding) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.UpdateFinding(ctx, finding)
+}
+
+func (s *RegionalStore) PatchFinding(ctx context.Context, id string, patch []byte, expectedVersion int64) (models.Finding, int64, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return models.Finding{}, 0, err
+	}
+	return store.PatchFinding(ctx, id, patch, expectedVersion)
+}
+
+func (s *RegionalStore) ResolveFinding(ctx context.Context, findingID string) (models.Finding, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return models.Finding{}, err
+	}
+	return store.ResolveFinding(ctx, findingID)
+}
+
+func (s *RegionalStore) GetFindingGroups(ctx context.Context, status string, minSize int) ([]models.FindingGroup, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetFindingGroups(ctx, status, minSize)
+}
+
+func (s *RegionalStore) RecordFindingFeedback(ctx context.Context, feedback models.FindingFeedback) (models.Finding, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return models.Finding{}, err
+	}
+	return store.RecordFindingFeedback(ctx, feedback)
+}
+
+func (s *RegionalStore) GetPolicyAccuracy(ctx context.Context, policyID string) (*models.PolicyAccuracy, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetPolicyAccuracy(ctx, policyID)
+}
+
+func (s *RegionalStore) GetRiskSummary(ctx context.Context, filter models.AssetFilter) (*models.RiskSummary, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetRiskSummary(ctx, filter)
+}
+
+func (s *RegionalStore) GetRiskTrends(ctx context.Context, assetID string, timeRange models.TimeRange) (*models.RiskTrend, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetRiskTrends(ctx, assetID, timeRange)
+}
+
+func (s *RegionalStore) GetAssetStatistics(ctx context.Context) (map[string]interface{}, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetAssetStatistics(ctx)
+}
+
+func (s *RegionalStore) BulkCreateAssets(ctx context.Context, assets []models.Asset) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.BulkCreateAssets(ctx, assets)
+}
+
+func (s *RegionalStore) BulkUpdateAssets(ctx context.Context, assets []models.Asset) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.BulkUpdateAssets(ctx, assets)
+}
+
+func (s *RegionalStore) BulkCreateRelationships(ctx context.Context, relationships []models.Relationship) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.BulkCreateRelationships(ctx, relationships)
+}
+
+func (s *RegionalStore) BulkDeleteAssets(ctx context.Context, assetIDs []string) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.BulkDeleteAssets(ctx, assetIDs)
+}
+
+func (s *RegionalStore) ExportGraph(ctx context.Context, tenant string) (io.Reader, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.ExportGraph(ctx, tenant)
+}
+
+func (s *RegionalStore) ImportGraph(ctx context.Context, tenant string, r io.Reader, labels []string) (*ImportResult, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.ImportGraph(ctx, tenant, r, labels)
+}
+
+func (s *RegionalStore) ExpireStaleAssets(ctx context.Context, ttls map[models.AssetType]time.Duration, decayPeriod time.Duration) (*ExpiryResult, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.ExpireStaleAssets(ctx, ttls, decayPeriod)
+}
+
+func (s *RegionalStore) PruneRiskSnapshots(ctx context.Context, before time.Time) (int, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return store.PruneRiskSnapshots(ctx, before)
+}
+
+// Optional capabilities of the regional stores, reporting NOT_IMPLEMENTED
+// when the store of the resolved region lacks them
+
+func (s *RegionalStore) AutocompleteAssets(ctx context.Context, prefix string, limit int) ([]AssetSuggestion, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	completer, ok := store.(assetAutocompleter)
+	if !ok {
+		return nil, notSupported("asset autocomplete")
+	}
+	return completer.AutocompleteAssets(ctx, prefix, limit)
+}
+
+func (s *RegionalStore) ExportFeatures(ctx context.Context) (*FeatureSet, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exporter, ok := store.(featureExporter)
+	if !ok {
+		return nil, notSupported("feature export")
+	}
+	return exporter.ExportFeatures(ctx)
+}
+
+func (s *RegionalStore) SchemaStatus(ctx context.Context) (SchemaMigrationStatus, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return SchemaMigrationStatus{}, err
+	}
+	versioned, ok := store.(schemaVersioner)
+	if !ok {
+		return SchemaMigrationStatus{}, notSupported("schema status")
+	}
+	return versioned.SchemaStatus(ctx)
+}
+
+func (s *RegionalStore) ReencodeAssets(ctx context.Context) (int, error) {
+	store, err := s.store(ctx)
+	if err != nil {
+		return 0, err
+	}
+	reencoder, ok := store.(assetReencoder)
+	if !ok {
+		return 0, notSupported("asset re-encoding")
+	}
+	return reencoder.ReencodeAssets(ctx)
+}
+
+// Accepted paths are kept in the region of the tenant that accepted them.
+// Without a region in the context, as when the allowlist is loaded at
+// startup or an administrator deletes an entry, every region is reached.
+
+func (s *RegionalStore) SaveAcceptedPath(ctx context.Context, entry models.AcceptedPath) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	paths, ok := store.(AcceptedPathStore)
+	if !ok {
+		return notSupported("accepted path storage")
+	}
+	return paths.SaveAcceptedPath(ctx, entry)
+}
+
+func (s *RegionalStore) DeleteAcceptedPath(ctx context.Context, id string) error {
+	stores, err := s.acceptedPathStores(ctx)
+	if err != nil {
+		return err
+	}
+	for region, store := range stores {
+		if err := store.DeleteAcceptedPath(ctx, id); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+func (s *RegionalStore) ListAcceptedPaths(ctx context.Context) ([]models.AcceptedPath, error) {
+	stores, err := s.acceptedPathStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var entries []models.AcceptedPath
+	for region, store := range stores {
+		regionEntries, err := store.ListAcceptedPaths(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		entries = append(entries, regionEntries...)
+	}
+	return entries, nil
+}
+
+// acceptedPathStores returns the accepted path store of the region ctx
+// resolves to, or of every region when it resolves to none
+func (s *RegionalStore) acceptedPathStores(ctx context.Context) (map[string]AcceptedPathStore, error) {
+	region, err := tenant.RegionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	regions := s.regions
+	if region != "" {
+		store, ok := s.regions[region]
+		if !ok {
+			return nil, tenant.RegionNotServed(region, "graph")
+		}
+		regions = map[string]GraphStore{region: store}
+	}
+
+	stores := make(map[string]AcceptedPathStore, len(regions))
+	for name, store := range regions {
+		paths, ok := store.(AcceptedPathStore)
+		if !ok {
+			return nil, notSupported("accepted path storage")
+		}
+		stores[name] = paths
+	}
+	return stores, nil
+}
+
+// Ping checks every regional store
+func (s *RegionalStore) Ping(ctx context.Context) error {
+	for region, store := range s.regions {
+		if err := store.Ping(ctx); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// Close closes every regional store
+func (s *RegionalStore) Close() error {
+	var firstErr error
+	for region, store := range s.regions {
+		if err := store.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return firstErr
+}
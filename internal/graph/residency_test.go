@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/models"
+)
+
+// pathStore keeps accepted paths in memory
+type pathStore struct {
+	GraphStore
+	paths map[string]models.AcceptedPath
+}
+
+func (s *pathStore) SaveAcceptedPath(ctx context.Context, entry models.AcceptedPath) error {
+	s.paths[entry.ID] = entry
+	return nil
+}
+
+func (s *pathStore) DeleteAcceptedPath(ctx context.Context, id string) error {
+	delete(s.paths, id)
+	return nil
+}
+
+func (s *pathStore) ListAcceptedPaths(ctx context.Context) ([]models.AcceptedPath, error) {
+	var entries []models.AcceptedPath
+	for _, entry := range s.paths {
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func TestRegionalStoreKeepsAcceptedPathsInTenantRegion(t *testing.T) {
+	home := &pathStore{paths: make(map[string]models.AcceptedPath)}
+	eu := &pathStore{paths: make(map[string]models.AcceptedPath)}
+	store, err := NewRegionalStore(map[string]GraphStore{"default": home, "eu": eu}, "default")
+	if err != nil {
+		t.Fatalf("NewRegionalStore returned error: %v", err)
+	}
+
+	euCtx := tenant.WithTenantContext(context.Background(), &tenant.TenantContext{TenantID: "t-1", Region: "eu"})
+	if err := store.SaveAcceptedPath(euCtx, models.AcceptedPath{ID: "path-1"}); err != nil {
+		t.Fatalf("SaveAcceptedPath returned error: %v", err)
+	}
+	if _, ok := eu.paths["path-1"]; !ok || len(home.paths) != 0 {
+		t.Fatalf("accepted path stored in %v and %v, want the eu region only", home.paths, eu.paths)
+	}
+
+	// Loading without a region reads every region
+	entries, err := store.ListAcceptedPaths(context.Background())
+	if err != nil {
+		t.Fatalf("ListAcceptedPaths returned error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("listed %d accepted paths, want 1", len(entries))
+	}
+
+	if err := store.DeleteAcceptedPath(context.Background(), "path-1"); err != nil {
+		t.Fatalf("DeleteAcceptedPath returned error: %v", err)
+	}
+	if len(eu.paths) != 0 {
+		t.Errorf("accepted path was not deleted from the eu region")
+	}
+}
@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// RiskHistoryConfig represents risk snapshot retention configuration
+type RiskHistoryConfig struct {
+	// Retention is how long risk snapshots are kept for trends. 0 keeps them
+	// forever.
+	Retention     time.Duration `json:"retention" yaml:"retention"`
+	PruneInterval time.Duration `json:"prune_interval" yaml:"prune_interval"`
+}
+
+// DefaultRiskHistoryConfig returns default risk snapshot retention
+func DefaultRiskHistoryConfig() RiskHistoryConfig {
+	return RiskHistoryConfig{
+		Retention:     90 * 24 * time.Hour,
+		PruneInterval: time.Hour,
+	}
+}
+
+// PruneRiskSnapshots deletes the risk snapshots taken before the given time,
+// in batches so a large backlog does not build one huge transaction. It
+// returns the number deleted.
+func (s *Neo4jStore) PruneRiskSnapshots(ctx context.Context, before time.Time) (int, error) {
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	query := `
+		MATCH (s:RiskSnapshot)
+		WHERE s.timestamp < $before
+		WITH s LIMIT $batchSize
+		DETACH DELETE s
+		RETURN count(*) as deleted
+	`
+
+	params := map[string]interface{}{
+		"before":    before.UTC(),
+		"batchSize": writeBatchSize,
+	}
+
+	total := 0
+	for {
+		result, err := session.Run(ctx, query, params, s.txTimeout())
+		if err != nil {
+			return total, fmt.Errorf("failed to prune risk snapshots: %w", classifyError(err))
+		}
+		record, err := result.Single(ctx)
+		if err != nil {
+			return total, fmt.Errorf("failed to prune risk snapshots: %w", classifyError(err))
+		}
+
+		deleted, _ := record.AsMap()["deleted"].(int64)
+		total += int(deleted)
+		if deleted < writeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// RiskHistoryPruner periodically deletes risk snapshots past their retention
+type RiskHistoryPruner struct {
+	store  GraphStore
+	config RiskHistoryConfig
+}
+
+// NewRiskHistoryPruner creates a new risk history pruner
+func NewRiskHistoryPruner(store GraphStore, config RiskHistoryConfig) *RiskHistoryPruner {
+	return &RiskHistoryPruner{
+		store:  store,
+		config: config,
+	}
+}
+
+// Run prunes snapshots every prune interval until the context is cancelled
+func (p *RiskHistoryPruner) Run(ctx context.Context) {
+	if p.config.Retention <= 0 || p.config.PruneInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.config.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pruned, err := p.store.PruneRiskSnapshots(ctx, time.Now().Add(-p.config.Retention))
+			if err != nil {
+				log.Printf("Risk snapshot pruning failed: %v", err)
+				continue
+			}
+			if pruned > 0 {
+				log.Printf("Pruned %d risk snapshots older than %v", pruned, p.config.Retention)
+			}
+		}
+	}
+}
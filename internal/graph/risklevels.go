@@ -0,0 +1,49 @@
+package graph
+
+import "github.com/securizon/pkg/models"
+
+// RiskLevelFunc maps a score of an asset of assetType to its risk level,
+// such as the risk engine's configured mapping
+type RiskLevelFunc func(assetType models.AssetType, score float64) models.RiskLevel
+
+// SetRiskLevels makes store level the risk scores it reads and writes with
+// levels instead of the default thresholds, through any stores wrapping it.
+// It must be called before the store is used.
+func SetRiskLevels(store GraphStore, levels RiskLevelFunc) {
+	if setter, ok := store.(interface{ SetRiskLevels(RiskLevelFunc) }); ok {
+		setter.SetRiskLevels(levels)
+	}
+}
+
+// SetRiskLevels sets the risk level mapping of every region
+func (s *RegionalStore) SetRiskLevels(levels RiskLevelFunc) {
+	for _, store := range s.regions {
+		SetRiskLevels(store, levels)
+	}
+}
+
+// SetRiskLevels sets the risk level mapping of every region
+func (f *FederatedStore) SetRiskLevels(levels RiskLevelFunc) {
+	for _, store := range f.regions {
+		SetRiskLevels(store, levels)
+	}
+}
+
+// SetRiskLevels sets the risk level mapping of the wrapped store
+func (s *CDCStore) SetRiskLevels(levels RiskLevelFunc) {
+	SetRiskLevels(s.GraphStore, levels)
+}
+
+// SetRiskLevels sets the mapping risk scores are leveled with
+func (s *Neo4jStore) SetRiskLevels(levels RiskLevelFunc) {
+	s.levels = levels
+}
+
+// riskLevel maps a score of an asset of assetType to its risk level with
+// the configured mapping, or the default thresholds without one
+func (s *Neo4jStore) riskLevel(assetType models.AssetType, score float64) models.RiskLevel {
+	if s.levels != nil {
+		return s.levels(assetType, score)
+	}
+	return models.GetRiskLevel(score)
+}
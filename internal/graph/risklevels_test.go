@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// strictComputeLevels rates compute assets critical from a score of 30
+func strictComputeLevels(assetType models.AssetType, score float64) models.RiskLevel {
+	if assetType == models.AssetTypeCompute && score >= 30 {
+		return models.RiskLevelCritical
+	}
+	return models.GetRiskLevel(score)
+}
+
+func TestStoredRiskUsesConfiguredLevels(t *testing.T) {
+	store := &Neo4jStore{}
+	regional, err := NewRegionalStore(map[string]GraphStore{"eu": store}, "eu")
+	if err != nil {
+		t.Fatalf("NewRegionalStore() error = %v", err)
+	}
+	SetRiskLevels(regional, strictComputeLevels)
+
+	// The stored breakdown was leveled with the default thresholds
+	data, _ := json.Marshal(models.RiskScore{AssetID: "vm-1", Score: 35, Level: models.RiskLevelLow})
+	values := map[string]interface{}{
+		"score":     35.0,
+		"updatedAt": time.Now(),
+		"riskData":  string(data),
+		"labels":    []interface{}{"Compute"},
+	}
+	risk, err := store.riskFromValues("vm-1", values)
+	if err != nil {
+		t.Fatalf("riskFromValues() error = %v", err)
+	}
+	if risk.AssetType != models.AssetTypeCompute || risk.Level != models.RiskLevelCritical {
+		t.Errorf("risk = %s at level %s, want compute at level critical", risk.AssetType, risk.Level)
+	}
+
+	values["labels"] = []interface{}{"Data"}
+	if risk, _ := store.riskFromValues("db-1", values); risk.Level != models.RiskLevelLow {
+		t.Errorf("level of a data asset = %s, want the default low", risk.Level)
+	}
+
+	if _, level, err := store.riskData(models.RiskScore{AssetID: "vm-1", AssetType: models.AssetTypeCompute, Score: 35}); err != nil || level != models.RiskLevelCritical {
+		t.Errorf("riskData() level = %s, %v, want critical", level, err)
+	}
+}
@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeSelector selects graph nodes by label and property predicates, e.g.
+// every Data node whose compliance tag is PCI. An empty label matches any
+// asset node; all predicates must hold.
+type NodeSelector struct {
+	Label      string              `json:"label,omitempty" yaml:"label"`
+	Predicates []PropertyPredicate `json:"predicates,omitempty" yaml:"predicates"`
+}
+
+// PropertyPredicate compares a node property against a value
+type PropertyPredicate struct {
+	Property string      `json:"property" yaml:"property"`
+	Operator string      `json:"operator" yaml:"operator"` // eq, ne, in, gt, gte, lt, lte, contains, exists
+	Value    interface{} `json:"value,omitempty" yaml:"value"`
+}
+
+// predicateOperators maps predicate operators to Cypher comparisons
+var predicateOperators = map[string]string{
+	"eq":       "=",
+	"ne":       "<>",
+	"in":       "IN",
+	"gt":       ">",
+	"gte":      ">=",
+	"lt":       "<",
+	"lte":      "<=",
+	"contains": "CONTAINS",
+}
+
+// ValidateSelectors checks that selectors can be compiled into Cypher
+func ValidateSelectors(selectors []NodeSelector) error {
+	_, err := compileSelectors("n", "v", selectors, make(map[string]interface{}))
+	return err
+}
+
+// compileSelectors compiles selectors into a Cypher predicate on variable
+// that holds when any selector matches. Labels and property names are
+// validated before being interpolated; values are passed as parameters named
+// with prefix and added to params.
+func compileSelectors(variable, prefix string, selectors []NodeSelector, params map[string]interface{}) (string, error) {
+	if len(selectors) == 0 {
+		return "", fmt.Errorf("at least one selector is required")
+	}
+
+	clauses := make([]string, 0, len(selectors))
+	for i, selector := range selectors {
+		var conditions []string
+
+		if selector.Label != "" {
+			if !identifierPattern.MatchString(selector.Label) {
+				return "", fmt.Errorf("invalid selector label %q", selector.Label)
+			}
+			conditions = append(conditions, fmt.Sprintf("%s:%s", variable, selector.Label))
+		}
+
+		for j, predicate := range selector.Predicates {
+			if !identifierPattern.MatchString(predicate.Property) {
+				return "", fmt.Errorf("invalid selector property %q", predicate.Property)
+			}
+			property := fmt.Sprintf("%s.%s", variable, predicate.Property)
+
+			if predicate.Operator == "exists" {
+				conditions = append(conditions, property+" IS NOT NULL")
+				continue
+			}
+
+			operator, ok := predicateOperators[predicate.Operator]
+			if !ok {
+				return "", fmt.Errorf("unsupported selector operator %q", predicate.Operator)
+			}
+			if predicate.Value == nil {
+				return "", fmt.Errorf("selector predicate on %s requires a value", predicate.Property)
+			}
+
+			param := fmt.Sprintf("%s_%d_%d", prefix, i, j)
+			params[param] = predicate.Value
+			conditions = append(conditions, fmt.Sprintf("%s %s $%s", property, operator, param))
+		}
+
+		if len(conditions) == 0 {
+			conditions = append(conditions, "true")
+		}
+		clauses = append(clauses, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", nil
+}
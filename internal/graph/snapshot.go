@@ -0,0 +1,536 @@
+package graph
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/pkg/apperrors"
+)
+
+// SnapshotRecordKind identifies the entity stored in a snapshot record
+type SnapshotRecordKind string
+
+const (
+	SnapshotKindNode         SnapshotRecordKind = "node"
+	SnapshotKindFinding      SnapshotRecordKind = "finding"
+	SnapshotKindRelationship SnapshotRecordKind = "relationship"
+)
+
+// writeBatchSize is the number of rows written per UNWIND statement
+const writeBatchSize = 500
+
+// identifierPattern matches labels and relationship types that are safe to
+// interpolate into Cypher
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SnapshotRecord is a single newline-delimited JSON record of a graph snapshot.
+// For relationships Label holds the relationship type; for findings ToID holds
+// the ID of the asset the finding was generated for. Label is a node's primary
+// label and Labels all of its labels.
+type SnapshotRecord struct {
+	Kind       SnapshotRecordKind     `json:"kind"`
+	ID         string                 `json:"id"`
+	Label      string                 `json:"label"`
+	Labels     []string               `json:"labels,omitempty"`
+	FromID     string                 `json:"from_id,omitempty"`
+	FromLabel  string                 `json:"from_label,omitempty"`
+	ToID       string                 `json:"to_id,omitempty"`
+	ToLabel    string                 `json:"to_label,omitempty"`
+	Properties map[string]interface{} `json:"properties"`
+	Temporal   []string               `json:"temporal,omitempty"`
+	// Floats are the properties holding floating point numbers. Other
+	// numbers are restored as integers.
+	Floats []string `json:"floats,omitempty"`
+}
+
+// ImportResult summarizes a graph snapshot restore
+type ImportResult struct {
+	Nodes         int `json:"nodes"`
+	Findings      int `json:"findings"`
+	Relationships int `json:"relationships"`
+	Skipped       int `json:"skipped"`
+}
+
+// ExportGraph streams all nodes, findings and relationships of the tenant
+// database as newline-delimited JSON. An empty tenant exports the configured
+// default database. All records are read in a single transaction so the
+// snapshot is consistent. The export stops when ctx is done, so a reader
+// that goes away must cancel it.
+func (s *Neo4jStore) ExportGraph(ctx context.Context, tenant string) (io.Reader, error) {
+	database, err := s.tenantDatabase(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{
+		AccessMode:   neo4j.AccessModeRead,
+		DatabaseName: database,
+	})
+
+	tx, err := session.BeginTransaction(ctx)
+	if err != nil {
+		session.Close(ctx)
+		return nil, fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+
+	// A writer blocked on a reader that stopped reading is released by
+	// closing the pipe
+	go func() {
+		select {
+		case <-ctx.Done():
+			pw.CloseWithError(ctx.Err())
+		case <-done:
+		}
+	}()
+
+	go func() {
+		defer close(done)
+		defer session.Close(context.Background())
+		defer tx.Close(context.Background())
+
+		w := bufio.NewWriter(pw)
+		err := s.writeSnapshot(ctx, tx, json.NewEncoder(w))
+		if err == nil {
+			err = w.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// writeSnapshot encodes every snapshot record read through tx
+func (s *Neo4jStore) writeSnapshot(ctx context.Context, tx neo4j.ExplicitTransaction, enc *json.Encoder) error {
+	// Nodes first so that a restore can attach findings and relationships
+	result, err := tx.Run(ctx, `
+		MATCH (n)
+		WHERE n.id IS NOT NULL AND NOT n:Finding
+		RETURN n.id as id, labels(n) as labels, properties(n) as props
+	`, nil)
+	if err != nil {
+		return fmt.Errorf("failed to export nodes: %w", err)
+	}
+
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		record := newSnapshotRecord(SnapshotKindNode, values["props"])
+		record.ID, _ = values["id"].(string)
+		record.Labels = stringLabels(values["labels"])
+		record.Label = primaryLabel(record.Labels)
+
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	if err := result.Err(); err != nil {
+		return fmt.Errorf("failed to export nodes: %w", err)
+	}
+
+	result, err = tx.Run(ctx, `
+		MATCH (f:Finding)
+		OPTIONAL MATCH (f)-[:GENERATES]->(asset)
+		RETURN f.id as id, properties(f) as props, asset.id as assetId
+	`, nil)
+	if err != nil {
+		return fmt.Errorf("failed to export findings: %w", err)
+	}
+
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		record := newSnapshotRecord(SnapshotKindFinding, values["props"])
+		record.ID, _ = values["id"].(string)
+		record.Label = "Finding"
+		record.ToID, _ = values["assetId"].(string)
+
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	if err := result.Err(); err != nil {
+		return fmt.Errorf("failed to export findings: %w", err)
+	}
+
+	result, err = tx.Run(ctx, `
+		MATCH (from)-[r]->(to)
+		WHERE type(r) <> 'GENERATES' AND NOT to:RiskSnapshot
+		RETURN r.id as id, type(r) as type, properties(r) as props,
+			from.id as fromId, labels(from) as fromLabels,
+			to.id as toId, labels(to) as toLabels
+	`, nil)
+	if err != nil {
+		return fmt.Errorf("failed to export relationships: %w", err)
+	}
+
+	for result.Next(ctx) {
+		values := result.Record().AsMap()
+		record := newSnapshotRecord(SnapshotKindRelationship, values["props"])
+		record.ID, _ = values["id"].(string)
+		record.Label, _ = values["type"].(string)
+		record.FromID, _ = values["fromId"].(string)
+		record.FromLabel = primaryLabel(stringLabels(values["fromLabels"]))
+		record.ToID, _ = values["toId"].(string)
+		record.ToLabel = primaryLabel(stringLabels(values["toLabels"]))
+
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	if err := result.Err(); err != nil {
+		return fmt.Errorf("failed to export relationships: %w", err)
+	}
+
+	return nil
+}
+
+// ImportGraph restores a snapshot produced by ExportGraph into the tenant
+// database. Existing entities with the same ID are overwritten. When labels is
+// non-empty only nodes and findings with one of those labels are restored,
+// along with relationships whose endpoints both carry a selected label.
+func (s *Neo4jStore) ImportGraph(ctx context.Context, tenant string, r io.Reader, labels []string) (*ImportResult, error) {
+	database, err := s.tenantDatabase(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	session := s.driver.NewSession(ctx, neo4j.SessionConfig{
+		AccessMode:   neo4j.AccessModeWrite,
+		DatabaseName: database,
+	})
+	defer session.Close(ctx)
+
+	selected := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		selected[label] = true
+	}
+	include := func(labels ...string) bool {
+		if len(selected) == 0 {
+			return true
+		}
+		for _, label := range labels {
+			if selected[label] {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := &ImportResult{}
+	nodes := make(map[string][]map[string]interface{})
+	relationships := make(map[string][]map[string]interface{})
+	var findings []map[string]interface{}
+
+	// Nodes are batched by their label set: the primary label is merged on
+	// and the others are added to it
+	flushNodes := func() error {
+		for key, rows := range nodes {
+			labels := strings.Split(key, ":")
+			query := fmt.Sprintf(`
+				UNWIND $rows AS row
+				MERGE (n:%s {id: row.id})
+				SET n += row.props
+			`, labels[0])
+			if len(labels) > 1 {
+				query += fmt.Sprintf(", n:%s", strings.Join(labels[1:], ":"))
+			}
+			if _, err := session.Run(ctx, query, map[string]interface{}{"rows": rows}); err != nil {
+				return fmt.Errorf("failed to restore %s nodes: %w", labels[0], err)
+			}
+			result.Nodes += len(rows)
+		}
+		nodes = make(map[string][]map[string]interface{})
+		return nil
+	}
+
+	flushFindings := func() error {
+		if len(findings) == 0 {
+			return nil
+		}
+		query := `
+			UNWIND $rows AS row
+			MERGE (f:Finding {id: row.id})
+			SET f += row.props
+			WITH f, row
+			MATCH (asset {id: row.assetId})
+			MERGE (f)-[:GENERATES]->(asset)
+		`
+		if _, err := session.Run(ctx, query, map[string]interface{}{"rows": findings}); err != nil {
+			return fmt.Errorf("failed to restore findings: %w", err)
+		}
+		result.Findings += len(findings)
+		findings = nil
+		return nil
+	}
+
+	flushRelationships := func() error {
+		// Endpoints must exist before relationships can be matched
+		if err := flushNodes(); err != nil {
+			return err
+		}
+		for relType, rows := range relationships {
+			query := fmt.Sprintf(`
+				UNWIND $rows AS row
+				MATCH (from {id: row.fromId}), (to {id: row.toId})
+				MERGE (from)-[r:%s]->(to)
+				SET r += row.props, r.id = row.id
+			`, relType)
+			if _, err := session.Run(ctx, query, map[string]interface{}{"rows": rows}); err != nil {
+				return fmt.Errorf("failed to restore %s relationships: %w", relType, err)
+			}
+			result.Relationships += len(rows)
+		}
+		relationships = make(map[string][]map[string]interface{})
+		return nil
+	}
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	for {
+		var record SnapshotRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return result, fmt.Errorf("failed to decode snapshot record: %w", err)
+		}
+
+		if record.ID == "" || !identifierPattern.MatchString(record.Label) {
+			result.Skipped++
+			continue
+		}
+
+		row := map[string]interface{}{
+			"id":    record.ID,
+			"props": record.restoreProperties(),
+		}
+
+		switch record.Kind {
+		case SnapshotKindNode:
+			key, ok := record.labelKey()
+			if !ok {
+				result.Skipped++
+				continue
+			}
+			if !include(append(record.Labels, record.Label)...) {
+				result.Skipped++
+				continue
+			}
+			nodes[key] = append(nodes[key], row)
+			if len(nodes[key]) >= writeBatchSize {
+				if err := flushNodes(); err != nil {
+					return result, err
+				}
+			}
+
+		case SnapshotKindFinding:
+			if !include(record.Label) {
+				result.Skipped++
+				continue
+			}
+			row["assetId"] = record.ToID
+			findings = append(findings, row)
+			if len(findings) >= writeBatchSize {
+				if err := flushNodes(); err != nil {
+					return result, err
+				}
+				if err := flushFindings(); err != nil {
+					return result, err
+				}
+			}
+
+		case SnapshotKindRelationship:
+			if !include(record.FromLabel) || !include(record.ToLabel) {
+				result.Skipped++
+				continue
+			}
+			row["fromId"] = record.FromID
+			row["toId"] = record.ToID
+			relationships[record.Label] = append(relationships[record.Label], row)
+			if len(relationships[record.Label]) >= writeBatchSize {
+				if err := flushRelationships(); err != nil {
+					return result, err
+				}
+			}
+
+		default:
+			result.Skipped++
+		}
+	}
+
+	if err := flushNodes(); err != nil {
+		return result, err
+	}
+	if err := flushFindings(); err != nil {
+		return result, err
+	}
+	if err := flushRelationships(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// tenantDatabase returns the database holding a tenant's graph, rejecting
+// databases that are not configured as tenant databases
+func (s *Neo4jStore) tenantDatabase(tenant string) (string, error) {
+	if tenant == "" || tenant == s.config.Database {
+		return s.config.Database, nil
+	}
+	for _, database := range s.config.TenantDatabases {
+		if database == tenant {
+			return tenant, nil
+		}
+	}
+	return "", apperrors.Invalid("unknown tenant database: %q", tenant)
+}
+
+// stringLabels converts labels as returned by labels(n)
+func stringLabels(value interface{}) []string {
+	values, _ := value.([]interface{})
+	labels := make([]string, 0, len(values))
+	for _, v := range values {
+		if label, ok := v.(string); ok {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// primaryLabel returns the label a node is identified by in a snapshot: its
+// schema label if it has one, since Neo4j does not order labels, or else
+// the first label
+func primaryLabel(labels []string) string {
+	for _, label := range labels {
+		if schemaNodeLabels[label] || label == "Finding" || label == "RiskSnapshot" {
+			return label
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return labels[0]
+}
+
+// labelKey returns the labels of a node record joined with its primary
+// label first, and false if any label is unsafe to interpolate into Cypher.
+// Records written before Labels was exported carry only Label.
+func (r SnapshotRecord) labelKey() (string, bool) {
+	labels := []string{r.Label}
+	for _, label := range r.Labels {
+		if label != r.Label {
+			labels = append(labels, label)
+		}
+	}
+	for _, label := range labels {
+		if !identifierPattern.MatchString(label) {
+			return "", false
+		}
+	}
+	return strings.Join(labels, ":"), true
+}
+
+// newSnapshotRecord builds a record from node or relationship properties,
+// converting temporal values to RFC 3339 strings so they survive JSON
+func newSnapshotRecord(kind SnapshotRecordKind, props interface{}) SnapshotRecord {
+	record := SnapshotRecord{
+		Kind:       kind,
+		Properties: make(map[string]interface{}),
+	}
+
+	values, _ := props.(map[string]interface{})
+	for key, value := range values {
+		if key == "id" {
+			continue
+		}
+		// Compressed documents are exported as JSON so snapshots stay
+		// portable between stores with different data encodings
+		if raw, ok := value.([]byte); ok && key == "data" {
+			if data, err := decodeData(raw); err == nil {
+				value = string(data)
+			}
+		}
+		if t, ok := value.(time.Time); ok {
+			record.Properties[key] = t.Format(time.RFC3339Nano)
+			record.Temporal = append(record.Temporal, key)
+			continue
+		}
+		if isFloat(value) {
+			record.Floats = append(record.Floats, key)
+		}
+		record.Properties[key] = value
+	}
+
+	return record
+}
+
+// isFloat reports whether a property value is a float or a list of them
+func isFloat(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(float64); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// restoreProperties returns the record properties with temporal values
+// converted back to time.Time so they are stored as Neo4j datetimes, and
+// numbers decoded as json.Number converted back to integers or floats
+func (r SnapshotRecord) restoreProperties() map[string]interface{} {
+	floats := make(map[string]bool, len(r.Floats))
+	for _, key := range r.Floats {
+		floats[key] = true
+	}
+
+	props := make(map[string]interface{}, len(r.Properties))
+	for key, value := range r.Properties {
+		props[key] = restoreNumbers(value, floats[key])
+	}
+
+	for _, key := range r.Temporal {
+		if value, ok := props[key].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				props[key] = t
+			}
+		}
+	}
+
+	return props
+}
+
+// restoreNumbers converts json.Number values, alone or in a list, to
+// float64 when float is set and to int64 otherwise
+func restoreNumbers(value interface{}, float bool) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if !float {
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = restoreNumbers(item, float)
+		}
+		return values
+	}
+	return value
+}
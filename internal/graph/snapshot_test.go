@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRecordRoundTripKeepsPropertyTypes(t *testing.T) {
+	seen := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	record := newSnapshotRecord(SnapshotKindNode, map[string]interface{}{
+		"id":         "asset-1",
+		"version":    int64(3),
+		"risk_score": 7.0,
+		"strength":   0.25,
+		"ports":      []interface{}{int64(22), int64(443)},
+		"first_seen": seen,
+		"name":       "web",
+	})
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(record); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	decoder := json.NewDecoder(&buf)
+	decoder.UseNumber()
+	var decoded SnapshotRecord
+	if err := decoder.Decode(&decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	props := decoded.restoreProperties()
+	if v, ok := props["version"].(int64); !ok || v != 3 {
+		t.Errorf("version = %#v, want int64(3)", props["version"])
+	}
+	if v, ok := props["risk_score"].(float64); !ok || v != 7.0 {
+		t.Errorf("risk_score = %#v, want float64(7)", props["risk_score"])
+	}
+	if v, ok := props["strength"].(float64); !ok || v != 0.25 {
+		t.Errorf("strength = %#v, want float64(0.25)", props["strength"])
+	}
+	ports, _ := props["ports"].([]interface{})
+	if len(ports) != 2 || ports[0] != int64(22) || ports[1] != int64(443) {
+		t.Errorf("ports = %#v, want [int64(22) int64(443)]", props["ports"])
+	}
+	if v, ok := props["first_seen"].(time.Time); !ok || !v.Equal(seen) {
+		t.Errorf("first_seen = %#v, want %v", props["first_seen"], seen)
+	}
+	if props["name"] != "web" {
+		t.Errorf("name = %#v, want web", props["name"])
+	}
+}
+
+func TestSnapshotRecordLabelKey(t *testing.T) {
+	record := SnapshotRecord{Label: "Compute", Labels: []string{"Tenant_a", "Compute"}}
+	if key, ok := record.labelKey(); !ok || key != "Compute:Tenant_a" {
+		t.Errorf("labelKey() = %q, %v, want Compute:Tenant_a, true", key, ok)
+	}
+
+	record = SnapshotRecord{Label: "Compute", Labels: []string{"Compute", "x) DETACH DELETE n //"}}
+	if key, ok := record.labelKey(); ok {
+		t.Errorf("labelKey() = %q, want rejected", key)
+	}
+}
+
+func TestTenantDatabaseRequiresConfiguredDatabase(t *testing.T) {
+	s := &Neo4jStore{config: GraphConfig{Database: "neo4j", TenantDatabases: []string{"db_acme"}}}
+
+	for tenant, want := range map[string]string{"": "neo4j", "neo4j": "neo4j", "db_acme": "db_acme"} {
+		if got, err := s.tenantDatabase(tenant); err != nil || got != want {
+			t.Errorf("tenantDatabase(%q) = %q, %v, want %q", tenant, got, err, want)
+		}
+	}
+	if _, err := s.tenantDatabase("system"); err == nil {
+		t.Error("tenantDatabase(system) succeeded, want an error")
+	}
+}
@@ -9,11 +9,19 @@ import (
     "time"
 
     "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+    "github.com/securizon/internal/tenant"
+    "github.com/securizon/pkg/models"
 )
 
 type AttackPathEngine struct {
-    driver neo4j.Driver
-    config AttackPathConfig
+    driver    neo4j.DriverWithContext
+    // regions are the drivers of a regional engine by data region, with
+    // driver serving defaultRegion; nil for an engine on a single database
+    regions       map[string]neo4j.DriverWithContext
+    defaultRegion string
+    config    AttackPathConfig
+    affected  *AffectedPathCache
+    allowlist *PathAllowlist
 }
 
 type AttackPathConfig struct {
@@ -22,6 +30,100 @@ type AttackPathConfig struct {
     RiskThreshold          float64
     CacheTTL               time.Duration
     EnableParallelTraversal bool
+    // RelationshipWeights scales the risk each hop contributes by relationship
+    // type; types not listed contribute with weight 1.0
+    RelationshipWeights    map[string]float64
+    // BetweennessSamplingSize is the number of source nodes sampled when
+    // computing betweenness centrality; 0 computes it exactly over all nodes
+    BetweennessSamplingSize int
+    // CriticalNodeMinCentrality and CriticalNodeMinRisk select the nodes
+    // GetCriticalPaths reports as critical
+    CriticalNodeMinCentrality float64
+    CriticalNodeMinRisk       float64
+    // EntryPoints and Targets define where attack paths start and what they
+    // try to reach; a node matching any selector qualifies
+    EntryPoints []NodeSelector
+    Targets     []NodeSelector
+    // TechniqueMapping maps relationship types to the MITRE ATT&CK tactic
+    // and techniques used to traverse them in a path's kill chain
+    TechniqueMapping map[string]TechniqueMapping
+}
+
+// DefaultAttackPathConfig returns the default attack path configuration
+func DefaultAttackPathConfig() AttackPathConfig {
+    return AttackPathConfig{
+        MaxHops:                   5,
+        MaxPathsPerQuery:          50,
+        RiskThreshold:             50.0,
+        CacheTTL:                  5 * time.Minute,
+        RelationshipWeights:       DefaultRelationshipWeights(),
+        BetweennessSamplingSize:   1000,
+        CriticalNodeMinCentrality: 0.1,
+        CriticalNodeMinRisk:       40.0,
+        EntryPoints:               DefaultEntryPoints(),
+        Targets:                   DefaultTargets(),
+        TechniqueMapping:          DefaultTechniqueMapping(),
+    }
+}
+
+// DefaultEntryPoints selects internet-exposed assets
+func DefaultEntryPoints() []NodeSelector {
+    return []NodeSelector{
+        {Predicates: []PropertyPredicate{{Property: "internet_exposed", Operator: "eq", Value: true}}},
+    }
+}
+
+// DefaultTargets selects sensitive data stores and admin identities
+func DefaultTargets() []NodeSelector {
+    return []NodeSelector{
+        {Label: "Data", Predicates: []PropertyPredicate{
+            {Property: "data_sensitivity", Operator: "in", Value: []string{"confidential", "restricted"}},
+        }},
+        {Label: "Identity", Predicates: []PropertyPredicate{
+            {Property: "privilege_level", Operator: "eq", Value: "admin"},
+        }},
+    }
+}
+
+// Validate checks the attack path configuration
+func (c AttackPathConfig) Validate() error {
+    if c.MaxHops <= 0 {
+        return fmt.Errorf("max hops must be positive, got %d", c.MaxHops)
+    }
+    if c.BetweennessSamplingSize < 0 {
+        return fmt.Errorf("betweenness sampling size must not be negative, got %d", c.BetweennessSamplingSize)
+    }
+    if c.CriticalNodeMinCentrality < 0 {
+        return fmt.Errorf("critical node minimum centrality must not be negative, got %f", c.CriticalNodeMinCentrality)
+    }
+    if c.CriticalNodeMinRisk < 0 || c.CriticalNodeMinRisk > 100 {
+        return fmt.Errorf("critical node minimum risk must be between 0 and 100, got %f", c.CriticalNodeMinRisk)
+    }
+    if err := ValidateSelectors(c.EntryPoints); err != nil {
+        return fmt.Errorf("invalid entry points: %w", err)
+    }
+    if err := ValidateSelectors(c.Targets); err != nil {
+        return fmt.Errorf("invalid targets: %w", err)
+    }
+    if err := ValidateTechniqueMapping(c.TechniqueMapping); err != nil {
+        return fmt.Errorf("invalid technique mapping: %w", err)
+    }
+    return nil
+}
+
+// DefaultRelationshipWeights returns per-relationship-type hop weights that
+// reflect how easily an attacker can traverse each kind of edge
+func DefaultRelationshipWeights() map[string]float64 {
+    return map[string]float64{
+        "ASSUMES_ROLE":  1.5,
+        "HAS_ACCESS_TO": 1.3,
+        "MANAGES":       1.2,
+        "RUNS_ON":       1.0,
+        "STORES":        1.0,
+        "CONNECTED_TO":  0.7,
+        "CONTAINS":      0.5,
+        "DEPENDS_ON":    0.5,
+    }
 }
 
 type AttackPath struct {
@@ -30,9 +132,14 @@ type AttackPath struct {
     TargetID       string               `json:"target_id"`
     Hops           int                  `json:"hops"`
     CumulativeRisk float64              `json:"cumulative_risk"`
+    Likelihood     float64              `json:"likelihood"`
+    Priority       float64              `json:"priority"` // CumulativeRisk x Likelihood
     Path           []PathNode           `json:"path"`
     Vulnerabilities []PathVulnerability `json:"vulnerabilities"`
     Exploitable    bool                 `json:"exploitable"`
+    KillChain      []KillChainStep      `json:"kill_chain,omitempty"` // MITRE ATT&CK tactic of each hop
+    Accepted       bool                 `json:"accepted,omitempty"` // matches an accepted path
+    AcceptedPathID string               `json:"accepted_path_id,omitempty"`
 }
 
 type PathNode struct {
@@ -51,32 +158,158 @@ type PathVulnerability struct {
     RemediationURL string  `json:"remediation_url,omitempty"`
 }
 
-func NewAttackPathEngine(driver neo4j.Driver) *AttackPathEngine {
+func NewAttackPathEngine(driver neo4j.DriverWithContext) *AttackPathEngine {
+    // The default configuration is always valid
+    engine, _ := NewAttackPathEngineWithConfig(driver, DefaultAttackPathConfig())
+    return engine
+}
+
+// NewAttackPathEngineWithConfig creates an attack path engine with a custom configuration
+func NewAttackPathEngineWithConfig(driver neo4j.DriverWithContext, config AttackPathConfig) (*AttackPathEngine, error) {
+    if config.MaxHops == 0 {
+        config.MaxHops = DefaultAttackPathConfig().MaxHops
+    }
+    if config.RelationshipWeights == nil {
+        config.RelationshipWeights = DefaultRelationshipWeights()
+    }
+    if config.EntryPoints == nil {
+        config.EntryPoints = DefaultEntryPoints()
+    }
+    if config.Targets == nil {
+        config.Targets = DefaultTargets()
+    }
+    if config.TechniqueMapping == nil {
+        config.TechniqueMapping = DefaultTechniqueMapping()
+    }
+    if err := config.Validate(); err != nil {
+        return nil, fmt.Errorf("invalid attack path config: %w", err)
+    }
     return &AttackPathEngine{
-        driver: driver,
-        config: AttackPathConfig{
-            MaxHops:          5,
-            MaxPathsPerQuery: 50,
-            RiskThreshold:    50.0,
-            CacheTTL:         5 * time.Minute,
-        },
+        driver:   driver,
+        config:   config,
+        affected: NewAffectedPathCache(),
+    }, nil
+}
+
+// AttackPathEngine creates an attack path engine querying the store's database
+func (s *Neo4jStore) AttackPathEngine(config AttackPathConfig) (*AttackPathEngine, error) {
+    return NewAttackPathEngineWithConfig(s.driver, config)
+}
+
+// AttackPathEngine creates an attack path engine querying the database of
+// the region each request resolves to, like the store itself. Every region
+// must be served by a Neo4j store.
+func (s *RegionalStore) AttackPathEngine(config AttackPathConfig) (*AttackPathEngine, error) {
+    regions := make(map[string]neo4j.DriverWithContext, len(s.regions))
+    for region, store := range s.regions {
+        neo4jStore, ok := store.(*Neo4jStore)
+        if !ok {
+            return nil, fmt.Errorf("region %s is not served by a Neo4j store", region)
+        }
+        regions[region] = neo4jStore.driver
+    }
+
+    engine, err := NewAttackPathEngineWithConfig(regions[s.defaultRegion], config)
+    if err != nil {
+        return nil, err
+    }
+    engine.regions = regions
+    engine.defaultRegion = s.defaultRegion
+    return engine, nil
+}
+
+// session opens a read session on the database of the region ctx resolves to
+func (ape *AttackPathEngine) session(ctx context.Context) (neo4j.SessionWithContext, error) {
+    driver, err := ape.regionDriver(ctx)
+    if err != nil {
+        return nil, err
+    }
+    return driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead}), nil
+}
+
+// regionDriver returns the driver of the region ctx resolves to. A regional
+// engine rejects regions it has no database for rather than serving them
+// from another region.
+func (ape *AttackPathEngine) regionDriver(ctx context.Context) (neo4j.DriverWithContext, error) {
+    if ape.regions == nil {
+        return ape.driver, nil
+    }
+    region, err := tenant.RegionFromContext(ctx)
+    if err != nil {
+        return nil, err
+    }
+    if region == "" {
+        region = ape.defaultRegion
+    }
+    driver, ok := ape.regions[region]
+    if !ok {
+        return nil, tenant.RegionNotServed(region, "graph")
+    }
+    return driver, nil
+}
+
+// SetAllowlist flags paths matching an accepted path entry as accepted and
+// leaves them out of critical path reports
+func (ape *AttackPathEngine) SetAllowlist(allowlist *PathAllowlist) {
+    ape.allowlist = allowlist
+}
+
+// markAccepted flags path as accepted if it matches the allowlist
+func (ape *AttackPathEngine) markAccepted(ctx context.Context, path *AttackPath, relTypes []string) {
+    if ape.allowlist == nil {
+        return
+    }
+    if entry, ok := ape.allowlist.Match(ctx, path.SourceID, path.TargetID, relTypes); ok {
+        path.Accepted = true
+        path.AcceptedPathID = entry.ID
     }
 }
 
-// FindPathsFromInternet finds all attack paths from internet-facing assets
+// FindPathsFromInternet finds all attack paths from the configured entry
+// points, internet-facing assets by default, to the configured targets
 func (ape *AttackPathEngine) FindPathsFromInternet(ctx context.Context, maxHops int) ([]AttackPath, error) {
-    session := ape.driver.NewSession(neo4j.SessionConfig{})
-    defer session.Close()
+    return ape.FindPathsMatching(ctx, ape.config.EntryPoints, ape.config.Targets, maxHops)
+}
+
+// FindPathsMatching finds attack paths from any node matching an entry point
+// selector to any node matching a target selector
+func (ape *AttackPathEngine) FindPathsMatching(ctx context.Context, entryPoints, targets []NodeSelector, maxHops int) ([]AttackPath, error) {
+    // Path enumeration grows exponentially with depth, so callers cannot
+    // search deeper than the configured limit
+    if maxHops <= 0 || maxHops > ape.config.MaxHops {
+        maxHops = ape.config.MaxHops
+    }
+
+    params := map[string]interface{}{
+        "max_hops":       maxHops,
+        "risk_threshold": ape.config.RiskThreshold,
+        "max_paths":      ape.config.MaxPathsPerQuery,
+        "rel_weights":    ape.config.RelationshipWeights,
+    }
+
+    entryClause, err := compileSelectors("entry", "entry", entryPoints, params)
+    if err != nil {
+        return nil, fmt.Errorf("invalid entry points: %w", err)
+    }
+    targetClause, err := compileSelectors("target", "target", targets, params)
+    if err != nil {
+        return nil, fmt.Errorf("invalid targets: %w", err)
+    }
+
+    session, err := ape.session(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer session.Close(ctx)
 
     query := `
-        // Find internet-facing assets as entry points
-        MATCH (entry:Asset {internet_exposed: true})
-        WHERE entry.risk_score >= $risk_threshold
+        // Find entry points
+        MATCH (entry:Asset)
+        WHERE ` + entryClause + ` AND entry.risk_score >= $risk_threshold
         
-        // Find potential targets (sensitive data, admin roles)
+        // Find potential targets
         MATCH (target:Asset)
-        WHERE (target:Data AND target.data_sensitivity IN ['confidential', 'restricted'])
-           OR (target:Identity AND target.privilege_level = 'admin')
+        WHERE ` + targetClause + `
         
         // Find all simple paths between entry and target
         MATCH path = shortestPath((entry)-[:HAS_ACCESS_TO|CONNECTED_TO|RUNS_ON|ASSUMES_ROLE*1..$max_hops]-(target))
@@ -92,11 +325,11 @@ func (ape *AttackPathEngine) FindPathsFromInternet(ctx context.Context, maxHops
                 CASE WHEN n.risk_score > maxRisk THEN n.risk_score ELSE maxRisk END
                 ) as maxNodeRisk,
              reduce(relRisk = 0.0, r IN pathRels | 
-                relRisk + COALESCE(r.trust_score, 1.0) * 10
+                relRisk + COALESCE($rel_weights[type(r)], 1.0) * COALESCE(r.trust_score, 1.0) * 10
                 ) as relationshipRisk
              
         // Combine risks with weights
-        WITH entry, target, path, pathNodes,
+        WITH entry, target, path, pathNodes, maxNodeRisk, relationshipRisk,
              (maxNodeRisk * 0.7 + relationshipRisk * 0.3) as cumulativeRisk
              
         WHERE cumulativeRisk >= $risk_threshold
@@ -104,16 +337,17 @@ func (ape *AttackPathEngine) FindPathsFromInternet(ctx context.Context, maxHops
                target.id as target_id,
                path,
                cumulativeRisk,
+               maxNodeRisk as max_node_risk,
+               relationshipRisk as relationship_risk,
                [n IN pathNodes | n.id] as node_ids,
+               [r IN relationships(path) | COALESCE(r.trust_score, 1.0)] as trust_scores,
+               [r IN relationships(path) | type(r)] as rel_types,
                length(path) as hop_count
         ORDER BY cumulativeRisk DESC
         LIMIT $max_paths`
 
-    params := map[string]interface{}{
-        "max_hops":       maxHops,
-        "risk_threshold": ape.config.RiskThreshold,
-        "max_paths":      ape.config.MaxPathsPerQuery,
-    }
+    explain := explanationFrom(ctx)
+    start := time.Now()
 
     result, err := session.Run(ctx, query, params)
     if err != nil {
@@ -121,23 +355,46 @@ func (ape *AttackPathEngine) FindPathsFromInternet(ctx context.Context, maxHops
     }
 
     var paths []AttackPath
+    var breakdowns []PathRiskBreakdown
+    evaluated := 0
     for result.Next(ctx) {
+        evaluated++
         record := result.Record()
         path, err := ape.recordToAttackPath(record)
         if err != nil {
             log.Printf("Failed to convert record to attack path: %v", err)
             continue
         }
+        trustScores, _ := record.Get("trust_scores")
+        ape.scorePath(&path, toFloatSlice(trustScores))
+        relTypes, _ := record.Get("rel_types")
+        AnnotateKillChain(&path, toStringSlice(relTypes), ape.config.TechniqueMapping)
+        ape.markAccepted(ctx, &path, toStringSlice(relTypes))
         paths = append(paths, path)
+
+        if explain != nil {
+            maxNodeRisk, _ := record.Get("max_node_risk")
+            relationshipRisk, _ := record.Get("relationship_risk")
+            breakdowns = append(breakdowns, explainPath(path, map[string]float64{
+                "max_node_risk":     toFloat(maxNodeRisk),
+                "relationship_risk": toFloat(relationshipRisk),
+            }))
+        }
     }
 
+    explain.recordQuery(query, params, time.Since(start))
+    explain.recordPaths(evaluated, len(paths), breakdowns...)
+
     return paths, nil
 }
 
 // FindPathsBetween finds attack paths between specific assets
 func (ape *AttackPathEngine) FindPathsBetween(ctx context.Context, sourceID, targetID string, maxHops int) ([]AttackPath, error) {
-    session := ape.driver.NewSession(neo4j.SessionConfig{})
-    defer session.Close()
+    session, err := ape.session(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer session.Close(ctx)
 
     query := `
         MATCH (source:Asset {id: $source_id})
@@ -166,13 +423,18 @@ func (ape *AttackPathEngine) FindPathsBetween(ctx context.Context, sourceID, tar
              [n IN pathNodes | n.id] as nodeIds,
              length(path) as hopCount,
              
+             // Weight each hop by how easily its relationship type is traversed
+             reduce(edgeRisk = 0.0, r IN pathRels |
+                edgeRisk + COALESCE($rel_weights[type(r)], 1.0) * 2
+                ) as edgeRisk,
+             
              // Find vulnerabilities along the path
              [n IN pathNodes WHERE EXISTS((n)-[:GENERATES]->(:Finding)) | 
                 {node_id: n.id, findings: [(n)-[:GENERATES]->(f:Finding) | f]}
              ] as nodeFindings
         
         // Calculate cumulative risk
-        WITH path, nodeIds, hopCount, nodeFindings,
+        WITH path, nodeIds, hopCount, edgeRisk, nodeFindings,
              reduce(maxRisk = 0.0, r IN nodeRisks | 
                 CASE WHEN r > maxRisk THEN r ELSE maxRisk END
                 ) as maxRisk,
@@ -186,13 +448,18 @@ func (ape *AttackPathEngine) FindPathsBetween(ctx context.Context, sourceID, tar
         WITH *, 
              (maxRisk * 0.6 + 
               (criticalVulns * 15) + 
-              (hopCount * 2)) as cumulativeRisk
+              edgeRisk) as cumulativeRisk
               
         WHERE cumulativeRisk >= $risk_threshold
         RETURN nodeIds,
                cumulativeRisk,
+               maxRisk,
+               edgeRisk,
+               criticalVulns,
                hopCount,
-               nodeFindings
+               nodeFindings,
+               [r IN relationships(path) | COALESCE(r.trust_score, 1.0)] as trustScores,
+               [r IN relationships(path) | type(r)] as relTypes
         ORDER BY cumulativeRisk DESC`
 
     params := map[string]interface{}{
@@ -200,20 +467,28 @@ func (ape *AttackPathEngine) FindPathsBetween(ctx context.Context, sourceID, tar
         "target_id":      targetID,
         "max_hops":       maxHops,
         "risk_threshold": ape.config.RiskThreshold,
+        "rel_weights":    ape.config.RelationshipWeights,
     }
 
+    start := time.Now()
+
     result, err := session.Run(ctx, query, params)
     if err != nil {
         return nil, fmt.Errorf("failed to execute path query: %v", err)
     }
 
-    return ape.processPathResults(ctx, result)
+    paths, err := ape.processPathResults(ctx, result)
+    explanationFrom(ctx).recordQuery(query, params, time.Since(start))
+    return paths, err
 }
 
 // SimulateAttack simulates an attack from a starting point
 func (ape *AttackPathEngine) SimulateAttack(ctx context.Context, startAssetID string, maxHops int) (*AttackSimulation, error) {
-    session := ape.driver.NewSession(neo4j.SessionConfig{})
-    defer session.Close()
+    session, err := ape.session(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer session.Close(ctx)
 
     query := `
         MATCH (start:Asset {id: $start_id})
@@ -271,8 +546,11 @@ func (ape *AttackPathEngine) SimulateAttack(ctx context.Context, startAssetID st
 
 // GetCriticalPaths returns the most critical attack paths across the environment
 func (ape *AttackPathEngine) GetCriticalPaths(ctx context.Context, limit int) ([]CriticalPath, error) {
-    session := ape.driver.NewSession(neo4j.SessionConfig{})
-    defer session.Close()
+    session, err := ape.session(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer session.Close(ctx)
 
     // This query uses Neo4j's Graph Data Science library for more advanced analysis
     query := `
@@ -292,10 +570,10 @@ func (ape *AttackPathEngine) GetCriticalPaths(ctx context.Context, limit int) ([
         )
         
         // Find betweenness centrality to identify critical nodes
-        CALL gds.betweenness.stream('attack-graph', {samplingSize: 1000})
+        CALL gds.betweenness.stream('attack-graph', $betweenness_config)
         YIELD nodeId, score
         WITH gds.util.asNode(nodeId) as node, score
-        WHERE score > 0.1 AND node.risk_score > 40
+        WHERE score > $min_centrality AND node.risk_score > $min_risk
         ORDER BY score DESC
         LIMIT 10
         
@@ -314,7 +592,19 @@ func (ape *AttackPathEngine) GetCriticalPaths(ctx context.Context, limit int) ([
                }) as exposure_paths
         ORDER BY node_risk DESC`
 
-    result, err := session.Run(ctx, query, nil)
+    // Sampling trades accuracy for speed on large graphs
+    betweennessConfig := map[string]interface{}{}
+    if ape.config.BetweennessSamplingSize > 0 {
+        betweennessConfig["samplingSize"] = ape.config.BetweennessSamplingSize
+    }
+
+    params := map[string]interface{}{
+        "betweenness_config": betweennessConfig,
+        "min_centrality":     ape.config.CriticalNodeMinCentrality,
+        "min_risk":           ape.config.CriticalNodeMinRisk,
+    }
+
+    result, err := session.Run(ctx, query, params)
     if err != nil {
         // Fallback to simpler query if GDS is not available
         return ape.getCriticalPathsFallback(ctx, limit)
@@ -325,8 +615,11 @@ func (ape *AttackPathEngine) GetCriticalPaths(ctx context.Context, limit int) ([
 
 // Optimized path finding for real-time updates
 func (ape *AttackPathEngine) FindPathsAffectedByAsset(ctx context.Context, assetID string) ([]AffectedPath, error) {
-    session := ape.driver.NewSession(neo4j.SessionConfig{})
-    defer session.Close()
+    session, err := ape.session(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer session.Close(ctx)
 
     // Find all paths that include this asset and recalculate their risk
     query := `
@@ -366,11 +659,113 @@ func (ape *AttackPathEngine) FindPathsAffectedByAsset(ctx context.Context, asset
     return ape.processAffectedPaths(ctx, result)
 }
 
+// processAffectedPaths reads affected paths from a query returning node_ids
+// and path_risk, dropping paths already read
+func (ape *AttackPathEngine) processAffectedPaths(ctx context.Context, result neo4j.ResultWithContext) ([]AffectedPath, error) {
+    var paths []AffectedPath
+    seen := make(map[string]bool)
+
+    for result.Next(ctx) {
+        record := result.Record()
+        nodeIDs, _ := record.Get("node_ids")
+        pathRisk, _ := record.Get("path_risk")
+
+        path := AffectedPath{
+            NodeIDs:  toStringSlice(nodeIDs),
+            PathRisk: toFloat(pathRisk),
+        }
+        if seen[path.key()] {
+            continue
+        }
+        seen[path.key()] = true
+        paths = append(paths, path)
+    }
+    if err := result.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read affected paths: %v", err)
+    }
+
+    return paths, nil
+}
+
+// maxAffectedPathsPerAsset bounds the paths kept for each changed asset
+const maxAffectedPathsPerAsset = 25
+
+// FindPathsAffectedByAssets finds the union of the paths running through any
+// of assetIDs in one query, so a batch of changes does not cost one traversal
+// per asset as FindPathsAffectedByAsset would
+func (ape *AttackPathEngine) FindPathsAffectedByAssets(ctx context.Context, assetIDs []string) ([]AffectedPath, error) {
+    if len(assetIDs) == 0 {
+        return nil, nil
+    }
+
+    session, err := ape.session(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer session.Close(ctx)
+
+    query := `
+        UNWIND $asset_ids as asset_id
+        MATCH (asset:Asset {id: asset_id})
+
+        // Keep the strongest paths through each changed asset
+        CALL {
+            WITH asset
+            MATCH path = (n1)-[*1..3]-(asset)-[*1..3]-(n2)
+            WHERE n1 <> n2
+              AND n1.internet_exposed = true
+              AND (n2:Data OR n2.privilege_level = 'admin')
+              AND ALL(r IN relationships(path) WHERE r.valid_to = 0)
+            WITH path, reduce(maxRisk = 0.0, n IN nodes(path) |
+                CASE WHEN n.risk_score > maxRisk THEN n.risk_score ELSE maxRisk END
+            ) as path_risk
+            RETURN path, path_risk
+            ORDER BY path_risk DESC
+            LIMIT $max_paths
+        }
+
+        RETURN DISTINCT [n IN nodes(path) | n.id] as node_ids, path_risk
+        ORDER BY path_risk DESC`
+
+    params := map[string]interface{}{
+        "asset_ids": assetIDs,
+        "max_paths": maxAffectedPathsPerAsset,
+    }
+
+    result, err := session.Run(ctx, query, params)
+    if err != nil {
+        return nil, fmt.Errorf("failed to find affected paths: %v", err)
+    }
+
+    return ape.processAffectedPaths(ctx, result)
+}
+
+// RecomputeAffectedPaths recomputes the paths through a batch of changed
+// assets and replaces their cached paths in one update
+func (ape *AttackPathEngine) RecomputeAffectedPaths(ctx context.Context, assetIDs []string) error {
+    paths, err := ape.FindPathsAffectedByAssets(ctx, assetIDs)
+    if err != nil {
+        return err
+    }
+
+    ape.affected.ReplaceMany(assetIDs, paths)
+    return nil
+}
+
+// AffectedPaths returns the paths last computed through assetID
+func (ape *AttackPathEngine) AffectedPaths(assetID string) ([]AffectedPath, bool) {
+    return ape.affected.Get(assetID)
+}
+
 // Helper function to process path results
-func (ape *AttackPathEngine) processPathResults(ctx context.Context, result neo4j.Result) ([]AttackPath, error) {
+func (ape *AttackPathEngine) processPathResults(ctx context.Context, result neo4j.ResultWithContext) ([]AttackPath, error) {
     var paths []AttackPath
+    var breakdowns []PathRiskBreakdown
+    explain := explanationFrom(ctx)
+    evaluated := 0
     
     for result.Next(ctx) {
+        evaluated++
         record := result.Record()
         
         nodeIDs, _ := record.Get("nodeIds")
@@ -420,12 +815,90 @@ func (ape *AttackPathEngine) processPathResults(ctx context.Context, result neo4
             path.Exploitable = ape.isPathExploitable(vulns)
         }
         
+        trustScores, _ := record.Get("trustScores")
+        ape.scorePath(&path, toFloatSlice(trustScores))
+        
+        relTypes, _ := record.Get("relTypes")
+        AnnotateKillChain(&path, toStringSlice(relTypes), ape.config.TechniqueMapping)
+        ape.markAccepted(ctx, &path, toStringSlice(relTypes))
+        
         paths = append(paths, path)
+        
+        if explain != nil {
+            maxRisk, _ := record.Get("maxRisk")
+            edgeRisk, _ := record.Get("edgeRisk")
+            criticalVulns, _ := record.Get("criticalVulns")
+            breakdowns = append(breakdowns, explainPath(path, map[string]float64{
+                "max_node_risk":       toFloat(maxRisk),
+                "edge_risk":           toFloat(edgeRisk),
+                "critical_vulns":      toFloat(criticalVulns),
+                "critical_vulns_risk": toFloat(criticalVulns) * 15,
+            }))
+        }
     }
     
+    explain.recordPaths(evaluated, len(paths), breakdowns...)
+    
     return paths, nil
 }
 
+// explainPath builds the risk breakdown of a path for explain mode
+func explainPath(path AttackPath, components map[string]float64) PathRiskBreakdown {
+    nodeIDs := make([]string, 0, len(path.Path))
+    for _, node := range path.Path {
+        nodeIDs = append(nodeIDs, node.ID)
+    }
+    
+    return PathRiskBreakdown{
+        NodeIDs:        nodeIDs,
+        Components:     components,
+        CumulativeRisk: path.CumulativeRisk,
+        Likelihood:     path.Likelihood,
+    }
+}
+
+// toFloat converts a numeric value returned by Neo4j to float64
+func toFloat(value interface{}) float64 {
+    switch n := value.(type) {
+    case float64:
+        return n
+    case int64:
+        return float64(n)
+    }
+    return 0
+}
+
+// scorePath sets the likelihood of a path and its priority with the same
+// scoring as models.AttackPath
+func (ape *AttackPathEngine) scorePath(path *AttackPath, trustScores []float64) {
+    scored := models.AttackPath{
+        Hops:            path.Hops,
+        CumulativeRisk:  path.CumulativeRisk,
+        Vulnerabilities: make([]models.AttackPathVulnerability, 0, len(path.Vulnerabilities)),
+    }
+    for _, vuln := range path.Vulnerabilities {
+        scored.Vulnerabilities = append(scored.Vulnerabilities, models.AttackPathVulnerability{
+            FindingID:       vuln.FindingID,
+            Severity:        vuln.Severity,
+            ExploitedInPath: vuln.Exploited,
+        })
+    }
+    scored.Score(trustScores)
+    
+    path.Likelihood = scored.Likelihood
+    path.Priority = scored.Priority
+}
+
+// toFloatSlice converts a list value returned by Neo4j to float64s
+func toFloatSlice(value interface{}) []float64 {
+    values, _ := value.([]interface{})
+    floats := make([]float64, 0, len(values))
+    for _, v := range values {
+        floats = append(floats, toFloat(v))
+    }
+    return floats
+}
+
 // Calculate if a path is exploitable based on vulnerabilities
 func (ape *AttackPathEngine) isPathExploitable(vulns []PathVulnerability) bool {
     // A path is considered exploitable if it has at least one high-severity vulnerability
@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/apperrors"
+)
+
+func TestRegionalAttackPathEngineQueriesTheRequestRegion(t *testing.T) {
+	drivers := make(map[string]neo4j.DriverWithContext)
+	stores := make(map[string]GraphStore)
+	for _, region := range []string{"default", "eu"} {
+		// Drivers connect lazily, so none is reached here
+		driver, err := neo4j.NewDriverWithContext("bolt://"+region+".invalid:7687", neo4j.NoAuth())
+		if err != nil {
+			t.Fatalf("NewDriverWithContext returned error: %v", err)
+		}
+		defer driver.Close(context.Background())
+		drivers[region] = driver
+		stores[region] = &Neo4jStore{driver: driver}
+	}
+	store, err := NewRegionalStore(stores, "default")
+	if err != nil {
+		t.Fatalf("NewRegionalStore returned error: %v", err)
+	}
+	engine, err := store.AttackPathEngine(DefaultAttackPathConfig())
+	if err != nil {
+		t.Fatalf("AttackPathEngine returned error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		driver string
+	}{
+		{"no region", context.Background(), "default"},
+		{"pinned tenant", tenant.WithTenantContext(context.Background(), &tenant.TenantContext{TenantID: "t-1", Region: "eu"}), "eu"},
+		{"scoped batch", tenant.WithRegion(context.Background(), "eu"), "eu"},
+	}
+	for _, tt := range tests {
+		driver, err := engine.regionDriver(tt.ctx)
+		if err != nil {
+			t.Errorf("%s: regionDriver returned error: %v", tt.name, err)
+			continue
+		}
+		if driver != drivers[tt.driver] {
+			t.Errorf("%s: queried another region's database, want %s", tt.name, tt.driver)
+		}
+	}
+
+	if _, err := engine.FindPathsAffectedByAsset(tenant.WithRegion(context.Background(), "ap"), "vm-1"); apperrors.CodeOf(err) != apperrors.CodeForbidden {
+		t.Errorf("a region without a database returned %v, want it rejected", err)
+	}
+}
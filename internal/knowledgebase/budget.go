@@ -0,0 +1,429 @@
+package knowledgebase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/securizon/internal/tenant"
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// ErrBudgetExceeded is returned instead of calling the LLM when a tenant is
+// over its rate limit or monthly budget. Callers fall back to behavior that
+// needs no LLM.
+var ErrBudgetExceeded = &apperrors.Error{Code: apperrors.CodeQuotaExceeded, Message: "LLM budget exceeded"}
+
+// systemTenant accounts for LLM calls made outside any tenant
+const systemTenant = "system"
+
+// BudgetConfig limits LLM use per tenant. Zero limits are unlimited.
+type BudgetConfig struct {
+	Enabled           bool    `yaml:"enabled"`
+	RequestsPerMinute int     `yaml:"requests_per_minute"`
+	MonthlyTokens     int64   `yaml:"monthly_tokens"`
+	MonthlyCostUSD    float64 `yaml:"monthly_cost_usd"`
+	// Pricing is the price of each model in USD per 1000 tokens; calls to
+	// unpriced models count towards token budgets only
+	Pricing map[string]ModelPrice `yaml:"pricing"`
+	// Tenants overrides the limits of individual tenants by ID
+	Tenants map[string]TenantBudget `yaml:"tenants"`
+}
+
+// ModelPrice is the USD price of 1000 prompt and completion tokens
+type ModelPrice struct {
+	PromptPer1K     float64 `yaml:"prompt_per_1k"`
+	CompletionPer1K float64 `yaml:"completion_per_1k"`
+}
+
+// TenantBudget is the limits of one tenant
+type TenantBudget struct {
+	RequestsPerMinute int     `yaml:"requests_per_minute"`
+	MonthlyTokens     int64   `yaml:"monthly_tokens"`
+	MonthlyCostUSD    float64 `yaml:"monthly_cost_usd"`
+}
+
+// DefaultBudgetConfig returns default LLM budget configuration
+func DefaultBudgetConfig() BudgetConfig {
+	return BudgetConfig{
+		Enabled:           false,
+		RequestsPerMinute: 60,
+		MonthlyTokens:     2000000,
+		MonthlyCostUSD:    50,
+		Pricing: map[string]ModelPrice{
+			"gpt-4":                  {PromptPer1K: 0.03, CompletionPer1K: 0.06},
+			"gpt-4o":                 {PromptPer1K: 0.005, CompletionPer1K: 0.015},
+			"gpt-3.5-turbo":          {PromptPer1K: 0.0005, CompletionPer1K: 0.0015},
+			"text-embedding-ada-002": {PromptPer1K: 0.0001},
+			"text-embedding-3-small": {PromptPer1K: 0.00002},
+			"text-embedding-3-large": {PromptPer1K: 0.00013},
+		},
+	}
+}
+
+// limits returns the limits of tenantID
+func (c BudgetConfig) limits(tenantID string) TenantBudget {
+	if budget, ok := c.Tenants[tenantID]; ok {
+		return budget
+	}
+	return TenantBudget{
+		RequestsPerMinute: c.RequestsPerMinute,
+		MonthlyTokens:     c.MonthlyTokens,
+		MonthlyCostUSD:    c.MonthlyCostUSD,
+	}
+}
+
+// cost returns the USD cost of usage on model
+func (c BudgetConfig) cost(model string, usage Usage) float64 {
+	price := c.Pricing[model]
+	return float64(usage.PromptTokens)/1000*price.PromptPer1K +
+		float64(usage.CompletionTokens)/1000*price.CompletionPer1K
+}
+
+// Usage is the tokens consumed by an LLM call
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Total returns the tokens consumed in all
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// usageProvider is implemented by providers that report the tokens each
+// call consumed
+type usageProvider interface {
+	EmbedWithUsage(ctx context.Context, text string) ([]float32, Usage, error)
+	CompleteWithUsage(ctx context.Context, req CompletionRequest) (string, Usage, error)
+}
+
+// embedWithUsage embeds text with p, estimating usage if p does not report it
+func embedWithUsage(ctx context.Context, p LLMProvider, text string) ([]float32, Usage, error) {
+	if up, ok := p.(usageProvider); ok {
+		return up.EmbedWithUsage(ctx, text)
+	}
+	embedding, err := p.Embed(ctx, text)
+	return embedding, Usage{PromptTokens: estimateTokens(text)}, err
+}
+
+// completeWithUsage completes req with p, estimating usage if p does not
+// report it
+func completeWithUsage(ctx context.Context, p LLMProvider, req CompletionRequest) (string, Usage, error) {
+	if up, ok := p.(usageProvider); ok {
+		return up.CompleteWithUsage(ctx, req)
+	}
+	content, err := p.Complete(ctx, req)
+	return content, Usage{
+		PromptTokens:     estimateTokens(req.SystemPrompt) + estimateTokens(req.Prompt),
+		CompletionTokens: estimateTokens(content),
+	}, err
+}
+
+// estimateTokens approximates the tokens in text at four characters each
+func estimateTokens(text string) int {
+	return (len([]rune(text)) + 3) / 4
+}
+
+// TenantSpend is a tenant's LLM use in the current month
+type TenantSpend = models.LLMSpend
+
+// SpendStore persists tenants' monthly spend across restarts.
+// *cache.RedisCache satisfies it.
+type SpendStore interface {
+	Get(ctx context.Context, key string, target interface{}) (bool, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+// spendTTL keeps stored spend until well after its month has ended
+const spendTTL = 62 * 24 * time.Hour
+
+// tenantUsage is the spend and current rate window of a tenant, with the
+// tokens and cost reserved by calls still in flight
+type tenantUsage struct {
+	spend          TenantSpend
+	reservedTokens int64
+	reservedCost   float64
+	windowStart    time.Time
+	windowCount    int
+	loaded         bool // spend has been read from the store
+}
+
+// reservation is the estimated use of an admitted call, held against the
+// tenant's budget until the call's actual use is recorded
+type reservation struct {
+	tokens int64
+	cost   float64
+}
+
+// BudgetedProvider enforces per-tenant rate limits and monthly budgets
+// before each call to the wrapped provider and tracks what each tenant
+// spends. Each admitted call reserves its estimated tokens and cost until it
+// completes, so concurrent calls cannot overshoot a budget together. Spend
+// restarts at zero each month and is kept in memory, and in the spend store
+// when one is set.
+type BudgetedProvider struct {
+	next            LLMProvider
+	config          BudgetConfig
+	embeddingModel  string
+	completionModel string
+	store           SpendStore
+
+	mu      sync.Mutex
+	month   string
+	tenants map[string]*tenantUsage
+	now     func() time.Time
+}
+
+// NewBudgetedProvider wraps next with the budgets in config. The models are
+// those next uses, for pricing.
+func NewBudgetedProvider(next LLMProvider, config BudgetConfig, embeddingModel, completionModel string) *BudgetedProvider {
+	return &BudgetedProvider{
+		next:            next,
+		config:          config,
+		embeddingModel:  embeddingModel,
+		completionModel: completionModel,
+		tenants:         make(map[string]*tenantUsage),
+		now:             time.Now,
+	}
+}
+
+// SetSpendStore persists spend in store, reading each tenant's spend for the
+// month from it on first use
+func (p *BudgetedProvider) SetSpendStore(store SpendStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.store = store
+}
+
+// Embed generates an embedding if the tenant has budget left
+func (p *BudgetedProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	tenantID := budgetTenant(ctx)
+	estimate := Usage{PromptTokens: estimateTokens(text)}
+	held, err := p.reserve(ctx, tenantID, p.embeddingModel, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	embedding, usage, err := embedWithUsage(ctx, p.next, text)
+	p.record(ctx, tenantID, p.embeddingModel, held, usage, err)
+	return embedding, err
+}
+
+// Complete generates a completion if the tenant has budget left, counting
+// the requested maximum completion tokens against it
+func (p *BudgetedProvider) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	tenantID := budgetTenant(ctx)
+	estimate := Usage{
+		PromptTokens:     estimateTokens(req.SystemPrompt) + estimateTokens(req.Prompt),
+		CompletionTokens: req.MaxTokens,
+	}
+	held, err := p.reserve(ctx, tenantID, p.completionModel, estimate)
+	if err != nil {
+		return "", err
+	}
+
+	content, usage, err := completeWithUsage(ctx, p.next, req)
+	p.record(ctx, tenantID, p.completionModel, held, usage, err)
+	return content, err
+}
+
+// Spend returns what tenantID has spent this month
+func (p *BudgetedProvider) Spend(tenantID string) TenantSpend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rollMonth()
+	if usage, ok := p.tenants[tenantID]; ok {
+		return usage.spend
+	}
+	return TenantSpend{TenantID: tenantID, Month: p.month}
+}
+
+// SpendReport returns what every tenant has spent this month, highest cost
+// first
+func (p *BudgetedProvider) SpendReport() []TenantSpend {
+	p.mu.Lock()
+	p.rollMonth()
+	report := make([]TenantSpend, 0, len(p.tenants))
+	for _, usage := range p.tenants {
+		report = append(report, usage.spend)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].CostUSD != report[j].CostUSD {
+			return report[i].CostUSD > report[j].CostUSD
+		}
+		return report[i].TenantID < report[j].TenantID
+	})
+	return report
+}
+
+// reserve admits a call expected to consume estimate, counting it against
+// the tenant's rate limit and holding its tokens and cost against the
+// tenant's budgets until it is recorded, or returns ErrBudgetExceeded
+func (p *BudgetedProvider) reserve(ctx context.Context, tenantID, model string, estimate Usage) (reservation, error) {
+	p.load(ctx, tenantID)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := p.usage(tenantID)
+	limits := p.config.limits(tenantID)
+	now := p.now()
+
+	if now.Sub(usage.windowStart) >= time.Minute {
+		usage.windowStart = now
+		usage.windowCount = 0
+	}
+
+	held := reservation{tokens: int64(estimate.Total()), cost: p.config.cost(model, estimate)}
+	spend := usage.spend
+	var err error
+	switch {
+	case limits.RequestsPerMinute > 0 && usage.windowCount >= limits.RequestsPerMinute:
+		err = apperrors.Wrap(apperrors.CodeQuotaExceeded, ErrBudgetExceeded,
+			"tenant %s exceeded its limit of %d LLM requests per minute", tenantID, limits.RequestsPerMinute)
+	case limits.MonthlyTokens > 0 && spend.PromptTokens+spend.CompletionTokens+usage.reservedTokens+held.tokens > limits.MonthlyTokens:
+		err = apperrors.Wrap(apperrors.CodeQuotaExceeded, ErrBudgetExceeded,
+			"tenant %s exceeded its monthly budget of %d LLM tokens", tenantID, limits.MonthlyTokens)
+	case limits.MonthlyCostUSD > 0 && spend.CostUSD+usage.reservedCost+held.cost > limits.MonthlyCostUSD:
+		err = apperrors.Wrap(apperrors.CodeQuotaExceeded, ErrBudgetExceeded,
+			"tenant %s exceeded its monthly LLM budget of $%.2f", tenantID, limits.MonthlyCostUSD)
+	}
+	if err != nil {
+		usage.spend.Rejected++
+		return reservation{}, err
+	}
+
+	usage.windowCount++
+	usage.reservedTokens += held.tokens
+	usage.reservedCost += held.cost
+	return held, nil
+}
+
+// record releases a call's reservation and adds its usage to the tenant's
+// spend. Failed calls are counted as requests but consume no tokens.
+func (p *BudgetedProvider) record(ctx context.Context, tenantID, model string, held reservation, usage Usage, err error) {
+	p.mu.Lock()
+	tenantUsage := p.usage(tenantID)
+	tenantUsage.reservedTokens -= held.tokens
+	tenantUsage.reservedCost -= held.cost
+	// A reservation made before the month rolled over held nothing in the
+	// new month
+	if tenantUsage.reservedTokens < 0 {
+		tenantUsage.reservedTokens = 0
+	}
+	if tenantUsage.reservedCost < 0 {
+		tenantUsage.reservedCost = 0
+	}
+
+	spend := &tenantUsage.spend
+	spend.Requests++
+	if err == nil {
+		spend.PromptTokens += int64(usage.PromptTokens)
+		spend.CompletionTokens += int64(usage.CompletionTokens)
+		spend.CostUSD += p.config.cost(model, usage)
+	}
+	saved, store := *spend, p.store
+	p.mu.Unlock()
+
+	if store != nil {
+		if err := store.Set(ctx, spendKey(saved.Month, tenantID), saved, spendTTL); err != nil {
+			log.Printf("Failed to persist LLM spend of tenant %s: %v", tenantID, err)
+		}
+	}
+}
+
+// load reads the tenant's spend for the month from the store the first time
+// the tenant is seen in the month
+func (p *BudgetedProvider) load(ctx context.Context, tenantID string) {
+	p.mu.Lock()
+	usage := p.usage(tenantID)
+	if usage.loaded || p.store == nil {
+		p.mu.Unlock()
+		return
+	}
+	month, store := p.month, p.store
+	p.mu.Unlock()
+
+	var stored TenantSpend
+	found, err := store.Get(ctx, spendKey(month, tenantID), &stored)
+	if err != nil {
+		// Count from what is held in memory and try again on the next call
+		log.Printf("Failed to read LLM spend of tenant %s: %v", tenantID, err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	usage = p.usage(tenantID)
+	if usage.loaded || p.month != month {
+		return
+	}
+	usage.loaded = true
+	if found {
+		usage.spend.Requests += stored.Requests
+		usage.spend.Rejected += stored.Rejected
+		usage.spend.PromptTokens += stored.PromptTokens
+		usage.spend.CompletionTokens += stored.CompletionTokens
+		usage.spend.CostUSD += stored.CostUSD
+	}
+}
+
+// spendKey is the store key of a tenant's spend in month
+func spendKey(month, tenantID string) string {
+	return "llm-spend:" + month + ":" + tenantID
+}
+
+// usage returns the tenant's usage for the current month. p.mu must be held.
+func (p *BudgetedProvider) usage(tenantID string) *tenantUsage {
+	p.rollMonth()
+	usage, ok := p.tenants[tenantID]
+	if !ok {
+		usage = &tenantUsage{spend: TenantSpend{TenantID: tenantID, Month: p.month}}
+		p.tenants[tenantID] = usage
+	}
+	return usage
+}
+
+// rollMonth starts a new month of spend when the month changes. p.mu must
+// be held.
+func (p *BudgetedProvider) rollMonth() {
+	month := p.now().UTC().Format("2006-01")
+	if month != p.month {
+		p.month = month
+		p.tenants = make(map[string]*tenantUsage)
+	}
+}
+
+// budgetTenant returns the tenant an LLM call in ctx is accounted to
+func budgetTenant(ctx context.Context) string {
+	if tenantCtx, err := tenant.GetTenantContext(ctx); err == nil && tenantCtx.TenantID != "" {
+		return tenantCtx.TenantID
+	}
+	return systemTenant
+}
+
+// pricedModels returns the embedding and completion models calls are priced
+// at, applying the provider defaults
+func pricedModels(config KBConfig) (string, string) {
+	embeddingModel, completionModel := config.EmbeddingModel, config.CompletionModel
+	if embeddingModel == "" {
+		embeddingModel = "text-embedding-ada-002"
+	}
+	if completionModel == "" {
+		completionModel = "gpt-4"
+	}
+	return embeddingModel, completionModel
+}
+
+// isBudgetExceeded reports whether err is a rejected LLM call
+func isBudgetExceeded(err error) bool {
+	return errors.Is(err, ErrBudgetExceeded)
+}
@@ -0,0 +1,187 @@
+package knowledgebase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EmbeddingStore persists embeddings across restarts and replicas.
+// *cache.RedisCache satisfies it.
+type EmbeddingStore interface {
+	Get(ctx context.Context, key string, target interface{}) (bool, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+}
+
+type EmbeddingCacheConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"`
+}
+
+func DefaultEmbeddingCacheConfig() EmbeddingCacheConfig {
+	return EmbeddingCacheConfig{
+		Enabled:    true,
+		TTL:        24 * time.Hour,
+		MaxEntries: 10000,
+	}
+}
+
+type EmbeddingCacheStats struct {
+	Hits           int64 `json:"hits"`
+	Misses         int64 `json:"misses"`
+	PersistentHits int64 `json:"persistent_hits"`
+	Entries        int   `json:"entries"`
+}
+
+type cachedEmbedding struct {
+	embedding []float32
+	expiresAt time.Time
+}
+
+// CachedEmbedder caches embeddings by content hash so repeated queries and
+// unchanged articles don't call the embedding API again. Lookups go to memory
+// first, then to the optional persistent store.
+type CachedEmbedder struct {
+	next      Embedder
+	namespace string
+	config    EmbeddingCacheConfig
+	store     EmbeddingStore
+
+	mu      sync.Mutex
+	entries map[string]cachedEmbedding
+
+	hits           int64
+	misses         int64
+	persistentHits int64
+}
+
+// NewCachedEmbedder wraps next with a cache. Namespace should identify the
+// provider and model so embeddings from different models never mix; store may
+// be nil.
+func NewCachedEmbedder(next Embedder, namespace string, config EmbeddingCacheConfig, store EmbeddingStore) *CachedEmbedder {
+	if config.TTL <= 0 {
+		config.TTL = DefaultEmbeddingCacheConfig().TTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultEmbeddingCacheConfig().MaxEntries
+	}
+	return &CachedEmbedder{
+		next:      next,
+		namespace: namespace,
+		config:    config,
+		store:     store,
+		entries:   make(map[string]cachedEmbedding),
+	}
+}
+
+// Embed returns the cached embedding for text or generates and caches it
+func (c *CachedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	key := c.key(text)
+
+	if embedding, ok := c.getLocal(key); ok {
+		atomic.AddInt64(&c.hits, 1)
+		return embedding, nil
+	}
+
+	if c.store != nil {
+		var embedding []float32
+		found, err := c.store.Get(ctx, key, &embedding)
+		if err != nil {
+			log.Printf("Failed to read cached embedding: %v", err)
+		} else if found {
+			atomic.AddInt64(&c.hits, 1)
+			atomic.AddInt64(&c.persistentHits, 1)
+			c.setLocal(key, embedding)
+			return embedding, nil
+		}
+	}
+
+	atomic.AddInt64(&c.misses, 1)
+
+	embedding, err := c.next.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setLocal(key, embedding)
+	if c.store != nil {
+		if err := c.store.Set(ctx, key, embedding, c.config.TTL); err != nil {
+			log.Printf("Failed to store cached embedding: %v", err)
+		}
+	}
+
+	return embedding, nil
+}
+
+// Stats returns cache hit metrics
+func (c *CachedEmbedder) Stats() EmbeddingCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return EmbeddingCacheStats{
+		Hits:           atomic.LoadInt64(&c.hits),
+		Misses:         atomic.LoadInt64(&c.misses),
+		PersistentHits: atomic.LoadInt64(&c.persistentHits),
+		Entries:        entries,
+	}
+}
+
+func (c *CachedEmbedder) key(text string) string {
+	sum := sha256.Sum256([]byte(c.namespace + "\x00" + text))
+	return "embedding:" + hex.EncodeToString(sum[:])
+}
+
+func (c *CachedEmbedder) getLocal(key string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.embedding, true
+}
+
+func (c *CachedEmbedder) setLocal(key string, embedding []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.config.MaxEntries {
+		c.evictLocked()
+	}
+	c.entries[key] = cachedEmbedding{
+		embedding: embedding,
+		expiresAt: time.Now().Add(c.config.TTL),
+	}
+}
+
+// evictLocked drops expired entries, or the entry closest to expiry if none have
+func (c *CachedEmbedder) evictLocked() {
+	now := time.Now()
+	var oldestKey string
+	var oldest time.Time
+
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, entry.expiresAt
+		}
+	}
+
+	if len(c.entries) >= c.config.MaxEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
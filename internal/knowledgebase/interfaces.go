@@ -20,3 +20,21 @@ type ArticleStore interface {
 	UpdateArticle(ctx context.Context, article *Article) error
 	ListArticles(ctx context.Context, filters map[string]interface{}) ([]*Article, error)
 }
+
+// Embedder turns text into a vector for semantic search
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// LLMProvider generates embeddings and chat completions
+type LLMProvider interface {
+	Embedder
+	Complete(ctx context.Context, req CompletionRequest) (string, error)
+}
+
+type CompletionRequest struct {
+	SystemPrompt string
+	Prompt       string
+	Temperature  float32
+	MaxTokens    int
+}
@@ -0,0 +1,282 @@
+package knowledgebase
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/securizon/internal/tenant"
+)
+
+const localEmbeddingDimensions = 256
+
+// NewLLMProvider returns the provider selected by config.Provider, defaulting
+// to OpenAI. Providers with regional endpoints are routed by data region.
+func NewLLMProvider(config KBConfig) (LLMProvider, error) {
+	if len(config.RegionEndpoints) > 0 && config.Provider != "local" {
+		return NewRegionalProvider(config)
+	}
+	return newLLMProvider(config)
+}
+
+func newLLMProvider(config KBConfig) (LLMProvider, error) {
+	switch config.Provider {
+	case "", "openai":
+		return NewOpenAIProvider(config)
+	case "azure":
+		return NewAzureOpenAIProvider(config)
+	case "local":
+		return NewLocalProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", config.Provider)
+	}
+}
+
+// OpenAIProvider calls the OpenAI API, or Azure OpenAI when built with
+// NewAzureOpenAIProvider
+type OpenAIProvider struct {
+	client          *openai.Client
+	embeddingModel  openai.EmbeddingModel
+	completionModel string
+}
+
+// NewOpenAIProvider creates a provider for the public OpenAI API
+func NewOpenAIProvider(config KBConfig) (*OpenAIProvider, error) {
+	if config.OpenAIAPIKey == "" {
+		return nil, fmt.Errorf("openai api key is required")
+	}
+	clientConfig := openai.DefaultConfig(config.OpenAIAPIKey)
+	if config.BaseURL != "" {
+		clientConfig.BaseURL = config.BaseURL
+	}
+	return newOpenAIProvider(clientConfig, config)
+}
+
+// NewAzureOpenAIProvider creates a provider for an Azure OpenAI resource.
+// Models are mapped to deployments through config.AzureDeployments; unmapped
+// models are assumed to be deployed under their own name.
+func NewAzureOpenAIProvider(config KBConfig) (*OpenAIProvider, error) {
+	if config.OpenAIAPIKey == "" || config.AzureEndpoint == "" {
+		return nil, fmt.Errorf("azure openai requires an api key and endpoint")
+	}
+
+	clientConfig := openai.DefaultAzureConfig(config.OpenAIAPIKey, config.AzureEndpoint)
+	if config.AzureAPIVersion != "" {
+		clientConfig.APIVersion = config.AzureAPIVersion
+	}
+	defaultMapper := clientConfig.AzureModelMapperFunc
+	clientConfig.AzureModelMapperFunc = func(model string) string {
+		if deployment, ok := config.AzureDeployments[model]; ok {
+			return deployment
+		}
+		return defaultMapper(model)
+	}
+
+	return newOpenAIProvider(clientConfig, config)
+}
+
+func newOpenAIProvider(clientConfig openai.ClientConfig, config KBConfig) (*OpenAIProvider, error) {
+	embeddingModel := openai.AdaEmbeddingV2
+	if config.EmbeddingModel != "" {
+		if err := embeddingModel.UnmarshalText([]byte(config.EmbeddingModel)); err != nil || embeddingModel == openai.Unknown {
+			return nil, fmt.Errorf("unsupported embedding model: %s", config.EmbeddingModel)
+		}
+	}
+
+	completionModel := config.CompletionModel
+	if completionModel == "" {
+		completionModel = openai.GPT4
+	}
+
+	return &OpenAIProvider{
+		client:          openai.NewClientWithConfig(clientConfig),
+		embeddingModel:  embeddingModel,
+		completionModel: completionModel,
+	}, nil
+}
+
+// Embed generates an embedding for text
+func (p *OpenAIProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	embedding, _, err := p.EmbedWithUsage(ctx, text)
+	return embedding, err
+}
+
+// EmbedWithUsage generates an embedding for text and reports the tokens used
+func (p *OpenAIProvider) EmbedWithUsage(ctx context.Context, text string) ([]float32, Usage, error) {
+	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: []string{text},
+		Model: p.embeddingModel,
+	})
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	usage := Usage{PromptTokens: resp.Usage.PromptTokens}
+	if len(resp.Data) == 0 {
+		return nil, usage, fmt.Errorf("no embedding returned")
+	}
+	return resp.Data[0].Embedding, usage, nil
+}
+
+// Complete generates a chat completion
+func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	content, _, err := p.CompleteWithUsage(ctx, req)
+	return content, err
+}
+
+// CompleteWithUsage generates a chat completion and reports the tokens used
+func (p *OpenAIProvider) CompleteWithUsage(ctx context.Context, req CompletionRequest) (string, Usage, error) {
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: p.completionModel,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: req.SystemPrompt,
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: req.Prompt,
+			},
+		},
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	})
+	if err != nil {
+		return "", Usage{}, err
+	}
+	usage := Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}
+	if len(resp.Choices) == 0 {
+		return "", usage, fmt.Errorf("no completion returned")
+	}
+	return resp.Choices[0].Message.Content, usage, nil
+}
+
+// RegionalProvider sends each call to the endpoint of the data region
+// resolved from the context by tenant.RegionFromContext, so prompts and
+// embeddings of a pinned tenant's data never leave its region. Calls outside
+// any region use the configured default endpoint.
+type RegionalProvider struct {
+	defaultProvider LLMProvider
+	regions         map[string]LLMProvider
+}
+
+// NewRegionalProvider creates a provider for the default endpoint and one for
+// each of config.RegionEndpoints
+func NewRegionalProvider(config KBConfig) (*RegionalProvider, error) {
+	defaultProvider, err := newLLMProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &RegionalProvider{
+		defaultProvider: defaultProvider,
+		regions:         make(map[string]LLMProvider, len(config.RegionEndpoints)),
+	}
+	for region, endpoint := range config.RegionEndpoints {
+		regionConfig := config
+		if config.Provider == "azure" {
+			regionConfig.AzureEndpoint = endpoint
+		} else {
+			regionConfig.BaseURL = endpoint
+		}
+
+		provider, err := newLLMProvider(regionConfig)
+		if err != nil {
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		p.regions[region] = provider
+	}
+	return p, nil
+}
+
+// provider returns the provider of the region ctx resolves to
+func (p *RegionalProvider) provider(ctx context.Context) (LLMProvider, error) {
+	region, err := tenant.RegionFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		return p.defaultProvider, nil
+	}
+
+	provider, ok := p.regions[region]
+	if !ok {
+		return nil, tenant.RegionNotServed(region, "LLM")
+	}
+	return provider, nil
+}
+
+// Embed generates an embedding in the resolved region
+func (p *RegionalProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	embedding, _, err := p.EmbedWithUsage(ctx, text)
+	return embedding, err
+}
+
+// EmbedWithUsage generates an embedding in the resolved region and reports
+// the tokens used
+func (p *RegionalProvider) EmbedWithUsage(ctx context.Context, text string) ([]float32, Usage, error) {
+	provider, err := p.provider(ctx)
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	return embedWithUsage(ctx, provider, text)
+}
+
+// Complete generates a chat completion in the resolved region
+func (p *RegionalProvider) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	content, _, err := p.CompleteWithUsage(ctx, req)
+	return content, err
+}
+
+// CompleteWithUsage generates a chat completion in the resolved region and
+// reports the tokens used
+func (p *RegionalProvider) CompleteWithUsage(ctx context.Context, req CompletionRequest) (string, Usage, error) {
+	provider, err := p.provider(ctx)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	return completeWithUsage(ctx, provider, req)
+}
+
+// LocalProvider runs without any external service. Embeddings are hashed
+// bag-of-words vectors, which keeps keyword-level search working offline and
+// in tests; completions are not available.
+type LocalProvider struct{}
+
+// NewLocalProvider creates a local provider
+func NewLocalProvider() *LocalProvider {
+	return &LocalProvider{}
+}
+
+// Embed generates a normalized hashed term-frequency vector for text
+func (p *LocalProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	embedding := make([]float32, localEmbeddingDimensions)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		embedding[h.Sum32()%localEmbeddingDimensions]++
+	}
+
+	var norm float64
+	for _, v := range embedding {
+		norm += float64(v * v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range embedding {
+			embedding[i] *= scale
+		}
+	}
+
+	return embedding, nil
+}
+
+// Complete always fails; answers require a hosted model
+func (p *LocalProvider) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	return "", fmt.Errorf("completions are not supported by the local provider")
+}
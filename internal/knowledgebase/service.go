@@ -9,30 +9,102 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/sashabaranov/go-openai"
 	"github.com/securizon/internal/support"
 )
 
 type KnowledgeBaseService struct {
 	vectorStore  VectorStore
-	openaiClient *openai.Client
+	llm          LLMProvider
+	embedder     Embedder
 	articleStore ArticleStore
+	budget       *BudgetedProvider
 	config       KBConfig
 }
 
-func NewKnowledgeBaseService(vectorStore VectorStore, articleStore ArticleStore, config KBConfig) *KnowledgeBaseService {
+// NewKnowledgeBaseService creates a service using the LLM provider selected in config
+func NewKnowledgeBaseService(vectorStore VectorStore, articleStore ArticleStore, config KBConfig) (*KnowledgeBaseService, error) {
+	llm, err := NewLLMProvider(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LLM provider: %v", err)
+	}
+	return NewKnowledgeBaseServiceWithProvider(vectorStore, articleStore, llm, config), nil
+}
+
+// NewKnowledgeBaseServiceWithProvider creates a service with an explicit LLM provider
+func NewKnowledgeBaseServiceWithProvider(vectorStore VectorStore, articleStore ArticleStore, llm LLMProvider, config KBConfig) *KnowledgeBaseService {
+	// Budgets apply to calls that reach the provider, not to cache hits
+	var budget *BudgetedProvider
+	if config.Budget.Enabled {
+		embeddingModel, completionModel := pricedModels(config)
+		budget = NewBudgetedProvider(llm, config.Budget, embeddingModel, completionModel)
+		llm = budget
+	}
+
+	var embedder Embedder = llm
+	if config.EmbeddingCache.Enabled {
+		embedder = NewCachedEmbedder(llm, config.Provider+":"+config.EmbeddingModel, config.EmbeddingCache, nil)
+	}
+
 	return &KnowledgeBaseService{
 		vectorStore:  vectorStore,
 		articleStore: articleStore,
-		openaiClient: openai.NewClient(config.OpenAIAPIKey),
+		llm:          llm,
+		embedder:     embedder,
+		budget:       budget,
 		config:       config,
 	}
 }
 
+// LLMSpend returns what a tenant has spent on LLM calls this month, or false
+// when budgets are disabled
+func (kbs *KnowledgeBaseService) LLMSpend(tenantID string) (TenantSpend, bool) {
+	if kbs.budget == nil {
+		return TenantSpend{}, false
+	}
+	return kbs.budget.Spend(tenantID), true
+}
+
+// LLMSpendReport returns every tenant's LLM spend this month, or false when
+// budgets are disabled
+func (kbs *KnowledgeBaseService) LLMSpendReport() ([]TenantSpend, bool) {
+	if kbs.budget == nil {
+		return nil, false
+	}
+	return kbs.budget.SpendReport(), true
+}
+
+// SetSpendStore persists tenants' LLM spend in store. It has no effect when
+// budgets are disabled.
+func (kbs *KnowledgeBaseService) SetSpendStore(store SpendStore) {
+	if kbs.budget != nil {
+		kbs.budget.SetSpendStore(store)
+	}
+}
+
+// SetEmbeddingStore backs the embedding cache with a persistent store
+func (kbs *KnowledgeBaseService) SetEmbeddingStore(store EmbeddingStore) {
+	if cached, ok := kbs.embedder.(*CachedEmbedder); ok {
+		cached.store = store
+	}
+}
+
+// EmbeddingCacheStats returns embedding cache metrics, or false when caching is disabled
+func (kbs *KnowledgeBaseService) EmbeddingCacheStats() (EmbeddingCacheStats, bool) {
+	cached, ok := kbs.embedder.(*CachedEmbedder)
+	if !ok {
+		return EmbeddingCacheStats{}, false
+	}
+	return cached.Stats(), true
+}
+
 // Search searches the knowledge base using semantic search
 func (kbs *KnowledgeBaseService) Search(ctx context.Context, query string, filters map[string]interface{}) ([]SearchResult, error) {
 	// Generate embedding for query
 	embedding, err := kbs.generateEmbedding(ctx, query)
+	if isBudgetExceeded(err) {
+		log.Printf("Falling back to keyword search: %v", err)
+		return kbs.keywordSearch(ctx, query, filters)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate embedding: %v", err)
 	}
@@ -165,7 +237,7 @@ func (kbs *KnowledgeBaseService) GenerateAnswer(ctx context.Context, question st
 		contextBuilder.WriteString("\n\n")
 	}
 
-	// Generate answer using OpenAI
+	// Generate answer using the configured LLM
 	prompt := fmt.Sprintf(`You are a SecuRizon support assistant. Use the following context to answer the question.
 
 %s
@@ -175,28 +247,23 @@ Question: %s
 Provide a helpful, accurate answer based on the context. If you're not sure, say so and suggest contacting support.`,
 		contextBuilder.String(), question)
 
-	resp, err := kbs.openaiClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openai.GPT4,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are a helpful SecuRizon support assistant.",
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature: 0.7,
-		MaxTokens:   500,
+	content, err := kbs.llm.Complete(ctx, CompletionRequest{
+		SystemPrompt: "You are a helpful SecuRizon support assistant.",
+		Prompt:       prompt,
+		Temperature:  0.7,
+		MaxTokens:    500,
 	})
 
+	if isBudgetExceeded(err) {
+		log.Printf("Answering with articles only: %v", err)
+		return kbs.articlesAnswer(results), nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate answer: %v", err)
 	}
 
 	answer := &GeneratedAnswer{
-		Answer:     resp.Choices[0].Message.Content,
+		Answer:     content,
 		Sources:    make([]Article, 0, len(results)),
 		Confidence: kbs.calculateConfidence(results),
 	}
@@ -239,15 +306,82 @@ func (kbs *KnowledgeBaseService) TrackHelpfulness(ctx context.Context, articleID
 
 // Helper methods
 
-func (kbs *KnowledgeBaseService) generateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	resp, err := kbs.openaiClient.CreateEmbeddings(ctx, openai.EmbeddingRequest{
-		Input: []string{text},
-		Model: openai.AdaEmbeddingV2,
-	})
+// keywordSearch ranks articles by the share of query words they contain. It
+// stands in for semantic search when embeddings cannot be generated.
+func (kbs *KnowledgeBaseService) keywordSearch(ctx context.Context, query string, filters map[string]interface{}) ([]SearchResult, error) {
+	articles, err := kbs.articleStore.ListArticles(ctx, filters)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list articles: %v", err)
 	}
-	return resp.Data[0].Embedding, nil
+
+	words := strings.Fields(strings.ToLower(query))
+	results := make([]SearchResult, 0)
+	for _, article := range articles {
+		if len(words) == 0 || !kbs.matchesFilters(article, filters) {
+			continue
+		}
+
+		text := strings.ToLower(article.Title + "\n" + article.Content)
+		matched := 0
+		for _, word := range words {
+			if strings.Contains(text, word) {
+				matched++
+			}
+		}
+		if matched == 0 {
+			continue
+		}
+
+		score := float64(matched) / float64(len(words))
+		snippets := kbs.extractSnippets(article.Content, query)
+		results = append(results, SearchResult{
+			Article:   article,
+			Score:     score,
+			Relevance: kbs.determineRelevance(score, len(snippets)),
+			Snippets:  snippets,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if kbs.config.MaxResults > 0 && len(results) > kbs.config.MaxResults {
+		results = results[:kbs.config.MaxResults]
+	}
+
+	return results, nil
+}
+
+// articlesAnswer points to the most relevant articles when no answer can be
+// generated
+func (kbs *KnowledgeBaseService) articlesAnswer(results []SearchResult) *GeneratedAnswer {
+	var answer strings.Builder
+	answer.WriteString("An AI-generated answer is not available right now.")
+	if len(results) > 0 {
+		answer.WriteString(" These articles may help:\n")
+	} else {
+		answer.WriteString(" Please contact support.")
+	}
+
+	sources := make([]Article, 0, 3)
+	for i, result := range results {
+		if i >= 3 {
+			break
+		}
+		answer.WriteString(fmt.Sprintf("- %s\n", result.Article.Title))
+		sources = append(sources, *result.Article)
+	}
+
+	return &GeneratedAnswer{
+		Answer:     answer.String(),
+		Sources:    sources,
+		Confidence: kbs.calculateConfidence(results),
+		Degraded:   true,
+	}
+}
+
+func (kbs *KnowledgeBaseService) generateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return kbs.embedder.Embed(ctx, text)
 }
 
 func (kbs *KnowledgeBaseService) matchesFilters(article *Article, filters map[string]interface{}) bool {
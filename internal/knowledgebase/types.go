@@ -32,11 +32,31 @@ type GeneratedAnswer struct {
 	Answer     string    `json:"answer"`
 	Sources    []Article `json:"sources"`
 	Confidence float64   `json:"confidence"`
+	Degraded   bool      `json:"degraded,omitempty"` // answered without the LLM
 }
 
 type KBConfig struct {
+	Provider            string  `yaml:"provider"` // openai, azure, local
 	OpenAIAPIKey        string  `yaml:"openai_api_key"`
 	EmbeddingModel      string  `yaml:"embedding_model"`
+	CompletionModel     string  `yaml:"completion_model"`
 	SimilarityThreshold float64 `yaml:"similarity_threshold"`
 	MaxResults          int     `yaml:"max_results"`
+
+	EmbeddingCache EmbeddingCacheConfig `yaml:"embedding_cache"`
+
+	// Azure OpenAI settings; the API key is read from OpenAIAPIKey
+	AzureEndpoint    string            `yaml:"azure_endpoint"`
+	AzureAPIVersion  string            `yaml:"azure_api_version"`
+	AzureDeployments map[string]string `yaml:"azure_deployments"` // model -> deployment name
+
+	// BaseURL overrides the OpenAI API endpoint, e.g. for a regional endpoint
+	BaseURL string `yaml:"base_url"`
+	// RegionEndpoints maps data regions to the endpoint serving them: the
+	// base URL for OpenAI or the resource endpoint for Azure. When set,
+	// tenants pinned to a region only ever reach that region's endpoint.
+	RegionEndpoints map[string]string `yaml:"region_endpoints"`
+
+	// Budget limits LLM requests, tokens and cost per tenant
+	Budget BudgetConfig `yaml:"budget"`
 }
@@ -1,25 +1,33 @@
 package policy
 
 import (
+	"log"
 	"regexp"
 	"sync"
 	"time"
 
 	"github.com/securazion/event-processor/internal/models"
+	taxonomy "github.com/securizon/pkg/models"
 )
 
 type PolicyEngine struct {
-	mu       sync.RWMutex
-	policies []Policy
-	enabled  map[string]bool
-	compiled map[string]*CompiledPolicy
+	mu         sync.RWMutex
+	policies   []Policy
+	enabled    map[string]bool
+	compiled   map[string]*CompiledPolicy
+	categories *taxonomy.PolicyCategoryRegistry
+}
+
+type PolicyConfig struct {
+	PolicyPaths []string                  `yaml:"policy_paths"`
+	Categories  []taxonomy.PolicyCategory `yaml:"categories"` // defaults to the built-in taxonomy
 }
 
 type Policy struct {
 	ID          string                 `yaml:"id"`
 	Name        string                 `yaml:"name"`
 	Description string                 `yaml:"description"`
-	Severity    float64                `yaml:"severity"` // 0-10
+	Severity    float64                `yaml:"severity"` // 0-10, defaults to the category severity
 	Category    string                 `yaml:"category"`
 	Provider    string                 `yaml:"provider"` // aws, azure, gcp, all
 	Resource    string                 `yaml:"resource"` // ec2, s3, iam, etc.
@@ -47,10 +55,17 @@ type RemediationStep struct {
 }
 
 func NewEngine(policyConfig PolicyConfig) *PolicyEngine {
+	categories, err := taxonomy.NewPolicyCategoryRegistry(policyConfig.Categories)
+	if err != nil {
+		log.Printf("Invalid policy categories, using defaults: %v", err)
+		categories, _ = taxonomy.NewPolicyCategoryRegistry(nil)
+	}
+	
 	engine := &PolicyEngine{
-		policies: make([]Policy, 0),
-		enabled:  make(map[string]bool),
-		compiled: make(map[string]*CompiledPolicy),
+		policies:   make([]Policy, 0),
+		enabled:    make(map[string]bool),
+		compiled:   make(map[string]*CompiledPolicy),
+		categories: categories,
 	}
 	
 	// Load built-in policies
@@ -61,12 +76,39 @@ func NewEngine(policyConfig PolicyConfig) *PolicyEngine {
 		engine.loadPoliciesFromPath(path)
 	}
 	
+	// Reject policies with unknown categories and apply default severities
+	engine.validateCategories()
+	
 	// Compile all policies
 	engine.compilePolicies()
 	
 	return engine
 }
 
+// Categories returns the known policy categories
+func (pe *PolicyEngine) Categories() []taxonomy.PolicyCategory {
+	return pe.categories.List()
+}
+
+// validateCategories drops policies whose category is not registered and
+// gives policies without a severity their category's default
+func (pe *PolicyEngine) validateCategories() {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	
+	valid := pe.policies[:0]
+	for _, policy := range pe.policies {
+		severity, err := pe.categories.ResolveSeverity(policy.Category, policy.Severity)
+		if err != nil {
+			log.Printf("Skipping policy %s: %v", policy.ID, err)
+			continue
+		}
+		policy.Severity = severity
+		valid = append(valid, policy)
+	}
+	pe.policies = valid
+}
+
 func (pe *PolicyEngine) EvaluateAsset(asset models.Asset) []models.Finding {
 	pe.mu.RLock()
 	defer pe.mu.RUnlock()
@@ -2,10 +2,13 @@ package risk
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -20,6 +23,10 @@ type Engine struct {
 	policyEngine     PolicyEngine
 	cache            *RiskCache
 	metrics          *EngineMetrics
+	levels           *levelMappers
+	scorers          map[models.AssetType]RiskScorer
+	defaultScorer    RiskScorer
+	strategy         ScoringStrategy
 	mu               sync.RWMutex
 }
 
@@ -28,9 +35,19 @@ type GraphStore interface {
 	GetAsset(ctx context.Context, id string) (models.Asset, error)
 	GetAssetFindings(ctx context.Context, assetID string) ([]models.Finding, error)
 	GetAssetRisk(ctx context.Context, assetID string) (models.RiskScore, error)
+	GetAssetRisks(ctx context.Context, assetIDs []string) (map[string]models.RiskScore, error)
 	UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error
+	BulkUpdateAssetRisk(ctx context.Context, risks []models.RiskScore) error
 	ListAssets(ctx context.Context, filter models.AssetFilter) ([]models.Asset, error)
 	GetNeighbors(ctx context.Context, assetID string, direction string, maxDepth int) ([]models.Asset, []models.Relationship, error)
+	GetNeighborsByType(ctx context.Context, assetID string, direction string, maxDepth int, relTypes []models.RelationshipType) ([]models.Asset, error)
+	GetFrontierNeighbors(ctx context.Context, assetIDs []string, direction string, relTypes []models.RelationshipType) ([]models.Asset, error)
+}
+
+// RiskSummaryStore is implemented by graph stores that aggregate the risk
+// summary in the database, using the default risk level thresholds
+type RiskSummaryStore interface {
+	GetRiskSummary(ctx context.Context, filter models.AssetFilter) (*models.RiskSummary, error)
 }
 
 // ThreatIntelProvider interface for threat intelligence
@@ -49,31 +66,70 @@ type PolicyEngine interface {
 // EngineConfig represents risk engine configuration
 type EngineConfig struct {
 	// Risk calculation weights
-	BaseSeverityWeight    float64 `json:"base_severity_weight"`
-	ExposureWeight        float64 `json:"exposure_weight"`
-	EnvironmentWeight     float64 `json:"environment_weight"`
-	ThreatIntelWeight     float64 `json:"threat_intel_weight"`
+	BaseSeverityWeight    float64 `json:"base_severity_weight" yaml:"base_severity_weight"`
+	ExposureWeight        float64 `json:"exposure_weight" yaml:"exposure_weight"`
+	EnvironmentWeight     float64 `json:"environment_weight" yaml:"environment_weight"`
+	ThreatIntelWeight     float64 `json:"threat_intel_weight" yaml:"threat_intel_weight"`
+	// EPSSWeight raises the severity of findings by the exploit probability
+	// of their CVEs; a finding whose CVE is certain to be exploited weighs
+	// 1+EPSSWeight times its severity, so base severity can exceed 10. 0
+	// scores on severity alone.
+	EPSSWeight            float64 `json:"epss_weight" yaml:"epss_weight"`
 	
 	// Risk thresholds
-	CriticalThreshold     float64 `json:"critical_threshold"`
-	HighThreshold         float64 `json:"high_threshold"`
-	MediumThreshold       float64 `json:"medium_threshold"`
+	CriticalThreshold     float64 `json:"critical_threshold" yaml:"critical_threshold"`
+	HighThreshold         float64 `json:"high_threshold" yaml:"high_threshold"`
+	MediumThreshold       float64 `json:"medium_threshold" yaml:"medium_threshold"`
 	
 	// Cache configuration
-	CacheEnabled          bool          `json:"cache_enabled"`
-	CacheTTL              time.Duration `json:"cache_ttl"`
-	CacheSize             int           `json:"cache_size"`
+	CacheEnabled          bool          `json:"cache_enabled" yaml:"cache_enabled"`
+	CacheTTL              time.Duration `json:"cache_ttl" yaml:"cache_ttl"`
+	CacheSize             int           `json:"cache_size" yaml:"cache_size"`
+	// CacheRefreshAhead recalculates a cached score in the background when
+	// it is read within CacheRefreshThreshold (a fraction of CacheTTL) of
+	// expiring, so popular assets are not recalculated on the request path
+	CacheRefreshAhead     bool          `json:"cache_refresh_ahead" yaml:"cache_refresh_ahead"`
+	CacheRefreshThreshold float64       `json:"cache_refresh_threshold" yaml:"cache_refresh_threshold"`
 	
 	// Calculation settings
-	EnablePropagation     bool          `json:"enable_propagation"`
-	PropagationDepth      int           `json:"propagation_depth"`
-	DecayFactor           float64       `json:"decay_factor"`
+	EnablePropagation     bool          `json:"enable_propagation" yaml:"enable_propagation"`
+	PropagationDepth      int           `json:"propagation_depth" yaml:"propagation_depth"`
+	DecayFactor           float64       `json:"decay_factor" yaml:"decay_factor"` // applied once per hop
+	// MaxPropagationAssets caps how many assets one propagation visits, so
+	// densely connected graphs cannot make it unbounded; 0 means no cap
+	MaxPropagationAssets  int           `json:"max_propagation_assets" yaml:"max_propagation_assets"`
+	// PropagationRelationships are the relationship types risk propagates
+	// along; empty means every relationship
+	PropagationRelationships []models.RelationshipType `json:"propagation_relationships" yaml:"propagation_relationships"`
+	
+	// Sensitivity weighting scales propagation by what is at stake at the
+	// receiving asset: the per-hop decay is applied 1/weight times. Assets
+	// weighted at or above CrownJewelThreshold are crown jewels, and assets
+	// that can reach one get an extra risk factor, which costs a neighbor
+	// query per calculation. Off by default.
+	SensitivityWeighting  bool                                   `json:"sensitivity_weighting" yaml:"sensitivity_weighting"`
+	DataSensitivityWeights map[models.DataSensitivity]float64    `json:"data_sensitivity_weights" yaml:"data_sensitivity_weights"`
+	PrivilegeWeights      map[models.PrivilegeLevel]float64      `json:"privilege_weights" yaml:"privilege_weights"`
+	CrownJewelThreshold   float64                                `json:"crown_jewel_threshold" yaml:"crown_jewel_threshold"`
+	CrownJewelWeight      float64                                `json:"crown_jewel_weight" yaml:"crown_jewel_weight"`
+	
+	// RiskLevels maps scores to risk levels, and RiskLevelsByType overrides
+	// it for individual asset types
+	RiskLevels            RiskLevelConfig                            `json:"risk_levels" yaml:"risk_levels"`
+	RiskLevelsByType      map[models.AssetType]RiskLevelConfig       `json:"risk_levels_by_type" yaml:"risk_levels_by_type"`
+	
+	// TypeScorers scores identities and data stores with their built-in
+	// type-specific scorers instead of the default formula
+	TypeScorers           bool          `json:"type_scorers" yaml:"type_scorers"`
 	
 	// Performance settings
-	BatchSize             int           `json:"batch_size"`
-	CalculationTimeout    time.Duration `json:"calculation_timeout"`
-	EnableMetrics         bool          `json:"enable_metrics"`
-	MetricsInterval       time.Duration `json:"metrics_interval"`
+	BatchSize             int           `json:"batch_size" yaml:"batch_size"`
+	// MaxConcurrency caps how many assets of a batch are recalculated at
+	// once, each holding graph sessions; CalculationTimeout applies to each
+	MaxConcurrency        int           `json:"max_concurrency" yaml:"max_concurrency"`
+	CalculationTimeout    time.Duration `json:"calculation_timeout" yaml:"calculation_timeout"`
+	EnableMetrics         bool          `json:"enable_metrics" yaml:"enable_metrics"`
+	MetricsInterval       time.Duration `json:"metrics_interval" yaml:"metrics_interval"`
 }
 
 // DefaultEngineConfig returns default engine configuration
@@ -83,6 +139,7 @@ func DefaultEngineConfig() EngineConfig {
 		ExposureWeight:      1.0,
 		EnvironmentWeight:   1.0,
 		ThreatIntelWeight:   1.0,
+		EPSSWeight:          1.0,
 		
 		CriticalThreshold:   80.0,
 		HighThreshold:       60.0,
@@ -91,12 +148,44 @@ func DefaultEngineConfig() EngineConfig {
 		CacheEnabled:        true,
 		CacheTTL:            5 * time.Minute,
 		CacheSize:           10000,
+		CacheRefreshThreshold: 0.1,
 		
 		EnablePropagation:   true,
 		PropagationDepth:    3,
 		DecayFactor:         0.5,
+		MaxPropagationAssets: 1000,
+		PropagationRelationships: []models.RelationshipType{
+			models.RelationshipAssumesRole,
+			models.RelationshipHasAccessTo,
+			models.RelationshipConnectedTo,
+			models.RelationshipRunsOn,
+			models.RelationshipStores,
+			models.RelationshipContains,
+			models.RelationshipDependsOn,
+			models.RelationshipManages,
+		},
+		
+		SensitivityWeighting: false,
+		DataSensitivityWeights: map[models.DataSensitivity]float64{
+			models.DataSensitivityPublic:       0.5,
+			models.DataSensitivityInternal:     1.0,
+			models.DataSensitivityConfidential: 1.5,
+			models.DataSensitivityRestricted:   2.0,
+		},
+		PrivilegeWeights: map[models.PrivilegeLevel]float64{
+			models.PrivilegeLevelLow:    0.75,
+			models.PrivilegeLevelMedium: 1.0,
+			models.PrivilegeLevelHigh:   1.25,
+			models.PrivilegeLevelAdmin:  1.5,
+		},
+		CrownJewelThreshold: 1.5,
+		CrownJewelWeight:    0.5,
+		
+		RiskLevels:          DefaultRiskLevelConfig(),
+		TypeScorers:         true,
 		
 		BatchSize:           100,
+		MaxConcurrency:      10,
 		CalculationTimeout:  30 * time.Second,
 		EnableMetrics:       true,
 		MetricsInterval:     60 * time.Second,
@@ -109,13 +198,21 @@ type RiskCache struct {
 	mu      sync.RWMutex
 	maxSize int
 	ttl     time.Duration
+	
+	// refreshThreshold is the fraction of the TTL left at which a read
+	// triggers a refresh; 0 disables refresh-ahead
+	refreshThreshold float64
+	refreshing       map[string]bool // assets with a refresh in flight
+	version          uint64          // last version given to an entry
 }
 
 // CacheEntry represents a cached risk score
 type CacheEntry struct {
 	RiskScore  models.RiskScore
+	Inputs     string // hash of what the score was calculated from
 	ExpiresAt  time.Time
 	AccessedAt time.Time
+	version    uint64 // changes whenever the entry is replaced
 }
 
 // NewRiskCache creates a new risk cache
@@ -124,6 +221,7 @@ func NewRiskCache(maxSize int, ttl time.Duration) *RiskCache {
 		entries: make(map[string]*CacheEntry),
 		maxSize: maxSize,
 		ttl:     ttl,
+		refreshing: make(map[string]bool),
 	}
 	
 	// Start cleanup goroutine
@@ -132,13 +230,13 @@ func NewRiskCache(maxSize int, ttl time.Duration) *RiskCache {
 	return cache
 }
 
-// Get retrieves a cached risk score
-func (c *RiskCache) Get(assetID string) (models.RiskScore, bool) {
+// Get retrieves a cached risk score calculated from the same inputs
+func (c *RiskCache) Get(assetID, inputs string) (models.RiskScore, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	
 	entry, exists := c.entries[assetID]
-	if !exists || time.Now().After(entry.ExpiresAt) {
+	if !exists || entry.Inputs != inputs || time.Now().After(entry.ExpiresAt) {
 		return models.RiskScore{}, false
 	}
 	
@@ -146,23 +244,87 @@ func (c *RiskCache) Get(assetID string) (models.RiskScore, bool) {
 	return entry.RiskScore, true
 }
 
-// Set stores a risk score in cache
-func (c *RiskCache) Set(assetID string, risk models.RiskScore) {
+// Set stores a risk score calculated from inputs in cache, replacing the
+// asset's previous score
+func (c *RiskCache) Set(assetID, inputs string, risk models.RiskScore) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
 	// Evict if cache is full
-	if len(c.entries) >= c.maxSize {
+	if _, exists := c.entries[assetID]; !exists && len(c.entries) >= c.maxSize {
 		c.evictLRU()
 	}
 	
+	c.version++
 	c.entries[assetID] = &CacheEntry{
 		RiskScore:  risk,
+		Inputs:     inputs,
 		ExpiresAt:  time.Now().Add(c.ttl),
 		AccessedAt: time.Now(),
+		version:    c.version,
 	}
 }
 
+// replace stores a refreshed score only if the entry it was refreshed from
+// is still cached, so a refresh finishing after the asset's score was
+// invalidated or replaced cannot bring back the stale score
+func (c *RiskCache) replace(assetID string, version uint64, risk models.RiskScore) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	entry, exists := c.entries[assetID]
+	if !exists || entry.version != version {
+		return false
+	}
+	
+	c.version++
+	c.entries[assetID] = &CacheEntry{
+		RiskScore:  risk,
+		Inputs:     entry.Inputs,
+		ExpiresAt:  time.Now().Add(c.ttl),
+		AccessedAt: entry.AccessedAt,
+		version:    c.version,
+	}
+	return true
+}
+
+// claimRefresh reports whether a score read from the cache should be
+// refreshed ahead of expiry, and the version of the entry to pass to replace.
+// At most one caller is told to refresh an asset until it calls
+// finishRefresh, so concurrent reads share one refresh.
+func (c *RiskCache) claimRefresh(assetID string) (uint64, bool) {
+	if c.refreshThreshold <= 0 {
+		return 0, false
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	entry, exists := c.entries[assetID]
+	if !exists || c.refreshing[assetID] {
+		return 0, false
+	}
+	if time.Until(entry.ExpiresAt) > time.Duration(float64(c.ttl)*c.refreshThreshold) {
+		return 0, false
+	}
+	c.refreshing[assetID] = true
+	return entry.version, true
+}
+
+// finishRefresh releases the refresh claimed for an asset
+func (c *RiskCache) finishRefresh(assetID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.refreshing, assetID)
+}
+
+// Delete removes an asset's cached score
+func (c *RiskCache) Delete(assetID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, assetID)
+}
+
 // evictLRU evicts the least recently used entry
 func (c *RiskCache) evictLRU() {
 	var oldestKey string
@@ -197,6 +359,43 @@ func (c *RiskCache) cleanup() {
 	}
 }
 
+// unscoredAssetFields are asset fields no scorer reads that change on every
+// collection or scoring run. They are left out of the score inputs so
+// routine refreshes of an asset keep its cached score.
+var unscoredAssetFields = []string{"created_at", "updated_at", "first_seen", "last_seen", "risk_score"}
+
+// scoreInputs hashes what a risk score is calculated from: the asset's
+// scored fields, and the severity and exploitability of its findings and
+// the threats against it, in any order
+func scoreInputs(asset models.Asset, findings []models.Finding, threats []models.ThreatEvent) string {
+	parts := make([]string, 0, len(findings)+len(threats))
+	for _, finding := range findings {
+		parts = append(parts, fmt.Sprintf("f|%s|%s|%g|%g|%g",
+			finding.ID, finding.Status, finding.Severity, finding.MaxCVSS(), finding.MaxEPSS()))
+	}
+	for _, threat := range threats {
+		parts = append(parts, fmt.Sprintf("t|%s|%s|%g", threat.ID, threat.ThreatID, threat.Confidence))
+	}
+	sort.Strings(parts)
+	
+	h := sha256.New()
+	var fields map[string]interface{}
+	if data, err := json.Marshal(asset); err == nil && json.Unmarshal(data, &fields) == nil {
+		for _, field := range unscoredAssetFields {
+			delete(fields, field)
+		}
+		// Map keys are marshaled in sorted order, so equal fields hash equally
+		if data, err := json.Marshal(fields); err == nil {
+			h.Write(data)
+		}
+	}
+	for _, part := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // EngineMetrics represents risk engine metrics
 type EngineMetrics struct {
 	CalculationsPerformed int64     `json:"calculations_performed"`
@@ -210,13 +409,17 @@ type EngineMetrics struct {
 	mu                   sync.RWMutex
 }
 
-// NewEngine creates a new risk engine
-func NewEngine(config EngineConfig, graphStore GraphStore, threatIntel ThreatIntelProvider, policyEngine PolicyEngine) *Engine {
+// NewEngine creates a new risk engine. Risk is scored with strategy, or with
+// the DefaultStrategy when it is nil.
+func NewEngine(config EngineConfig, graphStore GraphStore, threatIntel ThreatIntelProvider, policyEngine PolicyEngine, strategy ScoringStrategy) *Engine {
 	engine := &Engine{
 		config:      config,
 		graphStore:  graphStore,
 		threatIntel: threatIntel,
 		policyEngine: policyEngine,
+		levels:      newLevelMappers(config),
+		scorers:     make(map[models.AssetType]RiskScorer),
+		defaultScorer: NewDefaultScorer(config),
 		metrics: &EngineMetrics{
 			RiskDistribution: make(map[models.RiskLevel]int64),
 			CalculationErrors: make(map[string]int64),
@@ -225,6 +428,19 @@ func NewEngine(config EngineConfig, graphStore GraphStore, threatIntel ThreatInt
 	
 	if config.CacheEnabled {
 		engine.cache = NewRiskCache(config.CacheSize, config.CacheTTL)
+		if config.CacheRefreshAhead {
+			engine.cache.refreshThreshold = config.CacheRefreshThreshold
+		}
+	}
+	
+	engine.strategy = strategy
+	if engine.strategy == nil {
+		engine.strategy = &DefaultStrategy{engine: engine}
+	}
+	
+	if config.TypeScorers {
+		engine.scorers[models.AssetTypeIdentity] = NewIdentityScorer(config)
+		engine.scorers[models.AssetTypeData] = NewDataScorer(config)
 	}
 	
 	return engine
@@ -233,17 +449,24 @@ func NewEngine(config EngineConfig, graphStore GraphStore, threatIntel ThreatInt
 // CalculateRisk calculates risk score for an asset
 func (e *Engine) CalculateRisk(ctx context.Context, asset models.Asset, findings []models.Finding, threats []models.ThreatEvent) (models.RiskScore, error) {
 	start := time.Now()
+	var failed error
 	defer func() {
 		duration := time.Since(start)
-		e.updateMetrics(duration, nil)
+		e.updateMetrics(duration, failed)
 	}()
 
-	// Check cache first
+	// Check cache first. Scores are only reused for the same asset state,
+	// findings and threats.
+	var inputs string
 	if e.cache != nil {
-		if cached, found := e.cache.Get(asset.GetID()); found {
+		inputs = scoreInputs(asset, findings, threats)
+		if cached, found := e.cache.Get(asset.GetID(), inputs); found {
 			e.metrics.mu.Lock()
 			e.metrics.CacheHits++
 			e.metrics.mu.Unlock()
+			if version, ok := e.cache.claimRefresh(asset.GetID()); ok {
+				go e.refreshCachedRisk(context.WithoutCancel(ctx), asset, findings, threats, version)
+			}
 			return cached, nil
 		}
 		e.metrics.mu.Lock()
@@ -251,54 +474,69 @@ func (e *Engine) CalculateRisk(ctx context.Context, asset models.Asset, findings
 		e.metrics.mu.Unlock()
 	}
 
-	// Calculate base severity from findings
-	baseSeverity := e.calculateBaseSeverity(findings)
-	
-	// Calculate exposure multiplier
-	exposureMult := e.calculateExposureMultiplier(asset)
-	
-	// Calculate environment multiplier
-	environmentMult := e.calculateEnvironmentMultiplier(asset)
-	
-	// Calculate threat intelligence multiplier
-	threatIntelMult := e.calculateThreatIntelMultiplier(threats)
-	
-	// Apply weights
-	weightedBaseSeverity := baseSeverity * e.config.BaseSeverityWeight
-	weightedExposure := (exposureMult - 1.0) * e.config.ExposureWeight
-	weightedEnvironment := (environmentMult - 1.0) * e.config.EnvironmentWeight
-	weightedThreatIntel := (threatIntelMult - 1.0) * e.config.ThreatIntelWeight
-	
-	// Calculate final risk score
-	riskScore := weightedBaseSeverity * (1.0 + weightedExposure + weightedEnvironment + weightedThreatIntel)
-	
-	// Ensure score is within bounds
-	riskScore = math.Min(100, math.Max(0, riskScore))
-	
-	risk := models.RiskScore{
-		AssetID:         asset.GetID(),
-		Score:           riskScore,
-		BaseSeverity:    baseSeverity,
-		ExposureMult:    exposureMult,
-		EnvironmentMult: environmentMult,
-		ThreatIntelMult: threatIntelMult,
-		LastCalculated:  time.Now(),
-		Contributors:    e.buildContributors(findings, threats),
+	risk, err := e.strategy.Score(ctx, asset, findings, threats, e.config)
+	if err != nil {
+		failed = err
+		return models.RiskScore{}, fmt.Errorf("failed to score asset %s: %w", asset.GetID(), err)
 	}
+	e.completeScore(asset, &risk)
 	
 	// Cache the result
 	if e.cache != nil {
-		e.cache.Set(asset.GetID(), risk)
+		e.cache.Set(asset.GetID(), inputs, risk)
 	}
 	
 	// Update risk distribution
-	e.updateRiskDistribution(risk.Score)
+	e.updateRiskDistribution(risk.Level)
 	
 	return risk, nil
 }
 
+// refreshCachedRisk recalculates a cached score before it expires and
+// replaces it, keeping the cached score if the recalculation fails. The
+// result is dropped if the entry was invalidated or replaced meanwhile.
+func (e *Engine) refreshCachedRisk(ctx context.Context, asset models.Asset, findings []models.Finding, threats []models.ThreatEvent, version uint64) {
+	defer e.cache.finishRefresh(asset.GetID())
+	
+	if e.config.CalculationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.CalculationTimeout)
+		defer cancel()
+	}
+	
+	start := time.Now()
+	risk, err := e.strategy.Score(ctx, asset, findings, threats, e.config)
+	e.updateMetrics(time.Since(start), err)
+	if err != nil {
+		log.Printf("Failed to refresh cached risk for asset %s: %v", asset.GetID(), err)
+		return
+	}
+	e.completeScore(asset, &risk)
+	e.cache.replace(asset.GetID(), version, risk)
+}
+
 // RecalculateRisk recalculates risk for an asset
 func (e *Engine) RecalculateRisk(ctx context.Context, assetID string) (models.RiskScore, error) {
+	risk, err := e.computeRisk(ctx, assetID)
+	if err != nil {
+		return models.RiskScore{}, err
+	}
+	
+	// Update in graph store
+	if err := e.graphStore.UpdateAssetRisk(ctx, risk); err != nil {
+		return models.RiskScore{}, fmt.Errorf("failed to update risk for asset %s: %w", assetID, err)
+	}
+	
+	// Propagate risk to connected assets if enabled
+	if e.config.EnablePropagation {
+		go e.propagateRisk(ctx, assetID, risk.Score)
+	}
+	
+	return risk, nil
+}
+
+// computeRisk calculates the current risk of an asset without storing it
+func (e *Engine) computeRisk(ctx context.Context, assetID string) (models.RiskScore, error) {
 	// Get asset
 	asset, err := e.graphStore.GetAsset(ctx, assetID)
 	if err != nil {
@@ -320,46 +558,45 @@ func (e *Engine) RecalculateRisk(ctx context.Context, assetID string) (models.Ri
 		}
 	}
 	
-	// Calculate risk
-	risk, err := e.CalculateRisk(ctx, asset, findings, threats)
-	if err != nil {
-		return models.RiskScore{}, err
-	}
-	
-	// Update in graph store
-	if err := e.graphStore.UpdateAssetRisk(ctx, risk); err != nil {
-		return models.RiskScore{}, fmt.Errorf("failed to update risk for asset %s: %w", assetID, err)
-	}
-	
-	// Propagate risk to connected assets if enabled
-	if e.config.EnablePropagation {
-		go e.propagateRisk(ctx, assetID, risk.Score)
-	}
-	
-	return risk, nil
+	return e.CalculateRisk(ctx, asset, findings, threats)
 }
 
 // UpdateRiskScore updates risk score for an asset
 func (e *Engine) UpdateRiskScore(ctx context.Context, assetID string, score models.RiskScore) error {
+	score.Level = e.RiskLevel(score.AssetType, score.Score)
+	
 	// Update in graph store
 	if err := e.graphStore.UpdateAssetRisk(ctx, score); err != nil {
 		return fmt.Errorf("failed to update risk for asset %s: %w", assetID, err)
 	}
 	
-	// Update cache
+	// The stored score replaces any calculated one
 	if e.cache != nil {
-		e.cache.Set(assetID, score)
+		e.cache.Delete(assetID)
 	}
 	
 	// Update risk distribution
-	e.updateRiskDistribution(score.Score)
+	e.updateRiskDistribution(score.Level)
 	
 	return nil
 }
 
-// BatchRecalculateRisk recalculates risk for multiple assets
-func (e *Engine) BatchRecalculateRisk(ctx context.Context, assetIDs []string) ([]models.RiskScore, error) {
-	results := make([]models.RiskScore, 0, len(assetIDs))
+// BatchRiskResult is the outcome of a batch risk recalculation
+type BatchRiskResult struct {
+	Scores []models.RiskScore `json:"scores"`
+	Errors map[string]string  `json:"errors,omitempty"` // by asset ID, for assets that failed
+}
+
+// BatchRecalculateRisk recalculates risk for multiple assets. Scores are
+// written one batch at a time, and propagated scores are accumulated across
+// all batches and written once at the end. Assets that fail are reported in
+// the result; an error is only returned if scores cannot be written.
+func (e *Engine) BatchRecalculateRisk(ctx context.Context, assetIDs []string) (BatchRiskResult, error) {
+	result := BatchRiskResult{
+		Scores: make([]models.RiskScore, 0, len(assetIDs)),
+		Errors: make(map[string]string),
+	}
+	propagated := make(map[string]models.RiskScore)
 	
 	// Process in batches
 	for i := 0; i < len(assetIDs); i += e.config.BatchSize {
@@ -368,34 +605,80 @@ func (e *Engine) BatchRecalculateRisk(ctx context.Context, assetIDs []string) ([
 			end = len(assetIDs)
 		}
 		
-		batch := assetIDs[i:end]
-		batchResults := make([]models.RiskScore, len(batch))
-		
-		// Process batch in parallel
-		var wg sync.WaitGroup
-		for j, assetID := range batch {
-			wg.Add(1)
-			go func(idx int, id string) {
-				defer wg.Done()
-				risk, err := e.RecalculateRisk(ctx, id)
-				if err != nil {
-					log.Printf("Failed to recalculate risk for asset %s: %v", id, err)
-					return
-				}
-				batchResults[idx] = risk
-			}(j, assetID)
-		}
-		wg.Wait()
+		scores := e.computeBatch(ctx, assetIDs[i:end], result.Errors)
+		if err := e.graphStore.BulkUpdateAssetRisk(ctx, scores); err != nil {
+			return result, fmt.Errorf("failed to update risk for batch: %w", err)
+		}
+		result.Scores = append(result.Scores, scores...)
 		
-		// Filter out empty results
-		for _, result := range batchResults {
-			if result.AssetID != "" {
-				results = append(results, result)
+		if e.config.EnablePropagation {
+			for _, score := range scores {
+				e.collectPropagatedRisk(ctx, score.AssetID, score.Score, propagated)
 			}
 		}
 	}
 	
-	return results, nil
+	// Recalculated scores take precedence over propagated ones
+	for _, score := range result.Scores {
+		delete(propagated, score.AssetID)
+	}
+	
+	if err := e.flushRiskScores(ctx, propagated); err != nil {
+		return result, err
+	}
+	
+	return result, nil
+}
+
+// computeBatch recalculates the risk of a batch of assets on at most
+// MaxConcurrency workers, each asset within CalculationTimeout. Scores are
+// returned in batch order and failures are recorded in errs.
+func (e *Engine) computeBatch(ctx context.Context, batch []string, errs map[string]string) []models.RiskScore {
+	results := make([]models.RiskScore, len(batch))
+	failures := make([]error, len(batch))
+	
+	workers := e.config.MaxConcurrency
+	if workers <= 0 || workers > len(batch) {
+		workers = len(batch)
+	}
+	
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				results[idx], failures[idx] = e.computeRiskWithTimeout(ctx, batch[idx])
+			}
+		}()
+	}
+	for idx := range batch {
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+	
+	scores := make([]models.RiskScore, 0, len(batch))
+	for idx, assetID := range batch {
+		if failures[idx] != nil {
+			log.Printf("Failed to recalculate risk for asset %s: %v", assetID, failures[idx])
+			errs[assetID] = failures[idx].Error()
+			continue
+		}
+		scores = append(scores, results[idx])
+	}
+	return scores
+}
+
+// computeRiskWithTimeout computes an asset's risk within CalculationTimeout
+func (e *Engine) computeRiskWithTimeout(ctx context.Context, assetID string) (models.RiskScore, error) {
+	if e.config.CalculationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.CalculationTimeout)
+		defer cancel()
+	}
+	return e.computeRisk(ctx, assetID)
 }
 
 // propagateRisk propagates risk to connected assets
@@ -404,39 +687,189 @@ func (e *Engine) propagateRisk(ctx context.Context, assetID string, riskScore fl
 		return
 	}
 	
-	// Get neighbors
-	neighbors, _, err := e.graphStore.GetNeighbors(ctx, assetID, "both", e.config.PropagationDepth)
-	if err != nil {
-		log.Printf("Failed to get neighbors for asset %s: %v", assetID, err)
-		return
-	}
+	updates := make(map[string]models.RiskScore)
+	e.collectPropagatedRisk(ctx, assetID, riskScore, updates)
 	
-	// Calculate propagated risk for each neighbor
-	for _, neighbor := range neighbors {
-		// Apply decay factor
-		propagatedRisk := riskScore * e.config.DecayFactor
+	if err := e.flushRiskScores(ctx, updates); err != nil {
+		log.Printf("Failed to update propagated risk from asset %s: %v", assetID, err)
+	}
+}
+
+// collectPropagatedRisk adds the risk propagated from an asset to the
+// assets around it into updates, keeping the highest score per asset. The
+// graph is walked breadth-first from the source, visiting each asset once so
+// cycles cannot feed risk back, and the score decays by DecayFactor per hop.
+func (e *Engine) collectPropagatedRisk(ctx context.Context, assetID string, riskScore float64, updates map[string]models.RiskScore) {
+	visited := map[string]bool{assetID: true}
+	frontier := []string{assetID}
+	for depth := 1; depth <= e.config.PropagationDepth && len(frontier) > 0; depth++ {
+		// Expand the whole level over propagation-relevant relationships in one query
+		neighbors, err := e.graphStore.GetFrontierNeighbors(ctx, frontier, "both", e.config.PropagationRelationships)
+		if err != nil {
+			log.Printf("Failed to get neighbors at depth %d from asset %s: %v", depth, assetID, err)
+			return
+		}
+		
+		frontier = nil
+		var reached []models.Asset
+		var propagated []float64
+		capped := false
+		for _, neighbor := range neighbors {
+			if visited[neighbor.GetID()] {
+				continue
+			}
+			if e.config.MaxPropagationAssets > 0 && len(visited) >= e.config.MaxPropagationAssets {
+				log.Printf("Stopped propagating risk from asset %s after %d assets", assetID, e.config.MaxPropagationAssets)
+				capped = true
+				break
+			}
+			visited[neighbor.GetID()] = true
+			frontier = append(frontier, neighbor.GetID())
+			
+			// Apply decay, weighted by what is at stake at the neighbor
+			reached = append(reached, neighbor)
+			propagated = append(propagated, math.Min(100, riskScore*e.propagationDecay(neighbor, depth)))
+		}
 		
-		// Get current risk
-		currentRisk, err := e.graphStore.GetAssetRisk(ctx, neighbor.GetID())
+		e.mergePropagatedRisk(ctx, reached, propagated, updates)
+		if capped {
+			return
+		}
+	}
+}
+
+// mergePropagatedRisk records the risk propagated to each of a level's
+// assets in updates where it raises the asset's score significantly. The
+// current risk of the assets without a pending update is read in one query.
+func (e *Engine) mergePropagatedRisk(ctx context.Context, assets []models.Asset, propagatedRisk []float64, updates map[string]models.RiskScore) {
+	var ids []string
+	for _, asset := range assets {
+		if _, pending := updates[asset.GetID()]; !pending {
+			ids = append(ids, asset.GetID())
+		}
+	}
+	
+	// Get current risk
+	var current map[string]models.RiskScore
+	if len(ids) > 0 {
+		var err error
+		current, err = e.graphStore.GetAssetRisks(ctx, ids)
 		if err != nil {
-			log.Printf("Failed to get current risk for neighbor %s: %v", neighbor.GetID(), err)
+			log.Printf("Failed to get current risk for %d neighbors: %v", len(ids), err)
+			return
+		}
+	}
+	
+	for i, asset := range assets {
+		if pending, ok := updates[asset.GetID()]; ok {
+			if propagatedRisk[i] > pending.Score {
+				pending.Score = propagatedRisk[i]
+				updates[asset.GetID()] = pending
+			}
 			continue
 		}
 		
-		// Combine risks (take maximum)
-		newRisk := math.Max(currentRisk.Score, propagatedRisk)
+		currentRisk, ok := current[asset.GetID()]
+		if !ok {
+			log.Printf("Failed to get current risk for neighbor %s: asset not found", asset.GetID())
+			continue
+		}
 		
-		// Update if significantly different
+		// Combine risks (take maximum) and update if significantly different
+		newRisk := math.Max(currentRisk.Score, propagatedRisk[i])
 		if math.Abs(newRisk-currentRisk.Score) > 1.0 {
 			updatedRisk := currentRisk
+			updatedRisk.AssetID = asset.GetID()
+			updatedRisk.AssetType = asset.GetType()
 			updatedRisk.Score = newRisk
 			updatedRisk.LastCalculated = time.Now()
-			
-			if err := e.graphStore.UpdateAssetRisk(ctx, updatedRisk); err != nil {
-				log.Printf("Failed to update propagated risk for neighbor %s: %v", neighbor.GetID(), err)
+			updates[asset.GetID()] = updatedRisk
+		}
+	}
+}
+
+// propagationDecay returns the share of risk that reaches an asset depth hops
+// away. Sensitive assets keep more of it and others less, but a weight never
+// cancels the decay: it scales the decay exponent rather than the risk.
+func (e *Engine) propagationDecay(asset models.Asset, depth int) float64 {
+	exponent := float64(depth)
+	if weight := e.sensitivityWeight(asset); weight > 0 {
+		exponent /= weight
+	}
+	return math.Pow(e.config.DecayFactor, exponent)
+}
+
+// sensitivityWeight returns how much risk propagated to an asset is scaled
+// by the sensitivity of its data or the privileges it grants
+func (e *Engine) sensitivityWeight(asset models.Asset) float64 {
+	if !e.config.SensitivityWeighting {
+		return 1.0
+	}
+	
+	switch a := asset.(type) {
+	case *models.Data:
+		if weight, ok := e.config.DataSensitivityWeights[a.DataSensitivity]; ok {
+			return weight
+		}
+	case *models.Identity:
+		if weight, ok := e.config.PrivilegeWeights[a.PrivilegeLevel]; ok {
+			return weight
+		}
+	}
+	return 1.0
+}
+
+// isCrownJewel reports whether an asset is sensitive enough that reaching
+// it is a risk factor of its own
+func (e *Engine) isCrownJewel(asset models.Asset) bool {
+	return e.config.SensitivityWeighting &&
+		e.config.CrownJewelThreshold > 0 &&
+		e.sensitivityWeight(asset) >= e.config.CrownJewelThreshold
+}
+
+// calculateReachabilityMultiplier returns the multiplier for assets that can
+// reach a crown jewel within the propagation depth, and the jewels reached
+func (e *Engine) calculateReachabilityMultiplier(ctx context.Context, asset models.Asset) (float64, []models.Asset) {
+	if !e.config.SensitivityWeighting || e.config.CrownJewelWeight <= 0 || e.config.PropagationDepth <= 0 {
+		return 1.0, nil
+	}
+	
+	neighbors, err := e.graphStore.GetNeighborsByType(ctx, asset.GetID(), "outgoing", e.config.PropagationDepth, e.config.PropagationRelationships)
+	if err != nil {
+		log.Printf("Failed to get neighbors for asset %s: %v", asset.GetID(), err)
+		return 1.0, nil
+	}
+	
+	var jewels []models.Asset
+	for _, neighbor := range neighbors {
+		if neighbor.GetID() != asset.GetID() && e.isCrownJewel(neighbor) {
+			jewels = append(jewels, neighbor)
+		}
+	}
+	if len(jewels) == 0 {
+		return 1.0, nil
+	}
+	return 1.0 + e.config.CrownJewelWeight, jewels
+}
+
+// flushRiskScores writes risk scores in chunks of the configured batch size
+func (e *Engine) flushRiskScores(ctx context.Context, updates map[string]models.RiskScore) error {
+	chunk := make([]models.RiskScore, 0, e.config.BatchSize)
+	for _, update := range updates {
+		update.Level = e.RiskLevel(update.AssetType, update.Score)
+		chunk = append(chunk, update)
+		if len(chunk) >= e.config.BatchSize {
+			if err := e.graphStore.BulkUpdateAssetRisk(ctx, chunk); err != nil {
+				return fmt.Errorf("failed to update propagated risk: %w", err)
 			}
+			chunk = chunk[:0]
 		}
 	}
+	
+	if err := e.graphStore.BulkUpdateAssetRisk(ctx, chunk); err != nil {
+		return fmt.Errorf("failed to update propagated risk: %w", err)
+	}
+	return nil
 }
 
 // calculateBaseSeverity calculates base severity from findings
@@ -449,9 +882,10 @@ func (e *Engine) calculateBaseSeverity(findings []models.Finding) float64 {
 	maxSeverity := 0.0
 	
 	for _, finding := range findings {
-		totalSeverity += finding.Severity
-		if finding.Severity > maxSeverity {
-			maxSeverity = finding.Severity
+		severity := e.findingSeverity(finding)
+		totalSeverity += severity
+		if severity > maxSeverity {
+			maxSeverity = severity
 		}
 	}
 	
@@ -462,10 +896,29 @@ func (e *Engine) calculateBaseSeverity(findings []models.Finding) float64 {
 	return weightedSeverity
 }
 
+// findingSeverity returns a finding's severity, capped at 10, weighted by
+// the exploit probability of its CVEs. Findings without their own severity
+// take the highest CVSS score of their CVEs. The weight is applied after the
+// cap so that it still separates likely exploited critical findings from
+// other critical ones.
+func (e *Engine) findingSeverity(finding models.Finding) float64 {
+	severity := finding.Severity
+	if severity == 0 {
+		severity = finding.MaxCVSS()
+	}
+	return math.Min(10, severity) * (1 + e.config.EPSSWeight*finding.MaxEPSS())
+}
+
 // calculateExposureMultiplier calculates exposure based on asset characteristics
 func (e *Engine) calculateExposureMultiplier(asset models.Asset) float64 {
 	baseMultiplier := 1.0
 	
+	// Assets reachable through the graph from an exposed entry point are
+	// effectively exposed even if they have no public interface themselves
+	if asset.GetBaseAsset().ReachableFromInternet {
+		baseMultiplier *= reachableExposureWeight
+	}
+	
 	switch a := asset.(type) {
 	case *models.Compute:
 		if a.InternetExposed {
@@ -494,9 +947,17 @@ func (e *Engine) calculateExposureMultiplier(asset models.Asset) float64 {
 		}
 	}
 	
-	return math.Min(2.0, baseMultiplier)
+	return math.Min(maxExposureMultiplier, baseMultiplier)
 }
 
+const (
+	// reachableExposureWeight scales the exposure of assets reachable from
+	// the internet
+	reachableExposureWeight = 1.5
+	// maxExposureMultiplier caps the exposure multiplier
+	maxExposureMultiplier = 2.0
+)
+
 // calculateEnvironmentMultiplier calculates environment-based risk
 func (e *Engine) calculateEnvironmentMultiplier(asset models.Asset) float64 {
 	switch asset.GetEnvironment() {
@@ -537,7 +998,7 @@ func (e *Engine) buildContributors(findings []models.Finding, threats []models.T
 			Type:        "finding",
 			ID:          finding.ID,
 			Name:        finding.PolicyID,
-			Impact:      finding.Severity,
+			Impact:      e.findingSeverity(finding),
 			Description: finding.Description,
 		})
 	}
@@ -557,7 +1018,7 @@ func (e *Engine) buildContributors(findings []models.Finding, threats []models.T
 }
 
 // updateRiskDistribution updates risk distribution metrics
-func (e *Engine) updateRiskDistribution(score float64) {
+func (e *Engine) updateRiskDistribution(level models.RiskLevel) {
 	if !e.config.EnableMetrics {
 		return
 	}
@@ -565,7 +1026,6 @@ func (e *Engine) updateRiskDistribution(score float64) {
 	e.metrics.mu.Lock()
 	defer e.metrics.mu.Unlock()
 	
-	level := models.GetRiskLevel(score)
 	e.metrics.RiskDistribution[level]++
 }
 
@@ -594,15 +1054,56 @@ func (e *Engine) updateMetrics(duration time.Duration, err error) {
 	}
 }
 
-// GetMetrics returns current engine metrics
-func (e *Engine) GetMetrics() EngineMetrics {
+// EngineMetricsSnapshot is a copy of the engine metrics taken under their
+// lock. It holds no lock itself, so it can be copied and encoded freely.
+type EngineMetricsSnapshot struct {
+	CalculationsPerformed  int64                      `json:"calculations_performed"`
+	CalculationsFailed     int64                      `json:"calculations_failed"`
+	CacheHits              int64                      `json:"cache_hits"`
+	CacheMisses            int64                      `json:"cache_misses"`
+	AverageCalculationTime time.Duration              `json:"average_calculation_time"`
+	LastCalculation        time.Time                  `json:"last_calculation"`
+	RiskDistribution       map[models.RiskLevel]int64 `json:"risk_distribution"`
+	CalculationErrors      map[string]int64           `json:"calculation_errors"`
+}
+
+// GetMetrics returns a snapshot of the engine metrics that is safe to use
+// while risk is still being calculated
+func (e *Engine) GetMetrics() EngineMetricsSnapshot {
 	e.metrics.mu.RLock()
 	defer e.metrics.mu.RUnlock()
-	return *e.metrics
+
+	snapshot := EngineMetricsSnapshot{
+		CalculationsPerformed:  e.metrics.CalculationsPerformed,
+		CalculationsFailed:     e.metrics.CalculationsFailed,
+		CacheHits:              e.metrics.CacheHits,
+		CacheMisses:            e.metrics.CacheMisses,
+		AverageCalculationTime: e.metrics.AverageCalculationTime,
+		LastCalculation:        e.metrics.LastCalculation,
+		RiskDistribution:       make(map[models.RiskLevel]int64, len(e.metrics.RiskDistribution)),
+		CalculationErrors:      make(map[string]int64, len(e.metrics.CalculationErrors)),
+	}
+	for level, count := range e.metrics.RiskDistribution {
+		snapshot.RiskDistribution[level] = count
+	}
+	for message, count := range e.metrics.CalculationErrors {
+		snapshot.CalculationErrors[message] = count
+	}
+	return snapshot
 }
 
 // GetRiskSummary returns risk summary for all assets
 func (e *Engine) GetRiskSummary(ctx context.Context) (*models.RiskSummary, error) {
+	// Stores that aggregate risk apply the default level thresholds, so they
+	// are only used while the engine maps levels the same way
+	if store, ok := e.graphStore.(RiskSummaryStore); ok && e.usesDefaultLevels() {
+		summary, err := store.GetRiskSummary(ctx, models.AssetFilter{})
+		if err == nil {
+			return summary, nil
+		}
+		log.Printf("Failed to get risk summary from the graph, summarizing per asset: %v", err)
+	}
+	
 	// Get all assets
 	assets, err := e.graphStore.ListAssets(ctx, models.AssetFilter{})
 	if err != nil {
@@ -636,7 +1137,7 @@ func (e *Engine) GetRiskSummary(ctx context.Context) (*models.RiskSummary, error
 		totalRisk += risk.Score
 		
 		// Count by risk level
-		level := models.GetRiskLevel(risk.Score)
+		level := e.RiskLevel(asset.GetType(), risk.Score)
 		summary.RiskDistribution[level]++
 		
 		// Track high-risk assets
@@ -0,0 +1,261 @@
+package risk
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+func TestPropagationDecayNeverCancelled(t *testing.T) {
+	config := DefaultEngineConfig()
+	config.SensitivityWeighting = true
+	e := &Engine{config: config}
+
+	restricted := &models.Data{DataSensitivity: models.DataSensitivityRestricted}
+	public := &models.Data{DataSensitivity: models.DataSensitivityPublic}
+	internal := &models.Data{DataSensitivity: models.DataSensitivityInternal}
+
+	plain := e.propagationDecay(internal, 1)
+	if plain != config.DecayFactor {
+		t.Errorf("decay for an unweighted asset = %v, want %v", plain, config.DecayFactor)
+	}
+	if got := e.propagationDecay(restricted, 1); got <= plain || got >= 1 {
+		t.Errorf("decay for restricted data = %v, want between %v and 1", got, plain)
+	}
+	if got := e.propagationDecay(public, 1); got >= plain {
+		t.Errorf("decay for public data = %v, want below %v", got, plain)
+	}
+	if e.propagationDecay(restricted, 2) >= e.propagationDecay(restricted, 1) {
+		t.Error("decay does not grow with depth")
+	}
+}
+
+func TestEPSSRaisesCriticalFindingSeverity(t *testing.T) {
+	e := &Engine{config: DefaultEngineConfig()}
+
+	quiet := models.Finding{CVEScores: []models.CVEScore{{CVEID: "CVE-2026-0001", CVSS: 9.8, EPSS: 0.01}}}
+	exploited := models.Finding{CVEScores: []models.CVEScore{{CVEID: "CVE-2026-0002", CVSS: 9.8, EPSS: 0.97}}}
+
+	if got, want := e.findingSeverity(quiet), e.findingSeverity(exploited); got >= want {
+		t.Errorf("severity of a rarely exploited critical CVE = %v, want below %v for a likely exploited one", got, want)
+	}
+	if got := e.findingSeverity(models.Finding{Severity: 12}); got != 10 {
+		t.Errorf("severity without CVEs = %v, want it capped at 10", got)
+	}
+}
+
+func TestIdentityScorerKeepsInternetReachability(t *testing.T) {
+	config := DefaultEngineConfig()
+	scorer := NewIdentityScorer(config)
+	e := &Engine{config: config}
+
+	score := func(identity *models.Identity) float64 {
+		got, _ := scorer.Score(RiskFactors{
+			Asset:            identity,
+			BaseSeverity:     5,
+			ExposureMult:     e.calculateExposureMultiplier(identity),
+			EnvironmentMult:  1,
+			ThreatIntelMult:  1,
+			ReachabilityMult: 1,
+		})
+		return got
+	}
+
+	internal := &models.Identity{PrivilegeLevel: models.PrivilegeLevelMedium, IsHuman: true}
+	reachable := &models.Identity{PrivilegeLevel: models.PrivilegeLevelMedium, IsHuman: true}
+	reachable.ReachableFromInternet = true
+	if score(reachable) <= score(internal) {
+		t.Errorf("reachable identity scored %v, want above %v for an internal one", score(reachable), score(internal))
+	}
+
+	// Admin is weighted once, by its privilege weight
+	admin := &models.Identity{PrivilegeLevel: models.PrivilegeLevelAdmin, IsHuman: true}
+	want := 5 * (1 + (config.PrivilegeWeights[models.PrivilegeLevelAdmin]-1)*config.ExposureWeight)
+	if got := score(admin); got != want {
+		t.Errorf("admin identity scored %v, want %v", got, want)
+	}
+}
+
+func TestGetMetricsSnapshotIsIndependent(t *testing.T) {
+	config := DefaultEngineConfig()
+	config.EnableMetrics = true
+	e := &Engine{config: config, metrics: &EngineMetrics{
+		RiskDistribution:  make(map[models.RiskLevel]int64),
+		CalculationErrors: make(map[string]int64),
+	}}
+
+	e.updateRiskDistribution(models.RiskLevelHigh)
+	snapshot := e.GetMetrics()
+	e.updateRiskDistribution(models.RiskLevelHigh)
+
+	if got := snapshot.RiskDistribution[models.RiskLevelHigh]; got != 1 {
+		t.Errorf("snapshot RiskDistribution[high] = %d after a later update, want 1", got)
+	}
+	if got := e.GetMetrics().RiskDistribution[models.RiskLevelHigh]; got != 2 {
+		t.Errorf("RiskDistribution[high] = %d, want 2", got)
+	}
+}
+
+// countingStrategy scores an asset by its number of findings and counts the
+// calculations it performs
+type countingStrategy struct {
+	calls int
+}
+
+func (s *countingStrategy) Score(ctx context.Context, asset models.Asset, findings []models.Finding, threats []models.ThreatEvent, config EngineConfig) (models.RiskScore, error) {
+	s.calls++
+	return models.RiskScore{AssetID: asset.GetID(), Score: float64(10 * len(findings))}, nil
+}
+
+func TestCachedRiskFollowsFindingsNotCollectionTimes(t *testing.T) {
+	config := DefaultEngineConfig()
+	config.CacheEnabled = true
+	config.CacheSize = 10
+	config.CacheTTL = time.Hour
+	config.CacheRefreshAhead = false
+	strategy := &countingStrategy{}
+	e := NewEngine(config, nil, nil, nil, strategy)
+
+	asset := &models.Compute{}
+	asset.ID = "vm-1"
+	asset.LastSeen = time.Now()
+	finding := models.Finding{PolicyID: "open-ssh", Severity: 7, Status: "open"}
+	finding.ID = "finding-1"
+
+	first, err := e.CalculateRisk(context.Background(), asset, []models.Finding{finding}, nil)
+	if err != nil {
+		t.Fatalf("CalculateRisk() error = %v", err)
+	}
+
+	// A collector refresh only moves the asset's timestamps
+	asset.LastSeen = asset.LastSeen.Add(time.Minute)
+	asset.UpdatedAt = asset.LastSeen
+	if _, err := e.CalculateRisk(context.Background(), asset, []models.Finding{finding}, nil); err != nil {
+		t.Fatalf("CalculateRisk() error = %v", err)
+	}
+	if strategy.calls != 1 {
+		t.Errorf("scored %d times after a timestamp-only refresh, want the cached score", strategy.calls)
+	}
+
+	second := finding
+	second.ID = "finding-2"
+	fresh, err := e.CalculateRisk(context.Background(), asset, []models.Finding{finding, second}, nil)
+	if err != nil {
+		t.Fatalf("CalculateRisk() error = %v", err)
+	}
+	if strategy.calls != 2 {
+		t.Errorf("scored %d times after the findings changed, want 2", strategy.calls)
+	}
+	if fresh.Score == first.Score {
+		t.Errorf("score = %v after the findings changed, want a fresh score", fresh.Score)
+	}
+}
+
+// gatedStrategy scores immediately once, then holds every later calculation
+// until released
+type gatedStrategy struct {
+	calls   int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *gatedStrategy) Score(ctx context.Context, asset models.Asset, findings []models.Finding, threats []models.ThreatEvent, config EngineConfig) (models.RiskScore, error) {
+	if atomic.AddInt32(&s.calls, 1) > 1 {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return models.RiskScore{AssetID: asset.GetID(), Score: 40}, nil
+}
+
+// riskWriteStore accepts stored risk scores
+type riskWriteStore struct {
+	GraphStore
+}
+
+func (s *riskWriteStore) UpdateAssetRisk(ctx context.Context, risk models.RiskScore) error {
+	return nil
+}
+
+func TestRefreshDoesNotRestoreInvalidatedScore(t *testing.T) {
+	config := DefaultEngineConfig()
+	config.CacheEnabled = true
+	config.CacheSize = 10
+	config.CacheTTL = time.Hour
+	config.CacheRefreshAhead = true
+	config.CacheRefreshThreshold = 1 // every read refreshes
+	strategy := &gatedStrategy{started: make(chan struct{}), release: make(chan struct{})}
+	e := NewEngine(config, &riskWriteStore{}, nil, nil, strategy)
+
+	asset := &models.Compute{}
+	asset.ID = "vm-1"
+	ctx := context.Background()
+	if _, err := e.CalculateRisk(ctx, asset, nil, nil); err != nil {
+		t.Fatalf("CalculateRisk() error = %v", err)
+	}
+	if _, err := e.CalculateRisk(ctx, asset, nil, nil); err != nil {
+		t.Fatalf("CalculateRisk() error = %v", err)
+	}
+	<-strategy.started
+
+	// The score is replaced while the refresh is still calculating
+	if err := e.UpdateRiskScore(ctx, asset.ID, models.RiskScore{AssetID: asset.ID, Score: 90}); err != nil {
+		t.Fatalf("UpdateRiskScore() error = %v", err)
+	}
+	close(strategy.release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		e.cache.mu.RLock()
+		refreshing := e.cache.refreshing[asset.ID]
+		e.cache.mu.RUnlock()
+		if !refreshing {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refresh did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if cached, found := e.cache.Get(asset.ID, scoreInputs(asset, nil, nil)); found {
+		t.Errorf("refresh restored the invalidated score %v", cached.Score)
+	}
+}
+
+func TestScoreBreakdownReportsSeverityFloors(t *testing.T) {
+	config := DefaultEngineConfig()
+	config.TypeScorers = true
+	config.SensitivityWeighting = false
+	e := NewEngine(config, nil, nil, nil, nil)
+
+	admin := &models.Identity{PrivilegeLevel: models.PrivilegeLevelAdmin, IsHuman: true}
+	admin.ID = "admin"
+	admin.BaseAsset.Type = models.AssetTypeIdentity
+	shared := &models.Data{DataSensitivity: models.DataSensitivityRestricted, ExternalSharing: true, Encryption: true}
+	shared.ID = "bucket"
+	shared.Type = models.AssetTypeData
+
+	tests := []struct {
+		asset models.Asset
+		want  float64
+	}{
+		{admin, privilegeSeverity[models.PrivilegeLevelAdmin]},
+		{shared, 6},
+	}
+	for _, tt := range tests {
+		risk, err := e.strategy.Score(context.Background(), tt.asset, nil, nil, config)
+		if err != nil {
+			t.Fatalf("Score(%s) error = %v", tt.asset.GetID(), err)
+		}
+		if risk.BaseSeverity != tt.want {
+			t.Errorf("base severity of %s = %v, want the floor %v", tt.asset.GetID(), risk.BaseSeverity, tt.want)
+		}
+		if factors := explainFactors(risk); !strings.Contains(factors[0].Summary, "minimum") {
+			t.Errorf("base severity of %s explained as %q, want the floor named", tt.asset.GetID(), factors[0].Summary)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/securizon/pkg/apperrors"
+	"github.com/securizon/pkg/models"
+)
+
+// RiskExplanation explains an asset's stored risk score
+type RiskExplanation struct {
+	Risk    models.RiskScore    `json:"risk"` // contributors ordered by impact, highest first
+	Factors []FactorExplanation `json:"factors"`
+}
+
+// FactorExplanation describes how one factor shaped a risk score
+type FactorExplanation struct {
+	Factor  string  `json:"factor"`
+	Value   float64 `json:"value"`
+	Summary string  `json:"summary"`
+}
+
+// ExplainRisk explains the risk score last stored for an asset from its
+// persisted breakdown, without recalculating it. Factors are empty for
+// scores stored without a breakdown.
+func (e *Engine) ExplainRisk(ctx context.Context, assetID string) (*RiskExplanation, error) {
+	risk, err := e.graphStore.GetAssetRisk(ctx, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk for asset %s: %w", assetID, err)
+	}
+	if risk.LastCalculated.IsZero() {
+		return nil, apperrors.NotFound("asset %s has not been scored", assetID)
+	}
+
+	contributors := make([]models.RiskContributor, len(risk.Contributors))
+	copy(contributors, risk.Contributors)
+	sort.SliceStable(contributors, func(i, j int) bool {
+		return contributors[i].Impact > contributors[j].Impact
+	})
+	risk.Contributors = contributors
+
+	return &RiskExplanation{Risk: risk, Factors: explainFactors(risk)}, nil
+}
+
+// explainFactors summarizes the severity and multipliers of a score. A
+// breakdown always has multipliers of at least 1, so scores without one
+// have none to explain.
+func explainFactors(risk models.RiskScore) []FactorExplanation {
+	if risk.ExposureMult == 0 {
+		return []FactorExplanation{}
+	}
+
+	findings, threats := 0, 0
+	for _, contributor := range risk.Contributors {
+		switch contributor.Type {
+		case "finding":
+			findings++
+		case "threat":
+			threats++
+		}
+	}
+
+	severitySummary := fmt.Sprintf("%d finding(s) give a base severity of %.1f, weighted towards the most severe", findings, risk.BaseSeverity)
+	for _, contributor := range risk.Contributors {
+		if severityFloors[contributor.Type] && contributor.Impact == risk.BaseSeverity {
+			severitySummary = fmt.Sprintf("Base severity raised to a minimum of %.1f: %s", risk.BaseSeverity, contributor.Description)
+			break
+		}
+	}
+
+	factors := []FactorExplanation{
+		{
+			Factor:  "base_severity",
+			Value:   risk.BaseSeverity,
+			Summary: severitySummary,
+		},
+		{
+			Factor:  "exposure",
+			Value:   risk.ExposureMult,
+			Summary: multiplierSummary(risk.ExposureMult, "how reachable the asset is from the internet"),
+		},
+		{
+			Factor:  "environment",
+			Value:   risk.EnvironmentMult,
+			Summary: multiplierSummary(risk.EnvironmentMult, "the environment the asset runs in"),
+		},
+		{
+			Factor:  "threat_intel",
+			Value:   risk.ThreatIntelMult,
+			Summary: multiplierSummary(risk.ThreatIntelMult, fmt.Sprintf("%d active threat(s) against the asset", threats)),
+		},
+	}
+	if risk.ReachabilityMult != 0 {
+		factors = append(factors, FactorExplanation{
+			Factor:  "reachability",
+			Value:   risk.ReachabilityMult,
+			Summary: multiplierSummary(risk.ReachabilityMult, "crown jewels the asset can reach"),
+		})
+	}
+	return factors
+}
+
+func multiplierSummary(multiplier float64, cause string) string {
+	if multiplier <= 1 {
+		return fmt.Sprintf("No increase from %s", cause)
+	}
+	return fmt.Sprintf("Raised %.2fx by %s", multiplier, cause)
+}
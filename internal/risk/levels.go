@@ -0,0 +1,127 @@
+package risk
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/securizon/pkg/models"
+)
+
+// Risk level mappers selectable in configuration
+const (
+	LevelMapperThresholds  = "thresholds"
+	LevelMapperLinear      = "linear"
+	LevelMapperLogarithmic = "logarithmic"
+)
+
+// RiskLevelConfig configures how scores map to risk levels. Thresholds apply
+// to the score after the mapper's curve; linear rescales the score to
+// Scale*score + Offset and logarithmic spreads out low scores.
+type RiskLevelConfig struct {
+	Mapper     string                     `json:"mapper" yaml:"mapper"`
+	Thresholds models.RiskLevelThresholds `json:"thresholds" yaml:"thresholds"`
+	Scale      float64                    `json:"scale" yaml:"scale"`
+	Offset     float64                    `json:"offset" yaml:"offset"`
+}
+
+// DefaultRiskLevelConfig returns the standard threshold mapping
+func DefaultRiskLevelConfig() RiskLevelConfig {
+	return RiskLevelConfig{
+		Mapper:     LevelMapperThresholds,
+		Thresholds: models.DefaultRiskLevelThresholds(),
+	}
+}
+
+// NewRiskLevelMapper builds the mapper described by config
+func NewRiskLevelMapper(config RiskLevelConfig) (models.RiskLevelMapper, error) {
+	thresholds := config.Thresholds
+	if thresholds == (models.RiskLevelThresholds{}) {
+		thresholds = models.DefaultRiskLevelThresholds()
+	}
+	if !(thresholds.Critical > thresholds.High && thresholds.High > thresholds.Medium && thresholds.Medium > thresholds.Low) {
+		return nil, fmt.Errorf("thresholds must be in descending order from critical to low")
+	}
+
+	switch config.Mapper {
+	case "", LevelMapperThresholds:
+		return thresholds, nil
+	case LevelMapperLinear:
+		if config.Scale <= 0 {
+			return nil, fmt.Errorf("linear mapper requires a positive scale")
+		}
+		return models.CalibratedRiskLevels{Curve: models.LinearCurve(config.Scale, config.Offset), Thresholds: thresholds}, nil
+	case LevelMapperLogarithmic:
+		return models.CalibratedRiskLevels{Curve: models.LogarithmicCurve(), Thresholds: thresholds}, nil
+	default:
+		return nil, fmt.Errorf("unknown risk level mapper: %s", config.Mapper)
+	}
+}
+
+// levelMappers holds the configured mapper for each asset type
+type levelMappers struct {
+	defaultMapper models.RiskLevelMapper
+	byType        map[models.AssetType]models.RiskLevelMapper
+}
+
+// newLevelMappers builds the mappers from the engine configuration, falling
+// back to the default thresholds for invalid entries
+func newLevelMappers(config EngineConfig) *levelMappers {
+	mappers := &levelMappers{
+		defaultMapper: models.DefaultRiskLevelThresholds(),
+		byType:        make(map[models.AssetType]models.RiskLevelMapper),
+	}
+
+	if mapper, err := NewRiskLevelMapper(config.RiskLevels); err != nil {
+		log.Printf("Invalid risk level mapping, using default thresholds: %v", err)
+	} else {
+		mappers.defaultMapper = mapper
+	}
+
+	for assetType, levelConfig := range config.RiskLevelsByType {
+		mapper, err := NewRiskLevelMapper(levelConfig)
+		if err != nil {
+			log.Printf("Invalid risk level mapping for %s, using the default mapping: %v", assetType, err)
+			continue
+		}
+		mappers.byType[assetType] = mapper
+	}
+
+	return mappers
+}
+
+// SetRiskLevelMapper replaces the mapper used for assetType, or the default
+// mapper when assetType is empty, e.g. with a custom RiskLevelMapperFunc
+func (e *Engine) SetRiskLevelMapper(assetType models.AssetType, mapper models.RiskLevelMapper) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if assetType == "" {
+		e.levels.defaultMapper = mapper
+		return
+	}
+	e.levels.byType[assetType] = mapper
+}
+
+// RiskLevel maps a score of an asset of assetType to its risk level
+func (e *Engine) RiskLevel(assetType models.AssetType, score float64) models.RiskLevel {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if mapper, ok := e.levels.byType[assetType]; ok {
+		return mapper.RiskLevel(score)
+	}
+	return e.levels.defaultMapper.RiskLevel(score)
+}
+
+// usesDefaultLevels reports whether every asset type is mapped with the
+// default thresholds
+func (e *Engine) usesDefaultLevels() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if len(e.levels.byType) > 0 {
+		return false
+	}
+	thresholds, ok := e.levels.defaultMapper.(models.RiskLevelThresholds)
+	return ok && thresholds == models.DefaultRiskLevelThresholds()
+}
@@ -0,0 +1,157 @@
+package risk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/securizon/pkg/models"
+)
+
+// edgeStore is a GraphStore over an in-memory set of typed edges
+type edgeStore struct {
+	GraphStore
+	edges     map[string]map[string]models.RelationshipType
+	queries   int
+	riskReads int
+}
+
+func (s *edgeStore) link(from, to string, relType models.RelationshipType) {
+	if s.edges == nil {
+		s.edges = make(map[string]map[string]models.RelationshipType)
+	}
+	for _, pair := range [][2]string{{from, to}, {to, from}} {
+		if s.edges[pair[0]] == nil {
+			s.edges[pair[0]] = make(map[string]models.RelationshipType)
+		}
+		s.edges[pair[0]][pair[1]] = relType
+	}
+}
+
+func (s *edgeStore) GetNeighborsByType(ctx context.Context, assetID string, direction string, maxDepth int, relTypes []models.RelationshipType) ([]models.Asset, error) {
+	var neighbors []models.Asset
+	for id, relType := range s.edges[assetID] {
+		allowed := len(relTypes) == 0
+		for _, t := range relTypes {
+			allowed = allowed || t == relType
+		}
+		if allowed {
+			neighbors = append(neighbors, &models.Compute{BaseAsset: models.BaseAsset{ID: id, Type: models.AssetTypeCompute}})
+		}
+	}
+	return neighbors, nil
+}
+
+func (s *edgeStore) GetFrontierNeighbors(ctx context.Context, assetIDs []string, direction string, relTypes []models.RelationshipType) ([]models.Asset, error) {
+	s.queries++
+	start := make(map[string]bool, len(assetIDs))
+	for _, id := range assetIDs {
+		start[id] = true
+	}
+	seen := make(map[string]bool)
+	var neighbors []models.Asset
+	for _, id := range assetIDs {
+		found, _ := s.GetNeighborsByType(ctx, id, direction, 1, relTypes)
+		for _, neighbor := range found {
+			if !start[neighbor.GetID()] && !seen[neighbor.GetID()] {
+				seen[neighbor.GetID()] = true
+				neighbors = append(neighbors, neighbor)
+			}
+		}
+	}
+	return neighbors, nil
+}
+
+func (s *edgeStore) GetAssetRisks(ctx context.Context, assetIDs []string) (map[string]models.RiskScore, error) {
+	s.riskReads++
+	risks := make(map[string]models.RiskScore, len(assetIDs))
+	for _, id := range assetIDs {
+		risks[id] = models.RiskScore{AssetID: id}
+	}
+	return risks, nil
+}
+
+func TestPropagationStopsAtDisallowedRelationships(t *testing.T) {
+	store := &edgeStore{}
+	store.link("source", "host", models.RelationshipRunsOn)
+	store.link("host", "bucket", models.RelationshipStores)
+	store.link("source", "lookalike", "SIMILAR_TO")
+	store.link("lookalike", "beyond", models.RelationshipRunsOn)
+
+	config := DefaultEngineConfig()
+	config.PropagationDepth = 3
+	config.PropagationRelationships = []models.RelationshipType{models.RelationshipRunsOn, models.RelationshipStores}
+	e := &Engine{config: config, graphStore: store}
+
+	updates := make(map[string]models.RiskScore)
+	e.collectPropagatedRisk(context.Background(), "source", 80, updates)
+
+	for _, id := range []string{"host", "bucket"} {
+		if _, ok := updates[id]; !ok {
+			t.Errorf("risk did not propagate to %s over an allowed relationship", id)
+		}
+	}
+	for _, id := range []string{"lookalike", "beyond"} {
+		if _, ok := updates[id]; ok {
+			t.Errorf("risk propagated to %s over a disallowed relationship", id)
+		}
+	}
+}
+
+func TestPropagationAroundCycleTerminates(t *testing.T) {
+	store := &edgeStore{}
+	store.link("source", "a", models.RelationshipRunsOn)
+	store.link("a", "b", models.RelationshipRunsOn)
+	store.link("b", "source", models.RelationshipRunsOn)
+
+	config := DefaultEngineConfig()
+	config.PropagationDepth = 10
+	config.PropagationRelationships = []models.RelationshipType{models.RelationshipRunsOn}
+	e := &Engine{config: config, graphStore: store}
+
+	updates := make(map[string]models.RiskScore)
+	e.collectPropagatedRisk(context.Background(), "source", 80, updates)
+
+	if _, ok := updates["source"]; ok {
+		t.Error("risk propagated around the cycle back to its source")
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, ok := updates[id]; !ok {
+			t.Errorf("risk did not propagate to %s", id)
+		}
+	}
+	// Both cycle members are one hop from the source, so the walk expands the
+	// source's level and then finds nothing new
+	if store.queries != 2 {
+		t.Errorf("propagation ran %d neighbor queries, want one per level (2)", store.queries)
+	}
+}
+
+func TestPropagationReadsRiskOncePerLevelWithinCap(t *testing.T) {
+	store := &edgeStore{}
+	for _, id := range []string{"a", "b", "c"} {
+		store.link("source", id, models.RelationshipRunsOn)
+		store.link(id, id+"-child", models.RelationshipRunsOn)
+	}
+
+	config := DefaultEngineConfig()
+	config.PropagationDepth = 2
+	config.PropagationRelationships = []models.RelationshipType{models.RelationshipRunsOn}
+	e := &Engine{config: config, graphStore: store}
+
+	updates := make(map[string]models.RiskScore)
+	e.collectPropagatedRisk(context.Background(), "source", 80, updates)
+	if len(updates) != 6 {
+		t.Errorf("risk propagated to %d assets, want 6", len(updates))
+	}
+	if store.riskReads != 2 {
+		t.Errorf("propagation read current risk %d times, want once per level (2)", store.riskReads)
+	}
+
+	// The cap counts the source among the assets visited
+	e.config.MaxPropagationAssets = 3
+	updates = make(map[string]models.RiskScore)
+	e.collectPropagatedRisk(context.Background(), "source", 80, updates)
+	if len(updates) != 2 {
+		t.Errorf("risk propagated to %d assets with a cap of 3 assets, want 2", len(updates))
+	}
+}
@@ -0,0 +1,241 @@
+package risk
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// ScheduleConfig represents scheduled risk recalculation configuration
+type ScheduleConfig struct {
+	// Cadences is how often assets in each environment are recalculated.
+	// Environments without a cadence use DefaultCadence.
+	Cadences       map[models.Environment]time.Duration `json:"cadences" yaml:"cadences"`
+	DefaultCadence time.Duration                        `json:"default_cadence" yaml:"default_cadence"`
+	// CheckInterval is how often the scheduler looks for environments that are due
+	CheckInterval time.Duration `json:"check_interval" yaml:"check_interval"`
+	// MaxAssetsPerCycle caps the assets recalculated per environment and
+	// cycle; high-risk assets are recalculated first and the remaining
+	// assets take turns across cycles. 0 means no limit.
+	MaxAssetsPerCycle int `json:"max_assets_per_cycle" yaml:"max_assets_per_cycle"`
+}
+
+// DefaultScheduleConfig returns default schedule configuration
+func DefaultScheduleConfig() ScheduleConfig {
+	return ScheduleConfig{
+		Cadences: map[models.Environment]time.Duration{
+			models.EnvironmentProduction:  15 * time.Minute,
+			models.EnvironmentStaging:     time.Hour,
+			models.EnvironmentTesting:     6 * time.Hour,
+			models.EnvironmentDevelopment: 24 * time.Hour,
+		},
+		DefaultCadence: 6 * time.Hour,
+		CheckInterval:  time.Minute,
+	}
+}
+
+// schedulePageSize is the number of assets listed per query
+const schedulePageSize = 500
+
+// otherEnvironments keys the scheduling state of assets whose environment is
+// neither known nor configured; they are recalculated on DefaultCadence
+const otherEnvironments models.Environment = ""
+
+// Scheduler periodically recalculates risk with a per-environment cadence
+type Scheduler struct {
+	engine  *Engine
+	config  ScheduleConfig
+	lastRun map[models.Environment]time.Time
+	// cursor is where the next capped cycle of an environment resumes
+	cursor map[models.Environment]int
+}
+
+// NewScheduler creates a new risk recalculation scheduler
+func NewScheduler(engine *Engine, config ScheduleConfig) *Scheduler {
+	return &Scheduler{
+		engine:  engine,
+		config:  config,
+		lastRun: make(map[models.Environment]time.Time),
+		cursor:  make(map[models.Environment]int),
+	}
+}
+
+// Run recalculates due environments every check interval until the context
+// is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.config.CheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runDue(ctx, now)
+		}
+	}
+}
+
+// runDue recalculates every environment whose cadence has elapsed, then
+// the assets of unlisted environments
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	environments := s.environments()
+	listed := make(map[models.Environment]bool, len(environments))
+	for _, env := range environments {
+		listed[env] = true
+	}
+
+	for _, env := range append(environments, otherEnvironments) {
+		cadence := s.cadence(env)
+		if cadence <= 0 || now.Sub(s.lastRun[env]) < cadence {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		filter := models.AssetFilter{Environments: []models.Environment{env}}
+		include := func(models.Asset) bool { return true }
+		if env == otherEnvironments {
+			filter.Environments = nil
+			include = func(asset models.Asset) bool { return !listed[asset.GetEnvironment()] }
+		}
+
+		count, err := s.recalculateEnvironment(ctx, env, filter, include)
+		if err != nil {
+			log.Printf("Scheduled risk recalculation for %s failed: %v", environmentName(env), err)
+			continue
+		}
+		s.lastRun[env] = now
+
+		if count > 0 {
+			log.Printf("Scheduled risk recalculation updated %d %s assets", count, environmentName(env))
+		}
+	}
+}
+
+// recalculateEnvironment recalculates the included assets matching filter,
+// starting with those already at high risk
+func (s *Scheduler) recalculateEnvironment(ctx context.Context, env models.Environment, filter models.AssetFilter, include func(models.Asset) bool) (int, error) {
+	highRiskFilter := filter
+	highRiskFilter.MinRiskScore = s.engine.config.HighThreshold
+	highRisk, err := s.listAssetIDs(ctx, highRiskFilter, include)
+	if err != nil {
+		return 0, err
+	}
+
+	all, err := s.listAssetIDs(ctx, filter, include)
+	if err != nil {
+		return 0, err
+	}
+
+	assetIDs := s.selectAssets(env, highRisk, all)
+	if len(assetIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := s.engine.BatchRecalculateRisk(ctx, assetIDs)
+	return len(result.Scores), err
+}
+
+// listAssetIDs pages through the assets matching filter and returns the IDs
+// of those included
+func (s *Scheduler) listAssetIDs(ctx context.Context, filter models.AssetFilter, include func(models.Asset) bool) ([]string, error) {
+	var assetIDs []string
+	filter.Limit = schedulePageSize
+	for filter.Offset = 0; ; filter.Offset += schedulePageSize {
+		assets, err := s.engine.graphStore.ListAssets(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, asset := range assets {
+			if include(asset) {
+				assetIDs = append(assetIDs, asset.GetID())
+			}
+		}
+		if len(assets) < schedulePageSize {
+			return assetIDs, nil
+		}
+	}
+}
+
+// selectAssets orders the high-risk assets first and caps the selection at
+// MaxAssetsPerCycle. Under the cap, the remaining assets are taken from
+// where the environment's previous cycle stopped so every asset gets a turn.
+func (s *Scheduler) selectAssets(env models.Environment, highRisk, all []string) []string {
+	seen := make(map[string]bool, len(all))
+	selected := make([]string, 0, len(all))
+	for _, id := range highRisk {
+		if !seen[id] {
+			seen[id] = true
+			selected = append(selected, id)
+		}
+	}
+	rest := make([]string, 0, len(all))
+	for _, id := range all {
+		if !seen[id] {
+			seen[id] = true
+			rest = append(rest, id)
+		}
+	}
+
+	limit := s.config.MaxAssetsPerCycle
+	if limit <= 0 || len(selected)+len(rest) <= limit {
+		return append(selected, rest...)
+	}
+	if len(selected) >= limit {
+		return selected[:limit]
+	}
+
+	start := s.cursor[env] % len(rest)
+	take := limit - len(selected)
+	for i := 0; i < take; i++ {
+		selected = append(selected, rest[(start+i)%len(rest)])
+	}
+	s.cursor[env] = (start + take) % len(rest)
+	return selected
+}
+
+// environments returns the known environments followed by any configured extras
+func (s *Scheduler) environments() []models.Environment {
+	environments := []models.Environment{
+		models.EnvironmentProduction,
+		models.EnvironmentStaging,
+		models.EnvironmentTesting,
+		models.EnvironmentDevelopment,
+	}
+	for env := range s.config.Cadences {
+		known := false
+		for _, e := range environments {
+			if e == env {
+				known = true
+				break
+			}
+		}
+		if !known {
+			environments = append(environments, env)
+		}
+	}
+	return environments
+}
+
+func (s *Scheduler) cadence(env models.Environment) time.Duration {
+	if cadence, ok := s.config.Cadences[env]; ok {
+		return cadence
+	}
+	return s.config.DefaultCadence
+}
+
+// environmentName names an environment in log messages
+func environmentName(env models.Environment) string {
+	if env == otherEnvironments {
+		return "unlisted environments"
+	}
+	return string(env)
+}
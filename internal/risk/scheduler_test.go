@@ -0,0 +1,33 @@
+package risk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/securizon/pkg/models"
+)
+
+func TestSelectAssetsRotatesUnderTheCap(t *testing.T) {
+	s := NewScheduler(nil, ScheduleConfig{MaxAssetsPerCycle: 3})
+	env := models.EnvironmentProduction
+	highRisk := []string{"b"}
+	all := []string{"a", "b", "c", "d", "e"}
+
+	for _, want := range [][]string{
+		{"b", "a", "c"},
+		{"b", "d", "e"},
+		{"b", "a", "c"},
+	} {
+		if got := s.selectAssets(env, highRisk, all); !reflect.DeepEqual(got, want) {
+			t.Errorf("selectAssets = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSelectAssetsWithoutCapSelectsAll(t *testing.T) {
+	s := NewScheduler(nil, ScheduleConfig{})
+	got := s.selectAssets(models.EnvironmentStaging, []string{"c"}, []string{"a", "b", "c"})
+	if want := []string{"c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selectAssets = %v, want %v", got, want)
+	}
+}
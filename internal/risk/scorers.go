@@ -0,0 +1,209 @@
+package risk
+
+import (
+	"math"
+
+	"github.com/securizon/pkg/models"
+)
+
+// RiskFactors are the factors the engine derives for an asset before it is
+// scored. Multipliers are 1 when a factor does not apply.
+type RiskFactors struct {
+	Asset            models.Asset
+	Findings         []models.Finding
+	Threats          []models.ThreatEvent
+	BaseSeverity     float64 // 0-10 from findings, raised by EPSSWeight for likely exploited CVEs
+	ExposureMult     float64
+	EnvironmentMult  float64
+	ThreatIntelMult  float64
+	ReachabilityMult float64 // crown jewel reachability
+}
+
+// RiskScorer turns an asset's risk factors into a 0-100 score. Scorers are
+// registered per asset type, so each type can weigh what matters for it, and
+// may add contributors explaining type-specific adjustments.
+type RiskScorer interface {
+	Score(factors RiskFactors) (float64, []models.RiskContributor)
+}
+
+// RiskScorerFunc adapts a function to RiskScorer
+type RiskScorerFunc func(factors RiskFactors) (float64, []models.RiskContributor)
+
+// Score calls f
+func (f RiskScorerFunc) Score(factors RiskFactors) (float64, []models.RiskContributor) {
+	return f(factors)
+}
+
+// RegisterScorer scores assets of assetType with scorer, replacing any
+// scorer registered for the type
+func (e *Engine) RegisterScorer(assetType models.AssetType, scorer RiskScorer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scorers[assetType] = scorer
+}
+
+// scorer returns the scorer for assetType, or the default scorer
+func (e *Engine) scorer(assetType models.AssetType) RiskScorer {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if scorer, ok := e.scorers[assetType]; ok {
+		return scorer
+	}
+	return e.defaultScorer
+}
+
+// DefaultScorer applies the configured weights to every factor: the weighted
+// base severity scaled by the weighted sum of the multipliers
+type DefaultScorer struct {
+	config EngineConfig
+}
+
+// NewDefaultScorer creates the default scorer
+func NewDefaultScorer(config EngineConfig) *DefaultScorer {
+	return &DefaultScorer{config: config}
+}
+
+// Score scores any asset
+func (s *DefaultScorer) Score(f RiskFactors) (float64, []models.RiskContributor) {
+	weightedExposure := (f.ExposureMult - 1.0) * s.config.ExposureWeight
+	return s.score(f, weightedExposure), nil
+}
+
+// score combines the weighted factors, with exposure weighted by the caller
+func (s *DefaultScorer) score(f RiskFactors, weightedExposure float64) float64 {
+	weightedBaseSeverity := f.BaseSeverity * s.config.BaseSeverityWeight
+	weightedEnvironment := (f.EnvironmentMult - 1.0) * s.config.EnvironmentWeight
+	weightedThreatIntel := (f.ThreatIntelMult - 1.0) * s.config.ThreatIntelWeight
+	weightedReachability := f.ReachabilityMult - 1.0
+
+	return weightedBaseSeverity * (1.0 + weightedExposure + weightedEnvironment + weightedThreatIntel + weightedReachability)
+}
+
+// privilegeSeverity is the inherent severity of an identity at each
+// privilege level, whether or not it has findings
+var privilegeSeverity = map[models.PrivilegeLevel]float64{
+	models.PrivilegeLevelLow:    1,
+	models.PrivilegeLevelMedium: 2,
+	models.PrivilegeLevelHigh:   3.5,
+	models.PrivilegeLevelAdmin:  5,
+}
+
+// severityFloors are the contributor types a scorer adds when the asset sets
+// a minimum on its base severity, with the minimum as the impact
+var severityFloors = map[string]bool{
+	"privilege":     true,
+	"data_handling": true,
+}
+
+// flooredSeverity is baseSeverity raised to the highest severity floor among
+// a scorer's contributors, the base severity the scorer actually used
+func flooredSeverity(baseSeverity float64, contributors []models.RiskContributor) float64 {
+	for _, contributor := range contributors {
+		if severityFloors[contributor.Type] && contributor.Impact > baseSeverity {
+			baseSeverity = contributor.Impact
+		}
+	}
+	return baseSeverity
+}
+
+// IdentityScorer scores identities by what they can do. An identity's
+// privileges set a floor on its severity, and its privilege weight scales
+// its internet exposure in place of the default admin weighting; non-human
+// identities with admin privileges weigh more, as their credentials are
+// long-lived and rarely reviewed.
+type IdentityScorer struct {
+	DefaultScorer
+}
+
+// NewIdentityScorer creates the identity scorer
+func NewIdentityScorer(config EngineConfig) *IdentityScorer {
+	return &IdentityScorer{DefaultScorer{config: config}}
+}
+
+// Score scores an identity, falling back to the default for other assets
+func (s *IdentityScorer) Score(f RiskFactors) (float64, []models.RiskContributor) {
+	identity, ok := f.Asset.(*models.Identity)
+	if !ok {
+		return s.DefaultScorer.Score(f)
+	}
+
+	var contributors []models.RiskContributor
+	if floor := privilegeSeverity[identity.PrivilegeLevel]; floor > f.BaseSeverity {
+		f.BaseSeverity = floor
+		contributors = append(contributors, models.RiskContributor{
+			Type:        "privilege",
+			ID:          identity.GetID(),
+			Name:        string(identity.PrivilegeLevel),
+			Impact:      floor,
+			Description: "Identity privileges set a minimum severity",
+		})
+	}
+
+	privilegeWeight := 1.0
+	if weight, ok := s.config.PrivilegeWeights[identity.PrivilegeLevel]; ok {
+		privilegeWeight = weight
+	}
+	if !identity.IsHuman && identity.PrivilegeLevel == models.PrivilegeLevelAdmin {
+		privilegeWeight += 0.25
+	}
+
+	// The privilege weight replaces the admin weighting of the default
+	// exposure multiplier, so only reachability is kept from it
+	exposure := 1.0
+	if identity.ReachableFromInternet {
+		exposure = reachableExposureWeight
+	}
+	exposure = math.Min(maxExposureMultiplier, exposure*privilegeWeight)
+
+	return s.score(f, (exposure-1.0)*s.config.ExposureWeight), contributors
+}
+
+// DataScorer scores data stores by what they hold and who can read it. Its
+// sensitivity weight scales the score, and external sharing of sensitive
+// data or storing it unencrypted raise the severity.
+type DataScorer struct {
+	DefaultScorer
+}
+
+// NewDataScorer creates the data scorer
+func NewDataScorer(config EngineConfig) *DataScorer {
+	return &DataScorer{DefaultScorer{config: config}}
+}
+
+// Score scores a data store, falling back to the default for other assets
+func (s *DataScorer) Score(f RiskFactors) (float64, []models.RiskContributor) {
+	data, ok := f.Asset.(*models.Data)
+	if !ok {
+		return s.DefaultScorer.Score(f)
+	}
+
+	sensitivity := 1.0
+	if weight, ok := s.config.DataSensitivityWeights[data.DataSensitivity]; ok {
+		sensitivity = weight
+	}
+	sensitive := data.DataSensitivity == models.DataSensitivityConfidential ||
+		data.DataSensitivity == models.DataSensitivityRestricted
+
+	var contributors []models.RiskContributor
+	raise := func(severity float64, name, description string) {
+		if severity > f.BaseSeverity {
+			f.BaseSeverity = severity
+		}
+		contributors = append(contributors, models.RiskContributor{
+			Type:        "data_handling",
+			ID:          data.GetID(),
+			Name:        name,
+			Impact:      severity,
+			Description: description,
+		})
+	}
+	if sensitive && data.ExternalSharing {
+		raise(6, "external_sharing", "Sensitive data is shared externally")
+	}
+	if sensitive && !data.Encryption {
+		raise(4, "unencrypted", "Sensitive data is stored unencrypted")
+	}
+
+	weightedExposure := (f.ExposureMult - 1.0) * s.config.ExposureWeight
+	return math.Min(100, s.score(f, weightedExposure)*sensitivity), contributors
+}
@@ -0,0 +1,90 @@
+package risk
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// ScoringStrategy computes an asset's risk score from its findings and the
+// threats observed against it. The engine caches, propagates and counts the
+// scores a strategy returns, so a strategy only implements the formula.
+// Fields left unset in the returned score, such as the asset ID or level,
+// are filled in by the engine.
+type ScoringStrategy interface {
+	Score(ctx context.Context, asset models.Asset, findings []models.Finding, threats []models.ThreatEvent, config EngineConfig) (models.RiskScore, error)
+}
+
+// DefaultStrategy is the built-in formula: the weighted base severity of the
+// findings scaled by the exposure, environment, threat intelligence and
+// crown jewel reachability multipliers, combined by the scorer registered
+// for the asset type
+type DefaultStrategy struct {
+	engine *Engine
+}
+
+// Score implements ScoringStrategy
+func (s *DefaultStrategy) Score(ctx context.Context, asset models.Asset, findings []models.Finding, threats []models.ThreatEvent, config EngineConfig) (models.RiskScore, error) {
+	e := s.engine
+
+	baseSeverity := e.calculateBaseSeverity(findings)
+	exposureMult := e.calculateExposureMultiplier(asset)
+	environmentMult := e.calculateEnvironmentMultiplier(asset)
+	threatIntelMult := e.calculateThreatIntelMultiplier(threats)
+	reachabilityMult, crownJewels := e.calculateReachabilityMultiplier(ctx, asset)
+
+	// Score with the asset type's scorer
+	riskScore, typeContributors := e.scorer(asset.GetType()).Score(RiskFactors{
+		Asset:            asset,
+		Findings:         findings,
+		Threats:          threats,
+		BaseSeverity:     baseSeverity,
+		ExposureMult:     exposureMult,
+		EnvironmentMult:  environmentMult,
+		ThreatIntelMult:  threatIntelMult,
+		ReachabilityMult: reachabilityMult,
+	})
+
+	risk := models.RiskScore{
+		AssetID:          asset.GetID(),
+		Score:            riskScore,
+		BaseSeverity:     flooredSeverity(baseSeverity, typeContributors),
+		ExposureMult:     exposureMult,
+		EnvironmentMult:  environmentMult,
+		ThreatIntelMult:  threatIntelMult,
+		ReachabilityMult: reachabilityMult,
+		Contributors:     append(e.buildContributors(findings, threats), typeContributors...),
+	}
+
+	for _, jewel := range crownJewels {
+		risk.Contributors = append(risk.Contributors, models.RiskContributor{
+			Type:        "crown_jewel",
+			ID:          jewel.GetID(),
+			Name:        jewel.GetBaseAsset().Name,
+			Impact:      config.CrownJewelWeight * 10, // Scale weight to 0-10
+			Description: "Asset can reach a highly sensitive asset",
+		})
+	}
+
+	return risk, nil
+}
+
+// completeScore fills in what a strategy left unset and keeps the score
+// within bounds, whichever strategy produced it
+func (e *Engine) completeScore(asset models.Asset, risk *models.RiskScore) {
+	risk.Score = math.Min(100, math.Max(0, risk.Score))
+	if risk.AssetID == "" {
+		risk.AssetID = asset.GetID()
+	}
+	if risk.AssetType == "" {
+		risk.AssetType = asset.GetType()
+	}
+	if risk.Level == "" {
+		risk.Level = e.RiskLevel(risk.AssetType, risk.Score)
+	}
+	if risk.LastCalculated.IsZero() {
+		risk.LastCalculated = time.Now()
+	}
+}
@@ -0,0 +1,102 @@
+package sandbox
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxCalls bounds the calls a Recorder keeps; older calls are dropped
+const maxCalls = 1000
+
+// Call is an external call a fake recorded instead of making
+type Call struct {
+	Service   string                 `json:"service"` // billing, notifier, remediation
+	Operation string                 `json:"operation"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+	At        time.Time              `json:"at"`
+}
+
+// Recorder keeps the most recent calls made to sandbox fakes so a demo can
+// show what would have happened
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record logs and keeps a call
+func (r *Recorder) Record(service, operation string, params map[string]interface{}) {
+	log.Printf("Sandbox: skipped %s %s %v", service, operation, params)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.calls) >= maxCalls {
+		r.calls = r.calls[1:]
+	}
+	r.calls = append(r.calls, Call{Service: service, Operation: operation, Params: params, At: time.Now()})
+}
+
+// Calls returns the recorded calls, oldest first, optionally only those of
+// service
+func (r *Recorder) Calls(service string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]Call, 0, len(r.calls))
+	for _, call := range r.calls {
+		if service == "" || call.Service == service {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// UsageReporter records metered usage instead of reporting it to the
+// payment provider. It satisfies billing.UsageReporter.
+type UsageReporter struct {
+	recorder *Recorder
+}
+
+// NewUsageReporter creates a usage reporter recording to recorder
+func NewUsageReporter(recorder *Recorder) *UsageReporter {
+	return &UsageReporter{recorder: recorder}
+}
+
+// ReportUsage records the usage report
+func (u *UsageReporter) ReportUsage(ctx context.Context, subscriptionItemID string, quantity int64, timestamp time.Time) error {
+	u.recorder.Record("billing", "report_usage", map[string]interface{}{
+		"subscription_item": subscriptionItemID,
+		"quantity":          quantity,
+		"timestamp":         timestamp,
+	})
+	return nil
+}
+
+// Notifier records notifications instead of sending them. It has the
+// methods of the approval workflow notifier and the Slack client.
+type Notifier struct {
+	recorder *Recorder
+}
+
+// NewNotifier creates a notifier recording to recorder
+func NewNotifier(recorder *Recorder) *Notifier {
+	return &Notifier{recorder: recorder}
+}
+
+// Notify records a notification
+func (n *Notifier) Notify(ctx context.Context, message string) error {
+	n.recorder.Record("notifier", "notify", map[string]interface{}{"message": message})
+	return nil
+}
+
+// SendMessage records a chat message
+func (n *Notifier) SendMessage(channel, message string) error {
+	n.recorder.Record("notifier", "send_message", map[string]interface{}{"channel": channel, "message": message})
+	return nil
+}
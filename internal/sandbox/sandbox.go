@@ -0,0 +1,216 @@
+// Package sandbox runs the platform against a synthetic estate for demos and
+// evaluation. Collectors publish generated assets instead of calling cloud
+// APIs, while the event processor, graph and risk engine run unchanged.
+// Remediation runners, notifiers and billing are replaced with recording
+// fakes so nothing outside the platform is touched.
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/securizon/internal/events"
+	"github.com/securizon/pkg/models"
+)
+
+// Config toggles sandbox mode and shapes the synthetic estate
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// Accounts are the synthetic accounts collected for each provider
+	Accounts  map[models.Provider][]string `yaml:"accounts"`
+	Workloads int                          `yaml:"workloads"` // per account
+	// Seed makes the estate, and so asset IDs, stable across runs
+	Seed     int64         `yaml:"seed"`
+	Interval time.Duration `yaml:"interval"` // between synthetic collections
+}
+
+// DefaultConfig returns default sandbox configuration, disabled
+func DefaultConfig() Config {
+	return Config{
+		Accounts: map[models.Provider][]string{
+			models.ProviderAWS:   {"sandbox-aws-prod", "sandbox-aws-dev"},
+			models.ProviderAzure: {"sandbox-azure"},
+			models.ProviderGCP:   {"sandbox-gcp"},
+		},
+		Workloads: 10,
+		Seed:      1,
+		Interval:  5 * time.Minute,
+	}
+}
+
+// Estate is the synthetic inventory of one account
+type Estate struct {
+	Assets        []models.Asset
+	Relationships []models.Relationship
+}
+
+// Generator builds synthetic estates and publishes them as collector events
+type Generator struct {
+	config Config
+}
+
+// NewGenerator creates a generator
+func NewGenerator(config Config) *Generator {
+	return &Generator{config: config}
+}
+
+// Generate builds the estate of an account: per workload an internet-facing
+// or internal VM whose role can read a data store, plus one admin role that
+// manages every store. The same account always yields the same estate.
+func (g *Generator) Generate(provider models.Provider, account string) Estate {
+	rng := rand.New(rand.NewSource(g.config.Seed + seedOf(string(provider)+"/"+account)))
+	now := time.Now()
+	var estate Estate
+
+	newBase := func(assetType models.AssetType, env models.Environment, name string) models.BaseAsset {
+		base := models.NewBaseAsset(provider, assetType, env, name)
+		base.ID = fmt.Sprintf("sandbox:%s:%s:%s", provider, account, name)
+		base.Tags = map[string]string{"sandbox": "true", "account": account}
+		return base
+	}
+	relate := func(from, to string, relType models.RelationshipType) {
+		estate.Relationships = append(estate.Relationships, models.Relationship{
+			ID:          fmt.Sprintf("%s-%s->%s", relType, from, to),
+			FromAssetID: from,
+			ToAssetID:   to,
+			Type:        relType,
+			ValidFrom:   now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Strength:    1.0,
+		})
+	}
+
+	admin := &models.Identity{
+		BaseAsset:      newBase(models.AssetTypeIdentity, models.EnvironmentProduction, "admin-role"),
+		Type:           "Role",
+		PrivilegeLevel: models.PrivilegeLevelAdmin,
+	}
+	estate.Assets = append(estate.Assets, admin)
+
+	environments := []models.Environment{models.EnvironmentProduction, models.EnvironmentStaging, models.EnvironmentDevelopment}
+	sensitivities := []models.DataSensitivity{models.DataSensitivityPublic, models.DataSensitivityInternal, models.DataSensitivityConfidential, models.DataSensitivityRestricted}
+	privileges := []models.PrivilegeLevel{models.PrivilegeLevelLow, models.PrivilegeLevelMedium, models.PrivilegeLevelHigh}
+
+	for i := 0; i < g.config.Workloads; i++ {
+		env := environments[rng.Intn(len(environments))]
+		exposed := rng.Intn(3) == 0
+
+		vm := &models.Compute{
+			BaseAsset:       newBase(models.AssetTypeCompute, env, fmt.Sprintf("vm-%d", i)),
+			SubType:         "VM",
+			OS:              "linux",
+			InternetExposed: exposed,
+			PrivateIP:       fmt.Sprintf("10.0.%d.%d", i/250, i%250+4),
+		}
+		if exposed {
+			vm.ExposedPorts = []int{443}
+			vm.PublicIP = fmt.Sprintf("203.0.113.%d", i%250+1)
+		}
+
+		role := &models.Identity{
+			BaseAsset:      newBase(models.AssetTypeIdentity, env, fmt.Sprintf("role-%d", i)),
+			Type:           "Role",
+			PrivilegeLevel: privileges[rng.Intn(len(privileges))],
+		}
+
+		store := &models.Data{
+			BaseAsset:       newBase(models.AssetTypeData, env, fmt.Sprintf("store-%d", i)),
+			SubType:         "Storage",
+			DataSensitivity: sensitivities[rng.Intn(len(sensitivities))],
+			ExternalSharing: rng.Intn(5) == 0,
+			Encryption:      rng.Intn(4) != 0,
+		}
+
+		estate.Assets = append(estate.Assets, vm, role, store)
+		relate(vm.ID, role.ID, models.RelationshipAssumesRole)
+		relate(role.ID, store.ID, models.RelationshipHasAccessTo)
+		relate(admin.ID, store.ID, models.RelationshipManages)
+	}
+
+	return estate
+}
+
+// Publish generates an account's estate and publishes it to bus the way a
+// collector would, as asset and relationship creation events
+func (g *Generator) Publish(ctx context.Context, bus events.EventBus, provider models.Provider, account string) error {
+	estate := g.Generate(provider, account)
+
+	for _, asset := range estate.Assets {
+		event := models.AssetEvent{
+			BaseEvent: models.NewBaseEvent(models.EventTypeAssetCreated, provider, asset.GetEnvironment(), "sandbox", "Synthetic asset collected"),
+			Asset:     asset,
+		}
+		event.AssetID = asset.GetID()
+		if err := publish(ctx, bus, events.TopicAssetUpserts, event.BaseEvent, event); err != nil {
+			return err
+		}
+	}
+
+	for _, rel := range estate.Relationships {
+		event := models.RelationshipEvent{
+			BaseEvent:    models.NewBaseEvent(models.EventTypeRelationshipCreated, provider, models.EnvironmentProduction, "sandbox", "Synthetic relationship collected"),
+			Relationship: rel,
+		}
+		event.AssetID = rel.FromAssetID
+		if err := publish(ctx, bus, events.TopicAssetRelationships, event.BaseEvent, event); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Sandbox collected %d assets and %d relationships for %s account %s",
+		len(estate.Assets), len(estate.Relationships), provider, account)
+	return nil
+}
+
+// Run publishes the estate of every configured account each Interval until
+// ctx is done
+func (g *Generator) Run(ctx context.Context, bus events.EventBus) {
+	log.Printf("Sandbox mode: publishing synthetic assets every %v", g.config.Interval)
+
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		for provider, accounts := range g.config.Accounts {
+			for _, account := range accounts {
+				if err := g.Publish(ctx, bus, provider, account); err != nil {
+					log.Printf("Sandbox collection of %s account %s failed: %v", provider, account, err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish sends event with payload as its raw data, which is how the event
+// processor reads typed events
+func publish(ctx context.Context, bus events.EventBus, topic string, event models.BaseEvent, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sandbox event: %w", err)
+	}
+	event.RawData = data
+	if err := bus.PublishEvent(ctx, topic, event); err != nil {
+		return fmt.Errorf("failed to publish sandbox event: %w", err)
+	}
+	return nil
+}
+
+// seedOf derives a stable seed from s
+func seedOf(s string) int64 {
+	var h int64
+	for _, c := range s {
+		h = h*31 + int64(c)
+	}
+	return h
+}
@@ -12,6 +12,7 @@ type Tenant struct {
     Name               string                 `json:"name"`
     Slug               string                 `json:"slug"`
     Plan               string                 `json:"plan"`
+    Region             string                 `json:"region,omitempty"` // Data residency region; empty if unpinned
     Status             TenantStatus           `json:"status"`
     CreatedAt          time.Time              `json:"created_at"`
     UpdatedAt          time.Time              `json:"updated_at"`
@@ -87,6 +88,7 @@ type TenantContext struct {
     IsolationLevel IsolationLevel
     DatabaseName   string // For dedicated isolation
     KafkaPrefix    string
+    Region         string // Region the tenant's data is pinned to, if any
     RequestID      string
     UserID         string
     UserRole       string
@@ -106,6 +108,7 @@ func NewTenantContext(tenant *Tenant, user *User) *TenantContext {
         IsolationLevel: determineIsolationLevel(tenant.Plan),
         DatabaseName:   getDatabaseName(tenant),
         KafkaPrefix:    fmt.Sprintf("tenant_%s", tenant.Slug),
+        Region:         tenant.Region,
         UserID:         user.ID,
         UserRole:       user.Role,
     }
@@ -0,0 +1,94 @@
+package tenant
+
+import (
+	"context"
+	"sort"
+
+	"github.com/securizon/pkg/apperrors"
+)
+
+// Data residency
+//
+// A tenant with a Region has its data pinned to that region: graph reads and
+// writes, events and LLM calls made on its behalf are routed to the region's
+// endpoints and are rejected, never rerouted, when the region has none.
+// Work that is not done for a tenant, such as consuming a regional Kafka
+// cluster or running maintenance, is scoped to a region with WithRegion, and
+// an operation scoped to one region for a tenant pinned to another is
+// rejected as crossing regions.
+
+// ResidencyConfig maps data regions to the endpoints that hold their data
+type ResidencyConfig struct {
+	// DefaultRegion names the region served by the main graph and event bus
+	// configuration. Unpinned tenants' data is kept there.
+	DefaultRegion string                     `yaml:"default_region"`
+	Regions       map[string]RegionEndpoints `yaml:"regions"`
+}
+
+// RegionEndpoints are the connection endpoints of one region. Credentials
+// and tuning are shared with the default region's configuration.
+type RegionEndpoints struct {
+	Neo4jURI      string   `yaml:"neo4j_uri"`
+	Neo4jDatabase string   `yaml:"neo4j_database"`
+	KafkaBrokers  []string `yaml:"kafka_brokers"`
+}
+
+// DefaultResidencyConfig returns a configuration with no regional endpoints
+func DefaultResidencyConfig() ResidencyConfig {
+	return ResidencyConfig{
+		DefaultRegion: "default",
+	}
+}
+
+// Enabled reports whether any regional endpoints are configured
+func (c ResidencyConfig) Enabled() bool {
+	return len(c.Regions) > 0
+}
+
+// RegionNames returns the default region followed by the configured regions
+// in name order
+func (c ResidencyConfig) RegionNames() []string {
+	names := make([]string, 0, len(c.Regions))
+	for name := range c.Regions {
+		if name != c.DefaultRegion {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{c.DefaultRegion}, names...)
+}
+
+type regionKey struct{}
+
+// WithRegion scopes ctx to a region. An empty region leaves ctx unchanged.
+func WithRegion(ctx context.Context, region string) context.Context {
+	if region == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+// RegionFromContext returns the region an operation must run in: the region
+// of the tenant in ctx if it is pinned, otherwise the region ctx is scoped
+// to. It is empty if neither is set. An operation scoped to a region other
+// than the tenant's is rejected.
+func RegionFromContext(ctx context.Context) (string, error) {
+	scoped, _ := ctx.Value(regionKey{}).(string)
+
+	tenantCtx, err := GetTenantContext(ctx)
+	if err != nil || tenantCtx.Region == "" {
+		return scoped, nil
+	}
+	if scoped != "" && scoped != tenantCtx.Region {
+		return "", apperrors.New(apperrors.CodeForbidden,
+			"tenant %s is pinned to region %s and cannot be accessed from region %s",
+			tenantCtx.TenantID, tenantCtx.Region, scoped)
+	}
+	return tenantCtx.Region, nil
+}
+
+// RegionNotServed is the error for an operation pinned to a region that has
+// no endpoint for it
+func RegionNotServed(region, service string) error {
+	return apperrors.New(apperrors.CodeForbidden, "no %s endpoint serves region %s", service, region)
+}
@@ -0,0 +1,216 @@
+package threatintel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Indicator types
+const (
+	IndicatorIP     = "ip"
+	IndicatorDomain = "domain"
+	IndicatorHash   = "hash" // file or image digest
+)
+
+// Feed formats
+const (
+	FormatJSON = "json"
+	FormatSTIX = "stix"
+)
+
+// Indicator is an observable a feed reports as malicious
+type Indicator struct {
+	Type        string    `json:"type"`
+	Value       string    `json:"value"`
+	Confidence  float64   `json:"confidence"` // 0-1
+	ThreatType  string    `json:"threat_type"`
+	ThreatID    string    `json:"threat_id"`
+	MITRETTP    string    `json:"mitre_ttp,omitempty"`
+	Description string    `json:"description"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Source      string    `json:"-"` // name of the feed
+}
+
+// Feed downloads the indicators a threat intelligence source publishes
+type Feed interface {
+	Name() string
+	Fetch(ctx context.Context) ([]Indicator, error)
+}
+
+// FeedConfig configures one threat intelligence feed
+type FeedConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Format is "json" for {"indicators": [...]} documents of Indicator, or
+	// "stix" for a STIX 2.1 bundle or TAXII 2.1 collection objects response
+	Format string `yaml:"format"`
+	APIKey string `yaml:"api_key"`
+}
+
+// HTTPFeed downloads indicators from a JSON or STIX/TAXII endpoint
+type HTTPFeed struct {
+	config FeedConfig
+	client *http.Client
+}
+
+// NewHTTPFeed creates a feed client
+func NewHTTPFeed(config FeedConfig, timeout time.Duration) *HTTPFeed {
+	return &HTTPFeed{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements Feed
+func (f *HTTPFeed) Name() string {
+	return f.config.Name
+}
+
+// Fetch implements Feed
+func (f *HTTPFeed) Fetch(ctx context.Context) ([]Indicator, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.config.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for feed %s: %w", f.config.Name, err)
+	}
+	if f.config.Format == FormatSTIX {
+		req.Header.Set("Accept", "application/taxii+json;version=2.1")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	if f.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+f.config.APIKey)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feed %s: %w", f.config.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed %s returned status %d", f.config.Name, resp.StatusCode)
+	}
+
+	var indicators []Indicator
+	if f.config.Format == FormatSTIX {
+		indicators, err = decodeSTIX(resp.Body)
+	} else {
+		indicators, err = decodeJSON(resp.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode feed %s: %w", f.config.Name, err)
+	}
+
+	for i := range indicators {
+		indicators[i].Source = f.config.Name
+	}
+	return indicators, nil
+}
+
+func decodeJSON(body io.Reader) ([]Indicator, error) {
+	var doc struct {
+		Indicators []Indicator `json:"indicators"`
+	}
+	if err := json.NewDecoder(body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc.Indicators, nil
+}
+
+// stixObject holds the fields of a STIX 2.1 indicator object that are used
+type stixObject struct {
+	Type               string    `json:"type"`
+	ID                 string    `json:"id"`
+	Name               string    `json:"name"`
+	Description        string    `json:"description"`
+	IndicatorTypes     []string  `json:"indicator_types"`
+	Pattern            string    `json:"pattern"`
+	PatternType        string    `json:"pattern_type"`
+	Confidence         *int      `json:"confidence"` // 0-100
+	ValidFrom          time.Time `json:"valid_from"`
+	ValidUntil         time.Time `json:"valid_until"`
+	Modified           time.Time `json:"modified"`
+	ExternalReferences []struct {
+		SourceName string `json:"source_name"`
+		ExternalID string `json:"external_id"`
+	} `json:"external_references"`
+}
+
+// stixComparison matches the equality comparisons of a STIX pattern on the
+// observables assets are matched by, e.g. [ipv4-addr:value = '203.0.113.7']
+var stixComparison = regexp.MustCompile(`(ipv4-addr|ipv6-addr|domain-name|file):(value|hashes\.'?[A-Za-z0-9-]+'?)\s*=\s*'([^']+)'`)
+
+// stixDefaultConfidence is used for indicators that do not state one
+const stixDefaultConfidence = 0.5
+
+// decodeSTIX reads the indicators of a STIX bundle or TAXII envelope, both
+// of which carry them in "objects". Expired indicators and comparisons on
+// other observables are skipped.
+func decodeSTIX(body io.Reader) ([]Indicator, error) {
+	var doc struct {
+		Objects []stixObject `json:"objects"`
+	}
+	if err := json.NewDecoder(body).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var indicators []Indicator
+	for _, object := range doc.Objects {
+		if object.Type != "indicator" || (object.PatternType != "" && object.PatternType != "stix") {
+			continue
+		}
+		if !object.ValidUntil.IsZero() && object.ValidUntil.Before(now) {
+			continue
+		}
+
+		template := Indicator{
+			Confidence:  stixDefaultConfidence,
+			ThreatType:  "malicious-activity",
+			ThreatID:    object.ID,
+			Description: object.Description,
+			FirstSeen:   object.ValidFrom,
+			LastSeen:    object.Modified,
+		}
+		if object.Confidence != nil {
+			template.Confidence = float64(*object.Confidence) / 100
+		}
+		if len(object.IndicatorTypes) > 0 {
+			template.ThreatType = object.IndicatorTypes[0]
+		}
+		if template.Description == "" {
+			template.Description = object.Name
+		}
+		for _, ref := range object.ExternalReferences {
+			if ref.SourceName == "mitre-attack" {
+				template.MITRETTP = ref.ExternalID
+				break
+			}
+		}
+
+		for _, match := range stixComparison.FindAllStringSubmatch(object.Pattern, -1) {
+			indicator := template
+			indicator.Value = match[3]
+			switch match[1] {
+			case "ipv4-addr", "ipv6-addr":
+				indicator.Type = IndicatorIP
+			case "domain-name":
+				indicator.Type = IndicatorDomain
+			case "file":
+				if !strings.HasPrefix(match[2], "hashes.") {
+					continue
+				}
+				indicator.Type = IndicatorHash
+			}
+			indicators = append(indicators, indicator)
+		}
+	}
+	return indicators, nil
+}
@@ -0,0 +1,349 @@
+package threatintel
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// Config configures the threat intelligence feeds assets are matched against
+type Config struct {
+	Enabled bool         `yaml:"enabled"`
+	Feeds   []FeedConfig `yaml:"feeds"`
+	// RefreshInterval is how often the feeds are downloaded again;
+	// indicators are served from memory in between
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	Timeout         time.Duration `yaml:"timeout"`
+	// MinConfidence drops indicators reported with a lower confidence (0-1)
+	MinConfidence float64 `yaml:"min_confidence"`
+}
+
+// DefaultConfig returns default threat intelligence configuration
+func DefaultConfig() Config {
+	return Config{
+		RefreshInterval: time.Hour,
+		Timeout:         30 * time.Second,
+		MinConfidence:   0.3,
+	}
+}
+
+// assetIndicatorKeys are the asset metadata and tag keys holding public
+// IPs, domains and image digests, in addition to the fields of compute and
+// SaaS assets
+var assetIndicatorKeys = map[string]string{
+	"public_ip":    IndicatorIP,
+	"public_ips":   IndicatorIP,
+	"domain":       IndicatorDomain,
+	"dns_name":     IndicatorDomain,
+	"hostname":     IndicatorDomain,
+	"image_digest": IndicatorHash,
+	"image":        IndicatorHash, // only references pinned by digest
+}
+
+// Provider matches assets and indicators against the indicators of its
+// feeds, which are kept in memory and refreshed by Run. A feed that fails
+// to refresh keeps serving the indicators it last returned.
+type Provider struct {
+	config Config
+	feeds  []Feed
+
+	mu     sync.RWMutex
+	byFeed map[string][]Indicator
+	index  map[string][]Indicator // by indicator type and normalized value
+}
+
+// NewProvider creates a provider for feeds; nil feeds are created from the
+// configured feeds
+func NewProvider(config Config, feeds []Feed) *Provider {
+	if feeds == nil {
+		for _, feed := range config.Feeds {
+			feeds = append(feeds, NewHTTPFeed(feed, config.Timeout))
+		}
+	}
+	return &Provider{
+		config: config,
+		feeds:  feeds,
+		byFeed: make(map[string][]Indicator),
+		index:  make(map[string][]Indicator),
+	}
+}
+
+// Run refreshes the feeds every RefreshInterval until ctx is canceled,
+// starting immediately. Without an interval the feeds are loaded once.
+func (p *Provider) Run(ctx context.Context) {
+	if err := p.Refresh(ctx); err != nil {
+		log.Printf("Threat intelligence refresh failed: %v", err)
+	}
+	if p.config.RefreshInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Refresh(ctx); err != nil {
+				log.Printf("Threat intelligence refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+// Refresh downloads every feed and rebuilds the index. It returns an error
+// naming the feeds that failed.
+func (p *Provider) Refresh(ctx context.Context) error {
+	fetched := make(map[string][]Indicator, len(p.feeds))
+	var failed []string
+	for _, feed := range p.feeds {
+		indicators, err := feed.Fetch(ctx)
+		if err != nil {
+			log.Printf("Failed to refresh threat intelligence feed %s: %v", feed.Name(), err)
+			failed = append(failed, feed.Name())
+			continue
+		}
+		fetched[feed.Name()] = indicators
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := 0
+	for name, indicators := range fetched {
+		p.byFeed[name] = indicators
+	}
+	p.index = make(map[string][]Indicator)
+	for _, indicators := range p.byFeed {
+		for _, indicator := range indicators {
+			if indicator.Confidence < p.config.MinConfidence {
+				continue
+			}
+			indicator.Value = normalize(indicator.Type, indicator.Value)
+			if indicator.Value == "" {
+				continue
+			}
+			key := indexKey(indicator.Type, indicator.Value)
+			p.index[key] = append(p.index[key], indicator)
+			total++
+		}
+	}
+	log.Printf("Loaded %d threat indicators from %d feed(s)", total, len(p.byFeed))
+
+	if len(failed) > 0 {
+		return fmt.Errorf("feeds failed to refresh: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// GetThreatsForAsset returns the threats matching the public IPs, domains
+// and image digests of an asset
+func (p *Provider) GetThreatsForAsset(ctx context.Context, asset models.Asset) ([]models.ThreatEvent, error) {
+	var threats []models.ThreatEvent
+	seen := make(map[string]bool)
+	for _, observable := range assetIndicators(asset) {
+		for _, indicator := range p.lookup(observable.kind, observable.value) {
+			threat := threatEvent(indicator)
+			if seen[threat.ID] {
+				continue
+			}
+			seen[threat.ID] = true
+			threat.AssetID = asset.GetID()
+			threats = append(threats, threat)
+		}
+	}
+	return threats, nil
+}
+
+// GetThreatsByIndicator returns the threats matching an IP, domain or hash
+func (p *Provider) GetThreatsByIndicator(ctx context.Context, indicator string) ([]models.ThreatEvent, error) {
+	var threats []models.ThreatEvent
+	for _, match := range p.lookup(indicatorType(indicator), indicator) {
+		threats = append(threats, threatEvent(match))
+	}
+	return threats, nil
+}
+
+// IsIndicatorMalicious reports whether any feed lists an IP, domain or hash,
+// with the highest confidence reported for it
+func (p *Provider) IsIndicatorMalicious(ctx context.Context, indicator string) (bool, float64, error) {
+	matches := p.lookup(indicatorType(indicator), indicator)
+	confidence := 0.0
+	for _, match := range matches {
+		if match.Confidence > confidence {
+			confidence = match.Confidence
+		}
+	}
+	return len(matches) > 0, confidence, nil
+}
+
+// lookup returns the indicators matching value. Domains also match the
+// indicators of their parent domains.
+func (p *Provider) lookup(kind, value string) []Indicator {
+	value = normalize(kind, value)
+	if value == "" {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	matches := p.index[indexKey(kind, value)]
+	if kind == IndicatorDomain {
+		for i := strings.IndexByte(value, '.'); i >= 0; i = strings.IndexByte(value, '.') {
+			value = value[i+1:]
+			if !strings.Contains(value, ".") {
+				break
+			}
+			matches = append(matches[:len(matches):len(matches)], p.index[indexKey(kind, value)]...)
+		}
+	}
+	return matches
+}
+
+func indexKey(kind, value string) string {
+	return kind + "|" + value
+}
+
+// indicatorType guesses the type of a bare indicator
+func indicatorType(value string) string {
+	value = strings.TrimSpace(value)
+	if net.ParseIP(value) != nil {
+		return IndicatorIP
+	}
+	if strings.Contains(value, ".") {
+		return IndicatorDomain
+	}
+	return IndicatorHash
+}
+
+// normalize returns the canonical form of an indicator value, or "" if it
+// is not a valid value of its type
+func normalize(kind, value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch kind {
+	case IndicatorIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return ""
+		}
+		return ip.String()
+	case IndicatorDomain:
+		return strings.TrimSuffix(value, ".")
+	case IndicatorHash:
+		if i := strings.LastIndex(value, ":"); i >= 0 {
+			value = value[i+1:] // sha256:<digest>
+		}
+		return value
+	}
+	return ""
+}
+
+// threatEvent describes an indicator match as a threat. Its ID is stable
+// across refreshes so cached risk scores stay valid.
+func threatEvent(indicator Indicator) models.ThreatEvent {
+	threatID := indicator.ThreatID
+	if threatID == "" {
+		threatID = indicator.Type + ":" + indicator.Value
+	}
+
+	severity := models.EventSeverityLow
+	switch {
+	case indicator.Confidence >= 0.9:
+		severity = models.EventSeverityCritical
+	case indicator.Confidence >= 0.7:
+		severity = models.EventSeverityHigh
+	case indicator.Confidence >= 0.4:
+		severity = models.EventSeverityMedium
+	}
+
+	timestamp := indicator.LastSeen
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	return models.ThreatEvent{
+		BaseEvent: models.BaseEvent{
+			ID:          indicator.Source + ":" + threatID + ":" + indicator.Value,
+			Type:        models.EventTypeThreatDetected,
+			Severity:    severity,
+			Timestamp:   timestamp,
+			Source:      indicator.Source,
+			Description: indicator.Description,
+		},
+		ThreatType: indicator.ThreatType,
+		ThreatID:   threatID,
+		Confidence: indicator.Confidence,
+		Indicators: []models.ThreatIndicator{{
+			Type:       indicator.Type,
+			Value:      indicator.Value,
+			Confidence: indicator.Confidence,
+			Source:     indicator.Source,
+			FirstSeen:  indicator.FirstSeen,
+			LastSeen:   indicator.LastSeen,
+		}},
+		MITRETTP: indicator.MITRETTP,
+	}
+}
+
+// observable is an indicator value found on an asset
+type observable struct {
+	kind  string
+	value string
+}
+
+// assetIndicators returns the public IPs, domains and image digests of an
+// asset. Private and loopback addresses are left out.
+func assetIndicators(asset models.Asset) []observable {
+	var observables []observable
+	add := func(kind, value string) {
+		if kind == IndicatorIP {
+			ip := net.ParseIP(strings.TrimSpace(value))
+			if ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() {
+				return
+			}
+		}
+		if kind == IndicatorHash && !strings.Contains(value, "sha256:") {
+			return
+		}
+		if value != "" {
+			observables = append(observables, observable{kind: kind, value: value})
+		}
+	}
+
+	switch a := asset.(type) {
+	case *models.Compute:
+		add(IndicatorIP, a.PublicIP)
+	case *models.SaaS:
+		if u, err := url.Parse(a.URL); err == nil {
+			add(IndicatorDomain, u.Hostname())
+		}
+	}
+
+	base := asset.GetBaseAsset()
+	for key, kind := range assetIndicatorKeys {
+		if value, ok := base.Tags[key]; ok {
+			add(kind, value)
+		}
+		switch value := base.Metadata[key].(type) {
+		case string:
+			add(kind, value)
+		case []interface{}:
+			for _, item := range value {
+				if s, ok := item.(string); ok {
+					add(kind, s)
+				}
+			}
+		}
+	}
+	return observables
+}
@@ -0,0 +1,228 @@
+package validator
+
+import "github.com/securizon/pkg/models"
+
+// builtinSchemas are registered by NewEventValidator, in match order. They
+// cover GuardDuty findings and the resource changes recorded by CloudTrail,
+// the Azure Activity Log and GCP Cloud Audit Logs. CloudTrail names the
+// affected resource only in the resources list, which not every record has.
+var builtinSchemas = []struct {
+	eventType models.EventType
+	schema    string
+}{
+	{models.EventTypeThreatDetected, `{
+		"title": "GuardDuty finding delivered by EventBridge",
+		"x-provider": "aws",
+		"x-match": {
+			"type": "object",
+			"required": ["source"],
+			"properties": {"source": {"enum": ["aws.guardduty"]}}
+		},
+		"type": "object",
+		"required": ["id", "time", "detail"],
+		"properties": {
+			"time": {"type": "string", "format": "date-time"},
+			"detail": {
+				"type": "object",
+				"required": ["id", "type", "severity", "title"],
+				"properties": {
+					"id": {"type": "string", "minLength": 1},
+					"type": {"type": "string"},
+					"severity": {"type": "number", "minimum": 0, "maximum": 10},
+					"title": {"type": "string"}
+				}
+			}
+		},
+		"x-fields": {
+			"id": "detail.id",
+			"timestamp": "time",
+			"severity": "detail.severity",
+			"description": "detail.title",
+			"asset_id": "detail.resource.instanceDetails.instanceId",
+			"source": "source",
+			"finding_type": "detail.type",
+			"account_id": "account",
+			"region": "region"
+		}
+	}`},
+	{models.EventTypeResourceCreated, `{
+		"title": "CloudTrail record of a resource being created",
+		"x-provider": "aws",
+		"x-match": {
+			"type": "object",
+			"required": ["eventSource", "eventName"],
+			"properties": {"eventName": {"type": "string", "pattern": "^(Create|Run|Register)"}}
+		},
+		"type": "object",
+		"required": ["eventID", "eventTime", "eventSource", "eventName"],
+		"properties": {
+			"eventID": {"type": "string", "minLength": 1},
+			"eventTime": {"type": "string", "format": "date-time"},
+			"userIdentity": {"type": "object"}
+		},
+		"x-fields": {
+			"id": "eventID",
+			"timestamp": "eventTime",
+			"actor": "userIdentity.arn",
+			"source": "eventSource",
+			"description": "eventName",
+			"asset_id": "resources.0.ARN",
+			"account_id": "recipientAccountId",
+			"region": "awsRegion"
+		}
+	}`},
+	{models.EventTypeResourceDeleted, `{
+		"title": "CloudTrail record of a resource being deleted",
+		"x-provider": "aws",
+		"x-match": {
+			"type": "object",
+			"required": ["eventSource", "eventName"],
+			"properties": {"eventName": {"type": "string", "pattern": "^(Delete|Terminate|Deregister)"}}
+		},
+		"type": "object",
+		"required": ["eventID", "eventTime", "eventSource", "eventName"],
+		"properties": {
+			"eventID": {"type": "string", "minLength": 1},
+			"eventTime": {"type": "string", "format": "date-time"},
+			"userIdentity": {"type": "object"}
+		},
+		"x-fields": {
+			"id": "eventID",
+			"timestamp": "eventTime",
+			"actor": "userIdentity.arn",
+			"source": "eventSource",
+			"description": "eventName",
+			"asset_id": "resources.0.ARN",
+			"account_id": "recipientAccountId",
+			"region": "awsRegion"
+		}
+	}`},
+	{models.EventTypeResourceUpdated, `{
+		"title": "Azure Activity Log write operation",
+		"x-provider": "azure",
+		"x-match": {
+			"type": "object",
+			"required": ["operationName", "resourceId"],
+			"properties": {
+				"operationName": {
+					"type": "object",
+					"required": ["value"],
+					"properties": {"value": {"type": "string", "pattern": "(?i)/write$"}}
+				}
+			}
+		},
+		"type": "object",
+		"required": ["eventDataId", "eventTimestamp", "resourceId", "operationName"],
+		"properties": {
+			"eventDataId": {"type": "string", "minLength": 1},
+			"eventTimestamp": {"type": "string", "format": "date-time"},
+			"resourceId": {"type": "string", "minLength": 1}
+		},
+		"x-fields": {
+			"id": "eventDataId",
+			"timestamp": "eventTimestamp",
+			"severity": "level",
+			"actor": "caller",
+			"asset_id": "resourceId",
+			"description": "operationName.value",
+			"status": "status.value",
+			"subscription_id": "subscriptionId"
+		}
+	}`},
+	{models.EventTypeResourceDeleted, `{
+		"title": "Azure Activity Log delete operation",
+		"x-provider": "azure",
+		"x-match": {
+			"type": "object",
+			"required": ["operationName", "resourceId"],
+			"properties": {
+				"operationName": {
+					"type": "object",
+					"required": ["value"],
+					"properties": {"value": {"type": "string", "pattern": "(?i)/delete$"}}
+				}
+			}
+		},
+		"type": "object",
+		"required": ["eventDataId", "eventTimestamp", "resourceId", "operationName"],
+		"properties": {
+			"eventDataId": {"type": "string", "minLength": 1},
+			"eventTimestamp": {"type": "string", "format": "date-time"},
+			"resourceId": {"type": "string", "minLength": 1}
+		},
+		"x-fields": {
+			"id": "eventDataId",
+			"timestamp": "eventTimestamp",
+			"severity": "level",
+			"actor": "caller",
+			"asset_id": "resourceId",
+			"description": "operationName.value",
+			"status": "status.value",
+			"subscription_id": "subscriptionId"
+		}
+	}`},
+	{models.EventTypeResourceCreated, `{
+		"title": "GCP Cloud Audit Log entry of a resource being created",
+		"x-provider": "gcp",
+		"x-match": {
+			"type": "object",
+			"required": ["protoPayload"],
+			"properties": {
+				"protoPayload": {
+					"type": "object",
+					"required": ["methodName"],
+					"properties": {"methodName": {"type": "string", "pattern": "(\\.insert|\\.create|\\.Create[A-Za-z]+)$"}}
+				}
+			}
+		},
+		"type": "object",
+		"required": ["insertId", "timestamp", "protoPayload"],
+		"properties": {
+			"insertId": {"type": "string", "minLength": 1},
+			"timestamp": {"type": "string", "format": "date-time"},
+			"protoPayload": {"type": "object", "required": ["resourceName"]}
+		},
+		"x-fields": {
+			"id": "insertId",
+			"timestamp": "timestamp",
+			"severity": "severity",
+			"actor": "protoPayload.authenticationInfo.principalEmail",
+			"asset_id": "protoPayload.resourceName",
+			"source": "protoPayload.serviceName",
+			"description": "protoPayload.methodName",
+			"project_id": "resource.labels.project_id"
+		}
+	}`},
+	{models.EventTypeResourceDeleted, `{
+		"title": "GCP Cloud Audit Log entry of a resource being deleted",
+		"x-provider": "gcp",
+		"x-match": {
+			"type": "object",
+			"required": ["protoPayload"],
+			"properties": {
+				"protoPayload": {
+					"type": "object",
+					"required": ["methodName"],
+					"properties": {"methodName": {"type": "string", "pattern": "(\\.delete|\\.Delete[A-Za-z]+)$"}}
+				}
+			}
+		},
+		"type": "object",
+		"required": ["insertId", "timestamp", "protoPayload"],
+		"properties": {
+			"insertId": {"type": "string", "minLength": 1},
+			"timestamp": {"type": "string", "format": "date-time"},
+			"protoPayload": {"type": "object", "required": ["resourceName"]}
+		},
+		"x-fields": {
+			"id": "insertId",
+			"timestamp": "timestamp",
+			"severity": "severity",
+			"actor": "protoPayload.authenticationInfo.principalEmail",
+			"asset_id": "protoPayload.resourceName",
+			"source": "protoPayload.serviceName",
+			"description": "protoPayload.methodName",
+			"project_id": "resource.labels.project_id"
+		}
+	}`},
+}
@@ -0,0 +1,249 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/securizon/pkg/models"
+)
+
+// Schema is the subset of JSON Schema raw events are validated against,
+// with extension keywords describing which events it applies to and how
+// they are normalized. Unsupported keywords are rejected when the schema is
+// registered rather than silently not enforced.
+type Schema struct {
+	SchemaURI   string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type       string             `json:"type,omitempty"` // object, array, string, number, integer, boolean or null
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Enum       []interface{}      `json:"enum,omitempty"`
+	Pattern    string             `json:"pattern,omitempty"`
+	Format     string             `json:"format,omitempty"` // date-time
+	MinLength  int                `json:"minLength,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+
+	// Provider is the provider whose events the schema describes
+	Provider models.Provider `json:"x-provider,omitempty"`
+	// Match selects the events the schema applies to: an event is
+	// validated against the first registered schema whose Match it passes
+	Match *Schema `json:"x-match,omitempty"`
+	// Fields maps BaseEvent fields (id, timestamp, severity, actor,
+	// asset_id, environment, source, description) to dotted paths in the
+	// raw event. Other keys are copied into the event's metadata.
+	Fields map[string]string `json:"x-fields,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Violation is one way an event fails its schema
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// schemaTypes are the supported values of the type keyword
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// parseSchema decodes and compiles a schema document
+func parseSchema(data []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var schema Schema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := schema.compile("$"); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// compile checks the keywords of the schema and its subschemas and
+// compiles their patterns
+func (s *Schema) compile(path string) error {
+	if s.Type != "" && !schemaTypes[s.Type] {
+		return fmt.Errorf("%s: unsupported type %q", path, s.Type)
+	}
+	if s.Format != "" && s.Format != "date-time" {
+		return fmt.Errorf("%s: unsupported format %q", path, s.Format)
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+		s.pattern = pattern
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%s.%s: empty schema", path, name)
+		}
+		if err := property.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+	if s.Match != nil {
+		if err := s.Match.compile(path + ".x-match"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate appends the ways value fails the schema to violations
+func (s *Schema) validate(value interface{}, path string, violations []Violation) []Violation {
+	fail := func(format string, args ...interface{}) []Violation {
+		return append(violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Type != "" && !hasType(value, s.Type) {
+		return fail("must be of type %s, not %s", s.Type, typeOf(value))
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+		return fail("must be one of %s", formatEnum(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, Violation{Path: path + "." + name, Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		// Violations are reported in a stable order
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := v[name]; ok {
+				violations = s.Properties[name].validate(property, path+"."+name, violations)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				violations = s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case string:
+		if len([]rune(v)) < s.MinLength {
+			return fail("must be at least %d characters", s.MinLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fail("must match %s", s.Pattern)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				return fail("must be an RFC 3339 date-time")
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fail("must be at most %v", *s.Maximum)
+		}
+	}
+	return violations
+}
+
+// matches reports whether value passes the schema
+func (s *Schema) matches(value interface{}) bool {
+	return len(s.validate(value, "$", nil)) == 0
+}
+
+func hasType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	default:
+		return typeOf(value) == schemaType
+	}
+}
+
+// typeOf names the JSON type of a decoded value
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		encoded, _ := json.Marshal(v)
+		parts[i] = string(encoded)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// lookup returns the value at a dotted path, where numeric segments index
+// arrays
+func lookup(value interface{}, path string) (interface{}, bool) {
+	for _, segment := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
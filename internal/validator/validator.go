@@ -0,0 +1,293 @@
+// Package validator validates raw provider events against registered JSON
+// schemas and normalizes them into models.BaseEvent.
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/securizon/pkg/models"
+)
+
+// ErrNoSchema is wrapped by the ValidationError of an event no registered
+// schema matches
+var ErrNoSchema = errors.New("no registered schema matches the event")
+
+// eventIDNamespace is the namespace of the ids derived for events that have
+// none
+var eventIDNamespace = uuid.MustParse("6f1c2b9e-4d0a-4f57-9a3e-8c2d7b51e0a4")
+
+// ValidationError is returned for events that are not valid JSON, match no
+// schema or fail the schema they match. Such events cannot be processed and
+// belong in the dead letter queue.
+type ValidationError struct {
+	EventType  models.EventType // empty unless a schema matched
+	Provider   models.Provider
+	Violations []Violation
+	Err        error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = fmt.Sprintf("%s %s", v.Path, v.Message)
+	}
+	return fmt.Sprintf("invalid %s %s event: %s", e.Provider, e.EventType, strings.Join(parts, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// registeredSchema is a schema and the event type it produces
+type registeredSchema struct {
+	eventType models.EventType
+	schema    *Schema
+}
+
+// EventValidator validates and normalizes raw events with the schemas
+// registered for each event type and provider. It is safe for concurrent
+// use, including registering schemas while events are validated.
+type EventValidator struct {
+	mu      sync.RWMutex
+	schemas []registeredSchema // in the order events are matched
+}
+
+// NewEventValidator creates a validator with the built-in schemas for
+// AWS, Azure and GCP events registered
+func NewEventValidator() *EventValidator {
+	v := &EventValidator{}
+	for _, builtin := range builtinSchemas {
+		if err := v.RegisterSchema(builtin.eventType, []byte(builtin.schema)); err != nil {
+			panic(fmt.Sprintf("invalid built-in %s schema: %v", builtin.eventType, err))
+		}
+	}
+	return v
+}
+
+// RegisterSchema registers the JSON schema of a provider's events of
+// eventType; the provider is named by the schema's x-provider keyword. It
+// replaces the schema already registered for the same event type and
+// provider, keeping its place in the match order, and is otherwise matched
+// after the schemas registered before it.
+func (v *EventValidator) RegisterSchema(eventType models.EventType, schema []byte) error {
+	if eventType == "" {
+		return fmt.Errorf("event type is required")
+	}
+	parsed, err := parseSchema(schema)
+	if err != nil {
+		return fmt.Errorf("schema for %s: %w", eventType, err)
+	}
+	if parsed.Provider == "" {
+		return fmt.Errorf("schema for %s: x-provider is required", eventType)
+	}
+	if parsed.Match == nil {
+		return fmt.Errorf("schema for %s: x-match is required", eventType)
+	}
+	for field, path := range parsed.Fields {
+		if strings.TrimSpace(path) == "" {
+			return fmt.Errorf("schema for %s: x-fields.%s has no path", eventType, field)
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entry := registeredSchema{eventType: eventType, schema: parsed}
+	for i, existing := range v.schemas {
+		if existing.eventType == eventType && existing.schema.Provider == parsed.Provider {
+			v.schemas[i] = entry
+			return nil
+		}
+	}
+	v.schemas = append(v.schemas, entry)
+	return nil
+}
+
+// ValidateAndNormalize validates a raw event against the first registered
+// schema it matches and returns it as a BaseEvent, with the original bytes
+// kept as its raw data. Any error is a *ValidationError.
+func (v *EventValidator) ValidateAndNormalize(data []byte) (*models.BaseEvent, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, &ValidationError{Err: fmt.Errorf("event is not valid JSON: %w", err)}
+	}
+
+	entry, ok := v.match(raw)
+	if !ok {
+		return nil, &ValidationError{Err: ErrNoSchema}
+	}
+
+	invalid := func(violations []Violation) error {
+		return &ValidationError{EventType: entry.eventType, Provider: entry.schema.Provider, Violations: violations}
+	}
+	if violations := entry.schema.validate(raw, "$", nil); len(violations) > 0 {
+		return nil, invalid(violations)
+	}
+
+	event, violations := normalize(data, raw, entry)
+	if len(violations) > 0 {
+		return nil, invalid(violations)
+	}
+	event.RawData = data
+	return event, nil
+}
+
+func (v *EventValidator) match(raw interface{}) (registeredSchema, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	for _, entry := range v.schemas {
+		if entry.schema.Match.matches(raw) {
+			return entry, true
+		}
+	}
+	return registeredSchema{}, false
+}
+
+// normalize maps the schema's fields of a raw event onto a BaseEvent.
+// Events without an id are given one derived from their content, so a
+// redelivered event keeps its id and is deduplicated; events without a
+// timestamp are given the current time.
+func normalize(data []byte, raw interface{}, entry registeredSchema) (*models.BaseEvent, []Violation) {
+	event := &models.BaseEvent{
+		Type:      entry.eventType,
+		Severity:  models.EventSeverityMedium,
+		Timestamp: time.Now().UTC(),
+		Provider:  entry.schema.Provider,
+		Source:    string(entry.schema.Provider),
+	}
+
+	fields := make([]string, 0, len(entry.schema.Fields))
+	for field := range entry.schema.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var violations []Violation
+	for _, field := range fields {
+		path := entry.schema.Fields[field]
+		value, ok := lookup(raw, path)
+		if !ok || value == nil {
+			continue
+		}
+		fail := func(message string) {
+			violations = append(violations, Violation{Path: "$." + path, Message: message})
+		}
+
+		switch field {
+		case "timestamp":
+			timestamp, ok := parseTimestamp(value)
+			if !ok {
+				fail("is not a timestamp")
+				continue
+			}
+			event.Timestamp = timestamp
+		case "severity":
+			severity, ok := parseSeverity(value)
+			if !ok {
+				fail("is not a severity")
+				continue
+			}
+			event.Severity = severity
+		case "environment":
+			environment := models.Environment(strings.ToLower(stringValue(value)))
+			switch environment {
+			case models.EnvironmentProduction, models.EnvironmentStaging, models.EnvironmentDevelopment, models.EnvironmentTesting:
+				event.Environment = environment
+			default:
+				fail("is not a known environment")
+			}
+		case "id":
+			event.ID = stringValue(value)
+		case "actor":
+			event.Actor = stringValue(value)
+		case "asset_id":
+			event.AssetID = stringValue(value)
+		case "source":
+			event.Source = stringValue(value)
+		case "description":
+			event.Description = stringValue(value)
+		default:
+			if event.Metadata == nil {
+				event.Metadata = make(map[string]interface{})
+			}
+			event.Metadata[field] = value
+		}
+	}
+	if event.ID == "" {
+		event.ID = uuid.NewSHA1(eventIDNamespace, data).String()
+	}
+	return event, violations
+}
+
+// parseTimestamp reads an RFC 3339 string or a Unix time in seconds or
+// milliseconds
+func parseTimestamp(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t.UTC(), err == nil
+	case float64:
+		if v > 1e12 {
+			return time.UnixMilli(int64(v)).UTC(), true
+		}
+		return time.Unix(int64(v), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// parseSeverity reads a severity or log level name, such as those of Azure
+// and GCP logs, or a 0-10 score, the scale used by GuardDuty and CVSS
+func parseSeverity(value interface{}) (models.EventSeverity, bool) {
+	switch v := value.(type) {
+	case string:
+		switch strings.ToLower(v) {
+		case "informational", "info", "notice", "debug", "default", "low":
+			return models.EventSeverityLow, true
+		case "medium", "moderate", "warning":
+			return models.EventSeverityMedium, true
+		case "high", "error":
+			return models.EventSeverityHigh, true
+		case "critical", "alert", "emergency":
+			return models.EventSeverityCritical, true
+		}
+	case float64:
+		switch {
+		case v >= 9:
+			return models.EventSeverityCritical, true
+		case v >= 7:
+			return models.EventSeverityHigh, true
+		case v >= 4:
+			return models.EventSeverityMedium, true
+		case v >= 0:
+			return models.EventSeverityLow, true
+		}
+	}
+	return "", false
+}
+
+// stringValue renders a scalar as a string; objects and arrays are encoded
+// as JSON
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
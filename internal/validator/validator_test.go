@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/securizon/pkg/models"
+)
+
+func TestNormalizeCloudTrailRecord(t *testing.T) {
+	record := []byte(`{
+		"eventID": "3d6c1a52-0f5b-4d7e-9d7c-1f2e3a4b5c6d",
+		"eventTime": "2026-10-01T12:00:00Z",
+		"eventSource": "ec2.amazonaws.com",
+		"eventName": "RunInstances",
+		"userIdentity": {"arn": "arn:aws:iam::123456789012:user/deploy"},
+		"resources": [{"ARN": "arn:aws:ec2:us-east-1:123456789012:instance/i-0abc"}]
+	}`)
+
+	event, err := NewEventValidator().ValidateAndNormalize(record)
+	if err != nil {
+		t.Fatalf("ValidateAndNormalize() error = %v", err)
+	}
+	if event.Type != models.EventTypeResourceCreated {
+		t.Errorf("type = %s, want %s", event.Type, models.EventTypeResourceCreated)
+	}
+	if event.ID != "3d6c1a52-0f5b-4d7e-9d7c-1f2e3a4b5c6d" {
+		t.Errorf("id = %s, want the CloudTrail event id", event.ID)
+	}
+	if event.AssetID != "arn:aws:ec2:us-east-1:123456789012:instance/i-0abc" {
+		t.Errorf("asset id = %q, want the resource ARN", event.AssetID)
+	}
+}
+
+func TestNormalizeDerivesStableIDs(t *testing.T) {
+	v := &EventValidator{}
+	schema := `{
+		"x-provider": "github",
+		"x-match": {"type": "object", "required": ["action"]},
+		"type": "object",
+		"x-fields": {"description": "action"}
+	}`
+	if err := v.RegisterSchema(models.EventTypeThreatDetected, []byte(schema)); err != nil {
+		t.Fatalf("RegisterSchema() error = %v", err)
+	}
+
+	first, err := v.ValidateAndNormalize([]byte(`{"action": "secret_scanning_alert"}`))
+	if err != nil {
+		t.Fatalf("ValidateAndNormalize() error = %v", err)
+	}
+	redelivered, err := v.ValidateAndNormalize([]byte(`{"action": "secret_scanning_alert"}`))
+	if err != nil {
+		t.Fatalf("ValidateAndNormalize() error = %v", err)
+	}
+	other, err := v.ValidateAndNormalize([]byte(`{"action": "repository_deleted"}`))
+	if err != nil {
+		t.Fatalf("ValidateAndNormalize() error = %v", err)
+	}
+
+	if first.ID == "" || first.ID != redelivered.ID {
+		t.Errorf("ids of one event delivered twice = %q and %q, want the same id", first.ID, redelivered.ID)
+	}
+	if other.ID == first.ID {
+		t.Errorf("two different events share the id %s", first.ID)
+	}
+}
@@ -4,6 +4,7 @@ import (
     "context"
     "fmt"
     "log"
+    "strings"
     "sync"
     "time"
 
@@ -104,6 +105,13 @@ func NewApprovalManager(store store.Store, producer kafka.Producer) *ApprovalMan
     return mgr
 }
 
+// AddNotifier sends approval notifications to notifier as well
+func (am *ApprovalManager) AddNotifier(notifier Notifier) {
+    am.mu.Lock()
+    defer am.mu.Unlock()
+    am.notifiers = append(am.notifiers, notifier)
+}
+
 func (am *ApprovalManager) loadDefaultWorkflows() {
     // Placeholder – load built‑in workflow templates from configuration or files.
     // For now we leave it empty.
@@ -295,10 +303,38 @@ func (am *ApprovalManager) handleStepTimeout(ctx context.Context, requestID stri
 func (am *ApprovalManager) isApproverAuthorized(step *ApprovalStepInstance, approverID string) bool { return true }
 func (am *ApprovalManager) hasAlreadyVoted(step *ApprovalStepInstance, approverID string) bool { return false }
 func (am *ApprovalManager) isStepComplete(step *ApprovalStepInstance) bool { return len(step.Approvals)+len(step.Rejections) >= step.Step.RequiredCount }
-func (am *ApprovalManager) notifyApprovers(ctx context.Context, request ApprovalRequest, stepIdx int) {}
-func (am *ApprovalManager) notifyStepApproved(ctx context.Context, request ApprovalRequest, stepIdx int) {}
-func (am *ApprovalManager) notifyStepRejected(ctx context.Context, request ApprovalRequest, stepIdx int) {}
-func (am *ApprovalManager) notifyEscalation(ctx context.Context, request ApprovalRequest, stepIdx int) {}
+func (am *ApprovalManager) notifyApprovers(ctx context.Context, request ApprovalRequest, stepIdx int) {
+    am.notifyStep(ctx, request, stepIdx, "awaits approval")
+}
+func (am *ApprovalManager) notifyStepApproved(ctx context.Context, request ApprovalRequest, stepIdx int) {
+    am.notifyStep(ctx, request, stepIdx, "was approved")
+}
+func (am *ApprovalManager) notifyStepRejected(ctx context.Context, request ApprovalRequest, stepIdx int) {
+    am.notifyStep(ctx, request, stepIdx, "was rejected")
+}
+func (am *ApprovalManager) notifyEscalation(ctx context.Context, request ApprovalRequest, stepIdx int) {
+    am.notifyStep(ctx, request, stepIdx, "was escalated")
+}
+
+// notifyStep tells every notifier what happened to a step of request
+func (am *ApprovalManager) notifyStep(ctx context.Context, request ApprovalRequest, stepIdx int, event string) {
+    if stepIdx < 0 || stepIdx >= len(request.Steps) {
+        return
+    }
+    step := request.Steps[stepIdx].Step
+    message := fmt.Sprintf("Approval step %q of remediation %s %s (approvers: %s)",
+        step.Name, request.RemediationID, event, strings.Join(step.ApproverIDs, ", "))
+
+    am.mu.RLock()
+    notifiers := append([]Notifier(nil), am.notifiers...)
+    am.mu.RUnlock()
+
+    for _, notifier := range notifiers {
+        if err := notifier.Notify(ctx, message); err != nil {
+            log.Printf("Failed to send approval notification for request %s: %v", request.ID, err)
+        }
+    }
+}
 func (am *ApprovalManager) completeApprovalWorkflow(ctx context.Context, requestID string) {}
 func (am *ApprovalManager) handleStepFailure(ctx context.Context, requestID string, stepIdx int) {}
 func (am *ApprovalManager) failApprovalWorkflow(ctx context.Context, requestID string, reason string) {}
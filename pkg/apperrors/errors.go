@@ -0,0 +1,138 @@
+// Package apperrors defines typed errors shared by stores and API handlers so
+// failures map to consistent error codes and HTTP statuses
+package apperrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code is a stable, client-facing error code
+type Code string
+
+const (
+	CodeInvalidRequest Code = "INVALID_REQUEST"
+	CodeUnauthorized   Code = "UNAUTHORIZED"
+	CodeForbidden      Code = "FORBIDDEN"
+	CodeNotFound       Code = "NOT_FOUND"
+	CodeConflict       Code = "CONFLICT"
+	CodeQuotaExceeded  Code = "QUOTA_EXCEEDED"
+	CodeInternal       Code = "INTERNAL_ERROR"
+	CodeNotImplemented Code = "NOT_IMPLEMENTED"
+	CodeUnavailable    Code = "SERVICE_UNAVAILABLE"
+	CodeTimeout        Code = "TIMEOUT"
+)
+
+// Sentinels for errors.Is. Any *Error with the same code matches.
+var (
+	ErrNotFound    = &Error{Code: CodeNotFound, Message: "not found"}
+	ErrConflict    = &Error{Code: CodeConflict, Message: "conflict"}
+	ErrValidation  = &Error{Code: CodeInvalidRequest, Message: "validation failed"}
+	ErrUnavailable = &Error{Code: CodeUnavailable, Message: "service unavailable"}
+	ErrTimeout     = &Error{Code: CodeTimeout, Message: "timed out"}
+)
+
+// Error is an error with a code. Message is safe to show to clients; the
+// wrapped error may carry internal details and is only logged.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches sentinels by code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Err == nil && t.Code == e.Code
+}
+
+// New creates an error with a code and client-safe message
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap attaches a code and client-safe message to err
+func Wrap(code Code, err error, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// NotFound creates a not-found error
+func NotFound(format string, args ...interface{}) *Error {
+	return New(CodeNotFound, format, args...)
+}
+
+// Conflict creates a conflict error
+func Conflict(format string, args ...interface{}) *Error {
+	return New(CodeConflict, format, args...)
+}
+
+// Invalid creates a validation error
+func Invalid(format string, args ...interface{}) *Error {
+	return New(CodeInvalidRequest, format, args...)
+}
+
+// CodeOf returns the code of the first typed error in err's chain.
+// Untyped errors are internal errors unless they are context deadlines.
+func CodeOf(err error) Code {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Code
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return CodeTimeout
+	}
+	return CodeInternal
+}
+
+// MessageOf returns the client-safe message of err, or "" for errors whose
+// details must not leave the server
+func MessageOf(err error) string {
+	var typed *Error
+	if !errors.As(err, &typed) {
+		return ""
+	}
+	switch typed.Code {
+	case CodeInternal, CodeUnavailable, CodeTimeout:
+		return ""
+	}
+	return typed.Message
+}
+
+// HTTPStatus returns the HTTP status for a code
+func HTTPStatus(code Code) int {
+	switch code {
+	case CodeInvalidRequest:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeQuotaExceeded:
+		return http.StatusTooManyRequests
+	case CodeNotImplemented:
+		return http.StatusNotImplemented
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
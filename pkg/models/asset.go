@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 	"github.com/google/uuid"
 )
@@ -70,6 +72,8 @@ type BaseAsset struct {
 	UpdatedAt    time.Time  `json:"updated_at"`
 	FirstSeen    time.Time  `json:"first_seen"`
 	LastSeen     time.Time  `json:"last_seen"`
+	ReachableFromInternet bool `json:"reachable_from_internet,omitempty"` // derived from graph reachability
+	RiskScore    float64    `json:"risk_score,omitempty"` // set from the graph when the asset is read
 	Tags         map[string]string `json:"tags,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -146,6 +150,7 @@ type Finding struct {
 	Severity      float64   `json:"severity"` // 0-10
 	RiskScore     float64   `json:"risk_score"` // 0-100
 	Status        string    `json:"status"` // open, resolved, suppressed
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
 	FirstSeen     time.Time `json:"first_seen"`
 	LastSeen      time.Time `json:"last_seen"`
 	Description   string    `json:"description"`
@@ -154,6 +159,45 @@ type Finding struct {
 	FalsePositive bool      `json:"false_positive"`
 	Suppressed    bool      `json:"suppressed"`
 	SuppressedReason string `json:"suppressed_reason,omitempty"`
+	Feedback      []FindingFeedback `json:"feedback,omitempty"`
+	CVEs          []string  `json:"cves,omitempty"` // referenced vulnerabilities, e.g. CVE-2021-44228
+	CVEScores     []CVEScore `json:"cve_scores,omitempty"` // set by enrichment
+}
+
+// FindingResourceKey is the metadata key naming the resource of an asset a
+// finding is about, such as a rule of a security group, for policies that
+// raise several findings per asset
+const FindingResourceKey = "resource"
+
+// CVEScore holds the severity and exploit prediction scores of a CVE
+type CVEScore struct {
+	CVEID          string    `json:"cve_id"`
+	CVSS           float64   `json:"cvss"`            // 0-10 base score
+	EPSS           float64   `json:"epss"`            // 0-1 probability of exploitation in the next 30 days
+	EPSSPercentile float64   `json:"epss_percentile"` // 0-1
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// MaxCVSS returns the highest CVSS score of the finding's CVEs
+func (f Finding) MaxCVSS() float64 {
+	max := 0.0
+	for _, score := range f.CVEScores {
+		if score.CVSS > max {
+			max = score.CVSS
+		}
+	}
+	return max
+}
+
+// MaxEPSS returns the highest exploit probability of the finding's CVEs
+func (f Finding) MaxEPSS() float64 {
+	max := 0.0
+	for _, score := range f.CVEScores {
+		if score.EPSS > max {
+			max = score.EPSS
+		}
+	}
+	return max
 }
 
 // NewBaseAsset creates a new base asset
@@ -174,6 +218,38 @@ func NewBaseAsset(provider Provider, assetType AssetType, environment Environmen
 	}
 }
 
+// UnmarshalAsset decodes an asset document into the asset model for its type
+func UnmarshalAsset(data []byte) (Asset, error) {
+	var base BaseAsset
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	var asset Asset
+	switch base.Type {
+	case AssetTypeIdentity:
+		asset = &Identity{}
+	case AssetTypeCompute:
+		asset = &Compute{}
+	case AssetTypeNetwork:
+		asset = &Network{}
+	case AssetTypeData:
+		asset = &Data{}
+	case AssetTypeSaaS:
+		asset = &SaaS{}
+	default:
+		return nil, fmt.Errorf("unknown asset type %q", base.Type)
+	}
+	if err := json.Unmarshal(data, asset); err != nil {
+		return nil, err
+	}
+	if identity, ok := asset.(*Identity); ok {
+		// Identity's own type field shadows the asset type when decoding
+		identity.BaseAsset.Type = base.Type
+	}
+	return asset, nil
+}
+
 // Asset interface for all asset types
 type Asset interface {
 	GetID() string
@@ -183,6 +259,8 @@ type Asset interface {
 	GetName() string
 	GetBaseAsset() BaseAsset
 	UpdateLastSeen()
+	SetSeen(firstSeen, lastSeen time.Time)
+	SetRiskScore(score float64)
 }
 
 // Interface methods implementations
@@ -197,9 +275,53 @@ func (a *BaseAsset) UpdateLastSeen() {
 	a.UpdatedAt = time.Now()
 }
 
+// SetSeen sets when the asset was first and last observed
+func (a *BaseAsset) SetSeen(firstSeen, lastSeen time.Time) {
+	a.FirstSeen = firstSeen
+	a.LastSeen = lastSeen
+}
+
+// SetRiskScore sets the asset's current risk score
+func (a *BaseAsset) SetRiskScore(score float64) {
+	a.RiskScore = score
+}
+
 func (i Identity) GetBaseAsset() BaseAsset { return i.BaseAsset }
 func (c Compute) GetBaseAsset() BaseAsset { return c.BaseAsset }
 func (n Network) GetBaseAsset() BaseAsset { return n.BaseAsset }
 func (d Data) GetBaseAsset() BaseAsset { return d.BaseAsset }
 func (s SaaS) GetBaseAsset() BaseAsset { return s.BaseAsset }
 func (f Finding) GetBaseAsset() BaseAsset { return f.BaseAsset }
+
+// AssetFilter represents a filter for assets
+type AssetFilter struct {
+	Types        []AssetType   `json:"types,omitempty"`
+	Providers    []Provider    `json:"providers,omitempty"`
+	Environments []Environment `json:"environments,omitempty"`
+	MinRiskScore float64       `json:"min_risk_score,omitempty"`
+	MaxRiskScore float64       `json:"max_risk_score,omitempty"`
+	// FirstSeenAfter and FirstSeenBefore select assets that first appeared
+	// within a range; zero values leave the range open
+	FirstSeenAfter  time.Time `json:"first_seen_after"`
+	FirstSeenBefore time.Time `json:"first_seen_before"`
+	Limit           int       `json:"limit,omitempty"`
+	Offset          int       `json:"offset,omitempty"`
+}
+
+// FindingFilter represents a filter for findings. Severity bounds are
+// inclusive; zero values leave them open.
+type FindingFilter struct {
+	Statuses    []string `json:"statuses,omitempty"`
+	MinSeverity float64  `json:"min_severity,omitempty"`
+	MaxSeverity float64  `json:"max_severity,omitempty"`
+	AssetIDs    []string `json:"asset_ids,omitempty"`
+	PolicyIDs   []string `json:"policy_ids,omitempty"`
+	Limit       int      `json:"limit,omitempty"`
+	Offset      int      `json:"offset,omitempty"`
+}
+
+// AssetQuery represents a text search over assets
+type AssetQuery struct {
+	AssetFilter
+	TextSearch string `json:"text_search"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
 // AttackPath represents a chain of assets that can be exploited to reach a target
 type AttackPath struct {
@@ -8,12 +11,17 @@ type AttackPath struct {
 	SourceID           string                      `json:"source_id"`
 	TargetID           string                      `json:"target_id"`
 	Hops               int                         `json:"hops"`
-	CumulativeRisk     float64                     `json:"cumulative_risk"`
+	CumulativeRisk     float64                     `json:"cumulative_risk"`    // impact: what the path reaches
+	Likelihood         float64                     `json:"likelihood"`         // 0-1, how likely the path is exploited
+	Priority           float64                     `json:"priority"`           // CumulativeRisk x Likelihood
 	Path               []PathNode                  `json:"path"`
+	EdgeTypes          []string                    `json:"edge_types,omitempty"` // relationship type of each hop
 	Vulnerabilities    []AttackPathVulnerability   `json:"vulnerabilities"`
 	CreatedAt          time.Time                   `json:"created_at"`
 	UpdatedAt          time.Time                   `json:"updated_at"`
 	Recommendations    []string                    `json:"recommendations,omitempty"`
+	Accepted           bool                        `json:"accepted,omitempty"`         // matches an AcceptedPath
+	AcceptedPathID     string                      `json:"accepted_path_id,omitempty"`
 }
 
 // PathNode represents an asset in an attack path
@@ -164,3 +172,106 @@ type PathExploitability struct {
 	DetectionGaps    []string `json:"detection_gaps"`
 	MitigationStatus string  `json:"mitigation_status"`
 }
+
+// Likelihood tuning for attack paths
+const (
+	// baseExploitability is the exploitability of a path with no known vulnerabilities
+	baseExploitability = 0.2
+	// exploitedVulnBonus raises exploitability for vulnerabilities that enable a hop
+	exploitedVulnBonus = 1.2
+	// stepSuccessRate is the chance an attacker completes each additional hop
+	stepSuccessRate = 0.85
+)
+
+// PathLikelihood estimates how likely an attack path is to be exploited, from
+// 0 to 1. It combines the exploitability of the most severe vulnerability on
+// the path, the average trust of the relationships traversed (1.0 when
+// unknown), and the number of steps the attacker has to complete.
+func PathLikelihood(vulns []AttackPathVulnerability, trustScores []float64, hops int) float64 {
+	exploitability := baseExploitability
+	for _, vuln := range vulns {
+		score := vuln.Severity / 10
+		if vuln.ExploitedInPath {
+			score *= exploitedVulnBonus
+		}
+		exploitability = math.Max(exploitability, math.Min(1, score))
+	}
+
+	trust := 1.0
+	if len(trustScores) > 0 {
+		total := 0.0
+		for _, score := range trustScores {
+			total += math.Min(1, math.Max(0, score))
+		}
+		trust = total / float64(len(trustScores))
+	}
+
+	steps := 1.0
+	if hops > 1 {
+		steps = math.Pow(stepSuccessRate, float64(hops-1))
+	}
+
+	return exploitability * trust * steps
+}
+
+// Score sets the path likelihood and priority from its vulnerabilities and
+// the trust scores of the relationships it traverses
+func (p *AttackPath) Score(trustScores []float64) {
+	p.Likelihood = PathLikelihood(p.Vulnerabilities, trustScores, p.Hops)
+	p.Priority = p.CumulativeRisk * p.Likelihood
+}
+
+// AcceptedPath allowlists attack paths that are known and intended, such as a
+// jump box reaching production. Matching paths are still reported, flagged as
+// accepted, but are left out of critical path reports and alerts.
+//
+// A path matches when it starts at SourceID and ends at TargetID, either of
+// which may be "*" for any asset, and when its relationship types equal
+// EdgeTypes hop by hop, with "*" matching any one hop. Empty EdgeTypes match
+// paths of any shape.
+type AcceptedPath struct {
+	ID        string     `json:"id"`
+	TenantID  string     `json:"tenant_id,omitempty"` // tenant the entry applies to
+	SourceID  string     `json:"source_id"`
+	TargetID  string     `json:"target_id"`
+	EdgeTypes []string   `json:"edge_types,omitempty"`
+	Reason    string     `json:"reason"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil never expires
+}
+
+// AcceptedPathWildcard matches any asset or relationship type
+const AcceptedPathWildcard = "*"
+
+// Expired reports whether the acceptance has lapsed at now
+func (a AcceptedPath) Expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
+
+// Matches reports whether a path from sourceID to targetID over edgeTypes is
+// accepted at now. Paths whose relationship types are unknown only match
+// entries without EdgeTypes.
+func (a AcceptedPath) Matches(sourceID, targetID string, edgeTypes []string, now time.Time) bool {
+	if a.Expired(now) {
+		return false
+	}
+	if a.SourceID != AcceptedPathWildcard && a.SourceID != sourceID {
+		return false
+	}
+	if a.TargetID != AcceptedPathWildcard && a.TargetID != targetID {
+		return false
+	}
+	if len(a.EdgeTypes) == 0 {
+		return true
+	}
+	if len(a.EdgeTypes) != len(edgeTypes) {
+		return false
+	}
+	for i, edgeType := range a.EdgeTypes {
+		if edgeType != AcceptedPathWildcard && edgeType != edgeTypes[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 	"github.com/google/uuid"
 )
@@ -21,6 +22,11 @@ const (
 	EventTypePolicyViolation  EventType = "policy.violation"
 	EventTypeThreatDetected   EventType = "threat.detected"
 	EventTypeRiskScoreChanged EventType = "risk.score_changed"
+	// Resource changes recorded in provider audit logs. They carry the raw
+	// log record rather than an asset, which collection picks up.
+	EventTypeResourceCreated  EventType = "resource.created"
+	EventTypeResourceUpdated  EventType = "resource.updated"
+	EventTypeResourceDeleted  EventType = "resource.deleted"
 )
 
 // EventSeverity represents the severity of an event
@@ -57,6 +63,38 @@ type AssetEvent struct {
 	Changes    []FieldChange `json:"changes,omitempty"`
 }
 
+// UnmarshalJSON decodes the assets of the event into the models for their
+// types
+func (e *AssetEvent) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		BaseEvent
+		Asset    json.RawMessage `json:"asset"`
+		OldAsset json.RawMessage `json:"old_asset"`
+		Changes  []FieldChange   `json:"changes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	event := AssetEvent{BaseEvent: raw.BaseEvent, Changes: raw.Changes}
+	if len(raw.Asset) > 0 && string(raw.Asset) != "null" {
+		asset, err := UnmarshalAsset(raw.Asset)
+		if err != nil {
+			return err
+		}
+		event.Asset = asset
+	}
+	if len(raw.OldAsset) > 0 && string(raw.OldAsset) != "null" {
+		old, err := UnmarshalAsset(raw.OldAsset)
+		if err != nil {
+			return err
+		}
+		event.OldAsset = &old
+	}
+	*e = event
+	return nil
+}
+
 // RelationshipEvent represents events related to relationships
 type RelationshipEvent struct {
 	BaseEvent
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FeedbackVerdict is an analyst's judgement of a finding
+type FeedbackVerdict string
+
+const (
+	FeedbackTruePositive  FeedbackVerdict = "true_positive"
+	FeedbackFalsePositive FeedbackVerdict = "false_positive"
+)
+
+const (
+	// minFeedbackForTuning is the number of reviewed findings needed before
+	// a policy's false-positive rate is trusted enough to suggest tuning
+	minFeedbackForTuning = 5
+	// tuneFalsePositiveRate and reviewFalsePositiveRate are the rates above
+	// which a policy should be tuned or have its conditions reviewed
+	tuneFalsePositiveRate   = 0.5
+	reviewFalsePositiveRate = 0.2
+	// suppressionRepeats is the number of false positives on one asset after
+	// which suppressing the policy for that asset is suggested
+	suppressionRepeats = 2
+)
+
+// FindingFeedback records whether a finding was a true or false positive
+type FindingFeedback struct {
+	FindingID   string          `json:"finding_id"`
+	Verdict     FeedbackVerdict `json:"verdict"`
+	Reason      string          `json:"reason,omitempty"`
+	SubmittedBy string          `json:"submitted_by,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Validate checks the feedback verdict and requires a reason for false positives
+func (f FindingFeedback) Validate() error {
+	switch f.Verdict {
+	case FeedbackTruePositive:
+	case FeedbackFalsePositive:
+		if f.Reason == "" {
+			return fmt.Errorf("a reason is required for false positive feedback")
+		}
+	default:
+		return fmt.Errorf("invalid verdict %q", f.Verdict)
+	}
+	return nil
+}
+
+// PolicyAccuracy aggregates analyst feedback on a policy's findings
+type PolicyAccuracy struct {
+	PolicyID              string         `json:"policy_id"`
+	TotalFindings         int            `json:"total_findings"`
+	Reviewed              int            `json:"reviewed"`
+	TruePositives         int            `json:"true_positives"`
+	FalsePositives        int            `json:"false_positives"`
+	FalsePositiveRate     float64        `json:"false_positive_rate"`
+	FalsePositiveReasons  map[string]int `json:"false_positive_reasons,omitempty"`
+	SuppressionCandidates []string       `json:"suppression_candidates,omitempty"` // assets with repeated false positives
+	Suggestion            string         `json:"suggestion,omitempty"`
+}
+
+// NewPolicyAccuracy computes accuracy and tuning suggestions from feedback
+// counts. fpAssets and fpReasons hold the asset ID and reason of every false
+// positive.
+func NewPolicyAccuracy(policyID string, total, truePositives, falsePositives int, fpAssets, fpReasons []string) *PolicyAccuracy {
+	accuracy := &PolicyAccuracy{
+		PolicyID:             policyID,
+		TotalFindings:        total,
+		Reviewed:             truePositives + falsePositives,
+		TruePositives:        truePositives,
+		FalsePositives:       falsePositives,
+		FalsePositiveReasons: make(map[string]int),
+	}
+
+	for _, reason := range fpReasons {
+		accuracy.FalsePositiveReasons[reason]++
+	}
+
+	perAsset := make(map[string]int)
+	for _, assetID := range fpAssets {
+		perAsset[assetID]++
+	}
+	for assetID, count := range perAsset {
+		if count >= suppressionRepeats {
+			accuracy.SuppressionCandidates = append(accuracy.SuppressionCandidates, assetID)
+		}
+	}
+
+	accuracy.summarize()
+	return accuracy
+}
+
+// Merge adds the feedback counts of other, e.g. from another region
+func (a *PolicyAccuracy) Merge(other *PolicyAccuracy) {
+	a.TotalFindings += other.TotalFindings
+	a.Reviewed += other.Reviewed
+	a.TruePositives += other.TruePositives
+	a.FalsePositives += other.FalsePositives
+	if a.FalsePositiveReasons == nil {
+		a.FalsePositiveReasons = make(map[string]int)
+	}
+	for reason, count := range other.FalsePositiveReasons {
+		a.FalsePositiveReasons[reason] += count
+	}
+	a.SuppressionCandidates = append(a.SuppressionCandidates, other.SuppressionCandidates...)
+
+	a.summarize()
+}
+
+// summarize computes the false-positive rate and tuning suggestion
+func (a *PolicyAccuracy) summarize() {
+	sort.Strings(a.SuppressionCandidates)
+
+	a.FalsePositiveRate = 0
+	if a.Reviewed > 0 {
+		a.FalsePositiveRate = float64(a.FalsePositives) / float64(a.Reviewed)
+	}
+
+	switch {
+	case a.Reviewed < minFeedbackForTuning:
+		a.Suggestion = "insufficient feedback"
+	case a.FalsePositiveRate >= tuneFalsePositiveRate:
+		a.Suggestion = "tune or disable policy: most reviewed findings are false positives"
+	case a.FalsePositiveRate >= reviewFalsePositiveRate:
+		a.Suggestion = "review policy conditions"
+	case len(a.SuppressionCandidates) > 0:
+		a.Suggestion = "suppress policy for repeatedly misflagged assets"
+	default:
+		a.Suggestion = ""
+	}
+}
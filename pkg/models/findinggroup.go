@@ -0,0 +1,149 @@
+package models
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	resourceTokenPattern = regexp.MustCompile(`[a-z0-9]+`)
+	digitRunPattern      = regexp.MustCompile(`[0-9]+`)
+	hexIDPattern         = regexp.MustCompile(`^[0-9a-f]{8,}$`)
+)
+
+// FindingGroup is a set of findings that likely share a root cause: the same
+// policy failing on assets of one type whose names follow one pattern, such
+// as one misconfigured module deployed many times
+type FindingGroup struct {
+	ID              string    `json:"id"`
+	PolicyID        string    `json:"policy_id"`
+	AssetType       AssetType `json:"asset_type"`
+	ResourcePattern string    `json:"resource_pattern"`
+	Representative  Finding   `json:"representative"` // highest-risk finding in the group
+	Count           int       `json:"count"`
+	AssetIDs        []string  `json:"asset_ids"`
+	MaxSeverity     float64   `json:"max_severity"`
+	MaxRiskScore    float64   `json:"max_risk_score"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+}
+
+// CorrelatedFinding is a finding with the asset attributes used for grouping
+type CorrelatedFinding struct {
+	Finding   Finding
+	AssetName string
+	AssetType AssetType
+}
+
+// ResourcePattern generalizes a resource name by masking the parts that vary
+// between instances, e.g. "web-prod-01" and "web-prod-17" both become
+// "web-prod-*"
+func ResourcePattern(name string) string {
+	return resourceTokenPattern.ReplaceAllStringFunc(strings.ToLower(name), func(token string) string {
+		if hexIDPattern.MatchString(token) && digitRunPattern.MatchString(token) {
+			return "*"
+		}
+		return digitRunPattern.ReplaceAllString(token, "*")
+	})
+}
+
+// GroupFindings correlates findings into groups of at least minSize findings,
+// largest groups first
+func GroupFindings(findings []CorrelatedFinding, minSize int) []FindingGroup {
+	groups := make(map[string]*FindingGroup)
+	for _, cf := range findings {
+		group := FindingGroup{
+			PolicyID:        cf.Finding.PolicyID,
+			AssetType:       cf.AssetType,
+			ResourcePattern: ResourcePattern(cf.AssetName),
+			Representative:  cf.Finding,
+			Count:           1,
+			AssetIDs:        []string{cf.Finding.AssetID},
+			MaxSeverity:     cf.Finding.Severity,
+			MaxRiskScore:    cf.Finding.RiskScore,
+			FirstSeen:       cf.Finding.FirstSeen,
+			LastSeen:        cf.Finding.LastSeen,
+		}
+		group.ID = findingGroupID(group.PolicyID, group.AssetType, group.ResourcePattern)
+		addFindingGroup(groups, group)
+	}
+	return finishFindingGroups(groups, minSize)
+}
+
+// MergeFindingGroups combines groups with the same ID, e.g. computed in
+// different regions
+func MergeFindingGroups(groups []FindingGroup, minSize int) []FindingGroup {
+	merged := make(map[string]*FindingGroup)
+	for _, group := range groups {
+		addFindingGroup(merged, group)
+	}
+	return finishFindingGroups(merged, minSize)
+}
+
+func addFindingGroup(groups map[string]*FindingGroup, group FindingGroup) {
+	existing, ok := groups[group.ID]
+	if !ok {
+		group.AssetIDs = append([]string(nil), group.AssetIDs...)
+		groups[group.ID] = &group
+		return
+	}
+
+	existing.Count += group.Count
+	existing.AssetIDs = append(existing.AssetIDs, group.AssetIDs...)
+	if group.Representative.RiskScore > existing.Representative.RiskScore {
+		existing.Representative = group.Representative
+	}
+	if group.MaxSeverity > existing.MaxSeverity {
+		existing.MaxSeverity = group.MaxSeverity
+	}
+	if group.MaxRiskScore > existing.MaxRiskScore {
+		existing.MaxRiskScore = group.MaxRiskScore
+	}
+	if !group.FirstSeen.IsZero() && (existing.FirstSeen.IsZero() || group.FirstSeen.Before(existing.FirstSeen)) {
+		existing.FirstSeen = group.FirstSeen
+	}
+	if group.LastSeen.After(existing.LastSeen) {
+		existing.LastSeen = group.LastSeen
+	}
+}
+
+func finishFindingGroups(groups map[string]*FindingGroup, minSize int) []FindingGroup {
+	result := make([]FindingGroup, 0, len(groups))
+	for _, group := range groups {
+		if group.Count < minSize {
+			continue
+		}
+		group.AssetIDs = uniqueStrings(group.AssetIDs)
+		result = append(result, *group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].MaxRiskScore > result[j].MaxRiskScore
+	})
+	return result
+}
+
+func findingGroupID(policyID string, assetType AssetType, pattern string) string {
+	sum := sha1.Sum([]byte(policyID + "|" + string(assetType) + "|" + pattern))
+	return hex.EncodeToString(sum[:8])
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
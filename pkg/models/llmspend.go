@@ -0,0 +1,12 @@
+package models
+
+// LLMSpend is a tenant's use of LLM calls in one calendar month
+type LLMSpend struct {
+	TenantID         string  `json:"tenant_id"`
+	Month            string  `json:"month"` // YYYY-MM, in UTC
+	Requests         int64   `json:"requests"`
+	Rejected         int64   `json:"rejected"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// PolicyCategory is a policy category with the severity applied to policies
+// that do not set their own
+type PolicyCategory struct {
+	Name            string  `json:"name" yaml:"name"`
+	Description     string  `json:"description,omitempty" yaml:"description"`
+	DefaultSeverity float64 `json:"default_severity" yaml:"default_severity"` // 0-10
+}
+
+// DefaultPolicyCategories returns the built-in policy category taxonomy
+func DefaultPolicyCategories() []PolicyCategory {
+	return []PolicyCategory{
+		{Name: "misconfiguration", Description: "Insecure resource configuration", DefaultSeverity: 6.0},
+		{Name: "vulnerability", Description: "Known software vulnerabilities", DefaultSeverity: 7.0},
+		{Name: "exposure", Description: "Resources reachable from untrusted networks", DefaultSeverity: 8.0},
+		{Name: "identity", Description: "Excessive or unsafe identity permissions", DefaultSeverity: 7.5},
+		{Name: "data_protection", Description: "Unprotected or unencrypted sensitive data", DefaultSeverity: 7.0},
+		{Name: "network", Description: "Network segmentation and firewall rules", DefaultSeverity: 6.0},
+		{Name: "logging", Description: "Missing audit logging and monitoring", DefaultSeverity: 4.0},
+		{Name: "compliance", Description: "Deviations from compliance frameworks", DefaultSeverity: 5.0},
+	}
+}
+
+// PolicyCategoryRegistry is the set of known policy categories
+type PolicyCategoryRegistry struct {
+	mu         sync.RWMutex
+	categories map[string]PolicyCategory
+}
+
+// NewPolicyCategoryRegistry creates a registry from categories, or from the
+// default taxonomy when none are given
+func NewPolicyCategoryRegistry(categories []PolicyCategory) (*PolicyCategoryRegistry, error) {
+	if len(categories) == 0 {
+		categories = DefaultPolicyCategories()
+	}
+
+	registry := &PolicyCategoryRegistry{categories: make(map[string]PolicyCategory, len(categories))}
+	for _, category := range categories {
+		if err := registry.Register(category); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register adds a category, rejecting duplicates and invalid severities
+func (r *PolicyCategoryRegistry) Register(category PolicyCategory) error {
+	if category.Name == "" {
+		return fmt.Errorf("policy category name is required")
+	}
+	if category.DefaultSeverity < 0 || category.DefaultSeverity > 10 {
+		return fmt.Errorf("policy category %s: default severity must be between 0 and 10, got %f", category.Name, category.DefaultSeverity)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.categories[category.Name]; exists {
+		return fmt.Errorf("duplicate policy category: %s", category.Name)
+	}
+	r.categories[category.Name] = category
+	return nil
+}
+
+// Get returns the named category
+func (r *PolicyCategoryRegistry) Get(name string) (PolicyCategory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	category, ok := r.categories[name]
+	return category, ok
+}
+
+// List returns all categories sorted by name
+func (r *PolicyCategoryRegistry) List() []PolicyCategory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categories := make([]PolicyCategory, 0, len(r.categories))
+	for _, category := range r.categories {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories
+}
+
+// ResolveSeverity validates that category is known and returns severity, or
+// the category's default severity when severity is unset
+func (r *PolicyCategoryRegistry) ResolveSeverity(category string, severity float64) (float64, error) {
+	known, ok := r.Get(category)
+	if !ok {
+		return 0, fmt.Errorf("unknown policy category: %q", category)
+	}
+	if severity == 0 {
+		return known.DefaultSeverity, nil
+	}
+	if severity < 0 || severity > 10 {
+		return 0, fmt.Errorf("severity must be between 0 and 10, got %f", severity)
+	}
+	return severity, nil
+}
@@ -32,6 +32,9 @@ const (
 	RelationshipOwns RelationshipType = "OWNS"
 )
 
+// relationshipNamespace is the UUID namespace for deterministic relationship IDs
+var relationshipNamespace = uuid.MustParse("6f1c2a4e-8d3b-5e7f-9a10-2b4c6d8e0f12")
+
 // Relationship represents a relationship between two assets
 type Relationship struct {
 	ID           string           `json:"id"`
@@ -44,14 +47,46 @@ type Relationship struct {
 	CreatedAt    time.Time        `json:"created_at"`
 	UpdatedAt    time.Time        `json:"updated_at"`
 	Strength     float64          `json:"strength"` // 0.0-1.0, relationship strength/confidence
+	TrustScore   *float64         `json:"trust_score,omitempty"` // 0.0-1.0, how reliably an attacker can traverse it; unset means full trust
 	Description  string           `json:"description,omitempty"`
+
+	// ReactivatedAt and LastRevokedAt record the last time a soft-deleted
+	// relationship was collected again, and when it had been revoked
+	ReactivatedAt     *time.Time `json:"reactivated_at,omitempty"`
+	LastRevokedAt     *time.Time `json:"last_revoked_at,omitempty"`
+	ReactivationCount int        `json:"reactivation_count,omitempty"`
+
+	// Direction, AssetID and PeerAssetID orient the relationship relative to
+	// the asset it was queried by; they are only set on filtered results
+	Direction    RelationshipDirection `json:"direction,omitempty"`
+	AssetID      string           `json:"asset_id,omitempty"`
+	PeerAssetID  string           `json:"peer_asset_id,omitempty"`
+}
+
+// RelationshipDirection is the direction of a relationship relative to an asset
+type RelationshipDirection string
+
+const (
+	RelationshipOutgoing RelationshipDirection = "outgoing"
+	RelationshipIncoming RelationshipDirection = "incoming"
+)
+
+// Orient sets the direction of the relationship relative to the endpoint it
+// was queried by, along with that endpoint and its peer
+func (r *Relationship) Orient(direction RelationshipDirection) {
+	r.Direction = direction
+	if direction == RelationshipIncoming {
+		r.AssetID, r.PeerAssetID = r.ToAssetID, r.FromAssetID
+		return
+	}
+	r.AssetID, r.PeerAssetID = r.FromAssetID, r.ToAssetID
 }
 
 // NewRelationship creates a new relationship between two assets
 func NewRelationship(fromAssetID, toAssetID string, relType RelationshipType) Relationship {
 	now := time.Now()
 	return Relationship{
-		ID:          uuid.New().String(),
+		ID:          RelationshipID(fromAssetID, relType, toAssetID),
 		FromAssetID: fromAssetID,
 		ToAssetID:   toAssetID,
 		Type:        relType,
@@ -63,6 +98,18 @@ func NewRelationship(fromAssetID, toAssetID string, relType RelationshipType) Re
 	}
 }
 
+// RelationshipID returns the deterministic ID of the relationship of relType
+// from fromAssetID to toAssetID, so re-emitting the same edge yields the same ID
+func RelationshipID(fromAssetID string, relType RelationshipType, toAssetID string) string {
+	key := fromAssetID + "|" + string(relType) + "|" + toAssetID
+	return uuid.NewSHA1(relationshipNamespace, []byte(key)).String()
+}
+
+// CanonicalID returns the deterministic ID for the relationship's endpoints and type
+func (r *Relationship) CanonicalID() string {
+	return RelationshipID(r.FromAssetID, r.Type, r.ToAssetID)
+}
+
 // WithProperties adds properties to the relationship
 func (r *Relationship) WithProperties(props map[string]interface{}) *Relationship {
 	if r.Properties == nil {
@@ -140,6 +187,39 @@ type RelationshipFilter struct {
 	ValidAt       time.Time         `json:"valid_at,omitempty"`
 	MinStrength   float64           `json:"min_strength,omitempty"`
 	MaxStrength   float64           `json:"max_strength,omitempty"`
+	Limit         int               `json:"limit,omitempty"`
+	Offset        int               `json:"offset,omitempty"`
+	After         *RelationshipCursor `json:"after,omitempty"` // return only relationships past this position
+}
+
+// RelationshipCursor is a position in a listing of relationships ordered by
+// creation time and then ID. Paging from a cursor stays stable while
+// relationships are created, unlike paging by offset.
+type RelationshipCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// CursorOf returns the position of rel in a listing
+func CursorOf(rel Relationship) RelationshipCursor {
+	return RelationshipCursor{CreatedAt: rel.CreatedAt, ID: rel.ID}
+}
+
+// Before reports whether c is ordered before other
+func (c RelationshipCursor) Before(other RelationshipCursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.Before(other.CreatedAt)
+	}
+	return c.ID < other.ID
+}
+
+// RelationshipDeleteFilter selects relationships to remove in bulk. Unless
+// Hard is set, matching relationships are soft-deleted by closing their
+// validity, so their history is kept and collecting them again reactivates
+// them.
+type RelationshipDeleteFilter struct {
+	RelationshipFilter
+	Hard bool `json:"hard,omitempty"`
 }
 
 // RelationshipEdge represents an edge in the graph with additional metadata
@@ -156,6 +236,9 @@ type GraphPath struct {
 	Nodes []Asset           `json:"nodes"`
 	TotalWeight float64     `json:"total_weight"`
 	Length int              `json:"length"`
+	Likelihood float64      `json:"likelihood,omitempty"` // 0-1, see PathLikelihood
+	Accepted bool           `json:"accepted,omitempty"` // matches an AcceptedPath
+	AcceptedPathID string   `json:"accepted_path_id,omitempty"`
 }
 
 // AddEdge adds an edge to the path
@@ -13,6 +13,9 @@ type RiskScore struct {
 	ExposureMult   float64   `json:"exposure_mult"`   // 1-2
 	EnvironmentMult float64  `json:"environment_mult"` // 1-1.5
 	ThreatIntelMult float64  `json:"threat_intel_mult"` // 1-2
+	ReachabilityMult float64 `json:"reachability_mult,omitempty"` // 1 + crown jewel weight when a crown jewel is reachable
+	AssetType      AssetType `json:"asset_type,omitempty"`
+	Level          RiskLevel `json:"level,omitempty"` // set by the risk engine's level mapper for the asset type
 	LastCalculated time.Time `json:"last_calculated"`
 	Contributors   []RiskContributor `json:"contributors,omitempty"`
 }
@@ -28,22 +31,78 @@ const (
 	RiskLevelInfo     RiskLevel = "info"
 )
 
-// GetRiskLevel returns the risk level based on score
+// GetRiskLevel returns the risk level based on score, using the default
+// thresholds
 func GetRiskLevel(score float64) RiskLevel {
+	return DefaultRiskLevelThresholds().RiskLevel(score)
+}
+
+// RiskLevelMapper maps a 0-100 risk score to a risk level
+type RiskLevelMapper interface {
+	RiskLevel(score float64) RiskLevel
+}
+
+// RiskLevelMapperFunc adapts a function, e.g. a custom calibration curve, to
+// a RiskLevelMapper
+type RiskLevelMapperFunc func(score float64) RiskLevel
+
+func (f RiskLevelMapperFunc) RiskLevel(score float64) RiskLevel {
+	return f(score)
+}
+
+// RiskLevelThresholds maps scores to levels with a step function: a score is at
+// the highest level whose threshold it reaches, and info below Low
+type RiskLevelThresholds struct {
+	Critical float64 `json:"critical" yaml:"critical"`
+	High     float64 `json:"high" yaml:"high"`
+	Medium   float64 `json:"medium" yaml:"medium"`
+	Low      float64 `json:"low" yaml:"low"`
+}
+
+// DefaultRiskLevelThresholds returns the standard level thresholds
+func DefaultRiskLevelThresholds() RiskLevelThresholds {
+	return RiskLevelThresholds{Critical: 80, High: 60, Medium: 40, Low: 20}
+}
+
+func (t RiskLevelThresholds) RiskLevel(score float64) RiskLevel {
 	switch {
-	case score >= 80:
+	case score >= t.Critical:
 		return RiskLevelCritical
-	case score >= 60:
+	case score >= t.High:
 		return RiskLevelHigh
-	case score >= 40:
+	case score >= t.Medium:
 		return RiskLevelMedium
-	case score >= 20:
+	case score >= t.Low:
 		return RiskLevelLow
 	default:
 		return RiskLevelInfo
 	}
 }
 
+// CalibratedRiskLevels applies a calibration curve to the score, clamped to
+// 0-100, before mapping it with the thresholds
+type CalibratedRiskLevels struct {
+	Curve      func(score float64) float64
+	Thresholds RiskLevelThresholds
+}
+
+func (c CalibratedRiskLevels) RiskLevel(score float64) RiskLevel {
+	return c.Thresholds.RiskLevel(math.Min(100, math.Max(0, c.Curve(score))))
+}
+
+// LinearCurve returns the curve scale*score + offset
+func LinearCurve(scale, offset float64) func(float64) float64 {
+	return func(score float64) float64 { return scale*score + offset }
+}
+
+// LogarithmicCurve returns a curve that spreads out low scores and
+// compresses high ones, keeping 0 and 100 fixed
+func LogarithmicCurve() func(float64) float64 {
+	return func(score float64) float64 {
+		return 100 * math.Log1p(math.Max(0, score)) / math.Log1p(100)
+	}
+}
+
 // RiskEngine interface for risk calculation
 type RiskEngine interface {
 	CalculateRisk(asset Asset, findings []Finding, threats []ThreatEvent) RiskScore
@@ -236,11 +295,15 @@ type RiskTrend struct {
 	ChangeRate float64   `json:"change_rate"` // Score change per day
 }
 
-// RiskScorePoint represents a risk score at a point in time
+// RiskScorePoint represents a risk score at a point in time. Points
+// aggregating several scores hold their average as Score.
 type RiskScorePoint struct {
 	Timestamp time.Time `json:"timestamp"`
 	Score     float64   `json:"score"`
 	Level     RiskLevel `json:"level"`
+	Min       float64   `json:"min,omitempty"`
+	Max       float64   `json:"max,omitempty"`
+	Samples   int       `json:"samples,omitempty"`
 }
 
 // TimeRange represents a time range